@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/internal/anonymizer"
+)
+
+// runAnonymize implements `planguard anonymize`, which writes a sanitized
+// copy of a Terraform directory that is safe to share in bug reports
+func runAnonymize(args []string) int {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	directory := fs.String("directory", ".", "Directory containing Terraform files to anonymize")
+	outDir := fs.String("out", "", "Directory to write anonymized files to (required)")
+	fs.Parse(args)
+
+	if *outDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: -out is required\n\nUsage:\n  planguard anonymize -directory . -out sanitized/\n")
+		return 1
+	}
+
+	written, err := anonymizer.New().AnonymizeDirectory(*directory, *outDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error anonymizing Terraform files: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %d anonymized files to %s\n", len(written), *outDir)
+	fmt.Fprintf(os.Stderr, "Review the output before sharing: free-form text may still contain sensitive details\n")
+	return 0
+}
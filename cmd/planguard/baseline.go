@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/pkg/baseline"
+)
+
+// runBaseline implements `planguard baseline`, which records the current
+// violations so that later scans with -baseline only fail on new ones
+func runBaseline(args []string) int {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	var opts scanOptions
+	registerScanFlags(fs, &opts)
+	out := fs.String("out", ".planguard/baseline.json", "Path to write the baseline to")
+	fs.Parse(args)
+
+	if err := opts.resolve(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if opts.samplePercent > 0 && opts.samplePercent < 100 {
+		fmt.Fprintf(os.Stderr, "Error: a baseline must be generated from a full scan; remove -sample/-fast\n")
+		return 1
+	}
+	if opts.changedOnly {
+		fmt.Fprintf(os.Stderr, "Error: a baseline must be generated from a full scan; remove -changed-only/-since\n")
+		return 1
+	}
+	if len(opts.rules) > 0 || len(opts.excludeRules) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: a baseline must be generated from a full scan; remove -rule/-exclude-rule\n")
+		return 1
+	}
+
+	ctx, cancel := scanContext(opts)
+	defer cancel()
+
+	result, _, err := scanAll(ctx, opts)
+	if err != nil {
+		printError("%v\n", err)
+		return 1
+	}
+
+	localizePaths(result, opts.absolutePaths)
+	b := baseline.New(result.Violations)
+	if err := b.Save(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote baseline with %d violations to %s\n", len(b.Violations), *out)
+	return 0
+}
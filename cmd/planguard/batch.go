@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/cache"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// scanTargetInBatches scans a directory -batch-size files at a time. Each
+// batch is parsed, scanned, and released before the next, so memory use
+// follows the largest batch rather than the whole configuration. Batches
+// hold whole directories, so references and providers within a module
+// still resolve. Rules that need every resource, such as those calling
+// resources(), opt out of batching: they are evaluated afterwards in one
+// pass over all files.
+func scanTargetInBatches(ctx context.Context, cfg *config.Config, opts scanOptions, directory string, changed []string, resultCache *cache.Cache, ruleSetHash string) (*scanner.ScanResult, error) {
+	paths, err := parser.ListFiles(ctx, directory, cfg.Settings.ExcludePaths)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Terraform files: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("No Terraform files found in %s", directory)
+	}
+
+	var batched, global []config.Rule
+	for _, rule := range cfg.Rules {
+		if scanner.NeedsAllResources(rule) {
+			global = append(global, rule)
+		} else {
+			batched = append(batched, rule)
+		}
+	}
+
+	scanCfg, err := withInlineSkips(cfg, opts.honorInlineSkips, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := parser.Batches(paths, opts.batchSize)
+	logf("Scanning %d files in %d batches of up to %d files", len(paths), len(batches), opts.batchSize)
+
+	result := &scanner.ScanResult{}
+	// Only block kinds and resource types are kept across batches, for
+	// counts and diagnostics
+	var types []*config.Resource
+	warned := make(map[string]bool)
+	changedFilter, sampleFilter := targetFilters(opts, changed)
+	selected, sampled := 0, 0
+
+	for i, batch := range batches {
+		status.startBatch(i+1, len(batches))
+		resources, err := parseBatch(ctx, cfg, batch, warned)
+		if err != nil {
+			if isCancellation(err) {
+				result.Incomplete = true
+				return finishBatches(result, cfg), err
+			}
+			return nil, err
+		}
+		logParsedFiles(resources)
+		for _, resource := range resources {
+			types = append(types, &config.Resource{Kind: resource.Kind, Type: resource.Type})
+		}
+
+		s := scanner.NewScanner(scanCfg, batched, parser.NewScanContext(resources))
+		s.SetConcurrency(opts.concurrency)
+		s.SetProgress(status.evaluatedRules)
+		if resultCache != nil {
+			s.SetCache(resultCache, ruleSetHash)
+		}
+		if changedFilter != nil {
+			s.AddResourceFilter(changedFilter)
+			selected += countAccepted(resources, changedFilter)
+		}
+		if sampleFilter != nil {
+			s.AddResourceFilter(sampleFilter)
+			sampled += countAccepted(resources, sampleFilter)
+		}
+
+		batchResult, err := s.ScanWithContext(ctx)
+		if err != nil && !isCancellation(err) {
+			return nil, fmt.Errorf("Error during scan: %w", err)
+		}
+		mergeBatch(result, batchResult)
+		result.Resources += batchResult.Resources
+		result.CachedFiles += batchResult.CachedFiles
+		if err != nil {
+			return finishBatches(result, cfg), err
+		}
+	}
+
+	total := config.CountResources(types)
+	logf("Found %d resources in %d files", total, len(paths))
+	if changedFilter != nil {
+		logf("Evaluating %d of %d resources in %d changed files", selected, total, len(changed))
+	}
+	if sampleFilter != nil {
+		logf("Sampling %g%% of resources: evaluating %d of %d (run without -sample for a full scan)", opts.samplePercent, sampled, total)
+	}
+	if result.CachedFiles > 0 {
+		logf("Reused cached results for %d of %d files", result.CachedFiles, len(paths))
+	}
+
+	if len(global) > 0 {
+		var ids []string
+		for _, rule := range global {
+			ids = append(ids, rule.ID)
+		}
+		logf("Evaluating %d rules that need every resource in one pass over all files: %s", len(global), strings.Join(ids, ", "))
+		status.startBatch(0, 0)
+
+		resources, err := parseBatch(ctx, cfg, paths, warned)
+		if err != nil {
+			if isCancellation(err) {
+				result.Incomplete = true
+				return finishBatches(result, cfg), err
+			}
+			return nil, err
+		}
+
+		// The cache only holds results of rules that read a single file, so
+		// it is not used here
+		s := scanner.NewScanner(scanCfg, global, parser.NewScanContext(resources))
+		s.SetConcurrency(opts.concurrency)
+		s.SetProgress(status.evaluatedRules)
+		if changedFilter != nil {
+			s.AddResourceFilter(changedFilter)
+		}
+		if sampleFilter != nil {
+			s.AddResourceFilter(sampleFilter)
+		}
+
+		globalResult, err := s.ScanWithContext(ctx)
+		if err != nil && !isCancellation(err) {
+			return nil, fmt.Errorf("Error during scan: %w", err)
+		}
+		mergeBatch(result, globalResult)
+		if err != nil {
+			return finishBatches(result, cfg), err
+		}
+	}
+
+	result = finishBatches(result, cfg)
+	if diagnostics := scanner.Diagnose(result, types); len(diagnostics) > 0 {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", diagnostics[0])
+		for _, diagnostic := range diagnostics[1:] {
+			fmt.Fprintf(os.Stderr, "  - %s\n", diagnostic)
+		}
+	}
+	for _, evalErr := range result.EvaluationErrors() {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", evalErr)
+	}
+	return result, nil
+}
+
+// parseBatch parses files with a parser of its own, so nothing is kept once
+// the batch's resources are released, and extracts their resources. Rule
+// pack warnings not already in warned are printed.
+func parseBatch(ctx context.Context, cfg *config.Config, paths []string, warned map[string]bool) ([]*config.Resource, error) {
+	p := parser.NewParser()
+	p.SetProgress(status.parsedFiles)
+	files, err := p.ParseFiles(ctx, paths)
+	if err != nil {
+		if isCancellation(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Error parsing Terraform files: %w", err)
+	}
+
+	resources, err := parser.ExtractResources(files)
+	if err != nil {
+		return nil, fmt.Errorf("Error extracting resources: %w", err)
+	}
+
+	for _, warning := range packWarnings(cfg.Rules, files) {
+		if !warned[warning] {
+			warned[warning] = true
+			status.clear()
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+	}
+	return resources, nil
+}
+
+// mergeBatch adds a batch's violations and coverage to result
+func mergeBatch(result, batch *scanner.ScanResult) {
+	result.Violations = append(result.Violations, batch.Violations...)
+	result.FilteredViolations = append(result.FilteredViolations, batch.FilteredViolations...)
+	result.Coverage = scanner.MergeCoverage(result.Coverage, batch.Coverage)
+	result.Incomplete = result.Incomplete || batch.Incomplete
+}
+
+// finishBatches puts merged batch results in the order of a single scan and
+// scores them
+func finishBatches(result *scanner.ScanResult, cfg *config.Config) *scanner.ScanResult {
+	scanner.SortViolations(result.Violations)
+	scanner.SortFilteredViolations(result.FilteredViolations)
+
+	ruleIndex := make(map[string]int, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		ruleIndex[rule.ID] = i
+	}
+	sort.SliceStable(result.Coverage, func(i, j int) bool {
+		return ruleIndex[result.Coverage[i].RuleID] < ruleIndex[result.Coverage[j].RuleID]
+	})
+
+	result.Score = scanner.RiskScore(cfg.Settings, result.Violations)
+	return result
+}
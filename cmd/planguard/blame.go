@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/internal/gitdiff"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// addBlame records who last changed each violation's line, running git
+// blame once per file. Files that cannot be blamed, e.g. outside a git
+// repository, are reported once and left without blame.
+func addBlame(result *scanner.ScanResult) {
+	blames := make(map[string][]gitdiff.BlameLine)
+	failed := make(map[string]bool)
+
+	blame := func(v *config.Violation) {
+		if failed[v.File] {
+			return
+		}
+		lines, ok := blames[v.File]
+		if !ok {
+			var err error
+			lines, err = gitdiff.Blame(v.File)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				failed[v.File] = true
+				return
+			}
+			blames[v.File] = lines
+		}
+		if v.Line <= 0 || v.Line >= len(lines) {
+			return
+		}
+		line := lines[v.Line]
+		v.Blame = &config.Blame{
+			Commit:      line.Commit,
+			Author:      line.Author,
+			AuthorEmail: line.AuthorEmail,
+			Date:        line.Date,
+			Uncommitted: line.Uncommitted,
+		}
+	}
+
+	for i := range result.Violations {
+		blame(&result.Violations[i])
+	}
+	for i := range result.FilteredViolations {
+		blame(&result.FilteredViolations[i].Violation)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/cache"
+)
+
+const cacheUsage = `Usage: planguard cache <command> [flags]
+
+Commands:
+  info   Show the result cache's location, size, and hit rate
+  clean  Remove cached results, all of them or those unused for a while
+`
+
+// runCache implements `planguard cache`, which manages the result cache
+// used by scan -cache
+func runCache(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, cacheUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "info":
+		return runCacheInfo(args[1:])
+	case "clean":
+		return runCacheClean(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cache command %q\n\n%s", args[0], cacheUsage)
+		return 1
+	}
+}
+
+// runCacheInfo implements `planguard cache info`
+func runCacheInfo(args []string) int {
+	fs := flag.NewFlagSet("cache info", flag.ExitOnError)
+	cacheDir := fs.String("cache-dir", "", "Cache directory (default: user cache directory)")
+	fs.Parse(args)
+
+	c, err := openCacheDir(*cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	info, err := c.Info()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Directory: %s\n", c.Dir())
+	fmt.Printf("Entries:   %d (%s)\n", info.Entries, formatSize(info.Bytes))
+	if info.Entries > 0 {
+		fmt.Printf("Last used: %s to %s\n", info.Oldest.Format(time.DateTime), info.Newest.Format(time.DateTime))
+	}
+	if stats := info.Stats; stats.Hits+stats.Misses > 0 {
+		fmt.Printf("Lookups:   %d hits, %d misses (%.0f%% hit rate) since %s\n", stats.Hits, stats.Misses, 100*stats.HitRate(), stats.Since.Local().Format(time.DateTime))
+	} else {
+		fmt.Printf("Lookups:   none recorded\n")
+	}
+	return 0
+}
+
+// runCacheClean implements `planguard cache clean`
+func runCacheClean(args []string) int {
+	fs := flag.NewFlagSet("cache clean", flag.ExitOnError)
+	cacheDir := fs.String("cache-dir", "", "Cache directory (default: user cache directory)")
+	olderThan := fs.Duration("older-than", 0, "Only remove results not used for this long, e.g. 720h (default: remove everything, including hit rate stats)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be removed without removing it")
+	fs.Parse(args)
+
+	if *olderThan < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid -older-than %s (expected a positive duration)\n", *olderThan)
+		return 1
+	}
+	c, err := openCacheDir(*cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var cutoff time.Time
+	if *olderThan > 0 {
+		cutoff = time.Now().Add(-*olderThan)
+	}
+	removed, freed, err := c.Clean(cutoff, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d cached results (%s) from %s\n", verb, removed, formatSize(freed), c.Dir())
+	return 0
+}
+
+// openCacheDir opens the cache in dir, or the default cache directory
+func openCacheDir(dir string) (*cache.Cache, error) {
+	dir, err := resolveCacheDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(dir)
+}
+
+// formatSize formats a size in bytes with a binary unit, e.g. 1.5 MiB
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
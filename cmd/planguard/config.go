@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/configmigrate"
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+const configUsage = `Usage: planguard config <command> [flags]
+
+Commands:
+  migrate  Upgrade config, exception, rule, and pack files written for older versions
+`
+
+// runConfig implements `planguard config`, which maintains config files
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runConfigMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command %q\n\n%s", args[0], configUsage)
+		return 1
+	}
+}
+
+// runConfigMigrate implements `planguard config migrate`, which rewrites
+// HCL files in place to the current schema and lists what it changed. It
+// migrates the config file and its exception files, or the files and
+// directories given as arguments.
+func runConfigMigrate(args []string) int {
+	fs := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	configPath := fs.String("config", "", "Config file to migrate, with the exception files next to it (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules, whose rules and categories migrations check against (default: ~/.planguard/rules, or the rules built into planguard if it does not exist)")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	dryRun := fs.Bool("dry-run", false, "List the changes without writing them")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard config migrate [flags] [files or directories...]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	files, err := migrationFiles(*configPath, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts, err := migrationOptions(*configPath, *rulesDir, *prefer, files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	changed := 0
+	for _, file := range files {
+		if filepath.Ext(file) != ".hcl" {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s; only .hcl files can be migrated, so migrate it by hand\n", file)
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		out, fileChanges, err := configmigrate.Migrate(file, src, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(fileChanges) == 0 {
+			continue
+		}
+		changed++
+
+		if !*dryRun {
+			if err := os.WriteFile(file, out, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", file, err)
+				return 1
+			}
+		}
+		for _, change := range fileChanges {
+			fmt.Printf("%s: %s\n", file, change)
+		}
+	}
+
+	switch {
+	case changed == 0:
+		fmt.Fprintf(os.Stderr, "No changes needed\n")
+	case *dryRun:
+		fmt.Fprintf(os.Stderr, "Would migrate %d of %d files\n", changed, len(files))
+	default:
+		fmt.Fprintf(os.Stderr, "Migrated %d of %d files\n", changed, len(files))
+	}
+	return 0
+}
+
+// migrationFiles returns the files to migrate: those in paths, searching
+// directories for .hcl files, or else the config file with its exception
+// files
+func migrationFiles(configPath string, paths []string) ([]string, error) {
+	if len(paths) > 0 {
+		return ruleFiles(paths)
+	}
+	path, err := resolveConfigPath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	files, err := exceptionFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{path}, files...), nil
+}
+
+// migrationOptions collects what migrations check against: the aliases of
+// the presupplied rules, the config's rules, and the rules in files, and
+// the presupplied rule categories. A config that fails to load only leaves
+// out its rules, since an outdated config is what migrate is for.
+func migrationOptions(configPath, rulesDir, prefer string, files []string) (configmigrate.Options, error) {
+	dir, err := resolveRulesDir(rulesDir)
+	if err != nil {
+		return configmigrate.Options{}, err
+	}
+	source := packRulesSource(dir, prefer)
+	categories, err := config.RuleCategoryNames(presuppliedRulesFS(dir, source))
+	if err != nil {
+		return configmigrate.Options{}, fmt.Errorf("failed to list presupplied rule categories: %w", err)
+	}
+	rules, err := loadPresuppliedRules(dir, source, categories)
+	if err != nil {
+		return configmigrate.Options{}, err
+	}
+
+	if configPath != "" {
+		if configPath, err = expandHomePath(configPath); err != nil {
+			return configmigrate.Options{}, err
+		}
+	}
+	if cfg, _, err := loadConfigLayers(configLayers(configPath)); err == nil {
+		rules = append(rules, cfg.Rules...)
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: %v; the former IDs of its rules are not migrated\n", err)
+	}
+
+	// Files that do not hold rules, such as config and exception files, fail
+	// to load as rule files
+	for _, file := range files {
+		if fileRules, err := config.LoadRules([]string{file}); err == nil {
+			rules = append(rules, fileRules...)
+		}
+	}
+
+	aliases, warnings := config.RuleAliases(rules)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return configmigrate.Options{Aliases: aliases, Categories: categories}, nil
+}
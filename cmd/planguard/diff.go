@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/pkg/resultdiff"
+)
+
+// runDiff implements `planguard diff`, which compares two JSON reports and
+// lists the violations a change introduces and the ones it fixes
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format (text, json, markdown)")
+	failOnNew := fs.Bool("fail-on-new", false, "Exit 1 when the new report has violations the old one does not")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: planguard diff [flags] <old.json> <new.json>\n\nReports are written with planguard -format json.\n")
+		return 1
+	}
+	if *format != "text" && *format != "json" && *format != "markdown" {
+		fmt.Fprintf(os.Stderr, "Error: invalid -format value %q (expected text, json, or markdown)\n", *format)
+		return 1
+	}
+
+	old, err := resultdiff.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	new, err := resultdiff.Load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	d := resultdiff.Compare(old, new)
+	output := d.FormatText()
+	switch *format {
+	case "json":
+		output, err = d.FormatJSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
+			return 1
+		}
+		output += "\n"
+	case "markdown":
+		output = d.FormatMarkdown()
+	}
+	fmt.Print(output)
+
+	if *failOnNew && len(d.New) > 0 {
+		return 1
+	}
+	return 0
+}
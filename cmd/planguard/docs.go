@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jonathanhle/planguard/internal/docs"
+	"github.com/jonathanhle/planguard/pkg/config"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+// runDocs implements `planguard docs`, which renders documentation for the
+// loaded rules
+func runDocs(args []string) int {
+	if len(args) == 0 || args[0] != "bundle" {
+		fmt.Fprintf(os.Stderr, "Usage: planguard docs bundle [-out site.tar.gz]\n")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("docs bundle", flag.ExitOnError)
+	out := fs.String("out", "site.tar.gz", "File to write the gzipped tar archive of the site to")
+	title := fs.String("title", "Planguard Policies", "Title shown on every page")
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
+	usePresuppliedRules := fs.String("use-presupplied-rules", "", "Enable presupplied rules (true/false, default: true)")
+	presuppliedRulesCategories := fs.String("presupplied-rules-categories", "", "Comma-separated list of presupplied rule categories to document")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	fs.Parse(args[1:])
+
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cfg, err := loadConfiguration(*configPath, "", *rulesDir, *usePresuppliedRules, *presuppliedRulesCategories, *prefer, nil)
+	if err != nil {
+		printError("Error loading configuration: %v\n", err)
+		return 1
+	}
+
+	categories, err := ruleCategories(cfg, *rulesDir, *prefer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	site := &docs.Site{
+		Title:      *title,
+		Version:    version,
+		Generated:  time.Now().UTC().Truncate(time.Second),
+		Categories: categories,
+	}
+
+	var buf bytes.Buffer
+	if err := site.WriteBundle(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering documentation: %v\n", err)
+		return 1
+	}
+	if err := writeFile(*out, buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing bundle: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote documentation for %d rules in %d categories to %s; extract it and open site/index.html\n",
+		len(site.Rules()), len(categories), *out)
+	return 0
+}
+
+// ruleCategories groups the loaded rules by the presupplied category they
+// come from. Rules defined in the config, or in no category, are grouped as
+// "custom". Rules turned off by enabled_rules or disabled_rules, and opt-in
+// rules not turned on, are left out.
+func ruleCategories(cfg *config.Config, rulesDir, prefer string) ([]docs.Category, error) {
+	loaded := make(map[string]config.Rule, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		loaded[rule.ID] = rule
+	}
+
+	dir, err := resolveRulesDir(rulesDir)
+	if err != nil {
+		return nil, err
+	}
+	_, statErr := os.Stat(dir)
+
+	var categories []docs.Category
+	categorized := make(map[string]bool)
+	if statErr == nil || prefer == "embedded" {
+		names, err := config.RuleCategoryNames(presuppliedRulesFS(dir, prefer))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rule categories: %w", err)
+		}
+		for _, name := range names {
+			var rules []config.Rule
+			if prefer == "embedded" {
+				rules, err = config.LoadDefaultRulesFS(embeddedrules.FS, []string{name})
+			} else {
+				rules, err = config.LoadDefaultRulesWithCategories(dir, []string{name})
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s rules: %w", name, err)
+			}
+
+			category := docs.Category{Name: name}
+			for _, rule := range rules {
+				// Use the loaded rule, which carries any severity
+				// remapping and the pack it came from
+				if r, ok := loaded[rule.ID]; ok {
+					category.Rules = append(category.Rules, r)
+					categorized[rule.ID] = true
+				}
+			}
+			if len(category.Rules) > 0 {
+				categories = append(categories, category)
+			}
+		}
+	}
+
+	custom := docs.Category{Name: "custom"}
+	for _, rule := range cfg.Rules {
+		if !categorized[rule.ID] {
+			custom.Rules = append(custom.Rules, rule)
+		}
+	}
+	if len(custom.Rules) > 0 {
+		categories = append(categories, custom)
+	}
+
+	return categories, nil
+}
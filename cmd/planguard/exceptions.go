@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jonathanhle/planguard/internal/configedit"
+	"github.com/jonathanhle/planguard/internal/gitdiff"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+	"github.com/jonathanhle/planguard/pkg/resultdiff"
+)
+
+const exceptionsUsage = `Usage: planguard exceptions <command> [flags]
+
+Commands:
+  list   List the config file's exceptions and those of its exceptions directory, with which have expired or expire soon
+  prune  Remove expired exceptions from the config file and its exceptions directory
+  add    Add an exception for a violation from a JSON report to the config file or an exception file
+`
+
+// runExceptions implements `planguard exceptions`, which manages the
+// exceptions in a config file
+func runExceptions(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, exceptionsUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		return runExceptionsList(args[1:])
+	case "prune":
+		return runExceptionsPrune(args[1:])
+	case "add":
+		return runExceptionsAdd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown exceptions command %q\n\n%s", args[0], exceptionsUsage)
+		return 1
+	}
+}
+
+// runExceptionsList implements `planguard exceptions list`
+func runExceptionsList(args []string) int {
+	fs := flag.NewFlagSet("exceptions list", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	expiredOnly := fs.Bool("expired", false, "Only list expired exceptions")
+	fs.Parse(args)
+
+	path, err := resolveConfigPath(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	files, err := exceptionFiles(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, file := range files {
+		exceptions, err := config.LoadExceptionFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		cfg.Exceptions = append(cfg.Exceptions, exceptions...)
+	}
+
+	warningDays := config.DefaultExpiryWarningDays
+	if cfg.Settings.ExceptionExpiryWarningDays != nil {
+		warningDays = *cfg.Settings.ExceptionExpiryWarningDays
+	}
+	now := time.Now()
+	expiring := make(map[int]config.ExpiringException)
+	for i, exception := range cfg.Exceptions {
+		if ee := config.ExpiringExceptions([]config.Exception{exception}, now, warningDays); len(ee) > 0 {
+			expiring[i] = ee[0]
+		}
+	}
+
+	expired := 0
+	for i, exception := range cfg.Exceptions {
+		ee, soon := expiring[i]
+		if soon && ee.Expired {
+			expired++
+		} else if *expiredOnly {
+			continue
+		}
+
+		status := "active"
+		switch {
+		case soon && ee.Expired:
+			status = "EXPIRED " + ee.ExpiresAt
+		case soon:
+			status = fmt.Sprintf("expires %s (in %d days)", ee.ExpiresAt, ee.DaysLeft)
+		case exception.ExpiresAt != nil:
+			status = "expires " + *exception.ExpiresAt
+		}
+		fmt.Printf("%-30s %s\n", status, reporter.ExceptionScope(exception))
+		approval := "approved by " + exception.ApprovedBy
+		if exception.Ticket != nil {
+			approval += ", ticket " + *exception.Ticket
+		}
+		fmt.Printf("    Reason: %s (%s)\n", exception.Reason, approval)
+		if len(files) > 0 {
+			fmt.Printf("    In: %s\n", exception.Source)
+		}
+	}
+	where := path
+	if len(files) > 0 {
+		where += fmt.Sprintf(" and %d exception files", len(files))
+	}
+	fmt.Fprintf(os.Stderr, "%d exceptions in %s, %d expired\n", len(cfg.Exceptions), where, expired)
+	return 0
+}
+
+// runExceptionsPrune implements `planguard exceptions prune`
+func runExceptionsPrune(args []string) int {
+	fs := flag.NewFlagSet("exceptions prune", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	dryRun := fs.Bool("dry-run", false, "List the expired exceptions without removing them")
+	fs.Parse(args)
+
+	path, err := resolveConfigPath(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := checkEditable(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	files, err := exceptionFiles(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, file := range append([]string{path}, files...) {
+		if err := pruneFile(file, *dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// pruneFile removes the expired exceptions from a config or exception file
+func pruneFile(path string, dryRun bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	out, removed, err := configedit.PruneExpired(path, src, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, exception := range removed {
+		fmt.Printf("expired %s  %s\n    Reason: %s (approved by %s)\n", *exception.ExpiresAt, reporter.ExceptionScope(exception), exception.Reason, exception.ApprovedBy)
+	}
+	switch {
+	case len(removed) == 0:
+		fmt.Fprintf(os.Stderr, "No expired exceptions in %s\n", path)
+	case dryRun:
+		fmt.Fprintf(os.Stderr, "Would remove %d expired exceptions from %s\n", len(removed), path)
+	default:
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Removed %d expired exceptions from %s\n", len(removed), path)
+	}
+	return nil
+}
+
+// runExceptionsAdd implements `planguard exceptions add`, which appends an
+// exception for one reported violation to the config file. Values not given
+// as flags are prompted for on a terminal.
+func runExceptionsAdd(args []string) int {
+	fs := flag.NewFlagSet("exceptions add", flag.ExitOnError)
+	report := fs.String("report", "", "JSON report the violation is from (required)")
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	reason := fs.String("reason", "", "Why the violation is accepted")
+	approvedBy := fs.String("approved-by", "", "Who approved the exception")
+	ticket := fs.String("ticket", "", "Ticket tracking the exception (optional)")
+	expires := fs.String("expires", "", "Date the exception expires, as YYYY-MM-DD (optional)")
+	file := fs.String("file", "", "Exception file to add to instead of the config file, e.g. .planguard/exceptions/legacy.hcl, created if missing (requires an expiry)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard exceptions add -report report.json [flags] <fingerprint|file:line>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *report == "" {
+		fs.Usage()
+		return 1
+	}
+	var path string
+	var err error
+	if *file != "" {
+		path, err = expandHomePath(*file)
+	} else {
+		path, err = resolveConfigPath(*configPath)
+	}
+	if err == nil {
+		err = checkEditable(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	reported, err := resultdiff.Load(*report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	violations, err := findViolations(reported, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(violations) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: the report has %d violations at %s; give the fingerprint of one:\n", len(violations), fs.Arg(0))
+		for _, v := range violations {
+			fmt.Fprintf(os.Stderr, "  %s  %s on %s.%s\n", v.Fingerprint, v.RuleID, v.ResourceType, v.ResourceName)
+		}
+		return 1
+	}
+	v := violations[0]
+
+	// Prompt for what the flags left out, when someone can answer
+	interactive := isTerminal(os.Stdin)
+	if interactive {
+		fmt.Fprintf(os.Stderr, "Adding an exception for %s on %s.%s at %s:%d\n", v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line)
+	}
+	in := bufio.NewReader(os.Stdin)
+	fields := []struct {
+		value    *string
+		prompt   string
+		flag     string
+		required bool
+		validate func(string) error
+	}{
+		{reason, "Reason", "-reason", true, nil},
+		{approvedBy, "Approved by", "-approved-by", true, nil},
+		{ticket, "Ticket (optional)", "-ticket", false, nil},
+		{expires, "Expires (YYYY-MM-DD, optional)", "-expires", false, validateExpiry},
+	}
+	// Exception files require an expiry
+	if *file != "" {
+		fields[3].prompt, fields[3].required = "Expires (YYYY-MM-DD)", true
+	}
+	for _, field := range fields {
+		if *field.value == "" && interactive {
+			for {
+				answer, err := prompt(in, field.prompt)
+				if err == io.EOF {
+					fmt.Fprintln(os.Stderr)
+					break
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return 1
+				}
+				if answer == "" && field.required {
+					continue
+				}
+				if answer != "" && field.validate != nil {
+					if err := field.validate(answer); err != nil {
+						fmt.Fprintf(os.Stderr, "%v\n", err)
+						continue
+					}
+				}
+				*field.value = answer
+				break
+			}
+		}
+		if *field.value == "" && field.required {
+			fmt.Fprintf(os.Stderr, "Error: %s is required\n", field.flag)
+			return 1
+		}
+		if *field.value != "" && field.validate != nil {
+			if err := field.validate(*field.value); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+	}
+
+	// Report paths are relative to the repository root, exception paths to
+	// the config's directory
+	root, ok := gitdiff.RepoRoot(".")
+	if !ok {
+		root = "."
+	}
+	exception := config.Exception{
+		Rules:         []string{v.RuleID},
+		Paths:         []string{configedit.ExceptionPath(path, root, v.File)},
+		ResourceNames: []string{v.ResourceName},
+		Reason:        *reason,
+		ApprovedBy:    *approvedBy,
+	}
+	if *ticket != "" {
+		exception.Ticket = ticket
+	}
+	if *expires != "" {
+		exception.ExpiresAt = expires
+	}
+
+	src, err := os.ReadFile(path)
+	if os.IsNotExist(err) && *file != "" {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read config: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, configedit.AppendException(src, exception), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+		return 1
+	}
+	fmt.Print(string(config.FormatExceptions([]config.Exception{exception})))
+	fmt.Fprintf(os.Stderr, "Added the exception above to %s\n", path)
+	return 0
+}
+
+// resolveConfigPath returns the config file to edit: configPath with ~
+// expanded, or the one scans find by default
+func resolveConfigPath(configPath string) (string, error) {
+	if configPath != "" {
+		return expandHomePath(configPath)
+	}
+	if path := findConfigFile(); path != "" {
+		return path, nil
+	}
+	return "", fmt.Errorf("no config file found in .planguard in this or a parent directory or in ~/.planguard (config.hcl, config.yaml, config.yml, config.json); create one with planguard init or pass -config")
+}
+
+// exceptionFiles returns the files of the exceptions directory next to the
+// config file at path
+func exceptionFiles(path string) ([]string, error) {
+	return config.ExceptionFiles(filepath.Join(filepath.Dir(path), config.ExceptionsDir))
+}
+
+// checkEditable returns an error for config files that prune and add
+// cannot rewrite, which are those not written in HCL
+func checkEditable(path string) error {
+	if ext := filepath.Ext(path); ext != ".hcl" {
+		return fmt.Errorf("%s is not an HCL file; only .hcl configs can be edited, so edit its exceptions by hand", path)
+	}
+	return nil
+}
+
+// prompt asks a question on stderr and reads a line of answer from in. It
+// returns io.EOF when the input ends without an answer.
+func prompt(in *bufio.Reader, question string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", question)
+	answer, err := in.ReadString('\n')
+	if err == io.EOF && answer != "" {
+		err = nil
+	}
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(answer), err
+}
+
+// validateExpiry checks an expires_at date
+func validateExpiry(date string) error {
+	if _, err := time.Parse(config.ExpiryDateFormat, date); err != nil {
+		return fmt.Errorf("invalid expiry date %q (expected YYYY-MM-DD)", date)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jonathanhle/planguard/internal/gitdiff"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/repro"
+	"github.com/jonathanhle/planguard/pkg/resultdiff"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// runExplain implements `planguard explain`, which re-evaluates the
+// resource of a reported violation against its rule and shows the value of
+// each expression and of the attributes it reads
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	report := fs.String("report", "", "JSON report the violation is from (required)")
+	configPath := fs.String("config", "", "Path to config file whose rules are loaded (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory of rules to load, including subdirectories (default: ~/.planguard/rules, or the rules built into planguard if it does not exist)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard explain -report report.json [flags] <fingerprint|file:line>\n\nA file:line explains every violation the report has at that line.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *report == "" {
+		fs.Usage()
+		return 1
+	}
+
+	reported, err := resultdiff.Load(*report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	violations, err := findViolations(reported, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	rules, err := loadAllRules(*configPath, *rulesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	byID := make(map[string]config.Rule)
+	for _, rule := range rules {
+		if _, ok := byID[rule.ID]; !ok {
+			byID[rule.ID] = rule
+		}
+	}
+
+	for i, v := range violations {
+		rule, ok := byID[v.RuleID]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: rule %s is not loaded; pass the -config or -rules-dir the report was made with\n", v.RuleID)
+			return 1
+		}
+		explanation, err := explainViolation(v, rule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(formatExplanation(explanation, v))
+	}
+	return 0
+}
+
+// findViolations returns the reported violation with a fingerprint, or
+// those at a file:line. Files are matched as the report wrote them, or by a
+// trailing part of the path.
+func findViolations(violations []config.Violation, target string) ([]config.Violation, error) {
+	sep := strings.LastIndex(target, ":")
+	if sep < 0 {
+		v, err := repro.Find(violations, target)
+		if err != nil {
+			return nil, err
+		}
+		return []config.Violation{v}, nil
+	}
+
+	line, err := strconv.Atoi(target[sep+1:])
+	if err != nil || line < 1 {
+		return nil, fmt.Errorf("invalid location %q (expected file:line)", target)
+	}
+	file := filepath.ToSlash(filepath.Clean(target[:sep]))
+	var found []config.Violation
+	for _, v := range violations {
+		reported := filepath.ToSlash(v.File)
+		if v.Line == line && (reported == file || strings.HasSuffix(reported, "/"+file)) {
+			found = append(found, v)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("the report has no violation at %s", target)
+	}
+	return found, nil
+}
+
+// explainViolation parses the directory of a violation's file and explains
+// how rule evaluates its resource
+func explainViolation(v config.Violation, rule config.Rule) (*scanner.Explanation, error) {
+	path, err := reportedFile(v.File)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parser.NewParser().ParseDirectory(filepath.Dir(path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform files: %w", err)
+	}
+	resources, err := parser.ExtractResources(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract resources: %w", err)
+	}
+
+	var resource *config.Resource
+	for _, r := range resources {
+		if r.Type == v.ResourceType && r.Name == v.ResourceName && sameFile(r.File, path) {
+			resource = r
+			break
+		}
+	}
+	if resource == nil {
+		return nil, fmt.Errorf("%s.%s is no longer in %s", v.ResourceType, v.ResourceName, path)
+	}
+
+	s := scanner.NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+	return s.Explain(rule, resource)
+}
+
+// reportedFile finds a file named in a report: as written, or relative to
+// the root of the current git repository, as reports write paths by default
+func reportedFile(file string) (string, error) {
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	if root, ok := gitdiff.RepoRoot("."); ok {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("cannot find %s; run planguard explain where the scan was run", file)
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// formatExplanation formats an explanation as text: each expression with
+// its result and the attribute values it read, then the verdict
+func formatExplanation(e *scanner.Explanation, v config.Violation) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s on %s (%s:%d)\n", e.Rule.ID, resourceLabel(e.Resource), v.File, v.Line))
+
+	writeResult := func(title string, result scanner.ExpressionResult) {
+		output.WriteString(fmt.Sprintf("\n%s:\n", title))
+		for _, line := range strings.Split(result.Expression, "\n") {
+			output.WriteString("    " + strings.TrimSpace(line) + "\n")
+		}
+		output.WriteString(fmt.Sprintf("  Result: %s\n", result.Result))
+		for _, value := range result.Values {
+			output.WriteString(fmt.Sprintf("  %s = %s\n", value.Path, value.Value))
+		}
+	}
+	if e.When != nil {
+		writeResult("When", *e.When)
+	}
+	for i, condition := range e.Conditions {
+		writeResult(fmt.Sprintf("Condition %d", i+1), condition)
+	}
+
+	output.WriteString("\n")
+	switch {
+	case !e.Targeted:
+		output.WriteString(fmt.Sprintf("Not evaluated: the rule applies to %s resources\n", e.Rule.ResourceType))
+	case !e.Runs:
+		output.WriteString("Not evaluated: when is not true\n")
+	case e.Violated:
+		output.WriteString("Violation: a condition is true\n")
+	default:
+		output.WriteString("No violation: no condition is true (the configuration may have changed since the report)\n")
+	}
+	return output.String()
+}
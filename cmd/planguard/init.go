@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/scaffold"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+// runInit implements `planguard init`, which writes a starter config with
+// commented defaults and, optionally, a copy of the presupplied rules
+func runInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	directory := flags.String("directory", ".", "Directory to create .planguard/config.hcl in")
+	copyRules := flags.Bool("copy-rules", false, "Copy the presupplied rules built into planguard to the rules directory")
+	rulesDir := flags.String("rules-dir", "", "Directory to copy the presupplied rules to (default: ~/.planguard/rules)")
+	force := flags.Bool("force", false, "Replace an existing config and rule files")
+	flags.Parse(args)
+
+	configPath := filepath.Join(*directory, ".planguard", "config.hcl")
+	err := scaffold.WriteConfig(configPath, *force)
+	switch {
+	case errors.Is(err, fs.ErrExist) && *copyRules:
+		// Copying the rules into a project set up earlier
+		fmt.Fprintf(os.Stderr, "Kept existing %s\n", configPath)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	default:
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", configPath)
+	}
+
+	dir, err := resolveRulesDir(*rulesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *copyRules {
+		written, skipped, err := scaffold.CopyRules(embeddedrules.FS, dir, *force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Copied %d presupplied rule files to %s", written, dir)
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, " (kept %d existing files; pass -force to replace them)", skipped)
+		}
+		fmt.Fprintln(os.Stderr)
+	} else if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "No rules directory at %s; run planguard init -copy-rules to create it, or scan with -prefer embedded\n", dir)
+	}
+
+	fmt.Fprintf(os.Stderr, "Next: edit the config, then run planguard scan -directory %s\n", *directory)
+	return 0
+}
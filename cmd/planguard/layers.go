@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/rulelock"
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// orgConfigDir holds the organization-wide config layer, merged under every
+// other config
+const orgConfigDir = "/etc/planguard"
+
+// userConfigDir holds the user's config layer
+const userConfigDir = "~/.planguard"
+
+// configLayers returns the config files to merge, broadest first: the
+// organization's in /etc/planguard, the user's in ~/.planguard, then the
+// repository's in the nearest .planguard. An explicit -config replaces the
+// user and repository layers, but not the organization's.
+func configLayers(configPath string) []string {
+	dirs := []string{orgConfigDir}
+	if configPath == "" {
+		dirs = append(dirs, userConfigDir, projectConfigDir())
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		path := findConfigIn(dir)
+		if path == "" {
+			continue
+		}
+		// ~/.planguard is the nearest .planguard when run from the home
+		// directory or below it, outside any repository with its own
+		if abs, err := filepath.Abs(path); err == nil {
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+		}
+		paths = append(paths, path)
+	}
+	if configPath != "" {
+		paths = append(paths, configPath)
+	}
+	return paths
+}
+
+// projectConfigDir returns the .planguard directory with a config file
+// nearest the working directory, searching it and then its parents the way
+// .editorconfig files are found, so a scan run from a subdirectory of a
+// repository uses the repository's config. It returns ./.planguard when no
+// directory has one. A directory found above the working directory is
+// returned relative to it, like ../../.planguard.
+func projectConfigDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "./.planguard"
+	}
+	for dir := wd; ; {
+		candidate := filepath.Join(dir, ".planguard")
+		if findConfigIn(candidate) != "" {
+			if rel, err := filepath.Rel(wd, candidate); err == nil {
+				return rel
+			}
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "./.planguard"
+		}
+		dir = parent
+	}
+}
+
+// findConfigIn returns the first of configFileNames in dir, or "" if there
+// is none
+func findConfigIn(dir string) string {
+	for _, name := range configFileNames {
+		path, err := expandHomePath(dir + "/" + name)
+		if err != nil {
+			return ""
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfigLayers loads and merges config files. Each layer's rule_sources
+// are loaded with the lock file next to it, so their rules are protected
+// like the rules written in the layer itself, and are fetched and verified
+// under the credentials and signing settings of the organization and user
+// layers too (see ruleSourceSettings). The files of the
+// exceptions directory next to it add to its exceptions. It also reports
+// whether the nearest layer, the last one unless that is the organization
+// layer, defines rules of its own: only those replace the presupplied rules,
+// so the rules of broader layers are scanned alongside them.
+func loadConfigLayers(paths []string) (*config.Config, bool, error) {
+	var layers []config.Layer
+	var broader []*config.Settings
+	for _, path := range paths {
+		cfg, err := config.DecodeConfig(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to load config from %s: %w", path, err)
+		}
+		if err := checkLayerSettings(path, cfg.Settings); err != nil {
+			return nil, false, err
+		}
+		// The tracker's token is sent to ticket_validation's url, so a
+		// repository's config cannot set or weaken it
+		if cfg.Settings != nil && cfg.Settings.TicketValidation != nil && !trustedLayer(path) {
+			status.clear()
+			fmt.Fprintf(os.Stderr, "Warning: %s: ignoring ticket_validation, which only applies from the configs in %s and %s\n", path, orgConfigDir, userConfigDir)
+			cfg.Settings.TicketValidation = nil
+		}
+		if cfg.Settings != nil && len(cfg.Settings.RuleSources) > 0 {
+			rules, err := loadRuleSources(ruleSourceSettings(cfg.Settings, broader), filepath.Join(filepath.Dir(path), rulelock.FileName))
+			if err != nil {
+				return nil, false, err
+			}
+			cfg.Rules = mergeRules(cfg.Rules, rules)
+		}
+		if cfg.Settings != nil && trustedLayer(path) {
+			broader = append(broader, cfg.Settings)
+		}
+		verbosef("Loaded config from %s with %d rules and %d exceptions", path, len(cfg.Rules), len(cfg.Exceptions))
+		dir := filepath.Join(filepath.Dir(path), config.ExceptionsDir)
+		exceptions, err := config.LoadExceptionsDir(dir)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(exceptions) > 0 {
+			verbosef("Loaded %d exceptions from %s", len(exceptions), dir)
+			cfg.Exceptions = append(cfg.Exceptions, exceptions...)
+		}
+		layers = append(layers, config.Layer{Path: path, Config: cfg})
+	}
+
+	replacesPresupplied := false
+	if n := len(layers); n > 0 && filepath.Dir(layers[n-1].Path) != orgConfigDir {
+		replacesPresupplied = len(layers[n-1].Config.Rules) > 0
+	}
+
+	cfg, warnings := config.MergeLayers(layers)
+	if len(warnings) > 0 {
+		status.clear()
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return cfg, replacesPresupplied, nil
+}
+
+// trustedLayer reports whether the config at path is the organization's or
+// the user's, rather than one that came with a repository
+func trustedLayer(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir := filepath.Dir(abs)
+	if dir == orgConfigDir {
+		return true
+	}
+	userDir, err := expandHomePath(userConfigDir)
+	if err != nil {
+		return false
+	}
+	userDir, err = filepath.Abs(userDir)
+	return err == nil && dir == userDir
+}
+
+// checkLayerSettings rejects credentials blocks and rule_sources outside
+// the organization and user configs. A repository's config can come from
+// anyone who opens a pull request: its credentials block could send a CI
+// secret to any host or run any command as a helper, and its rule sources
+// would be fetched by running git with what it wrote.
+func checkLayerSettings(path string, settings *config.Settings) error {
+	if settings == nil || trustedLayer(path) {
+		return nil
+	}
+	if len(settings.Credentials) > 0 {
+		return fmt.Errorf("%s: credentials blocks are only allowed in the configs in %s and %s, not in a repository's config", path, orgConfigDir, userConfigDir)
+	}
+	if len(settings.RuleSources) > 0 {
+		return fmt.Errorf("%s: rule_sources are only allowed in the configs in %s and %s, not in a repository's config", path, orgConfigDir, userConfigDir)
+	}
+	return nil
+}
+
+// broaderSettings returns the settings of the organization and user configs
+// under the config at path, broadest first
+func broaderSettings(path string) ([]*config.Settings, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var settings []*config.Settings
+	for _, dir := range []string{orgConfigDir, userConfigDir} {
+		layer := findConfigIn(dir)
+		if layer == "" {
+			continue
+		}
+		if layerAbs, err := filepath.Abs(layer); err == nil && layerAbs == abs {
+			break
+		}
+		cfg, err := config.DecodeConfig(layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", layer, err)
+		}
+		if cfg.Settings != nil {
+			settings = append(settings, cfg.Settings)
+		}
+	}
+	return settings, nil
+}
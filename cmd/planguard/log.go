@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// verbosity is how much is printed to stderr besides reports, warnings,
+// and errors: -1 with -quiet, 0 by default, and 1 or 2 with -v or -vv.
+// It is set by scanOptions.resolve.
+var verbosity int
+
+// logf prints a progress message to stderr, unless -quiet
+func logf(format string, args ...interface{}) {
+	if verbosity >= 0 {
+		status.clear()
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// verbosef prints a message to stderr with -v or -vv
+func verbosef(format string, args ...interface{}) {
+	if verbosity >= 1 {
+		status.clear()
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// tracef prints a message to stderr with -vv
+func tracef(format string, args ...interface{}) {
+	if verbosity >= 2 {
+		status.clear()
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// logRules reports the rules a scan runs with: their count with -v, and
+// each rule with where it was loaded from with -vv
+func logRules(rules []config.Rule) {
+	verbosef("Loaded %d rules", len(rules))
+	for _, rule := range rules {
+		source := rule.Source
+		if source == "" {
+			source = "config"
+		}
+		tracef("  %s (%s, %s) from %s", rule.ID, rule.Severity, rule.ResourceType, source)
+	}
+}
+
+// logParsedFiles reports the resources parsed from each file with -v, and
+// each resource with -vv
+func logParsedFiles(resources []*config.Resource) {
+	if verbosity < 1 {
+		return
+	}
+	byFile := make(map[string][]*config.Resource)
+	for _, resource := range resources {
+		byFile[resource.File] = append(byFile[resource.File], resource)
+	}
+	files := make([]string, 0, len(byFile))
+	for file := range byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		verbosef("Parsed %s: %d resources", file, config.CountResources(byFile[file]))
+		for _, resource := range byFile[file] {
+			tracef("  %s.%s (line %d)", resource.Type, resource.Name, resource.Line)
+		}
+	}
+}
+
+// logExceptions reports how many violations exceptions covered, each of
+// them with the exception's reason with -v, and the exceptions that matched
+// nothing with -vv
+func logExceptions(exceptions []config.Exception, filtered []config.FilteredViolation) {
+	if len(filtered) > 0 {
+		logf("Exceptions applied to %d violations", len(filtered))
+	}
+	matched := make(map[string]bool)
+	for _, fv := range filtered {
+		v := fv.Violation
+		verbosef("Exception matched %s on %s.%s at %s:%d: %s (approved by %s)",
+			v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line, fv.Exception.Reason, fv.Exception.ApprovedBy)
+		matched[exceptionKey(fv.Exception)] = true
+	}
+	for _, exception := range exceptions {
+		if !matched[exceptionKey(exception)] {
+			tracef("Exception for %s matched no violations: %s", strings.Join(exception.Rules, ", "), exception.Reason)
+		}
+	}
+}
+
+// exceptionKey identifies an exception for logExceptions. Exceptions have
+// no ID; two with the same rules, scope, and reason are reported as one.
+func exceptionKey(exception config.Exception) string {
+	return strings.Join([]string{
+		strings.Join(exception.Rules, ","),
+		strings.Join(exception.Paths, ","),
+		strings.Join(exception.ResourceNames, ","),
+		strings.Join(exception.Addresses, ","),
+		exception.Reason,
+	}, "\x00")
+}
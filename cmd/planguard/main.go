@@ -1,110 +1,1022 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/jonathanhle/planguard/internal/gitdiff"
+	"github.com/jonathanhle/planguard/internal/migrate"
+	"github.com/jonathanhle/planguard/pkg/baseline"
+	"github.com/jonathanhle/planguard/pkg/cache"
 	"github.com/jonathanhle/planguard/pkg/config"
 	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/plan"
 	"github.com/jonathanhle/planguard/pkg/reporter"
 	"github.com/jonathanhle/planguard/pkg/scanner"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
 )
 
 // Version is set at build time
 var version = "dev"
 
+const usage = `Usage: planguard <command> [flags]
+
+Commands:
+  init       Create a starter .planguard/config.hcl, optionally copying the presupplied rules
+  scan       Scan Terraform for violations of the loaded rules
+  rules      Check, edit, and export rule files
+  test       Run the tests in *_test.hcl files against the rules they test
+  baseline   Record the current violations so later scans only fail on new ones
+  exceptions List, prune, and add exceptions in the config file
+  config     Upgrade config and rule files written for older versions
+  cache      Show the size and hit rate of the result cache, or clean it
+  diff       Compare two JSON reports and list new and fixed violations
+  docs       Render the loaded rules as a static HTML site
+  migrate    Convert tfsec or checkov suppressions into exceptions
+  anonymize  Write a sanitized copy of a Terraform directory for bug reports
+  repro      Extract a standalone reproduction of a violation
+  explain    Show how a rule evaluated the resource of a reported violation
+  serve      Answer policy decisions for single resources over HTTP
+  version    Print the planguard version
+
+Run planguard <command> -h for the flags of a command.
+`
+
 func main() {
-	// Command-line flags
-	configPath := flag.String("config", "", "Path to config file (default: ./.planguard/config.hcl or ~/.planguard/config.hcl)")
-	directory := flag.String("directory", ".", "Directory to scan")
-	format := flag.String("format", "text", "Output format (text, json, sarif)")
-	failOn := flag.String("fail-on", "error", "Fail on severity level (error, warning, info)")
-	rulesDir := flag.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
-	usePresuppliedRules := flag.String("use-presupplied-rules", "", "Enable presupplied rules (true/false, default: true)")
-	presuppliedRulesCategories := flag.String("presupplied-rules-categories", "", "Comma-separated list of presupplied rule categories (aws,azure,common,security,tagging)")
-	showVersion := flag.Bool("version", false, "Show version")
-
-	flag.Parse()
-
-	if *showVersion {
+	if len(os.Args) < 2 || (strings.HasPrefix(os.Args[1], "-") && !isHelpFlag(os.Args[1])) {
+		// The scan flags without a command, as before subcommands
+		fmt.Fprintf(os.Stderr, "Warning: running planguard without a command is deprecated and will stop working in the next release; use planguard scan\n")
+		os.Exit(runScan(os.Args[1:]))
+	}
+
+	switch os.Args[1] {
+	case "scan":
+		os.Exit(runScan(os.Args[2:]))
+	case "init":
+		os.Exit(runInit(os.Args[2:]))
+	case "version":
 		fmt.Printf("Planguard v%s\n", version)
-		os.Exit(0)
+	case "anonymize":
+		os.Exit(runAnonymize(os.Args[2:]))
+	case "serve":
+		os.Exit(runServe(os.Args[2:]))
+	case "baseline":
+		os.Exit(runBaseline(os.Args[2:]))
+	case "exceptions":
+		os.Exit(runExceptions(os.Args[2:]))
+	case "config":
+		os.Exit(runConfig(os.Args[2:]))
+	case "cache":
+		os.Exit(runCache(os.Args[2:]))
+	case "migrate":
+		os.Exit(runMigrate(os.Args[2:]))
+	case "docs":
+		os.Exit(runDocs(os.Args[2:]))
+	case "repro":
+		os.Exit(runRepro(os.Args[2:]))
+	case "explain":
+		os.Exit(runExplain(os.Args[2:]))
+	case "test":
+		os.Exit(runTest(os.Args[2:]))
+	case "rules":
+		os.Exit(runRules(os.Args[2:]))
+	case "diff":
+		os.Exit(runDiff(os.Args[2:]))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(1)
 	}
+}
 
-	// Run scan
-	exitCode := run(*configPath, *directory, *format, *failOn, *rulesDir, *usePresuppliedRules, *presuppliedRulesCategories)
-	os.Exit(exitCode)
+// isHelpFlag reports whether arg asks for help, which shows the commands
+// rather than the scan flags
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// scanOptions holds the command-line options for a scan
+type scanOptions struct {
+	configPath                 string
+	profile                    string
+	directory                  string
+	plan                       planFlags
+	plans                      []string // Plan files matched by -plan
+	formats                    formatFlags
+	output                     string
+	template                   string
+	tmpl                       *template.Template // Parsed from -template
+	commentMaxBytes            int
+	reportURL                  string
+	groupBy                    string
+	noColor                    bool
+	severityThreshold          string
+	failOn                     string
+	exitCodeMap                string
+	exitCodes                  map[string]int // Parsed from -exit-code-map
+	failOnScore                float64
+	rulesDir                   string
+	usePresuppliedRules        string
+	presuppliedRulesCategories string
+	sample                     string
+	fast                       bool
+	samplePercent              float64 // 0 means scan everything
+	concurrency                int
+	targetsFile                string
+	labels                     labelFlags
+	severityMap                string
+	cache                      bool
+	cacheDir                   string
+	baseline                   string
+	changedOnly                bool
+	since                      string
+	honorInlineSkips           string
+	strict                     bool
+	suppress                   suppressFlags
+	failOnExpiredExceptions    bool
+	prefer                     string
+	explainMatching            bool
+	timeout                    time.Duration
+	partialResultsOnTimeout    bool
+	stats                      bool
+	blame                      bool
+	showSource                 int
+	showFiltered               bool
+	tui                        bool
+	compliance                 bool
+	top                        int
+	packs                      packFlags
+	rules                      ruleFlags
+	excludeRules               ruleFlags
+	absolutePaths              bool
+	batchSize                  int
+	maxMemoryMB                int
+	cpuProfile                 string
+	memProfile                 string
+	traceFile                  string
+	quiet                      bool
+	verbose                    bool
+	veryVerbose                bool
+}
+
+// registerScanFlags registers the flags that control what is scanned and
+// how, shared by the scan and baseline commands
+func registerScanFlags(fs *flag.FlagSet, opts *scanOptions) {
+	fs.StringVar(&opts.configPath, "config", "", "Path to config file, merged over /etc/planguard (default: the nearest .planguard/config.hcl in this or a parent directory, merged over ~/.planguard/config.hcl)")
+	fs.StringVar(&opts.profile, "profile", "", "Profile from the config to scan with, e.g. prod")
+	fs.StringVar(&opts.directory, "directory", ".", "Directory to scan")
+	fs.StringVar(&opts.rulesDir, "rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
+	fs.StringVar(&opts.usePresuppliedRules, "use-presupplied-rules", "", "Enable presupplied rules (true/false, default: true)")
+	fs.StringVar(&opts.presuppliedRulesCategories, "presupplied-rules-categories", "", "Comma-separated list of presupplied rule categories, e.g. aws,security (see planguard rules categories)")
+	fs.StringVar(&opts.prefer, "prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	fs.Var(&opts.packs, "pack", "Rule pack to scan with, by name (from .planguard/packs or the rules directory's packs) or directory, in place of presupplied rules (repeatable)")
+	fs.Var(&opts.rules, "rule", "Only run rules with this ID or glob pattern, e.g. aws_s3_* (repeatable or comma-separated)")
+	fs.Var(&opts.excludeRules, "exclude-rule", "Skip rules with this ID or glob pattern (repeatable or comma-separated)")
+	fs.StringVar(&opts.sample, "sample", "", "Only evaluate a deterministic sample of resources, e.g. 10% (for quick local feedback)")
+	fs.BoolVar(&opts.fast, "fast", false, "Shorthand for -sample 10%")
+	fs.IntVar(&opts.concurrency, "concurrency", runtime.NumCPU(), "Number of rules to evaluate in parallel")
+	fs.StringVar(&opts.targetsFile, "targets-file", "", "HCL file listing directories to scan with their labels (overrides -directory)")
+	fs.StringVar(&opts.severityMap, "severity-map", "", "YAML file remapping rule severities by rule ID or tag")
+	fs.BoolVar(&opts.cache, "cache", false, "Reuse results for unchanged files from previous runs")
+	fs.StringVar(&opts.cacheDir, "cache-dir", "", "Directory for cached results (default: user cache directory; implies -cache)")
+	fs.BoolVar(&opts.changedOnly, "changed-only", false, "Only evaluate resources in Terraform files changed in the git working tree")
+	fs.StringVar(&opts.since, "since", "", "Only evaluate resources in Terraform files changed since this git ref, e.g. origin/main (implies -changed-only)")
+	fs.Var(&opts.plan, "plan", "Terraform plan in JSON form (terraform show -json) whose planned values are evaluated in place of values from source; repeat it or use a glob to scan one plan per stack")
+	fs.BoolVar(&opts.absolutePaths, "absolute-paths", false, "Report absolute file paths instead of paths relative to the git repository root")
+	fs.IntVar(&opts.batchSize, "batch-size", 0, "Parse and scan this many Terraform files at a time, keeping memory use bounded on large repositories (default: 0, all files at once)")
+	fs.IntVar(&opts.maxMemoryMB, "max-memory-mb", 0, "Keep memory use near this many MiB, scanning in batches when the files would not fit at once (default: 0, no limit)")
+	fs.StringVar(&opts.cpuProfile, "cpuprofile", "", "Write a CPU profile of the scan to this file, for go tool pprof")
+	fs.StringVar(&opts.memProfile, "memprofile", "", "Write a heap profile taken at the end of the scan to this file, for go tool pprof")
+	fs.StringVar(&opts.traceFile, "trace", "", "Write an execution trace of the scan to this file, for go tool trace")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "Stop the scan after this long, e.g. 5m (default: no limit)")
+	fs.BoolVar(&opts.quiet, "q", false, "Print only the report, warnings, and errors, without progress messages on stderr")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Same as -q")
+	fs.BoolVar(&opts.verbose, "v", false, "Also print the config and rules loaded, the resources parsed from each file, and the violations each exception matched")
+	fs.BoolVar(&opts.veryVerbose, "vv", false, "Like -v, also listing every rule and resource, and exceptions that matched nothing")
+	fs.BoolVar(&opts.strict, "strict", false, "Fail on problems in config and rule files, such as an invalid severity or an unknown attribute, instead of warning (like strict = true in settings)")
+	fs.StringVar(&opts.honorInlineSkips, "honor-inline-skips", "", "Comma-separated scanners whose inline skip comments are honored (tfsec,checkov)")
+	opts.labels = labelFlags{}
+	fs.Var(opts.labels, "label", "Label for the scanned directory as key=value, used to group results (repeatable)")
+}
+
+// resolve validates flag values and derives options from them
+func (opts *scanOptions) resolve() error {
+	if opts.fast && opts.sample == "" {
+		opts.sample = "10%"
+	}
+	if opts.sample != "" {
+		percent, err := parsePercent(opts.sample)
+		if err != nil {
+			return fmt.Errorf("invalid -sample value: %w", err)
+		}
+		opts.samplePercent = percent
+	}
+	if opts.since != "" {
+		opts.changedOnly = true
+	}
+	if len(opts.plan) > 0 && opts.targetsFile != "" {
+		return fmt.Errorf("-plan cannot be combined with -targets-file; set plan on each target instead")
+	}
+	for _, pattern := range opts.plan {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid -plan pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("-plan %s matches no files", pattern)
+		}
+		opts.plans = append(opts.plans, matches...)
+	}
+	if opts.quiet && (opts.verbose || opts.veryVerbose) {
+		return fmt.Errorf("-quiet cannot be combined with -v or -vv")
+	}
+	switch {
+	case opts.quiet:
+		verbosity = -1
+	case opts.veryVerbose:
+		verbosity = 2
+	case opts.verbose:
+		verbosity = 1
+	}
+	if opts.concurrency < 1 {
+		return fmt.Errorf("invalid -concurrency %d (expected at least 1)", opts.concurrency)
+	}
+	if opts.maxMemoryMB < 0 {
+		return fmt.Errorf("invalid -max-memory-mb %d (expected a number of MiB, or 0 for no limit)", opts.maxMemoryMB)
+	}
+	if opts.batchSize < 0 {
+		return fmt.Errorf("invalid -batch-size %d (expected a number of files, or 0 to scan all at once)", opts.batchSize)
+	}
+	if opts.batchSize > 0 && len(opts.plans) > 0 {
+		return fmt.Errorf("-batch-size cannot be combined with -plan, whose planned values are merged with every resource")
+	}
+	if opts.batchSize > 0 && opts.explainMatching {
+		return fmt.Errorf("-batch-size cannot be combined with -explain-matching")
+	}
+	if opts.exitCodeMap != "" {
+		codes, err := parseExitCodeMap(opts.exitCodeMap)
+		if err != nil {
+			return fmt.Errorf("invalid -exit-code-map value: %w", err)
+		}
+		opts.exitCodes = codes
+	}
+	if opts.commentMaxBytes != 0 && opts.commentMaxBytes < 1024 {
+		return fmt.Errorf("invalid -comment-max-bytes %d (expected at least 1024)", opts.commentMaxBytes)
+	}
+	switch opts.severityThreshold {
+	case "", "error", "warning", "info":
+	default:
+		return fmt.Errorf("invalid -severity-threshold value %q (expected error, warning, or info)", opts.severityThreshold)
+	}
+	if opts.groupBy != "" && !reporter.ValidGroupBy(opts.groupBy) {
+		return fmt.Errorf("invalid -group-by value %q (expected severity, rule, file, or resource_type)", opts.groupBy)
+	}
+	if opts.tui {
+		if len(opts.formats) > 0 || opts.output != "" {
+			return fmt.Errorf("-tui cannot be combined with -format or -output")
+		}
+		if opts.explainMatching {
+			return fmt.Errorf("-tui cannot be combined with -explain-matching")
+		}
+		if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+			return fmt.Errorf("-tui needs a terminal on stdin and stdout")
+		}
+	}
+	formats, err := resolveFormats(opts.formats, opts.output)
+	if err != nil {
+		return err
+	}
+	opts.formats = formats
+	for _, format := range opts.formats {
+		if format.name != "template" {
+			continue
+		}
+		if opts.template == "" {
+			return fmt.Errorf("-format template requires -template")
+		}
+		tmpl, err := reporter.ParseTemplate(opts.template)
+		if err != nil {
+			return err
+		}
+		opts.tmpl = tmpl
+		break
+	}
+	if opts.template != "" && opts.tmpl == nil {
+		return fmt.Errorf("-template is only used with -format template")
+	}
+	if err := validatePrefer(opts.prefer); err != nil {
+		return err
+	}
+	for _, pattern := range append(append([]string(nil), opts.rules...), opts.excludeRules...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid rule pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validatePrefer checks a -prefer value
+func validatePrefer(prefer string) error {
+	if prefer != "disk" && prefer != "embedded" {
+		return fmt.Errorf("invalid -prefer value %q (expected disk or embedded)", prefer)
+	}
+	return nil
+}
+
+// labelFlags collects repeated -label key=value flags
+type labelFlags map[string]string
+
+func (l labelFlags) String() string {
+	var pairs []string
+	for key, value := range l {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	l[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	return nil
+}
+
+// packFlags collects repeated -pack flags
+type packFlags []string
+
+func (p *packFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *packFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// ruleFlags collects rule IDs and patterns from repeated, comma-separated
+// -rule and -exclude-rule flags
+type ruleFlags []string
+
+func (r *ruleFlags) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *ruleFlags) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			*r = append(*r, pattern)
+		}
+	}
+	return nil
+}
+
+// planFlags collects repeated -plan flags
+type planFlags []string
+
+func (p *planFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *planFlags) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// suppressFlags collects repeated -suppress rule_id:type.name flags
+type suppressFlags []scanner.Suppression
+
+func (s *suppressFlags) String() string {
+	var values []string
+	for _, suppression := range *s {
+		values = append(values, suppression.RuleID+":"+suppression.Address)
+	}
+	return strings.Join(values, ",")
+}
+
+func (s *suppressFlags) Set(value string) error {
+	suppression, err := scanner.ParseSuppression(value)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, suppression)
+	return nil
+}
+
+// parseExitCodeMap parses values such as "error=1,warning=2" into exit
+// codes by severity
+func parseExitCodeMap(value string) (map[string]int, error) {
+	codes := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		severity, code, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not severity=code", pair)
+		}
+		if severity != "error" && severity != "warning" && severity != "info" {
+			return nil, fmt.Errorf("unknown severity %q (expected error, warning, or info)", severity)
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 0 || n > 125 {
+			return nil, fmt.Errorf("exit code %q for %s must be between 0 and 125", code, severity)
+		}
+		codes[severity] = n
+	}
+	return codes, nil
+}
+
+// parsePercent parses values such as "10%" or "10" into a percentage
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a percentage", value)
+	}
+	if percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("%q must be between 0%% and 100%%", value)
+	}
+	return percent, nil
+}
+
+// scanContext returns the context a scan runs under: it is cancelled on
+// SIGINT or SIGTERM and once -timeout elapses
+func scanContext(opts scanOptions) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if opts.timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// useColor reports whether text output to stdout should be colored: only
+// on a terminal, and never with -no-color, NO_COLOR set (see
+// https://no-color.org), or TERM=dumb
+func useColor(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// isCancellation reports whether err means the scan was interrupted or
+// timed out
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
-func run(configPath, directory, format, failOn, rulesDir string, usePresuppliedRules string, presuppliedRulesCategories string) int {
+// scanAll loads the configuration and scans every target. It also returns
+// the configuration the scan used. When ctx is done it stops and returns
+// ctx's error with the results found so far.
+func scanAll(ctx context.Context, opts scanOptions) (*scanner.ScanResult, *config.Config, error) {
+	stopProfiling, err := startProfiling(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error: %w", err)
+	}
+	defer stopProfiling()
+
+	limitResources(opts)
+
+	status = startProgress()
+	defer func() {
+		status.stop()
+		status = nil
+	}()
+
 	// Load configuration
-	cfg, err := loadConfiguration(configPath, rulesDir, usePresuppliedRules, presuppliedRulesCategories)
+	cfg, err := loadConfiguration(opts.configPath, opts.profile, opts.rulesDir, opts.usePresuppliedRules, opts.presuppliedRulesCategories, opts.prefer, opts.packs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error loading configuration: %w", err)
+	}
+	if err := checkRuleDefinitions(cfg, opts); err != nil {
+		return nil, nil, fmt.Errorf("Error validating rules: %w", err)
+	}
+	if err := checkTickets(cfg); err != nil {
+		return nil, nil, fmt.Errorf("Error validating exception tickets: %w", err)
+	}
+
+	severityMap, err := loadSeverityMap(cfg, opts.severityMap)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return nil, nil, fmt.Errorf("Error loading severity map: %w", err)
+	}
+	if severityMap != nil {
+		cfg.Rules = severityMap.Apply(cfg.Rules)
+	}
+	if cfg.ActiveProfile != nil {
+		cfg.Rules = cfg.ActiveProfile.ApplySeverities(cfg.Rules)
+	}
+
+	if len(opts.rules) > 0 || len(opts.excludeRules) > 0 {
+		loaded := len(cfg.Rules)
+		var unmatched []string
+		cfg.Rules, unmatched = config.SelectRules(cfg.Rules, opts.rules, opts.excludeRules)
+		for _, pattern := range unmatched {
+			fmt.Fprintf(os.Stderr, "Warning: rule pattern %q in -rule/-exclude-rule does not match any loaded rule\n", pattern)
+		}
+		logf("Running %d of %d loaded rules", len(cfg.Rules), loaded)
+	}
+
+	// A single plan belongs to -directory; several plans are scanned as one
+	// target per plan, each in its plan's directory
+	targets := []config.Target{{Name: opts.directory, Directory: opts.directory}}
+	if len(opts.plans) == 1 {
+		targets[0].Plan = opts.plans[0]
+	} else if len(opts.plans) > 1 {
+		targets = config.PlanTargets(opts.plans)
+	}
+	if opts.targetsFile != "" {
+		targets, err = config.LoadTargets(opts.targetsFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error loading targets: %w", err)
+		}
+	}
+
+	var resultCache *cache.Cache
+	var ruleSetHash string
+	if opts.cache || opts.cacheDir != "" {
+		resultCache, ruleSetHash, err = openCache(opts.cacheDir, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error opening cache: %w", err)
+		}
+		defer func() {
+			if err := resultCache.SaveStats(); err != nil {
+				status.clear()
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}()
+	}
+
+	// Scan each target, labeling its results. -label flags apply to every
+	// target and override labels from the targets file.
+	result := &scanner.ScanResult{}
+	for _, target := range targets {
+		labels := make(map[string]string)
+		for key, value := range target.Labels {
+			labels[key] = value
+		}
+		for key, value := range opts.labels {
+			labels[key] = value
+		}
+
+		if len(targets) > 1 {
+			logf("Scanning target %s (%s)", target.Name, target.Directory)
+		}
+
+		targetResult, err := scanTarget(ctx, cfg, opts, target, resultCache, ruleSetHash)
+		if err != nil && !(isCancellation(err) && targetResult != nil) {
+			if isCancellation(err) {
+				return result, cfg, err
+			}
+			return nil, nil, err
+		}
+
+		if len(labels) > 0 {
+			for i := range targetResult.Violations {
+				targetResult.Violations[i].Labels = labels
+			}
+			for i := range targetResult.FilteredViolations {
+				targetResult.FilteredViolations[i].Violation.Labels = labels
+			}
+		}
+
+		result.Violations = append(result.Violations, targetResult.Violations...)
+		result.FilteredViolations = append(result.FilteredViolations, targetResult.FilteredViolations...)
+		result.Coverage = scanner.MergeCoverage(result.Coverage, targetResult.Coverage)
+		result.Resources += targetResult.Resources
+		result.CachedFiles += targetResult.CachedFiles
+		result.Score += targetResult.Score
+		if err != nil {
+			return result, cfg, err
+		}
+	}
+
+	return result, cfg, nil
+}
+
+func run(opts scanOptions) int {
+	ctx, cancel := scanContext(opts)
+	defer cancel()
+
+	result, cfg, err := scanAll(ctx, opts)
+	partialReason := ""
+	if err != nil {
+		if !isCancellation(err) || result == nil || cfg == nil {
+			printError("%v\n", err)
+			return 1
+		}
+
+		partialReason = "scan interrupted"
+		if errors.Is(err, context.DeadlineExceeded) {
+			partialReason = fmt.Sprintf("scan timed out after %s", opts.timeout)
+		}
+		if !opts.partialResultsOnTimeout {
+			fmt.Fprintf(os.Stderr, "Error: %s (pass -partial-results-on-timeout to report the violations found so far)\n", partialReason)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s; reporting partial results\n", partialReason)
+	}
+
+	if opts.failOn == "" {
+		opts.failOn = "error"
+		if cfg.ActiveProfile != nil && cfg.ActiveProfile.FailOn != nil {
+			opts.failOn = *cfg.ActiveProfile.FailOn
+		}
+	}
+
+	// Matching was printed per target instead of scanning
+	if opts.explainMatching {
+		return 0
+	}
+
+	if len(opts.suppress) > 0 {
+		before := len(result.Violations)
+		scanner.ApplySuppressions(result, opts.suppress)
+		result.Score = scanner.RiskScore(cfg.Settings, result.Violations)
+		logf("Suppressed %d findings for this run (-suppress)", before-len(result.Violations))
+	}
+
+	logExceptions(cfg.Exceptions, result.FilteredViolations)
+
+	if opts.blame {
+		addBlame(result)
+	}
+	// The browser leaves paths as scanned, which is how exceptions match them
+	if opts.tui {
+		addSource(result, max(opts.showSource, tuiSourceLines))
+	} else {
+		if opts.showSource > 0 {
+			addSource(result, opts.showSource)
+		}
+		localizePaths(result, opts.absolutePaths)
+	}
+
+	// Separate violations already recorded in the baseline
+	violations := result.Violations
+	var known []config.Violation
+	if opts.baseline != "" {
+		b, err := baseline.Load(opts.baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %v\n", err)
+			return 1
+		}
+		if n := b.ResolveAliases(cfg.Aliases); n > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: baseline %s records %d violations under former rule IDs; regenerate it with planguard baseline\n", opts.baseline, n)
+		}
+		if opts.tui {
+			// The browser keeps paths as scanned, but the baseline
+			// records them as reports show them
+			localize := pathLocalizer(opts.absolutePaths)
+			violations, known = b.SplitFunc(violations, func(v config.Violation) config.Violation {
+				localize(&v)
+				return v
+			})
+		} else {
+			violations, known = b.Split(violations)
+		}
+	}
+
+	// Report results
+	rep := reporter.NewReporter(violations, result.FilteredViolations)
+	rep.SetRules(cfg.Rules)
+	if opts.baseline != "" {
+		rep.SetKnownViolations(known)
+	}
+	if opts.severityThreshold != "" {
+		if hidden := rep.SetSeverityThreshold(opts.severityThreshold); hidden > 0 {
+			logf("Hiding %d violations below %s (-severity-threshold)", hidden, opts.severityThreshold)
+		}
+	}
+	if partialReason != "" {
+		rep.SetPartial(partialReason)
+	}
+	// The score and top issues include violations known from the
+	// baseline: they are still risk, just not new
+	scoring := len(cfg.Settings.RiskSeverityWeights) > 0 || len(cfg.Settings.RiskRuleWeights) > 0 || opts.failOnScore > 0
+	if scoring {
+		rep.SetScore(result.Score)
+	}
+	if opts.top > 0 {
+		weight := func(v config.Violation) float64 { return scanner.RiskWeight(cfg.Settings, v) }
+		top := result.Violations
+		if opts.severityThreshold != "" {
+			top = nil
+			for _, v := range result.Violations {
+				if reporter.SeverityAtLeast(v.Severity, opts.severityThreshold) {
+					top = append(top, v)
+				}
+			}
+		}
+		rep.SetTopIssues(reporter.TopIssues(top, weight, opts.top))
+	}
+	if opts.compliance {
+		rep.SetCompliance(reporter.ComplianceSummary(cfg.Rules, result.Violations, result.FilteredViolations, result.EvaluatedRules()))
+	}
+	if opts.stats {
+		stats := make([]reporter.RuleStats, 0, len(result.Coverage))
+		for _, c := range result.Coverage {
+			stats = append(stats, reporter.RuleStats{
+				RuleID:     c.RuleID,
+				Seconds:    c.Duration.Seconds(),
+				Evaluated:  c.Evaluated,
+				Violations: c.Violations,
+			})
+		}
+		rep.SetRuleStats(stats)
+	}
+
+	warningDays := config.DefaultExpiryWarningDays
+	if cfg.Settings.ExceptionExpiryWarningDays != nil {
+		warningDays = *cfg.Settings.ExceptionExpiryWarningDays
+	}
+	expiring := config.ExpiringExceptions(cfg.Exceptions, time.Now(), warningDays)
+	rep.SetExpiringExceptions(expiring)
+	rep.SetGroupBy(opts.groupBy)
+	rep.SetShowFiltered(opts.showFiltered)
+	rep.SetPRComment(opts.commentMaxBytes, opts.reportURL)
+
+	if opts.tui {
+		// The browser takes the place of the reports; the exit code is
+		// decided below as for them
+		if err := browse(violations, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else if err := writeReports(rep, cfg, result, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+
+	// Determine exit code. A partial scan never passes.
+	if opts.exitCodes != nil {
+		if code := rep.ExitCode(opts.exitCodes); code != 0 {
+			return code
+		}
+	} else if rep.ShouldFail(opts.failOn) {
+		return 1
+	}
+	if partialReason != "" {
 		return 1
 	}
 
-	// Parse Terraform files
+	if opts.failOnScore > 0 && result.Score >= opts.failOnScore {
+		fmt.Fprintf(os.Stderr, "Failing: risk score %g reaches -fail-on-score %g\n", result.Score, opts.failOnScore)
+		return 1
+	}
+
+	if opts.failOnExpiredExceptions || cfg.Settings.FailOnExpiredExceptions {
+		expired := 0
+		for _, ee := range expiring {
+			if ee.Expired {
+				expired++
+			}
+		}
+		if expired > 0 {
+			fmt.Fprintf(os.Stderr, "Failing: %d exceptions have expired; renew or remove them\n", expired)
+			return 1
+		}
+	}
+
+	if cfg.Settings.HasExceptionBudgets() {
+		exceeded, err := checkExceptionBudgets(cfg, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking exception budgets: %v\n", err)
+			return 1
+		}
+		if len(exceeded) > 0 {
+			fmt.Fprintf(os.Stderr, "Failing: exception budget exceeded; fix the findings or remove exceptions\n")
+			for _, budget := range exceeded {
+				fmt.Fprintf(os.Stderr, "  - %s\n", budget)
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// writeReports writes the report in each -format. Sections that some
+// report has no place for go to stderr, once.
+func writeReports(rep *reporter.Reporter, cfg *config.Config, result *scanner.ScanResult, opts scanOptions) error {
+	var stderrTop, stderrCompliance, stderrStats bool
+	for _, format := range opts.formats {
+		top, compliance, stats := formatSections(format.name)
+		stderrTop = stderrTop || !top
+		stderrCompliance = stderrCompliance || !compliance
+		stderrStats = stderrStats || !stats
+	}
+	if stderrTop {
+		fmt.Fprint(os.Stderr, rep.FormatTopIssues())
+	}
+	if stderrCompliance {
+		fmt.Fprint(os.Stderr, rep.FormatCompliance())
+	}
+	if stderrStats {
+		fmt.Fprint(os.Stderr, rep.FormatRuleStats())
+	}
+
+	for _, format := range opts.formats {
+		rep.SetColor(format.path == "-" && useColor(opts.noColor))
+		output, err := formatReport(rep, format.name, cfg, result, opts.tmpl)
+		if err != nil {
+			return fmt.Errorf("formatting %s output: %w", format.name, err)
+		}
+		if format.path == "-" {
+			fmt.Println(output)
+		} else if err := writeOutput(format.path, output+"\n"); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkExceptionBudgets returns the exception budgets in the config that
+// its active exceptions exceed. Rule categories are only resolved when a
+// budget is set per category.
+func checkExceptionBudgets(cfg *config.Config, opts scanOptions) ([]config.BudgetExceeded, error) {
+	categories := make(map[string][]string)
+	if len(cfg.Settings.MaxActiveExceptionsPerCategory) > 0 {
+		grouped, err := ruleCategories(cfg, opts.rulesDir, opts.prefer)
+		if err != nil {
+			return nil, err
+		}
+		for _, category := range grouped {
+			for _, rule := range category.Rules {
+				categories[category.Name] = append(categories[category.Name], rule.ID)
+			}
+		}
+	}
+	return config.CheckExceptionBudgets(cfg.Settings, cfg.Exceptions, categories, time.Now()), nil
+}
+
+// scanTarget parses and scans a single directory. Each target gets its own
+// scan context, so cross-resource functions only see resources of the same
+// target.
+func scanTarget(ctx context.Context, cfg *config.Config, opts scanOptions, target config.Target, resultCache *cache.Cache, ruleSetHash string) (*scanner.ScanResult, error) {
+	directory := target.Directory
+	var changed []string
+	if opts.changedOnly {
+		var err error
+		changed, err = gitdiff.ChangedFiles(directory, opts.since)
+		if err != nil {
+			return nil, fmt.Errorf("Error finding changed files: %w", err)
+		}
+		if len(changed) == 0 {
+			logf("No changed Terraform files in %s, skipping", directory)
+			return &scanner.ScanResult{}, nil
+		}
+	}
+
+	if opts.batchSize == 0 && opts.maxMemoryMB > 0 && target.Plan == "" && !opts.explainMatching {
+		size, err := memoryBatchSize(ctx, cfg, directory, opts.maxMemoryMB)
+		if err != nil {
+			return nil, err
+		}
+		opts.batchSize = size
+	}
+	if opts.batchSize > 0 && target.Plan == "" {
+		return scanTargetInBatches(ctx, cfg, opts, directory, changed, resultCache, ruleSetHash)
+	}
+
+	// Parse Terraform files. Unchanged files are still parsed so
+	// cross-resource rules see the whole configuration.
 	p := parser.NewParser()
-	files, err := p.ParseDirectory(directory, cfg.Settings.ExcludePaths)
+	p.SetProgress(status.parsedFiles)
+	files, err := p.ParseDirectoryContext(ctx, directory, cfg.Settings.ExcludePaths)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing Terraform files: %v\n", err)
-		return 1
+		return nil, fmt.Errorf("Error parsing Terraform files: %w", err)
 	}
 
 	if len(files) == 0 {
-		fmt.Fprintf(os.Stderr, "No Terraform files found in %s\n", directory)
-		return 1
+		return nil, fmt.Errorf("No Terraform files found in %s", directory)
 	}
 
 	// Extract resources
 	resources, err := parser.ExtractResources(files)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting resources: %v\n", err)
-		return 1
+		return nil, fmt.Errorf("Error extracting resources: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Found %d resources in %d files\n", len(resources), len(files))
+	logf("Found %d resources in %d files", config.CountResources(resources), len(files))
+	logParsedFiles(resources)
+
+	// Take values from the plan where it has them, keeping source locations
+	if target.Plan != "" {
+		planned, err := plan.Load(target.Plan)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading plan: %w", err)
+		}
+		resources = planned.Merge(resources, target.Plan)
+		logf("Using planned values for %d resource instances from %s", len(planned.Resources), target.Plan)
+	}
+
+	for _, warning := range packWarnings(cfg.Rules, files) {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
 
 	// Create scan context
-	ctx := parser.NewScanContext(resources)
+	scanCtx := parser.NewScanContext(resources)
 
-	// Run scan
-	s := scanner.NewScanner(cfg, cfg.Rules, ctx)
-	result, err := s.Scan()
+	scanCfg, err := withInlineSkips(cfg, opts.honorInlineSkips, directory)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error during scan: %v\n", err)
-		return 1
+		return nil, err
 	}
 
-	// Report results
-	rep := reporter.NewReporter(result.Violations, result.FilteredViolations)
-
-	var output string
-	switch format {
-	case "json":
-		output, err = rep.FormatJSON()
-	case "sarif":
-		output, err = rep.FormatSARIF()
-	default:
-		output = rep.FormatText()
+	// Run scan
+	s := scanner.NewScanner(scanCfg, cfg.Rules, scanCtx)
+	s.SetConcurrency(opts.concurrency)
+	s.SetProgress(status.evaluatedRules)
+	// Cached results are keyed on source files, which do not capture
+	// planned values
+	if resultCache != nil && target.Plan == "" {
+		s.SetCache(resultCache, ruleSetHash)
+	}
+	changedFilter, sampleFilter := targetFilters(opts, changed)
+	if changedFilter != nil {
+		s.AddResourceFilter(changedFilter)
+		logf("Evaluating %d of %d resources in %d changed files", countAccepted(resources, changedFilter), config.CountResources(resources), len(changed))
+	}
+	if sampleFilter != nil {
+		s.AddResourceFilter(sampleFilter)
+		logf("Sampling %g%% of resources: evaluating %d of %d (run without -sample for a full scan)", opts.samplePercent, countAccepted(resources, sampleFilter), config.CountResources(resources))
+	}
+
+	if opts.explainMatching {
+		matches, err := s.ExplainMatching()
+		if err != nil {
+			return nil, fmt.Errorf("Error explaining rule matching: %w", err)
+		}
+		fmt.Print(formatMatches(matches))
+		return &scanner.ScanResult{}, nil
 	}
 
+	result, err := s.ScanWithContext(ctx)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
-		return 1
+		if isCancellation(err) {
+			return result, err
+		}
+		return nil, fmt.Errorf("Error during scan: %w", err)
 	}
 
-	fmt.Println(output)
+	if result.CachedFiles > 0 {
+		logf("Reused cached results for %d of %d files", result.CachedFiles, len(files))
+	}
 
-	// Determine exit code
-	if rep.ShouldFail(failOn) {
-		return 1
+	if diagnostics := scanner.Diagnose(result, resources); len(diagnostics) > 0 {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", diagnostics[0])
+		for _, diagnostic := range diagnostics[1:] {
+			fmt.Fprintf(os.Stderr, "  - %s\n", diagnostic)
+		}
+	}
+	for _, evalErr := range result.EvaluationErrors() {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", evalErr)
 	}
 
-	return 0
+	return result, nil
+}
+
+// targetFilters returns the resource filters of -changed-only and -sample,
+// each nil when its flag is not set
+func targetFilters(opts scanOptions, changed []string) (changedFilter, sampleFilter scanner.ResourceFilter) {
+	if opts.changedOnly {
+		changedFilter = scanner.FileFilter(changed)
+	}
+	if opts.samplePercent > 0 && opts.samplePercent < 100 {
+		sampleFilter = scanner.SampleFilter(opts.samplePercent)
+	}
+	return changedFilter, sampleFilter
+}
+
+// countAccepted counts the resource and data blocks a filter accepts
+func countAccepted(resources []*config.Resource, filter scanner.ResourceFilter) int {
+	accepted := 0
+	for _, resource := range resources {
+		if resource.IsResource() && filter(resource) {
+			accepted++
+		}
+	}
+	return accepted
 }
 
 func expandHomePath(path string) (string, error) {
@@ -124,23 +1036,19 @@ func expandHomePath(path string) (string, error) {
 	return homeDir + path[1:], nil
 }
 
-func findConfigFile() string {
-	// Search order: ./.planguard/config.hcl → ~/.planguard/config.hcl
-	candidates := []string{
-		"./.planguard/config.hcl",
-		"~/.planguard/config.hcl",
-	}
+// configFileNames are the config files looked for in a .planguard
+// directory, in order
+var configFileNames = []string{"config.hcl", "config.yaml", "config.yml", "config.json"}
 
-	for _, path := range candidates {
-		expanded, err := expandHomePath(path)
-		if err != nil {
-			continue
-		}
-		if _, err := os.Stat(expanded); err == nil {
-			return expanded
+func findConfigFile() string {
+	// Search order: the nearest .planguard/config.hcl in this or a parent
+	// directory → ~/.planguard/config.hcl, each also as config.yaml,
+	// config.yml, or config.json
+	for _, dir := range []string{projectConfigDir(), "~/.planguard"} {
+		if path := findConfigIn(dir); path != "" {
+			return path
 		}
 	}
-
 	return ""
 }
 
@@ -152,7 +1060,204 @@ func getDefaultRulesDir() (string, error) {
 	return homeDir + "/.planguard/rules", nil
 }
 
-func loadConfiguration(configPath, rulesDir string, usePresuppliedRulesStr string, presuppliedRulesCategoriesStr string) (*config.Config, error) {
+// openCache opens the result cache in dir (or the default cache directory)
+// and returns it with the hash identifying the configured rule set
+func openCache(dir string, cfg *config.Config) (*cache.Cache, string, error) {
+	dir, err := resolveCacheDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := cache.New(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ruleSetHash, err := cache.HashRuleSet(cfg.Rules, cfg.Functions, version)
+	if err != nil {
+		return nil, "", err
+	}
+	return c, ruleSetHash, nil
+}
+
+// resolveCacheDir returns dir with ~ expanded, or the default cache
+// directory when dir is empty
+func resolveCacheDir(dir string) (string, error) {
+	if dir == "" {
+		return cache.DefaultDir()
+	}
+	return expandHomePath(dir)
+}
+
+// loadSeverityMap loads the severity map named by the -severity-map flag,
+// falling back to the severity_map setting, which cannot make rules from
+// broader config layers less severe. It returns nil when neither is set.
+func loadSeverityMap(cfg *config.Config, flagPath string) (*config.SeverityMap, error) {
+	path := flagPath
+	if path == "" && cfg.Settings != nil && cfg.Settings.SeverityMap != nil {
+		path = *cfg.Settings.SeverityMap
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	path, err := expandHomePath(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := config.LoadSeverityMap(path)
+	if err != nil {
+		return nil, err
+	}
+	warnings := cfg.Aliases.ResolveSeverities(m.Rules, "severity map "+path)
+	if flagPath == "" {
+		warnings = append(warnings, m.Protect(cfg.SeverityFloors, "severity map "+path)...)
+	}
+	for _, warning := range warnings {
+		status.clear()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return m, nil
+}
+
+// packWarnings checks the provider versions required by the scanned files
+// against the versions targeted by the packs the rules come from
+func packWarnings(rules []config.Rule, files map[string]*hcl.File) []string {
+	var packs []*config.PackManifest
+	seen := make(map[*config.PackManifest]bool)
+	for _, rule := range rules {
+		if rule.Pack != nil && !seen[rule.Pack] {
+			seen[rule.Pack] = true
+			packs = append(packs, rule.Pack)
+		}
+	}
+	if len(packs) == 0 {
+		return nil
+	}
+
+	requirements := parser.ExtractRequiredProviders(files)
+	var warnings []string
+	for _, pack := range packs {
+		warnings = append(warnings, pack.CheckProviders(requirements)...)
+	}
+	return warnings
+}
+
+// withInlineSkips returns cfg with exceptions added for the inline skip
+// comments of other scanners under directory. The scanners come from the
+// -honor-inline-skips flag, falling back to the honor_inline_skips setting.
+func withInlineSkips(cfg *config.Config, flagTools, directory string) (*config.Config, error) {
+	tools := cfg.Settings.HonorInlineSkips
+	if flagTools != "" {
+		tools = strings.Split(flagTools, ",")
+	}
+	if len(tools) == 0 {
+		return cfg, nil
+	}
+
+	scanCfg := *cfg
+	scanCfg.Exceptions = append([]config.Exception(nil), cfg.Exceptions...)
+	for _, tool := range tools {
+		tool = strings.TrimSpace(tool)
+		exceptions, err := migrate.InlineExceptions(tool, directory, cfg.Settings.InlineSkipMappings)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading inline %s skips: %w", tool, err)
+		}
+		logf("Honoring %d inline %s skips", len(exceptions), tool)
+		scanCfg.Exceptions = append(scanCfg.Exceptions, exceptions...)
+	}
+	return &scanCfg, nil
+}
+
+// resolveRulesDir expands ~ in rulesDir, falling back to the default rules
+// directory when it is empty
+func resolveRulesDir(rulesDir string) (string, error) {
+	if rulesDir != "" {
+		return expandHomePath(rulesDir)
+	}
+	return getDefaultRulesDir()
+}
+
+// loadPresuppliedRules loads the presupplied rules in categories from the
+// rules directory, or from the rules built into planguard when prefer is
+// "embedded". Rules loaded from the directory are compared with the built-in
+// ones, so a stale copy does not silently weaken the policy.
+func loadPresuppliedRules(rulesDir, prefer string, categories []string) ([]config.Rule, error) {
+	available, err := config.RuleCategoryNames(presuppliedRulesFS(rulesDir, prefer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presupplied rule categories: %w", err)
+	}
+	known := make(map[string]bool, len(available))
+	for _, name := range available {
+		known[name] = true
+	}
+	for _, name := range categories {
+		if !known[name] {
+			fmt.Fprintf(os.Stderr, "Warning: presupplied rule category %q does not exist (available: %s)\n", name, strings.Join(available, ", "))
+		}
+	}
+
+	embedded, err := config.LoadDefaultRulesFS(embeddedrules.FS, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded presupplied rules: %w", err)
+	}
+	if prefer == "embedded" {
+		logf("Using presupplied rules built into planguard v%s", version)
+		return embedded, nil
+	}
+
+	rules, err := config.LoadDefaultRulesWithCategories(rulesDir, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load presupplied rules from %s: %w", rulesDir, err)
+	}
+
+	if drift := config.CompareRules(embedded, shippedRules(rules, rulesDir)); !drift.Empty() {
+		var details []string
+		if len(drift.Missing) > 0 {
+			details = append(details, fmt.Sprintf("%d missing (%s)", len(drift.Missing), strings.Join(drift.Missing, ", ")))
+		}
+		if len(drift.Extra) > 0 {
+			details = append(details, fmt.Sprintf("%d extra (%s)", len(drift.Extra), strings.Join(drift.Extra, ", ")))
+		}
+		if len(drift.Modified) > 0 {
+			details = append(details, fmt.Sprintf("%d modified (%s)", len(drift.Modified), strings.Join(drift.Modified, ", ")))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: presupplied rules in %s differ from those built into planguard v%s: %s. Update the directory or pass -prefer embedded\n",
+			rulesDir, version, strings.Join(details, "; "))
+	}
+
+	return rules, nil
+}
+
+// presuppliedRulesFS returns the rules directory, or the rules built into
+// planguard when prefer is "embedded"
+func presuppliedRulesFS(rulesDir, prefer string) fs.FS {
+	if prefer == "embedded" {
+		return embeddedrules.FS
+	}
+	return os.DirFS(rulesDir)
+}
+
+// shippedRules drops the rules loaded from categories planguard does not
+// ship, which a rules directory adds without drifting from the built-in
+// rules
+func shippedRules(rules []config.Rule, rulesDir string) []config.Rule {
+	var shipped []config.Rule
+	for _, rule := range rules {
+		rel, err := filepath.Rel(rulesDir, rule.Source)
+		if err == nil {
+			if dir, _, nested := strings.Cut(filepath.ToSlash(rel), "/"); nested {
+				if _, err := fs.Stat(embeddedrules.FS, dir); err != nil {
+					continue
+				}
+			}
+		}
+		shipped = append(shipped, rule)
+	}
+	return shipped
+}
+
+func loadConfiguration(configPath, profile, rulesDir string, usePresuppliedRulesStr string, presuppliedRulesCategoriesStr string, prefer string, packs []string) (*config.Config, error) {
 	// Expand home directory in paths
 	if configPath != "" {
 		expanded, err := expandHomePath(configPath)
@@ -160,37 +1265,25 @@ func loadConfiguration(configPath, rulesDir string, usePresuppliedRulesStr strin
 			return nil, err
 		}
 		configPath = expanded
-	} else {
-		// Auto-search for config file
-		configPath = findConfigFile()
 	}
 
 	// Expand rules directory path
-	if rulesDir != "" {
-		expanded, err := expandHomePath(rulesDir)
-		if err != nil {
-			return nil, err
-		}
-		rulesDir = expanded
-	} else {
-		// Use default rules directory
-		defaultRulesDir, err := getDefaultRulesDir()
-		if err != nil {
-			return nil, err
-		}
-		rulesDir = defaultRulesDir
+	rulesDir, err := resolveRulesDir(rulesDir)
+	if err != nil {
+		return nil, err
 	}
 
 	var cfg *config.Config
-	var err error
+	var rulesReplacePresupplied bool
 
-	// Load config file if it exists
-	if configPath != "" {
-		cfg, err = config.LoadConfig(configPath)
+	// Load and merge config layers if there are any
+	if paths := configLayers(configPath); len(paths) > 0 {
+		cfg, rulesReplacePresupplied, err = loadConfigLayers(paths)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load config from %s: %w", configPath, err)
+			return nil, err
 		}
 	} else {
+		verbosef("No config file found; using default settings")
 		// Create default config
 		defaultUsePresuppliedRules := true
 		cfg = &config.Config{
@@ -204,6 +1297,22 @@ func loadConfiguration(configPath, rulesDir string, usePresuppliedRulesStr strin
 			Exceptions: []config.Exception{},
 			Functions:  []config.Function{},
 		}
+		// Exception files apply without a config file too
+		exceptions, err := config.LoadExceptionsDir(filepath.Join(projectConfigDir(), config.ExceptionsDir))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Exceptions = append(cfg.Exceptions, exceptions...)
+	}
+
+	if profile != "" {
+		active, err := cfg.FindProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		active.Apply(cfg.Settings)
+		cfg.ActiveProfile = active
+		logf("Using profile %s", profile)
 	}
 
 	// Override config settings with CLI flags (only if explicitly provided)
@@ -226,37 +1335,161 @@ func loadConfiguration(configPath, rulesDir string, usePresuppliedRulesStr strin
 		}
 	}
 
-	// Check if we should load presupplied rules
-	shouldLoadPresuppliedRules := cfg.Settings.UsePresuppliedRules != nil && *cfg.Settings.UsePresuppliedRules
+	// Check if we should load presupplied rules. The rules of the nearest
+	// config layer replace them; those of broader layers are scanned with them.
+	usePresuppliedRules := cfg.Settings.UsePresuppliedRules != nil && *cfg.Settings.UsePresuppliedRules
+	shouldLoadPresuppliedRules := usePresuppliedRules && !rulesReplacePresupplied
+	if usePresuppliedRules && rulesReplacePresupplied {
+		logf("Presupplied rules replaced by the rules in the config")
+	}
+
+	// Packs replace the presupplied rules
+	if len(packs) > 0 {
+		rules, err := loadPacks(packs, rulesDir, prefer)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rules = mergeRules(cfg.Rules, rules)
+		shouldLoadPresuppliedRules = false
+	}
 
-	// Check if rules directory exists (only if we need to load presupplied rules)
-	if shouldLoadPresuppliedRules {
+	// Check if rules directory exists (only if we need to load presupplied rules from it)
+	if shouldLoadPresuppliedRules && prefer != "embedded" {
 		if _, err := os.Stat(rulesDir); os.IsNotExist(err) {
-			return nil, fmt.Errorf("rules directory not found: %s\n\nPlease create it and add rules:\n  mkdir -p %s\n  cp -r /path/to/rules/* %s/\n\nOr specify a different location:\n  planguard -rules-dir /path/to/rules", rulesDir, rulesDir, rulesDir)
+			return nil, fmt.Errorf("rules directory not found: %s\n\nCopy the presupplied rules built into planguard there:\n  planguard init -copy-rules -rules-dir %s\n\nOr use them without a copy:\n  planguard scan -prefer embedded\n\nOr specify a different location:\n  planguard scan -rules-dir /path/to/rules", rulesDir, rulesDir)
 		}
 	}
 
-	// Load presupplied rules if enabled and not already present in config
-	if len(cfg.Rules) == 0 && shouldLoadPresuppliedRules && rulesDir != "" {
-		var rules []config.Rule
+	// Load presupplied rules if enabled and not replaced by the config's rules
+	if shouldLoadPresuppliedRules && (rulesDir != "" || prefer == "embedded") {
+		// Without categories, all presupplied rules are loaded
+		rules, err := loadPresuppliedRules(rulesDir, prefer, cfg.Settings.PresuppliedRulesCategories)
+		if err != nil {
+			return nil, err
+		}
 		if len(cfg.Settings.PresuppliedRulesCategories) > 0 {
-			// Load specific categories
-			rules, err = config.LoadDefaultRulesWithCategories(rulesDir, cfg.Settings.PresuppliedRulesCategories)
+			logf("Loaded presupplied rules for categories: %s", strings.Join(cfg.Settings.PresuppliedRulesCategories, ", "))
+		}
+		cfg.Rules = addPresuppliedRules(cfg.Rules, rules)
+	} else if !usePresuppliedRules && len(packs) == 0 {
+		logf("Presupplied rules disabled")
+	}
+
+	// Former IDs of renamed rules resolve before rules are filtered
+	aliases, warnings := config.RuleAliases(cfg.Rules)
+	warnings = append(warnings, aliases.ResolveConfig(cfg)...)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	cfg.Aliases = aliases
+
+	var unknown []string
+	cfg.Rules, unknown = config.FilterRules(cfg.Rules, cfg.Settings)
+	for _, id := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: rule %q in enabled_rules/disabled_rules/enable_rules does not match any loaded rule\n", id)
+	}
+	for _, warning := range config.DeprecationWarnings(cfg.Rules) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	logRules(cfg.Rules)
+
+	return cfg, nil
+}
+
+// addPresuppliedRules adds the presupplied rules to the rules of broader
+// config layers. A config rule with the ID of a presupplied rule takes its
+// place, since organizations customize presupplied rules that way.
+func addPresuppliedRules(rules, presupplied []config.Rule) []config.Rule {
+	defined := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		defined[rule.ID] = true
+	}
+	for _, rule := range presupplied {
+		if !defined[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// loadPacks loads the rule packs named by -pack flags. Packs are looked up
+// in the nearest .planguard's packs, then in the rules directory's packs
+// directory.
+// Presupplied rules are only loaded when a pack includes rules by ID.
+func loadPacks(names []string, rulesDir, prefer string) ([]config.Rule, error) {
+	searchDirs := []string{filepath.Join(projectConfigDir(), config.PacksDir), filepath.Join(rulesDir, config.PacksDir)}
+
+	var presupplied []config.Rule
+	presuppliedLoaded := false
+
+	var rules []config.Rule
+	for _, name := range names {
+		dir, err := config.FindPack(name, searchDirs)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err := config.LoadPackManifest(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pack %s: %w", name, err)
+		}
+		if manifest != nil && len(manifest.Rules) > 0 && !presuppliedLoaded {
+			source := packRulesSource(rulesDir, prefer)
+			categories, err := config.RuleCategoryNames(presuppliedRulesFS(rulesDir, source))
 			if err != nil {
-				return nil, fmt.Errorf("failed to load presupplied rules from %s: %w", rulesDir, err)
+				return nil, fmt.Errorf("failed to list presupplied rule categories: %w", err)
 			}
-			fmt.Fprintf(os.Stderr, "Loaded presupplied rules for categories: %s\n", strings.Join(cfg.Settings.PresuppliedRulesCategories, ", "))
-		} else {
-			// Load all presupplied rules
-			rules, err = config.LoadDefaultRules(rulesDir)
+			presupplied, err = loadPresuppliedRules(rulesDir, source, categories)
 			if err != nil {
-				return nil, fmt.Errorf("failed to load presupplied rules from %s: %w", rulesDir, err)
+				return nil, err
 			}
+			presuppliedLoaded = true
+		}
+
+		manifest, packRules, err := config.LoadPack(dir, presupplied)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pack %s: %w", name, err)
 		}
-		cfg.Rules = rules
-	} else if !shouldLoadPresuppliedRules {
-		fmt.Fprintf(os.Stderr, "Presupplied rules disabled\n")
+		logf("Loaded pack %s%s with %d rules from %s", manifest.Name, packVersion(manifest), len(packRules), dir)
+		rules = mergeRules(rules, packRules)
 	}
+	return rules, nil
+}
 
-	return cfg, nil
+// packRulesSource returns where to load the presupplied rules packs include
+// from: the rules directory when preferred and present, otherwise the rules
+// built into planguard
+func packRulesSource(rulesDir, prefer string) string {
+	if prefer == "disk" {
+		if _, err := os.Stat(rulesDir); err == nil {
+			return "disk"
+		}
+	}
+	return "embedded"
+}
+
+// packVersion formats a pack's version for messages, e.g. " v1.2.0"
+func packVersion(manifest *config.PackManifest) string {
+	if manifest.Version == "" {
+		return ""
+	}
+	return " v" + strings.TrimPrefix(manifest.Version, "v")
+}
+
+// mergeRules appends the rules in extra whose IDs are not already in rules,
+// warning about each duplicate
+func mergeRules(rules, extra []config.Rule) []config.Rule {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		seen[rule.ID] = true
+	}
+	for _, rule := range extra {
+		if seen[rule.ID] {
+			fmt.Fprintf(os.Stderr, "Warning: rule %q is defined more than once; keeping the first definition\n", rule.ID)
+			continue
+		}
+		seen[rule.ID] = true
+		rules = append(rules, rule)
+	}
+	return rules
 }
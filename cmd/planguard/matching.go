@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// formatMatches renders -explain-matching output: each resource that a
+// rule targets with the rules and their when results, followed by the
+// types of resources no rule targets
+func formatMatches(matches []scanner.ResourceMatches) string {
+	var output strings.Builder
+	unmatched := make(map[string]int)
+
+	for _, m := range matches {
+		if len(m.Rules) == 0 {
+			unmatched[m.Resource.Type]++
+			continue
+		}
+
+		output.WriteString(fmt.Sprintf("%s (%s:%d)\n", resourceLabel(m.Resource), m.Resource.File, m.Resource.Line))
+		for _, rule := range m.Rules {
+			switch {
+			case rule.When == "":
+				output.WriteString(fmt.Sprintf("  ✓ %s\n", rule.RuleID))
+			case rule.Runs:
+				output.WriteString(fmt.Sprintf("  ✓ %s (when: %s)\n", rule.RuleID, rule.When))
+			default:
+				output.WriteString(fmt.Sprintf("  ✗ %s (skipped, when: %s)\n", rule.RuleID, rule.When))
+			}
+		}
+	}
+
+	if len(unmatched) > 0 {
+		types := make([]string, 0, len(unmatched))
+		for resourceType, count := range unmatched {
+			types = append(types, fmt.Sprintf("%s (%d)", resourceType, count))
+		}
+		sort.Strings(types)
+		output.WriteString(fmt.Sprintf("\nNo rules target: %s\n", strings.Join(types, ", ")))
+	}
+
+	return output.String()
+}
+
+// resourceLabel names a block the way Terraform addresses it, e.g.
+// aws_s3_bucket.logs or data.aws_iam_policy_document.assume
+func resourceLabel(resource *config.Resource) string {
+	if resource.Kind == config.KindData {
+		return "data." + resource.Type + "." + resource.Name
+	}
+	return resource.Type + "." + resource.Name
+}
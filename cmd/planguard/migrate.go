@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/migrate"
+)
+
+// runMigrate implements `planguard migrate`, which converts suppressions
+// from tfsec or checkov into planguard exceptions
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "", "Tool to migrate from (tfsec, checkov)")
+	directory := fs.String("directory", ".", "Directory to read tool config and Terraform files from")
+	out := fs.String("out", "", "File to write exception blocks to (default: stdout)")
+	approvedBy := fs.String("approved-by", "", "Approver recorded on migrated exceptions (default: migrated from <tool>)")
+	severityMapOut := fs.String("severity-map-out", "", "File to write migrated severity overrides to, for use with -severity-map")
+	fs.Parse(args)
+
+	if *from == "" {
+		fmt.Fprintf(os.Stderr, "Error: -from is required (tfsec or checkov)\n")
+		return 1
+	}
+	if *approvedBy == "" {
+		*approvedBy = "migrated from " + *from
+	}
+
+	result, err := migrate.Migrate(*from, *directory, *approvedBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	output := migrate.Render(*from, result)
+	if *out == "" {
+		os.Stdout.Write(output)
+	} else {
+		if err := writeFile(*out, output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing exceptions: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d exceptions to %s; add them to your planguard config\n", len(result.Exceptions), *out)
+	}
+
+	if len(result.SeverityOverrides) > 0 {
+		if *severityMapOut == "" {
+			fmt.Fprintf(os.Stderr, "Found %d severity overrides; pass -severity-map-out to migrate them\n", len(result.SeverityOverrides))
+		} else {
+			if err := writeFile(*severityMapOut, migrate.RenderSeverityMap(result.SeverityOverrides)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing severity map: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %d severity overrides to %s; use it with -severity-map\n", len(result.SeverityOverrides), *severityMapOut)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Migrated %d of %d %s suppressions", len(result.Exceptions), result.Suppressions, *from)
+	if skipped := len(result.Unmapped) + len(result.Unattached); skipped > 0 {
+		fmt.Fprintf(os.Stderr, " (%d need review, listed as comments in the output)", skipped)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	return 0
+}
+
+// writeFile writes data to path, creating parent directories as needed
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// reportFormats lists the formats accepted by -format
+var reportFormats = []string{"text", "json", "json-v2", "sarif", "oneline", "markdown", "tap", "table", "template", "badge", "pr-comment"}
+
+// reportFormat is a format to report in and where to write the report:
+// a file, or "-" for stdout
+type reportFormat struct {
+	name string
+	path string
+}
+
+// formatFlags collects repeated -format name or name=path flags
+type formatFlags []reportFormat
+
+func (f *formatFlags) String() string {
+	var values []string
+	for _, format := range *f {
+		if format.path == "" {
+			values = append(values, format.name)
+		} else {
+			values = append(values, format.name+"="+format.path)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func (f *formatFlags) Set(value string) error {
+	name, path, _ := strings.Cut(value, "=")
+	known := false
+	for _, format := range reportFormats {
+		known = known || name == format
+	}
+	if !known {
+		return fmt.Errorf("unknown format %q (expected %s)", name, strings.Join(reportFormats, ", "))
+	}
+	if strings.Contains(value, "=") && path == "" {
+		return fmt.Errorf("missing path in %q (use %s=- for stdout)", value, name)
+	}
+	*f = append(*f, reportFormat{name: name, path: path})
+	return nil
+}
+
+// resolveFormats defaults -format to text and sends reports without a path
+// to -output, or stdout. No two reports may go to the same place.
+func resolveFormats(formats formatFlags, output string) (formatFlags, error) {
+	if len(formats) == 0 {
+		formats = formatFlags{{name: "text"}}
+	}
+	resolved := make(formatFlags, 0, len(formats))
+	destinations := make(map[string]string)
+	for _, format := range formats {
+		if format.path == "" {
+			format.path = output
+		}
+		if format.path == "" {
+			format.path = "-"
+		}
+		destination := format.path
+		if destination != "-" {
+			destination = filepath.Clean(destination)
+		}
+		if other, ok := destinations[destination]; ok {
+			if destination == "-" {
+				return nil, fmt.Errorf("-format %s and -format %s both write to stdout; give one of them a file, e.g. -format %s=report", other, format.name, format.name)
+			}
+			return nil, fmt.Errorf("-format %s and -format %s both write to %s", other, format.name, format.path)
+		}
+		destinations[destination] = format.name
+		resolved = append(resolved, format)
+	}
+	return resolved, nil
+}
+
+// formatReport renders the report in the named format. tmpl is the
+// -template template, for the template format.
+func formatReport(rep *reporter.Reporter, name string, cfg *config.Config, result *scanner.ScanResult, tmpl *template.Template) (string, error) {
+	switch name {
+	case "template":
+		return rep.FormatTemplate(tmpl)
+	case "json":
+		return rep.FormatJSON()
+	case "json-v2":
+		return rep.FormatJSONV2()
+	case "sarif":
+		return rep.FormatSARIF()
+	case "oneline":
+		return rep.FormatOneline(result.Resources), nil
+	case "markdown":
+		return rep.FormatMarkdown(), nil
+	case "tap":
+		return rep.FormatTAP(cfg.Rules, result.EvaluatedRules()), nil
+	case "table":
+		return rep.FormatTable(), nil
+	case "badge":
+		return rep.FormatBadge()
+	case "pr-comment":
+		return rep.FormatPRComment(), nil
+	default:
+		return rep.FormatText(), nil
+	}
+}
+
+// formatSections reports whether a format includes the top issues, the
+// compliance summary, and rule stats. What a format has no place for is
+// printed to stderr instead.
+func formatSections(name string) (top, compliance, stats bool) {
+	switch name {
+	case "text", "json", "json-v2", "template":
+		return true, true, true
+	case "markdown":
+		return true, false, false
+	default:
+		return false, false, false
+	}
+}
+
+// writeOutput writes a report to path for -output, creating its parent
+// directories. The report is written to a temporary file that replaces path
+// once complete, so readers never see a partial report.
+func writeOutput(path, report string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := tmp.WriteString(report); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
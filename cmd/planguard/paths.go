@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/gitdiff"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// localizePaths rewrites the files of result's violations relative to the
+// root of the git repository containing them, or as absolute paths with
+// -absolute-paths, and recomputes their fingerprints. Reports, baselines,
+// and fingerprints then match however the scan was invoked. Files outside a
+// repository keep the path they were scanned with.
+func localizePaths(result *scanner.ScanResult, absolute bool) {
+	localize := pathLocalizer(absolute)
+	for i := range result.Violations {
+		localize(&result.Violations[i])
+	}
+	for i := range result.FilteredViolations {
+		localize(&result.FilteredViolations[i].Violation)
+	}
+}
+
+// pathLocalizer returns a function rewriting a violation's file as
+// localizePaths does, caching the repository root of each directory
+func pathLocalizer(absolute bool) func(*config.Violation) {
+	roots := make(map[string]string)
+
+	return func(v *config.Violation) {
+		path, err := filepath.Abs(v.File)
+		if err != nil {
+			return
+		}
+		if absolute {
+			v.File = path
+			v.Fingerprint = config.Fingerprint(*v)
+			return
+		}
+
+		dir := filepath.Dir(path)
+		root, ok := roots[dir]
+		if !ok {
+			root, _ = gitdiff.RepoRoot(dir)
+			roots[dir] = root
+		}
+		if root == "" {
+			return
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return
+		}
+		v.File = filepath.ToSlash(rel)
+		v.Fingerprint = config.Fingerprint(*v)
+	}
+}
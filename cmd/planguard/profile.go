@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts the CPU profile and execution trace asked for with
+// -cpuprofile and -trace. The function it returns stops them and, with
+// -memprofile, writes a heap profile; failures to write are warnings, as
+// the scan itself succeeded.
+func startProfiling(opts scanOptions) (stop func(), err error) {
+	var cpuFile, traceFile *os.File
+	stop = func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			closeProfile(cpuFile)
+		}
+		if traceFile != nil {
+			trace.Stop()
+			closeProfile(traceFile)
+		}
+		if opts.memProfile != "" {
+			if err := writeHeapProfile(opts.memProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write memory profile: %v\n", err)
+			}
+		}
+	}
+
+	if opts.cpuProfile != "" {
+		if cpuFile, err = os.Create(opts.cpuProfile); err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+	}
+	if opts.traceFile != "" {
+		if traceFile, err = os.Create(opts.traceFile); err != nil {
+			err = fmt.Errorf("failed to create trace: %w", err)
+		} else if err = trace.Start(traceFile); err != nil {
+			traceFile.Close()
+			err = fmt.Errorf("failed to start trace: %w", err)
+		}
+		if err != nil {
+			if cpuFile != nil {
+				pprof.StopCPUProfile()
+				cpuFile.Close()
+			}
+			return nil, err
+		}
+	}
+	return stop, nil
+}
+
+// writeHeapProfile writes a profile of the memory in use, after a garbage
+// collection so it is up to date
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func closeProfile(f *os.File) {
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write %s: %v\n", f.Name(), err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressDelay is how long a scan runs before its status line appears, so
+// quick scans print nothing extra
+const progressDelay = 2 * time.Second
+
+// progressInterval is how often the status line is redrawn
+const progressInterval = 250 * time.Millisecond
+
+// status is the status line of the scan in progress, or nil. Its methods
+// do nothing on nil, so scans started without it need no checks.
+var status *progress
+
+// progress keeps a status line on stderr, e.g. "Parsed 1200/5000 files,
+// evaluated 12/40 rules (14s)", while a scan runs. It is only shown when
+// stderr is a terminal, without -quiet, once the scan has taken longer
+// than progressDelay.
+type progress struct {
+	mu      sync.Mutex
+	start   time.Time
+	shown   bool // Whether the status line is on screen
+	stopped chan struct{}
+	done    sync.WaitGroup
+
+	batch, batches   int
+	parsed, files    int
+	evaluated, rules int
+}
+
+// startProgress starts drawing the status line, or returns nil when it
+// would not be shown
+func startProgress() *progress {
+	if verbosity < 0 || !isTerminal(os.Stderr) {
+		return nil
+	}
+	p := &progress{start: time.Now(), stopped: make(chan struct{})}
+	p.done.Add(1)
+	go func() {
+		defer p.done.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopped:
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+	return p
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stop stops drawing and erases the status line
+func (p *progress) stop() {
+	if p == nil {
+		return
+	}
+	close(p.stopped)
+	p.done.Wait()
+	p.clear()
+}
+
+// parsedFiles records that done of total files have been parsed. It suits
+// parser.Parser.SetProgress.
+func (p *progress) parsedFiles(done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.parsed, p.files = done, total
+	p.evaluated, p.rules = 0, 0
+}
+
+// evaluatedRules records that done of total rules have been evaluated. It
+// suits scanner.Scanner.SetProgress.
+func (p *progress) evaluatedRules(done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evaluated, p.rules = done, total
+}
+
+// startBatch records that batch of batches, counted from 1, is being
+// scanned
+func (p *progress) startBatch(batch, batches int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batch, p.batches = batch, batches
+	p.parsed, p.files, p.evaluated, p.rules = 0, 0, 0, 0
+}
+
+// clear erases the status line, so another message can be printed. The
+// next draw puts it back.
+func (p *progress) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// draw redraws the status line once the scan has taken long enough
+func (p *progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elapsed := time.Since(p.start)
+	if elapsed < progressDelay || p.files == 0 {
+		return
+	}
+
+	line := fmt.Sprintf("Parsed %d/%d files", p.parsed, p.files)
+	if p.batches > 0 {
+		line = fmt.Sprintf("Batch %d/%d: parsed %d/%d files", p.batch, p.batches, p.parsed, p.files)
+	}
+	if p.rules > 0 {
+		line += fmt.Sprintf(", evaluated %d/%d rules", p.evaluated, p.rules)
+	}
+	line += fmt.Sprintf(" (%s)", elapsed.Truncate(time.Second))
+	fmt.Fprint(os.Stderr, "\r\033[K"+line)
+	p.shown = true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/repro"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// runRepro implements `planguard repro`, which extracts a standalone
+// reproduction of the violation with a given fingerprint
+func runRepro(args []string) int {
+	fs := flag.NewFlagSet("repro", flag.ExitOnError)
+	var opts scanOptions
+	registerScanFlags(fs, &opts)
+	out := fs.String("out", "", "File to write the reproduction to (default: stdout)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: planguard repro [flags] <fingerprint>\n\nFingerprints are shown with each violation in text output.\n")
+		return 1
+	}
+	if err := opts.resolve(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := scanContext(opts)
+	defer cancel()
+
+	result, cfg, err := scanAll(ctx, opts)
+	if err != nil {
+		printError("%v\n", err)
+		return 1
+	}
+
+	// Excepted violations can be reproduced too
+	violations := append([]config.Violation(nil), result.Violations...)
+	for _, fv := range result.FilteredViolations {
+		violations = append(violations, fv.Violation)
+	}
+
+	// Reported fingerprints are of localized paths, but the files are
+	// read from the paths they were scanned with
+	localized := &scanner.ScanResult{Violations: append([]config.Violation(nil), violations...)}
+	localizePaths(localized, opts.absolutePaths)
+	found, err := repro.Find(localized.Violations, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var v config.Violation
+	for i, candidate := range localized.Violations {
+		if candidate.Fingerprint == found.Fingerprint {
+			v = violations[i]
+			v.Fingerprint = found.Fingerprint
+			break
+		}
+	}
+
+	var rule config.Rule
+	for _, r := range cfg.Rules {
+		if r.ID == v.RuleID {
+			rule = r
+			break
+		}
+	}
+
+	// Re-parse the violation's directory for the blocks it refers to
+	files, err := parser.NewParser().ParseDirectoryContext(ctx, filepath.Dir(v.File), cfg.Settings.ExcludePaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing Terraform files: %v\n", err)
+		return 1
+	}
+	resources, err := parser.ExtractResources(files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error extracting resources: %v\n", err)
+		return 1
+	}
+
+	src, err := repro.Extract(parser.NewScanContext(resources), v, rule)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error extracting reproduction: %v\n", err)
+		return 1
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return 0
+	}
+	if err := writeFile(*out, src); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing reproduction: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote reproduction of %s on %s.%s to %s\n", v.RuleID, v.ResourceType, v.ResourceName, *out)
+	return 0
+}
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jonathanhle/planguard/internal/codemod"
+	"github.com/jonathanhle/planguard/internal/docs"
+	"github.com/jonathanhle/planguard/internal/rulelock"
+	"github.com/jonathanhle/planguard/internal/rulesign"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/rulecheck"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+const rulesUsage = `Usage: planguard rules <command> [flags]
+
+Commands:
+  categories  List the presupplied rule categories that can be selected
+  check       Find rules with duplicate IDs, identical logic, or contradictory conditions
+  checksum    Write the rules.sum manifest a rule bundle is signed through
+  codemod     Set attributes of every rule matching a filter, editing rule files in place
+  describe    Print a rule's full definition and where it was loaded from
+  export      Write a catalog of the loaded rules as JSON or Markdown
+  update      Fetch rule sources and pin what they resolve to in rules.lock.hcl
+  validate    Check rule files for syntax errors, invalid values, and expressions that cannot run
+`
+
+// runRules implements `planguard rules`, which works with rule files
+// rather than Terraform
+func runRules(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, rulesUsage)
+		return 1
+	}
+
+	switch args[0] {
+	case "categories":
+		return runRulesCategories(args[1:])
+	case "check":
+		return runRulesCheck(args[1:])
+	case "checksum":
+		return runRulesChecksum(args[1:])
+	case "codemod":
+		return runRulesCodemod(args[1:])
+	case "describe":
+		return runRulesDescribe(args[1:])
+	case "export":
+		return runRulesExport(args[1:])
+	case "update":
+		return runRulesUpdate(args[1:])
+	case "validate":
+		return runRulesValidate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown rules command %q\n\n%s", args[0], rulesUsage)
+		return 1
+	}
+}
+
+// runRulesCategories implements `planguard rules categories`, which lists
+// the categories -presupplied-rules-categories accepts: every subdirectory
+// of the rules directory, plus the presupplied files selectable on their own
+func runRulesCategories(args []string) int {
+	fs := flag.NewFlagSet("rules categories", flag.ExitOnError)
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules, or the rules built into planguard if it does not exist)")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	fs.Parse(args)
+
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	dir, err := resolveRulesDir(*rulesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	source := packRulesSource(dir, *prefer)
+	fsys := presuppliedRulesFS(dir, source)
+
+	categories, err := config.RuleCategories(fsys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to list rule categories: %v\n", err)
+		return 1
+	}
+	if source == "embedded" {
+		fmt.Fprintf(os.Stderr, "Rule categories built into planguard v%s, with their rule counts:\n", version)
+	} else {
+		fmt.Fprintf(os.Stderr, "Rule categories in %s, with their rule counts:\n", dir)
+	}
+
+	for _, category := range categories {
+		rules, err := config.LoadDefaultRulesFS(fsys, []string{category.Name})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load %s rules: %v\n", category.Name, err)
+			return 1
+		}
+		count := 0
+		for _, rule := range rules {
+			// Rules in the root directory are loaded with every category
+			if matched, _ := path.Match(category.Pattern, rule.Source); matched {
+				count++
+			}
+		}
+		note := ""
+		if category.OptIn {
+			note = " (opt-in)"
+		}
+		fmt.Printf("%-12s %3d  %s%s\n", category.Name, count, category.Pattern, note)
+	}
+	return 0
+}
+
+// runRulesCheck implements `planguard rules check`. It loads every rule
+// definition, keeping duplicates that scanning would drop, and reports the
+// conflicts and overlaps between them.
+func runRulesCheck(args []string) int {
+	fs := flag.NewFlagSet("rules check", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file whose rules are checked (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory of rules to check, including subdirectories (default: ~/.planguard/rules, or the rules built into planguard if it does not exist)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard rules check [flags] [rule files or directories...]\n\nWith no arguments, the config file's rules and the rules directory are checked.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var rules []config.Rule
+	var err error
+	if fs.NArg() > 0 {
+		rules, err = loadRuleFiles(fs.Args())
+	} else {
+		rules, err = loadAllRules(*configPath, *rulesDir)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	findings := rulecheck.Check(rules)
+	for _, finding := range findings {
+		fmt.Println(finding)
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(os.Stderr, "Found %d problems in %d rules\n", len(findings), len(rules))
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "No conflicts found in %d rules\n", len(rules))
+	return 0
+}
+
+// loadAllRules loads the rules defined in the config file and every rule
+// file in the rules directory, or the rules built into planguard when the
+// directory does not exist
+func loadAllRules(configPath, rulesDir string) ([]config.Rule, error) {
+	var rules []config.Rule
+
+	if configPath == "" {
+		configPath = findConfigFile()
+	} else {
+		expanded, err := expandHomePath(configPath)
+		if err != nil {
+			return nil, err
+		}
+		configPath = expanded
+	}
+	if configPath != "" {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", configPath, err)
+		}
+		rules = append(rules, cfg.Rules...)
+	}
+
+	dir, err := resolveRulesDir(rulesDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		embedded, err := allEmbeddedRules()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load embedded presupplied rules: %w", err)
+		}
+		return append(rules, embedded...), nil
+	}
+
+	dirRules, err := loadRuleFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, dirRules...), nil
+}
+
+// allEmbeddedRules loads every rule built into planguard, including the
+// opt-in categories
+func allEmbeddedRules() ([]config.Rule, error) {
+	categories, err := config.RuleCategoryNames(embeddedrules.FS)
+	if err != nil {
+		return nil, err
+	}
+	return config.LoadDefaultRulesFS(embeddedrules.FS, categories)
+}
+
+// loadRuleFiles loads the rules in paths, which may be rule files, globs,
+// or directories searched recursively for .hcl files
+func loadRuleFiles(paths []string) ([]config.Rule, error) {
+	files, err := ruleFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	return config.LoadRules(files)
+}
+
+// ruleFiles lists the files in paths, searching directories recursively for
+// .hcl files other than rule tests. Other paths are returned as they are.
+func ruleFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(p) == ".hcl" && !config.IsRuleTestFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rules in %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
+
+// runRulesCodemod implements `planguard rules codemod`, which sets
+// attributes of the rules matching a filter across rule files
+func runRulesCodemod(args []string) int {
+	fs := flag.NewFlagSet("rules codemod", flag.ExitOnError)
+	var sets setFlags
+	fs.Var(&sets, "set", "Attribute to set on matching rules as attribute=value, e.g. severity=warning (repeatable)")
+	where := fs.String("where", "", `Rules to change, e.g. 'tags contains "experimental" and severity == error'`)
+	rulesDir := fs.String("rules-dir", "", "Directory of rules to edit, including subdirectories (default: ~/.planguard/rules)")
+	dryRun := fs.Bool("dry-run", false, "List the rules that would change without writing any file")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard rules codemod -set attribute=value -where filter [flags] [rule files or directories...]\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if len(sets) == 0 || *where == "" {
+		fs.Usage()
+		return 1
+	}
+	filter, err := codemod.ParseWhere(*where)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -where: %v\n", err)
+		return 1
+	}
+	var assignments []codemod.Assignment
+	for _, set := range sets {
+		a, err := codemod.ParseSet(set)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -set: %v\n", err)
+			return 1
+		}
+		assignments = append(assignments, a)
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		dir, err := resolveRulesDir(*rulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		paths = []string{dir}
+	}
+	files, err := ruleFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	matched, changed := 0, 0
+	for _, file := range files {
+		if filepath.Base(file) == config.PackManifestFile {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		out, fileMatched, fileChanged, err := codemod.Rewrite(file, src, filter, assignments)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		matched += len(fileMatched)
+		if len(fileChanged) == 0 {
+			continue
+		}
+		changed += len(fileChanged)
+
+		if *dryRun {
+			fmt.Printf("%s: would change %s\n", file, strings.Join(fileChanged, ", "))
+			continue
+		}
+		if err := os.WriteFile(file, out, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", file, err)
+			return 1
+		}
+		fmt.Printf("%s: changed %s\n", file, strings.Join(fileChanged, ", "))
+	}
+
+	if matched == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no rules match %s\n", *where)
+		return 1
+	}
+	verb := "Changed"
+	if *dryRun {
+		verb = "Would change"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d of %d matching rules\n", verb, changed, matched)
+	return 0
+}
+
+// setFlags collects repeated -set flags
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runRulesChecksum implements `planguard rules checksum`, which writes the
+// manifest of a rule bundle's files for its publisher to sign
+func runRulesChecksum(args []string) int {
+	fs := flag.NewFlagSet("rules checksum", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard rules checksum <directory>\n\nWrites <directory>/%s listing the SHA-256 of each rule file that rule_sources would load from the directory.\n", rulesign.ManifestFile)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	dir := fs.Arg(0)
+	files, err := sourceRuleFiles(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no rule files in %s\n", dir)
+		return 1
+	}
+	manifest, err := rulesign.Manifest(dir, files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	path := filepath.Join(dir, rulesign.ManifestFile)
+	if err := os.WriteFile(path, manifest, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing manifest: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote checksums of %d rule files to %s; sign it with:\n  minisign -Sm %s\n", len(files), path, path)
+	return 0
+}
+
+// runRulesUpdate implements `planguard rules update`, which fetches the
+// config's rule sources at their refs and writes the lock file that scans
+// then load them from
+func runRulesUpdate(args []string) int {
+	fs := flag.NewFlagSet("rules update", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	fs.Parse(args)
+
+	path, err := resolveConfigPath(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(cfg.Settings.RuleSources) == 0 {
+		fmt.Fprintf(os.Stderr, "No rule_sources in %s; nothing to lock\n", path)
+		return 0
+	}
+
+	lockPath := filepath.Join(filepath.Dir(path), rulelock.FileName)
+	previous, err := rulelock.Load(lockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := checkLayerSettings(path, cfg.Settings); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	broader, err := broaderSettings(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	lock, err := lockRuleSources(ruleSourceSettings(cfg.Settings, broader))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, entry := range lock.Sources {
+		status := "locked"
+		if previous != nil {
+			if old, ok := previous.Find(entry.Source); ok && old == entry {
+				status = "unchanged"
+			} else if ok {
+				status = "updated"
+			}
+		}
+		pinned := entry.Digest
+		if entry.Commit != "" {
+			pinned = "commit " + entry.Commit
+		}
+		fmt.Printf("%-9s %s at %s\n", status, entry.Source, pinned)
+	}
+	if err := os.WriteFile(lockPath, lock.Format(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing lock file: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s with %d rule sources\n", lockPath, len(lock.Sources))
+	return 0
+}
+
+// runRulesExport implements `planguard rules export`, which writes a
+// catalog of the loaded rules with their metadata and remediation, for
+// publishing on a policy catalog site
+func runRulesExport(args []string) int {
+	fs := flag.NewFlagSet("rules export", flag.ExitOnError)
+	format := fs.String("format", "json", "Catalog format: json or markdown")
+	out := fs.String("out", "", "File to write the catalog to (default: stdout)")
+	title := fs.String("title", "Planguard Policies", "Title of the markdown catalog")
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
+	usePresuppliedRules := fs.String("use-presupplied-rules", "", "Enable presupplied rules (true/false, default: true)")
+	presuppliedRulesCategories := fs.String("presupplied-rules-categories", "", "Comma-separated list of presupplied rule categories to export")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "markdown" {
+		fmt.Fprintf(os.Stderr, "Error: invalid -format value %q (expected json or markdown)\n", *format)
+		return 1
+	}
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cfg, err := loadConfiguration(*configPath, "", *rulesDir, *usePresuppliedRules, *presuppliedRulesCategories, *prefer, nil)
+	if err != nil {
+		printError("Error loading configuration: %v\n", err)
+		return 1
+	}
+	categories, err := ruleCategories(cfg, *rulesDir, *prefer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	site := &docs.Site{Title: *title, Version: version, Categories: categories}
+	var catalog string
+	if *format == "markdown" {
+		catalog = site.CatalogMarkdown()
+	} else {
+		data, err := site.CatalogJSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		catalog = string(data)
+	}
+
+	if *out == "" {
+		fmt.Print(catalog)
+		return 0
+	}
+	if err := writeOutput(*out, catalog); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote a catalog of %d rules to %s\n", len(site.Rules()), *out)
+	return 0
+}
+
+// runRulesDescribe implements `planguard rules describe`, which prints the
+// full definition of a rule as a scan would run it, with the layer it came
+// from: the config file, a pack, or the presupplied rules
+func runRulesDescribe(args []string) int {
+	fs := flag.NewFlagSet("rules describe", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	severityMap := fs.String("severity-map", "", "YAML file remapping rule severities by rule ID or tag, as for scans")
+	var packs packFlags
+	fs.Var(&packs, "pack", "Rule pack to load, as for scans (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard rules describe [flags] <rule ID>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	id := fs.Arg(0)
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cfg, err := loadConfiguration(*configPath, "", *rulesDir, "", "", *prefer, packs)
+	if err != nil {
+		printError("Error loading configuration: %v\n", err)
+		return 1
+	}
+
+	if current := cfg.Aliases.Resolve(id); current != id {
+		fmt.Fprintf(os.Stderr, "Warning: %s is the former ID of rule %s\n", id, current)
+		id = current
+	}
+	var rule *config.Rule
+	for i := range cfg.Rules {
+		if cfg.Rules[i].ID == id {
+			rule = &cfg.Rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		reason := ruleTurnedOff(cfg.Settings, id)
+		if reason == "" {
+			reason = optInReason(id)
+		}
+		if reason != "" {
+			fmt.Fprintf(os.Stderr, "Error: rule %q is %s\n", id, reason)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: no loaded rule has ID %q\n", id)
+		}
+		return 1
+	}
+
+	var notes []string
+	remap, err := loadSeverityMap(cfg, *severityMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading severity map: %v\n", err)
+		return 1
+	}
+	if remap != nil {
+		if remapped := remap.Apply([]config.Rule{*rule})[0]; remapped.Severity != rule.Severity {
+			notes = append(notes, fmt.Sprintf("severity changed from %s to %s by the severity map", rule.Severity, remapped.Severity))
+			rule.Severity = remapped.Severity
+		}
+	}
+
+	origin, err := ruleOrigin(*rule, *configPath, *prefer, len(packs) > 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if strings.HasPrefix(origin, "custom") {
+		embedded, err := allEmbeddedRules()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load embedded presupplied rules: %v\n", err)
+			return 1
+		}
+		for _, presupplied := range embedded {
+			if presupplied.ID == id {
+				notes = append(notes, "overrides the presupplied rule with the same ID")
+				break
+			}
+		}
+	}
+
+	fmt.Print(docs.Describe(*rule, origin, notes))
+	return 0
+}
+
+// ruleOrigin describes the layer a loaded rule came from: the config file,
+// a pack, or the presupplied rules on disk or built into planguard
+func ruleOrigin(rule config.Rule, configPath, prefer string, packs bool) (string, error) {
+	if configPath == "" {
+		configPath = findConfigFile()
+	} else {
+		expanded, err := expandHomePath(configPath)
+		if err != nil {
+			return "", err
+		}
+		configPath = expanded
+	}
+
+	switch {
+	case configPath != "" && rule.Source == configPath:
+		return fmt.Sprintf("custom, defined in %s", rule.Source), nil
+	case packs && rule.Pack != nil:
+		return fmt.Sprintf("pack %s%s, from %s", rule.Pack.Name, packVersion(rule.Pack), rule.Source), nil
+	case prefer == "embedded":
+		return fmt.Sprintf("presupplied, built into planguard v%s (%s)", version, rule.Source), nil
+	default:
+		return fmt.Sprintf("presupplied, from %s", rule.Source), nil
+	}
+}
+
+// ruleTurnedOff says why settings leave a rule out of scans, or returns ""
+// when they do not
+func ruleTurnedOff(settings *config.Settings, id string) string {
+	if settings == nil {
+		return ""
+	}
+	for _, disabled := range settings.DisabledRules {
+		if disabled == id {
+			return "turned off by disabled_rules"
+		}
+	}
+	if len(settings.EnabledRules) == 0 {
+		return ""
+	}
+	for _, enabled := range settings.EnabledRules {
+		if enabled == id {
+			return ""
+		}
+	}
+	return "not in enabled_rules"
+}
+
+// optInReason says a presupplied rule is left out of scans because it is
+// opt-in, or returns "" when no such rule is built into planguard
+func optInReason(id string) string {
+	embedded, err := allEmbeddedRules()
+	if err != nil {
+		return ""
+	}
+	for _, rule := range embedded {
+		if rule.ID == id && rule.IsOptIn() {
+			return "opt-in (enabled = false) and not in enable_rules"
+		}
+	}
+	return ""
+}
+
+// runRulesValidate implements `planguard rules validate`, which checks rule
+// files without scanning, so mistakes in a rule are reported with their
+// position before a scan runs into them
+func runRulesValidate(args []string) int {
+	fs := flag.NewFlagSet("rules validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard rules validate <rule files or directories...>\n\nDirectories are searched recursively for .hcl files.\n")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	files, err := ruleFiles(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	problems := rulecheck.Validate(files)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "Found %d problems in %d files\n", len(problems), len(files))
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d files are valid\n", len(files))
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/pkg/reporter"
+)
+
+// runScan implements `planguard scan`, which scans Terraform for
+// violations and reports them
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	var opts scanOptions
+	registerScanFlags(fs, &opts)
+	fs.Var(&opts.formats, "format", "Output format (text, json, json-v2, sarif, oneline, markdown, tap, table, template, badge, pr-comment); repeat as format=path to write several reports from one scan, with - for stdout (default text)")
+	fs.StringVar(&opts.output, "output", "", "Write the report to this file instead of stdout, creating its parent directories")
+	fs.StringVar(&opts.template, "template", "", "Go text/template file that renders the report for -format template")
+	fs.IntVar(&opts.commentMaxBytes, "comment-max-bytes", reporter.DefaultCommentMaxBytes, "Size limit of -format pr-comment; violations that do not fit are counted instead of listed")
+	fs.StringVar(&opts.reportURL, "report-url", "", "Link to the full report in -format pr-comment (default: "+reporter.ReportURLPlaceholder+", for CI to replace)")
+	fs.StringVar(&opts.groupBy, "group-by", reporter.GroupBySeverity, "Group violations in text and markdown output by severity, rule, file, or resource_type; grouped other than by severity, each violation takes one line")
+	fs.BoolVar(&opts.noColor, "no-color", false, "Disable colors in text output (also disabled by NO_COLOR and when stdout is not a terminal)")
+	fs.StringVar(&opts.severityThreshold, "severity-threshold", "", "Hide violations less severe than this (error, warning) from the report; they still count for -fail-on and -exit-code-map (default: show all)")
+	fs.StringVar(&opts.failOn, "fail-on", "", "Fail on severity level (error, warning, info) (default: error, or the fail_on of -profile)")
+	fs.StringVar(&opts.exitCodeMap, "exit-code-map", "", "Exit codes by the most severe new violation, e.g. error=1,warning=2 (unlisted severities exit 0; replaces -fail-on)")
+	fs.Float64Var(&opts.failOnScore, "fail-on-score", 0, "Fail when the risk score of the violations reaches this value (default: off)")
+	fs.StringVar(&opts.baseline, "baseline", "", "Baseline file; violations recorded in it are reported as known and do not fail the scan")
+	fs.BoolVar(&opts.explainMatching, "explain-matching", false, "List the rules that target each resource by resource_type and when condition, without evaluating conditions")
+	fs.BoolVar(&opts.partialResultsOnTimeout, "partial-results-on-timeout", false, "When the scan is interrupted or times out, report the violations found so far instead of only an error")
+	fs.IntVar(&opts.top, "top", 0, "Lead the report with the N rules and resources with the most severity-weighted violations (default: off)")
+	fs.BoolVar(&opts.compliance, "compliance", false, "Add a summary of pass/fail per compliance framework control, from the rules' compliance mappings")
+	fs.BoolVar(&opts.blame, "blame", false, "Record the commit, author, and date that last changed each violating line, using git blame")
+	fs.BoolVar(&opts.showFiltered, "show-filtered", false, "Include violations covered by exceptions, with the exception that matched, in JSON and SARIF output")
+	fs.IntVar(&opts.showSource, "show-source", 0, "Show N lines of source before and after each violation's line in text and SARIF output (default: off)")
+	fs.BoolVar(&opts.tui, "tui", false, "Browse the violations in an interactive terminal UI instead of printing a report; violations marked in it are printed as exception blocks on exit")
+	fs.BoolVar(&opts.stats, "stats", false, "Report the time spent evaluating each rule, slowest first, with the resources it evaluated and violations it found")
+	fs.BoolVar(&opts.failOnExpiredExceptions, "fail-on-expired-exceptions", false, "Fail the scan if any exception has passed its expires_at date")
+	fs.Var(&opts.suppress, "suppress", "Suppress a finding for this run only, as rule_id:resource_type.resource_name (repeatable)")
+	showVersion := fs.Bool("version", false, "Show version (deprecated: use planguard version)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard scan [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *showVersion {
+		fmt.Printf("Planguard v%s\n", version)
+		return 0
+	}
+
+	if err := opts.resolve(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return run(opts)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/server"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// serveShutdownTimeout bounds how long `planguard serve` waits for requests
+// in flight when it is stopped
+const serveShutdownTimeout = 30 * time.Second
+
+// runServe implements `planguard serve`, a read-only HTTP service that
+// answers policy decisions for single resources
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	configPath := fs.String("config", "", "Path to config file (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := fs.String("rules-dir", "", "Directory containing rules (default: ~/.planguard/rules)")
+	usePresuppliedRules := fs.String("use-presupplied-rules", "", "Enable presupplied rules (true/false, default: true)")
+	presuppliedRulesCategories := fs.String("presupplied-rules-categories", "", "Comma-separated list of presupplied rule categories for the default rule set")
+	severityMapPath := fs.String("severity-map", "", "YAML file remapping rule severities by rule ID or tag")
+	prefer := fs.String("prefer", "disk", "Where to load presupplied rules from: disk (the rules directory) or embedded (the rules built into planguard)")
+	fs.Parse(args)
+
+	if err := validatePrefer(*prefer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cfg, err := loadConfiguration(*configPath, "", *rulesDir, *usePresuppliedRules, *presuppliedRulesCategories, *prefer, nil)
+	if err != nil {
+		printError("Error loading configuration: %v\n", err)
+		return 1
+	}
+
+	ruleSets := map[string][]config.Rule{
+		server.DefaultRuleSet: cfg.Rules,
+	}
+
+	// Each presupplied category is also available as its own rule set
+	dir, err := resolveRulesDir(*rulesDir)
+	if err == nil {
+		_, statErr := os.Stat(dir)
+		if statErr == nil || *prefer == "embedded" {
+			categories, err := config.RuleCategories(presuppliedRulesFS(dir, *prefer))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing rule categories: %v\n", err)
+				return 1
+			}
+			for _, category := range categories {
+				// Hygiene rules count references across a whole
+				// configuration, which a single-resource decision lacks
+				if category.OptIn {
+					continue
+				}
+				var rules []config.Rule
+				if *prefer == "embedded" {
+					rules, err = config.LoadDefaultRulesFS(embeddedrules.FS, []string{category.Name})
+				} else {
+					rules, err = config.LoadDefaultRulesWithCategories(dir, []string{category.Name})
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading %s rules: %v\n", category.Name, err)
+					return 1
+				}
+				ruleSets[category.Name], _ = config.FilterRules(rules, cfg.Settings)
+			}
+		}
+	}
+
+	severityMap, err := loadSeverityMap(cfg, *severityMapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading severity map: %v\n", err)
+		return 1
+	}
+	if severityMap != nil {
+		for name, rules := range ruleSets {
+			ruleSets[name] = severityMap.Apply(rules)
+		}
+	}
+
+	srv := server.New(cfg, ruleSets)
+
+	shutdownMetrics, err := setupMetrics(srv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up metrics: %v\n", err)
+		return 1
+	}
+	defer shutdownMetrics()
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	// On SIGINT or SIGTERM, finish the requests in flight, then flush the
+	// metrics
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving policy decisions on http://%s/decision (rule sets: %s)\n", *addr, strings.Join(srv.RuleSets(), ", "))
+	select {
+	case err := <-serveErr:
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		return 1
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(os.Stderr, "Shutting down\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error shutting down: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// setupMetrics exports the server's metrics over OTLP/HTTP when an OTLP
+// endpoint is configured through the standard OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_METRICS_ENDPOINT environment variables. The exporter
+// reads its other settings (headers, TLS, export interval) from the
+// environment too. It returns a function flushing and stopping the export.
+func setupMetrics(srv *server.Server) (func(), error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT") == "" {
+		return func() {}, nil
+	}
+
+	ctx := context.Background()
+	exporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName("planguard"),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	if err := srv.SetMeterProvider(provider); err != nil {
+		_ = provider.Shutdown(ctx)
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exporting OpenTelemetry metrics over OTLP/HTTP\n")
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		_ = provider.Shutdown(shutdownCtx)
+	}, nil
+}
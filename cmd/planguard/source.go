@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// addSource records the context lines before and after each violation's
+// line, and the line itself, reading each file once. It must run before
+// localizePaths, while files still have the path they were scanned with.
+// Files that cannot be read are reported once and left without source.
+func addSource(result *scanner.ScanResult, context int) {
+	files := make(map[string][]string)
+	failed := make(map[string]bool)
+
+	source := func(v *config.Violation) {
+		if failed[v.File] {
+			return
+		}
+		lines, ok := files[v.File]
+		if !ok {
+			data, err := os.ReadFile(v.File)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to read source: %v\n", err)
+				failed[v.File] = true
+				return
+			}
+			lines = strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+			files[v.File] = lines
+		}
+		if v.Line <= 0 || v.Line > len(lines) {
+			return
+		}
+		start := max(v.Line-context, 1)
+		end := min(v.Line+context, len(lines))
+		v.Source = &config.Source{
+			StartLine: start,
+			Lines:     lines[start-1 : end],
+		}
+	}
+
+	for i := range result.Violations {
+		source(&result.Violations[i])
+	}
+	for i := range result.FilteredViolations {
+		source(&result.FilteredViolations[i].Violation)
+	}
+}
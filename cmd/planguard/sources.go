@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jonathanhle/planguard/internal/rulelock"
+	"github.com/jonathanhle/planguard/internal/rulesign"
+	"github.com/jonathanhle/planguard/internal/rulesource"
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// loadRuleSources loads the rules of the git repositories and buckets in
+// the rule_sources setting, fetched into the user cache directory. Rule
+// files are read from the source's directory and its immediate
+// subdirectories. When lockPath exists, sources are loaded as it pins
+// them; otherwise they follow their refs. Only the organization and user
+// configs have rule sources (see checkLayerSettings), so only lock files
+// next to them are read.
+func loadRuleSources(settings *config.Settings, lockPath string) ([]config.Rule, error) {
+	lock, err := rulelock.Load(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []config.Rule
+	for _, spec := range settings.RuleSources {
+		var pin *rulelock.Entry
+		if lock != nil {
+			entry, ok := lock.Find(spec)
+			if !ok {
+				return nil, fmt.Errorf("rule source %s is not in %s; run planguard rules update", spec, lockPath)
+			}
+			pin = &entry
+		}
+
+		dir, files, err := fetchRuleSource(settings, spec, pin)
+		if err != nil {
+			return nil, err
+		}
+		if pin != nil {
+			digest, err := rulelock.Digest(dir, files)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load rule source %s: %w", spec, err)
+			}
+			if digest != pin.Digest && pin.Commit != "" {
+				// A commit's files never change, so the cached copy has
+				return nil, fmt.Errorf("rule source %s at commit %s does not match %s (digest %s, locked %s); the cached copy in %s was modified, so remove it and scan again", spec, pin.Commit, lockPath, digest, pin.Digest, dir)
+			}
+			if digest != pin.Digest {
+				return nil, fmt.Errorf("rule source %s has changed since it was locked in %s (digest %s, locked %s); run planguard rules update if the change is expected", spec, lockPath, digest, pin.Digest)
+			}
+		}
+
+		sourceRules, err := config.LoadRules(files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rule source %s: %w", spec, err)
+		}
+		if len(sourceRules) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: rule source %s has no rules\n", spec)
+		}
+		logf("Loaded %d rules from %s", len(sourceRules), spec)
+		rules = mergeRules(rules, sourceRules)
+	}
+	return rules, nil
+}
+
+// ruleSourceSettings returns the settings a config layer's rule sources are
+// fetched and verified with, given the settings of the broader layers under
+// it. Broader layers' credentials apply after the layer's own, and their
+// require_signed_rules and trusted_rule_keys apply too. A layer adds its own
+// trusted keys only when no broader layer requires signed rules, so it
+// cannot trust its own signatures in place of the organization's.
+func ruleSourceSettings(settings *config.Settings, broader []*config.Settings) *config.Settings {
+	merged := *settings
+	merged.Credentials = append([]config.Credentials(nil), settings.Credentials...)
+	var keys []string
+	required := false
+	for i := len(broader) - 1; i >= 0; i-- {
+		merged.Credentials = append(merged.Credentials, broader[i].Credentials...)
+		keys = append(keys, broader[i].TrustedRuleKeys...)
+		required = required || broader[i].RequireSignedRules
+	}
+	if !required {
+		keys = append(append([]string(nil), settings.TrustedRuleKeys...), keys...)
+	}
+	merged.TrustedRuleKeys = keys
+	merged.RequireSignedRules = required || settings.RequireSignedRules
+	return &merged
+}
+
+// fetchRuleSource fetches a rule source, at the commit pin locks it to if
+// given, and returns its directory and rule files. Sources with a signature
+// are verified against trusted_rule_keys, and with require_signed_rules
+// every source must have one. Git sources over HTTPS are fetched with the
+// credentials block for their host, if there is one.
+func fetchRuleSource(settings *config.Settings, spec string, pin *rulelock.Entry) (string, []string, error) {
+	keys, err := trustedRuleKeys(settings)
+	if err != nil {
+		return "", nil, err
+	}
+	cacheDir, err := resolveCacheDir("")
+	if err != nil {
+		return "", nil, err
+	}
+
+	src, err := rulesource.Parse(spec)
+	if err != nil {
+		return "", nil, err
+	}
+	if pin != nil && pin.Commit != "" {
+		src.Ref = pin.Commit
+	}
+	src.Auth, err = rulesource.ResolveCredentials(settings.Credentials, src)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch rule source %s: %w", spec, err)
+	}
+	dir, warning, err := rulesource.Fetch(src, filepath.Join(cacheDir, "sources"))
+	if err != nil {
+		return "", nil, err
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	files, err := sourceRuleFiles(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load rule source %s: %w", spec, err)
+	}
+	_, statErr := os.Stat(filepath.Join(dir, rulesign.SignatureFile))
+	signed := statErr == nil
+	switch {
+	case signed && len(keys) > 0:
+		key, err := rulesign.Verify(dir, files, keys)
+		if err != nil {
+			return "", nil, fmt.Errorf("rule source %s failed verification: %w", spec, err)
+		}
+		verbosef("Verified rule source %s, signed by key %s", spec, key)
+	case settings.RequireSignedRules:
+		return "", nil, fmt.Errorf("rule source %s is not signed (no %s), and require_signed_rules is set", spec, rulesign.SignatureFile)
+	}
+	return dir, files, nil
+}
+
+// trustedRuleKeys parses the trusted_rule_keys setting
+func trustedRuleKeys(settings *config.Settings) ([]rulesign.PublicKey, error) {
+	var keys []rulesign.PublicKey
+	for _, trusted := range settings.TrustedRuleKeys {
+		key, err := rulesign.ParsePublicKey(trusted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted_rule_keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if settings.RequireSignedRules && len(keys) == 0 {
+		return nil, fmt.Errorf("require_signed_rules is set, but trusted_rule_keys lists no keys to verify rule sources with")
+	}
+	return keys, nil
+}
+
+// sourceRuleFiles lists the rule files of a rule source or bundle: the
+// .hcl files in dir and its immediate subdirectories
+func sourceRuleFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err != nil {
+		return nil, err
+	}
+	nested, err := filepath.Glob(filepath.Join(dir, "*", "*.hcl"))
+	if err != nil {
+		return nil, err
+	}
+	return append(files, nested...), nil
+}
+
+// lockRuleSources fetches every rule source at its current ref and returns
+// the lock pinning them there
+func lockRuleSources(settings *config.Settings) (*rulelock.Lock, error) {
+	lock := &rulelock.Lock{}
+	for _, spec := range settings.RuleSources {
+		dir, files, err := fetchRuleSource(settings, spec, nil)
+		if err != nil {
+			return nil, err
+		}
+		entry := rulelock.Entry{Source: spec}
+		if src, _ := rulesource.Parse(spec); src.Kind == rulesource.Git {
+			if entry.Commit, err = rulesource.Commit(dir); err != nil {
+				return nil, fmt.Errorf("failed to lock rule source %s: %w", spec, err)
+			}
+		}
+		if entry.Digest, err = rulelock.Digest(dir, files); err != nil {
+			return nil, fmt.Errorf("failed to lock rule source %s: %w", spec, err)
+		}
+		lock.Sources = append(lock.Sources, entry)
+	}
+	return lock, nil
+}
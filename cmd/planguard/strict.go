@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/rulecheck"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+// checkRuleDefinitions checks the loaded config and rule files for
+// problems loading accepts but a scan trips over: attributes and blocks
+// planguard does not know, such as a misspelled severty, which loading
+// ignores, and in the files the loaded rules came from, problems such as an
+// invalid severity or a call to a function that does not exist. They are
+// warnings, or fail the scan with -strict or strict = true in settings.
+func checkRuleDefinitions(cfg *config.Config, opts scanOptions) error {
+	unknown := append(hcl.Diagnostics(nil), cfg.Unknown...)
+	seen := make(map[string]bool)
+	var files []string
+	for _, rule := range cfg.Rules {
+		source := rule.Source
+		if source == "" || seen[source] {
+			continue
+		}
+		seen[source] = true
+		unknown = append(unknown, rule.Unknown...)
+		if filepath.Ext(source) != ".hcl" {
+			continue
+		}
+		// Rules built into planguard are checked when they are built
+		if opts.prefer == "embedded" && !filepath.IsAbs(source) {
+			if _, err := fs.Stat(embeddedrules.FS, source); err == nil {
+				continue
+			}
+		}
+		files = append(files, source)
+	}
+	sort.Strings(files)
+
+	problems := rulecheck.ValidateLoaded(files)
+	verbosef("Checked the rules of %d files", len(files))
+	if len(unknown) == 0 && len(problems) == 0 {
+		return nil
+	}
+
+	var messages []string
+	for _, diag := range unknown {
+		messages = append(messages, diagnosticString(diag))
+	}
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	if opts.strict || cfg.Settings.Strict {
+		err := fmt.Errorf("%d problems in config and rule files:\n  %s", len(messages), strings.Join(messages, "\n  "))
+		// Show the source lines of the unknown attributes and blocks too,
+		// except in YAML files, whose diagnostics have no position
+		var errs hcl.Diagnostics
+		for _, diag := range unknown {
+			if diag.Subject != nil && diag.Subject.Start.Line > 0 {
+				strict := *diag
+				strict.Severity = hcl.DiagError
+				errs = append(errs, &strict)
+			}
+		}
+		if len(errs) == 0 {
+			return err
+		}
+		return &sourceError{err: err, diags: errs}
+	}
+	status.clear()
+	for _, message := range messages {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	}
+	return nil
+}
+
+// diagnosticString formats a diagnostic as file:line:column: message, or
+// as file: message when it has no position in the file
+func diagnosticString(diag *hcl.Diagnostic) string {
+	message := diag.Summary
+	if diag.Detail != "" {
+		message += "; " + diag.Detail
+	}
+	switch {
+	case diag.Subject == nil:
+		return message
+	case diag.Subject.Start.Line == 0:
+		return fmt.Sprintf("%s: %s", diag.Subject.Filename, message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", diag.Subject.Filename, diag.Subject.Start.Line, diag.Subject.Start.Column, message)
+	}
+}
+
+// sourceError is an error whose message lists problems that printError
+// also shows the source lines of
+type sourceError struct {
+	err   error
+	diags hcl.Diagnostics
+}
+
+func (e *sourceError) Error() string { return e.err.Error() }
+
+func (e *sourceError) Unwrap() error { return e.diags }
+
+// printError prints an error to stderr with format, followed by the source
+// lines of the HCL diagnostics it wraps, such as an unsupported argument in
+// a config file
+func printError(format string, err error) {
+	fmt.Fprintf(os.Stderr, format, err)
+	if source := diagnosticsSource(err); source != "" {
+		fmt.Fprintf(os.Stderr, "\n%s", source)
+	}
+}
+
+// diagnosticsSource renders the HCL diagnostics wrapped in err with the
+// source ranges they point at, or returns "" when there are none
+func diagnosticsSource(err error) string {
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) {
+		return ""
+	}
+
+	parser := hclparse.NewParser()
+	files := make(map[string]*hcl.File)
+	for _, diag := range diags {
+		if diag.Subject == nil {
+			continue
+		}
+		name := diag.Subject.Filename
+		if _, ok := files[name]; ok {
+			continue
+		}
+		var file *hcl.File
+		switch filepath.Ext(name) {
+		case ".hcl":
+			file, _ = parser.ParseHCLFile(name)
+		case ".json":
+			file, _ = parser.ParseJSONFile(name)
+		}
+		files[name] = file
+	}
+
+	var b strings.Builder
+	if err := hcl.NewDiagnosticTextWriter(&b, files, 100, false).WriteDiagnostics(diags); err != nil {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/ruletest"
+)
+
+// runTest implements `planguard test`, which runs the test blocks in
+// *_test.hcl files against the rules they test
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to config file whose rules are tested (default: .planguard/config.hcl in this or a parent directory, or ~/.planguard/config.hcl)")
+	rulesDir := flags.String("rules-dir", "", "Directory of rules to test, including subdirectories (default: ~/.planguard/rules, or the rules built into planguard if it does not exist)")
+	run := flags.String("run", "", "Only run tests whose names match this regular expression")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: planguard test [flags] [test files or directories...]\n\nDirectories (default: .) are searched recursively for *_test.hcl files. Rules in the\nsame directory as a test file are loaded before the config file's and the rules directory's.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -run value %q: %v\n", *run, err)
+			return 1
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := ruletest.Discover(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no *%s files found\n", config.RuleTestSuffix)
+		return 1
+	}
+
+	// Rules beside the tests come first, so a rule being written shadows
+	// an installed rule with the same ID
+	var rules []config.Rule
+	loaded := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if loaded[dir] {
+			continue
+		}
+		loaded[dir] = true
+		dirRules, err := config.LoadRules([]string{filepath.Join(dir, "*.hcl")})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		rules = append(rules, dirRules...)
+	}
+	allRules, err := loadAllRules(*configPath, *rulesDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	rules = append(rules, allRules...)
+
+	var results []ruletest.Result
+	for _, file := range files {
+		tests, err := ruletest.LoadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load tests from %s: %v\n", file, err)
+			return 1
+		}
+		for _, test := range tests {
+			if filter != nil && !filter.MatchString(test.Name) {
+				continue
+			}
+			result := ruletest.Run(test, rules)
+			results = append(results, result)
+
+			status := "PASS"
+			if !result.Passed() {
+				status = "FAIL"
+			}
+			fmt.Printf("%s  %s:%d: %s\n", status, test.File, test.Line, test.Name)
+			for _, failure := range result.Failures {
+				fmt.Printf("      %s\n", failure)
+			}
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, ruletest.Summary(results))
+	for _, result := range results {
+		if !result.Passed() {
+			return 1
+		}
+	}
+	return 0
+}
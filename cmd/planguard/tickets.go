@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jonathanhle/planguard/internal/ticketcheck"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+)
+
+// ticketTimeout bounds each request to the issue tracker
+const ticketTimeout = 10 * time.Second
+
+// checkTickets validates the tickets of the active exceptions as set by
+// ticket_validation. Invalid tickets fail the scan unless on_failure is
+// "warning"; tickets the tracker could not be asked about only warn, so an
+// outage of the tracker does not block every pipeline.
+func checkTickets(cfg *config.Config) error {
+	settings := cfg.Settings.TicketValidation
+	if settings == nil {
+		return nil
+	}
+	checker, err := ticketcheck.New(*settings, &http.Client{Timeout: ticketTimeout})
+	if err != nil {
+		return err
+	}
+	problems := checker.Check(cfg.Exceptions, time.Now())
+	verbosef("Checked the tickets of %d exceptions", len(cfg.Exceptions))
+
+	warnOnly := settings.OnFailure != nil && *settings.OnFailure == "warning"
+	var failures []string
+	for _, problem := range problems {
+		message := fmt.Sprintf("exception for %s: %s", reporter.ExceptionScope(problem.Exception), problem.Message)
+		if problem.Exception.Source != "" {
+			message = problem.Exception.Source + ": " + message
+		}
+		if problem.Unverified || warnOnly {
+			status.clear()
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+			continue
+		}
+		failures = append(failures, message)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d exceptions failed ticket validation:\n  %s", len(failures), strings.Join(failures, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jonathanhle/planguard/internal/tui"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+)
+
+// tuiSourceLines is how many lines of source the browser shows before and
+// after a violation's line, unless -show-source asks for more
+const tuiSourceLines = 5
+
+// browse implements -tui: it shows the violations of a scan in the
+// terminal browser, then prints exception blocks for those marked. The
+// caller decides the exit code as it does for a report.
+func browse(violations []config.Violation, opts scanOptions) error {
+	if opts.severityThreshold != "" {
+		var shown []config.Violation
+		for _, v := range violations {
+			if reporter.SeverityAtLeast(v.Severity, opts.severityThreshold) {
+				shown = append(shown, v)
+			}
+		}
+		violations = shown
+	}
+
+	marked, err := tui.Run(os.Stdin, os.Stdout, violations)
+	if err != nil {
+		return err
+	}
+	if len(marked) > 0 {
+		exceptions := tui.Exceptions(marked)
+		fmt.Print(string(config.FormatExceptions(exceptions)))
+		fmt.Fprintf(os.Stderr, "Add the %d exception blocks above to your config, replacing %s in reason and approved_by\n", len(exceptions), tui.ExceptionPlaceholder)
+	}
+	return nil
+}
@@ -25,9 +25,9 @@ settings {
   # Presupplied rules categories (optional)
   # If specified, only load presupplied rules from these categories.
   # If empty or not specified, all presupplied rules are loaded.
-  # Available categories:
+  # Every subdirectory of the rules directory is a category;
+  # `planguard rules categories` lists them. Presupplied ones:
   #   - "aws": AWS provider rules (S3, IAM, RDS, EC2, etc.)
-  #   - "azure": Azure provider rules
   #   - "common": All common rules (security + tagging)
   #   - "security": Security-specific rules (exfiltration prevention)
   #   - "tagging": Resource tagging compliance rules
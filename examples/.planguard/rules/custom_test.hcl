@@ -0,0 +1,47 @@
+# Tests for the example custom rules. Run them with:
+#   planguard test examples/.planguard/rules
+
+test "large instance without approval is flagged" {
+  terraform = <<-EOT
+    resource "aws_instance" "batch" {
+      instance_type = "m5.4xlarge"
+    }
+  EOT
+
+  expect {
+    rule     = "expensive_instances_require_approval"
+    resource = "aws_instance.batch"
+    count    = 1
+  }
+}
+
+test "approved and small instances pass" {
+  rules = ["expensive_instances_require_approval"]
+
+  terraform = <<-EOT
+    resource "aws_instance" "batch" {
+      instance_type = "m5.4xlarge"
+      tags = {
+        CostApproval = "finance-team@example.com"
+      }
+    }
+
+    resource "aws_instance" "web" {
+      instance_type = "t3.micro"
+    }
+  EOT
+}
+
+test "resource names must be lowercase" {
+  rules = ["resource_naming_convention"]
+
+  terraform = <<-EOT
+    resource "aws_s3_bucket" "Logs" {}
+    resource "aws_s3_bucket" "audit_logs" {}
+  EOT
+
+  expect {
+    rule     = "resource_naming_convention"
+    resource = "aws_s3_bucket.Logs"
+  }
+}
@@ -0,0 +1,422 @@
+package anonymizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+var (
+	accessKeyPattern = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+	accountIDPattern = regexp.MustCompile(`\b\d{12}\b`)
+	ipv4Pattern      = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	emailPattern     = regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`)
+	tokenPattern     = regexp.MustCompile(`[A-Za-z0-9+/_\-]{32,}={0,2}`)
+
+	// Attribute names whose literal values are always redacted
+	secretAttributePattern = regexp.MustCompile(`(?i)(password|secret|token|private_key|access_key|api_key)`)
+)
+
+// Addresses that carry policy meaning and must survive anonymization
+var preservedIPs = map[string]bool{
+	"0.0.0.0":         true,
+	"127.0.0.1":       true,
+	"255.255.255.255": true,
+}
+
+// Anonymizer rewrites identifiers and sensitive-looking values in Terraform
+// sources while keeping the block structure intact. Replacements are
+// consistent across all files processed by the same Anonymizer, so
+// references between files keep resolving.
+type Anonymizer struct {
+	names   map[string]string
+	values  map[string]string
+	counter map[string]int
+}
+
+// New creates a new anonymizer instance
+func New() *Anonymizer {
+	return &Anonymizer{
+		names:   make(map[string]string),
+		values:  make(map[string]string),
+		counter: make(map[string]int),
+	}
+}
+
+// AnonymizeDirectory anonymizes all .tf and .tfvars files below dir and
+// writes them to outDir, mirroring the directory layout. It returns the
+// paths of the written files.
+func (a *Anonymizer) AnonymizeDirectory(dir, outDir string) ([]string, error) {
+	sources := make(map[string][]byte)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".terraform" || info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if ext != ".tf" && ext != ".tfvars" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sources[rel] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	anonymized, err := a.AnonymizeFiles(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, rel := range sortedKeys(anonymized) {
+		target := filepath.Join(outDir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", target, err)
+		}
+		if err := os.WriteFile(target, anonymized[rel], 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		written = append(written, target)
+	}
+
+	return written, nil
+}
+
+// AnonymizeFiles anonymizes a set of sources keyed by file name. All
+// declarations are collected before any file is rewritten so that
+// cross-file references are renamed consistently.
+func (a *Anonymizer) AnonymizeFiles(sources map[string][]byte) (map[string][]byte, error) {
+	files := make(map[string]*hclwrite.File)
+
+	for _, name := range sortedKeys(sources) {
+		file, diags := hclwrite.ParseConfig(sources[name], name, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, diags.Error())
+		}
+		files[name] = file
+		a.collect(file.BuildTokens(nil))
+	}
+
+	result := make(map[string][]byte)
+	for name, file := range files {
+		a.rewrite(file.BuildTokens(nil), filepath.Ext(name) == ".tfvars")
+		result[name] = hclwrite.Format(file.Bytes())
+	}
+
+	return result, nil
+}
+
+// collect records every declared name so references can be renamed
+func (a *Anonymizer) collect(tokens hclwrite.Tokens) {
+	walkHeaders(tokens, func(keyword string, labels []*hclwrite.Token) {
+		switch keyword {
+		case "resource", "data":
+			if len(labels) == 2 {
+				a.declare(keyword, keyword+"."+string(labels[0].Bytes)+"."+string(labels[1].Bytes))
+			}
+		case "variable", "module", "output":
+			if len(labels) == 1 {
+				a.declare(keyword, keyword+"."+string(labels[0].Bytes))
+			}
+		}
+	}, func(local *hclwrite.Token) {
+		a.declare("local", "local."+string(local.Bytes))
+	})
+}
+
+func (a *Anonymizer) declare(kind, key string) {
+	if _, exists := a.names[key]; exists {
+		return
+	}
+	a.counter[kind]++
+	prefix := kind
+	if kind == "variable" {
+		prefix = "var"
+	}
+	a.names[key] = fmt.Sprintf("%s_%d", prefix, a.counter[kind])
+}
+
+// rewrite renames declarations and references and scrubs literal values
+func (a *Anonymizer) rewrite(tokens hclwrite.Tokens, tfvars bool) {
+	walkHeaders(tokens, func(keyword string, labels []*hclwrite.Token) {
+		switch keyword {
+		case "resource", "data":
+			if len(labels) == 2 {
+				a.rename(labels[1], keyword+"."+string(labels[0].Bytes)+"."+string(labels[1].Bytes))
+			}
+		case "variable", "module", "output":
+			if len(labels) == 1 {
+				a.rename(labels[0], keyword+"."+string(labels[0].Bytes))
+			}
+		}
+	}, func(local *hclwrite.Token) {
+		a.rename(local, "local."+string(local.Bytes))
+	})
+
+	headerTokens := headerLabelTokens(tokens)
+	depth := 0
+	attribute := ""
+
+	for i, tok := range tokens {
+		switch tok.Type {
+		case hclsyntax.TokenOBrace:
+			depth++
+		case hclsyntax.TokenCBrace:
+			depth--
+		case hclsyntax.TokenNewline:
+			attribute = ""
+		case hclsyntax.TokenIdent:
+			if i+1 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenEqual {
+				attribute = string(tok.Bytes)
+				if tfvars && depth == 0 {
+					a.rename(tok, "variable."+attribute)
+				}
+				continue
+			}
+			if i > 0 && tokens[i-1].Type == hclsyntax.TokenDot {
+				continue
+			}
+			a.rewriteTraversal(tokens, i)
+		case hclsyntax.TokenQuotedLit, hclsyntax.TokenStringLit:
+			if headerTokens[tok] {
+				continue
+			}
+			if attribute != "" && secretAttributePattern.MatchString(attribute) && isSingleLiteral(tokens, i) {
+				tok.Bytes = []byte(a.replaceValue("secret", string(tok.Bytes)))
+				continue
+			}
+			tok.Bytes = []byte(a.scrub(string(tok.Bytes)))
+		case hclsyntax.TokenComment:
+			tok.Bytes = []byte(a.scrub(string(tok.Bytes)))
+		}
+	}
+}
+
+// rewriteTraversal renames the name segment of a reference starting at
+// tokens[i], e.g. var.name, module.name, data.type.name, or type.name.
+func (a *Anonymizer) rewriteTraversal(tokens hclwrite.Tokens, i int) {
+	segment := func(n int) *hclwrite.Token {
+		idx := i + n*2
+		if idx >= len(tokens) || tokens[idx-1].Type != hclsyntax.TokenDot || tokens[idx].Type != hclsyntax.TokenIdent {
+			return nil
+		}
+		return tokens[idx]
+	}
+
+	root := string(tokens[i].Bytes)
+	first := segment(1)
+	if first == nil {
+		return
+	}
+
+	switch root {
+	case "var":
+		a.rename(first, "variable."+string(first.Bytes))
+	case "local", "module":
+		a.rename(first, root+"."+string(first.Bytes))
+	case "data":
+		if second := segment(2); second != nil {
+			a.rename(second, "data."+string(first.Bytes)+"."+string(second.Bytes))
+		}
+	default:
+		a.rename(first, "resource."+root+"."+string(first.Bytes))
+	}
+}
+
+func (a *Anonymizer) rename(tok *hclwrite.Token, key string) {
+	if replacement, ok := a.names[key]; ok {
+		tok.Bytes = []byte(replacement)
+	}
+}
+
+// scrub replaces sensitive-looking substrings within a literal
+func (a *Anonymizer) scrub(text string) string {
+	text = accessKeyPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return a.replaceValue("access_key", match)
+	})
+	text = emailPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return a.replaceValue("email", match)
+	})
+	text = ipv4Pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if preservedIPs[match] {
+			return match
+		}
+		return a.replaceValue("ip", match)
+	})
+	text = accountIDPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return a.replaceValue("account", match)
+	})
+	text = tokenPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if !looksRandom(match) {
+			return match
+		}
+		return a.replaceValue("secret", match)
+	})
+	return text
+}
+
+// replaceValue returns a stable replacement for a sensitive value so that
+// equal inputs stay equal after anonymization
+func (a *Anonymizer) replaceValue(kind, original string) string {
+	key := kind + ":" + original
+	if replacement, ok := a.values[key]; ok {
+		return replacement
+	}
+
+	a.counter["value:"+kind]++
+	n := a.counter["value:"+kind]
+
+	var replacement string
+	switch kind {
+	case "access_key":
+		replacement = fmt.Sprintf("AKIAANONYMIZED%06d", n)
+	case "email":
+		replacement = fmt.Sprintf("user%d@example.com", n)
+	case "ip":
+		// 198.18.0.0/15 is reserved for benchmarking and never routable
+		replacement = fmt.Sprintf("198.%d.%d.%d", 18+(n>>16)&1, (n>>8)&0xff, n&0xff)
+	case "account":
+		replacement = fmt.Sprintf("%012d", 100000000000+n)
+	default:
+		replacement = fmt.Sprintf("REDACTED_%d", n)
+	}
+
+	a.values[key] = replacement
+	return replacement
+}
+
+// walkHeaders calls onHeader for every top-level block header and onLocal
+// for every attribute name declared inside a top-level locals block
+func walkHeaders(tokens hclwrite.Tokens, onHeader func(string, []*hclwrite.Token), onLocal func(*hclwrite.Token)) {
+	depth := 0
+	inLocals := false
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.Type {
+		case hclsyntax.TokenOBrace:
+			depth++
+		case hclsyntax.TokenCBrace:
+			depth--
+			if depth == 0 {
+				inLocals = false
+			}
+		case hclsyntax.TokenIdent:
+			if depth == 0 && (i == 0 || tokens[i-1].Type == hclsyntax.TokenNewline) {
+				keyword := string(tok.Bytes)
+				labels, end := readLabels(tokens, i+1)
+				if end < len(tokens) && tokens[end].Type == hclsyntax.TokenOBrace {
+					inLocals = keyword == "locals"
+					onHeader(keyword, labels)
+				}
+				continue
+			}
+			if inLocals && depth == 1 && tokens[i-1].Type == hclsyntax.TokenNewline &&
+				i+1 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenEqual {
+				onLocal(tok)
+			}
+		}
+	}
+}
+
+// readLabels reads block labels starting at tokens[start] and returns them
+// along with the index of the first token after the labels
+func readLabels(tokens hclwrite.Tokens, start int) ([]*hclwrite.Token, int) {
+	var labels []*hclwrite.Token
+	i := start
+	for i < len(tokens) {
+		switch tokens[i].Type {
+		case hclsyntax.TokenIdent:
+			labels = append(labels, tokens[i])
+			i++
+		case hclsyntax.TokenOQuote:
+			if i+2 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenQuotedLit && tokens[i+2].Type == hclsyntax.TokenCQuote {
+				labels = append(labels, tokens[i+1])
+				i += 3
+				continue
+			}
+			return labels, i
+		default:
+			return labels, i
+		}
+	}
+	return labels, i
+}
+
+// headerLabelTokens returns the set of tokens used as block labels so they
+// are not scrubbed as ordinary string values
+func headerLabelTokens(tokens hclwrite.Tokens) map[*hclwrite.Token]bool {
+	set := make(map[*hclwrite.Token]bool)
+	for i, tok := range tokens {
+		if tok.Type == hclsyntax.TokenIdent && (i == 0 || tokens[i-1].Type == hclsyntax.TokenNewline || tokens[i-1].Type == hclsyntax.TokenOBrace) {
+			labels, end := readLabels(tokens, i+1)
+			if end < len(tokens) && tokens[end].Type == hclsyntax.TokenOBrace {
+				for _, label := range labels {
+					set[label] = true
+				}
+			}
+		}
+	}
+	return set
+}
+
+// isSingleLiteral reports whether the quoted literal at tokens[i] is the
+// whole string, i.e. not part of a template with interpolations
+func isSingleLiteral(tokens hclwrite.Tokens, i int) bool {
+	return i > 0 && i+1 < len(tokens) &&
+		tokens[i-1].Type == hclsyntax.TokenOQuote &&
+		tokens[i+1].Type == hclsyntax.TokenCQuote
+}
+
+// looksRandom reports whether a long token mixes letter case and digits the
+// way generated keys and tokens do
+func looksRandom(s string) bool {
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && !strings.Contains(s, "__")
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,250 @@
+// Package codemod edits rule metadata in HCL files in place, for
+// catalog-wide changes such as downgrading every experimental rule to a
+// warning. Files are rewritten with hclwrite, so comments and layout
+// outside the edited attributes are kept.
+package codemod
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Clause is one comparison of a -where filter, e.g. tags contains
+// "experimental"
+type Clause struct {
+	Field string
+	Op    string // "==", "!=", or "contains"
+	Value string
+}
+
+// Filter selects rules whose fields satisfy all of its clauses
+type Filter []Clause
+
+// listFields are the rule fields holding several values; contains tests
+// membership for them and substrings for the others
+var listFields = map[string]bool{"tags": true, "references": true}
+
+// scalarFields are the rule fields filters can compare, and their values
+var scalarFields = map[string]func(config.Rule) string{
+	"id":            func(r config.Rule) string { return r.ID },
+	"name":          func(r config.Rule) string { return r.Name },
+	"severity":      func(r config.Rule) string { return r.Severity },
+	"resource_type": func(r config.Rule) string { return r.ResourceType },
+	"message":       func(r config.Rule) string { return r.Message },
+	"remediation":   func(r config.Rule) string { return deref(r.Remediation) },
+	"on_unknown":    func(r config.Rule) string { return deref(r.OnUnknown) },
+}
+
+// settable are the attributes assignments can change
+var settable = map[string]bool{
+	"name": true, "severity": true, "resource_type": true, "message": true,
+	"remediation": true, "on_unknown": true,
+}
+
+// ParseWhere parses a filter of clauses joined by "and", each a field, an
+// operator, and a value that may be quoted:
+//
+//	tags contains "experimental" and severity == error
+//
+// With ==, the value may be a glob (id == "aws_s3_*").
+func ParseWhere(where string) (Filter, error) {
+	var filter Filter
+	for _, part := range splitAnd(where) {
+		m := clausePattern.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("invalid clause %q (expected a field, ==, !=, or contains, and a value)", part)
+		}
+		clause := Clause{Field: m[1], Op: m[2], Value: strings.Trim(m[3], `"`)}
+
+		if _, ok := scalarFields[clause.Field]; !ok && !listFields[clause.Field] {
+			return nil, fmt.Errorf("unknown field %q in %q", clause.Field, part)
+		}
+		if clause.Op != "contains" {
+			if listFields[clause.Field] {
+				return nil, fmt.Errorf("%s holds several values; use contains in %q", clause.Field, part)
+			}
+			if _, err := path.Match(clause.Value, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in %q: %w", clause.Value, part, err)
+			}
+		}
+		filter = append(filter, clause)
+	}
+
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+	return filter, nil
+}
+
+// clausePattern matches a clause: field, operator, and value
+var clausePattern = regexp.MustCompile(`^(\w+)\s+(==|!=|contains)\s+(.+)$`)
+
+// splitAnd splits a filter on " and " outside quoted values
+func splitAnd(where string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(where); i++ {
+		switch {
+		case where[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(where[i:], " and "):
+			parts = append(parts, strings.TrimSpace(where[start:i]))
+			start = i + len(" and ")
+			i = start - 1
+		}
+	}
+	if last := strings.TrimSpace(where[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// Match reports whether rule satisfies every clause of the filter
+func (f Filter) Match(rule config.Rule) bool {
+	for _, clause := range f {
+		if !clause.match(rule) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Clause) match(rule config.Rule) bool {
+	if listFields[c.Field] {
+		values := rule.Tags
+		if c.Field == "references" {
+			values = rule.References
+		}
+		for _, v := range values {
+			if v == c.Value {
+				return true
+			}
+		}
+		return false
+	}
+
+	value := scalarFields[c.Field](rule)
+	switch c.Op {
+	case "contains":
+		return strings.Contains(value, c.Value)
+	default:
+		// Patterns were validated by ParseWhere
+		matched, _ := path.Match(c.Value, value)
+		return matched == (c.Op == "==")
+	}
+}
+
+// Assignment sets a rule attribute to a string value
+type Assignment struct {
+	Attribute string
+	Value     string
+}
+
+// ParseSet parses an attribute=value assignment
+func ParseSet(set string) (Assignment, error) {
+	attr, value, ok := strings.Cut(set, "=")
+	attr = strings.TrimSpace(attr)
+	if !ok || attr == "" {
+		return Assignment{}, fmt.Errorf("invalid assignment %q (expected attribute=value)", set)
+	}
+	if !settable[attr] {
+		return Assignment{}, fmt.Errorf("cannot set %q (expected name, severity, resource_type, message, remediation, or on_unknown)", attr)
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case attr == "severity" && value != "error" && value != "warning" && value != "info":
+		return Assignment{}, fmt.Errorf("invalid severity %q (expected error, warning, or info)", value)
+	case attr == "on_unknown" && value != "skip" && value != "violation":
+		return Assignment{}, fmt.Errorf("invalid on_unknown %q (expected skip or violation)", value)
+	}
+	return Assignment{Attribute: attr, Value: value}, nil
+}
+
+// Rewrite applies assignments to the rules in an HCL file that match
+// filter. It returns the new source, the IDs of the matching rules, and the
+// IDs of those it changed; rules that already have the assigned values are
+// left alone. Blocks other than rules, as in a config file, are kept as
+// they are.
+func Rewrite(filename string, src []byte, filter Filter, assignments []Assignment) (out []byte, matched, changed []string, err error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+	var decoded struct {
+		Rules  []config.Rule `hcl:"rule,block"`
+		Remain hcl.Body      `hcl:",remain"`
+	}
+	if diags := gohcl.DecodeBody(file.Body, nil, &decoded); diags.HasErrors() {
+		return nil, nil, nil, fmt.Errorf("failed to load rules from %s: %s", filename, diags.Error())
+	}
+
+	writable, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+
+	// Rule blocks appear in the same order in both parses
+	var blocks []*hclwrite.Block
+	for _, block := range writable.Body().Blocks() {
+		if block.Type() == "rule" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) != len(decoded.Rules) {
+		return nil, nil, nil, fmt.Errorf("failed to match rule blocks in %s", filename)
+	}
+
+	for i, rule := range decoded.Rules {
+		if !filter.Match(rule) {
+			continue
+		}
+		matched = append(matched, rule.ID)
+		body := blocks[i].Body()
+		modified := false
+		for _, a := range assignments {
+			if current, ok := currentValue(rule, a.Attribute); ok && current == a.Value {
+				continue
+			}
+			body.SetAttributeValue(a.Attribute, cty.StringVal(a.Value))
+			modified = true
+		}
+		if modified {
+			changed = append(changed, rule.ID)
+		}
+	}
+
+	if len(changed) == 0 {
+		return src, matched, nil, nil
+	}
+	return writable.Bytes(), matched, changed, nil
+}
+
+// currentValue returns a rule's value for a settable attribute, and false
+// when an optional attribute is not set
+func currentValue(rule config.Rule, attr string) (string, bool) {
+	switch attr {
+	case "remediation":
+		return deref(rule.Remediation), rule.Remediation != nil
+	case "on_unknown":
+		return deref(rule.OnUnknown), rule.OnUnknown != nil
+	default:
+		return scalarFields[attr](rule), true
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
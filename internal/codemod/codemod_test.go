@@ -0,0 +1,213 @@
+package codemod
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		where   string
+		want    Filter
+		wantErr string
+	}{
+		{where: `tags contains "experimental"`, want: Filter{{Field: "tags", Op: "contains", Value: "experimental"}}},
+		{
+			where: `severity == error and id != "aws_s3_*"`,
+			want:  Filter{{Field: "severity", Op: "==", Value: "error"}, {Field: "id", Op: "!=", Value: "aws_s3_*"}},
+		},
+		{where: `message contains "read and write"`, want: Filter{{Field: "message", Op: "contains", Value: "read and write"}}},
+		{where: `severity error`, wantErr: "invalid clause"},
+		{where: `owner == platform`, wantErr: `unknown field "owner"`},
+		{where: `tags == experimental`, wantErr: "use contains"},
+		{where: `id == "[aws"`, wantErr: "invalid pattern"},
+		{where: ``, wantErr: "empty filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			got, err := ParseWhere(tt.where)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseWhere() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWhere() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWhere() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	rule := config.Rule{
+		ID:           "aws_s3_versioning",
+		Severity:     "warning",
+		ResourceType: "aws_s3_bucket",
+		Message:      "Versioning should be enabled",
+		Tags:         []string{"experimental", "s3"},
+	}
+
+	tests := []struct {
+		where string
+		want  bool
+	}{
+		{`tags contains experimental`, true},
+		{`tags contains exp`, false},
+		{`references contains "https://example.com"`, false},
+		{`id == "aws_s3_*"`, true},
+		{`id != "aws_s3_*"`, false},
+		{`message contains Versioning and severity == warning`, true},
+		{`message contains Versioning and severity == error`, false},
+		{`on_unknown == ""`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			filter, err := ParseWhere(tt.where)
+			if err != nil {
+				t.Fatalf("ParseWhere() error = %v", err)
+			}
+			if got := filter.Match(rule); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		set     string
+		want    Assignment
+		wantErr string
+	}{
+		{set: "severity=warning", want: Assignment{Attribute: "severity", Value: "warning"}},
+		{set: "message = Use a private ACL", want: Assignment{Attribute: "message", Value: "Use a private ACL"}},
+		{set: "severity=critical", wantErr: "invalid severity"},
+		{set: "on_unknown=fail", wantErr: "invalid on_unknown"},
+		{set: "id=renamed", wantErr: `cannot set "id"`},
+		{set: "severity", wantErr: "expected attribute=value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.set, func(t *testing.T) {
+			got, err := ParseSet(tt.set)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSet() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSet() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSet() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	src := `settings {
+  fail_on_warning = true
+}
+
+# Still being tuned
+rule "s3_versioning" {
+  name          = "S3 versioning"
+  severity      = "error" # raised in 2024
+  resource_type = "aws_s3_bucket"
+  tags          = ["experimental"]
+
+  condition {
+    expression = "!has(self, \"versioning\")"
+  }
+
+  message = "Enable versioning"
+}
+
+rule "s3_acl" {
+  name          = "S3 ACL"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+
+  condition {
+    expression = "self.acl != \"private\""
+  }
+
+  message = "Use a private ACL"
+}
+
+rule "s3_logging" {
+  name          = "S3 logging"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  tags          = ["experimental"]
+
+  condition {
+    expression = "!has(self, \"logging\")"
+  }
+
+  message = "Enable logging"
+}
+`
+
+	filter, err := ParseWhere(`tags contains experimental`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignments := []Assignment{{Attribute: "severity", Value: "warning"}, {Attribute: "on_unknown", Value: "skip"}}
+
+	out, matched, changed, err := Rewrite("config.hcl", []byte(src), filter, assignments)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if want := []string{"s3_versioning", "s3_logging"}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("matched = %v, want %v", matched, want)
+	}
+	if want := []string{"s3_versioning", "s3_logging"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+
+	got := string(out)
+	for _, want := range []string{
+		"settings {\n  fail_on_warning = true\n}",
+		"# Still being tuned\nrule \"s3_versioning\" {",
+		"  severity      = \"warning\" # raised in 2024\n",
+		"  message    = \"Enable logging\"\n  on_unknown = \"skip\"\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in rewritten file:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, `severity      = "error"`) != 1 {
+		t.Errorf("Rules outside the filter should be unchanged:\n%s", got)
+	}
+
+	// Running it again changes nothing
+	_, matched, changed, err = Rewrite("config.hcl", out, filter, assignments)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if len(matched) != 2 || len(changed) != 0 {
+		t.Errorf("Second Rewrite() matched %v and changed %v, want 2 matched and none changed", matched, changed)
+	}
+}
+
+func TestRewriteInvalid(t *testing.T) {
+	filter := Filter{{Field: "id", Op: "==", Value: "*"}}
+	if _, _, _, err := Rewrite("rules.hcl", []byte("rule \"x\" {"), filter, nil); err == nil {
+		t.Error("Expected an error for invalid HCL")
+	}
+	if _, _, _, err := Rewrite("rules.hcl", []byte("rule \"x\" {\n  name = \"x\"\n}\n"), filter, nil); err == nil || !strings.Contains(err.Error(), "failed to load rules") {
+		t.Errorf("Rewrite() error = %v, want failed to load rules", err)
+	}
+}
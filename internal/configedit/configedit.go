@@ -0,0 +1,166 @@
+// Package configedit edits the exceptions in a planguard config file in
+// place. Only the edited blocks change, so comments and layout elsewhere
+// are kept.
+package configedit
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// PruneExpired removes the exception blocks whose expires_at date has
+// passed at now, with the comments directly above them. It returns the new
+// source and the removed exceptions, or src unchanged when none expired.
+// Only the lines of the removed blocks change: a blank line on both sides
+// of a removed block is collapsed into one, and everything else, such as
+// heredocs and the blank lines between other blocks, is kept as written.
+func PruneExpired(filename string, src []byte, now time.Time) (out []byte, removed []config.Exception, err error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+	var decoded struct {
+		Exceptions []config.Exception `hcl:"exception,block"`
+		Remain     hcl.Body           `hcl:",remain"`
+	}
+	if diags := gohcl.DecodeBody(file.Body, nil, &decoded); diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to load exceptions from %s: %s", filename, diags.Error())
+	}
+
+	// Exception blocks appear in the same order in the syntax tree
+	var blocks []*hclsyntax.Block
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type == "exception" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) != len(decoded.Exceptions) {
+		return nil, nil, fmt.Errorf("failed to match exception blocks in %s", filename)
+	}
+
+	next, atEnd := 0, false
+	for i, exception := range decoded.Exceptions {
+		if !exception.Expired(now) {
+			continue
+		}
+		start, end := blockLines(src, blocks[i].Range())
+		out = append(out, src[next:start]...)
+		next = end
+		atEnd = end == len(src)
+		removed = append(removed, exception)
+	}
+	if len(removed) == 0 {
+		return src, nil, nil
+	}
+	out = append(out, src[next:]...)
+	// A block removed from the end leaves the blank line above it behind
+	if atEnd {
+		if trimmed := bytes.TrimRight(out, "\n"); len(trimmed) > 0 {
+			out = append(trimmed, '\n')
+		} else {
+			out = trimmed
+		}
+	}
+	return out, removed, nil
+}
+
+// blockLines returns the byte range of the lines to remove with a block:
+// the block's own lines, the comment lines directly above it, and, when
+// the line above those is blank or there is none, the blank lines below
+// it, so that the blocks around it stay one blank line apart
+func blockLines(src []byte, rng hcl.Range) (start, end int) {
+	start = lineStart(src, rng.Start.Byte)
+	for start > 0 {
+		prev := lineStart(src, start-1)
+		if !isComment(src[prev:start]) {
+			break
+		}
+		start = prev
+	}
+
+	end = lineEnd(src, rng.End.Byte)
+	if start == 0 || isBlank(src[lineStart(src, start-1):start]) {
+		for end < len(src) {
+			next := lineEnd(src, end)
+			if !isBlank(src[end:next]) {
+				break
+			}
+			end = next
+		}
+	}
+	return start, end
+}
+
+// lineStart returns the offset of the start of the line containing offset
+func lineStart(src []byte, offset int) int {
+	return bytes.LastIndexByte(src[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset just past the newline ending the line
+// containing offset, or the end of src
+func lineEnd(src []byte, offset int) int {
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(src)
+}
+
+func isBlank(line []byte) bool {
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+func isComment(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return bytes.HasPrefix(line, []byte("#")) || bytes.HasPrefix(line, []byte("//"))
+}
+
+// AppendException adds an exception block to the end of a config file,
+// after a blank line
+func AppendException(src []byte, exception config.Exception) []byte {
+	out := bytes.TrimRight(src, "\n")
+	if len(out) > 0 {
+		out = append(out, "\n\n"...)
+	}
+	return append(out, config.FormatExceptions([]config.Exception{exception})...)
+}
+
+// ExceptionPath returns the path to write in an exception for file, a path
+// from a report relative to root (the repository root, or the directory
+// the scan ran in). Exceptions are matched against the paths scans of the
+// config's directory see, so the path is made relative to the directory
+// of the config file at configPath, or to the project directory holding
+// it for a config or exception file in .planguard. Files outside that
+// directory keep file.
+func ExceptionPath(configPath, root, file string) string {
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return file
+	}
+	if filepath.Base(dir) == config.ExceptionsDir && filepath.Base(filepath.Dir(dir)) == ".planguard" {
+		dir = filepath.Dir(dir)
+	}
+	if filepath.Base(dir) == ".planguard" {
+		dir = filepath.Dir(dir)
+	}
+
+	path := filepath.FromSlash(file)
+	if !filepath.IsAbs(path) {
+		if root, err = filepath.Abs(root); err != nil {
+			return file
+		}
+		path = filepath.Join(root, path)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,262 @@
+package configedit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestPruneExpired(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		src         string
+		want        string
+		wantRemoved []string // Reasons of the removed exceptions
+	}{
+		{
+			name: "expired exceptions are removed with their comments",
+			src: `settings {
+  fail_on = "error"
+}
+
+# Legacy bucket, to be migrated
+exception {
+  rules       = ["aws_s3_public_read"]
+  reason      = "Legacy"
+  expires_at  = "2024-06-01"
+  approved_by = "security"
+}
+
+# Still needed
+exception {
+  rules       = ["require_tags"]
+  reason      = "Scratch"
+  expires_at  = "2024-12-31"
+  approved_by = "platform"
+}
+
+exception {
+  rules       = ["aws_s3_versioning"]
+  reason      = "Old"
+  expires_at  = "2024-06-14"
+  approved_by = "platform"
+}
+`,
+			want: `settings {
+  fail_on = "error"
+}
+
+# Still needed
+exception {
+  rules       = ["require_tags"]
+  reason      = "Scratch"
+  expires_at  = "2024-12-31"
+  approved_by = "platform"
+}
+`,
+			wantRemoved: []string{"Legacy", "Old"},
+		},
+		{
+			name: "heredocs and blank lines elsewhere are kept",
+			src: `settings {
+  fail_on = "error"
+}
+
+
+exception {
+  rules       = ["require_tags"]
+  reason      = <<-EOT
+    Tags come from the account.
+
+
+    Reviewed quarterly.
+  EOT
+  approved_by = "platform"
+}
+
+exception {
+  rules       = ["aws_s3_versioning"]
+  reason      = "Old"
+  expires_at  = "2024-06-14"
+  approved_by = "platform"
+}
+
+// Scratch buckets
+exception {
+  rules       = ["aws_s3_public_read"]
+  reason      = "Scratch"
+  expires_at  = "2024-06-01"
+  approved_by = "security"
+}
+`,
+			want: `settings {
+  fail_on = "error"
+}
+
+
+exception {
+  rules       = ["require_tags"]
+  reason      = <<-EOT
+    Tags come from the account.
+
+
+    Reviewed quarterly.
+  EOT
+  approved_by = "platform"
+}
+`,
+			wantRemoved: []string{"Old", "Scratch"},
+		},
+		{
+			name: "first block",
+			src: `exception {
+  rules       = ["aws_s3_versioning"]
+  reason      = "Old"
+  expires_at  = "2024-06-14"
+  approved_by = "platform"
+}
+
+settings {
+  fail_on = "error"
+}
+`,
+			want: `settings {
+  fail_on = "error"
+}
+`,
+			wantRemoved: []string{"Old"},
+		},
+		{
+			name: "nothing expired",
+			src: `exception {
+  rules       = ["require_tags"]
+  reason      = "Permanent"
+  approved_by = "platform"
+}
+`,
+			want: `exception {
+  rules       = ["require_tags"]
+  reason      = "Permanent"
+  approved_by = "platform"
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, removed, err := PruneExpired("config.hcl", []byte(tt.src), now)
+			if err != nil {
+				t.Fatalf("PruneExpired() error = %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("PruneExpired() =\n%s\nwant\n%s", out, tt.want)
+			}
+			var reasons []string
+			for _, e := range removed {
+				reasons = append(reasons, e.Reason)
+			}
+			if len(reasons) != len(tt.wantRemoved) {
+				t.Fatalf("removed = %v, want %v", reasons, tt.wantRemoved)
+			}
+			for i := range reasons {
+				if reasons[i] != tt.wantRemoved[i] {
+					t.Errorf("removed = %v, want %v", reasons, tt.wantRemoved)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneExpiredInvalid(t *testing.T) {
+	if _, _, err := PruneExpired("config.hcl", []byte(`exception {`), time.Now()); err == nil {
+		t.Error("PruneExpired() error = nil for invalid HCL")
+	}
+	if _, _, err := PruneExpired("config.hcl", []byte("exception {\n  rules = [\"a\"]\n}\n"), time.Now()); err == nil {
+		t.Error("PruneExpired() error = nil for an exception without reason")
+	}
+}
+
+func TestAppendException(t *testing.T) {
+	ticket := "SEC-1"
+	exception := config.Exception{
+		Rules:         []string{"require_tags"},
+		Paths:         []string{"main.tf"},
+		ResourceNames: []string{"logs"},
+		Reason:        "Tagged by the account",
+		ApprovedBy:    "platform",
+		Ticket:        &ticket,
+	}
+	block := `exception {
+  rules          = ["require_tags"]
+  paths          = ["main.tf"]
+  resource_names = ["logs"]
+  reason         = "Tagged by the account"
+  approved_by    = "platform"
+  ticket         = "SEC-1"
+}
+`
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty file", "", block},
+		{"after settings", "settings {\n  fail_on = \"error\"\n}\n\n\n", "settings {\n  fail_on = \"error\"\n}\n\n" + block},
+		{"no trailing newline", "# config", "# config\n\n" + block},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(AppendException([]byte(tt.src), exception)); got != tt.want {
+				t.Errorf("AppendException() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceptionPath(t *testing.T) {
+	repo := t.TempDir()
+	subdir := filepath.Join(repo, "infra", "modules")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run from a subdirectory, where configs are found by relative path
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(subdir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		name       string
+		configPath string
+		file       string
+		want       string
+	}{
+		{"project config at the root", "../../.planguard/config.hcl", "infra/modules/main.tf", "infra/modules/main.tf"},
+		{"project config in a subdirectory", "../.planguard/config.hcl", "infra/modules/main.tf", "modules/main.tf"},
+		{"exception file", "../.planguard/exceptions/legacy.hcl", "infra/modules/main.tf", "modules/main.tf"},
+		{"config outside .planguard", "../config.hcl", "infra/modules/main.tf", "modules/main.tf"},
+		{"absolute report path", "../.planguard/config.hcl", filepath.ToSlash(filepath.Join(subdir, "main.tf")), "modules/main.tf"},
+		{"file outside the config's directory", "../.planguard/config.hcl", "global/main.tf", "global/main.tf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExceptionPath(tt.configPath, repo, tt.file); got != tt.want {
+				t.Errorf("ExceptionPath(%q, %q) = %q, want %q", tt.configPath, tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Package configmigrate upgrades config, exception, rule, and pack files
+// written for older versions of planguard, so they keep working as the
+// schema evolves. Files are rewritten with hclwrite, so comments and layout
+// outside the migrated attributes are kept.
+package configmigrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Change is one edit a migration made to a file
+type Change struct {
+	Migration string // Name of the migration, e.g. "former rule IDs"
+	Where     string // Block and attribute, e.g. settings.disabled_rules
+	Detail    string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s (%s)", c.Where, c.Detail, c.Migration)
+}
+
+// Options hold what migrations need to know about the loaded rules
+type Options struct {
+	Aliases config.Aliases // Former rule IDs and the current IDs they map to
+
+	// Categories are the rule categories of the rules directory. When nil,
+	// presupplied_rules_categories is left as it is.
+	Categories []string
+}
+
+// migration upgrades the attributes of one block, returning its changes
+type migration struct {
+	name  string
+	apply func(b *block, opts Options) []Change
+}
+
+// migrations run in order on every top-level block of a file
+var migrations = []migration{
+	{"former rule IDs", migrateRuleIDs},
+	{"unknown categories", migrateCategories},
+}
+
+// ruleListAttributes are the attributes, by block type, holding lists of
+// rule IDs
+var ruleListAttributes = map[string][]string{
+	"settings":  {"disabled_rules", "enabled_rules", "enable_rules"},
+	"profile":   {"disabled_rules", "enabled_rules", "enable_rules"},
+	"exception": {"rules"},
+	"rule":      {"superseded_by"},
+	"pack":      {"rules"},
+}
+
+// ruleMapAttributes are the attributes, by block type, holding maps keyed
+// by rule ID
+var ruleMapAttributes = map[string][]string{
+	"settings": {"risk_rule_weights", "max_active_exceptions_per_rule"},
+	"profile":  {"severities"},
+	"pack":     {"severities"},
+}
+
+// block is a top-level block being migrated, with the name changes report
+// it by
+type block struct {
+	*hclwrite.Block
+	name string
+}
+
+// Migrate upgrades the HCL file src to the current schema. It returns the
+// new source and the changes made, or src unchanged when there were none.
+func Migrate(filename string, src []byte, opts Options) (out []byte, changes []Change, err error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+
+	for _, b := range file.Body().Blocks() {
+		blk := &block{Block: b, name: blockName(b)}
+		for _, m := range migrations {
+			for _, change := range m.apply(blk, opts) {
+				change.Migration = m.name
+				changes = append(changes, change)
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return src, nil, nil
+	}
+	return file.Bytes(), changes, nil
+}
+
+// blockName names a block the way changes report it: its type followed by
+// its labels or, for an exception, the rules it covers
+func blockName(b *hclwrite.Block) string {
+	parts := []string{b.Type()}
+	for _, label := range b.Labels() {
+		parts = append(parts, fmt.Sprintf("%q", label))
+	}
+	if b.Type() == "exception" {
+		if attr := b.Body().GetAttribute("rules"); attr != nil {
+			if rules, ok := stringList(attr); ok {
+				parts = append(parts, "for", strings.Join(rules, ", "))
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// migrateRuleIDs replaces the former IDs of renamed rules, listed in their
+// aliases, with their current IDs wherever a rule is named by ID
+func migrateRuleIDs(b *block, opts Options) []Change {
+	if len(opts.Aliases) == 0 {
+		return nil
+	}
+	var changes []Change
+	for _, name := range ruleListAttributes[b.Type()] {
+		attr := b.Body().GetAttribute(name)
+		if attr == nil {
+			continue
+		}
+		tokens := attr.Expr().BuildTokens(nil)
+		for i, token := range tokens {
+			if !isStringLiteral(tokens, i) {
+				continue
+			}
+			if current, ok := opts.Aliases[string(token.Bytes)]; ok {
+				changes = append(changes, renamed(b, name, string(token.Bytes), current))
+				token.Bytes = []byte(current)
+			}
+		}
+	}
+
+	for _, name := range ruleMapAttributes[b.Type()] {
+		attr := b.Body().GetAttribute(name)
+		if attr == nil {
+			continue
+		}
+		tokens := attr.Expr().BuildTokens(nil)
+		keys := make(map[string]bool)
+		for i, token := range tokens {
+			if isKey(tokens, i) {
+				keys[string(token.Bytes)] = true
+			}
+		}
+		for i, token := range tokens {
+			if !isKey(tokens, i) {
+				continue
+			}
+			current, ok := opts.Aliases[string(token.Bytes)]
+			// Renaming a key the map already has would duplicate it
+			if !ok || keys[current] {
+				continue
+			}
+			changes = append(changes, renamed(b, name, string(token.Bytes), current))
+			keys[current] = true
+			token.Bytes = []byte(current)
+		}
+	}
+	return changes
+}
+
+func renamed(b *block, attribute, former, current string) Change {
+	return Change{
+		Where:  b.name + "." + attribute,
+		Detail: fmt.Sprintf("renamed %s to %s", former, current),
+	}
+}
+
+// migrateCategories removes the rule categories that do not exist, such
+// as azure, which early example configs suggested, from
+// presupplied_rules_categories. They select no rules. A list of nothing
+// but unknown categories is kept, since an empty one selects them all.
+func migrateCategories(b *block, opts Options) []Change {
+	if opts.Categories == nil || (b.Type() != "settings" && b.Type() != "profile") {
+		return nil
+	}
+	attr := b.Body().GetAttribute("presupplied_rules_categories")
+	if attr == nil {
+		return nil
+	}
+	selected, ok := stringList(attr)
+	if !ok {
+		return nil
+	}
+
+	known := make(map[string]bool, len(opts.Categories))
+	for _, category := range opts.Categories {
+		known[category] = true
+	}
+	var kept []cty.Value
+	var removed []string
+	for _, category := range selected {
+		if known[category] {
+			kept = append(kept, cty.StringVal(category))
+		} else {
+			removed = append(removed, category)
+		}
+	}
+	if len(removed) == 0 || len(kept) == 0 {
+		return nil
+	}
+
+	b.Body().SetAttributeValue("presupplied_rules_categories", cty.ListVal(kept))
+	sort.Strings(removed)
+	detail := fmt.Sprintf("removed %s, which is not a rule category", removed[0])
+	if len(removed) > 1 {
+		detail = fmt.Sprintf("removed %s, which are not rule categories", strings.Join(removed, ", "))
+	}
+	return []Change{{Where: b.name + ".presupplied_rules_categories", Detail: detail}}
+}
+
+// isStringLiteral reports whether tokens[i] is the whole content of a
+// quoted string
+func isStringLiteral(tokens hclwrite.Tokens, i int) bool {
+	return tokens[i].Type == hclsyntax.TokenQuotedLit &&
+		i > 0 && tokens[i-1].Type == hclsyntax.TokenOQuote &&
+		i+1 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenCQuote
+}
+
+// isKey reports whether tokens[i] is the key of an object element, an
+// identifier or quoted string followed by = or :
+func isKey(tokens hclwrite.Tokens, i int) bool {
+	next := i + 1
+	switch {
+	case tokens[i].Type == hclsyntax.TokenIdent:
+	case isStringLiteral(tokens, i):
+		next++
+	default:
+		return false
+	}
+	return next < len(tokens) && (tokens[next].Type == hclsyntax.TokenEqual || tokens[next].Type == hclsyntax.TokenColon)
+}
+
+// stringList returns the value of attr when it is a list of literal
+// strings
+func stringList(attr *hclwrite.Attribute) ([]string, bool) {
+	src := attr.Expr().BuildTokens(nil).Bytes()
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, false
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() || !value.CanIterateElements() {
+		return nil, false
+	}
+	var list []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if element.IsNull() || element.Type() != cty.String {
+			return nil, false
+		}
+		list = append(list, element.AsString())
+	}
+	return list, true
+}
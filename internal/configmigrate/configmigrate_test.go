@@ -0,0 +1,175 @@
+package configmigrate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestMigrate(t *testing.T) {
+	opts := Options{
+		Aliases:    config.Aliases{"s3_public": "s3_block_public_access", "old_tags": "s3_tags"},
+		Categories: []string{"aws", "gcp", "hygiene"},
+	}
+
+	tests := []struct {
+		name        string
+		src         string
+		opts        Options
+		want        string
+		wantChanges []string
+	}{
+		{
+			name: "settings",
+			src: `# Repository settings
+settings {
+  presupplied_rules_categories = ["aws", "azure"]
+  disabled_rules = [
+    "s3_public", # Public buckets are reviewed
+    "other",
+  ]
+  risk_rule_weights = {
+    old_tags    = 2
+    "s3_public" = 3
+  }
+}
+`,
+			opts: opts,
+			want: `# Repository settings
+settings {
+  presupplied_rules_categories = ["aws"]
+  disabled_rules = [
+    "s3_block_public_access", # Public buckets are reviewed
+    "other",
+  ]
+  risk_rule_weights = {
+    s3_tags                  = 2
+    "s3_block_public_access" = 3
+  }
+}
+`,
+			wantChanges: []string{
+				"settings.disabled_rules: renamed s3_public to s3_block_public_access (former rule IDs)",
+				"settings.risk_rule_weights: renamed old_tags to s3_tags (former rule IDs)",
+				"settings.risk_rule_weights: renamed s3_public to s3_block_public_access (former rule IDs)",
+				"settings.presupplied_rules_categories: removed azure, which is not a rule category (unknown categories)",
+			},
+		},
+		{
+			name: "exception and profile",
+			src: `exception {
+  rules  = ["old_tags"]
+  reason = "Tagged by the platform team"
+}
+
+profile "dev" {
+  enable_rules = ["old_tags"]
+  severities   = { s3_public = "info" }
+}
+`,
+			opts: opts,
+			want: `exception {
+  rules  = ["s3_tags"]
+  reason = "Tagged by the platform team"
+}
+
+profile "dev" {
+  enable_rules = ["s3_tags"]
+  severities   = { s3_block_public_access = "info" }
+}
+`,
+			wantChanges: []string{
+				"exception for old_tags.rules: renamed old_tags to s3_tags (former rule IDs)",
+				`profile "dev".enable_rules: renamed old_tags to s3_tags (former rule IDs)`,
+				`profile "dev".severities: renamed s3_public to s3_block_public_access (former rule IDs)`,
+			},
+		},
+		{
+			name: "rule and pack",
+			src: `rule "s3_acl" {
+  superseded_by = "s3_public"
+  message       = "s3_public"
+}
+
+pack "baseline" {
+  rules = ["s3_public"]
+}
+`,
+			opts: opts,
+			want: `rule "s3_acl" {
+  superseded_by = "s3_block_public_access"
+  message       = "s3_public"
+}
+
+pack "baseline" {
+  rules = ["s3_block_public_access"]
+}
+`,
+			wantChanges: []string{
+				`rule "s3_acl".superseded_by: renamed s3_public to s3_block_public_access (former rule IDs)`,
+				`pack "baseline".rules: renamed s3_public to s3_block_public_access (former rule IDs)`,
+			},
+		},
+		{
+			name: "current key kept",
+			src: `settings {
+  max_active_exceptions_per_rule = { old_tags = 1, s3_tags = 2 }
+}
+`,
+			opts: opts,
+			want: `settings {
+  max_active_exceptions_per_rule = { old_tags = 1, s3_tags = 2 }
+}
+`,
+		},
+		{
+			name: "only unknown categories",
+			src: `settings {
+  presupplied_rules_categories = ["azure"]
+}
+`,
+			opts: opts,
+			want: `settings {
+  presupplied_rules_categories = ["azure"]
+}
+`,
+		},
+		{
+			name: "no options",
+			src: `settings {
+  presupplied_rules_categories = ["aws", "azure"]
+  disabled_rules               = ["s3_public"]
+}
+`,
+			want: `settings {
+  presupplied_rules_categories = ["aws", "azure"]
+  disabled_rules               = ["s3_public"]
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, changes, err := Migrate("config.hcl", []byte(tt.src), tt.opts)
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Migrate() =\n%s\nwant\n%s", out, tt.want)
+			}
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("changes =\n%q\nwant\n%q", got, tt.wantChanges)
+			}
+		})
+	}
+
+	if _, _, err := Migrate("config.hcl", []byte("settings {"), opts); err == nil {
+		t.Error("Migrate() of invalid HCL: want error")
+	}
+}
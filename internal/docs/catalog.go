@@ -0,0 +1,175 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CatalogRule is a rule's entry in the machine-readable catalog
+type CatalogRule struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Severity     string            `json:"severity"`
+	ResourceType string            `json:"resource_type"`
+	Categories   []string          `json:"categories"`
+	Message      string            `json:"message"`
+	Remediation  string            `json:"remediation,omitempty"`
+	References   []string          `json:"references,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Compliance   map[string]string `json:"compliance,omitempty"`
+	Pack         string            `json:"pack,omitempty"`
+	Deprecated   bool              `json:"deprecated,omitempty"`
+	SupersededBy string            `json:"superseded_by,omitempty"`
+	Aliases      []string          `json:"aliases,omitempty"`
+	Owner        string            `json:"owner,omitempty"`
+	DocsURL      string            `json:"docs_url,omitempty"`
+	CISID        string            `json:"cis_id,omitempty"`
+	Created      string            `json:"created,omitempty"`
+}
+
+// Catalog lists the site's rules, sorted by ID, with their metadata
+func (s *Site) Catalog() []CatalogRule {
+	var catalog []CatalogRule
+	for _, rule := range s.Rules() {
+		entry := CatalogRule{
+			ID:           rule.ID,
+			Name:         rule.Name,
+			Severity:     rule.Severity,
+			ResourceType: rule.ResourceType,
+			Categories:   s.categoriesOf(rule.ID),
+			Message:      rule.Message,
+			References:   rule.References,
+			Tags:         rule.Tags,
+			Compliance:   rule.Compliance,
+			Aliases:      rule.Aliases,
+		}
+		if rule.Remediation != nil {
+			entry.Remediation = strings.TrimSpace(*rule.Remediation)
+		}
+		if rule.Pack != nil {
+			entry.Pack = rule.Pack.Name
+		}
+		entry.Deprecated = rule.IsDeprecated()
+		if rule.SupersededBy != nil {
+			entry.SupersededBy = *rule.SupersededBy
+		}
+		if rule.Metadata != nil {
+			entry.Owner = rule.Metadata.Owner
+			entry.DocsURL = rule.Metadata.DocsURL
+			entry.CISID = rule.Metadata.CISID
+			entry.Created = rule.Metadata.Created
+		}
+		catalog = append(catalog, entry)
+	}
+	return catalog
+}
+
+// CatalogJSON renders the catalog as JSON: the planguard version and the
+// rules
+func (s *Site) CatalogJSON() ([]byte, error) {
+	rules := s.Catalog()
+	if rules == nil {
+		rules = []CatalogRule{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Version string        `json:"version"`
+		Rules   []CatalogRule `json:"rules"`
+	}{s.Version, rules}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode catalog: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// CatalogMarkdown renders the catalog as Markdown: a table of the rules in
+// each category, then a section per rule
+func (s *Site) CatalogMarkdown() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("# %s\n\n", s.Title))
+	output.WriteString(fmt.Sprintf("%d rules", len(s.Rules())))
+	if s.Version != "" {
+		output.WriteString(fmt.Sprintf(", planguard %s", s.Version))
+	}
+	output.WriteString(".\n\n")
+
+	for _, category := range s.Categories {
+		output.WriteString(fmt.Sprintf("## %s\n\n", category.Name))
+		output.WriteString("| Rule | Severity | Resource type |\n")
+		output.WriteString("| --- | --- | --- |\n")
+		for _, rule := range category.Rules {
+			output.WriteString(fmt.Sprintf("| [%s](#%s) | %s | `%s` |\n", markdownText(rule.Name), rule.ID, rule.Severity, rule.ResourceType))
+		}
+		output.WriteString("\n")
+	}
+
+	output.WriteString("## Rules\n")
+	for _, rule := range s.Catalog() {
+		output.WriteString(fmt.Sprintf("\n<a id=\"%s\"></a>\n\n### %s\n\n", rule.ID, markdownText(rule.Name)))
+		output.WriteString(fmt.Sprintf("- **ID:** `%s`\n", rule.ID))
+		output.WriteString(fmt.Sprintf("- **Severity:** %s\n", rule.Severity))
+		output.WriteString(fmt.Sprintf("- **Resource type:** `%s`\n", rule.ResourceType))
+		output.WriteString(fmt.Sprintf("- **Categories:** %s\n", strings.Join(rule.Categories, ", ")))
+		if rule.SupersededBy != "" {
+			output.WriteString(fmt.Sprintf("- **Deprecated:** superseded by [`%s`](#%s)\n", rule.SupersededBy, rule.SupersededBy))
+		} else if rule.Deprecated {
+			output.WriteString("- **Deprecated:** yes\n")
+		}
+		if len(rule.Aliases) > 0 {
+			output.WriteString(fmt.Sprintf("- **Former IDs:** `%s`\n", strings.Join(rule.Aliases, "`, `")))
+		}
+		if len(rule.Tags) > 0 {
+			output.WriteString(fmt.Sprintf("- **Tags:** %s\n", strings.Join(rule.Tags, ", ")))
+		}
+		if len(rule.Compliance) > 0 {
+			frameworks := make([]string, 0, len(rule.Compliance))
+			for framework := range rule.Compliance {
+				frameworks = append(frameworks, framework)
+			}
+			sort.Strings(frameworks)
+			var controls []string
+			for _, framework := range frameworks {
+				controls = append(controls, fmt.Sprintf("%s %s", framework, rule.Compliance[framework]))
+			}
+			output.WriteString(fmt.Sprintf("- **Compliance:** %s\n", strings.Join(controls, ", ")))
+		}
+		if rule.Pack != "" {
+			output.WriteString(fmt.Sprintf("- **Pack:** %s\n", rule.Pack))
+		}
+		if rule.Owner != "" {
+			output.WriteString(fmt.Sprintf("- **Owner:** %s\n", markdownText(rule.Owner)))
+		}
+		if rule.DocsURL != "" {
+			output.WriteString(fmt.Sprintf("- **Docs:** <%s>\n", rule.DocsURL))
+		}
+		if rule.CISID != "" {
+			output.WriteString(fmt.Sprintf("- **CIS ID:** %s\n", markdownText(rule.CISID)))
+		}
+		if rule.Created != "" {
+			output.WriteString(fmt.Sprintf("- **Created:** %s\n", rule.Created))
+		}
+		output.WriteString(fmt.Sprintf("\n%s\n", strings.TrimSpace(rule.Message)))
+		if rule.Remediation != "" {
+			fence := "```"
+			for strings.Contains(rule.Remediation, fence) {
+				fence += "`"
+			}
+			output.WriteString(fmt.Sprintf("\n**Remediation:**\n\n%s\n%s\n%s\n", fence, rule.Remediation, fence))
+		}
+		if len(rule.References) > 0 {
+			output.WriteString("\n**References:**\n\n")
+			for _, reference := range rule.References {
+				output.WriteString(fmt.Sprintf("- <%s>\n", reference))
+			}
+		}
+	}
+	return output.String()
+}
+
+// markdownText escapes the characters that would break a table cell or
+// link text
+func markdownText(text string) string {
+	replacer := strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`)
+	return replacer.Replace(strings.TrimSpace(text))
+}
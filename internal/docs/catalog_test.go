@@ -0,0 +1,75 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := testSite().Catalog()
+	if len(catalog) != 2 {
+		t.Fatalf("Catalog() = %d rules, want 2", len(catalog))
+	}
+
+	public := catalog[0]
+	if public.ID != "aws_s3_public" || public.Remediation != "Set acl to private" || public.ResourceType != "aws_s3_bucket" {
+		t.Errorf("catalog[0] = %+v", public)
+	}
+	if strings.Join(public.Categories, ",") != "aws,common" {
+		t.Errorf("Categories = %v, want aws,common", public.Categories)
+	}
+	if tags := catalog[1]; tags.ID != "require_tags" || tags.Remediation != "" || strings.Join(tags.Categories, ",") != "common" {
+		t.Errorf("catalog[1] = %+v", tags)
+	}
+}
+
+func TestCatalogJSON(t *testing.T) {
+	data, err := testSite().CatalogJSON()
+	if err != nil {
+		t.Fatalf("CatalogJSON() error = %v", err)
+	}
+	var catalog struct {
+		Version string
+		Rules   []map[string]interface{}
+	}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if catalog.Version != "1.2.3" || len(catalog.Rules) != 2 {
+		t.Fatalf("catalog = %+v", catalog)
+	}
+	rule := catalog.Rules[0]
+	for _, key := range []string{"id", "name", "severity", "resource_type", "categories", "message", "remediation", "references", "compliance", "owner", "docs_url"} {
+		if _, ok := rule[key]; !ok {
+			t.Errorf("Expected %q in %v", key, rule)
+		}
+	}
+	if _, ok := catalog.Rules[1]["remediation"]; ok {
+		t.Errorf("Expected no remediation for a rule without one, got %v", catalog.Rules[1])
+	}
+
+	empty, err := (&Site{}).CatalogJSON()
+	if err != nil || !strings.Contains(string(empty), `"rules": []`) {
+		t.Errorf("CatalogJSON() of an empty site = %s, %v", empty, err)
+	}
+}
+
+func TestCatalogMarkdown(t *testing.T) {
+	output := testSite().CatalogMarkdown()
+	for _, want := range []string{
+		"# Policies\n\n2 rules, planguard 1.2.3.\n",
+		"## aws\n\n| Rule | Severity | Resource type |\n| --- | --- | --- |\n| [S3 bucket is public](#aws_s3_public) | error | `aws_s3_bucket` |\n",
+		"<a id=\"aws_s3_public\"></a>\n\n### S3 bucket is public\n",
+		"- **Categories:** aws, common\n",
+		"- **Former IDs:** `s3_public`\n",
+		"- **Compliance:** cis_aws 2.1.5, pci 1.3\n",
+		"- **Owner:** team-storage\n- **Docs:** <https://wiki.example.com/s3>\n",
+		"**Remediation:**\n\n```\nSet acl to private\n```\n",
+		"- <https://example.com/s3>\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+}
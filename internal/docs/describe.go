@@ -0,0 +1,120 @@
+package docs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Describe formats a rule's full definition as text, for planguard rules
+// describe. origin says which layer the rule came from, e.g. "presupplied
+// (rules/aws/s3.hcl)"; notes list what changed it after loading, such as a
+// severity map.
+func Describe(rule config.Rule, origin string, notes []string) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s: %s\n\n", rule.ID, strings.TrimSpace(rule.Name)))
+
+	fields := [][2]string{
+		{"Severity", rule.Severity},
+		{"Resource type", rule.ResourceType},
+		{"Origin", origin},
+	}
+	if rule.Pack != nil {
+		fields = append(fields, [2]string{"Pack", rule.Pack.Name})
+	}
+	if rule.IsDeprecated() {
+		fields = append(fields, [2]string{"Status", rule.Deprecation()})
+	}
+	if len(rule.Aliases) > 0 {
+		fields = append(fields, [2]string{"Former IDs", strings.Join(rule.Aliases, ", ")})
+	}
+	if rule.Metadata != nil {
+		for _, field := range [][2]string{
+			{"Owner", rule.Metadata.Owner},
+			{"Docs", rule.Metadata.DocsURL},
+			{"CIS ID", rule.Metadata.CISID},
+			{"Created", rule.Metadata.Created},
+		} {
+			if field[1] != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(rule.Tags) > 0 {
+		fields = append(fields, [2]string{"Tags", strings.Join(rule.Tags, ", ")})
+	}
+	if len(rule.Compliance) > 0 {
+		frameworks := make([]string, 0, len(rule.Compliance))
+		for framework := range rule.Compliance {
+			frameworks = append(frameworks, framework)
+		}
+		sort.Strings(frameworks)
+		var controls []string
+		for _, framework := range frameworks {
+			controls = append(controls, fmt.Sprintf("%s %s", framework, rule.Compliance[framework]))
+		}
+		fields = append(fields, [2]string{"Compliance", strings.Join(controls, ", ")})
+	}
+	onUnknown := "skip"
+	if rule.OnUnknown != nil {
+		onUnknown = *rule.OnUnknown
+	}
+	fields = append(fields, [2]string{"Unknown values", onUnknown})
+	for _, field := range fields {
+		output.WriteString(fmt.Sprintf("  %-15s %s\n", field[0]+":", field[1]))
+	}
+	for _, note := range notes {
+		output.WriteString(fmt.Sprintf("  Note: %s\n", note))
+	}
+
+	if rule.When != nil {
+		output.WriteString("\nWhen (the rule only applies to resources where this is true):\n")
+		output.WriteString(indentText(rule.When.Expression))
+	}
+	output.WriteString("\nConditions (a violation when any is true):\n")
+	for i, condition := range rule.Conditions {
+		if i > 0 {
+			output.WriteString("  --\n")
+		}
+		output.WriteString(indentText(condition.Expression))
+	}
+
+	output.WriteString("\nMessage:\n")
+	output.WriteString(indentText(rule.Message))
+	for _, section := range []struct {
+		title string
+		text  *string
+	}{
+		{"Remediation", rule.Remediation},
+		{"Passing example", rule.ExamplePass},
+		{"Failing example", rule.ExampleFail},
+	} {
+		if section.text != nil {
+			output.WriteString(fmt.Sprintf("\n%s:\n", section.title))
+			output.WriteString(indentText(*section.text))
+		}
+	}
+	if len(rule.References) > 0 {
+		output.WriteString("\nReferences:\n")
+		for _, reference := range rule.References {
+			output.WriteString(fmt.Sprintf("  %s\n", reference))
+		}
+	}
+	return output.String()
+}
+
+// indentText indents each line of text by two spaces, dropping leading and
+// trailing blank lines
+func indentText(text string) string {
+	var output strings.Builder
+	for _, line := range strings.Split(strings.Trim(text, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			output.WriteString("\n")
+			continue
+		}
+		output.WriteString("  " + strings.TrimRight(line, " \t") + "\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,44 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	site := testSite()
+	public := site.Categories[0].Rules[0]
+	output := Describe(public, "presupplied (rules/aws/s3.hcl)", []string{"severity changed from warning to error by severity map"})
+	for _, want := range []string{
+		"aws_s3_public: S3 bucket is public\n\n",
+		"  Severity:       error\n",
+		"  Resource type:  aws_s3_bucket\n",
+		"  Origin:         presupplied (rules/aws/s3.hcl)\n",
+		"  Former IDs:     s3_public\n",
+		"  Owner:          team-storage\n",
+		"  Docs:           https://wiki.example.com/s3\n",
+		"  Compliance:     cis_aws 2.1.5, pci 1.3\n",
+		"  Unknown values: skip\n",
+		"  Note: severity changed from warning to error by severity map\n",
+		"Conditions (a violation when any is true):\n  self.acl == \"public-read\"\n",
+		"Remediation:\n  Set acl to private\n",
+		"Failing example:\n  acl = \"public-read\"\n",
+		"References:\n  https://example.com/s3\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "When") {
+		t.Errorf("Expected no when section for a rule without one:\n%s", output)
+	}
+
+	tags := site.Categories[1].Rules[1]
+	output = Describe(tags, "custom (.planguard/config.hcl)", nil)
+	if !strings.Contains(output, "When (the rule only applies to resources where this is true):\n  has(self, \"tags\")\n") {
+		t.Errorf("Expected the when expression in output:\n%s", output)
+	}
+	if strings.Contains(output, "Remediation") || strings.Contains(output, "Note:") {
+		t.Errorf("Expected no remediation or notes:\n%s", output)
+	}
+}
@@ -0,0 +1,182 @@
+// Package docs renders the loaded rules as a static HTML site that can be
+// browsed offline, e.g. hosted on an internal server in an air-gapped
+// environment.
+package docs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+//go:embed templates/*.html templates/style.css
+var templates embed.FS
+
+// Category is a named group of rules, such as a presupplied rule category
+type Category struct {
+	Name  string
+	Rules []config.Rule
+}
+
+// Site describes the documentation to render
+type Site struct {
+	Title      string
+	Version    string
+	Generated  time.Time // Shown on every page and used as the archive's file times
+	Categories []Category
+}
+
+// page is the data a page template renders. Root is the relative path
+// from the page back to the site's root directory.
+type page struct {
+	Site       *Site
+	Root       string
+	Category   Category
+	Rule       config.Rule
+	Categories []string // Categories containing Rule
+}
+
+// Rules returns every rule in the site's categories once, sorted by ID
+func (s *Site) Rules() []config.Rule {
+	seen := make(map[string]bool)
+	var rules []config.Rule
+	for _, category := range s.Categories {
+		for _, rule := range category.Rules {
+			if !seen[rule.ID] {
+				seen[rule.ID] = true
+				rules = append(rules, rule)
+			}
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// categoriesOf returns the names of the categories containing a rule
+func (s *Site) categoriesOf(ruleID string) []string {
+	var names []string
+	for _, category := range s.Categories {
+		for _, rule := range category.Rules {
+			if rule.ID == ruleID {
+				names = append(names, category.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Pages renders the site, returning each file's contents by its path
+// within the site. Pages link to each other with relative paths, so the
+// site works from any directory or straight from disk.
+func (s *Site) Pages() (map[string][]byte, error) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"ruleFile": ruleFile,
+		"dict":     dict,
+	}).ParseFS(templates, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	pages := make(map[string][]byte)
+	render := func(path, name string, data interface{}) error {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		pages[path] = buf.Bytes()
+		return nil
+	}
+
+	if err := render("index.html", "index.html", page{Site: s}); err != nil {
+		return nil, err
+	}
+	for _, category := range s.Categories {
+		data := page{Site: s, Root: "../", Category: category}
+		if err := render("categories/"+category.Name+".html", "category.html", data); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range s.Rules() {
+		data := page{Site: s, Root: "../", Rule: rule, Categories: s.categoriesOf(rule.ID)}
+		if err := render("rules/"+ruleFile(rule.ID), "rule.html", data); err != nil {
+			return nil, err
+		}
+	}
+
+	style, err := templates.ReadFile("templates/style.css")
+	if err != nil {
+		return nil, err
+	}
+	pages["style.css"] = style
+
+	return pages, nil
+}
+
+// WriteBundle writes the rendered site to w as a gzipped tar archive with
+// its files under a site/ directory
+func (s *Site) WriteBundle(w io.Writer) error {
+	pages, err := s.Pages()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(pages))
+	for path := range pages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, path := range paths {
+		header := &tar.Header{
+			Name:    "site/" + path,
+			Mode:    0644,
+			Size:    int64(len(pages[path])),
+			ModTime: s.Generated,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if _, err := tw.Write(pages[path]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return gz.Close()
+}
+
+// ruleFile returns the file name of a rule's page
+func ruleFile(ruleID string) string {
+	return template.URLQueryEscaper(ruleID) + ".html"
+}
+
+// dict builds a map from alternating keys and values, for passing several
+// values to a nested template
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict needs key and value pairs")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings")
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
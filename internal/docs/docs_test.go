@@ -0,0 +1,125 @@
+package docs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func testSite() *Site {
+	remediation := "Set acl to private"
+	examplePass := `acl = "private"`
+	exampleFail := `acl = "public-read"`
+	public := config.Rule{
+		ID:           "aws_s3_public",
+		Name:         "S3 bucket is public",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: `self.acl == "public-read"`}},
+		Message:      "Bucket must not be public",
+		Remediation:  &remediation,
+		ExamplePass:  &examplePass,
+		ExampleFail:  &exampleFail,
+		References:   []string{"https://example.com/s3"},
+		Compliance:   map[string]string{"cis_aws": "2.1.5", "pci": "1.3"},
+		Aliases:      []string{"s3_public"},
+		Metadata:     &config.RuleMetadata{Owner: "team-storage", DocsURL: "https://wiki.example.com/s3"},
+	}
+	tags := config.Rule{
+		ID:           "require_tags",
+		Name:         "Resources need tags",
+		Severity:     "warning",
+		ResourceType: "*",
+		When:         &config.WhenBlock{Expression: "has(self, \"tags\")"},
+		Conditions:   []config.Condition{{Expression: "length(self.tags) == 0"}},
+		Message:      "Add tags",
+	}
+	return &Site{
+		Title:     "Policies",
+		Version:   "1.2.3",
+		Generated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Categories: []Category{
+			{Name: "aws", Rules: []config.Rule{public}},
+			{Name: "common", Rules: []config.Rule{public, tags}},
+		},
+	}
+}
+
+func TestPages(t *testing.T) {
+	pages, err := testSite().Pages()
+	if err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"index.html", []string{`href="categories/aws.html"`, `href="rules/require_tags.html"`, "2 rules in 2 categories", `href="style.css"`}},
+		{"categories/common.html", []string{`href="../rules/aws_s3_public.html"`, `href="../index.html"`, "require_tags"}},
+		{"rules/aws_s3_public.html", []string{"S3 bucket is public", "Set acl to private", `href="../categories/aws.html"`, `href="../categories/common.html"`, "https://example.com/s3", "self.acl == &#34;public-read&#34;", "planguard 1.2.3 on 2024-05-01", `<pre class="example pass">acl = &#34;private&#34;</pre>`, "<code>cis_aws</code> 2.1.5</div><div><code>pci</code> 1.3", "<dt>Owner</dt><dd>team-storage</dd>", "<dt>Former IDs</dt><dd><code>s3_public</code></dd>", `<a href="https://wiki.example.com/s3">`}},
+		{"rules/require_tags.html", []string{"Applies when", "length(self.tags) == 0"}},
+		{"style.css", []string{"body"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			page, ok := pages[tt.path]
+			if !ok {
+				t.Fatalf("Pages() has no %s, got %d pages", tt.path, len(pages))
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(page), want) {
+					t.Errorf("%s does not contain %q:\n%s", tt.path, want, page)
+				}
+			}
+		})
+	}
+	if len(pages) != 6 {
+		t.Errorf("Pages() returned %d pages, want 6", len(pages))
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	site := testSite()
+	var first, second bytes.Buffer
+	if err := site.WriteBundle(&first); err != nil {
+		t.Fatalf("WriteBundle() error = %v", err)
+	}
+	if err := site.WriteBundle(&second); err != nil {
+		t.Fatalf("WriteBundle() error = %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("WriteBundle() is not deterministic")
+	}
+
+	gz, err := gzip.NewReader(&first)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading archive: %v", err)
+		}
+		if !header.ModTime.Equal(site.Generated) {
+			t.Errorf("%s ModTime = %v, want %v", header.Name, header.ModTime, site.Generated)
+		}
+		names = append(names, header.Name)
+	}
+
+	want := "site/categories/aws.html site/categories/common.html site/index.html site/rules/aws_s3_public.html site/rules/require_tags.html site/style.css"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("archive files = %s, want %s", got, want)
+	}
+}
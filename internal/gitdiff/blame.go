@@ -0,0 +1,88 @@
+package gitdiff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameLine describes the commit that last changed a line
+type BlameLine struct {
+	Commit      string
+	Author      string
+	AuthorEmail string
+	Date        time.Time // Author date
+	Uncommitted bool      // The line has changes not yet committed
+}
+
+// uncommittedCommit is the commit git blame reports for lines changed in the
+// working tree
+const uncommittedCommit = "0000000000000000000000000000000000000000"
+
+// Blame runs git blame on a file and returns the commit that last changed
+// each line, indexed by line number (index 0 is unused)
+func Blame(path string) ([]BlameLine, error) {
+	output, err := git(filepath.Dir(path), "blame", "--line-porcelain", "--", filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s: %w", path, err)
+	}
+	return parseBlame(output)
+}
+
+// parseBlame parses git blame --line-porcelain output, in which each line
+// of the file is preceded by a header naming its commit and the commit's
+// details
+func parseBlame(output string) ([]BlameLine, error) {
+	lines := []BlameLine{{}}
+	var current BlameLine
+	var finalLine int
+	inHeader := false
+
+	for _, text := range strings.Split(output, "\n") {
+		if !inHeader {
+			if text == "" {
+				continue
+			}
+			// "<commit> <original line> <final line> [<group size>]"
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("unexpected blame header %q", text)
+			}
+			line, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected blame header %q", text)
+			}
+			current = BlameLine{Commit: fields[0], Uncommitted: fields[0] == uncommittedCommit}
+			finalLine = line
+			inHeader = true
+			continue
+		}
+
+		// The file's line, prefixed with a tab, ends the header
+		if strings.HasPrefix(text, "\t") {
+			for len(lines) <= finalLine {
+				lines = append(lines, BlameLine{})
+			}
+			lines[finalLine] = current
+			inHeader = false
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				current.Date = time.Unix(seconds, 0).UTC()
+			}
+		}
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,69 @@
+package gitdiff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	run := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	path := filepath.Join(repo, "infra", "main.tf")
+	write := func(content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("", "init", "-q", "-b", "main")
+	write("resource \"aws_s3_bucket\" \"a\" {\n}\n")
+	run("2021-03-04T10:00:00Z", "add", ".")
+	run("2021-03-04T10:00:00Z", "commit", "-q", "-m", "initial")
+	write("resource \"aws_s3_bucket\" \"a\" {\n}\nresource \"aws_s3_bucket\" \"b\" {}\n")
+
+	lines, err := Blame(path)
+	if err != nil {
+		t.Fatalf("Blame() error = %v", err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("Blame() returned %d lines, want 4 (3 lines and the unused index 0)", len(lines))
+	}
+
+	first := lines[1]
+	if first.Author != "Jane Doe" || first.AuthorEmail != "jane@example.com" || first.Uncommitted || len(first.Commit) != 40 {
+		t.Errorf("lines[1] = %+v, want a commit by Jane Doe", first)
+	}
+	if want := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC); !first.Date.Equal(want) {
+		t.Errorf("lines[1].Date = %v, want %v", first.Date, want)
+	}
+	if !lines[3].Uncommitted {
+		t.Errorf("lines[3] = %+v, want uncommitted", lines[3])
+	}
+
+	if _, err := Blame(filepath.Join(t.TempDir(), "main.tf")); err == nil {
+		t.Error("Blame() outside a repository should fail")
+	}
+}
@@ -0,0 +1,71 @@
+package gitdiff
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChangedFiles returns the Terraform files under dir that changed in the
+// git working tree, staged or not, including untracked files. Deleted files
+// are omitted. Paths are joined onto dir, matching the paths the parser
+// produces when walking dir.
+//
+// With an empty since, changes are relative to HEAD. Otherwise they are
+// relative to the merge base of since and HEAD, so on a branch only the
+// branch's own changes are listed (e.g. since = "origin/main").
+func ChangedFiles(dir, since string) ([]string, error) {
+	base := "HEAD"
+	if since != "" {
+		mergeBase, err := git(dir, "merge-base", since, "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find merge base with %s: %w", since, err)
+		}
+		base = strings.TrimSpace(mergeBase)
+	}
+
+	// --relative limits the diff to dir and prints paths relative to it,
+	// like ls-files does by default
+	diff, err := git(dir, "diff", "--name-only", "--relative", "--diff-filter=d", "-z", base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, name := range strings.Split(diff+untracked, "\x00") {
+		if filepath.Ext(name) != ".tf" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// git runs a git command in dir and returns its standard output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,112 @@
+package gitdiff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("infra/main.tf", `resource "aws_s3_bucket" "a" {}`)
+	write("infra/old.tf", `resource "aws_s3_bucket" "old" {}`)
+	write("infra/unchanged.tf", `resource "aws_s3_bucket" "b" {}`)
+	write("other/main.tf", `resource "aws_s3_bucket" "c" {}`)
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+
+	run("checkout", "-q", "-b", "feature")
+	write("infra/main.tf", `resource "aws_s3_bucket" "a" { bucket = "a" }`)
+	run("commit", "-q", "-am", "change main")
+
+	// Working tree changes: modified, deleted, untracked, and non-Terraform
+	write("infra/unchanged.tf", `resource "aws_s3_bucket" "b" { bucket = "b" }`)
+	write("infra/new.tf", `resource "aws_s3_bucket" "new" {}`)
+	write("infra/README.md", "docs")
+	write("other/main.tf", `resource "aws_s3_bucket" "c" { bucket = "c" }`)
+	if err := os.Remove(filepath.Join(repo, "infra", "old.tf")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(repo, "infra")
+
+	tests := []struct {
+		name  string
+		since string
+		want  []string
+	}{
+		{
+			name:  "working tree",
+			since: "",
+			want:  []string{"new.tf", "unchanged.tf"},
+		},
+		{
+			name:  "since ref",
+			since: "main",
+			want:  []string{"main.tf", "new.tf", "unchanged.tf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChangedFiles(dir, tt.since)
+			if err != nil {
+				t.Fatalf("ChangedFiles() error = %v", err)
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ChangedFiles() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestChangedFilesUnknownRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, output)
+	}
+
+	if _, err := ChangedFiles(repo, "does-not-exist"); err == nil {
+		t.Error("Expected error for unknown ref")
+	}
+}
@@ -0,0 +1,28 @@
+package gitdiff
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// RepoRoot returns the root of the git repository containing dir: the
+// nearest directory, dir or one of its parents, that has a .git entry.
+// Worktrees and submodules, whose .git is a file, count too. It reports
+// false when dir is not in a repository.
+func RepoRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
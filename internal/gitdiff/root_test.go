@@ -0,0 +1,48 @@
+package gitdiff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepoRoot(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	worktree := filepath.Join(tmp, "worktree")
+	for _, dir := range []string{filepath.Join(repo, ".git"), filepath.Join(repo, "infra", "prod"), filepath.Join(worktree, "infra"), filepath.Join(tmp, "plain")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Worktrees and submodules have a .git file pointing at the repository
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../repo/.git/worktrees/worktree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		want   string
+		wantOK bool
+	}{
+		{name: "repository root", dir: repo, want: repo, wantOK: true},
+		{name: "nested directory", dir: filepath.Join(repo, "infra", "prod"), want: repo, wantOK: true},
+		{name: "worktree", dir: filepath.Join(worktree, "infra"), want: worktree, wantOK: true},
+		{name: "outside a repository", dir: filepath.Join(tmp, "plain")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RepoRoot(tt.dir)
+			// The temporary directory may itself be inside a repository
+			if !tt.wantOK && ok && strings.HasPrefix(got, tmp) {
+				t.Errorf("RepoRoot(%s) = %s, want none", tt.dir, got)
+			}
+			if tt.wantOK && (!ok || got != tt.want) {
+				t.Errorf("RepoRoot(%s) = %s, %v, want %s", tt.dir, got, ok, tt.want)
+			}
+		})
+	}
+}
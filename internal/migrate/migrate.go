@@ -0,0 +1,506 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Tools that suppressions can be migrated from
+const (
+	FromTfsec   = "tfsec"
+	FromCheckov = "checkov"
+)
+
+// RuleMappings maps each tool's check IDs to the planguard rules covering
+// the same issue. Checks without an equivalent are reported as unmapped.
+var RuleMappings = map[string]map[string][]string{
+	FromTfsec: {
+		"aws-s3-enable-versioning":              {"aws_s3_versioning"},
+		"aws-s3-no-public-access-with-acl":      {"aws_s3_public_read", "aws_s3_public_readwrite"},
+		"aws-rds-encrypt-instance-storage-data": {"aws_rds_encryption"},
+		"aws-rds-no-public-db-access":           {"aws_rds_public_access"},
+		"aws-rds-specify-backup-retention":      {"aws_rds_backup_retention"},
+		"aws-ec2-enforce-http-token-imds":       {"aws_ec2_imdsv2"},
+		"aws-ec2-no-public-ingress-sgr":         {"aws_security_group_ingress_all"},
+		"aws-vpc-no-public-ingress-sgr":         {"aws_security_group_ingress_all"},
+		"aws-iam-no-policy-wildcards":           {"aws_iam_wildcard_actions", "aws_iam_admin_policy"},
+	},
+	FromCheckov: {
+		"CKV_AWS_21":  {"aws_s3_versioning"},
+		"CKV_AWS_20":  {"aws_s3_public_read"},
+		"CKV_AWS_57":  {"aws_s3_public_readwrite"},
+		"CKV_AWS_16":  {"aws_rds_encryption"},
+		"CKV_AWS_17":  {"aws_rds_public_access"},
+		"CKV_AWS_133": {"aws_rds_backup_retention"},
+		"CKV_AWS_79":  {"aws_ec2_imdsv2"},
+		"CKV_AWS_24":  {"aws_security_group_ingress_all"},
+		"CKV_AWS_25":  {"aws_security_group_ingress_all"},
+		"CKV_AWS_260": {"aws_security_group_ingress_all"},
+		"CKV_AWS_1":   {"aws_iam_wildcard_actions"},
+		"CKV_AWS_63":  {"aws_iam_wildcard_actions", "aws_iam_admin_policy"},
+	},
+}
+
+// toolSeverities maps tool severities onto planguard severities
+var toolSeverities = map[string]string{
+	"CRITICAL": "error",
+	"HIGH":     "error",
+	"MEDIUM":   "warning",
+	"LOW":      "info",
+}
+
+var (
+	tfsecIgnorePattern  = regexp.MustCompile(`(?:tfsec|trivy):ignore:([A-Za-z0-9_.\-]+)(?:\[[^\]]*\])?(?::exp:(\d{4}-\d{2}-\d{2}))?`)
+	checkovSkipPattern  = regexp.MustCompile(`checkov:skip=([A-Za-z0-9_]+)(?::(.*))?`)
+	commentOrBlankLines = regexp.MustCompile(`^\s*(#.*|//.*)?$`)
+)
+
+// Suppression is a check suppressed in a tool's configuration or by an
+// inline annotation
+type Suppression struct {
+	CheckID   string
+	File      string // Empty for suppressions that apply everywhere
+	Line      int
+	Resource  string // Name of the annotated resource, empty for config suppressions
+	Reason    string
+	ExpiresAt string
+}
+
+// Result is the outcome of a migration
+type Result struct {
+	Exceptions        []config.Exception
+	SeverityOverrides map[string]string // Planguard rule ID to severity
+	Unmapped          []Suppression     // Checks with no planguard equivalent
+	Unattached        []Suppression     // Annotations not attached to any block
+	Suppressions      int               // Suppressions found in total
+}
+
+// Migrate reads the suppressions of the given tool under dir and converts
+// them into planguard exceptions. Inline annotations become exceptions
+// scoped to the annotated file and resource; paths are written as a scan of
+// the same directory reports them. Exceptions require an approver, so every
+// migrated exception is attributed to approvedBy.
+func Migrate(from, dir, approvedBy string) (*Result, error) {
+	mappings, ok := RuleMappings[from]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tool %q (expected %s or %s)", from, FromTfsec, FromCheckov)
+	}
+
+	var suppressions []Suppression
+	overrides := make(map[string]string)
+
+	switch from {
+	case FromTfsec:
+		excluded, severities, err := loadTfsecConfig(dir)
+		if err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, excluded...)
+		for checkID, severity := range severities {
+			for _, ruleID := range mappings[checkID] {
+				overrides[ruleID] = severity
+			}
+		}
+	case FromCheckov:
+		skipped, err := loadCheckovConfig(dir)
+		if err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, skipped...)
+	}
+
+	result := &Result{SeverityOverrides: overrides}
+
+	inline, unattached, err := scanAnnotations(from, dir)
+	if err != nil {
+		return nil, err
+	}
+	suppressions = append(suppressions, inline...)
+	result.Unattached = unattached
+	result.Suppressions = len(suppressions) + len(unattached)
+
+	for _, s := range suppressions {
+		rules, ok := mappings[s.CheckID]
+		if !ok {
+			result.Unmapped = append(result.Unmapped, s)
+			continue
+		}
+
+		exception := config.Exception{
+			Rules:      rules,
+			Reason:     s.Reason,
+			ApprovedBy: approvedBy,
+		}
+		if s.File != "" {
+			exception.Paths = []string{s.File}
+		}
+		if s.Resource != "" {
+			exception.ResourceNames = []string{s.Resource}
+		}
+		if s.ExpiresAt != "" {
+			expiresAt := s.ExpiresAt
+			exception.ExpiresAt = &expiresAt
+		}
+		result.Exceptions = append(result.Exceptions, exception)
+	}
+
+	return result, nil
+}
+
+// InlineExceptions returns exceptions for the inline annotations of the
+// given tool under dir, so scans can honor them without a migration. Check
+// IDs are translated with RuleMappings and extra, which takes precedence;
+// annotations for checks without a translation are ignored.
+func InlineExceptions(from, dir string, extra map[string][]string) ([]config.Exception, error) {
+	mappings, ok := RuleMappings[from]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tool %q (expected %s or %s)", from, FromTfsec, FromCheckov)
+	}
+
+	suppressions, _, err := scanAnnotations(from, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceptions []config.Exception
+	for _, s := range suppressions {
+		rules, ok := extra[s.CheckID]
+		if !ok {
+			rules, ok = mappings[s.CheckID]
+		}
+		if !ok {
+			continue
+		}
+
+		exception := config.Exception{
+			Rules:         rules,
+			Paths:         []string{filepath.FromSlash(s.File)},
+			ResourceNames: []string{s.Resource},
+			Reason:        s.Reason,
+			ApprovedBy:    fmt.Sprintf("inline %s annotation", from),
+		}
+		if s.ExpiresAt != "" {
+			expiresAt := s.ExpiresAt
+			exception.ExpiresAt = &expiresAt
+		}
+		exceptions = append(exceptions, exception)
+	}
+
+	return exceptions, nil
+}
+
+// loadTfsecConfig reads .tfsec/config.yml (or .yaml/.json) and returns
+// its excluded checks and severity overrides
+func loadTfsecConfig(dir string) ([]Suppression, map[string]string, error) {
+	path, val, err := loadToolConfig(dir, ".tfsec/config.yml", ".tfsec/config.yaml", ".tfsec/config.json")
+	if err != nil || path == "" {
+		return nil, nil, err
+	}
+
+	var excluded []Suppression
+	for _, checkID := range stringList(val, "exclude") {
+		excluded = append(excluded, Suppression{
+			CheckID: checkID,
+			Reason:  fmt.Sprintf("Excluded in %s (migrated from tfsec)", filepath.ToSlash(path)),
+		})
+	}
+
+	severities := make(map[string]string)
+	if overrides, ok := attribute(val, "severity_overrides"); ok {
+		if !overrides.Type().IsObjectType() && !overrides.Type().IsMapType() {
+			return nil, nil, fmt.Errorf("%s: severity_overrides must map check IDs to severities", path)
+		}
+		for checkID, severity := range overrides.AsValueMap() {
+			str, err := convert.Convert(severity, cty.String)
+			if err != nil || str.IsNull() {
+				return nil, nil, fmt.Errorf("%s: severity_overrides.%s must be a string", path, checkID)
+			}
+			mapped, ok := toolSeverities[strings.ToUpper(str.AsString())]
+			if !ok {
+				return nil, nil, fmt.Errorf("%s: severity_overrides.%s has unknown severity %q", path, checkID, str.AsString())
+			}
+			severities[checkID] = mapped
+		}
+	}
+
+	return excluded, severities, nil
+}
+
+// loadCheckovConfig reads .checkov.yml (or .yaml) and returns its skipped
+// checks
+func loadCheckovConfig(dir string) ([]Suppression, error) {
+	path, val, err := loadToolConfig(dir, ".checkov.yml", ".checkov.yaml")
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	var skipped []Suppression
+	for _, entry := range stringList(val, "skip-check") {
+		// skip-check accepts comma-separated lists as well
+		for _, checkID := range strings.Split(entry, ",") {
+			if checkID = strings.TrimSpace(checkID); checkID == "" {
+				continue
+			}
+			skipped = append(skipped, Suppression{
+				CheckID: checkID,
+				Reason:  fmt.Sprintf("Skipped in %s (migrated from checkov)", filepath.ToSlash(path)),
+			})
+		}
+	}
+	return skipped, nil
+}
+
+// loadToolConfig loads the first of names that exists under dir. It returns
+// an empty path if none exist.
+func loadToolConfig(dir string, names ...string) (string, cty.Value, error) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		src, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", cty.NilVal, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		// JSON is valid YAML, so one decoder covers both
+		ty, err := ctyyaml.ImpliedType(src)
+		if err != nil {
+			return "", cty.NilVal, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		val, err := ctyyaml.Unmarshal(src, ty)
+		if err != nil {
+			return "", cty.NilVal, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return path, val, nil
+	}
+	return "", cty.NilVal, nil
+}
+
+// attribute returns the named attribute of a decoded mapping and whether
+// it is present and not null
+func attribute(val cty.Value, name string) (cty.Value, bool) {
+	if val.IsNull() || (!val.Type().IsObjectType() && !val.Type().IsMapType()) {
+		return cty.NilVal, false
+	}
+	attr, ok := val.AsValueMap()[name]
+	if !ok || attr.IsNull() {
+		return cty.NilVal, false
+	}
+	return attr, true
+}
+
+// stringList returns the named attribute as a list of strings. A single
+// string is treated as a one-element list.
+func stringList(val cty.Value, name string) []string {
+	attr, ok := attribute(val, name)
+	if !ok {
+		return nil
+	}
+	if attr.Type() == cty.String {
+		return []string{attr.AsString()}
+	}
+	if !attr.CanIterateElements() {
+		return nil
+	}
+
+	var list []string
+	for _, elem := range attr.AsValueSlice() {
+		if str, err := convert.Convert(elem, cty.String); err == nil && !str.IsNull() {
+			list = append(list, str.AsString())
+		}
+	}
+	return list
+}
+
+// scanAnnotations finds inline suppressions in the Terraform files under
+// dir. An annotation applies to the block it appears in, or to the block
+// directly below it when only comments and blank lines separate them.
+func scanAnnotations(from, dir string) ([]Suppression, []Suppression, error) {
+	var attached, unattached []Suppression
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".terraform" || d.Name() == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".tf" {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		found, orphaned, err := fileAnnotations(from, path, src)
+		if err != nil {
+			return err
+		}
+		attached = append(attached, found...)
+		unattached = append(unattached, orphaned...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attached, unattached, nil
+}
+
+// fileAnnotations returns the suppressions annotated in a single file
+func fileAnnotations(from, path string, src []byte) ([]Suppression, []Suppression, error) {
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+	body := file.Body.(*hclsyntax.Body)
+	lines := strings.Split(string(src), "\n")
+
+	tokens, _ := hclsyntax.LexConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+
+	var attached, unattached []Suppression
+	for _, token := range tokens {
+		if token.Type != hclsyntax.TokenComment {
+			continue
+		}
+		line := token.Range.Start.Line
+
+		for _, s := range parseAnnotations(from, string(token.Bytes)) {
+			s.File = filepath.ToSlash(path)
+			s.Line = line
+
+			// A block without all its labels names no resource to scope
+			// the exception to
+			block := annotatedBlock(body, lines, line)
+			if block == nil || len(block.Labels) < blockLabels[block.Type] {
+				unattached = append(unattached, s)
+				continue
+			}
+			s.Resource = block.Labels[len(block.Labels)-1]
+			if s.Reason == "" {
+				s.Reason = fmt.Sprintf("Inline %s annotation at %s:%d", from, s.File, line)
+			}
+			attached = append(attached, s)
+		}
+	}
+
+	return attached, unattached, nil
+}
+
+// parseAnnotations extracts the suppressions in a single comment
+func parseAnnotations(from, comment string) []Suppression {
+	var suppressions []Suppression
+
+	switch from {
+	case FromTfsec:
+		for _, match := range tfsecIgnorePattern.FindAllStringSubmatch(comment, -1) {
+			suppressions = append(suppressions, Suppression{CheckID: match[1], ExpiresAt: match[2]})
+		}
+	case FromCheckov:
+		for _, match := range checkovSkipPattern.FindAllStringSubmatch(comment, -1) {
+			suppressions = append(suppressions, Suppression{CheckID: match[1], Reason: strings.TrimSpace(match[2])})
+		}
+	}
+
+	return suppressions
+}
+
+// blockLabels is the number of labels each annotatable block type has
+var blockLabels = map[string]int{
+	"resource": 2,
+	"data":     2,
+	"module":   1,
+}
+
+// annotatedBlock returns the resource, data, or module block an annotation
+// on line applies to, or nil if there is none
+func annotatedBlock(body *hclsyntax.Body, lines []string, line int) *hclsyntax.Block {
+	var next *hclsyntax.Block
+	for _, block := range body.Blocks {
+		if _, ok := blockLabels[block.Type]; !ok {
+			continue
+		}
+
+		rng := block.Range()
+		if rng.Start.Line <= line && line <= rng.End.Line {
+			return block
+		}
+		if rng.Start.Line > line && (next == nil || rng.Start.Line < next.Range().Start.Line) {
+			next = block
+		}
+	}
+
+	if next == nil {
+		return nil
+	}
+	for l := line + 1; l < next.Range().Start.Line; l++ {
+		if !commentOrBlankLines.MatchString(lines[l-1]) {
+			return nil
+		}
+	}
+	return next
+}
+
+// Render formats the result as HCL exception blocks ready to be added to a
+// planguard config. Unmapped and unattached suppressions are listed as
+// comments so they can be reviewed by hand.
+func Render(from string, result *Result) []byte {
+	var notes []string
+	for _, s := range result.Unmapped {
+		notes = append(notes, fmt.Sprintf("# No planguard equivalent for %s check %s%s", from, s.CheckID, location(s)))
+	}
+	for _, s := range result.Unattached {
+		notes = append(notes, fmt.Sprintf("# Could not find the block for %s check %s%s", from, s.CheckID, location(s)))
+	}
+
+	out := config.FormatExceptions(result.Exceptions)
+	if len(notes) > 0 {
+		if len(out) > 0 {
+			out = append(out, '\n')
+		}
+		out = append(out, []byte(strings.Join(notes, "\n")+"\n")...)
+	}
+	return out
+}
+
+// RenderSeverityMap formats severity overrides as a severity map file
+// (see config.LoadSeverityMap)
+func RenderSeverityMap(overrides map[string]string) []byte {
+	ruleIDs := make([]string, 0, len(overrides))
+	for ruleID := range overrides {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+
+	var b strings.Builder
+	b.WriteString("rules:\n")
+	for _, ruleID := range ruleIDs {
+		fmt.Fprintf(&b, "  %s: %s\n", ruleID, overrides[ruleID])
+	}
+	return []byte(b.String())
+}
+
+func location(s Suppression) string {
+	if s.File == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s:%d)", s.File, s.Line)
+}
@@ -0,0 +1,264 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrateTfsec(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".tfsec/config.yml": `
+exclude:
+  - aws-rds-no-public-db-access
+  - aws-unknown-check
+severity_overrides:
+  aws-ec2-enforce-http-token-imds: LOW
+`,
+		"main.tf": `
+#tfsec:ignore:aws-s3-enable-versioning:exp:2030-01-01
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "aws_instance" "web" {
+  ami = "ami-123" # tfsec:ignore:aws-ec2-enforce-http-token-imds
+}
+
+# tfsec:ignore:aws-s3-enable-versioning
+
+locals {
+  x = 1
+}
+`,
+	})
+
+	result, err := Migrate(FromTfsec, dir, "security-team")
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	mainTF := filepath.ToSlash(filepath.Join(dir, "main.tf"))
+	expiry := "2030-01-01"
+	want := []config.Exception{
+		{
+			Rules:      []string{"aws_rds_public_access"},
+			Reason:     "Excluded in " + filepath.ToSlash(filepath.Join(dir, ".tfsec/config.yml")) + " (migrated from tfsec)",
+			ApprovedBy: "security-team",
+		},
+		{
+			Rules:         []string{"aws_s3_versioning"},
+			Paths:         []string{mainTF},
+			ResourceNames: []string{"logs"},
+			Reason:        "Inline tfsec annotation at " + mainTF + ":2",
+			ExpiresAt:     &expiry,
+			ApprovedBy:    "security-team",
+		},
+		{
+			Rules:         []string{"aws_ec2_imdsv2"},
+			Paths:         []string{mainTF},
+			ResourceNames: []string{"web"},
+			Reason:        "Inline tfsec annotation at " + mainTF + ":8",
+			ApprovedBy:    "security-team",
+		},
+	}
+	if !reflect.DeepEqual(result.Exceptions, want) {
+		t.Errorf("Exceptions = %+v, want %+v", result.Exceptions, want)
+	}
+
+	if len(result.Unmapped) != 1 || result.Unmapped[0].CheckID != "aws-unknown-check" {
+		t.Errorf("Unmapped = %+v, want aws-unknown-check", result.Unmapped)
+	}
+	if len(result.Unattached) != 1 || result.Unattached[0].Line != 11 {
+		t.Errorf("Unattached = %+v, want the annotation on line 11", result.Unattached)
+	}
+	if result.Suppressions != 5 {
+		t.Errorf("Suppressions = %d, want 5", result.Suppressions)
+	}
+	if !reflect.DeepEqual(result.SeverityOverrides, map[string]string{"aws_ec2_imdsv2": "info"}) {
+		t.Errorf("SeverityOverrides = %v", result.SeverityOverrides)
+	}
+}
+
+func TestMigrateCheckov(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".checkov.yml": `
+skip-check:
+  - CKV_AWS_21,CKV_AWS_16
+`,
+		"main.tf": `
+resource "aws_s3_bucket" "site" {
+  #checkov:skip=CKV_AWS_20:The bucket is a public static content host
+  bucket = "site"
+}
+`,
+	})
+
+	result, err := Migrate(FromCheckov, dir, "platform")
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	if len(result.Exceptions) != 3 {
+		t.Fatalf("Expected 3 exceptions, got %+v", result.Exceptions)
+	}
+	if got := result.Exceptions[0].Rules; !reflect.DeepEqual(got, []string{"aws_s3_versioning"}) {
+		t.Errorf("First config exception rules = %v", got)
+	}
+	if got := result.Exceptions[1].Rules; !reflect.DeepEqual(got, []string{"aws_rds_encryption"}) {
+		t.Errorf("Second config exception rules = %v", got)
+	}
+
+	inline := result.Exceptions[2]
+	if inline.Reason != "The bucket is a public static content host" {
+		t.Errorf("Reason = %q, want the skip comment", inline.Reason)
+	}
+	if !reflect.DeepEqual(inline.ResourceNames, []string{"site"}) {
+		t.Errorf("ResourceNames = %v, want [site]", inline.ResourceNames)
+	}
+}
+
+func TestInlineExceptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.tf": `
+resource "aws_s3_bucket" "site" {
+  #checkov:skip=CKV_AWS_20:Public static site
+  #checkov:skip=CKV_CUSTOM_1
+  #checkov:skip=CKV_AWS_999
+  bucket = "site"
+}
+`,
+	})
+
+	exceptions, err := InlineExceptions(FromCheckov, dir, map[string][]string{"CKV_CUSTOM_1": {"custom_rule"}})
+	if err != nil {
+		t.Fatalf("InlineExceptions() error = %v", err)
+	}
+
+	if len(exceptions) != 2 {
+		t.Fatalf("Expected 2 exceptions (unmapped checks ignored), got %+v", exceptions)
+	}
+	if !reflect.DeepEqual(exceptions[0].Rules, []string{"aws_s3_public_read"}) || exceptions[0].Reason != "Public static site" {
+		t.Errorf("First exception = %+v", exceptions[0])
+	}
+	if !reflect.DeepEqual(exceptions[1].Rules, []string{"custom_rule"}) {
+		t.Errorf("Extra mapping not applied: %+v", exceptions[1])
+	}
+	for _, e := range exceptions {
+		if !reflect.DeepEqual(e.Paths, []string{filepath.Join(dir, "main.tf")}) || !reflect.DeepEqual(e.ResourceNames, []string{"site"}) {
+			t.Errorf("Exception not scoped to the annotated resource: %+v", e)
+		}
+	}
+}
+
+func TestFileAnnotationsMalformedBlocks(t *testing.T) {
+	src := `
+resource "aws_s3_bucket" {
+  #checkov:skip=CKV_AWS_20:Missing its name
+}
+
+#checkov:skip=CKV_AWS_21:Missing its name
+data {
+}
+
+module {
+  #checkov:skip=CKV_AWS_18:Missing its name
+}
+
+resource "aws_s3_bucket" "site" {
+  #checkov:skip=CKV_AWS_19:Well formed
+}
+`
+	attached, unattached, err := fileAnnotations(FromCheckov, "main.tf", []byte(src))
+	if err != nil {
+		t.Fatalf("fileAnnotations() error = %v", err)
+	}
+
+	if len(attached) != 1 || attached[0].CheckID != "CKV_AWS_19" || attached[0].Resource != "site" {
+		t.Errorf("attached = %+v, want only CKV_AWS_19 on site", attached)
+	}
+	var got []string
+	for _, s := range unattached {
+		got = append(got, s.CheckID)
+	}
+	if want := []string{"CKV_AWS_20", "CKV_AWS_21", "CKV_AWS_18"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unattached = %v, want %v", got, want)
+	}
+}
+
+func TestMigrateUnsupportedTool(t *testing.T) {
+	if _, err := Migrate("terrascan", t.TempDir(), "me"); err == nil {
+		t.Error("Expected error for unsupported tool")
+	}
+}
+
+func TestRender(t *testing.T) {
+	expiry := "2030-01-01"
+	result := &Result{
+		Exceptions: []config.Exception{
+			{
+				Rules:         []string{"aws_s3_versioning"},
+				Paths:         []string{"main.tf"},
+				ResourceNames: []string{"logs"},
+				Reason:        "Logs are append-only",
+				ExpiresAt:     &expiry,
+				ApprovedBy:    "security-team",
+			},
+		},
+		Unmapped: []Suppression{{CheckID: "aws-unknown-check", File: "main.tf", Line: 3}},
+	}
+
+	out := string(Render(FromTfsec, result))
+	for _, want := range []string{
+		`exception {`,
+		`rules          = ["aws_s3_versioning"]`,
+		`resource_names = ["logs"]`,
+		`expires_at     = "2030-01-01"`,
+		`# No planguard equivalent for tfsec check aws-unknown-check (main.tf:3)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render() output missing %q:\n%s", want, out)
+		}
+	}
+
+	// The output must load as planguard config
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.Exceptions) != 1 {
+		t.Errorf("Expected 1 exception in rendered config, got %d", len(cfg.Exceptions))
+	}
+}
+
+func TestRenderSeverityMap(t *testing.T) {
+	got := string(RenderSeverityMap(map[string]string{"b_rule": "info", "a_rule": "error"}))
+	want := "rules:\n  a_rule: error\n  b_rule: info\n"
+	if got != want {
+		t.Errorf("RenderSeverityMap() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Package rulelock reads and writes .planguard/rules.lock.hcl, which pins
+// each rule source to the git commit and rule file contents it resolved to
+// when last updated, so every machine scans with the same rules until the
+// lock is updated:
+//
+//	source "git::https://github.com/org/policies.git//rules?ref=v1" {
+//	  commit = "0123456789abcdef0123456789abcdef01234567"
+//	  digest = "sha256:..."
+//	}
+package rulelock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jonathanhle/planguard/internal/rulesign"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FileName is the lock file's name, in the config file's directory
+const FileName = "rules.lock.hcl"
+
+const header = `# Rule sources as resolved by planguard rules update. Commit this file,
+# and run planguard rules update again to move sources to newer rules.
+`
+
+// commitPattern matches a full commit hash
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Entry pins one rule source
+type Entry struct {
+	Source string `hcl:"source,label"`
+	Commit string `hcl:"commit,optional"` // Resolved commit of git sources
+	Digest string `hcl:"digest"`          // Of the source's rule files
+}
+
+// Lock is a parsed lock file
+type Lock struct {
+	Sources []Entry `hcl:"source,block"`
+}
+
+// Load reads a lock file. It returns nil when there is none.
+func Load(path string) (*Lock, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var lock Lock
+	if err := hclsimple.DecodeFile(path, nil, &lock); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	// Commits are passed to git as refs, so anything but a full hash is
+	// rejected rather than fetched
+	for _, entry := range lock.Sources {
+		if entry.Commit != "" && !commitPattern.MatchString(entry.Commit) {
+			return nil, fmt.Errorf("failed to load %s: source %q has an invalid commit %q (expected a 40-character hex hash)", path, entry.Source, entry.Commit)
+		}
+	}
+	return &lock, nil
+}
+
+// Find returns the entry for a rule source as written in rule_sources
+func (l *Lock) Find(source string) (Entry, bool) {
+	for _, entry := range l.Sources {
+		if entry.Source == source {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Format renders a lock file
+func (l *Lock) Format() []byte {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for _, entry := range l.Sources {
+		body.AppendNewline()
+		block := body.AppendNewBlock("source", []string{entry.Source}).Body()
+		if entry.Commit != "" {
+			block.SetAttributeValue("commit", cty.StringVal(entry.Commit))
+		}
+		block.SetAttributeValue("digest", cty.StringVal(entry.Digest))
+	}
+	return append([]byte(header), f.Bytes()...)
+}
+
+// Digest identifies the contents of a rule source: a hash of the manifest
+// of its rule files, which are in dir
+func Digest(dir string, files []string) (string, error) {
+	manifest, err := rulesign.Manifest(dir, files)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(manifest)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
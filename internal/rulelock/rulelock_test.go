@@ -0,0 +1,112 @@
+package rulelock
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockRoundTrip(t *testing.T) {
+	lock := &Lock{Sources: []Entry{
+		{Source: "git::https://github.com/org/policies.git//rules?ref=v1", Commit: "0123456789abcdef0123456789abcdef01234567", Digest: "sha256:aa"},
+		{Source: "s3://bucket/rules", Digest: "sha256:bb"},
+	}}
+	want := `# Rule sources as resolved by planguard rules update. Commit this file,
+# and run planguard rules update again to move sources to newer rules.
+
+source "git::https://github.com/org/policies.git//rules?ref=v1" {
+  commit = "0123456789abcdef0123456789abcdef01234567"
+  digest = "sha256:aa"
+}
+
+source "s3://bucket/rules" {
+  digest = "sha256:bb"
+}
+`
+	if got := string(lock.Format()); got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, lock.Format(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, lock) {
+		t.Errorf("Load() = %+v, want %+v", loaded, lock)
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"s3://bucket/rules", true},
+		{"s3://bucket/other", false},
+	}
+	for _, tt := range tests {
+		if _, ok := loaded.Find(tt.source); ok != tt.want {
+			t.Errorf("Find(%s) = %v, want %v", tt.source, ok, tt.want)
+		}
+	}
+}
+
+func TestLoadMissingAndInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if lock, err := Load(filepath.Join(dir, FileName)); lock != nil || err != nil {
+		t.Errorf("Load() of a missing file = %v, %v; want nil, nil", lock, err)
+	}
+
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte(`source "s3://bucket" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Load() error = %v, want a missing digest", err)
+	}
+
+	for _, commit := range []string{"--upload-pack=touch /tmp/pwned", "main", "0123456789ABCDEF0123456789ABCDEF01234567"} {
+		content := `source "git::https://github.com/org/policies.git" {
+  commit = "` + commit + `"
+  digest = "sha256:aa"
+}
+`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid commit") {
+			t.Errorf("Load() with commit %q error = %v, want an invalid commit", commit, err)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rule.hcl")
+	digest := func() string {
+		t.Helper()
+		d, err := Digest(dir, []string{file})
+		if err != nil {
+			t.Fatalf("Digest() error = %v", err)
+		}
+		return d
+	}
+
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := digest()
+	if !strings.HasPrefix(first, "sha256:") || first != digest() {
+		t.Errorf("Digest() = %s, want a stable sha256 digest", first)
+	}
+	if err := os.WriteFile(file, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if digest() == first {
+		t.Error("Digest() did not change with the file")
+	}
+}
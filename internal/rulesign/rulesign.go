@@ -0,0 +1,192 @@
+// Package rulesign verifies that a rule bundle is the one its publisher
+// signed. A bundle's directory holds a manifest, rules.sum, listing the
+// SHA-256 of each rule file, and a minisign signature of the manifest,
+// rules.sum.minisig, made with:
+//
+//	minisign -Sm rules.sum
+//
+// Verification checks the signature against trusted public keys, then that
+// the rule files are exactly those the manifest lists, unmodified.
+package rulesign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// ManifestFile lists the SHA-256 of each file in a bundle
+	ManifestFile = "rules.sum"
+	// SignatureFile is the minisign signature of ManifestFile
+	SignatureFile = ManifestFile + ".minisig"
+)
+
+// PublicKey is a minisign public key
+type PublicKey struct {
+	ID  [8]byte
+	Key ed25519.PublicKey
+}
+
+// ParsePublicKey parses a minisign public key: the base64 line of a
+// minisign.pub file, or the whole file with its comment line
+func ParsePublicKey(s string) (PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	line := strings.TrimSpace(lines[len(lines)-1])
+	data, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != "Ed" {
+		return PublicKey{}, fmt.Errorf("invalid minisign public key %q", line)
+	}
+	var key PublicKey
+	copy(key.ID[:], data[2:10])
+	key.Key = ed25519.PublicKey(data[10:])
+	return key, nil
+}
+
+// String returns the key ID as minisign prints it
+func (k PublicKey) String() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(k.ID[:]))
+}
+
+// VerifySignature checks a minisign signature of message against keys and
+// returns the key that made it. Both the signature and the global
+// signature over its trusted comment must be valid.
+func VerifySignature(keys []PublicKey, message, signature []byte) (PublicKey, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(signature))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return PublicKey{}, fmt.Errorf("invalid minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return PublicKey{}, fmt.Errorf("invalid minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return PublicKey{}, fmt.Errorf("invalid minisign signature")
+	}
+
+	// "ED" signatures are of the BLAKE2b-512 hash of the message
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(message)
+		message = sum[:]
+	default:
+		return PublicKey{}, fmt.Errorf("unsupported minisign signature algorithm %q", sig[:2])
+	}
+
+	var id [8]byte
+	copy(id[:], sig[2:10])
+	for _, key := range keys {
+		if key.ID != id {
+			continue
+		}
+		if !ed25519.Verify(key.Key, message, sig[10:]) {
+			return PublicKey{}, fmt.Errorf("signature by key %s does not match", key)
+		}
+		trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+		signed := append(append([]byte{}, sig[10:]...), trusted...)
+		if !ed25519.Verify(key.Key, signed, global) {
+			return PublicKey{}, fmt.Errorf("trusted comment signed by key %s does not match", key)
+		}
+		return key, nil
+	}
+	return PublicKey{}, fmt.Errorf("signed by key %s, which is not trusted", PublicKey{ID: id})
+}
+
+// Manifest returns the manifest of files, which are in dir: a line of
+// SHA-256 and slash-separated path relative to dir per file, sorted by path
+func Manifest(dir string, files []string) ([]byte, error) {
+	var lines []string
+	for _, file := range files {
+		rel, sum, err := hashFile(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, sum+"  "+rel+"\n")
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][66:] < lines[j][66:] })
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// Verify checks the signature of dir's manifest against keys, then that
+// files, which are in dir, are exactly the files the manifest lists, with
+// the contents it lists. It returns the key that signed the manifest.
+func Verify(dir string, files []string, keys []PublicKey) (PublicKey, error) {
+	manifest, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("failed to read %s: %w", ManifestFile, err)
+	}
+	signature, err := os.ReadFile(filepath.Join(dir, SignatureFile))
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("failed to read %s: %w", SignatureFile, err)
+	}
+	key, err := VerifySignature(keys, manifest, signature)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("%s: %w", SignatureFile, err)
+	}
+
+	listed := make(map[string]string)
+	for i, line := range strings.Split(strings.TrimSuffix(string(manifest), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		sum, rel, ok := strings.Cut(line, "  ")
+		if !ok || len(sum) != 64 {
+			return PublicKey{}, fmt.Errorf("%s:%d: invalid line %q", ManifestFile, i+1, line)
+		}
+		listed[rel] = sum
+	}
+	for _, file := range files {
+		rel, sum, err := hashFile(dir, file)
+		if err != nil {
+			return PublicKey{}, err
+		}
+		want, ok := listed[rel]
+		if !ok {
+			return PublicKey{}, fmt.Errorf("%s is not listed in the signed %s", rel, ManifestFile)
+		}
+		if sum != want {
+			return PublicKey{}, fmt.Errorf("%s does not match the signed %s", rel, ManifestFile)
+		}
+		delete(listed, rel)
+	}
+	if len(listed) > 0 {
+		var missing []string
+		for rel := range listed {
+			missing = append(missing, rel)
+		}
+		sort.Strings(missing)
+		return PublicKey{}, fmt.Errorf("%s is listed in the signed %s but missing", strings.Join(missing, ", "), ManifestFile)
+	}
+	return key, nil
+}
+
+// hashFile returns the path of file relative to dir and its SHA-256
+func hashFile(dir, file string) (rel, sum string, err error) {
+	rel, err = filepath.Rel(dir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", "", fmt.Errorf("%s is not in %s", file, dir)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	hash := sha256.Sum256(data)
+	return filepath.ToSlash(rel), hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,180 @@
+package rulesign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// testKey generates a key pair and returns it with its minisign public key
+func testKey(t *testing.T, id byte) (ed25519.PrivateKey, PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte("Ed"), id, 2, 3, 4, 5, 6, 7, 8)
+	data = append(data, pub...)
+	key, err := ParsePublicKey("untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(data) + "\n")
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+	return priv, key
+}
+
+// sign signs message as minisign does, prehashed with alg "ED"
+func sign(priv ed25519.PrivateKey, key PublicKey, alg string, message []byte, trusted string) []byte {
+	if alg == "ED" {
+		sum := blake2b.Sum512(message)
+		message = sum[:]
+	}
+	sig := append([]byte(alg), key.ID[:]...)
+	sig = append(sig, ed25519.Sign(priv, message)...)
+	global := ed25519.Sign(priv, append(append([]byte{}, sig[10:]...), trusted...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(sig) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestParsePublicKey(t *testing.T) {
+	_, key := testKey(t, 0xAB)
+	if got := key.String(); got != "08070605040302AB" {
+		t.Errorf("String() = %s, want 08070605040302AB", got)
+	}
+	for _, invalid := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("Ed too short"))} {
+		if _, err := ParsePublicKey(invalid); err == nil {
+			t.Errorf("ParsePublicKey(%q) error = nil", invalid)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	priv, key := testKey(t, 1)
+	otherPriv, other := testKey(t, 2)
+	message := []byte("rules")
+
+	tampered := sign(priv, key, "ED", message, "timestamp:1")
+	tampered = []byte(strings.Replace(string(tampered), "timestamp:1", "timestamp:2", 1))
+
+	tests := []struct {
+		name      string
+		keys      []PublicKey
+		signature []byte
+		wantErr   string
+	}{
+		{"prehashed", []PublicKey{other, key}, sign(priv, key, "ED", message, "timestamp:1"), ""},
+		{"legacy", []PublicKey{key}, sign(priv, key, "Ed", message, "file:rules.sum"), ""},
+		{"untrusted key", []PublicKey{key}, sign(otherPriv, other, "ED", message, "t"), "not trusted"},
+		{"wrong key for ID", []PublicKey{key}, sign(otherPriv, key, "ED", message, "t"), "does not match"},
+		{"other message", []PublicKey{key}, sign(priv, key, "ED", []byte("other"), "t"), "does not match"},
+		{"tampered trusted comment", []PublicKey{key}, tampered, "trusted comment"},
+		{"malformed", []PublicKey{key}, []byte("untrusted comment: x\n"), "invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifySignature(tt.keys, message, tt.signature)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifySignature() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifySignature() error = %v", err)
+			}
+			if got.ID != key.ID {
+				t.Errorf("VerifySignature() key = %s, want %s", got, key)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	priv, key := testKey(t, 1)
+
+	tests := []struct {
+		name    string
+		change  func(dir string) error
+		wantErr string
+	}{
+		{"unchanged", func(string) error { return nil }, ""},
+		{"modified file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "aws", "s3.hcl"), []byte("changed"), 0644)
+		}, "aws/s3.hcl does not match"},
+		{"added file", func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "extra.hcl"), []byte("extra"), 0644)
+		}, "extra.hcl is not listed"},
+		{"removed file", func(dir string) error {
+			return os.Remove(filepath.Join(dir, "common.hcl"))
+		}, "common.hcl is listed in the signed rules.sum but missing"},
+		{"modified manifest", func(dir string) error {
+			f, err := os.OpenFile(filepath.Join(dir, ManifestFile), os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = f.WriteString(strings.Repeat("0", 64) + "  extra.hcl\n")
+			return err
+		}, "does not match"},
+		{"unsigned", func(dir string) error {
+			return os.Remove(filepath.Join(dir, SignatureFile))
+		}, "failed to read rules.sum.minisig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"common.hcl": "common", "aws/s3.hcl": "s3"}
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			list := func() []string {
+				matches, _ := filepath.Glob(filepath.Join(dir, "*.hcl"))
+				nested, _ := filepath.Glob(filepath.Join(dir, "*", "*.hcl"))
+				return append(matches, nested...)
+			}
+
+			manifest, err := Manifest(dir, list())
+			if err != nil {
+				t.Fatalf("Manifest() error = %v", err)
+			}
+			if !strings.HasSuffix(string(manifest), "  common.hcl\n") {
+				t.Errorf("Manifest() = %q, want lines sorted by path", manifest)
+			}
+			if err := os.WriteFile(filepath.Join(dir, ManifestFile), manifest, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, SignatureFile), sign(priv, key, "ED", manifest, "t"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(dir); err != nil {
+				t.Fatal(err)
+			}
+			_, err = Verify(dir, list(), []PublicKey{key})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package rulesource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// DefaultUsername is sent with a token when credentials name no username.
+// GitHub and most other hosts ignore it, but require one.
+const DefaultUsername = "x-access-token"
+
+// Credentials authenticate a git source fetched over HTTPS
+type Credentials struct {
+	Username string
+	Password string // Password or token
+}
+
+// ResolveCredentials returns the credentials for src from the credentials
+// block naming its host, reading the secret from where the block says it
+// is. It returns nil for sources no block names, and for sources not
+// fetched over HTTP(S), such as SSH, which authenticates with keys. A
+// source a block names that is fetched over plain HTTP is an error, since
+// the secret would be sent unencrypted.
+func ResolveCredentials(blocks []config.Credentials, src Source) (*Credentials, error) {
+	if src.Kind != Git {
+		return nil, nil
+	}
+	u, err := url.Parse(src.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, nil
+	}
+
+	for _, block := range blocks {
+		if !strings.EqualFold(block.Host, u.Hostname()) && !strings.EqualFold(block.Host, u.Host) {
+			continue
+		}
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("credentials for %s: refusing to send them over %s://; fetch the source over https://", block.Host, u.Scheme)
+		}
+		creds, err := resolve(block)
+		if err != nil {
+			return nil, fmt.Errorf("credentials for %s: %w", block.Host, err)
+		}
+		return creds, nil
+	}
+	return nil, nil
+}
+
+func resolve(block config.Credentials) (*Credentials, error) {
+	sources := 0
+	if block.TokenEnv != nil {
+		sources++
+	}
+	if block.Netrc {
+		sources++
+	}
+	if len(block.Helper) > 0 {
+		sources++
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("set exactly one of token_env, netrc, and helper")
+	}
+
+	creds := &Credentials{Username: DefaultUsername}
+	if block.Username != nil {
+		creds.Username = *block.Username
+	}
+
+	switch {
+	case block.TokenEnv != nil:
+		token := os.Getenv(*block.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", *block.TokenEnv)
+		}
+		creds.Password = token
+	case block.Netrc:
+		login, password, err := netrcEntry(block.Host)
+		if err != nil {
+			return nil, err
+		}
+		if login != "" && block.Username == nil {
+			creds.Username = login
+		}
+		creds.Password = password
+	default:
+		if err := runHelper(block.Helper, block.Host, creds); err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
+
+// netrcEntry returns the login and password for host in the netrc file
+// named by $NETRC, or ~/.netrc, falling back to its default entry
+func netrcEntry(host string) (string, string, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to find the netrc file: %w", err)
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read netrc file: %w", err)
+	}
+
+	// Entries are whitespace-separated tokens: machine <host> (or default)
+	// followed by login, password, and other keys with their values
+	var login, password string
+	var defaultLogin, defaultPassword string
+	var inHost, inDefault bool
+	tokens := strings.Fields(string(data))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			inDefault = false
+			inHost = i+1 < len(tokens) && strings.EqualFold(tokens[i+1], host)
+			i++
+		case "default":
+			inHost, inDefault = false, true
+		case "login", "password", "account":
+			if i+1 == len(tokens) {
+				continue
+			}
+			key, value := tokens[i], tokens[i+1]
+			i++
+			switch {
+			case inHost && key == "login":
+				login = value
+			case inHost && key == "password":
+				password = value
+			case inDefault && key == "login":
+				defaultLogin = value
+			case inDefault && key == "password":
+				defaultPassword = value
+			}
+		}
+	}
+	if password != "" {
+		return login, password, nil
+	}
+	if defaultPassword != "" {
+		return defaultLogin, defaultPassword, nil
+	}
+	return "", "", fmt.Errorf("%s has no password for %s", path, host)
+}
+
+// runHelper runs a credential helper command. It prints either the token
+// alone or, like a git credential helper, username= and password= lines.
+func runHelper(helper []string, host string, creds *Credentials) error {
+	cmd := exec.Command(helper[0], helper[1:]...)
+	cmd.Env = append(os.Environ(), "PLANGUARD_CREDENTIALS_HOST="+host)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("helper %s failed: %s", helper[0], msg)
+		}
+		return fmt.Errorf("helper %s failed: %w", helper[0], err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	keyed := false
+	for _, line := range lines {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "username":
+			creds.Username, keyed = value, true
+		case "password":
+			creds.Password, keyed = value, true
+		}
+	}
+	if !keyed {
+		creds.Password = strings.TrimSpace(lines[0])
+	}
+	if creds.Password == "" {
+		return fmt.Errorf("helper %s printed no token", helper[0])
+	}
+	return nil
+}
+
+// authEnv returns the environment variables that make git send creds to
+// the host of src as an Authorization header. The header is configured
+// with GIT_CONFIG_* variables, so the secret is never written to the
+// checkout's config or passed on a command line, where other users could
+// see it.
+func authEnv(src Source, creds *Credentials) []string {
+	if creds == nil {
+		return nil
+	}
+	u, err := url.Parse(src.URL)
+	if err != nil {
+		return nil
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+
+	// Add to any configuration already passed this way
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return []string{
+		"GIT_CONFIG_COUNT=" + strconv.Itoa(count+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", count, u.Scheme, u.Host),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", count, basic),
+	}
+}
@@ -0,0 +1,155 @@
+package rulesource
+
+import (
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestResolveCredentials(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	content := "machine git.example.com login deploy password netrc-secret\ndefault login anyone password default-secret\n"
+	if err := os.WriteFile(netrc, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETRC", netrc)
+	t.Setenv("POLICY_TOKEN", "env-secret")
+	t.Setenv("EMPTY_TOKEN", "")
+
+	str := func(s string) *string { return &s }
+	https := Source{Kind: Git, URL: "https://git.example.com/org/policies.git"}
+	tests := []struct {
+		name    string
+		blocks  []config.Credentials
+		src     Source
+		want    *Credentials
+		wantErr string
+	}{
+		{
+			name:   "token_env",
+			blocks: []config.Credentials{{Host: "git.example.com", TokenEnv: str("POLICY_TOKEN")}},
+			src:    https,
+			want:   &Credentials{Username: DefaultUsername, Password: "env-secret"},
+		},
+		{
+			name:   "username",
+			blocks: []config.Credentials{{Host: "GIT.example.com", Username: str("oauth2"), TokenEnv: str("POLICY_TOKEN")}},
+			src:    https,
+			want:   &Credentials{Username: "oauth2", Password: "env-secret"},
+		},
+		{
+			name:   "netrc",
+			blocks: []config.Credentials{{Host: "git.example.com", Netrc: true}},
+			src:    https,
+			want:   &Credentials{Username: "deploy", Password: "netrc-secret"},
+		},
+		{
+			name:   "netrc default",
+			blocks: []config.Credentials{{Host: "other.example.com", Netrc: true}},
+			src:    Source{Kind: Git, URL: "https://other.example.com/policies.git"},
+			want:   &Credentials{Username: "anyone", Password: "default-secret"},
+		},
+		{
+			name:   "helper token",
+			blocks: []config.Credentials{{Host: "git.example.com", Helper: []string{"sh", "-c", "echo helper-secret"}}},
+			src:    https,
+			want:   &Credentials{Username: DefaultUsername, Password: "helper-secret"},
+		},
+		{
+			name:   "helper key values",
+			blocks: []config.Credentials{{Host: "git.example.com", Helper: []string{"sh", "-c", `printf 'username=bot\npassword=%s\n' "$PLANGUARD_CREDENTIALS_HOST"`}}},
+			src:    https,
+			want:   &Credentials{Username: "bot", Password: "git.example.com"},
+		},
+		{
+			name:   "other host",
+			blocks: []config.Credentials{{Host: "github.com", TokenEnv: str("POLICY_TOKEN")}},
+			src:    https,
+		},
+		{
+			name:   "ssh",
+			blocks: []config.Credentials{{Host: "git.example.com", TokenEnv: str("POLICY_TOKEN")}},
+			src:    Source{Kind: Git, URL: "git@git.example.com:org/policies.git"},
+		},
+		{
+			name:    "unset variable",
+			blocks:  []config.Credentials{{Host: "git.example.com", TokenEnv: str("EMPTY_TOKEN")}},
+			src:     https,
+			wantErr: "environment variable EMPTY_TOKEN is not set",
+		},
+		{
+			name:    "two sources",
+			blocks:  []config.Credentials{{Host: "git.example.com", TokenEnv: str("POLICY_TOKEN"), Netrc: true}},
+			src:     https,
+			wantErr: "set exactly one of token_env, netrc, and helper",
+		},
+		{
+			name:    "plain http",
+			blocks:  []config.Credentials{{Host: "git.example.com", Helper: []string{"sh", "-c", "echo helper-secret"}}},
+			src:     Source{Kind: Git, URL: "http://git.example.com/org/policies.git"},
+			wantErr: "refusing to send them over http://",
+		},
+		{
+			name:   "plain http without credentials",
+			blocks: []config.Credentials{{Host: "github.com", TokenEnv: str("POLICY_TOKEN")}},
+			src:    Source{Kind: Git, URL: "http://git.example.com/org/policies.git"},
+		},
+		{
+			name:    "failing helper",
+			blocks:  []config.Credentials{{Host: "git.example.com", Helper: []string{"sh", "-c", "echo locked >&2; exit 1"}}},
+			src:     https,
+			wantErr: "helper sh failed: locked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveCredentials(tt.blocks, tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveCredentials() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveCredentials() error = %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("ResolveCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthEnv(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_COUNT", "")
+
+	src := Source{Kind: Git, URL: "https://git.example.com/org/policies.git"}
+	env := authEnv(src, &Credentials{Username: "bot", Password: "secret"})
+
+	// git applies the header to the source's host, and only to it
+	header := func(url string) string {
+		cmd := exec.Command("git", "config", "--get-urlmatch", "http.extraHeader", url)
+		cmd.Env = append(os.Environ(), env...)
+		output, _ := cmd.Output()
+		return strings.TrimSpace(string(output))
+	}
+	want := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("bot:secret"))
+	if got := header(src.URL); got != want {
+		t.Errorf("header for the source = %q, want %q", got, want)
+	}
+	if got := header("https://github.com/org/policies.git"); got != "" {
+		t.Errorf("header for another host = %q, want none", got)
+	}
+	if env := authEnv(src, nil); env != nil {
+		t.Errorf("authEnv() without credentials = %q, want nil", env)
+	}
+}
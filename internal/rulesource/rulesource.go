@@ -0,0 +1,289 @@
+// Package rulesource fetches rules from remote locations named in the
+// rule_sources setting: git repositories, e.g.
+//
+//	git::https://github.com/org/policies.git//rules?ref=v1.2.0
+//
+// and S3 or Cloud Storage prefixes, e.g. s3://bucket/policies. Copies are
+// cached on disk, so a git source pinned to a commit is only fetched once
+// and others can be used offline from their last fetch.
+package rulesource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Kinds of source
+const (
+	Git = "git"
+	S3  = "s3"
+	GCS = "gs"
+)
+
+// Source is a parsed rule source
+type Source struct {
+	Kind   string // Git, S3, or GCS
+	URL    string // Repository to clone, or s3:// or gs:// prefix to copy
+	Ref    string // Git branch, tag, or commit; empty for the default branch
+	Subdir string // Directory within the repository holding the rules
+
+	Auth *Credentials // Credentials for fetching a git source over HTTPS, if any
+}
+
+// commitPattern matches a full commit hash, which never moves
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Parse parses a rule source of the form git::<url>[//<subdir>][?ref=<ref>].
+// As in Terraform module sources, the query may also come before the
+// subdirectory.
+// Bucket sources are s3://<bucket>[/<prefix>] or gs://<bucket>[/<prefix>].
+func Parse(spec string) (Source, error) {
+	for _, kind := range []string{S3, GCS} {
+		if rest, ok := strings.CutPrefix(spec, kind+"://"); ok {
+			bucket, _, _ := strings.Cut(rest, "/")
+			if bucket == "" || strings.ContainsAny(rest, "?#") {
+				return Source{}, fmt.Errorf("invalid rule source %q (expected %s://<bucket>[/<prefix>])", spec, kind)
+			}
+			return Source{Kind: kind, URL: strings.TrimSuffix(spec, "/")}, nil
+		}
+	}
+
+	rest, ok := strings.CutPrefix(spec, "git::")
+	if !ok {
+		return Source{}, fmt.Errorf("unsupported rule source %q (expected git::<url>[//<dir>][?ref=<ref>], s3://<bucket>/<prefix>, or gs://<bucket>/<prefix>)", spec)
+	}
+
+	var query string
+	if i := strings.Index(rest, "?"); i >= 0 {
+		query = rest[i+1:]
+		rest = rest[:i]
+		// The subdirectory may follow the query
+		if j := strings.Index(query, "//"); j >= 0 {
+			rest += query[j:]
+			query = query[:j]
+		}
+	}
+
+	src := Source{Kind: Git}
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(param, "=")
+		if key != "ref" || value == "" {
+			return Source{}, fmt.Errorf("unsupported parameter %q in rule source %q (expected ref=<ref>)", param, spec)
+		}
+		src.Ref = value
+	}
+
+	// The subdirectory starts at the first // after the URL's scheme
+	start := 0
+	if i := strings.Index(rest, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(rest[start:], "//"); i >= 0 {
+		src.Subdir = strings.Trim(rest[start+i+2:], "/")
+		rest = rest[:start+i]
+	}
+	src.URL = rest
+	if src.URL == "" {
+		return Source{}, fmt.Errorf("rule source %q has no repository URL", spec)
+	}
+	// git would take a URL or ref starting with - for an option
+	if strings.HasPrefix(src.URL, "-") || strings.HasPrefix(src.Ref, "-") {
+		return Source{}, fmt.Errorf("rule source %q has a URL or ref starting with -", spec)
+	}
+	if src.Subdir != "" && (filepath.IsAbs(src.Subdir) || strings.HasPrefix(filepath.Clean(src.Subdir), "..")) {
+		return Source{}, fmt.Errorf("rule source %q has a directory outside the repository", spec)
+	}
+	return src, nil
+}
+
+// String formats the source as it is written in rule_sources
+func (s Source) String() string {
+	if s.Kind != Git {
+		return s.URL
+	}
+	out := "git::" + s.URL
+	if s.Subdir != "" {
+		out += "//" + s.Subdir
+	}
+	if s.Ref != "" {
+		out += "?ref=" + s.Ref
+	}
+	return out
+}
+
+// Fetch copies the source under cacheDir and returns the directory holding
+// its rules. A cached checkout of a git commit is used without fetching;
+// other sources are fetched again, falling back to the cached copy with a
+// warning when the fetch fails, e.g. offline.
+func Fetch(src Source, cacheDir string) (dir string, warning string, err error) {
+	sum := sha256.Sum256([]byte(src.URL + "\x00" + src.Ref))
+	checkout := filepath.Join(cacheDir, hex.EncodeToString(sum[:8]))
+	if src.Kind != Git {
+		return fetchBucket(src, checkout)
+	}
+	dir = filepath.Join(checkout, filepath.FromSlash(src.Subdir))
+
+	_, statErr := os.Stat(filepath.Join(checkout, ".git"))
+	cached := statErr == nil
+	if cached && commitPattern.MatchString(src.Ref) {
+		return dir, "", checkDir(src, dir)
+	}
+
+	env := authEnv(src, src.Auth)
+	if cached {
+		if err := update(checkout, src.Ref, env); err != nil {
+			warning = fmt.Sprintf("failed to update rule source %s, using the copy fetched before: %v", src, err)
+		}
+		return dir, warning, checkDir(src, dir)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create rule source cache: %w", err)
+	}
+	// Fetch into a temporary directory so an interrupted fetch never
+	// leaves a partial checkout behind
+	tmp, err := os.MkdirTemp(cacheDir, "fetch-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create rule source cache: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if _, err := git(tmp, "init", "-q"); err != nil {
+		return "", "", fmt.Errorf("failed to fetch rule source %s: %w", src, err)
+	}
+	if _, err := git(tmp, "remote", "add", "--", "origin", src.URL); err != nil {
+		return "", "", fmt.Errorf("failed to fetch rule source %s: %w", src, err)
+	}
+	if err := update(tmp, src.Ref, env); err != nil {
+		return "", "", fmt.Errorf("failed to fetch rule source %s: %w", src, err)
+	}
+	if err := os.Rename(tmp, checkout); err != nil && !cachedBy(checkout) {
+		return "", "", fmt.Errorf("failed to cache rule source %s: %w", src, err)
+	}
+	return dir, "", checkDir(src, dir)
+}
+
+// fetchBucket syncs an S3 or Cloud Storage prefix into dir with the aws or
+// gcloud CLI, which find credentials as they do for any other command:
+// from the environment, config files, or the instance's role
+func fetchBucket(src Source, dir string) (string, string, error) {
+	_, statErr := os.Stat(dir)
+	cached := statErr == nil
+
+	var name string
+	var args []string
+	switch src.Kind {
+	case S3:
+		name, args = "aws", []string{"s3", "sync", "--delete", "--only-show-errors", src.URL, dir}
+	case GCS:
+		name, args = "gcloud", []string{"storage", "rsync", "--recursive", "--delete-unmatched-destination-objects", src.URL, dir}
+	}
+
+	if _, err := exec.LookPath(name); err != nil && !cached {
+		return "", "", fmt.Errorf("fetching rule source %s needs the %s CLI, which is not on PATH", src, name)
+	}
+
+	if !cached {
+		// Sync into a temporary directory so an interrupted copy never
+		// looks complete
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create rule source cache: %w", err)
+		}
+		tmp, err := os.MkdirTemp(filepath.Dir(dir), "fetch-*")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create rule source cache: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		args[len(args)-1] = tmp
+		if _, err := run("", nil, name, args...); err != nil {
+			return "", "", fmt.Errorf("failed to fetch rule source %s: %w", src, err)
+		}
+		if err := os.Rename(tmp, dir); err != nil {
+			if _, statErr := os.Stat(dir); statErr != nil {
+				return "", "", fmt.Errorf("failed to cache rule source %s: %w", src, err)
+			}
+		}
+		return dir, "", nil
+	}
+
+	if _, err := run("", nil, name, args...); err != nil {
+		return dir, fmt.Sprintf("failed to update rule source %s, using the copy fetched before: %v", src, err), nil
+	}
+	return dir, "", nil
+}
+
+// Commit returns the commit checked out in dir, a directory returned by
+// Fetch for a git source
+func Commit(dir string) (string, error) {
+	out, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// update fetches ref, or the default branch, into the checkout in dir and
+// checks it out. env adds to the environment of the fetch. The ref follows
+// --, so it is never taken for an option, even when it comes from a lock
+// file rather than Parse.
+func update(dir, ref string, env []string) error {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := run(dir, env, "git", "fetch", "-q", "--depth", "1", "--", "origin", ref); err != nil {
+		return err
+	}
+	_, err := git(dir, "checkout", "-q", "--force", "FETCH_HEAD")
+	return err
+}
+
+// cachedBy reports whether another run cached the checkout first
+func cachedBy(checkout string) bool {
+	_, err := os.Stat(filepath.Join(checkout, ".git"))
+	return err == nil
+}
+
+func checkDir(src Source, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("rule source %s has no directory %s", src, src.Subdir)
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its standard output
+func git(dir string, args ...string) (string, error) {
+	return run(dir, nil, "git", args...)
+}
+
+// run runs a command in dir, with env added to its environment, and
+// returns its standard output. Errors include what the command printed on
+// stderr.
+func run(dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	// Never wait on a credentials prompt
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %s", name, args[0], msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,220 @@
+package rulesource
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Source
+		wantErr bool
+	}{
+		{
+			spec: "git::https://github.com/org/policies.git",
+			want: Source{Kind: Git, URL: "https://github.com/org/policies.git"},
+		},
+		{
+			spec: "git::https://github.com/org/policies.git?ref=v1.2.0//rules",
+			want: Source{Kind: Git, URL: "https://github.com/org/policies.git", Ref: "v1.2.0", Subdir: "rules"},
+		},
+		{
+			spec: "git::https://github.com/org/policies.git//rules/aws?ref=main",
+			want: Source{Kind: Git, URL: "https://github.com/org/policies.git", Ref: "main", Subdir: "rules/aws"},
+		},
+		{
+			spec: "git::git@github.com:org/policies.git//rules",
+			want: Source{Kind: Git, URL: "git@github.com:org/policies.git", Subdir: "rules"},
+		},
+		{
+			spec: "git::ssh://git@github.com/org/policies.git?ref=0123456789abcdef0123456789abcdef01234567",
+			want: Source{Kind: Git, URL: "ssh://git@github.com/org/policies.git", Ref: "0123456789abcdef0123456789abcdef01234567"},
+		},
+		{spec: "s3://policies/terraform/", want: Source{Kind: S3, URL: "s3://policies/terraform"}},
+		{spec: "gs://policies", want: Source{Kind: GCS, URL: "gs://policies"}},
+		{spec: "s3:///terraform", wantErr: true},
+		{spec: "gs://policies?generation=1", wantErr: true},
+		{spec: "https://github.com/org/policies.git", wantErr: true},
+		{spec: "git::https://github.com/org/policies.git?depth=1", wantErr: true},
+		{spec: "git::https://github.com/org/policies.git//../etc", wantErr: true},
+		{spec: "git::", wantErr: true},
+		{spec: "git::https://github.com/org/policies.git?ref=--upload-pack=touch /tmp/pwned", wantErr: true},
+		{spec: "git::--upload-pack=touch /tmp/pwned", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(repo, "rules"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, "rules", "rule.hcl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(dir string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, "rule.hcl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	run("init", "-q", "-b", "main")
+	write("v1")
+	run("add", ".")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1")
+	commit := run("rev-parse", "HEAD")
+	write("v2")
+	run("commit", "-q", "-am", "v2")
+
+	cacheDir := t.TempDir()
+	url := "file://" + repo
+	tests := []struct {
+		name string
+		src  Source
+		want string
+	}{
+		{"default branch", Source{Kind: Git, URL: url, Subdir: "rules"}, "v2"},
+		{"tag", Source{Kind: Git, URL: url, Ref: "v1", Subdir: "rules"}, "v1"},
+		{"commit", Source{Kind: Git, URL: url, Ref: commit, Subdir: "rules"}, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, warning, err := Fetch(tt.src, cacheDir)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if warning != "" {
+				t.Errorf("Fetch() warning = %q", warning)
+			}
+			if got := read(dir); got != tt.want {
+				t.Errorf("rule.hcl = %q, want %q", got, tt.want)
+			}
+			if got, err := Commit(dir); err != nil || (tt.want == "v1" && got != commit) {
+				t.Errorf("Commit() = %s, %v; want %s", got, err, commit)
+			}
+		})
+	}
+
+	// A ref that looks like an option is passed to git as a ref
+	marker := filepath.Join(t.TempDir(), "pwned")
+	if _, _, err := Fetch(Source{Kind: Git, URL: url, Ref: "--upload-pack=touch " + marker + "; false"}, t.TempDir()); err == nil {
+		t.Error("Fetch() of an option-like ref succeeded")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Fetch() ran the command in an option-like ref")
+	}
+
+	// Branches are fetched again; a commit is not
+	write("v3")
+	run("commit", "-q", "-am", "v3")
+	dir, _, err := Fetch(Source{Kind: Git, URL: url, Subdir: "rules"}, cacheDir)
+	if err != nil || read(dir) != "v3" {
+		t.Errorf("Fetch() of updated branch = %v, want v3", err)
+	}
+
+	// Cached checkouts are used when the repository is unreachable
+	if err := os.RemoveAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	dir, warning, err := Fetch(Source{Kind: Git, URL: url, Subdir: "rules"}, cacheDir)
+	if err != nil || warning == "" || read(dir) != "v3" {
+		t.Errorf("Fetch() offline = %v, warning %q; want the cached checkout with a warning", err, warning)
+	}
+	if _, warning, err := Fetch(Source{Kind: Git, URL: url, Ref: commit, Subdir: "rules"}, cacheDir); err != nil || warning != "" {
+		t.Errorf("Fetch() of cached commit = %v, warning %q; want no fetch", err, warning)
+	}
+	if _, _, err := Fetch(Source{Kind: Git, URL: url, Ref: "v2"}, cacheDir); err == nil {
+		t.Error("Fetch() of an uncached source offline succeeded")
+	}
+	if _, _, err := Fetch(Source{Kind: Git, URL: url, Ref: commit, Subdir: "missing"}, cacheDir); err == nil {
+		t.Error("Fetch() of a missing directory succeeded")
+	}
+}
+
+func TestFetchBucket(t *testing.T) {
+	// A stand-in for the aws CLI that copies $BUCKET into the destination
+	bin := t.TempDir()
+	script := "#!/bin/sh\n[ -n \"$FAIL\" ] && { echo 'Unable to locate credentials' >&2; exit 1; }\nrm -rf \"$6\" && mkdir -p \"$6\" && cp -R \"$BUCKET\"/. \"$6\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "aws"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	bucket := t.TempDir()
+	t.Setenv("BUCKET", bucket)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(bucket, "rule.hcl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := Source{Kind: S3, URL: "s3://policies/terraform"}
+	cacheDir := t.TempDir()
+	for _, content := range []string{"v1", "v2"} {
+		write(content)
+		dir, warning, err := Fetch(src, cacheDir)
+		if err != nil || warning != "" {
+			t.Fatalf("Fetch() = %v, warning %q", err, warning)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "rule.hcl")); string(data) != content {
+			t.Errorf("rule.hcl = %q, want %q", data, content)
+		}
+	}
+
+	// The last copy is used when the bucket cannot be read
+	t.Setenv("FAIL", "1")
+	dir, warning, err := Fetch(src, cacheDir)
+	if err != nil || !strings.Contains(warning, "Unable to locate credentials") {
+		t.Errorf("Fetch() = %v, warning %q; want the cached copy with a warning", err, warning)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "rule.hcl")); string(data) != "v2" {
+		t.Errorf("rule.hcl = %q, want the cached v2", data)
+	}
+	if _, _, err := Fetch(Source{Kind: S3, URL: "s3://policies/other"}, cacheDir); err == nil {
+		t.Error("Fetch() of an uncached prefix succeeded")
+	}
+	if _, _, err := Fetch(Source{Kind: GCS, URL: "gs://policies"}, cacheDir); err == nil {
+		t.Error("Fetch() without the gcloud CLI succeeded")
+	}
+}
@@ -0,0 +1,139 @@
+package scaffold
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConfigTemplate is the config written by planguard init: the default
+// settings with comments, and an example rule and exception, commented
+// out so they change nothing until edited
+const ConfigTemplate = `# Planguard configuration
+# Generated by planguard init. Run planguard scan to scan with it.
+
+settings {
+  # Exit non-zero on warnings, not only errors (default: false)
+  fail_on_warning = false
+
+  # Paths left out of scans (glob patterns)
+  exclude_paths = [
+    "**/.terraform/**",
+    "**/node_modules/**"
+  ]
+
+  # Load the presupplied rules from the rules directory
+  # (~/.planguard/rules, or -rules-dir) (default: true)
+  use_presupplied_rules = true
+
+  # Only load presupplied rules from these categories (default: all but
+  # the opt-in hygiene, unreferenced, and orphans); planguard rules
+  # categories lists them
+  # presupplied_rules_categories = ["aws", "security"]
+
+  # Turn off presupplied or custom rules by ID
+  # disabled_rules = ["aws_s3_versioning"]
+
+  # Turn on opt-in rules, defined with enabled = false, by ID
+  # enable_rules = ["my_strict_rule"]
+
+  # Fail the scan on problems in config and rule files, such as an invalid
+  # severity or an unknown attribute, instead of warning (default: false)
+  # strict = true
+
+  # Report exceptions expiring within this many days (default: 14)
+  # exception_expiry_warning_days = 14
+}
+
+# ====================================================================
+# CUSTOM RULES
+# ====================================================================
+# Rules defined here run alongside the presupplied rules. Uncomment this
+# example to require an Owner tag on S3 buckets.
+
+# rule "example_s3_owner_tag" {
+#   name          = "S3 buckets need an Owner tag"
+#   severity      = "warning"
+#   resource_type = "aws_s3_bucket"
+#
+#   condition {
+#     expression = "!has(try(self.tags, {}), \"Owner\")"
+#   }
+#
+#   message     = "Add an Owner tag so the bucket can be traced to a team"
+#   remediation = "tags = { Owner = \"team-name\" }"
+# }
+
+# ====================================================================
+# EXCEPTIONS
+# ====================================================================
+# Exceptions allow specific violations with a recorded justification.
+# rules, reason, and approved_by are required; paths, resource_names,
+# ticket, and expires_at (YYYY-MM-DD) narrow and track them.
+
+# exception {
+#   rules          = ["aws_s3_versioning"]
+#   paths          = ["modules/scratch/*.tf"]
+#   resource_names = ["scratch_*"]
+#   reason         = "Scratch buckets hold reproducible data"
+#   approved_by    = "platform-team@example.com"
+#   ticket         = "PLAT-123"
+#   expires_at     = "2030-12-31"
+# }
+`
+
+// WriteConfig writes ConfigTemplate to path, creating its directory. An
+// existing file is only replaced when overwrite is set; otherwise the error
+// wraps fs.ErrExist.
+func WriteConfig(path string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists (pass -force to replace it): %w", path, fs.ErrExist)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(ConfigTemplate), 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// CopyRules copies the rule files in rules, laid out like a rules
+// directory, into dir. Files that already exist are skipped unless
+// overwrite is set, so local edits survive a second run.
+func CopyRules(rules fs.FS, dir string, overwrite bool) (written, skipped int, err error) {
+	err = fs.WalkDir(rules, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if filepath.Ext(path) != ".hcl" {
+			return nil
+		}
+		if !overwrite {
+			if _, err := os.Stat(target); err == nil {
+				skipped++
+				return nil
+			}
+		}
+		data, err := fs.ReadFile(rules, path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, skipped, fmt.Errorf("failed to copy rules to %s: %w", dir, err)
+	}
+	return written, skipped, nil
+}
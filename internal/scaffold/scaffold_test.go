@@ -0,0 +1,107 @@
+package scaffold
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestWriteConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".planguard", "config.hcl")
+	if err := WriteConfig(path, false); err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.Rules) != 0 || len(cfg.Exceptions) != 0 {
+		t.Errorf("Expected the examples to be commented out, got %d rules and %d exceptions", len(cfg.Rules), len(cfg.Exceptions))
+	}
+	if cfg.Settings == nil || cfg.Settings.UsePresuppliedRules == nil || !*cfg.Settings.UsePresuppliedRules {
+		t.Errorf("Expected use_presupplied_rules = true, got %+v", cfg.Settings)
+	}
+
+	if err := WriteConfig(path, false); !errors.Is(err, fs.ErrExist) || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("WriteConfig() over an existing file error = %v, want a hint to pass -force", err)
+	}
+	if err := WriteConfig(path, true); err != nil {
+		t.Errorf("WriteConfig() with overwrite error = %v", err)
+	}
+}
+
+// TestConfigTemplateExamples checks that the commented-out examples are
+// valid once uncommented
+func TestConfigTemplateExamples(t *testing.T) {
+	var lines []string
+	inExample := false
+	for _, line := range strings.Split(ConfigTemplate, "\n") {
+		if strings.HasPrefix(line, "# rule \"") || strings.HasPrefix(line, "# exception {") {
+			inExample = true
+		}
+		if inExample {
+			line = strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+			inExample = line != "}"
+		}
+		lines = append(lines, line)
+	}
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() of the uncommented template error = %v", err)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].ID != "example_s3_owner_tag" {
+		t.Errorf("Rules = %+v, want example_s3_owner_tag", cfg.Rules)
+	}
+	if len(cfg.Exceptions) != 1 || cfg.Exceptions[0].Rules[0] != "aws_s3_versioning" {
+		t.Errorf("Exceptions = %+v, want one for aws_s3_versioning", cfg.Exceptions)
+	}
+}
+
+func TestCopyRules(t *testing.T) {
+	rules := fstest.MapFS{
+		"aws/s3.hcl":         {Data: []byte("# s3")},
+		"aws/README.md":      {Data: []byte("not a rule")},
+		"common/tagging.hcl": {Data: []byte("# tagging")},
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "aws"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "aws", "s3.hcl"), []byte("# edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, skipped, err := CopyRules(rules, dir, false)
+	if err != nil {
+		t.Fatalf("CopyRules() error = %v", err)
+	}
+	if written != 1 || skipped != 1 {
+		t.Errorf("CopyRules() = %d written, %d skipped, want 1, 1", written, skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "aws", "s3.hcl")); string(data) != "# edited" {
+		t.Errorf("Expected an existing rule file to be kept, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "aws", "README.md")); err == nil {
+		t.Errorf("Expected only .hcl files to be copied")
+	}
+
+	written, skipped, err = CopyRules(rules, dir, true)
+	if err != nil || written != 2 || skipped != 0 {
+		t.Errorf("CopyRules() with overwrite = %d, %d, %v, want 2, 0, nil", written, skipped, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "aws", "s3.hcl")); string(data) != "# s3" {
+		t.Errorf("Expected the rule file to be replaced, got %q", data)
+	}
+}
@@ -0,0 +1,215 @@
+// Package ticketcheck validates the tickets exceptions reference, as set by
+// the ticket_validation setting, so exceptions cannot be approved against
+// made-up or long-closed tickets. Tickets are checked against a pattern
+// and, optionally, looked up in the issue tracker's API, e.g. Jira's:
+//
+//	ticket_validation {
+//	  pattern = "^SEC-[0-9]+$"
+//	  url     = "https://example.atlassian.net/rest/api/2/issue/{ticket}"
+//	}
+//
+// Requests authenticate with PLANGUARD_TICKET_TOKEN, as a bearer token or,
+// with PLANGUARD_TICKET_USER, a basic auth password.
+package ticketcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Environment variables with the tracker's credentials
+const (
+	TokenEnv = "PLANGUARD_TICKET_TOKEN"
+	UserEnv  = "PLANGUARD_TICKET_USER"
+)
+
+// DefaultStatusField is where Jira's issue API has the status name
+const DefaultStatusField = "fields.status.name"
+
+// DefaultClosedStatuses are the statuses of closed tickets
+var DefaultClosedStatuses = []string{"Done", "Closed", "Resolved"}
+
+// Problem is an active exception whose ticket failed validation
+type Problem struct {
+	Exception  config.Exception
+	Message    string
+	Unverified bool // The tracker could not be asked, so the ticket may be fine
+}
+
+// Checker validates tickets, looking each up at most once
+type Checker struct {
+	pattern     *regexp.Regexp
+	url         string
+	statusField string
+	closed      []string
+	client      *http.Client
+	lookups     map[string]lookup
+}
+
+// lookup is the result of looking up a ticket
+type lookup struct {
+	status string
+	found  bool
+	err    error
+}
+
+// New returns a Checker for settings
+func New(settings config.TicketValidation, client *http.Client) (*Checker, error) {
+	c := &Checker{
+		statusField: DefaultStatusField,
+		closed:      DefaultClosedStatuses,
+		client:      client,
+		lookups:     make(map[string]lookup),
+	}
+	if settings.OnFailure != nil && *settings.OnFailure != "error" && *settings.OnFailure != "warning" {
+		return nil, fmt.Errorf("invalid ticket_validation on_failure %q (expected error or warning)", *settings.OnFailure)
+	}
+	if settings.Pattern != nil {
+		pattern, err := regexp.Compile(*settings.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket_validation pattern: %w", err)
+		}
+		c.pattern = pattern
+	}
+	if settings.URL != nil {
+		if !strings.Contains(*settings.URL, "{ticket}") {
+			return nil, fmt.Errorf("invalid ticket_validation url %q (expected {ticket} where the ticket goes)", *settings.URL)
+		}
+		c.url = *settings.URL
+	}
+	if settings.StatusField != nil {
+		c.statusField = *settings.StatusField
+	}
+	if settings.ClosedStatuses != nil {
+		c.closed = settings.ClosedStatuses
+	}
+	return c, nil
+}
+
+// Check validates the tickets of the exceptions active at now. Expired
+// exceptions no longer apply, so they are not checked.
+func (c *Checker) Check(exceptions []config.Exception, now time.Time) []Problem {
+	var problems []Problem
+	for _, exception := range exceptions {
+		if exception.Expired(now) {
+			continue
+		}
+		if message, unverified := c.check(exception); message != "" {
+			problems = append(problems, Problem{Exception: exception, Message: message, Unverified: unverified})
+		}
+	}
+	return problems
+}
+
+// check returns what is wrong with an exception's ticket, if anything
+func (c *Checker) check(exception config.Exception) (message string, unverified bool) {
+	if exception.Ticket == nil || strings.TrimSpace(*exception.Ticket) == "" {
+		return "has no ticket", false
+	}
+	ticket := *exception.Ticket
+	if c.pattern != nil && !c.pattern.MatchString(ticket) {
+		return fmt.Sprintf("ticket %q does not match %s", ticket, c.pattern), false
+	}
+	if c.url == "" {
+		return "", false
+	}
+
+	result, ok := c.lookups[ticket]
+	if !ok {
+		result = c.lookup(ticket)
+		c.lookups[ticket] = result
+	}
+	switch {
+	case result.err != nil:
+		return fmt.Sprintf("ticket %s could not be checked: %v", ticket, result.err), true
+	case !result.found:
+		return fmt.Sprintf("ticket %s does not exist", ticket), false
+	}
+	for _, closed := range c.closed {
+		if strings.EqualFold(result.status, closed) {
+			return fmt.Sprintf("ticket %s is closed (%s)", ticket, result.status), false
+		}
+	}
+	return "", false
+}
+
+// lookup fetches a ticket from the tracker and finds its status
+func (c *Checker) lookup(ticket string) lookup {
+	req, err := http.NewRequest(http.MethodGet, strings.ReplaceAll(c.url, "{ticket}", url.PathEscape(ticket)), nil)
+	if err != nil {
+		return lookup{err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := os.Getenv(TokenEnv); token != "" {
+		if user := os.Getenv(UserEnv); user != "" {
+			req.SetBasicAuth(user, token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return lookup{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return lookup{}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return lookup{err: fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return lookup{err: err}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return lookup{err: fmt.Errorf("invalid JSON response: %w", err)}
+	}
+	// A query with no results, e.g. ServiceNow's empty result list, has no
+	// status: the ticket does not exist
+	status, ok := field(doc, c.statusField)
+	if !ok {
+		return lookup{}
+	}
+	return lookup{status: status, found: true}
+}
+
+// field returns the value at a dotted path in a JSON document. Numeric
+// parts index arrays.
+func field(doc interface{}, path string) (string, bool) {
+	for _, part := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[part]
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+			doc = v[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := doc.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return "", false
+	case string:
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
@@ -0,0 +1,129 @@
+package ticketcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestCheck(t *testing.T) {
+	requests := make(map[string]int)
+	tracker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := strings.TrimPrefix(r.URL.Path, "/issue/")
+		requests[ticket]++
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the token", got)
+		}
+		switch ticket {
+		case "SEC-1":
+			fmt.Fprint(w, `{"fields": {"status": {"name": "In Progress"}}}`)
+		case "SEC-2":
+			fmt.Fprint(w, `{"fields": {"status": {"name": "Done"}}}`)
+		case "SEC-4":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer tracker.Close()
+	t.Setenv(TokenEnv, "secret")
+	t.Setenv(UserEnv, "")
+
+	pattern := `^SEC-[0-9]+$`
+	url := tracker.URL + "/issue/{ticket}"
+	checker, err := New(config.TicketValidation{Pattern: &pattern, URL: &url}, tracker.Client())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ticket := func(s string) *string { return &s }
+	expired := "2020-01-01"
+	exceptions := []config.Exception{
+		{Rules: []string{"open"}, Ticket: ticket("SEC-1")},
+		{Rules: []string{"open again"}, Ticket: ticket("SEC-1")},
+		{Rules: []string{"closed"}, Ticket: ticket("SEC-2")},
+		{Rules: []string{"missing"}, Ticket: ticket("SEC-3")},
+		{Rules: []string{"unreachable"}, Ticket: ticket("SEC-4")},
+		{Rules: []string{"malformed"}, Ticket: ticket("sec 5")},
+		{Rules: []string{"none"}},
+		{Rules: []string{"expired"}, ExpiresAt: &expired},
+	}
+	problems := checker.Check(exceptions, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	want := map[string]struct {
+		message    string
+		unverified bool
+	}{
+		"closed":      {"ticket SEC-2 is closed (Done)", false},
+		"missing":     {"ticket SEC-3 does not exist", false},
+		"unreachable": {"ticket SEC-4 could not be checked", true},
+		"malformed":   {`ticket "sec 5" does not match`, false},
+		"none":        {"has no ticket", false},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems, want %d: %+v", len(problems), len(want), problems)
+	}
+	for _, problem := range problems {
+		w, ok := want[problem.Exception.Rules[0]]
+		if !ok {
+			t.Errorf("unexpected problem %+v", problem)
+			continue
+		}
+		if !strings.Contains(problem.Message, w.message) || problem.Unverified != w.unverified {
+			t.Errorf("problem for %s = %q (unverified %v), want %q (unverified %v)", problem.Exception.Rules[0], problem.Message, problem.Unverified, w.message, w.unverified)
+		}
+	}
+	if requests["SEC-1"] != 1 {
+		t.Errorf("SEC-1 was looked up %d times, want once", requests["SEC-1"])
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	invalid := func(s string) *string { return &s }
+	tests := []struct {
+		name     string
+		settings config.TicketValidation
+		wantErr  string
+	}{
+		{"pattern", config.TicketValidation{Pattern: invalid("(")}, "invalid ticket_validation pattern"},
+		{"url", config.TicketValidation{URL: invalid("https://jira.example.com/issue")}, "expected {ticket}"},
+		{"on_failure", config.TicketValidation{OnFailure: invalid("ignore")}, `invalid ticket_validation on_failure "ignore"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.settings, http.DefaultClient)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    interface{}
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"jira", map[string]interface{}{"fields": map[string]interface{}{"status": map[string]interface{}{"name": "Done"}}}, "fields.status.name", "Done", true},
+		{"servicenow", map[string]interface{}{"result": []interface{}{map[string]interface{}{"state": float64(7)}}}, "result.0.state", "7", true},
+		{"empty result", map[string]interface{}{"result": []interface{}{}}, "result.0.state", "", false},
+		{"missing", map[string]interface{}{"fields": nil}, "fields.status.name", "", false},
+		{"object", map[string]interface{}{"status": map[string]interface{}{}}, "status", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := field(tt.doc, tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("field() = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
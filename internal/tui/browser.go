@@ -0,0 +1,335 @@
+// Package tui is an interactive terminal browser for scan results: the
+// violations grouped by file, rule, or severity, with the source and
+// remediation of the selected one, and marks that become exceptions.
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// groupings are the ways violations can be grouped, in the order the g
+// key cycles through them
+var groupings = []string{"file", "rule", "severity"}
+
+// severityOrder sorts severity groups most severe first
+var severityOrder = map[string]int{"error": 0, "warning": 1, "info": 2}
+
+// Browser is the state of the browser: how violations are grouped, which
+// one is selected, and which are marked. It draws into lines of text, so
+// it can be driven without a terminal.
+type Browser struct {
+	violations []config.Violation
+	grouping   int          // Index into groupings
+	order      []int        // Violations in display order, by index
+	rows       []row        // Group headers and violations, in display order
+	rowOf      []int        // Row of each violation in order
+	cursor     int          // Position of the selected violation in order
+	offset     int          // First row in view
+	page       int          // Rows in view at the last render
+	marked     map[int]bool // Marked violations, by index
+}
+
+// row is a line of the list: a group header, or a violation
+type row struct {
+	header    string
+	violation int // Index into violations, or -1 for a header
+}
+
+// NewBrowser returns a browser of violations, grouped by file
+func NewBrowser(violations []config.Violation) *Browser {
+	b := &Browser{violations: violations, marked: make(map[int]bool), page: 10}
+	b.regroup()
+	return b
+}
+
+// Handle applies a key press, reporting whether it quits the browser
+func (b *Browser) Handle(key Key) (quit bool) {
+	switch key {
+	case KeyUp, "k":
+		b.move(-1)
+	case KeyDown, "j":
+		b.move(1)
+	case KeyPageUp:
+		b.move(-b.page)
+	case KeyPageDown:
+		b.move(b.page)
+	case KeyHome:
+		b.move(-len(b.order))
+	case KeyEnd:
+		b.move(len(b.order))
+	case " ", "m":
+		if len(b.order) > 0 {
+			i := b.order[b.cursor]
+			if b.marked[i] {
+				delete(b.marked, i)
+			} else {
+				b.marked[i] = true
+			}
+		}
+	case "g":
+		b.grouping = (b.grouping + 1) % len(groupings)
+		b.regroup()
+	case "q", KeyCtrlC:
+		return true
+	}
+	return false
+}
+
+// Marked returns the marked violations, in the order they were given
+func (b *Browser) Marked() []config.Violation {
+	var marked []config.Violation
+	for i, v := range b.violations {
+		if b.marked[i] {
+			marked = append(marked, v)
+		}
+	}
+	return marked
+}
+
+func (b *Browser) move(n int) {
+	b.cursor = max(0, min(b.cursor+n, len(b.order)-1))
+}
+
+func (b *Browser) groupKey(v config.Violation) string {
+	switch groupings[b.grouping] {
+	case "rule":
+		return v.RuleID
+	case "severity":
+		return v.Severity
+	}
+	return v.File
+}
+
+// groupLess orders the groups: by severity, most severe first, or by name
+func (b *Browser) groupLess(a, c string) bool {
+	if groupings[b.grouping] == "severity" {
+		rankA, okA := severityOrder[a]
+		rankC, okC := severityOrder[c]
+		if !okA {
+			rankA = len(severityOrder)
+		}
+		if !okC {
+			rankC = len(severityOrder)
+		}
+		if rankA != rankC {
+			return rankA < rankC
+		}
+	}
+	return a < c
+}
+
+// regroup sorts the violations into groups for the current grouping,
+// keeping the selected violation selected
+func (b *Browser) regroup() {
+	selected := -1
+	if len(b.order) > 0 {
+		selected = b.order[b.cursor]
+	}
+
+	b.order = make([]int, len(b.violations))
+	for i := range b.order {
+		b.order[i] = i
+	}
+	sort.SliceStable(b.order, func(i, j int) bool {
+		a, c := b.violations[b.order[i]], b.violations[b.order[j]]
+		if keyA, keyC := b.groupKey(a), b.groupKey(c); keyA != keyC {
+			return b.groupLess(keyA, keyC)
+		}
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		return a.Line < c.Line
+	})
+
+	counts := make(map[string]int)
+	for _, v := range b.violations {
+		counts[b.groupKey(v)]++
+	}
+	b.rows = nil
+	b.rowOf = make([]int, len(b.order))
+	b.cursor, b.offset = 0, 0
+	for pos, i := range b.order {
+		v := b.violations[i]
+		key := b.groupKey(v)
+		if pos == 0 || key != b.groupKey(b.violations[b.order[pos-1]]) {
+			header := key
+			if groupings[b.grouping] == "rule" && v.RuleName != "" {
+				header += ": " + v.RuleName
+			}
+			b.rows = append(b.rows, row{header: fmt.Sprintf("%s (%d)", header, counts[key]), violation: -1})
+		}
+		b.rowOf[pos] = len(b.rows)
+		b.rows = append(b.rows, row{violation: i})
+		if i == selected {
+			b.cursor = pos
+		}
+	}
+}
+
+// Render draws the browser in width columns and height lines: a title,
+// the list of violations, the details of the selected one, and a line of
+// key help. The selected violation is shown in reverse video.
+func (b *Browser) Render(width, height int) []string {
+	title := fmt.Sprintf("%d violations by %s", len(b.violations), groupings[b.grouping])
+	if len(b.marked) > 0 {
+		title += fmt.Sprintf(", %d marked", len(b.marked))
+	}
+	next := groupings[(b.grouping+1)%len(groupings)]
+	footer := fmt.Sprintf("up/down move  space mark  g group by %s  q quit", next)
+
+	if len(b.violations) == 0 {
+		return fitLines([]string{title, "", "No violations found.", footer}, width, height)
+	}
+
+	// The details get the lines the list does not need, and at least half
+	detail := b.detail()
+	detailHeight := min(len(detail), max(height-3-len(b.rows), max(height-3, 0)/2))
+	listHeight := max(height-3-detailHeight, 1)
+	b.page = listHeight
+	b.scroll(listHeight)
+
+	lines := []string{truncate(title, width)}
+	selectedRow := b.rowOf[b.cursor]
+	for r := b.offset; r < b.offset+listHeight; r++ {
+		if r >= len(b.rows) {
+			lines = append(lines, "")
+			continue
+		}
+		line := truncate(b.rowText(b.rows[r]), width)
+		if r == selectedRow {
+			line = "\033[7m" + line + strings.Repeat(" ", max(width-len([]rune(line)), 0)) + "\033[0m"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, strings.Repeat("-", width))
+	for _, line := range detail[:detailHeight] {
+		lines = append(lines, truncate(line, width))
+	}
+	lines = append(lines, truncate(footer, width))
+	return fitLines(lines, width, height)
+}
+
+// scroll moves the view so the selected violation, and the header of its
+// group when it is first, are in it
+func (b *Browser) scroll(listHeight int) {
+	r := b.rowOf[b.cursor]
+	if r < b.offset {
+		b.offset = r
+	}
+	if r >= b.offset+listHeight {
+		b.offset = r - listHeight + 1
+	}
+	if b.offset == r && r > 0 && b.rows[r-1].violation < 0 && listHeight > 1 {
+		b.offset--
+	}
+}
+
+func (b *Browser) rowText(r row) string {
+	if r.violation < 0 {
+		return r.header
+	}
+	v := b.violations[r.violation]
+	mark := " "
+	if b.marked[r.violation] {
+		mark = "x"
+	}
+	return fmt.Sprintf("  [%s] %-7s %s:%d  %s.%s  %s", mark, v.Severity, v.File, v.Line, v.ResourceType, v.ResourceName, v.RuleID)
+}
+
+// detail describes the selected violation: its rule, message, source, and
+// remediation
+func (b *Browser) detail() []string {
+	v := b.violations[b.order[b.cursor]]
+	lines := []string{
+		fmt.Sprintf("%s (%s): %s", v.RuleID, v.Severity, v.RuleName),
+		fmt.Sprintf("%s.%s at %s:%d", v.ResourceType, v.ResourceName, v.File, v.Line),
+		"",
+	}
+	lines = append(lines, textLines(v.Message)...)
+	if v.Source != nil {
+		lines = append(lines, "")
+		for i, line := range v.Source.Lines {
+			n := v.Source.StartLine + i
+			marker := "  "
+			if n == v.Line {
+				marker = "> "
+			}
+			lines = append(lines, fmt.Sprintf("%s%4d | %s", marker, n, line))
+		}
+	}
+	if v.Remediation != "" {
+		lines = append(lines, "", "Remediation:")
+		lines = append(lines, textLines(v.Remediation)...)
+	}
+	return lines
+}
+
+// textLines splits text into lines, dropping leading and trailing blank
+// lines
+func textLines(text string) []string {
+	return strings.Split(strings.Trim(text, "\n"), "\n")
+}
+
+// truncate shortens a line to width columns, expanding tabs
+func truncate(line string, width int) string {
+	runes := []rune(strings.ReplaceAll(line, "\t", "    "))
+	if len(runes) > width {
+		runes = runes[:max(width, 0)]
+	}
+	return string(runes)
+}
+
+// fitLines truncates lines to the screen
+func fitLines(lines []string, width, height int) []string {
+	if len(lines) > height {
+		lines = lines[:max(height, 0)]
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "\033") {
+			lines[i] = truncate(line, width)
+		}
+	}
+	return lines
+}
+
+// ExceptionPlaceholder is the reason and approver of generated exceptions,
+// to be replaced before they are added to a config
+const ExceptionPlaceholder = "TODO"
+
+// Exceptions returns exception blocks that cover violations: one per rule
+// and file, naming the violating resources
+func Exceptions(violations []config.Violation) []config.Exception {
+	var exceptions []config.Exception
+	index := make(map[[2]string]int)
+	for _, v := range violations {
+		key := [2]string{v.RuleID, v.File}
+		i, ok := index[key]
+		if !ok {
+			i = len(exceptions)
+			index[key] = i
+			exceptions = append(exceptions, config.Exception{
+				Rules:      []string{v.RuleID},
+				Paths:      []string{v.File},
+				Reason:     ExceptionPlaceholder,
+				ApprovedBy: ExceptionPlaceholder,
+			})
+		}
+		if !contains(exceptions[i].ResourceNames, v.ResourceName) {
+			exceptions[i].ResourceNames = append(exceptions[i].ResourceNames, v.ResourceName)
+		}
+	}
+	return exceptions
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func testViolations() []config.Violation {
+	return []config.Violation{
+		{RuleID: "require_tags", RuleName: "Require tags", Severity: "warning", File: "main.tf", Line: 20, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "aws_s3_public_read", RuleName: "No public buckets", Severity: "error", File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "public", Message: "Bucket is public",
+			Source: &config.Source{StartLine: 2, Lines: []string{"", `resource "aws_s3_bucket" "public" {`, `  acl = "public-read"`}}, Remediation: "Set acl to private"},
+		{RuleID: "require_tags", RuleName: "Require tags", Severity: "warning", File: "iam.tf", Line: 7, ResourceType: "aws_iam_role", ResourceName: "admin"},
+	}
+}
+
+// listed returns the list rows of a render, between the title and the
+// separator, without the selection's escape codes
+func listed(lines []string) []string {
+	var rows []string
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "---") {
+			break
+		}
+		line = strings.TrimPrefix(line, "\033[7m")
+		line = strings.TrimSuffix(line, "\033[0m")
+		rows = append(rows, strings.TrimRight(line, " "))
+	}
+	return rows
+}
+
+func TestBrowserGroupings(t *testing.T) {
+	tests := []struct {
+		name    string
+		presses int // Times g is pressed
+		want    []string
+	}{
+		{"file", 0, []string{
+			"iam.tf (1)",
+			"  [ ] warning iam.tf:7  aws_iam_role.admin  require_tags",
+			"main.tf (2)",
+			"  [ ] error   main.tf:3  aws_s3_bucket.public  aws_s3_public_read",
+			"  [ ] warning main.tf:20  aws_s3_bucket.logs  require_tags",
+		}},
+		{"rule", 1, []string{
+			"aws_s3_public_read: No public buckets (1)",
+			"  [ ] error   main.tf:3  aws_s3_bucket.public  aws_s3_public_read",
+			"require_tags: Require tags (2)",
+			"  [ ] warning iam.tf:7  aws_iam_role.admin  require_tags",
+			"  [ ] warning main.tf:20  aws_s3_bucket.logs  require_tags",
+		}},
+		{"severity", 2, []string{
+			"error (1)",
+			"  [ ] error   main.tf:3  aws_s3_bucket.public  aws_s3_public_read",
+			"warning (2)",
+			"  [ ] warning iam.tf:7  aws_iam_role.admin  require_tags",
+			"  [ ] warning main.tf:20  aws_s3_bucket.logs  require_tags",
+		}},
+		{"back to file", 3, []string{
+			"iam.tf (1)",
+			"  [ ] warning iam.tf:7  aws_iam_role.admin  require_tags",
+			"main.tf (2)",
+			"  [ ] error   main.tf:3  aws_s3_bucket.public  aws_s3_public_read",
+			"  [ ] warning main.tf:20  aws_s3_bucket.logs  require_tags",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBrowser(testViolations())
+			for i := 0; i < tt.presses; i++ {
+				b.Handle("g")
+			}
+			// The details are taller than the list gets, so all rows fit
+			got := listed(b.Render(80, 30))
+			if !reflect.DeepEqual(got[:len(tt.want)], tt.want) {
+				t.Errorf("rows =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := NewBrowser(testViolations())
+	selected := func() string {
+		v := b.violations[b.order[b.cursor]]
+		return v.ResourceType + "." + v.ResourceName
+	}
+
+	if got := selected(); got != "aws_iam_role.admin" {
+		t.Errorf("first selected = %s, want aws_iam_role.admin", got)
+	}
+	// Moving skips group headers and stops at the ends
+	b.Handle(KeyDown)
+	if got := selected(); got != "aws_s3_bucket.public" {
+		t.Errorf("after down, selected = %s, want aws_s3_bucket.public", got)
+	}
+	b.Handle(KeyEnd)
+	b.Handle("j")
+	if got := selected(); got != "aws_s3_bucket.logs" {
+		t.Errorf("after end, selected = %s, want aws_s3_bucket.logs", got)
+	}
+	b.Handle(KeyHome)
+	b.Handle("k")
+	if got := selected(); got != "aws_iam_role.admin" {
+		t.Errorf("after home, selected = %s, want aws_iam_role.admin", got)
+	}
+
+	// Regrouping keeps the selection
+	b.Handle(KeyDown)
+	b.Handle("g")
+	b.Handle("g")
+	if got := selected(); got != "aws_s3_bucket.public" {
+		t.Errorf("after regrouping, selected = %s, want aws_s3_bucket.public", got)
+	}
+
+	if b.Handle("x") {
+		t.Error("Handle(x) quit, want it ignored")
+	}
+	if !b.Handle("q") {
+		t.Error("Handle(q) did not quit")
+	}
+}
+
+func TestBrowserMarks(t *testing.T) {
+	b := NewBrowser(testViolations())
+	b.Handle(" ")
+	b.Handle(KeyDown)
+	b.Handle(KeyDown)
+	b.Handle("m")
+	b.Handle(KeyUp)
+	b.Handle(" ")
+	b.Handle(" ")
+
+	got := b.Marked()
+	if len(got) != 2 || got[0].ResourceName != "logs" || got[1].ResourceName != "admin" {
+		t.Errorf("Marked() = %+v, want logs and admin", got)
+	}
+	lines := b.Render(80, 30)
+	if !strings.HasPrefix(lines[0], "3 violations by file, 2 marked") {
+		t.Errorf("title = %q", lines[0])
+	}
+	if rows := listed(lines); rows[1] != "  [x] warning iam.tf:7  aws_iam_role.admin  require_tags" {
+		t.Errorf("marked row = %q", rows[1])
+	}
+}
+
+func TestBrowserRender(t *testing.T) {
+	b := NewBrowser(testViolations())
+	b.Handle(KeyDown)
+	lines := b.Render(40, 20)
+
+	if len(lines) != 20 {
+		t.Fatalf("Render() = %d lines, want 20", len(lines))
+	}
+	for _, line := range lines {
+		if len([]rune(strings.TrimSuffix(strings.TrimPrefix(line, "\033[7m"), "\033[0m"))) > 40 {
+			t.Errorf("line %q is wider than 40 columns", line)
+		}
+	}
+	detail := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"aws_s3_public_read (error): No public b",
+		"Bucket is public",
+		">    3 | resource \"aws_s3_bucket\" \"pu",
+		"Remediation:",
+	} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("Render() does not contain %q:\n%s", want, detail)
+		}
+	}
+	if last := lines[len(lines)-1]; !strings.HasPrefix(last, "up/down move") {
+		t.Errorf("last line = %q, want key help", last)
+	}
+
+	// The list scrolls to keep the selection in view
+	b.Handle(KeyEnd)
+	if rows := listed(b.Render(80, 6)); len(rows) != 2 || !strings.Contains(rows[1], "aws_s3_bucket.logs") {
+		t.Errorf("scrolled rows = %q, want the last violation in view", rows)
+	}
+
+	empty := NewBrowser(nil).Render(80, 10)
+	if !reflect.DeepEqual(empty[2], "No violations found.") {
+		t.Errorf("empty Render() = %q", empty)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	violations := testViolations()
+	violations = append(violations, config.Violation{RuleID: "require_tags", File: "main.tf", ResourceName: "logs"})
+	violations = append(violations, config.Violation{RuleID: "require_tags", File: "main.tf", ResourceName: "assets"})
+
+	got := Exceptions(violations)
+	want := []config.Exception{
+		{Rules: []string{"require_tags"}, Paths: []string{"main.tf"}, ResourceNames: []string{"logs", "assets"}, Reason: "TODO", ApprovedBy: "TODO"},
+		{Rules: []string{"aws_s3_public_read"}, Paths: []string{"main.tf"}, ResourceNames: []string{"public"}, Reason: "TODO", ApprovedBy: "TODO"},
+		{Rules: []string{"require_tags"}, Paths: []string{"iam.tf"}, ResourceNames: []string{"admin"}, Reason: "TODO", ApprovedBy: "TODO"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Exceptions() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package tui
+
+// Key is a key press: one of the named keys below, or the character typed
+type Key string
+
+// Named keys
+const (
+	KeyUp       Key = "up"
+	KeyDown     Key = "down"
+	KeyPageUp   Key = "pgup"
+	KeyPageDown Key = "pgdown"
+	KeyHome     Key = "home"
+	KeyEnd      Key = "end"
+	KeyEnter    Key = "enter"
+	KeyCtrlC    Key = "ctrl+c"
+)
+
+// escapeKeys are the escape sequences terminals send for the named keys,
+// in both normal and application cursor mode
+var escapeKeys = map[string]Key{
+	"\x1b[A":  KeyUp,
+	"\x1bOA":  KeyUp,
+	"\x1b[B":  KeyDown,
+	"\x1bOB":  KeyDown,
+	"\x1b[5~": KeyPageUp,
+	"\x1b[6~": KeyPageDown,
+	"\x1b[H":  KeyHome,
+	"\x1bOH":  KeyHome,
+	"\x1b[1~": KeyHome,
+	"\x1b[F":  KeyEnd,
+	"\x1bOF":  KeyEnd,
+	"\x1b[4~": KeyEnd,
+}
+
+// ParseKey decodes what one read from a terminal in raw mode returned.
+// Unrecognized input decodes to "".
+func ParseKey(input []byte) Key {
+	s := string(input)
+	switch {
+	case s == "":
+		return ""
+	case s == "\r" || s == "\n":
+		return KeyEnter
+	case s == "\x03":
+		return KeyCtrlC
+	case s[0] == '\x1b':
+		return escapeKeys[s]
+	}
+	runes := []rune(s)
+	if len(runes) != 1 || runes[0] < ' ' {
+		return ""
+	}
+	return Key(s)
+}
@@ -0,0 +1,33 @@
+package tui
+
+import "testing"
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Key
+	}{
+		{"\x1b[A", KeyUp},
+		{"\x1bOB", KeyDown},
+		{"\x1b[5~", KeyPageUp},
+		{"\x1b[6~", KeyPageDown},
+		{"\x1b[H", KeyHome},
+		{"\x1b[4~", KeyEnd},
+		{"\r", KeyEnter},
+		{"\x03", KeyCtrlC},
+		{"j", "j"},
+		{" ", " "},
+		{"é", "é"},
+		{"\x1b[15~", ""},
+		{"\x1b", ""},
+		{"\x01", ""},
+		{"ab", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseKey([]byte(tt.input)); got != tt.want {
+			t.Errorf("ParseKey(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+type terminal struct{}
+
+func openTerminal(in *os.File) (*terminal, error) {
+	return nil, errors.New("the terminal UI is not supported on this platform")
+}
+
+func (t *terminal) size() (width, height int, err error) {
+	return 0, 0, errors.New("the terminal UI is not supported on this platform")
+}
+
+func (t *terminal) restore() error {
+	return nil
+}
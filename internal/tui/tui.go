@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Run shows a browser of violations on the terminal in and out, using the
+// alternate screen so the terminal is left as it was, until the user quits.
+// It returns the violations marked.
+func Run(in, out *os.File, violations []config.Violation) ([]config.Violation, error) {
+	term, err := openTerminal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.restore()
+
+	// Switch to the alternate screen and hide the cursor, and back
+	fmt.Fprint(out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(out, "\033[?25h\033[?1049l")
+
+	b := NewBrowser(violations)
+	buf := make([]byte, 32)
+	for {
+		// The size is read before each draw, so resizing takes effect on
+		// the next key press
+		width, height, err := term.size()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the terminal size: %w", err)
+		}
+		// Raw mode does not turn newlines into carriage return and
+		// newline, so lines are separated by both
+		fmt.Fprint(out, "\033[H\033[2J"+strings.Join(b.Render(width, height), "\r\n"))
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from the terminal: %w", err)
+		}
+		if b.Handle(ParseKey(buf[:n])) {
+			return b.Marked(), nil
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Version is the current baseline file format version
+const Version = 1
+
+// Baseline records violations that existed when planguard was adopted, so
+// that only new violations fail the build
+type Baseline struct {
+	Version    int     `json:"version"`
+	Violations []Entry `json:"violations"`
+}
+
+// Entry identifies a violation independently of its line number, so
+// unrelated edits that shift code around do not turn known violations
+// into new ones
+type Entry struct {
+	RuleID       string `json:"rule_id"`
+	File         string `json:"file"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+}
+
+// New creates a baseline from violations
+func New(violations []config.Violation) *Baseline {
+	seen := make(map[Entry]bool)
+	b := &Baseline{Version: Version, Violations: []Entry{}}
+
+	for _, v := range violations {
+		entry := entryFor(v)
+		if !seen[entry] {
+			seen[entry] = true
+			b.Violations = append(b.Violations, entry)
+		}
+	}
+
+	// Sort so regenerated baselines produce minimal diffs
+	sort.Slice(b.Violations, func(i, j int) bool {
+		a, c := b.Violations[i], b.Violations[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.RuleID != c.RuleID {
+			return a.RuleID < c.RuleID
+		}
+		if a.ResourceType != c.ResourceType {
+			return a.ResourceType < c.ResourceType
+		}
+		return a.ResourceName < c.ResourceName
+	})
+
+	return b
+}
+
+// Load reads a baseline file
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s (expected %d)", b.Version, path, Version)
+	}
+
+	return &b, nil
+}
+
+// Save writes the baseline to path, creating parent directories as needed
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Split separates violations into new ones and ones already recorded in
+// the baseline
+func (b *Baseline) Split(violations []config.Violation) (newViolations, known []config.Violation) {
+	return b.SplitFunc(violations, nil)
+}
+
+// SplitFunc separates violations as Split does, looking each one up as
+// normalize returns it, e.g. with its path as reports show it. The
+// violations themselves are returned unchanged. A nil normalize looks
+// violations up as they are.
+func (b *Baseline) SplitFunc(violations []config.Violation, normalize func(config.Violation) config.Violation) (newViolations, known []config.Violation) {
+	entries := make(map[Entry]bool, len(b.Violations))
+	for _, entry := range b.Violations {
+		entries[entry] = true
+	}
+
+	for _, v := range violations {
+		lookup := v
+		if normalize != nil {
+			lookup = normalize(v)
+		}
+		if entries[entryFor(lookup)] {
+			known = append(known, v)
+		} else {
+			newViolations = append(newViolations, v)
+		}
+	}
+	return newViolations, known
+}
+
+// ResolveAliases replaces the former IDs of renamed rules recorded in the
+// baseline with their current IDs, so violations recorded before a rename
+// stay known. It returns the number of entries changed.
+func (b *Baseline) ResolveAliases(aliases config.Aliases) int {
+	changed := 0
+	for i, entry := range b.Violations {
+		if current := aliases.Resolve(entry.RuleID); current != entry.RuleID {
+			b.Violations[i].RuleID = current
+			changed++
+		}
+	}
+	return changed
+}
+
+func entryFor(v config.Violation) Entry {
+	return Entry{
+		RuleID:       v.RuleID,
+		File:         filepath.ToSlash(v.File),
+		ResourceType: v.ResourceType,
+		ResourceName: v.ResourceName,
+	}
+}
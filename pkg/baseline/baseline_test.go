@@ -0,0 +1,130 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestBaselineSplit(t *testing.T) {
+	existing := []config.Violation{
+		{RuleID: "s3_public", File: "main.tf", Line: 10, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "s3_public", File: "main.tf", Line: 10, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+	}
+	b := New(existing)
+
+	if len(b.Violations) != 1 {
+		t.Errorf("Duplicate violations should be recorded once, got %d entries", len(b.Violations))
+	}
+
+	current := []config.Violation{
+		// Same violation moved to another line is still known
+		{RuleID: "s3_public", File: "main.tf", Line: 25, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "s3_public", File: "main.tf", Line: 40, ResourceType: "aws_s3_bucket", ResourceName: "uploads"},
+	}
+
+	newViolations, known := b.Split(current)
+	if len(known) != 1 || known[0].ResourceName != "logs" {
+		t.Errorf("Expected logs to be known, got %+v", known)
+	}
+	if len(newViolations) != 1 || newViolations[0].ResourceName != "uploads" {
+		t.Errorf("Expected uploads to be new, got %+v", newViolations)
+	}
+}
+
+func TestBaselineSplitFunc(t *testing.T) {
+	b := New([]config.Violation{
+		{RuleID: "s3_public", File: "modules/storage/main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+	})
+
+	// Violations keep the path they were scanned with, relative to a
+	// subdirectory, while the baseline records it relative to the repository
+	current := []config.Violation{
+		{RuleID: "s3_public", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "s3_public", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "uploads"},
+	}
+	toRoot := func(v config.Violation) config.Violation {
+		v.File = "modules/storage/" + v.File
+		return v
+	}
+
+	newViolations, known := b.SplitFunc(current, toRoot)
+	if len(known) != 1 || known[0].ResourceName != "logs" {
+		t.Fatalf("Expected logs to be known, got %+v", known)
+	}
+	if known[0].File != "main.tf" {
+		t.Errorf("Known violation should keep its scanned path, got %q", known[0].File)
+	}
+	if len(newViolations) != 1 || newViolations[0].ResourceName != "uploads" || newViolations[0].File != "main.tf" {
+		t.Errorf("Expected uploads to be new with its scanned path, got %+v", newViolations)
+	}
+
+	if newViolations, _ := b.SplitFunc(current, nil); len(newViolations) != 2 {
+		t.Errorf("Without normalize the scanned paths should not match, got %d new", len(newViolations))
+	}
+}
+
+func TestBaselineResolveAliases(t *testing.T) {
+	b := New([]config.Violation{
+		{RuleID: "s3_public_old", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "s3_versioning", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+	})
+
+	if n := b.ResolveAliases(config.Aliases{"s3_public_old": "s3_public"}); n != 1 {
+		t.Errorf("ResolveAliases() = %d, want 1", n)
+	}
+
+	// A violation recorded under the rule's former ID is still known
+	_, known := b.Split([]config.Violation{
+		{RuleID: "s3_public", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+	})
+	if len(known) != 1 {
+		t.Errorf("Expected the renamed rule's violation to be known, got %+v", known)
+	}
+}
+
+func TestBaselineSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".planguard", "baseline.json")
+
+	b := New([]config.Violation{
+		{RuleID: "b", File: "z.tf", ResourceType: "aws_instance", ResourceName: "web"},
+		{RuleID: "a", File: "a.tf", ResourceType: "aws_instance", ResourceName: "web"},
+	})
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Violations) != 2 || loaded.Violations[0].File != "a.tf" {
+		t.Errorf("Expected sorted entries, got %+v", loaded.Violations)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing baseline")
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(invalid); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version": 99, "violations": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(future); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
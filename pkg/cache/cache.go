@@ -0,0 +1,299 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Cache stores per-file scan results on disk. Entries are keyed on the
+// file's path and content together with a hash of the rule set, so they are
+// invalidated automatically when either changes.
+type Cache struct {
+	dir string
+
+	// Lookups since the cache was opened, added to the totals on disk by
+	// SaveStats
+	hits, misses atomic.Int64
+}
+
+// New creates a cache rooted at dir, creating the directory if needed
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "results"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// DefaultDir returns the default cache location, e.g. ~/.cache/planguard
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "planguard"), nil
+}
+
+// Dir returns the cache's root directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// HashRuleSet returns a hash identifying a rule set. extra holds anything
+// else that affects evaluation, such as the planguard version.
+func HashRuleSet(rules []config.Rule, functions []config.Function, extra ...string) (string, error) {
+	data, err := json.Marshal(struct {
+		Rules     []config.Rule
+		Functions []config.Function
+		Extra     []string
+	}{rules, functions, extra})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash rule set: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Key returns the cache key for the current contents of path under the
+// given rule set
+func (c *Cache) Key(ruleSetHash, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", ruleSetHash, path)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get returns the violations cached under key. A hit marks the entry as
+// used now, so Clean keeps entries still in use.
+func (c *Cache) Get(key string) ([]config.Violation, bool) {
+	path := c.entryPath(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	var violations []config.Violation
+	if err := json.Unmarshal(data, &violations); err != nil {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return violations, true
+}
+
+// Put stores violations under key
+func (c *Cache) Put(key string, violations []config.Violation) error {
+	if violations == nil {
+		violations = []config.Violation{}
+	}
+
+	data, err := json.Marshal(violations)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	// Write atomically so concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Join(c.dir, "results"), "entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.entryPath(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, "results", key+".json")
+}
+
+// Stats are the lookups recorded in a cache directory since it was last
+// cleaned
+type Stats struct {
+	Hits   int64     `json:"hits"`
+	Misses int64     `json:"misses"`
+	Since  time.Time `json:"since"`
+}
+
+// HitRate returns the share of lookups that were hits, from 0 to 1
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Info describes what a cache directory holds
+type Info struct {
+	Entries int
+	Bytes   int64
+	Oldest  time.Time // When the least recently used entry was last used
+	Newest  time.Time
+	Stats   Stats
+}
+
+// SaveStats adds the lookups made since the cache was opened to the totals
+// kept in the cache directory. Runs sharing a cache at the same moment may
+// lose each other's counts, so the totals are approximate.
+func (c *Cache) SaveStats() error {
+	stats, err := c.loadStats()
+	if err != nil {
+		return err
+	}
+	stats.Hits += c.hits.Swap(0)
+	stats.Misses += c.misses.Swap(0)
+	if stats.Since.IsZero() {
+		stats.Since = time.Now().UTC().Truncate(time.Second)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache stats: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, "stats-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache stats: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache stats: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache stats: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.statsPath()); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache stats: %w", err)
+	}
+	return nil
+}
+
+// Info returns the number and size of the cache's entries and the lookups
+// recorded by SaveStats
+func (c *Cache) Info() (Info, error) {
+	var info Info
+	err := c.walkEntries(func(path string, entry os.FileInfo) error {
+		info.Entries++
+		info.Bytes += entry.Size()
+		if info.Oldest.IsZero() || entry.ModTime().Before(info.Oldest) {
+			info.Oldest = entry.ModTime()
+		}
+		if entry.ModTime().After(info.Newest) {
+			info.Newest = entry.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return Info{}, err
+	}
+
+	info.Stats, err = c.loadStats()
+	if err != nil {
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// Clean removes the entries last used before cutoff, or every entry and the
+// recorded stats when cutoff is zero. With dryRun nothing is removed. It
+// returns the number and size of the entries removed.
+func (c *Cache) Clean(cutoff time.Time, dryRun bool) (removed int, freed int64, err error) {
+	err = c.walkEntries(func(path string, entry os.FileInfo) error {
+		if !cutoff.IsZero() && !entry.ModTime().Before(cutoff) {
+			return nil
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove cache entry: %w", err)
+			}
+		}
+		removed++
+		freed += entry.Size()
+		return nil
+	})
+	if err != nil {
+		return removed, freed, err
+	}
+
+	if cutoff.IsZero() && !dryRun {
+		if err := os.Remove(c.statsPath()); err != nil && !os.IsNotExist(err) {
+			return removed, freed, fmt.Errorf("failed to remove cache stats: %w", err)
+		}
+	}
+	return removed, freed, nil
+}
+
+// walkEntries calls fn for each entry in the cache, including temporary
+// files left by interrupted writes
+func (c *Cache) walkEntries(fn func(path string, entry os.FileInfo) error) error {
+	dir := filepath.Join(c.dir, "results")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !(strings.HasSuffix(entry.Name(), ".json") || strings.HasPrefix(entry.Name(), "entry-")) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue // Removed by a concurrent run
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		if err := fn(filepath.Join(dir, entry.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) loadStats() (Stats, error) {
+	var stats Stats
+	data, err := os.ReadFile(c.statsPath())
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to read cache stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		// Stats are informational; start again rather than fail the scan
+		return Stats{}, nil
+	}
+	return stats, nil
+}
+
+func (c *Cache) statsPath() string {
+	return filepath.Join(c.dir, "stats.json")
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(file, []byte(`resource "aws_s3_bucket" "a" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := c.Key("rules-v1", file)
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Expected cache miss before Put")
+	}
+
+	violations := []config.Violation{{RuleID: "r", File: file, Line: 1}}
+	if err := c.Put(key, violations); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	got, ok := c.Get(key)
+	if !ok || len(got) != 1 || got[0].RuleID != "r" {
+		t.Errorf("Get() = %v, %v; want cached violation", got, ok)
+	}
+
+	// Empty results are cached too
+	if err := c.Put("empty", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Get("empty"); !ok || len(got) != 0 {
+		t.Errorf("Expected cached empty result, got %v, %v", got, ok)
+	}
+}
+
+func TestCacheKeyInvalidation(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	original, _ := c.Key("rules-v1", file)
+
+	if other, _ := c.Key("rules-v2", file); other == original {
+		t.Error("Key should change when the rule set changes")
+	}
+
+	if err := os.WriteFile(file, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := c.Key("rules-v1", file); changed == original {
+		t.Error("Key should change when the file changes")
+	}
+
+	if _, err := c.Key("rules-v1", filepath.Join(t.TempDir(), "missing.tf")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestHashRuleSet(t *testing.T) {
+	rules := []config.Rule{{ID: "a", Severity: "error"}}
+
+	h1, err := HashRuleSet(rules, nil, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, _ := HashRuleSet(rules, nil, "v1")
+	if h1 != h2 {
+		t.Error("Hash should be stable")
+	}
+
+	if h3, _ := HashRuleSet([]config.Rule{{ID: "a", Severity: "warning"}}, nil, "v1"); h3 == h1 {
+		t.Error("Hash should change when a rule changes")
+	}
+	if h4, _ := HashRuleSet(rules, nil, "v2"); h4 == h1 {
+		t.Error("Hash should change with extra inputs")
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("a", nil); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+	if err := c.SaveStats(); err != nil {
+		t.Fatalf("SaveStats() error = %v", err)
+	}
+
+	// A later run adds to the totals
+	c, _ = New(dir)
+	c.Get("missing")
+	if err := c.SaveStats(); err != nil {
+		t.Fatalf("SaveStats() error = %v", err)
+	}
+
+	info, err := c.Info()
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.Entries != 1 || info.Bytes != 2 {
+		t.Errorf("Info() = %d entries, %d bytes; want 1 entry of 2 bytes", info.Entries, info.Bytes)
+	}
+	if info.Stats.Hits != 2 || info.Stats.Misses != 2 || info.Stats.Since.IsZero() {
+		t.Errorf("Info().Stats = %+v, want 2 hits and 2 misses", info.Stats)
+	}
+	if rate := info.Stats.HitRate(); rate != 0.5 {
+		t.Errorf("HitRate() = %g, want 0.5", rate)
+	}
+}
+
+func TestCacheClean(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		cutoff      time.Time
+		dryRun      bool
+		wantRemoved int
+		wantLeft    int
+		wantStats   bool
+	}{
+		{name: "everything", wantRemoved: 2, wantLeft: 0},
+		{name: "unused since cutoff", cutoff: now.Add(-24 * time.Hour), wantRemoved: 1, wantLeft: 1, wantStats: true},
+		{name: "dry run", dryRun: true, wantRemoved: 2, wantLeft: 2, wantStats: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"old", "recent"} {
+				if err := c.Put(key, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			old := now.Add(-48 * time.Hour)
+			if err := os.Chtimes(c.entryPath("old"), old, old); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SaveStats(); err != nil {
+				t.Fatal(err)
+			}
+
+			removed, freed, err := c.Clean(tt.cutoff, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Clean() error = %v", err)
+			}
+			if removed != tt.wantRemoved || freed != int64(2*tt.wantRemoved) {
+				t.Errorf("Clean() = %d entries, %d bytes; want %d entries", removed, freed, tt.wantRemoved)
+			}
+			info, err := c.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Entries != tt.wantLeft {
+				t.Errorf("%d entries left, want %d", info.Entries, tt.wantLeft)
+			}
+			if hasStats := !info.Stats.Since.IsZero(); hasStats != tt.wantStats {
+				t.Errorf("stats kept = %v, want %v", hasStats, tt.wantStats)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aliases maps the former IDs of rules, listed in their aliases, to their
+// current IDs, so a rule can be renamed without breaking the exceptions,
+// settings, and severity overrides that name it
+type Aliases map[string]string
+
+// RuleAliases returns the aliases of rules. An alias that is the ID of a
+// loaded rule, or that several rules claim, is left out with a warning.
+func RuleAliases(rules []Rule) (Aliases, []string) {
+	ids := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		ids[rule.ID] = true
+	}
+
+	aliases := make(Aliases)
+	var warnings []string
+	for _, rule := range rules {
+		for _, alias := range rule.Aliases {
+			if ids[alias] {
+				warnings = append(warnings, fmt.Sprintf("alias %s of rule %s is the ID of another rule and is ignored", alias, rule.ID))
+				continue
+			}
+			if other, ok := aliases[alias]; ok && other != rule.ID {
+				warnings = append(warnings, fmt.Sprintf("alias %s is claimed by rules %s and %s; it refers to %s", alias, other, rule.ID, other))
+				continue
+			}
+			aliases[alias] = rule.ID
+		}
+	}
+	return aliases, warnings
+}
+
+// Resolve returns the current ID of a rule, given its current or former ID
+func (a Aliases) Resolve(id string) string {
+	if current, ok := a[id]; ok {
+		return current
+	}
+	return id
+}
+
+// ResolveConfig replaces former rule IDs with current ones in the
+// exceptions, rule settings, and active profile of cfg. It returns a
+// warning for each replacement, so the references can be updated before
+// the aliases are dropped.
+func (a Aliases) ResolveConfig(cfg *Config) []string {
+	if len(a) == 0 {
+		return nil
+	}
+	var warnings []string
+	for i := range cfg.Exceptions {
+		exception := &cfg.Exceptions[i]
+		where := "exception for " + strings.Join(exception.Rules, ", ")
+		if exception.Source != "" {
+			where = exception.Source + ": " + where
+		}
+		warnings = append(warnings, a.resolveList(exception.Rules, where)...)
+	}
+
+	if s := cfg.Settings; s != nil {
+		warnings = append(warnings, a.resolveList(s.DisabledRules, "disabled_rules")...)
+		warnings = append(warnings, a.resolveList(s.EnabledRules, "enabled_rules")...)
+		warnings = append(warnings, a.resolveList(s.EnableRules, "enable_rules")...)
+		for id, weight := range s.RiskRuleWeights {
+			if current, ok := a[id]; ok {
+				delete(s.RiskRuleWeights, id)
+				s.RiskRuleWeights[current] = weight
+				warnings = append(warnings, a.warning(id, "risk_rule_weights"))
+			}
+		}
+		for id, limit := range s.MaxActiveExceptionsPerRule {
+			if current, ok := a[id]; ok {
+				delete(s.MaxActiveExceptionsPerRule, id)
+				s.MaxActiveExceptionsPerRule[current] = limit
+				warnings = append(warnings, a.warning(id, "max_active_exceptions_per_rule"))
+			}
+		}
+	}
+
+	if p := cfg.ActiveProfile; p != nil {
+		warnings = append(warnings, a.ResolveSeverities(p.Severities, "profile "+p.Name+" severities")...)
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// ResolveSeverities replaces former rule IDs with current ones in the keys
+// of severities, a rule ID to severity mapping read from where, returning a
+// warning for each replacement
+func (a Aliases) ResolveSeverities(severities map[string]string, where string) []string {
+	var warnings []string
+	for id, severity := range severities {
+		if current, ok := a[id]; ok {
+			delete(severities, id)
+			severities[current] = severity
+			warnings = append(warnings, a.warning(id, where))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// resolveList replaces former rule IDs in ids, read from where
+func (a Aliases) resolveList(ids []string, where string) []string {
+	var warnings []string
+	for i, id := range ids {
+		if current, ok := a[id]; ok {
+			ids[i] = current
+			warnings = append(warnings, a.warning(id, where))
+		}
+	}
+	return warnings
+}
+
+func (a Aliases) warning(id, where string) string {
+	return fmt.Sprintf("%s names %s, the former ID of rule %s; use %s", where, id, a[id], a[id])
+}
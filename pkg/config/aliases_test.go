@@ -0,0 +1,93 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleAliases(t *testing.T) {
+	rules := []Rule{
+		{ID: "s3_tags", Aliases: []string{"s3_tagging", "s3_labels"}},
+		{ID: "s3_versioning", Aliases: []string{"s3_labels", "s3_tags"}},
+	}
+	aliases, warnings := RuleAliases(rules)
+
+	if want := (Aliases{"s3_tagging": "s3_tags", "s3_labels": "s3_tags"}); !reflect.DeepEqual(aliases, want) {
+		t.Errorf("RuleAliases() = %v, want %v", aliases, want)
+	}
+	wantWarnings := []string{
+		"alias s3_labels is claimed by rules s3_tags and s3_versioning; it refers to s3_tags",
+		"alias s3_tags of rule s3_versioning is the ID of another rule and is ignored",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"s3_tagging", "s3_tags"},
+		{"s3_tags", "s3_tags"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := aliases.Resolve(tt.id); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	aliases := Aliases{"old_tags": "s3_tags", "old_versioning": "s3_versioning"}
+	cfg := &Config{
+		Settings: &Settings{
+			DisabledRules:              []string{"old_versioning", "other"},
+			EnableRules:                []string{"old_tags"},
+			RiskRuleWeights:            map[string]float64{"old_tags": 2},
+			MaxActiveExceptionsPerRule: map[string]int{"old_versioning": 1},
+		},
+		Exceptions: []Exception{
+			{Rules: []string{"old_tags", "other"}, Source: "exceptions/s3.hcl"},
+		},
+		ActiveProfile: &Profile{Name: "dev", Severities: map[string]string{"old_tags": "info", "other": "error"}},
+	}
+
+	warnings := aliases.ResolveConfig(cfg)
+
+	s := cfg.Settings
+	if want := []string{"s3_versioning", "other"}; !reflect.DeepEqual(s.DisabledRules, want) {
+		t.Errorf("DisabledRules = %v, want %v", s.DisabledRules, want)
+	}
+	if want := []string{"s3_tags"}; !reflect.DeepEqual(s.EnableRules, want) {
+		t.Errorf("EnableRules = %v, want %v", s.EnableRules, want)
+	}
+	if want := map[string]float64{"s3_tags": 2}; !reflect.DeepEqual(s.RiskRuleWeights, want) {
+		t.Errorf("RiskRuleWeights = %v, want %v", s.RiskRuleWeights, want)
+	}
+	if want := map[string]int{"s3_versioning": 1}; !reflect.DeepEqual(s.MaxActiveExceptionsPerRule, want) {
+		t.Errorf("MaxActiveExceptionsPerRule = %v, want %v", s.MaxActiveExceptionsPerRule, want)
+	}
+	if want := []string{"s3_tags", "other"}; !reflect.DeepEqual(cfg.Exceptions[0].Rules, want) {
+		t.Errorf("exception Rules = %v, want %v", cfg.Exceptions[0].Rules, want)
+	}
+	if want := map[string]string{"s3_tags": "info", "other": "error"}; !reflect.DeepEqual(cfg.ActiveProfile.Severities, want) {
+		t.Errorf("profile Severities = %v, want %v", cfg.ActiveProfile.Severities, want)
+	}
+
+	wantWarnings := []string{
+		"disabled_rules names old_versioning, the former ID of rule s3_versioning; use s3_versioning",
+		"enable_rules names old_tags, the former ID of rule s3_tags; use s3_tags",
+		"exceptions/s3.hcl: exception for old_tags, other names old_tags, the former ID of rule s3_tags; use s3_tags",
+		"max_active_exceptions_per_rule names old_versioning, the former ID of rule s3_versioning; use s3_versioning",
+		"profile dev severities names old_tags, the former ID of rule s3_tags; use s3_tags",
+		"risk_rule_weights names old_tags, the former ID of rule s3_tags; use s3_tags",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings =\n%q\nwant\n%q", warnings, wantWarnings)
+	}
+
+	if warnings := (Aliases{}).ResolveConfig(&Config{Settings: &Settings{}}); warnings != nil {
+		t.Errorf("ResolveConfig() without aliases = %q, want none", warnings)
+	}
+}
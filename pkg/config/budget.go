@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BudgetExceeded is an exception budget that the active exceptions exceed
+type BudgetExceeded struct {
+	Scope  string // "all exceptions", "rule <id>", or "category <name>"
+	Active int
+	Max    int
+}
+
+func (b BudgetExceeded) String() string {
+	return fmt.Sprintf("%s: %d active, budget %d", b.Scope, b.Active, b.Max)
+}
+
+// HasExceptionBudgets reports whether settings limit active exceptions
+func (s *Settings) HasExceptionBudgets() bool {
+	return s.MaxActiveExceptions != nil || len(s.MaxActiveExceptionsPerRule) > 0 || len(s.MaxActiveExceptionsPerCategory) > 0
+}
+
+// CheckExceptionBudgets counts the exceptions that have not expired and
+// returns the budgets in settings they exceed: overall, per rule ID, and
+// per category. categories maps category names to the IDs of their rules;
+// an exception counts once toward a category when any rule it covers is in
+// the category. The result is sorted by scope.
+func CheckExceptionBudgets(settings *Settings, exceptions []Exception, categories map[string][]string, now time.Time) []BudgetExceeded {
+	var active []Exception
+	for _, exception := range exceptions {
+		if !exception.Expired(now) {
+			active = append(active, exception)
+		}
+	}
+
+	var exceeded []BudgetExceeded
+	if settings.MaxActiveExceptions != nil && len(active) > *settings.MaxActiveExceptions {
+		exceeded = append(exceeded, BudgetExceeded{Scope: "all exceptions", Active: len(active), Max: *settings.MaxActiveExceptions})
+	}
+
+	for ruleID, max := range settings.MaxActiveExceptionsPerRule {
+		count := 0
+		for _, exception := range active {
+			if containsString(exception.Rules, ruleID) {
+				count++
+			}
+		}
+		if count > max {
+			exceeded = append(exceeded, BudgetExceeded{Scope: "rule " + ruleID, Active: count, Max: max})
+		}
+	}
+
+	for category, max := range settings.MaxActiveExceptionsPerCategory {
+		inCategory := make(map[string]bool)
+		for _, ruleID := range categories[category] {
+			inCategory[ruleID] = true
+		}
+		count := 0
+		for _, exception := range active {
+			for _, ruleID := range exception.Rules {
+				if inCategory[ruleID] {
+					count++
+					break
+				}
+			}
+		}
+		if count > max {
+			exceeded = append(exceeded, BudgetExceeded{Scope: "category " + category, Active: count, Max: max})
+		}
+	}
+
+	sort.Slice(exceeded, func(i, j int) bool {
+		return exceeded[i].Scope < exceeded[j].Scope
+	})
+	return exceeded
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
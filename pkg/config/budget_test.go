@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckExceptionBudgets(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	exceptions := []Exception{
+		{Rules: []string{"aws_s3_public_read"}},
+		{Rules: []string{"aws_s3_public_read", "aws_rds_encryption"}},
+		{Rules: []string{"require_tags"}},
+		{Rules: []string{"aws_s3_public_read"}, ExpiresAt: stringPtr("2024-06-01")}, // expired, not counted
+	}
+	categories := map[string][]string{
+		"aws":     {"aws_s3_public_read", "aws_rds_encryption"},
+		"tagging": {"require_tags"},
+	}
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		settings Settings
+		want     []BudgetExceeded
+	}{
+		{name: "no budgets", settings: Settings{}},
+		{name: "within budgets", settings: Settings{
+			MaxActiveExceptions:            intPtr(3),
+			MaxActiveExceptionsPerRule:     map[string]int{"aws_s3_public_read": 2},
+			MaxActiveExceptionsPerCategory: map[string]int{"aws": 2},
+		}},
+		{name: "overall", settings: Settings{MaxActiveExceptions: intPtr(2)}, want: []BudgetExceeded{
+			{Scope: "all exceptions", Active: 3, Max: 2},
+		}},
+		{name: "zero budget", settings: Settings{MaxActiveExceptionsPerRule: map[string]int{"require_tags": 0, "unused": 0}}, want: []BudgetExceeded{
+			{Scope: "rule require_tags", Active: 1, Max: 0},
+		}},
+		{name: "per rule and category", settings: Settings{
+			MaxActiveExceptionsPerRule:     map[string]int{"aws_s3_public_read": 1},
+			MaxActiveExceptionsPerCategory: map[string]int{"aws": 1, "tagging": 1},
+		}, want: []BudgetExceeded{
+			{Scope: "category aws", Active: 2, Max: 1},
+			{Scope: "rule aws_s3_public_read", Active: 2, Max: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckExceptionBudgets(&tt.settings, exceptions, categories, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckExceptionBudgets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RuleCategory is a selectable set of presupplied rules
+type RuleCategory struct {
+	Name    string
+	Pattern string // Slash-separated glob of its rule files, relative to the rules directory
+	OptIn   bool   // Only loaded when selected by name
+}
+
+// ruleFileCategories are presupplied rule files selectable on their own.
+// Their names differ from those of the directories, which are categories
+// too; a directory with one of these names takes the name.
+var ruleFileCategories = map[string]string{
+	"security":     "common/security.hcl",
+	"tagging":      "common/tagging.hcl",
+	"unreferenced": "hygiene/unreferenced.hcl",
+	"orphans":      "hygiene/orphans.hcl",
+}
+
+// optInRuleCategories are left out when no categories are selected. Their
+// rules count references across a whole configuration and are noisy on
+// most code bases.
+var optInRuleCategories = map[string]bool{
+	"hygiene":      true,
+	"unreferenced": true,
+	"orphans":      true,
+}
+
+// RuleCategories returns the rule categories of a file system laid out like
+// a rules directory, sorted by name: one per subdirectory holding rule
+// files, named after it, and one per presupplied file in
+// ruleFileCategories that exists and is not named like a directory. Hidden directories and the packs
+// directory are not categories. A missing directory has none.
+func RuleCategories(fsys fs.FS) ([]RuleCategory, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]RuleCategory)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || name == PacksDir {
+			continue
+		}
+		pattern := name + "/*.hcl"
+		ok, err := hasRuleFiles(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			byName[name] = RuleCategory{Name: name, Pattern: pattern, OptIn: optInRuleCategories[name]}
+		}
+	}
+	for name, file := range ruleFileCategories {
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		if _, err := fs.Stat(fsys, file); err == nil {
+			byName[name] = RuleCategory{Name: name, Pattern: file, OptIn: optInRuleCategories[name]}
+		}
+	}
+
+	categories := make([]RuleCategory, 0, len(byName))
+	for _, category := range byName {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+// RuleCategoryNames returns the names of the RuleCategories of fsys
+func RuleCategoryNames(fsys fs.FS) ([]string, error) {
+	categories, err := RuleCategories(fsys)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = category.Name
+	}
+	return names, nil
+}
+
+// hasRuleFiles reports whether pattern matches a file other than a pack
+// manifest or rule test file
+func hasRuleFiles(fsys fs.FS, pattern string) (bool, error) {
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return false, err
+	}
+	for _, match := range matches {
+		if path.Base(match) != PackManifestFile && !IsRuleTestFile(match) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// defaultRulePatterns returns the slash-separated patterns, relative to a
+// rules directory, of the rule files in categories. Files in several of
+// them match more than one pattern.
+func defaultRulePatterns(fsys fs.FS, categories []string) ([]string, error) {
+	available, err := RuleCategories(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rules in the root directory are always loaded
+	patterns := []string{"*.hcl"}
+
+	selected := make(map[string]bool, len(categories))
+	for _, name := range categories {
+		selected[name] = true
+	}
+	for _, category := range available {
+		if selected[category.Name] || (len(categories) == 0 && !category.OptIn) {
+			patterns = append(patterns, category.Pattern)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRuleCategories(t *testing.T) {
+	rule := func(id string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`
+rule "` + id + `" {
+  name          = "` + id + `"
+  severity      = "warning"
+  resource_type = "*"
+  condition {
+    expression = "true"
+  }
+  message = "` + id + `"
+}
+`)}
+	}
+	fsys := fstest.MapFS{
+		"root.hcl":                     rule("root_rule"),
+		"aws/s3.hcl":                   rule("aws_rule"),
+		"aws/pack.hcl":                 &fstest.MapFile{Data: []byte(`pack "aws" {}`)},
+		"common/security.hcl":          rule("security_rule"),
+		"common/tagging.hcl":           rule("tagging_rule"),
+		"hygiene/unreferenced.hcl":     rule("hygiene_rule"),
+		"hygiene/orphans.hcl":          rule("orphans_rule"),
+		"networking/vpc.hcl":           rule("networking_rule"),
+		"networking/vpc_test.hcl":      &fstest.MapFile{Data: []byte(`test "vpc" {}`)},
+		"drafts/README.md":             &fstest.MapFile{Data: []byte("Not a category")},
+		"packs/team/pack.hcl":          &fstest.MapFile{Data: []byte(`pack "team" {}`)},
+		".git/config.hcl":              &fstest.MapFile{Data: []byte("")},
+		"manifests/pack.hcl":           &fstest.MapFile{Data: []byte(`pack "manifests" {}`)},
+		"manifests/examples_test.hcl":  &fstest.MapFile{Data: []byte("")},
+		"networking/subnets/extra.hcl": rule("nested_rule"),
+	}
+
+	categories, err := RuleCategories(fsys)
+	if err != nil {
+		t.Fatalf("RuleCategories() error = %v", err)
+	}
+	want := []RuleCategory{
+		{Name: "aws", Pattern: "aws/*.hcl"},
+		{Name: "common", Pattern: "common/*.hcl"},
+		{Name: "hygiene", Pattern: "hygiene/*.hcl", OptIn: true},
+		{Name: "networking", Pattern: "networking/*.hcl"},
+		{Name: "orphans", Pattern: "hygiene/orphans.hcl", OptIn: true},
+		{Name: "security", Pattern: "common/security.hcl"},
+		{Name: "tagging", Pattern: "common/tagging.hcl"},
+		{Name: "unreferenced", Pattern: "hygiene/unreferenced.hcl", OptIn: true},
+	}
+	if !reflect.DeepEqual(categories, want) {
+		t.Errorf("RuleCategories() = %+v, want %+v", categories, want)
+	}
+
+	tests := []struct {
+		name       string
+		categories []string
+		wantRules  []string
+	}{
+		{
+			name:      "default",
+			wantRules: []string{"root_rule", "aws_rule", "security_rule", "tagging_rule", "networking_rule"},
+		},
+		{
+			name:       "custom directory",
+			categories: []string{"networking"},
+			wantRules:  []string{"root_rule", "networking_rule"},
+		},
+		{
+			name:       "overlapping categories",
+			categories: []string{"common", "security", "orphans"},
+			wantRules:  []string{"root_rule", "security_rule", "tagging_rule", "orphans_rule"},
+		},
+		{
+			name:       "unknown category",
+			categories: []string{"gcp"},
+			wantRules:  []string{"root_rule"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := LoadDefaultRulesFS(fsys, tt.categories)
+			if err != nil {
+				t.Fatalf("LoadDefaultRulesFS() error = %v", err)
+			}
+			got := make(map[string]bool)
+			for _, rule := range rules {
+				got[rule.ID] = true
+			}
+			if len(rules) != len(tt.wantRules) {
+				t.Errorf("loaded %d rules, want %v", len(rules), tt.wantRules)
+			}
+			for _, id := range tt.wantRules {
+				if !got[id] {
+					t.Errorf("rule %s was not loaded", id)
+				}
+			}
+		})
+	}
+
+	// A directory named like a file category is the category
+	named, err := RuleCategories(fstest.MapFS{
+		"common/security.hcl":  rule("security_rule"),
+		"security/network.hcl": rule("network_rule"),
+	})
+	if err != nil {
+		t.Fatalf("RuleCategories() error = %v", err)
+	}
+	wantNamed := []RuleCategory{
+		{Name: "common", Pattern: "common/*.hcl"},
+		{Name: "security", Pattern: "security/*.hcl"},
+	}
+	if !reflect.DeepEqual(named, wantNamed) {
+		t.Errorf("RuleCategories() = %+v, want %+v", named, wantNamed)
+	}
+
+	missing, err := RuleCategories(os.DirFS(filepath.Join(t.TempDir(), "missing")))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("RuleCategories(missing) = %v, %v; want none", missing, err)
+	}
+}
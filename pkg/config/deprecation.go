@@ -0,0 +1,45 @@
+package config
+
+import "fmt"
+
+// IsDeprecated reports whether the rule is deprecated, directly or by
+// naming a rule that supersedes it
+func (r Rule) IsDeprecated() bool {
+	return r.Deprecated || r.SupersededBy != nil
+}
+
+// Deprecation describes the rule's deprecation for reports, e.g.
+// "deprecated, superseded by aws_s3_versioning_v2", or returns "" when the
+// rule is not deprecated
+func (r Rule) Deprecation() string {
+	switch {
+	case r.SupersededBy != nil:
+		return "deprecated, superseded by " + *r.SupersededBy
+	case r.Deprecated:
+		return "deprecated"
+	}
+	return ""
+}
+
+// DeprecationWarnings returns a warning for each deprecated rule among the
+// enabled rules, saying what to do about it
+func DeprecationWarnings(rules []Rule) []string {
+	enabled := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		enabled[rule.ID] = true
+	}
+
+	var warnings []string
+	for _, rule := range rules {
+		switch {
+		case !rule.IsDeprecated():
+		case rule.SupersededBy == nil:
+			warnings = append(warnings, fmt.Sprintf("rule %s is deprecated and will be removed; add it to disabled_rules", rule.ID))
+		case enabled[*rule.SupersededBy]:
+			warnings = append(warnings, fmt.Sprintf("rule %s is deprecated and superseded by %s, which is also enabled; add %s to disabled_rules", rule.ID, *rule.SupersededBy, rule.ID))
+		default:
+			warnings = append(warnings, fmt.Sprintf("rule %s is deprecated; enable %s in its place and add %s to disabled_rules", rule.ID, *rule.SupersededBy, rule.ID))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeprecation(t *testing.T) {
+	successor := "new_rule"
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"not deprecated", Rule{ID: "r"}, ""},
+		{"deprecated", Rule{ID: "r", Deprecated: true}, "deprecated"},
+		{"superseded", Rule{ID: "r", SupersededBy: &successor}, "deprecated, superseded by new_rule"},
+		{"deprecated and superseded", Rule{ID: "r", Deprecated: true, SupersededBy: &successor}, "deprecated, superseded by new_rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Deprecation(); got != tt.want {
+				t.Errorf("Deprecation() = %q, want %q", got, tt.want)
+			}
+			if got := tt.rule.IsDeprecated(); got != (tt.want != "") {
+				t.Errorf("IsDeprecated() = %v, want %v", got, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	successor := "new_rule"
+	missing := "missing_rule"
+	rules := []Rule{
+		{ID: "current"},
+		{ID: "old", Deprecated: true},
+		{ID: "replaced", SupersededBy: &successor},
+		{ID: "orphaned", SupersededBy: &missing},
+		{ID: "new_rule"},
+	}
+	want := []string{
+		"rule old is deprecated and will be removed; add it to disabled_rules",
+		"rule replaced is deprecated and superseded by new_rule, which is also enabled; add replaced to disabled_rules",
+		"rule orphaned is deprecated; enable missing_rule in its place and add orphaned to disabled_rules",
+	}
+	if got := DeprecationWarnings(rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("DeprecationWarnings() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// ExceptionsDir is the directory next to a config file whose .hcl files
+// hold further exceptions, loaded with the config. Keeping exceptions there
+// lets changes to them be reviewed apart from policy changes.
+const ExceptionsDir = "exceptions"
+
+// ExceptionFiles returns the .hcl files in an exceptions directory, sorted
+// by name. A missing directory has none.
+func ExceptionFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exceptions directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".hcl" {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadExceptionsDir loads the exceptions in every file of an exceptions
+// directory, in order of file name
+func LoadExceptionsDir(dir string) ([]Exception, error) {
+	files, err := ExceptionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var exceptions []Exception
+	for _, file := range files {
+		loaded, err := LoadExceptionFile(file)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, loaded...)
+	}
+	return exceptions, nil
+}
+
+// LoadExceptionFile loads a file of exception blocks. Unlike in a config
+// file, every exception must name who approved it and when it expires.
+func LoadExceptionFile(path string) ([]Exception, error) {
+	var file struct {
+		Exceptions []Exception `hcl:"exception,block"`
+	}
+	if err := hclsimple.DecodeFile(path, nil, &file); err != nil {
+		return nil, fmt.Errorf("failed to load exceptions: %w", err)
+	}
+	for i := range file.Exceptions {
+		exception := &file.Exceptions[i]
+		if exception.ApprovedBy == "" {
+			return nil, fmt.Errorf("%s: exception %d has no approved_by", path, i+1)
+		}
+		if exception.ExpiresAt == nil {
+			return nil, fmt.Errorf("%s: exception %d has no expires_at; exceptions in %s/ must expire", path, i+1, ExceptionsDir)
+		}
+		if err := exception.checkExpiresAt(path, i+1); err != nil {
+			return nil, err
+		}
+		exception.Source = path
+	}
+	return file.Exceptions, nil
+}
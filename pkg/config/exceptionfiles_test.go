@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadExceptionsDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.hcl": `
+exception {
+  rules       = ["rule_b"]
+  reason      = "Second"
+  approved_by = "platform"
+  expires_at  = "2030-01-01"
+}
+`,
+		"a.hcl": `
+exception {
+  rules       = ["rule_a"]
+  reason      = "First"
+  approved_by = "security"
+  expires_at  = "2030-06-30"
+}
+`,
+		"notes.txt": "not loaded",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exceptions, err := LoadExceptionsDir(dir)
+	if err != nil {
+		t.Fatalf("LoadExceptionsDir() error = %v", err)
+	}
+	if len(exceptions) != 2 {
+		t.Fatalf("got %d exceptions, want 2", len(exceptions))
+	}
+	if exceptions[0].Rules[0] != "rule_a" || exceptions[1].Rules[0] != "rule_b" {
+		t.Errorf("exceptions not in file name order: %+v", exceptions)
+	}
+	if want := filepath.Join(dir, "a.hcl"); exceptions[0].Source != want {
+		t.Errorf("Source = %q, want %q", exceptions[0].Source, want)
+	}
+
+	missing, err := LoadExceptionsDir(filepath.Join(dir, "missing"))
+	if err != nil || len(missing) != 0 {
+		t.Errorf("LoadExceptionsDir(missing) = %v, %v; want none", missing, err)
+	}
+}
+
+func TestLoadExceptionFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "no expiry",
+			content: `
+exception {
+  rules       = ["r"]
+  reason      = "Testing"
+  approved_by = "platform"
+}
+`,
+			wantErr: "exception 1 has no expires_at",
+		},
+		{
+			name: "invalid expiry",
+			content: `
+exception {
+  rules       = ["r"]
+  reason      = "Testing"
+  approved_by = "platform"
+  expires_at  = "next year"
+}
+`,
+			wantErr: `invalid expires_at "next year"`,
+		},
+		{
+			name: "empty approver",
+			content: `
+exception {
+  rules       = ["r"]
+  reason      = "Testing"
+  approved_by = ""
+  expires_at  = "2030-01-01"
+}
+`,
+			wantErr: "exception 1 has no approved_by",
+		},
+		{
+			name: "missing approver",
+			content: `
+exception {
+  rules      = ["r"]
+  reason     = "Testing"
+  expires_at = "2030-01-01"
+}
+`,
+			wantErr: `"approved_by" is required`,
+		},
+		{
+			name: "other blocks",
+			content: `
+settings {
+  fail_on_warning = true
+}
+`,
+			wantErr: `Unsupported block type`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "exceptions.hcl")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadExceptionFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadExceptionFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
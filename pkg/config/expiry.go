@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExpiryDateFormat is the layout of an exception's expires_at date
+const ExpiryDateFormat = "2006-01-02"
+
+// DefaultExpiryWarningDays is how many days before its expires_at date an
+// exception is reported as expiring soon
+const DefaultExpiryWarningDays = 14
+
+// ExpiryDate returns the parsed expires_at date. ok is false when the
+// exception has no date or the date cannot be parsed.
+func (e Exception) ExpiryDate() (date time.Time, ok bool) {
+	if e.ExpiresAt == nil {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(ExpiryDateFormat, *e.ExpiresAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// checkExpiresAt returns an error naming the exception, the nth in the
+// file at path, when its expires_at is set but is not a YYYY-MM-DD date
+func (e Exception) checkExpiresAt(path string, n int) error {
+	if e.ExpiresAt == nil {
+		return nil
+	}
+	if _, err := time.Parse(ExpiryDateFormat, *e.ExpiresAt); err != nil {
+		return fmt.Errorf("%s: exception %d has an invalid expires_at %q (expected YYYY-MM-DD)", path, n, *e.ExpiresAt)
+	}
+	return nil
+}
+
+// Expired reports whether the exception's expires_at date has passed.
+// Exceptions without a valid date never expire.
+func (e Exception) Expired(now time.Time) bool {
+	date, ok := e.ExpiryDate()
+	return ok && now.After(date)
+}
+
+// ExpiringException is an exception that has expired or expires soon
+type ExpiringException struct {
+	Exception Exception
+	ExpiresAt string
+	Expired   bool
+	DaysLeft  int // Calendar days from today until expires_at
+}
+
+// ExpiringExceptions returns the exceptions that have expired or expire
+// within warningDays of now, soonest first
+func ExpiringExceptions(exceptions []Exception, now time.Time, warningDays int) []ExpiringException {
+	var expiring []ExpiringException
+	for _, exception := range exceptions {
+		date, ok := exception.ExpiryDate()
+		if !ok {
+			continue
+		}
+
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		daysLeft := int(date.Sub(today).Hours() / 24)
+		if daysLeft > warningDays {
+			continue
+		}
+
+		expiring = append(expiring, ExpiringException{
+			Exception: exception,
+			ExpiresAt: *exception.ExpiresAt,
+			Expired:   exception.Expired(now),
+			DaysLeft:  daysLeft,
+		})
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt < expiring[j].ExpiresAt
+	})
+	return expiring
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExceptionExpired(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expiresAt *string
+		want      bool
+	}{
+		{"no date", nil, false},
+		{"invalid date", stringPtr("next week"), false},
+		{"past date", stringPtr("2024-06-14"), true},
+		{"today", stringPtr("2024-06-15"), true},
+		{"future date", stringPtr("2024-06-16"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exception := Exception{ExpiresAt: tt.expiresAt}
+			if got := exception.Expired(now); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiringExceptions(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	exceptions := []Exception{
+		{Rules: []string{"far"}, ExpiresAt: stringPtr("2024-12-31")},
+		{Rules: []string{"soon"}, ExpiresAt: stringPtr("2024-06-20")},
+		{Rules: []string{"expired"}, ExpiresAt: stringPtr("2024-06-01")},
+		{Rules: []string{"permanent"}},
+	}
+
+	expiring := ExpiringExceptions(exceptions, now, 7)
+	if len(expiring) != 2 {
+		t.Fatalf("ExpiringExceptions() returned %d exceptions, want 2: %+v", len(expiring), expiring)
+	}
+
+	if expiring[0].Exception.Rules[0] != "expired" || !expiring[0].Expired || expiring[0].DaysLeft != -14 {
+		t.Errorf("First entry = %+v, want the expired exception 14 days past", expiring[0])
+	}
+	if expiring[1].Exception.Rules[0] != "soon" || expiring[1].Expired || expiring[1].DaysLeft != 5 {
+		t.Errorf("Second entry = %+v, want the exception expiring in 5 days", expiring[1])
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+)
+
+// fingerprintLength is the number of hex digits kept from the hash, enough
+// to tell the violations of a large configuration apart
+const fingerprintLength = 12
+
+// Fingerprint returns a short, stable ID for a violation, derived from its
+// rule, file, and resource. Like baseline entries, it does not depend on
+// the line number, so it survives unrelated edits that move code around.
+func Fingerprint(v Violation) string {
+	h := sha256.New()
+	for _, part := range []string{v.RuleID, filepath.ToSlash(v.File), v.ResourceType, v.ResourceName} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:fingerprintLength]
+}
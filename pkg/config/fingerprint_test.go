@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	base := Violation{RuleID: "s3_public", File: "modules/s3/main.tf", Line: 10, ResourceType: "aws_s3_bucket", ResourceName: "logs"}
+
+	fingerprint := Fingerprint(base)
+	if len(fingerprint) != fingerprintLength {
+		t.Fatalf("Fingerprint() = %q, want %d hex digits", fingerprint, fingerprintLength)
+	}
+
+	tests := []struct {
+		name   string
+		change func(v *Violation)
+		same   bool
+	}{
+		{name: "line moved", change: func(v *Violation) { v.Line = 42 }, same: true},
+		{name: "message changed", change: func(v *Violation) { v.Message = "reworded" }, same: true},
+		{name: "other rule", change: func(v *Violation) { v.RuleID = "s3_versioning" }},
+		{name: "other file", change: func(v *Violation) { v.File = "main.tf" }},
+		{name: "other resource", change: func(v *Violation) { v.ResourceName = "data" }},
+		{name: "parts not concatenated", change: func(v *Violation) { v.ResourceType = "aws_s3_bucketl"; v.ResourceName = "ogs" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := base
+			tt.change(&v)
+			if got := Fingerprint(v) == fingerprint; got != tt.same {
+				t.Errorf("Fingerprint() unchanged = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
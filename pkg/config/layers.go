@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Layer is one config file of a layered configuration, as decoded by
+// DecodeConfig
+type Layer struct {
+	Path   string
+	Config *Config
+}
+
+// MergeLayers merges config layers, broadest first (e.g. organization,
+// then user, then repository), into one config with defaults applied.
+// Later layers add rules, exceptions, and functions, and override settings,
+// but cannot weaken rules from earlier layers:
+//
+//   - A rule with the ID of an earlier layer's rule can only raise its
+//     severity; the rest of its definition is ignored
+//   - disabled_rules cannot name an earlier layer's rules, and
+//     enabled_rules always keeps them
+//   - Earlier layers' enabled_rules do not filter out later layers' rules
+//   - Profiles follow the same limits, and cannot make an earlier layer's
+//     rules less severe
+//   - severity_map cannot make an earlier layer's rules less severe either;
+//     their severities are recorded in SeverityFloors
+//
+// Lists in settings are combined, maps are combined with later keys taking
+// precedence, and flags such as fail_on_warning are on if any layer turns
+// them on. MergeLayers returns a warning for each change it ignored.
+func MergeLayers(layers []Layer) (*Config, []string) {
+	merged := &Config{Settings: &Settings{}}
+	var warnings []string
+
+	owner := make(map[string]int) // Rule ID to the layer defining it
+	index := make(map[string]int) // Rule ID to its index in merged.Rules
+	functions := make(map[string]string)
+	restricted := false     // An earlier layer set enabled_rules
+	severityMapLayer := -1  // Layer setting severity_map, the last one wins
+	var profileLayers []int // Layer defining each of merged.Profiles
+
+	for i, layer := range layers {
+		cfg := layer.Config
+		var added []string
+		for _, rule := range cfg.Rules {
+			j, defined := owner[rule.ID]
+			if !defined || j == i {
+				if !defined {
+					owner[rule.ID] = i
+					index[rule.ID] = len(merged.Rules)
+					added = append(added, rule.ID)
+				}
+				merged.Rules = append(merged.Rules, rule)
+				continue
+			}
+			existing := &merged.Rules[index[rule.ID]]
+			if severityRank[rule.Severity] > severityRank[existing.Severity] {
+				existing.Severity = rule.Severity
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("%s: rule %s is defined in %s, which only a more severe severity can override; ignoring it", layer.Path, rule.ID, layers[j].Path))
+		}
+
+		merged.Exceptions = append(merged.Exceptions, cfg.Exceptions...)
+		merged.Unknown = append(merged.Unknown, cfg.Unknown...)
+		for _, profile := range cfg.Profiles {
+			merged.Profiles = append(merged.Profiles, profile)
+			profileLayers = append(profileLayers, i)
+		}
+		for _, fn := range cfg.Functions {
+			if path, ok := functions[fn.Name]; ok {
+				warnings = append(warnings, fmt.Sprintf("%s: function %s is already defined in %s; ignoring it", layer.Path, fn.Name, path))
+				continue
+			}
+			functions[fn.Name] = layer.Path
+			merged.Functions = append(merged.Functions, fn)
+		}
+
+		if cfg.Settings == nil {
+			if restricted {
+				merged.Settings.EnabledRules = appendMissing(merged.Settings.EnabledRules, added...)
+			}
+			continue
+		}
+		settings := *cfg.Settings
+
+		var disabled []string
+		for _, id := range settings.DisabledRules {
+			if j, ok := owner[id]; ok && j < i {
+				warnings = append(warnings, fmt.Sprintf("%s: rule %s is defined in %s and cannot be disabled", layer.Path, id, layers[j].Path))
+				continue
+			}
+			disabled = append(disabled, id)
+		}
+		settings.DisabledRules = disabled
+
+		if len(settings.EnabledRules) > 0 {
+			enabled := append([]string{}, settings.EnabledRules...)
+			for _, rule := range merged.Rules {
+				if owner[rule.ID] < i {
+					enabled = appendMissing(enabled, rule.ID)
+				}
+			}
+			settings.EnabledRules = enabled
+		}
+		if restricted {
+			settings.EnabledRules = appendMissing(settings.EnabledRules, added...)
+		}
+		restricted = restricted || len(settings.EnabledRules) > 0
+		if settings.SeverityMap != nil {
+			severityMapLayer = i
+		}
+
+		mergeSettings(reflect.ValueOf(merged.Settings).Elem(), reflect.ValueOf(settings))
+	}
+
+	// Profiles are held to the same limits as settings, and their
+	// enabled_rules only filter the rules of their own layer
+	for k := range merged.Profiles {
+		profile := &merged.Profiles[k]
+		i := profileLayers[k]
+		path := layers[i].Path
+
+		var disabled []string
+		for _, id := range profile.DisabledRules {
+			if j, ok := owner[id]; ok && j < i {
+				warnings = append(warnings, fmt.Sprintf("%s: rule %s is defined in %s and cannot be disabled by profile %s", path, id, layers[j].Path, profile.Name))
+				continue
+			}
+			disabled = append(disabled, id)
+		}
+		profile.DisabledRules = disabled
+
+		if len(profile.EnabledRules) > 0 {
+			enabled := append([]string{}, profile.EnabledRules...)
+			for _, rule := range merged.Rules {
+				if owner[rule.ID] != i {
+					enabled = appendMissing(enabled, rule.ID)
+				}
+			}
+			profile.EnabledRules = enabled
+		}
+
+		if len(profile.Severities) > 0 {
+			severities := make(map[string]string)
+			for id, severity := range profile.Severities {
+				if j, ok := owner[id]; ok && j < i && severityRank[severity] < severityRank[merged.Rules[index[id]].Severity] {
+					warnings = append(warnings, fmt.Sprintf("%s: rule %s is defined in %s and cannot be made less severe by profile %s", path, id, layers[j].Path, profile.Name))
+					continue
+				}
+				severities[id] = severity
+			}
+			profile.Severities = severities
+		}
+	}
+
+	for _, rule := range merged.Rules {
+		if owner[rule.ID] < severityMapLayer {
+			if merged.SeverityFloors == nil {
+				merged.SeverityFloors = make(map[string]string)
+			}
+			merged.SeverityFloors[rule.ID] = rule.Severity
+		}
+	}
+
+	applyDefaults(merged)
+	return merged, warnings
+}
+
+// mergeSettings merges the fields of src, a later layer's settings, into dst
+func mergeSettings(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		switch d.Kind() {
+		case reflect.Bool:
+			d.SetBool(d.Bool() || s.Bool())
+		case reflect.Pointer:
+			if !s.IsNil() {
+				d.Set(s)
+			}
+		case reflect.Slice:
+			if d.Type().Elem().Kind() == reflect.String {
+				d.Set(reflect.ValueOf(appendMissing(d.Interface().([]string), s.Interface().([]string)...)))
+			} else {
+				d.Set(reflect.AppendSlice(d, s))
+			}
+		case reflect.Map:
+			if s.Len() == 0 {
+				continue
+			}
+			if d.IsNil() {
+				d.Set(reflect.MakeMap(d.Type()))
+			}
+			iter := s.MapRange()
+			for iter.Next() {
+				d.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+	}
+}
+
+// appendMissing appends the values not already in list
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeLayers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) Layer {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := DecodeConfig(path)
+		if err != nil {
+			t.Fatalf("DecodeConfig(%s) error = %v", name, err)
+		}
+		return Layer{Path: path, Config: cfg}
+	}
+	rule := func(id, severity string) string {
+		return `
+rule "` + id + `" {
+  name          = "` + id + `"
+  severity      = "` + severity + `"
+  resource_type = "aws_instance"
+  condition {
+    expression = "true"
+  }
+  message = "` + id + `"
+}
+`
+	}
+
+	org := write("org.hcl", rule("org_rule", "warning")+rule("org_other", "error")+`
+settings {
+  exclude_paths          = ["vendor/**"]
+  max_active_exceptions  = 5
+  risk_rule_weights      = { org_rule = 2 }
+  enabled_rules          = ["org_rule", "org_other", "aws_s3_versioning"]
+}
+`)
+	repo := write("repo.hcl", rule("org_rule", "error")+rule("org_other", "info")+rule("repo_rule", "info")+`
+settings {
+  fail_on_warning       = true
+  exclude_paths         = ["vendor/**", "examples/**"]
+  max_active_exceptions = 10
+  risk_rule_weights     = { repo_rule = 3 }
+  disabled_rules        = ["org_other", "aws_s3_versioning"]
+}
+
+exception {
+  rules       = ["repo_rule"]
+  reason      = "Testing"
+  approved_by = "test@example.com"
+}
+`)
+
+	cfg, warnings := MergeLayers([]Layer{org, repo})
+
+	severities := make(map[string]string)
+	for _, r := range cfg.Rules {
+		severities[r.ID] = r.Severity
+	}
+	wantSeverities := map[string]string{"org_rule": "error", "org_other": "error", "repo_rule": "info"}
+	if !reflect.DeepEqual(severities, wantSeverities) {
+		t.Errorf("rule severities = %v, want %v", severities, wantSeverities)
+	}
+	if len(cfg.Exceptions) != 1 {
+		t.Errorf("got %d exceptions, want 1", len(cfg.Exceptions))
+	}
+
+	s := cfg.Settings
+	if !s.FailOnWarning {
+		t.Error("FailOnWarning should be true")
+	}
+	if want := []string{"vendor/**", "examples/**"}; !reflect.DeepEqual(s.ExcludePaths, want) {
+		t.Errorf("ExcludePaths = %v, want %v", s.ExcludePaths, want)
+	}
+	if s.MaxActiveExceptions == nil || *s.MaxActiveExceptions != 10 {
+		t.Errorf("MaxActiveExceptions = %v, want 10", s.MaxActiveExceptions)
+	}
+	if want := map[string]float64{"org_rule": 2, "repo_rule": 3}; !reflect.DeepEqual(s.RiskRuleWeights, want) {
+		t.Errorf("RiskRuleWeights = %v, want %v", s.RiskRuleWeights, want)
+	}
+	if want := []string{"aws_s3_versioning"}; !reflect.DeepEqual(s.DisabledRules, want) {
+		t.Errorf("DisabledRules = %v, want %v", s.DisabledRules, want)
+	}
+	if want := []string{"org_rule", "org_other", "aws_s3_versioning", "repo_rule"}; !reflect.DeepEqual(s.EnabledRules, want) {
+		t.Errorf("EnabledRules = %v, want %v", s.EnabledRules, want)
+	}
+	if s.UsePresuppliedRules == nil || !*s.UsePresuppliedRules {
+		t.Error("UsePresuppliedRules should default to true")
+	}
+
+	wantWarnings := []string{
+		"rule org_other is defined in " + org.Path,
+		"rule org_other is defined in " + org.Path + " and cannot be disabled",
+	}
+	if len(warnings) != len(wantWarnings) {
+		t.Fatalf("warnings = %q, want %d", warnings, len(wantWarnings))
+	}
+	for i, want := range wantWarnings {
+		if !strings.Contains(warnings[i], want) {
+			t.Errorf("warning %d = %q, want it to contain %q", i, warnings[i], want)
+		}
+	}
+}
+
+func TestMergeLayersEnabledRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []*Config
+		want   []string
+	}{
+		{
+			name: "no layer restricts",
+			layers: []*Config{
+				{Rules: []Rule{{ID: "a"}}},
+				{Rules: []Rule{{ID: "b"}}},
+			},
+			want: nil,
+		},
+		{
+			name: "later layer keeps earlier rules",
+			layers: []*Config{
+				{Rules: []Rule{{ID: "a"}}},
+				{Settings: &Settings{EnabledRules: []string{"x"}}},
+			},
+			want: []string{"x", "a"},
+		},
+		{
+			name: "earlier layer does not filter later rules",
+			layers: []*Config{
+				{Settings: &Settings{EnabledRules: []string{"x"}}},
+				{Rules: []Rule{{ID: "b"}}},
+			},
+			want: []string{"x", "b"},
+		},
+		{
+			name: "within one layer",
+			layers: []*Config{
+				{Rules: []Rule{{ID: "a"}, {ID: "b"}}, Settings: &Settings{EnabledRules: []string{"a"}}},
+			},
+			want: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layers []Layer
+			for i, cfg := range tt.layers {
+				layers = append(layers, Layer{Path: string(rune('1' + i)), Config: cfg})
+			}
+			cfg, _ := MergeLayers(layers)
+			if !reflect.DeepEqual(cfg.Settings.EnabledRules, tt.want) {
+				t.Errorf("EnabledRules = %v, want %v", cfg.Settings.EnabledRules, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLayersProfiles(t *testing.T) {
+	layers := []Layer{
+		{Path: "org.hcl", Config: &Config{Rules: []Rule{{ID: "org_rule", Severity: "warning"}}}},
+		{Path: "repo.hcl", Config: &Config{
+			Rules: []Rule{{ID: "repo_rule", Severity: "error"}},
+			Profiles: []Profile{{
+				Name:          "dev",
+				DisabledRules: []string{"org_rule", "repo_rule"},
+				EnabledRules:  []string{"aws_s3_versioning"},
+				Severities:    map[string]string{"org_rule": "info", "repo_rule": "info", "aws_s3_versioning": "error"},
+			}},
+		}},
+	}
+
+	cfg, warnings := MergeLayers(layers)
+	if len(cfg.Profiles) != 1 {
+		t.Fatalf("got %d profiles, want 1", len(cfg.Profiles))
+	}
+	profile := cfg.Profiles[0]
+	if want := []string{"repo_rule"}; !reflect.DeepEqual(profile.DisabledRules, want) {
+		t.Errorf("DisabledRules = %v, want %v", profile.DisabledRules, want)
+	}
+	if want := []string{"aws_s3_versioning", "org_rule"}; !reflect.DeepEqual(profile.EnabledRules, want) {
+		t.Errorf("EnabledRules = %v, want %v", profile.EnabledRules, want)
+	}
+	if want := map[string]string{"repo_rule": "info", "aws_s3_versioning": "error"}; !reflect.DeepEqual(profile.Severities, want) {
+		t.Errorf("Severities = %v, want %v", profile.Severities, want)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %q, want 2", warnings)
+	}
+}
+
+func TestMergeLayersSeverityMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "severity.yaml")
+	content := `
+rules:
+  org_rule: info
+  repo_rule: info
+tags:
+  cost: info
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layers := []Layer{
+		{Path: "org.hcl", Config: &Config{Rules: []Rule{
+			{ID: "org_rule", Severity: "error"},
+			{ID: "org_tagged", Severity: "warning", Tags: []string{"cost"}},
+		}}},
+		{Path: "repo.hcl", Config: &Config{
+			Rules:    []Rule{{ID: "repo_rule", Severity: "error"}},
+			Settings: &Settings{SeverityMap: &path},
+		}},
+	}
+
+	cfg, _ := MergeLayers(layers)
+	if want := map[string]string{"org_rule": "error", "org_tagged": "warning"}; !reflect.DeepEqual(cfg.SeverityFloors, want) {
+		t.Errorf("SeverityFloors = %v, want %v", cfg.SeverityFloors, want)
+	}
+
+	m, err := LoadSeverityMap(*cfg.Settings.SeverityMap)
+	if err != nil {
+		t.Fatalf("LoadSeverityMap() error = %v", err)
+	}
+	warnings := m.Protect(cfg.SeverityFloors, path)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "org_rule") {
+		t.Errorf("Protect() warnings = %q, want one for org_rule", warnings)
+	}
+
+	severities := make(map[string]string)
+	for _, rule := range m.Apply(cfg.Rules) {
+		severities[rule.ID] = rule.Severity
+	}
+	if want := map[string]string{"org_rule": "error", "org_tagged": "warning", "repo_rule": "info"}; !reflect.DeepEqual(severities, want) {
+		t.Errorf("severities = %v, want %v", severities, want)
+	}
+
+	// A severity map in the broadest layer is not limited
+	layers[0].Config.Settings = &Settings{SeverityMap: &path}
+	layers[1].Config.Settings = nil
+	if cfg, _ := MergeLayers(layers); cfg.SeverityFloors != nil {
+		t.Errorf("SeverityFloors = %v, want none", cfg.SeverityFloors)
+	}
+}
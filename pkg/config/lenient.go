@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/json"
+)
+
+// unknownSummaries are the summaries of the diagnostics HCL reports for
+// attributes and blocks a schema does not have, in native and JSON syntax
+var unknownSummaries = map[string]bool{
+	"Unsupported argument":            true,
+	"Unsupported block type":          true,
+	"Extraneous JSON object property": true,
+}
+
+// decodeFile decodes an HCL or, for names ending in .json, JSON file into
+// target as hclsimple.Decode does, with unknown attributes and blocks
+// returned in unknown as decodeLenient returns them. Errors are the
+// hcl.Diagnostics of the file.
+func decodeFile(filename string, src []byte, target interface{}) (unknown hcl.Diagnostics, err error) {
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		file, diags = json.Parse(src, filename)
+	} else {
+		file, diags = hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	unknown, diags = decodeLenient(file.Body, target)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return unknown, nil
+}
+
+// readAndDecodeFile reads a file and decodes it with decodeFile
+func readAndDecodeFile(filename string, target interface{}) (hcl.Diagnostics, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFile(filename, src, target)
+}
+
+// decodeLenient decodes body into target like gohcl.DecodeBody, except
+// that attributes and blocks target has no field for, such as a misspelled
+// severty, do not fail decoding. They are returned as warnings in unknown,
+// in source order, and the other diagnostics in diags.
+func decodeLenient(body hcl.Body, target interface{}) (unknown, diags hcl.Diagnostics) {
+	diags = gohcl.DecodeBody(&lenientBody{Body: body, unknown: &unknown}, nil, target)
+	// Nested blocks are decoded after the body holding them
+	sort.SliceStable(unknown, func(i, j int) bool {
+		return unknown[i].Subject.Start.Byte < unknown[j].Subject.Start.Byte
+	})
+	return unknown, diags
+}
+
+// lenientBody is a body whose unknown attributes and blocks, and those of
+// the blocks nested in it, are recorded in unknown rather than reported as
+// errors
+type lenientBody struct {
+	hcl.Body
+	unknown *hcl.Diagnostics
+}
+
+func (b *lenientBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	content, diags := b.Body.Content(schema)
+	return b.wrap(content), b.sift(diags)
+}
+
+func (b *lenientBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	content, remain, diags := b.Body.PartialContent(schema)
+	return b.wrap(content), &lenientBody{Body: remain, unknown: b.unknown}, b.sift(diags)
+}
+
+// wrap makes the bodies of content's blocks lenient too
+func (b *lenientBody) wrap(content *hcl.BodyContent) *hcl.BodyContent {
+	if content == nil {
+		return nil
+	}
+	for _, block := range content.Blocks {
+		block.Body = &lenientBody{Body: block.Body, unknown: b.unknown}
+	}
+	return content
+}
+
+// sift moves the diagnostics for unknown attributes and blocks out of
+// diags into b.unknown as warnings
+func (b *lenientBody) sift(diags hcl.Diagnostics) hcl.Diagnostics {
+	var kept hcl.Diagnostics
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError && unknownSummaries[diag.Summary] {
+			warning := *diag
+			warning.Severity = hcl.DiagWarning
+			*b.unknown = append(*b.unknown, &warning)
+			continue
+		}
+		kept = append(kept, diag)
+	}
+	return kept
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestDecodeFileUnknown(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		src         string
+		wantUnknown []int // Lines of the unknown attributes and blocks
+		wantErr     string
+	}{
+		{
+			name:     "known attributes only",
+			filename: "config.hcl",
+			src: `settings {
+  fail_on_warning = true
+}
+`,
+		},
+		{
+			name:     "unknown attributes and blocks at any depth",
+			filename: "config.hcl",
+			src: `settings {
+  fail_on_warnings = true
+}
+
+rule "bucket_tags" {
+  name          = "Buckets must be tagged"
+  severty       = "error"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "true"
+    mesage     = "typo"
+  }
+  message = "Bucket has no tags"
+}
+
+rulle "typo" {
+}
+`,
+			wantUnknown: []int{2, 7, 12, 17},
+		},
+		{
+			name:        "JSON",
+			filename:    "config.json",
+			src:         `{"settings": {"fail_on_warnings": true}}`,
+			wantUnknown: []int{1},
+		},
+		{
+			name:     "missing required attribute still fails",
+			filename: "config.hcl",
+			src: `exception {
+  rules       = ["a"]
+  reson       = "typo"
+  approved_by = "security"
+}
+`,
+			wantErr: `The argument "reason" is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			unknown, err := decodeFile(tt.filename, []byte(tt.src), &config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeFile() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeFile() error = %v", err)
+			}
+
+			var got []int
+			for _, diag := range unknown {
+				if diag.Severity != hcl.DiagWarning {
+					t.Errorf("Unknown %q is not a warning", diag.Summary)
+				}
+				got = append(got, diag.Subject.Start.Line)
+			}
+			if !reflect.DeepEqual(got, tt.wantUnknown) {
+				t.Errorf("unknown on lines %v, want %v", got, tt.wantUnknown)
+			}
+		})
+	}
+}
@@ -1,23 +1,76 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/json"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
-// LoadConfig loads the guardian configuration from a file
+// LoadConfig loads the guardian configuration from a file. Files ending in
+// .json use HCL's JSON syntax, and files ending in .yaml or .yml the same
+// structure written as YAML; both decode to the same Config as HCL, with
+// the same defaults and validation.
 func LoadConfig(configPath string) (*Config, error) {
+	config, err := DecodeConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	applyDefaults(config)
+	return config, nil
+}
+
+// DecodeConfig loads a config file like LoadConfig, but without filling in
+// defaults, so it can be merged as a layer by MergeLayers
+func DecodeConfig(configPath string) (*Config, error) {
 	var config Config
 
-	err := hclsimple.DecodeFile(configPath, nil, &config)
+	var unknown hcl.Diagnostics
+	var err error
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".yaml", ".yml":
+		unknown, err = decodeYAMLFile(configPath, &config)
+	default:
+		unknown, err = readAndDecodeFile(configPath, &config)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	config.Unknown = unknown
+	for i := range config.Rules {
+		config.Rules[i].Source = configPath
+	}
+	for i := range config.Exceptions {
+		// A date that does not parse would never expire
+		if err := config.Exceptions[i].checkExpiresAt(configPath, i+1); err != nil {
+			return nil, err
+		}
+		config.Exceptions[i].Source = configPath
+	}
+	// A relative severity_map is found next to the config that sets it, as
+	// its exceptions directory is, wherever planguard runs from
+	if settings := config.Settings; settings != nil && settings.SeverityMap != nil {
+		if path := *settings.SeverityMap; path != "" && !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") {
+			resolved := filepath.Join(filepath.Dir(configPath), path)
+			settings.SeverityMap = &resolved
+		}
+	}
+	return &config, nil
+}
 
-	// Set defaults
+// applyDefaults fills in the settings a config file leaves out
+func applyDefaults(config *Config) {
 	if config.Settings == nil {
 		defaultUsePresuppliedRules := true
 		config.Settings = &Settings{
@@ -36,13 +89,123 @@ func LoadConfig(configPath string) (*Config, error) {
 			config.Settings.PresuppliedRulesCategories = []string{}
 		}
 	}
+}
 
-	return &config, nil
+// decodeYAMLFile decodes a YAML file into target by converting it to HCL's
+// JSON syntax, so it is validated exactly as a .json file would be. Unknown
+// attributes and blocks are returned with only the file name as their
+// subject, since their ranges point into the conversion rather than the
+// file.
+func decodeYAMLFile(filename string, target interface{}) (hcl.Diagnostics, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	body := hcl.EmptyBody()
+	if len(bytes.TrimSpace(src)) > 0 {
+		ty, err := ctyyaml.ImpliedType(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		val, err := ctyyaml.Unmarshal(src, ty)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		converted, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		file, diags := json.Parse(converted, filename)
+		if diags.HasErrors() {
+			return nil, yamlDiagnostics(filename, diags)
+		}
+		body = file.Body
+	}
+	unknown, diags := decodeLenient(body, target)
+	if diags.HasErrors() {
+		return nil, yamlDiagnostics(filename, diags)
+	}
+	for _, diag := range unknown {
+		diag.Subject, diag.Context = &hcl.Range{Filename: filename}, nil
+	}
+	return unknown, nil
+}
+
+// yamlDiagnostics reports diagnostics from decoding a converted YAML file
+// without their source ranges, which point into the conversion rather than
+// the file
+func yamlDiagnostics(filename string, diags hcl.Diagnostics) error {
+	var messages []string
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		message := diag.Summary
+		if diag.Detail != "" {
+			message += "; " + diag.Detail
+		}
+		messages = append(messages, message)
+	}
+	return fmt.Errorf("%s: %s", filename, strings.Join(messages, ", "))
+}
+
+// LoadTargets loads scan targets from an HCL targets file. Relative
+// directories and plans are resolved against the targets file's directory.
+func LoadTargets(path string) ([]Target, error) {
+	var file struct {
+		Targets []Target `hcl:"target,block"`
+	}
+
+	if err := hclsimple.DecodeFile(path, nil, &file); err != nil {
+		return nil, fmt.Errorf("failed to load targets: %w", err)
+	}
+
+	if len(file.Targets) == 0 {
+		return nil, fmt.Errorf("no targets defined in %s", path)
+	}
+
+	for i := range file.Targets {
+		if !filepath.IsAbs(file.Targets[i].Directory) {
+			file.Targets[i].Directory = filepath.Join(filepath.Dir(path), file.Targets[i].Directory)
+		}
+		if file.Targets[i].Plan != "" && !filepath.IsAbs(file.Targets[i].Plan) {
+			file.Targets[i].Plan = filepath.Join(filepath.Dir(path), file.Targets[i].Plan)
+		}
+	}
+
+	return file.Targets, nil
+}
+
+// PlanTargets returns one scan target per plan file, each scanning the
+// directory holding its plan and labeled plan=<path>, so violations can be
+// told apart per stack in a single report
+func PlanTargets(plans []string) []Target {
+	targets := make([]Target, 0, len(plans))
+	for _, plan := range plans {
+		targets = append(targets, Target{
+			Name:      plan,
+			Directory: filepath.Dir(plan),
+			Plan:      plan,
+			Labels:    map[string]string{"plan": filepath.ToSlash(plan)},
+		})
+	}
+	return targets
+}
+
+// RuleTestSuffix ends the names of rule test files, which hold test blocks
+// for planguard test rather than rules. Loading rules skips them.
+const RuleTestSuffix = "_test.hcl"
+
+// IsRuleTestFile reports whether a file is a rule test file
+func IsRuleTestFile(name string) bool {
+	return strings.HasSuffix(name, RuleTestSuffix)
 }
 
 // LoadRules loads rules from one or more HCL files
 func LoadRules(rulesPaths []string) ([]Rule, error) {
 	var allRules []Rule
+	packs := make(map[string]*PackManifest)
+	seen := make(map[string]bool)
 
 	for _, path := range rulesPaths {
 		// Check if path is a pattern
@@ -72,16 +235,38 @@ func LoadRules(rulesPaths []string) ([]Rule, error) {
 				continue
 			}
 
+			// Patterns overlap when a file is in several categories
+			if seen[match] || filepath.Base(match) == PackManifestFile || IsRuleTestFile(match) {
+				continue
+			}
+			seen[match] = true
+
 			// Load rules from file
 			var fileConfig struct {
 				Rules []Rule `hcl:"rule,block"`
 			}
 
-			err = hclsimple.DecodeFile(match, nil, &fileConfig)
+			unknown, err := readAndDecodeFile(match, &fileConfig)
 			if err != nil {
 				return nil, fmt.Errorf("failed to load rules from %s: %w", match, err)
 			}
 
+			// Rules belong to the pack described by their directory's manifest
+			dir := filepath.Dir(match)
+			pack, loaded := packs[dir]
+			if !loaded {
+				pack, err = LoadPackManifest(dir)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load rules from %s: %w", match, err)
+				}
+				packs[dir] = pack
+			}
+			for i := range fileConfig.Rules {
+				fileConfig.Rules[i].Pack = pack
+				fileConfig.Rules[i].Source = match
+				fileConfig.Rules[i].Unknown = unknown
+			}
+
 			allRules = append(allRules, fileConfig.Rules...)
 		}
 	}
@@ -89,80 +274,241 @@ func LoadRules(rulesPaths []string) ([]Rule, error) {
 	return allRules, nil
 }
 
+// FilterRules applies the enabled_rules allow-list and disabled_rules from
+// settings to rules, and leaves out opt-in rules (enabled = false) not
+// listed in enable_rules or enabled_rules. It also returns the listed rule
+// IDs that match no rule, which usually indicate a typo.
+func FilterRules(rules []Rule, settings *Settings) ([]Rule, []string) {
+	if settings == nil {
+		settings = &Settings{}
+	}
+
+	enabled := make(map[string]bool)
+	for _, id := range settings.EnabledRules {
+		enabled[id] = true
+	}
+	disabled := make(map[string]bool)
+	for _, id := range settings.DisabledRules {
+		disabled[id] = true
+	}
+	optedIn := make(map[string]bool)
+	for _, id := range settings.EnableRules {
+		optedIn[id] = true
+	}
+
+	known := make(map[string]bool)
+	var filtered []Rule
+	for _, rule := range rules {
+		known[rule.ID] = true
+		if len(enabled) > 0 && !enabled[rule.ID] {
+			continue
+		}
+		if disabled[rule.ID] {
+			continue
+		}
+		if rule.IsOptIn() && !optedIn[rule.ID] && !enabled[rule.ID] {
+			continue
+		}
+		filtered = append(filtered, rule)
+	}
+
+	var unknown []string
+	for _, list := range [][]string{settings.EnabledRules, settings.DisabledRules, settings.EnableRules} {
+		for _, id := range list {
+			if !known[id] {
+				unknown = append(unknown, id)
+			}
+		}
+	}
+
+	return filtered, unknown
+}
+
+// IsOptIn reports whether the rule is off unless a config enables it
+func (r Rule) IsOptIn() bool {
+	return r.Enabled != nil && !*r.Enabled
+}
+
+// SelectRules keeps the rules whose IDs match any of the include patterns,
+// or every rule when there are none, and drops those matching an exclude
+// pattern. Patterns are rule IDs or globs as in path.Match, e.g. aws_s3_*.
+// It also returns the patterns that match no rule, which usually indicate
+// a typo.
+func SelectRules(rules []Rule, include, exclude []string) ([]Rule, []string) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return rules, nil
+	}
+
+	matched := make(map[string]bool)
+	matches := func(patterns []string, id string) bool {
+		found := false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, id); ok {
+				matched[pattern] = true
+				found = true
+			}
+		}
+		return found
+	}
+
+	var selected []Rule
+	for _, rule := range rules {
+		included := len(include) == 0 || matches(include, rule.ID)
+		if matches(exclude, rule.ID) || !included {
+			continue
+		}
+		selected = append(selected, rule)
+	}
+
+	var unmatched []string
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if !matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return selected, unmatched
+}
+
 // LoadDefaultRules loads built-in default rules
 func LoadDefaultRules(rulesDir string) ([]Rule, error) {
 	return LoadDefaultRulesWithCategories(rulesDir, nil)
 }
 
-// LoadDefaultRulesWithCategories loads built-in default rules filtered by categories
-// Supported categories:
-//   - "aws": AWS provider rules (rules/aws/*.hcl)
-//   - "azure": Azure provider rules (rules/azure/*.hcl)
-//   - "common": All common rules (rules/common/*.hcl)
+// LoadDefaultRulesWithCategories loads built-in default rules filtered by
+// categories. Every subdirectory of the rules directory is a category, such
+// as "aws" (rules/aws/*.hcl) or "common" (rules/common/*.hcl), and some
+// presupplied files are categories of their own:
 //   - "security": Security-specific rules (rules/common/security.hcl)
 //   - "tagging": Tagging rules (rules/common/tagging.hcl)
+//   - "unreferenced": Unreferenced variables, locals, and outputs (rules/hygiene/unreferenced.hcl)
+//   - "orphans": Resources nothing references (rules/hygiene/orphans.hcl)
 //
-// If categories is nil or empty, all rules are loaded
+// If categories is nil or empty, every category but the opt-in hygiene,
+// unreferenced, and orphans is loaded. Unknown categories match no rules;
+// RuleCategories lists the known ones.
 func LoadDefaultRulesWithCategories(rulesDir string, categories []string) ([]Rule, error) {
 	if rulesDir == "" {
 		// Use embedded rules or skip
 		return []Rule{}, nil
 	}
 
+	relative, err := defaultRulePatterns(os.DirFS(rulesDir), categories)
+	if err != nil {
+		return nil, err
+	}
 	var patterns []string
+	for _, pattern := range relative {
+		patterns = append(patterns, filepath.Join(rulesDir, filepath.FromSlash(pattern)))
+	}
 
-	// If no categories specified, load all rules (backward compatible)
-	if len(categories) == 0 {
-		// Load rules from root directory
-		rootPattern := filepath.Join(rulesDir, "*.hcl")
-		patterns = append(patterns, rootPattern)
+	return LoadRules(patterns)
+}
 
-		// Load rules from provider subdirectories
-		providers := []string{"aws", "azure", "common"}
-		for _, provider := range providers {
-			pattern := filepath.Join(rulesDir, provider, "*.hcl")
-			patterns = append(patterns, pattern)
-		}
-	} else {
-		// Load specific categories
-		categoryMap := make(map[string]bool)
-		for _, cat := range categories {
-			categoryMap[cat] = true
-		}
+// LoadDefaultRulesFS loads built-in default rules filtered by categories
+// from a file system laid out like a rules directory, such as the rules
+// embedded in the binary. Categories are the same as for
+// LoadDefaultRulesWithCategories.
+func LoadDefaultRulesFS(fsys fs.FS, categories []string) ([]Rule, error) {
+	patterns, err := defaultRulePatterns(fsys, categories)
+	if err != nil {
+		return nil, err
+	}
 
-		// Load rules from root directory if any category is specified
-		if len(categoryMap) > 0 {
-			rootPattern := filepath.Join(rulesDir, "*.hcl")
-			patterns = append(patterns, rootPattern)
-		}
+	var allRules []Rule
+	packs := make(map[string]*PackManifest)
+	seen := make(map[string]bool)
 
-		// Map categories to file patterns
-		if categoryMap["aws"] {
-			pattern := filepath.Join(rulesDir, "aws", "*.hcl")
-			patterns = append(patterns, pattern)
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
 		}
 
-		if categoryMap["azure"] {
-			pattern := filepath.Join(rulesDir, "azure", "*.hcl")
-			patterns = append(patterns, pattern)
-		}
+		for _, match := range matches {
+			// A file is in several categories, e.g. common and security
+			if seen[match] || path.Base(match) == PackManifestFile || IsRuleTestFile(match) {
+				continue
+			}
+			seen[match] = true
 
-		if categoryMap["common"] {
-			// Load all common rules
-			pattern := filepath.Join(rulesDir, "common", "*.hcl")
-			patterns = append(patterns, pattern)
-		} else {
-			// Load specific common rule files
-			if categoryMap["security"] {
-				pattern := filepath.Join(rulesDir, "common", "security.hcl")
-				patterns = append(patterns, pattern)
+			src, err := fs.ReadFile(fsys, match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load rules from %s: %w", match, err)
 			}
-			if categoryMap["tagging"] {
-				pattern := filepath.Join(rulesDir, "common", "tagging.hcl")
-				patterns = append(patterns, pattern)
+
+			var fileConfig struct {
+				Rules []Rule `hcl:"rule,block"`
 			}
+			unknown, err := decodeFile(match, src, &fileConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load rules from %s: %w", match, err)
+			}
+
+			dir := path.Dir(match)
+			pack, loaded := packs[dir]
+			if !loaded {
+				pack, err = loadPackManifestFS(fsys, dir)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load rules from %s: %w", match, err)
+				}
+				packs[dir] = pack
+			}
+			for i := range fileConfig.Rules {
+				fileConfig.Rules[i].Pack = pack
+				fileConfig.Rules[i].Source = match
+				fileConfig.Rules[i].Unknown = unknown
+			}
+
+			allRules = append(allRules, fileConfig.Rules...)
 		}
 	}
 
-	return LoadRules(patterns)
+	return allRules, nil
+}
+
+// RuleDrift describes how one set of presupplied rules differs from another
+type RuleDrift struct {
+	Missing  []string // Rule IDs only in the reference set
+	Extra    []string // Rule IDs only in the compared set
+	Modified []string // Rule IDs in both whose definitions differ
+}
+
+// Empty reports whether the rule sets are identical
+func (d RuleDrift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Modified) == 0
+}
+
+// CompareRules reports how rules differ from reference, by rule ID. Rule
+// IDs are listed in sorted order.
+func CompareRules(reference, rules []Rule) RuleDrift {
+	byID := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		rule.Pack, rule.Source, rule.Unknown = nil, "", nil
+		byID[rule.ID] = rule
+	}
+
+	var drift RuleDrift
+	seen := make(map[string]bool, len(reference))
+	for _, want := range reference {
+		want.Pack, want.Source, want.Unknown = nil, "", nil
+		seen[want.ID] = true
+
+		got, ok := byID[want.ID]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, want.ID)
+		case !reflect.DeepEqual(got, want):
+			drift.Modified = append(drift.Modified, want.ID)
+		}
+	}
+	for _, rule := range rules {
+		if !seen[rule.ID] {
+			drift.Extra = append(drift.Extra, rule.ID)
+		}
+	}
+
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Extra)
+	sort.Strings(drift.Modified)
+	return drift
 }
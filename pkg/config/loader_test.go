@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -126,6 +130,198 @@ this is not valid HCL {{{
 	}
 }
 
+func TestLoadConfigInvalidExpiresAt(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.hcl")
+	content := `
+exception {
+  rules       = ["aws_s3_versioning"]
+  reason      = "Legacy bucket"
+  approved_by = "security-team"
+  expires_at  = "2020-1-5"
+}
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+
+	_, err := LoadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), `exception 1 has an invalid expires_at "2020-1-5" (expected YYYY-MM-DD)`) {
+		t.Errorf("LoadConfig() error = %v, want an invalid expires_at error", err)
+	}
+}
+
+func TestLoadConfigSeverityMapPath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name        string
+		severityMap string
+		want        string
+	}{
+		{"relative to the config", "severity.yaml", filepath.Join(dir, "severity.yaml")},
+		{"relative subdirectory", "maps/severity.yaml", filepath.Join(dir, "maps", "severity.yaml")},
+		{"absolute", "/etc/planguard/severity.yaml", "/etc/planguard/severity.yaml"},
+		{"home", "~/severity.yaml", "~/severity.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, "config.hcl")
+			content := fmt.Sprintf("settings {\n  severity_map = %q\n}\n", tt.severityMap)
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create test config: %v", err)
+			}
+
+			cfg, err := LoadConfig(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := *cfg.Settings.SeverityMap; got != tt.want {
+				t.Errorf("SeverityMap = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.hcl": `
+settings {
+  fail_on_warning = true
+  exclude_paths   = ["vendor/**"]
+  max_active_exceptions = 5
+}
+
+rule "require_versioning" {
+  name          = "Require versioning"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "self.versioning != null"
+  }
+  message = "Enable versioning"
+}
+
+exception {
+  rules       = ["require_versioning"]
+  paths       = ["legacy/*.tf"]
+  reason      = "Legacy"
+  approved_by = "platform"
+}
+`,
+		"config.json": `{
+  "settings": {
+    "fail_on_warning": true,
+    "exclude_paths": ["vendor/**"],
+    "max_active_exceptions": 5
+  },
+  "rule": {
+    "require_versioning": {
+      "name": "Require versioning",
+      "severity": "error",
+      "resource_type": "aws_s3_bucket",
+      "condition": {"expression": "self.versioning != null"},
+      "message": "Enable versioning"
+    }
+  },
+  "exception": [
+    {"rules": ["require_versioning"], "paths": ["legacy/*.tf"], "reason": "Legacy", "approved_by": "platform"}
+  ]
+}`,
+		"config.yaml": `
+settings:
+  fail_on_warning: true
+  exclude_paths: ["vendor/**"]
+  max_active_exceptions: 5
+rule:
+  require_versioning:
+    name: Require versioning
+    severity: error
+    resource_type: aws_s3_bucket
+    condition:
+      expression: self.versioning != null
+    message: Enable versioning
+exception:
+  - rules: [require_versioning]
+    paths: ["legacy/*.tf"]
+    reason: Legacy
+    approved_by: platform
+`,
+	}
+
+	tmpDir := t.TempDir()
+	load := func(name string) *Config {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("Failed to create test config: %v", err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s) error = %v", name, err)
+		}
+		for i := range cfg.Rules {
+			cfg.Rules[i].Source = ""
+		}
+		for i := range cfg.Exceptions {
+			cfg.Exceptions[i].Source = ""
+		}
+		return cfg
+	}
+
+	want := load("config.hcl")
+	for _, name := range []string{"config.json", "config.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			if got := load(name); !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadConfig(%s) = %+v, want %+v as from HCL", name, got, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     string
+		wantUnknown string
+	}{
+		{name: "empty file gets defaults", content: ""},
+		{name: "yml extension", content: "settings:\n  fail_on_warning: true\n"},
+		{name: "missing required attribute", content: "exception:\n  - rules: [a]\n    approved_by: platform\n", wantErr: `The argument "reason" is required`},
+		{name: "unknown block", content: "setting:\n  fail_on_warning: true\n", wantUnknown: `No argument or block type is named "setting"`},
+		{name: "invalid YAML", content: "settings: [\n", wantErr: "config.yml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test config: %v", err)
+			}
+			cfg, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Settings == nil || cfg.Settings.UsePresuppliedRules == nil || !*cfg.Settings.UsePresuppliedRules {
+				t.Errorf("LoadConfig() settings = %+v, want defaults", cfg.Settings)
+			}
+			if tt.wantUnknown == "" {
+				if len(cfg.Unknown) > 0 {
+					t.Errorf("Unknown = %v, want none", cfg.Unknown)
+				}
+			} else if len(cfg.Unknown) != 1 || !strings.Contains(cfg.Unknown[0].Detail, tt.wantUnknown) || cfg.Unknown[0].Subject.Filename != path {
+				t.Errorf("Unknown = %v, want one in %s containing %q", cfg.Unknown, path, tt.wantUnknown)
+			}
+		})
+	}
+}
+
 func TestLoadRules(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -179,6 +375,10 @@ rule "second_rule" {
 	if rules[1].ID != "second_rule" {
 		t.Errorf("Second rule ID = %s, want second_rule", rules[1].ID)
 	}
+
+	if rules[0].Source != ruleFile {
+		t.Errorf("First rule Source = %s, want %s", rules[0].Source, ruleFile)
+	}
 }
 
 func TestLoadRulesFromDirectory(t *testing.T) {
@@ -269,6 +469,44 @@ rule "glob_test" {
 	}
 }
 
+func TestLoadRulesSkipsTestFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	ruleContent := `
+rule "s3_tags" {
+  name     = "S3 Tags"
+  severity = "error"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "true"
+  }
+  message = "Test"
+}
+`
+	testContent := `
+test "flagged" {
+  terraform = "resource \"aws_s3_bucket\" \"a\" {}"
+  expect {
+    rule = "s3_tags"
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "s3.hcl"), []byte(ruleContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "s3_test.hcl"), []byte(testContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules([]string{filepath.Join(tmpDir, "*.hcl")})
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if len(rules) != 1 {
+		t.Errorf("Expected 1 rule, got %d", len(rules))
+	}
+}
+
 func TestLoadRulesNonexistent(t *testing.T) {
 	rules, err := LoadRules([]string{"/nonexistent/path/*.hcl"})
 	if err != nil {
@@ -727,6 +965,28 @@ rule "tagging_rule" {
 		t.Fatal(err)
 	}
 
+	// Create hygiene rules
+	hygieneDir := filepath.Join(tmpDir, "hygiene")
+	if err := os.MkdirAll(hygieneDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for id, file := range map[string]string{"hygiene_rule": "unreferenced.hcl", "orphans_rule": "orphans.hcl"} {
+		content := `
+rule "` + id + `" {
+  name     = "Hygiene Rule"
+  severity = "info"
+  resource_type = "*"
+  condition {
+    expression = "true"
+  }
+  message = "Hygiene"
+}
+`
+		if err := os.WriteFile(filepath.Join(hygieneDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	tests := []struct {
 		name       string
 		categories []string
@@ -777,6 +1037,21 @@ rule "tagging_rule" {
 			categories: []string{"aws", "azure", "tagging"},
 			wantRules:  []string{"root_rule", "aws_rule", "azure_rule", "tagging_rule"},
 		},
+		{
+			name:       "Unreferenced only",
+			categories: []string{"unreferenced"},
+			wantRules:  []string{"root_rule", "hygiene_rule"},
+		},
+		{
+			name:       "Unreferenced and orphans",
+			categories: []string{"unreferenced", "orphans"},
+			wantRules:  []string{"root_rule", "hygiene_rule", "orphans_rule"},
+		},
+		{
+			name:       "Hygiene (all hygiene rules)",
+			categories: []string{"hygiene"},
+			wantRules:  []string{"root_rule", "orphans_rule", "hygiene_rule"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -952,3 +1227,316 @@ func TestLoadConfigPresuppliedRulesDefaults(t *testing.T) {
 			len(cfg.Settings.PresuppliedRulesCategories))
 	}
 }
+
+func TestLoadConfigInlineSkipSettings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.hcl")
+	content := `
+settings {
+  honor_inline_skips = ["tfsec", "checkov"]
+  inline_skip_mappings = {
+    CKV_CUSTOM_1 = ["custom_rule", "other_rule"]
+  }
+}
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.Settings.HonorInlineSkips, []string{"tfsec", "checkov"}) {
+		t.Errorf("HonorInlineSkips = %v", cfg.Settings.HonorInlineSkips)
+	}
+	want := map[string][]string{"CKV_CUSTOM_1": {"custom_rule", "other_rule"}}
+	if !reflect.DeepEqual(cfg.Settings.InlineSkipMappings, want) {
+		t.Errorf("InlineSkipMappings = %v, want %v", cfg.Settings.InlineSkipMappings, want)
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	off := false
+	rules := []Rule{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "strict", Enabled: &off}}
+
+	tests := []struct {
+		name        string
+		settings    *Settings
+		wantIDs     []string
+		wantUnknown []string
+	}{
+		{
+			name:     "nil settings",
+			settings: nil,
+			wantIDs:  []string{"a", "b", "c"},
+		},
+		{
+			name:     "disabled",
+			settings: &Settings{DisabledRules: []string{"b"}},
+			wantIDs:  []string{"a", "c"},
+		},
+		{
+			name:     "enabled allow-list",
+			settings: &Settings{EnabledRules: []string{"a", "c"}},
+			wantIDs:  []string{"a", "c"},
+		},
+		{
+			name:     "disabled wins over enabled",
+			settings: &Settings{EnabledRules: []string{"a", "c"}, DisabledRules: []string{"c"}},
+			wantIDs:  []string{"a"},
+		},
+		{
+			name:        "unknown IDs reported",
+			settings:    &Settings{EnabledRules: []string{"a", "typo"}, DisabledRules: []string{"missing"}},
+			wantIDs:     []string{"a"},
+			wantUnknown: []string{"typo", "missing"},
+		},
+		{
+			name:     "opt-in rule enabled",
+			settings: &Settings{EnableRules: []string{"strict"}},
+			wantIDs:  []string{"a", "b", "c", "strict"},
+		},
+		{
+			name:     "opt-in rule in enabled allow-list",
+			settings: &Settings{EnabledRules: []string{"a", "strict"}},
+			wantIDs:  []string{"a", "strict"},
+		},
+		{
+			name:     "disabled wins over enable_rules",
+			settings: &Settings{EnableRules: []string{"strict"}, DisabledRules: []string{"strict"}},
+			wantIDs:  []string{"a", "b", "c"},
+		},
+		{
+			name:        "unknown enable_rules reported",
+			settings:    &Settings{EnableRules: []string{"experimental"}},
+			wantIDs:     []string{"a", "b", "c"},
+			wantUnknown: []string{"experimental"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, unknown := FilterRules(rules, tt.settings)
+			var ids []string
+			for _, rule := range filtered {
+				ids = append(ids, rule.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("FilterRules() rules = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("FilterRules() unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestSelectRules(t *testing.T) {
+	rules := []Rule{{ID: "aws_s3_acl"}, {ID: "aws_s3_versioning"}, {ID: "aws_ec2_tags"}, {ID: "azure_tags"}}
+
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		wantIDs       []string
+		wantUnmatched []string
+	}{
+		{
+			name:    "no patterns",
+			wantIDs: []string{"aws_s3_acl", "aws_s3_versioning", "aws_ec2_tags", "azure_tags"},
+		},
+		{
+			name:    "include ID",
+			include: []string{"aws_s3_acl"},
+			wantIDs: []string{"aws_s3_acl"},
+		},
+		{
+			name:    "include glob",
+			include: []string{"aws_s3_*", "azure_*"},
+			wantIDs: []string{"aws_s3_acl", "aws_s3_versioning", "azure_tags"},
+		},
+		{
+			name:    "exclude glob",
+			exclude: []string{"*_tags"},
+			wantIDs: []string{"aws_s3_acl", "aws_s3_versioning"},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"aws_*"},
+			exclude: []string{"aws_s3_versioning"},
+			wantIDs: []string{"aws_s3_acl", "aws_ec2_tags"},
+		},
+		{
+			name:          "unmatched patterns reported",
+			include:       []string{"aws_s3_acl", "gcp_*"},
+			exclude:       []string{"typo"},
+			wantIDs:       []string{"aws_s3_acl"},
+			wantUnmatched: []string{"gcp_*", "typo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, unmatched := SelectRules(rules, tt.include, tt.exclude)
+			var ids []string
+			for _, rule := range selected {
+				ids = append(ids, rule.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("SelectRules() rules = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(unmatched, tt.wantUnmatched) {
+				t.Errorf("SelectRules() unmatched = %v, want %v", unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
+
+func TestLoadTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetsPath := filepath.Join(tmpDir, "targets.hcl")
+
+	content := `
+target "payments-prod" {
+  directory = "stacks/payments/prod"
+  labels = {
+    env  = "prod"
+    team = "payments"
+  }
+}
+
+target "shared" {
+  directory = "/srv/shared"
+  plan      = "plans/shared.json"
+}
+`
+	if err := os.WriteFile(targetsPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := LoadTargets(targetsPath)
+	if err != nil {
+		t.Fatalf("LoadTargets() error = %v", err)
+	}
+
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].Directory != filepath.Join(tmpDir, "stacks/payments/prod") {
+		t.Errorf("Relative directory should be resolved against the targets file, got %s", targets[0].Directory)
+	}
+	if targets[0].Labels["team"] != "payments" {
+		t.Errorf("Expected team label, got %v", targets[0].Labels)
+	}
+	if targets[1].Directory != "/srv/shared" {
+		t.Errorf("Absolute directory should be kept, got %s", targets[1].Directory)
+	}
+	if targets[0].Plan != "" || targets[1].Plan != filepath.Join(tmpDir, "plans/shared.json") {
+		t.Errorf("Relative plan should be resolved against the targets file, got %q and %q", targets[0].Plan, targets[1].Plan)
+	}
+}
+
+func TestPlanTargets(t *testing.T) {
+	plans := []string{filepath.Join("stacks", "network", "plan.json"), filepath.Join("stacks", "app", "plan.json")}
+
+	targets := PlanTargets(plans)
+	want := []Target{
+		{Name: plans[0], Directory: filepath.Join("stacks", "network"), Plan: plans[0], Labels: map[string]string{"plan": "stacks/network/plan.json"}},
+		{Name: plans[1], Directory: filepath.Join("stacks", "app"), Plan: plans[1], Labels: map[string]string{"plan": "stacks/app/plan.json"}},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("PlanTargets() = %+v, want %+v", targets, want)
+	}
+}
+
+func TestLoadTargetsEmpty(t *testing.T) {
+	targetsPath := filepath.Join(t.TempDir(), "targets.hcl")
+	if err := os.WriteFile(targetsPath, []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadTargets(targetsPath); err == nil {
+		t.Error("Expected error for targets file without targets")
+	}
+}
+
+func TestLoadDefaultRulesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"aws/s3.hcl": &fstest.MapFile{Data: []byte(`
+rule "aws_s3_versioning" {
+  name          = "S3 versioning"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "true"
+  }
+  message = "Enable versioning"
+}
+`)},
+		"aws/pack.hcl": &fstest.MapFile{Data: []byte(`
+pack "aws" {
+  provider "aws" {
+    major_versions = [5]
+  }
+}
+`)},
+		"common/tagging.hcl": &fstest.MapFile{Data: []byte(`
+rule "required_tags" {
+  name          = "Required tags"
+  severity      = "warning"
+  resource_type = "*"
+  condition {
+    expression = "true"
+  }
+  message = "Add tags"
+}
+`)},
+	}
+
+	rules, err := LoadDefaultRulesFS(fsys, []string{"aws"})
+	if err != nil {
+		t.Fatalf("LoadDefaultRulesFS() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].ID != "aws_s3_versioning" {
+		t.Fatalf("LoadDefaultRulesFS() = %+v, want only aws_s3_versioning", rules)
+	}
+	if rules[0].Pack == nil || rules[0].Pack.Name != "aws" {
+		t.Errorf("Expected rule to carry the aws pack manifest, got %+v", rules[0].Pack)
+	}
+
+	all, err := LoadDefaultRulesFS(fsys, nil)
+	if err != nil {
+		t.Fatalf("LoadDefaultRulesFS() error = %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("LoadDefaultRulesFS() without categories loaded %d rules, want 2", len(all))
+	}
+}
+
+func TestCompareRules(t *testing.T) {
+	reference := []Rule{
+		{ID: "same", Severity: "error"},
+		{ID: "changed", Severity: "error"},
+		{ID: "removed", Severity: "error"},
+	}
+	rules := []Rule{
+		{ID: "same", Severity: "error", Pack: &PackManifest{Name: "aws"}},
+		{ID: "changed", Severity: "info"},
+		{ID: "added", Severity: "error"},
+	}
+
+	drift := CompareRules(reference, rules)
+	want := RuleDrift{
+		Missing:  []string{"removed"},
+		Extra:    []string{"added"},
+		Modified: []string{"changed"},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("CompareRules() = %+v, want %+v", drift, want)
+	}
+
+	if !CompareRules(reference, reference).Empty() {
+		t.Error("CompareRules() of identical rule sets should be empty")
+	}
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// PackManifestFile is the name of the manifest describing the rule pack in
+// a rules directory. It is not loaded as a rule file.
+const PackManifestFile = "pack.hcl"
+
+// PacksDir is the directory, inside a rules directory or .planguard, that
+// holds the named packs selected with -pack
+const PacksDir = "packs"
+
+// PackManifest describes a rule pack: the rule files in one directory,
+// optionally bundled with rules included by ID and default severities
+type PackManifest struct {
+	Name        string            `hcl:"name,label"`
+	Version     string            `hcl:"version,optional"`
+	Description string            `hcl:"description,optional"`
+	Rules       []string          `hcl:"rules,optional"`      // IDs of rules to include from outside the pack, e.g. presupplied rules
+	Severities  map[string]string `hcl:"severities,optional"` // Default severities by rule ID
+	Providers   []PackProvider    `hcl:"provider,block"`
+}
+
+// PackProvider declares the provider major versions a pack's rules were
+// written and tested against
+type PackProvider struct {
+	Name          string `hcl:"name,label"`
+	MajorVersions []int  `hcl:"major_versions"`
+}
+
+// ProviderRequirement is a provider version constraint declared in a
+// terraform required_providers block
+type ProviderRequirement struct {
+	Name    string // Local name, e.g. "aws"
+	Source  string // e.g. "hashicorp/aws"; empty if not declared
+	Version string // Constraint, e.g. "~> 5.0"
+	File    string
+	Line    int
+}
+
+// LoadPackManifest loads the pack manifest in dir. It returns nil if the
+// directory has none.
+func LoadPackManifest(dir string) (*PackManifest, error) {
+	path := filepath.Join(dir, PackManifestFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var file struct {
+		Pack PackManifest `hcl:"pack,block"`
+	}
+	if err := hclsimple.DecodeFile(path, nil, &file); err != nil {
+		return nil, fmt.Errorf("failed to load pack manifest: %w", err)
+	}
+	return &file.Pack, nil
+}
+
+// FindPack returns the directory of the pack called name. name may be the
+// path of a directory holding a pack manifest; otherwise the first of
+// searchDirs with a name/pack.hcl manifest is used.
+func FindPack(name string, searchDirs []string) (string, error) {
+	if _, err := os.Stat(filepath.Join(name, PackManifestFile)); err == nil {
+		return name, nil
+	}
+	for _, dir := range searchDirs {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(filepath.Join(candidate, PackManifestFile)); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("pack %q not found in %s", name, strings.Join(searchDirs, ", "))
+}
+
+// LoadPack loads the pack in dir: the rules in its own files, followed by
+// the rules its manifest includes by ID from available (typically the
+// presupplied rules), with the manifest's severities applied. Listed rules
+// that the pack defines itself are not duplicated.
+func LoadPack(dir string, available []Rule) (*PackManifest, []Rule, error) {
+	manifest, err := LoadPackManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("no %s in %s", PackManifestFile, dir)
+	}
+
+	rules, err := LoadRules([]string{filepath.Join(dir, "*.hcl")})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defined := make(map[string]bool, len(rules))
+	for i := range rules {
+		rules[i].Pack = manifest
+		defined[rules[i].ID] = true
+	}
+	byID := make(map[string]Rule, len(available))
+	for _, rule := range available {
+		byID[rule.ID] = rule
+	}
+	for _, id := range manifest.Rules {
+		if defined[id] {
+			continue
+		}
+		rule, ok := byID[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("pack %s includes unknown rule %q", manifest.Name, id)
+		}
+		rules = append(rules, rule)
+		defined[id] = true
+	}
+
+	for id, severity := range manifest.Severities {
+		if _, ok := severityRank[severity]; !ok {
+			return nil, nil, fmt.Errorf("pack %s: severity of %s is %q (expected error, warning, or info)", manifest.Name, id, severity)
+		}
+		if !defined[id] {
+			return nil, nil, fmt.Errorf("pack %s sets the severity of %q, which it does not include", manifest.Name, id)
+		}
+	}
+	for i := range rules {
+		if severity, ok := manifest.Severities[rules[i].ID]; ok {
+			rules[i].Severity = severity
+		}
+	}
+
+	return manifest, rules, nil
+}
+
+// loadPackManifestFS is LoadPackManifest for a directory in fsys
+func loadPackManifestFS(fsys fs.FS, dir string) (*PackManifest, error) {
+	name := path.Join(dir, PackManifestFile)
+	src, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pack manifest: %w", err)
+	}
+
+	var file struct {
+		Pack PackManifest `hcl:"pack,block"`
+	}
+	if err := hclsimple.Decode(name, src, nil, &file); err != nil {
+		return nil, fmt.Errorf("failed to load pack manifest: %w", err)
+	}
+	return &file.Pack, nil
+}
+
+// CheckProviders returns a warning for each requirement that allows a
+// provider major version the pack does not target. Requirements for
+// providers the pack does not declare, and constraints that cannot be
+// parsed, are skipped.
+func (p *PackManifest) CheckProviders(requirements []ProviderRequirement) []string {
+	var warnings []string
+
+	for _, req := range requirements {
+		provider := p.provider(req)
+		if provider == nil || req.Version == "" {
+			continue
+		}
+
+		allowed, openFrom, err := allowedMajorVersions(req.Version, provider.MajorVersions)
+		if err != nil {
+			continue
+		}
+
+		tested := make(map[int]bool)
+		for _, major := range provider.MajorVersions {
+			tested[major] = true
+		}
+
+		var untested []string
+		for _, major := range allowed {
+			if !tested[major] {
+				untested = append(untested, strconv.Itoa(major))
+			}
+		}
+		if openFrom > 0 {
+			untested = append(untested, fmt.Sprintf("%d and later", openFrom))
+		}
+		if len(untested) == 0 {
+			continue
+		}
+
+		warnings = append(warnings, fmt.Sprintf("%s provider constraint %q (%s:%d) allows major version %s, but the %s rule pack targets %s",
+			provider.Name, req.Version, req.File, req.Line, strings.Join(untested, ", "), p.Name, joinInts(provider.MajorVersions)))
+	}
+
+	return warnings
+}
+
+// provider returns the pack's declaration for the provider a requirement
+// refers to, matching on the source's type name when one is declared
+func (p *PackManifest) provider(req ProviderRequirement) *PackProvider {
+	name := req.Name
+	if req.Source != "" {
+		name = req.Source[strings.LastIndex(req.Source, "/")+1:]
+	}
+	for i := range p.Providers {
+		if p.Providers[i].Name == name {
+			return &p.Providers[i]
+		}
+	}
+	return nil
+}
+
+// version is a parsed version number; missing segments are zero
+type version [3]int
+
+func (v version) less(o version) bool {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] < o[i]
+		}
+	}
+	return false
+}
+
+// bound is one end of a version range
+type bound struct {
+	v         version
+	inclusive bool
+	set       bool
+}
+
+// allowedMajorVersions returns the major versions a constraint such as
+// "~> 5.0" or ">= 4.0, < 6.0" admits. Constraints without an upper bound
+// list majors up to the highest tested one and report the first major past
+// it as openFrom, meaning that major and every later one are allowed.
+func allowedMajorVersions(constraint string, tested []int) (majors []int, openFrom int, err error) {
+	var lower, upper bound
+
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		op := "="
+		for _, candidate := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(clause, candidate) {
+				op = candidate
+				clause = strings.TrimSpace(strings.TrimPrefix(clause, candidate))
+				break
+			}
+		}
+
+		v, segments, err := parseVersion(clause)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch op {
+		case "=":
+			lower = tighterLower(lower, bound{v: v, inclusive: true, set: true})
+			upper = tighterUpper(upper, bound{v: v, inclusive: true, set: true})
+		case ">=", ">":
+			lower = tighterLower(lower, bound{v: v, inclusive: op == ">=", set: true})
+		case "<=", "<":
+			upper = tighterUpper(upper, bound{v: v, inclusive: op == "<=", set: true})
+		case "~>":
+			// ~> 5 and ~> 5.1 allow 5.x; ~> 5.1.2 allows 5.1.x
+			next := version{v[0] + 1}
+			if segments == 3 {
+				next = version{v[0], v[1] + 1}
+			}
+			lower = tighterLower(lower, bound{v: v, inclusive: true, set: true})
+			upper = tighterUpper(upper, bound{v: next, set: true})
+		case "!=":
+			// Excluding a single version does not change the majors
+		}
+	}
+
+	first := lower.v[0]
+	last := first
+	for _, major := range tested {
+		if major > last {
+			last = major
+		}
+	}
+	if upper.set {
+		last = upper.v[0]
+		if !upper.inclusive && upper.v == (version{upper.v[0]}) {
+			last-- // < 6.0 stops before major 6
+		}
+	} else {
+		openFrom = last + 1
+	}
+
+	for major := first; major <= last; major++ {
+		majors = append(majors, major)
+	}
+	return majors, openFrom, nil
+}
+
+func tighterLower(current, candidate bound) bound {
+	if !current.set || current.v.less(candidate.v) || (current.v == candidate.v && !candidate.inclusive) {
+		return candidate
+	}
+	return current
+}
+
+func tighterUpper(current, candidate bound) bound {
+	if !current.set || candidate.v.less(current.v) || (current.v == candidate.v && !candidate.inclusive) {
+		return candidate
+	}
+	return current
+}
+
+// parseVersion parses a version such as "5", "5.1", or "5.1.2-beta" and
+// reports how many numeric segments it had
+func parseVersion(s string) (version, int, error) {
+	var v version
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, 0, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, 0, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, len(parts), nil
+}
+
+func joinInts(values []int) string {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	strs := make([]string, len(sorted))
+	for i, v := range sorted {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAllowedMajorVersions(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       []int
+		openFrom   int
+	}{
+		{constraint: "~> 5.0", want: []int{5}},
+		{constraint: "~> 5", want: []int{5}},
+		{constraint: "~> 4.67.1", want: []int{4}},
+		{constraint: "5.31.0", want: []int{5}},
+		{constraint: ">= 4.0, < 6.0", want: []int{4, 5}},
+		{constraint: ">= 4.0, <= 6.0", want: []int{4, 5, 6}},
+		{constraint: ">= 5.0", want: []int{5}, openFrom: 6},
+		{constraint: ">= 7.0", want: []int{7}, openFrom: 8},
+		{constraint: "< 4.0", want: []int{0, 1, 2, 3}},
+		{constraint: ">= 3.0, != 5.1.0, < 5.0", want: []int{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, openFrom, err := allowedMajorVersions(tt.constraint, []int{4, 5})
+			if err != nil {
+				t.Fatalf("allowedMajorVersions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || openFrom != tt.openFrom {
+				t.Errorf("allowedMajorVersions() = %v, %d; want %v, %d", got, openFrom, tt.want, tt.openFrom)
+			}
+		})
+	}
+
+	if _, _, err := allowedMajorVersions("~> five", nil); err == nil {
+		t.Error("Expected error for invalid version")
+	}
+}
+
+func TestPackCheckProviders(t *testing.T) {
+	pack := &PackManifest{
+		Name:      "aws",
+		Providers: []PackProvider{{Name: "aws", MajorVersions: []int{4, 5}}},
+	}
+
+	tests := []struct {
+		name string
+		req  ProviderRequirement
+		want string // Substring of the warning; empty for none
+	}{
+		{
+			name: "within tested range",
+			req:  ProviderRequirement{Name: "aws", Version: "~> 5.0"},
+		},
+		{
+			name: "newer major",
+			req:  ProviderRequirement{Name: "aws", Version: "~> 6.0", File: "versions.tf", Line: 4},
+			want: `aws provider constraint "~> 6.0" (versions.tf:4) allows major version 6, but the aws rule pack targets 4, 5`,
+		},
+		{
+			name: "open ended",
+			req:  ProviderRequirement{Name: "aws", Version: ">= 4.0"},
+			want: "allows major version 6 and later",
+		},
+		{
+			name: "matched by source",
+			req:  ProviderRequirement{Name: "aws_east", Source: "hashicorp/aws", Version: "~> 3.0"},
+			want: "allows major version 3",
+		},
+		{
+			name: "other provider",
+			req:  ProviderRequirement{Name: "google", Version: "~> 9.0"},
+		},
+		{
+			name: "no version",
+			req:  ProviderRequirement{Name: "aws", Source: "hashicorp/aws"},
+		},
+		{
+			name: "unparseable constraint",
+			req:  ProviderRequirement{Name: "aws", Version: "latest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := pack.CheckProviders([]ProviderRequirement{tt.req})
+			if tt.want == "" {
+				if len(warnings) != 0 {
+					t.Errorf("Expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.want) {
+				t.Errorf("Expected a warning containing %q, got %v", tt.want, warnings)
+			}
+		})
+	}
+}
+
+func TestLoadRulesWithPackManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `
+pack "aws" {
+  provider "aws" {
+    major_versions = [5]
+  }
+}
+`
+	rule := `
+rule "r" {
+  name          = "Rule"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "true"
+  }
+  message = "m"
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, PackManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "s3.hcl"), []byte(rule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules([]string{filepath.Join(dir, "*.hcl")})
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("Expected the manifest to be skipped and 1 rule loaded, got %d", len(rules))
+	}
+	if rules[0].Pack == nil || rules[0].Pack.Name != "aws" || !reflect.DeepEqual(rules[0].Pack.Providers[0].MajorVersions, []int{5}) {
+		t.Errorf("Rule pack = %+v, want the aws manifest", rules[0].Pack)
+	}
+
+	// Rules without a manifest have no pack
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "s3.hcl"), []byte(rule), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err = LoadRules([]string{filepath.Join(other, "*.hcl")})
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Pack != nil {
+		t.Errorf("Expected one rule without a pack, got %+v", rules)
+	}
+}
+
+func TestFindPack(t *testing.T) {
+	root := t.TempDir()
+	local := filepath.Join(root, ".planguard", PacksDir)
+	shared := filepath.Join(root, "rules", PacksDir)
+	for _, dir := range []string{filepath.Join(local, "soc2"), filepath.Join(shared, "soc2"), filepath.Join(shared, "pci"), filepath.Join(root, "custom")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, PackManifestFile), []byte(`pack "p" {}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	searchDirs := []string{local, shared}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "soc2", want: filepath.Join(local, "soc2")},
+		{name: "pci", want: filepath.Join(shared, "pci")},
+		{name: filepath.Join(root, "custom"), want: filepath.Join(root, "custom")},
+		{name: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindPack(tt.name, searchDirs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindPack() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FindPack() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPack(t *testing.T) {
+	rule := `
+rule "team_bucket_owner" {
+  name          = "Bucket owner"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "true"
+  }
+  message = "m"
+}
+`
+	available := []Rule{
+		{ID: "aws_s3_public_read", Severity: "error"},
+		{ID: "aws_ec2_imdsv2", Severity: "warning"},
+		{ID: "team_bucket_owner", Severity: "info"},
+	}
+
+	tests := []struct {
+		name         string
+		manifest     string
+		wantIDs      []string
+		wantSeverity map[string]string
+		wantErr      string
+	}{
+		{
+			name: "own and included rules",
+			manifest: `
+pack "soc2-baseline" {
+  version    = "1.2.0"
+  rules      = ["aws_s3_public_read", "aws_ec2_imdsv2", "team_bucket_owner"]
+  severities = { aws_ec2_imdsv2 = "error", team_bucket_owner = "error" }
+}`,
+			wantIDs:      []string{"team_bucket_owner", "aws_s3_public_read", "aws_ec2_imdsv2"},
+			wantSeverity: map[string]string{"team_bucket_owner": "error", "aws_s3_public_read": "error", "aws_ec2_imdsv2": "error"},
+		},
+		{
+			name:     "own rules only",
+			manifest: `pack "team" {}`,
+			wantIDs:  []string{"team_bucket_owner"},
+		},
+		{
+			name:     "unknown rule",
+			manifest: `pack "p" { rules = ["aws_typo"] }`,
+			wantErr:  `pack p includes unknown rule "aws_typo"`,
+		},
+		{
+			name:     "invalid severity",
+			manifest: `pack "p" { severities = { team_bucket_owner = "critical" } }`,
+			wantErr:  `severity of team_bucket_owner is "critical"`,
+		},
+		{
+			name:     "severity of a rule not included",
+			manifest: `pack "p" { severities = { aws_ec2_imdsv2 = "error" } }`,
+			wantErr:  `sets the severity of "aws_ec2_imdsv2", which it does not include`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, PackManifestFile), []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "rules.hcl"), []byte(rule), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, rules, err := LoadPack(dir, available)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadPack() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPack() error = %v", err)
+			}
+
+			var ids []string
+			for _, r := range rules {
+				ids = append(ids, r.ID)
+				if want, ok := tt.wantSeverity[r.ID]; ok && r.Severity != want {
+					t.Errorf("Severity of %s = %s, want %s", r.ID, r.Severity, want)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("LoadPack() rules = %v, want %v", ids, tt.wantIDs)
+			}
+			if rules[0].Pack != manifest {
+				t.Error("Expected the pack's own rules to carry its manifest")
+			}
+		})
+	}
+
+	// Included rules are copies; the available rules keep their severity
+	if available[1].Severity != "warning" {
+		t.Errorf("LoadPack() changed an available rule's severity to %s", available[1].Severity)
+	}
+
+	if _, _, err := LoadPack(t.TempDir(), nil); err == nil || !strings.Contains(err.Error(), "no pack.hcl") {
+		t.Errorf("LoadPack() without a manifest error = %v", err)
+	}
+}
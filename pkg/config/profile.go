@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile adjusts the settings for one environment, e.g. a stricter
+// fail_on for production pipelines, so dev, stage, and prod can share a
+// config file. It is selected with -profile.
+type Profile struct {
+	Name                       string            `hcl:"name,label"`
+	FailOn                     *string           `hcl:"fail_on,optional"`                      // Replaces the default of -fail-on
+	PresuppliedRulesCategories []string          `hcl:"presupplied_rules_categories,optional"` // Replaces the setting
+	ExcludePaths               []string          `hcl:"exclude_paths,optional"`                // Added to the setting
+	DisabledRules              []string          `hcl:"disabled_rules,optional"`               // Added to the setting
+	EnabledRules               []string          `hcl:"enabled_rules,optional"`                // Replaces the setting
+	EnableRules                []string          `hcl:"enable_rules,optional"`                 // Added to the setting
+	Severities                 map[string]string `hcl:"severities,optional"`                   // Rule ID to severity
+}
+
+// FindProfile returns the profile named name. Profile blocks with the same
+// name, e.g. from several config layers, are combined in order.
+func (c *Config) FindProfile(name string) (*Profile, error) {
+	var found *Profile
+	var names []string
+	for _, profile := range c.Profiles {
+		names = appendMissing(names, profile.Name)
+		if profile.Name != name {
+			continue
+		}
+		if found == nil {
+			found = &Profile{Name: name}
+		}
+		found.merge(profile)
+	}
+	if found == nil {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %q (the config defines no profiles)", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q (expected one of: %s)", name, strings.Join(names, ", "))
+	}
+
+	if found.FailOn != nil {
+		switch *found.FailOn {
+		case "error", "warning", "info":
+		default:
+			return nil, fmt.Errorf("profile %q: invalid fail_on %q (expected error, warning, or info)", name, *found.FailOn)
+		}
+	}
+	for id, severity := range found.Severities {
+		if _, ok := severityRank[severity]; !ok {
+			return nil, fmt.Errorf("profile %q: invalid severity %q for rule %s (expected error, warning, or info)", name, severity, id)
+		}
+	}
+	return found, nil
+}
+
+// merge combines a later block of the same profile into p
+func (p *Profile) merge(other Profile) {
+	if other.FailOn != nil {
+		p.FailOn = other.FailOn
+	}
+	if other.PresuppliedRulesCategories != nil {
+		p.PresuppliedRulesCategories = other.PresuppliedRulesCategories
+	}
+	p.ExcludePaths = appendMissing(p.ExcludePaths, other.ExcludePaths...)
+	p.DisabledRules = appendMissing(p.DisabledRules, other.DisabledRules...)
+	p.EnabledRules = appendMissing(p.EnabledRules, other.EnabledRules...)
+	p.EnableRules = appendMissing(p.EnableRules, other.EnableRules...)
+	for id, severity := range other.Severities {
+		if p.Severities == nil {
+			p.Severities = make(map[string]string)
+		}
+		p.Severities[id] = severity
+	}
+}
+
+// Apply applies the profile's settings to settings
+func (p *Profile) Apply(settings *Settings) {
+	if p.PresuppliedRulesCategories != nil {
+		settings.PresuppliedRulesCategories = p.PresuppliedRulesCategories
+	}
+	settings.ExcludePaths = appendMissing(settings.ExcludePaths, p.ExcludePaths...)
+	settings.DisabledRules = appendMissing(settings.DisabledRules, p.DisabledRules...)
+	settings.EnableRules = appendMissing(settings.EnableRules, p.EnableRules...)
+	if len(p.EnabledRules) > 0 {
+		settings.EnabledRules = p.EnabledRules
+	}
+}
+
+// ApplySeverities returns a copy of rules with the profile's severities
+func (p *Profile) ApplySeverities(rules []Rule) []Rule {
+	return (&SeverityMap{Rules: p.Severities}).Apply(rules)
+}
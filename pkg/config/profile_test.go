@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.hcl")
+	content := `
+settings {
+  exclude_paths  = ["**/.terraform/**"]
+  disabled_rules = ["aws_s3_logging"]
+}
+
+profile "dev" {
+  fail_on        = "error"
+  disabled_rules = ["aws_s3_versioning"]
+  enable_rules   = ["aws_s3_strict"]
+}
+
+profile "prod" {
+  fail_on                      = "warning"
+  presupplied_rules_categories = ["aws", "security"]
+  exclude_paths                = ["sandbox/**"]
+  severities                   = { aws_s3_versioning = "error" }
+}
+
+profile "prod" {
+  enabled_rules = ["aws_s3_versioning"]
+  severities    = { aws_s3_logging = "warning" }
+}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	prod, err := cfg.FindProfile("prod")
+	if err != nil {
+		t.Fatalf("FindProfile(prod) error = %v", err)
+	}
+	if prod.FailOn == nil || *prod.FailOn != "warning" {
+		t.Errorf("FailOn = %v, want warning", prod.FailOn)
+	}
+	if want := map[string]string{"aws_s3_versioning": "error", "aws_s3_logging": "warning"}; !reflect.DeepEqual(prod.Severities, want) {
+		t.Errorf("Severities = %v, want %v", prod.Severities, want)
+	}
+
+	settings := *cfg.Settings
+	prod.Apply(&settings)
+	if want := []string{"aws", "security"}; !reflect.DeepEqual(settings.PresuppliedRulesCategories, want) {
+		t.Errorf("PresuppliedRulesCategories = %v, want %v", settings.PresuppliedRulesCategories, want)
+	}
+	if want := []string{"**/.terraform/**", "sandbox/**"}; !reflect.DeepEqual(settings.ExcludePaths, want) {
+		t.Errorf("ExcludePaths = %v, want %v", settings.ExcludePaths, want)
+	}
+	if want := []string{"aws_s3_versioning"}; !reflect.DeepEqual(settings.EnabledRules, want) {
+		t.Errorf("EnabledRules = %v, want %v", settings.EnabledRules, want)
+	}
+
+	rules := prod.ApplySeverities([]Rule{{ID: "aws_s3_versioning", Severity: "warning"}, {ID: "other", Severity: "info"}})
+	if rules[0].Severity != "error" || rules[1].Severity != "info" {
+		t.Errorf("ApplySeverities() = %+v", rules)
+	}
+
+	dev, err := cfg.FindProfile("dev")
+	if err != nil {
+		t.Fatalf("FindProfile(dev) error = %v", err)
+	}
+	settings = *cfg.Settings
+	dev.Apply(&settings)
+	if want := []string{"aws_s3_logging", "aws_s3_versioning"}; !reflect.DeepEqual(settings.DisabledRules, want) {
+		t.Errorf("DisabledRules = %v, want %v", settings.DisabledRules, want)
+	}
+	if want := []string{"aws_s3_strict"}; !reflect.DeepEqual(settings.EnableRules, want) {
+		t.Errorf("EnableRules = %v, want %v", settings.EnableRules, want)
+	}
+}
+
+func TestFindProfileErrors(t *testing.T) {
+	invalid := "fatal"
+	tests := []struct {
+		name    string
+		cfg     Config
+		profile string
+		wantErr string
+	}{
+		{
+			name:    "no profiles",
+			profile: "prod",
+			wantErr: "the config defines no profiles",
+		},
+		{
+			name:    "unknown",
+			cfg:     Config{Profiles: []Profile{{Name: "dev"}, {Name: "stage"}, {Name: "dev"}}},
+			profile: "prod",
+			wantErr: `unknown profile "prod" (expected one of: dev, stage)`,
+		},
+		{
+			name:    "invalid fail_on",
+			cfg:     Config{Profiles: []Profile{{Name: "prod", FailOn: &invalid}}},
+			profile: "prod",
+			wantErr: `invalid fail_on "fatal"`,
+		},
+		{
+			name:    "invalid severity",
+			cfg:     Config{Profiles: []Profile{{Name: "prod", Severities: map[string]string{"r": "fatal"}}}},
+			profile: "prod",
+			wantErr: `invalid severity "fatal" for rule r`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.FindProfile(tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FindProfile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
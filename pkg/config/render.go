@@ -0,0 +1,72 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FormatExceptions formats exceptions as HCL exception blocks, separated by
+// blank lines, ready to be added to a config file
+func FormatExceptions(exceptions []Exception) []byte {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for i, exception := range exceptions {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		AppendException(body, exception)
+	}
+	return hclwrite.Format(f.Bytes())
+}
+
+// AppendException appends an exception block to body, writing only the
+// attributes the exception sets
+func AppendException(body *hclwrite.Body, exception Exception) {
+	block := body.AppendNewBlock("exception", nil).Body()
+	block.SetAttributeValue("rules", stringList(exception.Rules))
+	if len(exception.Paths) > 0 {
+		block.SetAttributeValue("paths", stringList(exception.Paths))
+	}
+	if len(exception.ResourceNames) > 0 {
+		block.SetAttributeValue("resource_names", stringList(exception.ResourceNames))
+	}
+	if len(exception.Addresses) > 0 {
+		block.SetAttributeValue("addresses", stringList(exception.Addresses))
+	}
+	if len(exception.Tags) > 0 {
+		keys := make([]string, 0, len(exception.Tags))
+		for key := range exception.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		tags := make(map[string]cty.Value, len(keys))
+		for _, key := range keys {
+			tags[key] = cty.StringVal(exception.Tags[key])
+		}
+		block.SetAttributeValue("tags", cty.ObjectVal(tags))
+	}
+	if len(exception.Severities) > 0 {
+		block.SetAttributeValue("severities", stringList(exception.Severities))
+	}
+	block.SetAttributeValue("reason", cty.StringVal(exception.Reason))
+	if exception.ExpiresAt != nil {
+		block.SetAttributeValue("expires_at", cty.StringVal(*exception.ExpiresAt))
+	}
+	block.SetAttributeValue("approved_by", cty.StringVal(exception.ApprovedBy))
+	if exception.Ticket != nil {
+		block.SetAttributeValue("ticket", cty.StringVal(*exception.Ticket))
+	}
+}
+
+func stringList(values []string) cty.Value {
+	if len(values) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	vals := make([]cty.Value, len(values))
+	for i, v := range values {
+		vals[i] = cty.StringVal(v)
+	}
+	return cty.ListVal(vals)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+func TestFormatExceptions(t *testing.T) {
+	exceptions := []Exception{
+		{
+			Rules:         []string{"aws_s3_public_read"},
+			Paths:         []string{"modules/cdn/main.tf"},
+			ResourceNames: []string{"assets"},
+			Reason:        "Serves public assets",
+			ApprovedBy:    "security-team",
+		},
+		{
+			Rules:      []string{"require_tags", "aws_s3_versioning"},
+			Addresses:  []string{"module.logs.aws_s3_bucket.*"},
+			Tags:       map[string]string{"env": "dev", "team": "data"},
+			Severities: []string{"warning"},
+			Reason:     "Scratch buckets",
+			ExpiresAt:  stringPtr("2025-01-31"),
+			ApprovedBy: "platform",
+			Ticket:     stringPtr("SEC-42"),
+		},
+	}
+
+	want := `exception {
+  rules          = ["aws_s3_public_read"]
+  paths          = ["modules/cdn/main.tf"]
+  resource_names = ["assets"]
+  reason         = "Serves public assets"
+  approved_by    = "security-team"
+}
+
+exception {
+  rules     = ["require_tags", "aws_s3_versioning"]
+  addresses = ["module.logs.aws_s3_bucket.*"]
+  tags = {
+    env  = "dev"
+    team = "data"
+  }
+  severities  = ["warning"]
+  reason      = "Scratch buckets"
+  expires_at  = "2025-01-31"
+  approved_by = "platform"
+  ticket      = "SEC-42"
+}
+`
+	got := FormatExceptions(exceptions)
+	if string(got) != want {
+		t.Errorf("FormatExceptions() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The blocks load back as the same exceptions
+	var loaded struct {
+		Exceptions []Exception `hcl:"exception,block"`
+	}
+	if err := hclsimple.Decode("exceptions.hcl", got, nil, &loaded); err != nil {
+		t.Fatalf("failed to load formatted exceptions: %v", err)
+	}
+	if len(loaded.Exceptions) != 2 || loaded.Exceptions[1].Tags["team"] != "data" || *loaded.Exceptions[1].Ticket != "SEC-42" {
+		t.Errorf("loaded exceptions = %+v", loaded.Exceptions)
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// severityRank orders severities from least to most severe
+var severityRank = map[string]int{
+	"info":    1,
+	"warning": 2,
+	"error":   3,
+}
+
+// SeverityMap remaps rule severities at scan time without editing rule
+// files. A mapping by rule ID takes precedence over mappings by tag; when
+// several of a rule's tags are mapped, the most severe mapping wins.
+type SeverityMap struct {
+	Rules map[string]string
+	Tags  map[string]string
+
+	floors map[string]string // Severities rules cannot be lowered below, by ID
+}
+
+// LoadSeverityMap loads a severity mapping file. The file is YAML (or
+// JSON) with optional "rules" and "tags" sections mapping rule IDs and rule
+// tags to a severity:
+//
+//	rules:
+//	  aws_s3_versioning: error
+//	tags:
+//	  cost: info
+func LoadSeverityMap(path string) (*SeverityMap, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read severity map: %w", err)
+	}
+
+	ty, err := ctyyaml.ImpliedType(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse severity map %s: %w", path, err)
+	}
+	val, err := ctyyaml.Unmarshal(src, ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse severity map %s: %w", path, err)
+	}
+
+	m := &SeverityMap{
+		Rules: make(map[string]string),
+		Tags:  make(map[string]string),
+	}
+
+	if val.IsNull() {
+		return m, nil
+	}
+	if !val.Type().IsObjectType() && !val.Type().IsMapType() {
+		return nil, fmt.Errorf("severity map %s: expected a mapping with rules and tags sections", path)
+	}
+
+	for section, entries := range val.AsValueMap() {
+		var target map[string]string
+		switch section {
+		case "rules":
+			target = m.Rules
+		case "tags":
+			target = m.Tags
+		default:
+			return nil, fmt.Errorf("severity map %s: unknown section %q (expected rules or tags)", path, section)
+		}
+
+		if entries.IsNull() {
+			continue
+		}
+		if !entries.Type().IsObjectType() && !entries.Type().IsMapType() {
+			return nil, fmt.Errorf("severity map %s: %s must map names to severities", path, section)
+		}
+
+		for name, severity := range entries.AsValueMap() {
+			str, err := convert.Convert(severity, cty.String)
+			if err != nil || str.IsNull() {
+				return nil, fmt.Errorf("severity map %s: %s.%s must be a string", path, section, name)
+			}
+			if _, ok := severityRank[str.AsString()]; !ok {
+				return nil, fmt.Errorf("severity map %s: %s.%s has invalid severity %q (expected error, warning, or info)", path, section, name, str.AsString())
+			}
+			target[name] = str.AsString()
+		}
+	}
+
+	return m, nil
+}
+
+// Protect keeps the map from making rules less severe than their floors,
+// by rule ID, as for a map set in a later config layer than the rules (see
+// Config.SeverityFloors). Mappings by rule ID that would are dropped, with a
+// warning naming source; mappings by tag skip those rules when applied.
+func (m *SeverityMap) Protect(floors map[string]string, source string) []string {
+	var warnings []string
+	for id, severity := range m.Rules {
+		if floor, ok := floors[id]; ok && severityRank[severity] < severityRank[floor] {
+			warnings = append(warnings, fmt.Sprintf("%s: rule %s is defined in an earlier config layer and cannot be made less severe than %s; ignoring its mapping to %s", source, id, floor, severity))
+			delete(m.Rules, id)
+		}
+	}
+	sort.Strings(warnings)
+	m.floors = floors
+	return warnings
+}
+
+// Apply returns a copy of rules with severities remapped
+func (m *SeverityMap) Apply(rules []Rule) []Rule {
+	remapped := make([]Rule, len(rules))
+	for i, rule := range rules {
+		remapped[i] = rule
+		severity, ok := m.severityFor(rule)
+		if !ok {
+			continue
+		}
+		if floor, protected := m.floors[rule.ID]; protected && severityRank[severity] < severityRank[floor] {
+			continue
+		}
+		remapped[i].Severity = severity
+	}
+	return remapped
+}
+
+func (m *SeverityMap) severityFor(rule Rule) (string, bool) {
+	if severity, ok := m.Rules[rule.ID]; ok {
+		return severity, true
+	}
+
+	var matched []string
+	for _, tag := range rule.Tags {
+		if severity, ok := m.Tags[tag]; ok {
+			matched = append(matched, severity)
+		}
+	}
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return severityRank[matched[i]] > severityRank[matched[j]]
+	})
+	return matched[0], true
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSeverityMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "severity.yaml")
+	content := `
+rules:
+  aws_s3_versioning: error
+tags:
+  cost: info
+  pci: error
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadSeverityMap(path)
+	if err != nil {
+		t.Fatalf("LoadSeverityMap() error = %v", err)
+	}
+
+	rules := []Rule{
+		{ID: "aws_s3_versioning", Severity: "warning", Tags: []string{"cost"}},
+		{ID: "tagged_cost", Severity: "warning", Tags: []string{"cost"}},
+		{ID: "tagged_both", Severity: "warning", Tags: []string{"cost", "pci"}},
+		{ID: "untouched", Severity: "warning", Tags: []string{"other"}},
+	}
+
+	remapped := m.Apply(rules)
+
+	expected := map[string]string{
+		"aws_s3_versioning": "error", // rule ID wins over tags
+		"tagged_cost":       "info",
+		"tagged_both":       "error", // most severe tag mapping wins
+		"untouched":         "warning",
+	}
+	for _, rule := range remapped {
+		if rule.Severity != expected[rule.ID] {
+			t.Errorf("Rule %s severity = %s, want %s", rule.ID, rule.Severity, expected[rule.ID])
+		}
+	}
+
+	if rules[0].Severity != "warning" {
+		t.Error("Apply() should not modify the input rules")
+	}
+}
+
+func TestLoadSeverityMapErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid severity", "rules:\n  a: critical\n"},
+		{"unknown section", "severities:\n  a: error\n"},
+		{"not a mapping", "- a\n- b\n"},
+		{"section not a mapping", "rules: error\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "severity.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadSeverityMap(path); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	if _, err := LoadSeverityMap(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -11,6 +13,19 @@ type Config struct {
 	Rules      []Rule      `hcl:"rule,block"`
 	Exceptions []Exception `hcl:"exception,block"`
 	Functions  []Function  `hcl:"function,block"`
+	Profiles   []Profile   `hcl:"profile,block"`
+
+	ActiveProfile *Profile // Profile selected with -profile, if any
+	Aliases       Aliases  // Former IDs of the loaded rules
+
+	// Severities that the severity_map setting cannot lower rules below, by
+	// rule ID: those of rules from config layers before the one setting it
+	SeverityFloors map[string]string
+
+	// Attributes and blocks of the config files that planguard does not
+	// know, such as a misspelled severty, as warnings. Loading ignores them;
+	// callers report them, or fail with strict = true in settings.
+	Unknown hcl.Diagnostics
 }
 
 // Settings contains global configuration
@@ -19,19 +34,109 @@ type Settings struct {
 	ExcludePaths               []string `hcl:"exclude_paths,optional"`
 	UsePresuppliedRules        *bool    `hcl:"use_presupplied_rules,optional"`
 	PresuppliedRulesCategories []string `hcl:"presupplied_rules_categories,optional"`
+	SeverityMap                *string  `hcl:"severity_map,optional"`
+	DisabledRules              []string `hcl:"disabled_rules,optional"`
+	EnabledRules               []string `hcl:"enabled_rules,optional"` // If set, only these rules run
+	EnableRules                []string `hcl:"enable_rules,optional"`  // Opt-in rules (enabled = false) to run
+	RuleSources                []string `hcl:"rule_sources,optional"`  // Where to load rules from: git::<url>, s3://, or gs:// locations
+
+	// Problems in config and rule files that loading accepts, such as an
+	// invalid severity or an unknown attribute, fail the scan instead of
+	// warning
+	Strict bool `hcl:"strict,optional"`
+
+	// Rule sources must carry a minisign signature by one of these keys
+	RequireSignedRules bool     `hcl:"require_signed_rules,optional"`
+	TrustedRuleKeys    []string `hcl:"trusted_rule_keys,optional"`
+
+	// Credentials for fetching git rule sources over HTTPS, by host
+	Credentials []Credentials `hcl:"credentials,block"`
+
+	// Exceptions expiring within this many days are reported (default 14),
+	// and expired exceptions can fail the scan
+	ExceptionExpiryWarningDays *int `hcl:"exception_expiry_warning_days,optional"`
+	FailOnExpiredExceptions    bool `hcl:"fail_on_expired_exceptions,optional"`
+
+	// Checks of exceptions' tickets before scanning
+	TicketValidation *TicketValidation `hcl:"ticket_validation,block"`
+
+	// Budgets for active (unexpired) exceptions, overall and per rule ID or
+	// rule category. The scan fails when one is exceeded.
+	MaxActiveExceptions            *int           `hcl:"max_active_exceptions,optional"`
+	MaxActiveExceptionsPerRule     map[string]int `hcl:"max_active_exceptions_per_rule,optional"`
+	MaxActiveExceptionsPerCategory map[string]int `hcl:"max_active_exceptions_per_category,optional"`
+
+	// Risk weights of violations by severity and by rule ID, summed into
+	// the risk score. Rule weights take precedence.
+	RiskSeverityWeights map[string]float64 `hcl:"risk_severity_weights,optional"`
+	RiskRuleWeights     map[string]float64 `hcl:"risk_rule_weights,optional"`
+
+	// Inline suppressions from other scanners (tfsec, checkov) to honor
+	// during a migration, and extra check ID to rule ID translations
+	HonorInlineSkips   []string            `hcl:"honor_inline_skips,optional"`
+	InlineSkipMappings map[string][]string `hcl:"inline_skip_mappings,optional"`
+}
+
+// TicketValidation checks that every active exception references a ticket
+// in the expected format and, with URL, that the ticket exists in the issue
+// tracker and is not closed
+type TicketValidation struct {
+	Pattern        *string  `hcl:"pattern,optional"`         // Regular expression tickets must match
+	URL            *string  `hcl:"url,optional"`             // Ticket API URL, with {ticket} for the ticket
+	StatusField    *string  `hcl:"status_field,optional"`    // Dotted path to the status in the response (default fields.status.name, as in Jira)
+	ClosedStatuses []string `hcl:"closed_statuses,optional"` // Statuses of closed tickets (default Done, Closed, Resolved)
+	OnFailure      *string  `hcl:"on_failure,optional"`      // "error" (default) fails the scan, "warning" only warns
+}
+
+// Credentials say where to find the secret for fetching rule sources from
+// a host, so tokens stay out of config files. Exactly one of token_env,
+// netrc, and helper is set.
+type Credentials struct {
+	Host     string   `hcl:"host,label"`
+	Username *string  `hcl:"username,optional"`  // Username sent with the token (default x-access-token)
+	TokenEnv *string  `hcl:"token_env,optional"` // Environment variable holding the token
+	Netrc    bool     `hcl:"netrc,optional"`     // Read the host's login and password from $NETRC or ~/.netrc
+	Helper   []string `hcl:"helper,optional"`    // Command that prints the token, or git credential helper output
 }
 
 // Rule represents a security/compliance rule
 type Rule struct {
-	ID           string      `hcl:"id,label"`
-	Name         string      `hcl:"name"`
-	Severity     string      `hcl:"severity"`
-	ResourceType string      `hcl:"resource_type"`
-	When         *WhenBlock  `hcl:"when,block"`
-	Conditions   []Condition `hcl:"condition,block"`
-	Message      string      `hcl:"message"`
-	Remediation  *string     `hcl:"remediation,optional"`
-	References   []string    `hcl:"references,optional"`
+	ID           string            `hcl:"id,label"`
+	Name         string            `hcl:"name"`
+	Severity     string            `hcl:"severity"`
+	ResourceType string            `hcl:"resource_type"`
+	When         *WhenBlock        `hcl:"when,block"`
+	Conditions   []Condition       `hcl:"condition,block"`
+	Message      string            `hcl:"message"`
+	Remediation  *string           `hcl:"remediation,optional"`
+	ExamplePass  *string           `hcl:"example_pass,optional"` // Terraform that satisfies the rule
+	ExampleFail  *string           `hcl:"example_fail,optional"` // Terraform that violates the rule
+	References   []string          `hcl:"references,optional"`
+	Tags         []string          `hcl:"tags,optional"`
+	Compliance   map[string]string `hcl:"compliance,optional"`    // Framework to control ID, e.g. cis_aws = "2.1.1"
+	OnUnknown    *string           `hcl:"on_unknown,optional"`    // "skip" (default) or "violation"
+	Deprecated   bool              `hcl:"deprecated,optional"`    // Kept for now, but due to be removed
+	SupersededBy *string           `hcl:"superseded_by,optional"` // Rule to use instead; implies deprecated
+	Aliases      []string          `hcl:"aliases,optional"`       // Former IDs, still accepted where rules are referenced
+	Enabled      *bool             `hcl:"enabled,optional"`       // false makes the rule opt-in, run only when listed in enable_rules
+	Metadata     *RuleMetadata     `hcl:"metadata,block"`
+
+	Pack   *PackManifest // Pack the rule was loaded from, if its directory has a manifest
+	Source string        `json:"-"` // File the rule was loaded from
+
+	// Unknown attributes and blocks of the rule file the rule was loaded
+	// from, as Config.Unknown records them for config files
+	Unknown hcl.Diagnostics `json:"-"`
+}
+
+// RuleMetadata describes who owns a rule and where it is documented. It is
+// copied onto the rule's violations so findings can be routed to the owning
+// team.
+type RuleMetadata struct {
+	Owner   string `hcl:"owner,optional" json:",omitempty"`    // Team or person responsible for the rule
+	DocsURL string `hcl:"docs_url,optional" json:",omitempty"` // Internal documentation for the rule
+	CISID   string `hcl:"cis_id,optional" json:",omitempty"`   // CIS benchmark control, e.g. "2.1.1"
+	Created string `hcl:"created,optional" json:",omitempty"`  // Date the rule was added, YYYY-MM-DD
 }
 
 // WhenBlock represents a conditional execution block
@@ -46,13 +151,18 @@ type Condition struct {
 
 // Exception represents a rule exception
 type Exception struct {
-	Rules         []string `hcl:"rules"`
-	Paths         []string `hcl:"paths,optional"`
-	ResourceNames []string `hcl:"resource_names,optional"`
-	Reason        string   `hcl:"reason"`
-	ExpiresAt     *string  `hcl:"expires_at,optional"`
-	ApprovedBy    string   `hcl:"approved_by"`
-	Ticket        *string  `hcl:"ticket,optional"`
+	Rules         []string          `hcl:"rules"`
+	Paths         []string          `hcl:"paths,optional"`
+	ResourceNames []string          `hcl:"resource_names,optional"`
+	Addresses     []string          `hcl:"addresses,optional"`  // e.g. module.storage.aws_s3_bucket.logs; globs allowed
+	Tags          map[string]string `hcl:"tags,optional"`       // Resource tag values that must all match; globs allowed
+	Severities    []string          `hcl:"severities,optional"` // Only except violations of these severities
+	Reason        string            `hcl:"reason"`
+	ExpiresAt     *string           `hcl:"expires_at,optional"`
+	ApprovedBy    string            `hcl:"approved_by"`
+	Ticket        *string           `hcl:"ticket,optional"`
+
+	Source string `json:"-"` // File the exception was loaded from
 }
 
 // Function represents a user-defined function
@@ -74,6 +184,37 @@ type Violation struct {
 	ResourceType string
 	ResourceName string
 	Remediation  string
+	Deprecation  string            `json:",omitempty"` // Set when the rule is deprecated; see Rule.Deprecation
+	Metadata     *RuleMetadata     `json:",omitempty"` // The rule's metadata block, if it has one
+	Fingerprint  string            `json:",omitempty"` // Stable ID of the violation; see Fingerprint
+	Labels       map[string]string `json:",omitempty"` // Labels of the scan target (e.g. env=prod)
+	Blame        *Blame            `json:",omitempty"` // Who last changed the violating line, with -blame
+	Source       *Source           `json:",omitempty"` // Lines around the violating line, with -show-source
+}
+
+// Blame records the commit that last changed a violation's line, from
+// git blame
+type Blame struct {
+	Commit      string
+	Author      string
+	AuthorEmail string
+	Date        time.Time
+	Uncommitted bool `json:",omitempty"` // The line has changes not yet committed
+}
+
+// Source holds the lines of a file around a violation's line
+type Source struct {
+	StartLine int // Line number of the first of Lines
+	Lines     []string
+}
+
+// Target is a directory to scan together with labels used to group results
+// (e.g. env = "prod", team = "payments")
+type Target struct {
+	Name      string            `hcl:"name,label"`
+	Directory string            `hcl:"directory"`
+	Plan      string            `hcl:"plan,optional"` // Plan JSON whose planned values are evaluated
+	Labels    map[string]string `hcl:"labels,optional"`
 }
 
 // FilteredViolation represents a violation that was filtered by an exception
@@ -82,8 +223,23 @@ type FilteredViolation struct {
 	Exception Exception
 }
 
+// Block kinds extracted from Terraform configurations. Blocks other than
+// resources and data sources use their kind as their Type so rules can
+// target them directly (e.g. resource_type = "variable").
+const (
+	KindResource = "resource"
+	KindData     = "data"
+	KindVariable = "variable"
+	KindCheck    = "check"
+	KindLocal    = "local"
+	KindOutput   = "output"
+	KindModule   = "module"
+	KindProvider = "provider"
+)
+
 // Resource represents a parsed Terraform resource
 type Resource struct {
+	Kind       string // Block kind, e.g. resource, data, or variable
 	Type       string
 	Name       string
 	Attributes map[string]cty.Value
@@ -93,3 +249,21 @@ type Resource struct {
 	Column     int
 	Labels     []string
 }
+
+// IsResource reports whether r is a resource or data block, rather than
+// another block such as a variable or output
+func (r *Resource) IsResource() bool {
+	return r.Kind == "" || r.Kind == KindResource || r.Kind == KindData
+}
+
+// CountResources returns how many of resources are resource or data
+// blocks, which are what scan totals count as resources
+func CountResources(resources []*Resource) int {
+	count := 0
+	for _, resource := range resources {
+		if resource.IsResource() {
+			count++
+		}
+	}
+	return count
+}
@@ -49,3 +49,20 @@ func TestRuleSeverity(t *testing.T) {
 		})
 	}
 }
+
+func TestCountResources(t *testing.T) {
+	resources := []*Resource{
+		{Kind: KindResource, Type: "aws_s3_bucket"},
+		{Kind: KindData, Type: "aws_ami"},
+		{Type: "aws_instance"},
+		{Kind: KindVariable, Type: KindVariable},
+		{Kind: KindLocal, Type: KindLocal},
+		{Kind: KindOutput, Type: KindOutput},
+		{Kind: KindModule, Type: KindModule},
+		{Kind: KindProvider, Type: KindProvider},
+		{Kind: KindCheck, Type: KindCheck},
+	}
+	if got := CountResources(resources); got != 3 {
+		t.Errorf("CountResources() = %d, want 3", got)
+	}
+}
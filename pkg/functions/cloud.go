@@ -0,0 +1,59 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// ARNParseFunc splits an AWS ARN into its components:
+// arn:partition:service:region:account-id:resource
+//
+// The resource part is further split on the first "/" or ":" into
+// resource_type and resource_id; for resources without a type (such as S3
+// buckets) resource_type is empty.
+var ARNParseFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "arn", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.Object(map[string]cty.Type{
+		"partition":     cty.String,
+		"service":       cty.String,
+		"region":        cty.String,
+		"account_id":    cty.String,
+		"resource":      cty.String,
+		"resource_type": cty.String,
+		"resource_id":   cty.String,
+	})),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		arn := strings.TrimSpace(args[0].AsString())
+
+		parts := strings.SplitN(arn, ":", 6)
+		if len(parts) != 6 || parts[0] != "arn" {
+			return cty.NilVal, fmt.Errorf("invalid ARN %q: expected arn:partition:service:region:account-id:resource", arn)
+		}
+		if parts[1] == "" || parts[2] == "" || parts[5] == "" {
+			return cty.NilVal, fmt.Errorf("invalid ARN %q: partition, service, and resource are required", arn)
+		}
+
+		resource := parts[5]
+		resourceType := ""
+		resourceID := resource
+		if idx := strings.IndexAny(resource, "/:"); idx >= 0 {
+			resourceType = resource[:idx]
+			resourceID = resource[idx+1:]
+		}
+
+		return cty.ObjectVal(map[string]cty.Value{
+			"partition":     cty.StringVal(parts[1]),
+			"service":       cty.StringVal(parts[2]),
+			"region":        cty.StringVal(parts[3]),
+			"account_id":    cty.StringVal(parts[4]),
+			"resource":      cty.StringVal(resource),
+			"resource_type": cty.StringVal(resourceType),
+			"resource_id":   cty.StringVal(resourceID),
+		}), nil
+	},
+})
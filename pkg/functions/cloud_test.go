@@ -0,0 +1,80 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestARNParseFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		arn      string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "iam role",
+			arn:  "arn:aws:iam::123456789012:role/deploy",
+			expected: map[string]string{
+				"partition":     "aws",
+				"service":       "iam",
+				"region":        "",
+				"account_id":    "123456789012",
+				"resource":      "role/deploy",
+				"resource_type": "role",
+				"resource_id":   "deploy",
+			},
+		},
+		{
+			name: "s3 bucket without type",
+			arn:  "arn:aws:s3:::my-bucket",
+			expected: map[string]string{
+				"service":       "s3",
+				"account_id":    "",
+				"resource_type": "",
+				"resource_id":   "my-bucket",
+			},
+		},
+		{
+			name: "lambda alias with colons",
+			arn:  "arn:aws-us-gov:lambda:us-gov-west-1:123456789012:function:my-fn:live",
+			expected: map[string]string{
+				"partition":     "aws-us-gov",
+				"region":        "us-gov-west-1",
+				"resource_type": "function",
+				"resource_id":   "my-fn:live",
+			},
+		},
+		{
+			name:    "not an arn",
+			arn:     "my-bucket",
+			wantErr: true,
+		},
+		{
+			name:    "missing resource",
+			arn:     "arn:aws:s3:::",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ARNParseFunc.Call([]cty.Value{cty.StringVal(tt.arn)})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.arn)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ARNParseFunc.Call() error = %v", err)
+			}
+			for attr, want := range tt.expected {
+				if got := result.GetAttr(attr).AsString(); got != want {
+					t.Errorf("arn_parse(%q).%s = %q, want %q", tt.arn, attr, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package functions
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// ToStringFunc converts a value to a string
+var ToStringFunc = makeToFunc(cty.String)
+
+// ToBoolFunc converts a value to a bool
+var ToBoolFunc = makeToFunc(cty.Bool)
+
+// ToListFunc converts a tuple, list, or set to a list
+var ToListFunc = makeToFunc(cty.List(cty.DynamicPseudoType))
+
+// ToSetFunc converts a tuple, list, or set to a set
+var ToSetFunc = makeToFunc(cty.Set(cty.DynamicPseudoType))
+
+// ToMapFunc converts an object or map to a map
+var ToMapFunc = makeToFunc(cty.Map(cty.DynamicPseudoType))
+
+// makeToFunc builds a function converting its argument to want, as
+// Terraform's tostring, tolist, and friends do. Collection element types
+// are unified from the value, so tolist(["a", "b"]) is a list of strings.
+func makeToFunc(want cty.Type) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "v", Type: cty.DynamicPseudoType, AllowNull: true, AllowDynamicType: true},
+		},
+		Type: func(args []cty.Value) (cty.Type, error) {
+			got := args[0].Type()
+			if got.Equals(want) {
+				return want, nil
+			}
+			conv := convert.GetConversionUnsafe(got, want)
+			if conv == nil {
+				return cty.NilType, function.NewArgErrorf(0, "cannot convert %s to %s", got.FriendlyName(), want.FriendlyNameForConstraint())
+			}
+			if !want.HasDynamicTypes() {
+				return want, nil
+			}
+			if !args[0].IsWhollyKnown() {
+				return cty.DynamicPseudoType, nil
+			}
+			converted, err := conv(args[0])
+			if err != nil {
+				return cty.NilType, function.NewArgErrorf(0, "cannot convert %s to %s: %s", got.FriendlyName(), want.FriendlyNameForConstraint(), err)
+			}
+			return converted.Type(), nil
+		},
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			// Partly unknown collections only have a dynamic return type
+			if retType == cty.DynamicPseudoType {
+				retType = want
+			}
+			converted, err := convert.Convert(args[0], retType)
+			if err != nil {
+				return cty.NilVal, function.NewArgErrorf(0, "cannot convert %s to %s: %s", args[0].Type().FriendlyName(), want.FriendlyNameForConstraint(), err)
+			}
+			return converted, nil
+		},
+	})
+}
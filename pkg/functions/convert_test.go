@@ -0,0 +1,85 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestConversionFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       function.Function
+		input    cty.Value
+		expected cty.Value
+		wantErr  bool
+	}{
+		{
+			name:     "tolist of tuple",
+			fn:       ToListFunc,
+			input:    cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			expected: cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		},
+		{
+			name:     "tolist of list",
+			fn:       ToListFunc,
+			input:    cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			expected: cty.ListVal([]cty.Value{cty.StringVal("a")}),
+		},
+		{
+			name:    "tolist of string",
+			fn:      ToListFunc,
+			input:   cty.StringVal("s3:*"),
+			wantErr: true,
+		},
+		{
+			name:     "toset removes duplicates",
+			fn:       ToSetFunc,
+			input:    cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("a")}),
+			expected: cty.SetVal([]cty.Value{cty.StringVal("a")}),
+		},
+		{
+			name:     "tomap of object",
+			fn:       ToMapFunc,
+			input:    cty.ObjectVal(map[string]cty.Value{"Owner": cty.StringVal("team")}),
+			expected: cty.MapVal(map[string]cty.Value{"Owner": cty.StringVal("team")}),
+		},
+		{
+			name:     "tostring of number",
+			fn:       ToStringFunc,
+			input:    cty.NumberIntVal(42),
+			expected: cty.StringVal("42"),
+		},
+		{
+			name:     "tobool of string",
+			fn:       ToBoolFunc,
+			input:    cty.StringVal("true"),
+			expected: cty.True,
+		},
+		{
+			name:    "tobool of other string",
+			fn:      ToBoolFunc,
+			input:   cty.StringVal("yes"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn.Call([]cty.Value{tt.input})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %#v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.RawEquals(tt.expected) {
+				t.Errorf("got %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func ResourcesFunc(ctx *parser.ScanContext) function.Function {
 		Params: []function.Parameter{
 			{Name: "type", Type: cty.String},
 		},
-		Type: function.StaticReturnType(cty.List(cty.DynamicPseudoType)),
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
 			resourceType := args[0].AsString()
 			resources := ctx.GetResourcesByType(resourceType)
@@ -35,7 +35,7 @@ func ResourcesInFileFunc(ctx *parser.ScanContext) function.Function {
 		Params: []function.Parameter{
 			{Name: "filepath", Type: cty.String},
 		},
-		Type: function.StaticReturnType(cty.List(cty.DynamicPseudoType)),
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
 			filePath := args[0].AsString()
 			resources := ctx.GetResourcesInFile(filePath)
@@ -44,6 +44,81 @@ func ResourcesInFileFunc(ctx *parser.ScanContext) function.Function {
 	})
 }
 
+// ReferenceCountFunc returns how many times other blocks in the same module
+// reference the resource being evaluated
+func ReferenceCountFunc(ctx *parser.ScanContext) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{},
+		Type:   function.StaticReturnType(cty.Number),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if ctx.CurrentResource == nil {
+				return cty.NumberIntVal(0), nil
+			}
+			return cty.NumberIntVal(int64(ctx.ReferenceCount(ctx.CurrentResource))), nil
+		},
+	})
+}
+
+// AttachedToFunc returns resources matching a type pattern that reference,
+// or are referenced by, a resource
+func AttachedToFunc(ctx *parser.ScanContext) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "resource", Type: cty.DynamicPseudoType},
+			{Name: "type", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			resource, err := lookupResource(ctx, args[0])
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return resourcesToCty(ctx.AttachedTo(resource, args[1].AsString())), nil
+		},
+	})
+}
+
+// ReachableFromFunc returns resources matching a type pattern that a
+// resource references directly or transitively
+func ReachableFromFunc(ctx *parser.ScanContext) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "resource", Type: cty.DynamicPseudoType},
+			{Name: "type", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			resource, err := lookupResource(ctx, args[0])
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return resourcesToCty(ctx.ReachableFrom(resource, args[1].AsString())), nil
+		},
+	})
+}
+
+// lookupResource finds the block a resource value (self, or an element of
+// resources()) was built from. Blocks are identified by file and line since
+// attributes may shadow the type and name metadata.
+func lookupResource(ctx *parser.ScanContext, val cty.Value) (*config.Resource, error) {
+	if !val.IsKnown() || val.IsNull() || !val.Type().IsObjectType() ||
+		!val.Type().HasAttribute("file") || !val.Type().HasAttribute("line") {
+		return nil, fmt.Errorf("expected a resource such as self or an element of resources()")
+	}
+
+	file, line := val.GetAttr("file"), val.GetAttr("line")
+	if file.Type() != cty.String || line.Type() != cty.Number || !file.IsKnown() || !line.IsKnown() || file.IsNull() || line.IsNull() {
+		return nil, fmt.Errorf("expected a resource such as self or an element of resources()")
+	}
+
+	n, _ := line.AsBigFloat().Int64()
+	resource := ctx.ResourceAt(file.AsString(), int(n))
+	if resource == nil {
+		return nil, fmt.Errorf("no resource declared at %s:%d", file.AsString(), n)
+	}
+	return resource, nil
+}
+
 // DayOfWeekFunc returns the current day of the week
 var DayOfWeekFunc = function.New(&function.Spec{
 	Params: []function.Parameter{},
@@ -202,10 +277,113 @@ var HasFunc = function.New(&function.Spec{
 	},
 })
 
-// Helper function to convert resources to cty values
+// BlocksFunc returns the nested blocks of a given type as a list. A block
+// declared once decodes as an object and one declared several times as a
+// tuple; blocks() hides the difference so rules can always iterate.
+var BlocksFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "object",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowUnknown:     true,
+			AllowDynamicType: true,
+		},
+		{Name: "block_type", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.DynamicPseudoType),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		obj := args[0]
+		blockType := args[1].AsString()
+
+		if !obj.IsKnown() {
+			return cty.DynamicVal, nil
+		}
+		if obj.IsNull() {
+			return cty.EmptyTupleVal, nil
+		}
+
+		var blocks cty.Value
+		switch {
+		case obj.Type().IsObjectType():
+			if !obj.Type().HasAttribute(blockType) {
+				return cty.EmptyTupleVal, nil
+			}
+			blocks = obj.GetAttr(blockType)
+		case obj.Type().IsMapType():
+			if !obj.HasIndex(cty.StringVal(blockType)).True() {
+				return cty.EmptyTupleVal, nil
+			}
+			blocks = obj.Index(cty.StringVal(blockType))
+		default:
+			return cty.EmptyTupleVal, nil
+		}
+
+		if !blocks.IsKnown() {
+			return cty.DynamicVal, nil
+		}
+		if blocks.IsNull() {
+			return cty.EmptyTupleVal, nil
+		}
+
+		ty := blocks.Type()
+		if ty.IsTupleType() || ty.IsListType() || ty.IsSetType() {
+			if blocks.LengthInt() == 0 {
+				return cty.EmptyTupleVal, nil
+			}
+			return cty.TupleVal(blocks.AsValueSlice()), nil
+		}
+
+		return cty.TupleVal([]cty.Value{blocks}), nil
+	},
+})
+
+// IsNullFunc checks if a value is null. Attributes that are not set at all
+// are not null but absent; combine with try() to handle both.
+var IsNullFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowUnknown:     true,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		val := args[0]
+		if !val.IsKnown() {
+			return cty.False, nil
+		}
+		return cty.BoolVal(val.IsNull()), nil
+	},
+})
+
+// IsUnknownFunc checks if a value could not be resolved statically, e.g.
+// because it is interpolated from a variable or another resource
+var IsUnknownFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowUnknown:     true,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.BoolVal(!args[0].IsKnown()), nil
+	},
+})
+
+// Helper function to convert resources to cty values. Resources of the same
+// type rarely share an identical attribute shape, so they are returned as a
+// tuple rather than a list.
 func resourcesToCty(resources []*config.Resource) cty.Value {
 	if len(resources) == 0 {
-		return cty.ListValEmpty(cty.DynamicPseudoType)
+		return cty.EmptyTupleVal
 	}
 
 	vals := make([]cty.Value, len(resources))
@@ -213,7 +391,7 @@ func resourcesToCty(resources []*config.Resource) cty.Value {
 		vals[i] = resourceToCty(resource)
 	}
 
-	return cty.ListVal(vals)
+	return cty.TupleVal(vals)
 }
 
 // Helper function to convert a single resource to cty value
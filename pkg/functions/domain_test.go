@@ -240,3 +240,116 @@ func TestRegexMatchFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNullFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    cty.Value
+		expected bool
+	}{
+		{name: "null string", input: cty.NullVal(cty.String), expected: true},
+		{name: "null dynamic", input: cty.NullVal(cty.DynamicPseudoType), expected: true},
+		{name: "empty string", input: cty.StringVal(""), expected: false},
+		{name: "unknown", input: cty.DynamicVal, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := IsNullFunc.Call([]cty.Value{tt.input})
+			if err != nil {
+				t.Fatalf("IsNullFunc.Call() error = %v", err)
+			}
+			if result.True() != tt.expected {
+				t.Errorf("is_null(%#v) = %v, want %v", tt.input, result.True(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsUnknownFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    cty.Value
+		expected bool
+	}{
+		{name: "unknown dynamic", input: cty.DynamicVal, expected: true},
+		{name: "unknown string", input: cty.UnknownVal(cty.String), expected: true},
+		{name: "known string", input: cty.StringVal("x"), expected: false},
+		{name: "null", input: cty.NullVal(cty.String), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := IsUnknownFunc.Call([]cty.Value{tt.input})
+			if err != nil {
+				t.Fatalf("IsUnknownFunc.Call() error = %v", err)
+			}
+			if result.True() != tt.expected {
+				t.Errorf("is_unknown(%#v) = %v, want %v", tt.input, result.True(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestBlocksFunc(t *testing.T) {
+	rule := cty.ObjectVal(map[string]cty.Value{"from_port": cty.NumberIntVal(22)})
+
+	tests := []struct {
+		name     string
+		input    cty.Value
+		expected int
+		unknown  bool
+	}{
+		{
+			name:     "single block object",
+			input:    cty.ObjectVal(map[string]cty.Value{"ingress": rule}),
+			expected: 1,
+		},
+		{
+			name:     "repeated blocks tuple",
+			input:    cty.ObjectVal(map[string]cty.Value{"ingress": cty.TupleVal([]cty.Value{rule, rule})}),
+			expected: 2,
+		},
+		{
+			name:     "attribute list",
+			input:    cty.ObjectVal(map[string]cty.Value{"ingress": cty.ListVal([]cty.Value{rule})}),
+			expected: 1,
+		},
+		{
+			name:     "missing block",
+			input:    cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("web")}),
+			expected: 0,
+		},
+		{
+			name:     "null block",
+			input:    cty.ObjectVal(map[string]cty.Value{"ingress": cty.NullVal(cty.DynamicPseudoType)}),
+			expected: 0,
+		},
+		{
+			name:    "dynamic block",
+			input:   cty.ObjectVal(map[string]cty.Value{"ingress": cty.DynamicVal}),
+			unknown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := BlocksFunc.Call([]cty.Value{tt.input, cty.StringVal("ingress")})
+			if err != nil {
+				t.Fatalf("BlocksFunc.Call() error = %v", err)
+			}
+			if tt.unknown {
+				if result.IsKnown() {
+					t.Errorf("Expected unknown result, got %#v", result)
+				}
+				return
+			}
+			if !result.Type().IsTupleType() {
+				t.Fatalf("Expected tuple, got %s", result.Type().FriendlyName())
+			}
+			if result.LengthInt() != tt.expected {
+				t.Errorf("blocks() length = %d, want %d", result.LengthInt(), tt.expected)
+			}
+		})
+	}
+}
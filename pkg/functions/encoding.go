@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/url"
 
 	"github.com/zclconf/go-cty/cty"
@@ -65,6 +66,34 @@ var Base64GzipFunc = function.New(&function.Spec{
 	},
 })
 
+// Base64GunzipFunc decodes a base64 string and decompresses the gzip payload.
+// It is the inverse of base64gzip and is useful for inspecting compressed
+// user_data or cloud-init documents.
+var Base64GunzipFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		decoded, err := base64.StdEncoding.DecodeString(args[0].AsString())
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("invalid base64: %w", err)
+		}
+
+		gzipReader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("gzip decompression failed: %w", err)
+		}
+		defer gzipReader.Close()
+
+		data, err := io.ReadAll(gzipReader)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("gzip decompression failed: %w", err)
+		}
+		return cty.StringVal(string(data)), nil
+	},
+})
+
 // URLEncodeFunc URL encodes a string
 var URLEncodeFunc = function.New(&function.Spec{
 	Params: []function.Parameter{
@@ -131,6 +131,27 @@ func TestBase64GzipEmpty(t *testing.T) {
 	}
 }
 
+func TestBase64GunzipFunc(t *testing.T) {
+	compressed, err := Base64GzipFunc.Call([]cty.Value{cty.StringVal("#!/bin/bash\necho hello")})
+	if err != nil {
+		t.Fatalf("base64gzip() error: %v", err)
+	}
+
+	result, err := Base64GunzipFunc.Call([]cty.Value{compressed})
+	if err != nil {
+		t.Fatalf("base64gunzip() error: %v", err)
+	}
+	if result.AsString() != "#!/bin/bash\necho hello" {
+		t.Errorf("base64gunzip() = %q, want round-tripped input", result.AsString())
+	}
+
+	// Plain base64 that is not gzip data should fail
+	_, err = Base64GunzipFunc.Call([]cty.Value{cty.StringVal("aGVsbG8=")})
+	if err == nil {
+		t.Error("base64gunzip() should fail for non-gzip data")
+	}
+}
+
 func TestURLEncodeFunc(t *testing.T) {
 	tests := []struct {
 		name     string
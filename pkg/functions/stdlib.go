@@ -63,6 +63,11 @@ func BuildFunctions(ctx *parser.ScanContext) map[string]function.Function {
 
 	// Type conversion functions
 	functions["tonumber"] = stdlib.IntFunc // Parse to number
+	functions["tostring"] = ToStringFunc
+	functions["tobool"] = ToBoolFunc
+	functions["tolist"] = ToListFunc
+	functions["toset"] = ToSetFunc
+	functions["tomap"] = ToMapFunc
 
 	// Add HCL extension functions
 	functions["try"] = tryfunc.TryFunc
@@ -79,6 +84,7 @@ func BuildFunctions(ctx *parser.ScanContext) map[string]function.Function {
 	functions["base64encode"] = Base64EncodeFunc
 	functions["base64decode"] = Base64DecodeFunc
 	functions["base64gzip"] = Base64GzipFunc
+	functions["base64gunzip"] = Base64GunzipFunc
 	functions["urlencode"] = URLEncodeFunc
 
 	// Add custom crypto functions
@@ -98,6 +104,9 @@ func BuildFunctions(ctx *parser.ScanContext) map[string]function.Function {
 	functions["cidrsubnet"] = CIDRSubnetFunc
 	functions["cidrsubnets"] = CIDRSubnetsFunc
 
+	// Add cloud functions
+	functions["arn_parse"] = ARNParseFunc
+
 	// Add datetime functions
 	functions["timestamp"] = TimestampFunc
 	functions["formatdate"] = FormatDateFunc
@@ -106,11 +115,17 @@ func BuildFunctions(ctx *parser.ScanContext) map[string]function.Function {
 	// Add domain-specific functions
 	functions["resources"] = ResourcesFunc(ctx)
 	functions["resources_in_file"] = ResourcesInFileFunc(ctx)
+	functions["reference_count"] = ReferenceCountFunc(ctx)
+	functions["attached_to"] = AttachedToFunc(ctx)
+	functions["reachable_from"] = ReachableFromFunc(ctx)
 	functions["day_of_week"] = DayOfWeekFunc
 	functions["git_branch"] = GitBranchFunc
 	functions["has"] = HasFunc
+	functions["blocks"] = BlocksFunc
 	functions["anytrue"] = AnyTrueFunc
 	functions["alltrue"] = AllTrueFunc
+	functions["is_null"] = IsNullFunc
+	functions["is_unknown"] = IsUnknownFunc
 
 	// Add security functions
 	functions["contains_function_call"] = ContainsFunctionCallFunc(ctx)
@@ -3,8 +3,12 @@ package parser
 import (
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // ScanContext holds all parsed resources and metadata for scanning
@@ -23,6 +27,26 @@ type ScanContext struct {
 
 	// Metadata (for GitHub context, etc.)
 	Metadata map[string]interface{}
+
+	// Reference counts keyed by module directory and address
+	// (e.g. "modules/vpc" + "var.cidr")
+	references map[referenceKey]int
+
+	// Reference graph: the blocks each block refers to, and the blocks
+	// referring to it
+	dependencies map[*config.Resource][]*config.Resource
+	dependents   map[*config.Resource][]*config.Resource
+
+	// Module blocks with a local source, keyed by the directory they call
+	moduleCalls map[string][]*config.Resource
+}
+
+// referenceKey identifies a referenceable block within a Terraform module.
+// Module outputs are consumed by callers in other directories, so they are
+// also counted under an empty directory by output name.
+type referenceKey struct {
+	dir     string
+	address string
 }
 
 // NewScanContext creates a new scan context from resources
@@ -40,28 +64,329 @@ func NewScanContext(resources []*config.Resource) *ScanContext {
 		ctx.ResourcesByFile[resource.File] = append(ctx.ResourcesByFile[resource.File], resource)
 	}
 
+	ctx.references = countReferences(resources)
+	ctx.buildGraph()
+	ctx.moduleCalls = indexModuleCalls(resources)
+
 	return ctx
 }
 
+// indexModuleCalls maps the directories of local module sources (e.g.
+// "./modules/vpc") to the module blocks calling them
+func indexModuleCalls(resources []*config.Resource) map[string][]*config.Resource {
+	calls := make(map[string][]*config.Resource)
+	for _, resource := range resources {
+		if resource.Kind != config.KindModule {
+			continue
+		}
+		source, ok := resource.Attributes["source"]
+		if !ok || !source.IsKnown() || source.IsNull() || source.Type() != cty.String {
+			continue
+		}
+		path := source.AsString()
+		if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+			continue
+		}
+		dir := filepath.Clean(filepath.Join(filepath.Dir(resource.File), path))
+		calls[dir] = append(calls[dir], resource)
+	}
+	return calls
+}
+
+// Addresses returns the Terraform addresses of resource, such as
+// aws_s3_bucket.logs. Resources in a directory that a scanned module block
+// calls through a local source are addressed through each call, e.g.
+// module.storage.aws_s3_bucket.logs; a module called twice yields two
+// addresses. The result is sorted, and empty for blocks without an address.
+func (ctx *ScanContext) Addresses(resource *config.Resource) []string {
+	address := blockAddress(resource)
+	if address == "" {
+		return nil
+	}
+
+	var addresses []string
+	for _, prefix := range ctx.modulePrefixes(filepath.Dir(resource.File), map[string]bool{}) {
+		addresses = append(addresses, prefix+address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// modulePrefixes returns the module address prefixes (e.g.
+// "module.network.module.vpc.") under which dir is instantiated. visiting
+// guards against modules that call themselves.
+func (ctx *ScanContext) modulePrefixes(dir string, visiting map[string]bool) []string {
+	calls := ctx.moduleCalls[dir]
+	if len(calls) == 0 || visiting[dir] {
+		return []string{""}
+	}
+
+	visiting[dir] = true
+	defer delete(visiting, dir)
+
+	var prefixes []string
+	for _, call := range calls {
+		for _, parent := range ctx.modulePrefixes(filepath.Dir(call.File), visiting) {
+			prefixes = append(prefixes, parent+"module."+call.Name+".")
+		}
+	}
+	return prefixes
+}
+
+// ReferenceCount returns how many references other blocks in the same
+// Terraform module make to resource. Outputs also count references from
+// module callers anywhere in the scan (module.<name>.<output>), since the
+// calling module's source cannot be resolved statically.
+func (ctx *ScanContext) ReferenceCount(resource *config.Resource) int {
+	address := blockAddress(resource)
+	if address == "" {
+		return 0
+	}
+
+	count := ctx.references[referenceKey{dir: filepath.Dir(resource.File), address: address}]
+	if resource.Kind == config.KindOutput {
+		count += ctx.references[referenceKey{address: address}]
+	}
+	return count
+}
+
+// ResourceAt returns the block declared at the given file and line, or nil
+func (ctx *ScanContext) ResourceAt(file string, line int) *config.Resource {
+	for _, resource := range ctx.ResourcesByFile[file] {
+		if resource.Line == line {
+			return resource
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the blocks resource refers to directly
+func (ctx *ScanContext) Dependencies(resource *config.Resource) []*config.Resource {
+	return ctx.dependencies[resource]
+}
+
+// Dependents returns the blocks that refer to resource directly
+func (ctx *ScanContext) Dependents(resource *config.Resource) []*config.Resource {
+	return ctx.dependents[resource]
+}
+
+// AttachedTo returns the blocks matching typePattern that refer to
+// resource or that resource refers to, e.g. the route table associations of
+// a subnet. Type patterns behave as in GetResourcesByType.
+func (ctx *ScanContext) AttachedTo(resource *config.Resource, typePattern string) []*config.Resource {
+	matches := typeMatcher(typePattern)
+	seen := make(map[*config.Resource]bool)
+
+	var attached []*config.Resource
+	for _, neighbors := range [][]*config.Resource{ctx.dependencies[resource], ctx.dependents[resource]} {
+		for _, neighbor := range neighbors {
+			if !seen[neighbor] && matches(neighbor) {
+				seen[neighbor] = true
+				attached = append(attached, neighbor)
+			}
+		}
+	}
+
+	sortByLocation(attached)
+	return attached
+}
+
+// ReachableFrom returns the blocks matching typePattern that resource
+// refers to directly or through other blocks, e.g. the internet gateway a
+// route table association reaches through its route table. Only outgoing
+// references are followed; combine with AttachedTo to step back along a
+// reference.
+func (ctx *ScanContext) ReachableFrom(resource *config.Resource, typePattern string) []*config.Resource {
+	matches := typeMatcher(typePattern)
+	visited := map[*config.Resource]bool{resource: true}
+	queue := []*config.Resource{resource}
+
+	var reachable []*config.Resource
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range ctx.dependencies[current] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+			if matches(next) {
+				reachable = append(reachable, next)
+			}
+		}
+	}
+
+	sortByLocation(reachable)
+	return reachable
+}
+
+// buildGraph records an edge from every block to each block it refers to
+// within the same Terraform module. Module outputs (module.<name>.<output>)
+// are edges to the module block.
+func (ctx *ScanContext) buildGraph() {
+	ctx.dependencies = make(map[*config.Resource][]*config.Resource)
+	ctx.dependents = make(map[*config.Resource][]*config.Resource)
+
+	byAddress := make(map[referenceKey]*config.Resource)
+	for _, resource := range ctx.AllResources {
+		if address := blockAddress(resource); address != "" {
+			byAddress[referenceKey{dir: filepath.Dir(resource.File), address: address}] = resource
+		}
+	}
+
+	for _, resource := range ctx.AllResources {
+		dir := filepath.Dir(resource.File)
+		seen := make(map[*config.Resource]bool)
+
+		for _, expr := range resource.RawExprs {
+			for _, traversal := range expr.Variables() {
+				address, _ := traversalAddress(traversal)
+				target := byAddress[referenceKey{dir: dir, address: address}]
+				if target == nil || target == resource || seen[target] {
+					continue
+				}
+				seen[target] = true
+				ctx.dependencies[resource] = append(ctx.dependencies[resource], target)
+				ctx.dependents[target] = append(ctx.dependents[target], resource)
+			}
+		}
+	}
+
+	// RawExprs is a map, so sort edges for stable results
+	for _, edges := range []map[*config.Resource][]*config.Resource{ctx.dependencies, ctx.dependents} {
+		for _, resources := range edges {
+			sortByLocation(resources)
+		}
+	}
+}
+
+// sortByLocation orders resources by file and line
+func sortByLocation(resources []*config.Resource) {
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].File != resources[j].File {
+			return resources[i].File < resources[j].File
+		}
+		return resources[i].Line < resources[j].Line
+	})
+}
+
+// countReferences counts the references made from every block's
+// expressions. A block referring to itself (e.g. a variable validation
+// using var.<name>) is not counted.
+func countReferences(resources []*config.Resource) map[referenceKey]int {
+	counts := make(map[referenceKey]int)
+
+	for _, resource := range resources {
+		dir := filepath.Dir(resource.File)
+		self := blockAddress(resource)
+
+		for _, expr := range resource.RawExprs {
+			for _, traversal := range expr.Variables() {
+				address, output := traversalAddress(traversal)
+				if address == "" || address == self {
+					continue
+				}
+				counts[referenceKey{dir: dir, address: address}]++
+				if output != "" {
+					counts[referenceKey{address: "output." + output}]++
+				}
+			}
+		}
+	}
+
+	return counts
+}
+
+// blockAddress returns the address other blocks use to refer to resource
+// (e.g. "var.region", "local.tags", or "data.aws_ami.base"), or "" for blocks
+// that cannot be referenced. Outputs use "output.<name>".
+func blockAddress(resource *config.Resource) string {
+	switch resource.Kind {
+	case "", config.KindResource:
+		return resource.Type + "." + resource.Name
+	case config.KindData:
+		return "data." + resource.Type + "." + resource.Name
+	case config.KindVariable:
+		return "var." + resource.Name
+	case config.KindLocal:
+		return "local." + resource.Name
+	case config.KindModule:
+		return "module." + resource.Name
+	case config.KindOutput:
+		return "output." + resource.Name
+	default:
+		return ""
+	}
+}
+
+// traversalAddress returns the block address a traversal refers to and, for
+// module outputs (module.<name>.<output>), the output name
+func traversalAddress(traversal hcl.Traversal) (string, string) {
+	var names []string
+	for _, step := range traversal {
+		if root, ok := step.(hcl.TraverseRoot); ok {
+			names = append(names, root.Name)
+		} else if attr, ok := step.(hcl.TraverseAttr); ok {
+			names = append(names, attr.Name)
+		} else {
+			break
+		}
+	}
+
+	if len(names) < 2 {
+		return "", ""
+	}
+
+	switch names[0] {
+	case "path", "terraform", "each", "count", "self":
+		return "", ""
+	case "var", "local":
+		return strings.Join(names[:2], "."), ""
+	case "module":
+		if len(names) >= 3 {
+			return strings.Join(names[:2], "."), names[2]
+		}
+		return strings.Join(names[:2], "."), ""
+	case "data":
+		if len(names) < 3 {
+			return "", ""
+		}
+		return strings.Join(names[:3], "."), ""
+	default:
+		return strings.Join(names[:2], "."), ""
+	}
+}
+
 // GetResourcesByType returns all resources matching a type pattern
 func (ctx *ScanContext) GetResourcesByType(typePattern string) []*config.Resource {
 	var matched []*config.Resource
 
-	// Check for wildcard
+	// Check for wildcard. Variables, checks, outputs, and other
+	// non-infrastructure blocks are only matched by their exact type so
+	// wildcard rules keep targeting infrastructure.
 	if typePattern == "*" {
-		return ctx.AllResources
+		for _, resource := range ctx.AllResources {
+			if matchesWildcard(resource) {
+				matched = append(matched, resource)
+			}
+		}
+		return matched
 	}
 
 	// Check for pattern matching (e.g., "aws_*")
-	if regexp.MustCompile(`[*?]`).MatchString(typePattern) {
-		pattern := "^" + regexp.QuoteMeta(typePattern)
-		pattern = regexp.MustCompile(`\\\*`).ReplaceAllString(pattern, ".*")
-		pattern = regexp.MustCompile(`\\\?`).ReplaceAllString(pattern, ".")
-		re := regexp.MustCompile(pattern + "$")
+	if isTypePattern(typePattern) {
+		re := typePatternRegexp(typePattern)
 
 		for resourceType, resources := range ctx.ResourcesByType {
-			if re.MatchString(resourceType) {
-				matched = append(matched, resources...)
+			if !re.MatchString(resourceType) {
+				continue
+			}
+			for _, resource := range resources {
+				if matchesWildcard(resource) {
+					matched = append(matched, resource)
+				}
 			}
 		}
 		return matched
@@ -71,6 +396,47 @@ func (ctx *ScanContext) GetResourcesByType(typePattern string) []*config.Resourc
 	return ctx.ResourcesByType[typePattern]
 }
 
+// isTypePattern reports whether typePattern contains wildcards
+func isTypePattern(typePattern string) bool {
+	return strings.ContainsAny(typePattern, "*?")
+}
+
+// typePatternRegexp compiles a wildcard type pattern such as "aws_*"
+func typePatternRegexp(typePattern string) *regexp.Regexp {
+	pattern := "^" + regexp.QuoteMeta(typePattern)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile(pattern + "$")
+}
+
+// typeMatcher returns a predicate selecting the resources
+// GetResourcesByType would return for typePattern
+func typeMatcher(typePattern string) func(*config.Resource) bool {
+	if typePattern == "*" {
+		return matchesWildcard
+	}
+	if isTypePattern(typePattern) {
+		re := typePatternRegexp(typePattern)
+		return func(resource *config.Resource) bool {
+			return re.MatchString(resource.Type) && matchesWildcard(resource)
+		}
+	}
+	return func(resource *config.Resource) bool {
+		return resource.Type == typePattern
+	}
+}
+
+// matchesWildcard reports whether resource can be selected by a wildcard
+// type pattern. Only managed resources and data sources qualify.
+func matchesWildcard(resource *config.Resource) bool {
+	switch resource.Kind {
+	case "", config.KindResource, config.KindData:
+		return true
+	default:
+		return false
+	}
+}
+
 // GetResourcesInFile returns all resources in a specific file
 func (ctx *ScanContext) GetResourcesInFile(filePath string) []*config.Resource {
 	return ctx.ResourcesByFile[filePath]
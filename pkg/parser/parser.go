@@ -1,12 +1,16 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/jonathanhle/planguard/pkg/config"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -14,6 +18,7 @@ import (
 // Parser handles parsing of Terraform files
 type Parser struct {
 	hclParser *hclparse.Parser
+	progress  func(done, total int)
 }
 
 // NewParser creates a new parser instance
@@ -40,12 +45,31 @@ func (p *Parser) ParseFile(path string) (*hcl.File, error) {
 
 // ParseDirectory recursively parses all .tf files in a directory
 func (p *Parser) ParseDirectory(dir string, excludePatterns []string) (map[string]*hcl.File, error) {
-	files := make(map[string]*hcl.File)
+	return p.ParseDirectoryContext(context.Background(), dir, excludePatterns)
+}
+
+// ParseDirectoryContext is ParseDirectory, stopping with ctx's error once
+// ctx is done
+func (p *Parser) ParseDirectoryContext(ctx context.Context, dir string, excludePatterns []string) (map[string]*hcl.File, error) {
+	paths, err := ListFiles(ctx, dir, excludePatterns)
+	if err != nil {
+		return make(map[string]*hcl.File), err
+	}
+	return p.ParseFiles(ctx, paths)
+}
+
+// ListFiles recursively lists the .tf files in a directory without parsing
+// them, skipping excluded directories and files
+func ListFiles(ctx context.Context, dir string, excludePatterns []string) ([]string, error) {
+	var paths []string
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		if info.IsDir() {
 			// Check if directory should be excluded
@@ -70,19 +94,72 @@ func (p *Parser) ParseDirectory(dir string, excludePatterns []string) (map[strin
 			}
 		}
 
+		paths = append(paths, path)
+		return nil
+	})
+
+	return paths, err
+}
+
+// SetProgress sets a function called after each file ParseFiles parses,
+// with the number of files parsed so far and the number to parse
+func (p *Parser) SetProgress(progress func(done, total int)) {
+	p.progress = progress
+}
+
+// ParseFiles parses the Terraform files at paths, stopping with ctx's error
+// once ctx is done
+func (p *Parser) ParseFiles(ctx context.Context, paths []string) (map[string]*hcl.File, error) {
+	files := make(map[string]*hcl.File, len(paths))
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
 		file, err := p.ParseFile(path)
 		if err != nil {
-			return fmt.Errorf("failed to parse %s: %w", path, err)
+			return files, fmt.Errorf("failed to parse %s: %w", path, err)
 		}
-
 		files[path] = file
-		return nil
-	})
+		if p.progress != nil {
+			p.progress(i+1, len(paths))
+		}
+	}
+	return files, nil
+}
 
-	return files, err
+// Batches splits files into batches of about size files, so a large
+// configuration can be parsed and scanned part by part. The files of a
+// directory, which form one Terraform module, are kept in the same batch;
+// a directory with more than size files makes a batch of its own.
+func Batches(files []string, size int) [][]string {
+	var dirs []string
+	byDir := make(map[string][]string)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+
+	var batches [][]string
+	var batch []string
+	for _, dir := range dirs {
+		if len(batch) > 0 && len(batch)+len(byDir[dir]) > size {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batch = append(batch, byDir[dir]...)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
 }
 
-// ExtractResources extracts all resources from parsed HCL files
+// ExtractResources extracts all resources from parsed HCL files. Resources
+// and data sources also get provider_alias and provider_region attributes
+// describing the provider configuration they use.
 func ExtractResources(files map[string]*hcl.File) ([]*config.Resource, error) {
 	var resources []*config.Resource
 
@@ -94,9 +171,72 @@ func ExtractResources(files map[string]*hcl.File) ([]*config.Resource, error) {
 		resources = append(resources, fileResources...)
 	}
 
+	resolveProviders(resources)
 	return resources, nil
 }
 
+// ExtractRequiredProviders returns the provider version constraints declared
+// in terraform required_providers blocks, ordered by file and line
+func ExtractRequiredProviders(files map[string]*hcl.File) []config.ProviderRequirement {
+	var requirements []config.ProviderRequirement
+
+	for path, file := range files {
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+		})
+		for _, terraform := range content.Blocks {
+			inner, _, _ := terraform.Body.PartialContent(&hcl.BodySchema{
+				Blocks: []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+			})
+			for _, block := range inner.Blocks {
+				attrs, diags := block.Body.JustAttributes()
+				if diags.HasErrors() {
+					continue
+				}
+				for name, attr := range attrs {
+					req := config.ProviderRequirement{
+						Name: name,
+						File: path,
+						Line: attr.Range.Start.Line,
+					}
+
+					// Either aws = "~> 5.0" or aws = { source = ..., version = ... }
+					val := evaluateStatic(attr.Expr)
+					switch {
+					case !val.IsWhollyKnown() || val.IsNull():
+						continue
+					case val.Type() == cty.String:
+						req.Version = val.AsString()
+					case val.Type().IsObjectType():
+						for _, field := range []struct {
+							name   string
+							target *string
+						}{{"source", &req.Source}, {"version", &req.Version}} {
+							if val.Type().HasAttribute(field.name) {
+								if v := val.GetAttr(field.name); !v.IsNull() && v.Type() == cty.String {
+									*field.target = v.AsString()
+								}
+							}
+						}
+					default:
+						continue
+					}
+
+					requirements = append(requirements, req)
+				}
+			}
+		}
+	}
+
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].File != requirements[j].File {
+			return requirements[i].File < requirements[j].File
+		}
+		return requirements[i].Line < requirements[j].Line
+	})
+	return requirements
+}
+
 func extractResourcesFromFile(file *hcl.File, path string) ([]*config.Resource, error) {
 	var resources []*config.Resource
 
@@ -110,6 +250,29 @@ func extractResourcesFromFile(file *hcl.File, path string) ([]*config.Resource,
 				Type:       "data",
 				LabelNames: []string{"type", "name"},
 			},
+			{
+				Type:       "variable",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       "check",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       "output",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       "module",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       "provider",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type: "locals",
+			},
 		},
 	})
 
@@ -118,13 +281,13 @@ func extractResourcesFromFile(file *hcl.File, path string) ([]*config.Resource,
 	}
 
 	for _, block := range content.Blocks {
-		if block.Type != "resource" && block.Type != "data" {
+		if block.Type == "locals" {
+			resources = append(resources, extractLocals(block, path)...)
 			continue
 		}
 
 		resource := &config.Resource{
-			Type:       block.Labels[0],
-			Name:       block.Labels[1],
+			Kind:       block.Type,
 			File:       path,
 			Line:       block.DefRange.Start.Line,
 			Column:     block.DefRange.Start.Column,
@@ -133,17 +296,34 @@ func extractResourcesFromFile(file *hcl.File, path string) ([]*config.Resource,
 			RawExprs:   make(map[string]hcl.Expression),
 		}
 
-		// Extract attributes
-		attrs, diags := block.Body.JustAttributes()
-		if !diags.HasErrors() {
-			for name, attr := range attrs {
-				// Store raw expression for function call detection
-				resource.RawExprs[name] = attr.Expr
+		switch block.Type {
+		case config.KindResource, config.KindData:
+			resource.Type = block.Labels[0]
+			resource.Name = block.Labels[1]
+		case config.KindVariable, config.KindCheck, config.KindOutput, config.KindModule, config.KindProvider:
+			// Other blocks are addressed by kind and name (e.g.
+			// variable.region), so their kind doubles as their type
+			resource.Type = block.Type
+			resource.Name = block.Labels[0]
+		default:
+			continue
+		}
+
+		// Extract attributes and nested blocks
+		if body, ok := block.Body.(*hclsyntax.Body); ok {
+			extractBody(body, "", resource.Attributes, resource.RawExprs)
 
-				// Also evaluate and store the value
-				val, diags := attr.Expr.Value(nil)
-				if !diags.HasErrors() {
-					resource.Attributes[name] = val
+			// Record what a check asserts on so rules can skip resources
+			// the configuration already self-enforces
+			if block.Type == config.KindCheck {
+				resource.Attributes["references"] = referencesValue(body)
+			}
+		} else {
+			attrs, diags := block.Body.JustAttributes()
+			if !diags.HasErrors() {
+				for name, attr := range attrs {
+					resource.RawExprs[name] = attr.Expr
+					resource.Attributes[name] = evaluateStatic(attr.Expr)
 				}
 			}
 		}
@@ -153,3 +333,178 @@ func extractResourcesFromFile(file *hcl.File, path string) ([]*config.Resource,
 
 	return resources, nil
 }
+
+// extractLocals returns one resource per local value declared in a locals
+// block, with the value's expression stored as its "value" attribute
+func extractLocals(block *hcl.Block, path string) []*config.Resource {
+	attrs, diags := block.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil
+	}
+
+	var locals []*config.Resource
+	for name, attr := range attrs {
+		locals = append(locals, &config.Resource{
+			Kind:       config.KindLocal,
+			Type:       config.KindLocal,
+			Name:       name,
+			File:       path,
+			Line:       attr.Range.Start.Line,
+			Column:     attr.Range.Start.Column,
+			Labels:     []string{name},
+			Attributes: map[string]cty.Value{"value": evaluateStatic(attr.Expr)},
+			RawExprs:   map[string]hcl.Expression{"value": attr.Expr},
+		})
+	}
+
+	// Attribute order from JustAttributes is random; keep source order
+	sort.Slice(locals, func(i, j int) bool {
+		return locals[i].Line < locals[j].Line
+	})
+	return locals
+}
+
+// referencesValue returns the addresses of resources and data sources
+// referenced anywhere in body (e.g. "aws_s3_bucket.logs" or
+// "data.aws_iam_policy.admin") as a sorted list of strings.
+func referencesValue(body *hclsyntax.Body) cty.Value {
+	seen := make(map[string]bool)
+	var refs []string
+
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(hclsyntax.Expression)
+		if !ok {
+			return nil
+		}
+		for _, traversal := range expr.Variables() {
+			if ref := referenceAddress(traversal); ref != "" && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+		return nil
+	})
+
+	if len(refs) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+
+	sort.Strings(refs)
+	vals := make([]cty.Value, len(refs))
+	for i, ref := range refs {
+		vals[i] = cty.StringVal(ref)
+	}
+	return cty.ListVal(vals)
+}
+
+// referenceAddress returns the resource or data source address a traversal
+// points at, or "" for references to variables, locals, and other values
+func referenceAddress(traversal hcl.Traversal) string {
+	var names []string
+	for _, step := range traversal {
+		// Stop at the first index step (e.g. aws_instance.web[0])
+		if root, ok := step.(hcl.TraverseRoot); ok {
+			names = append(names, root.Name)
+		} else if attr, ok := step.(hcl.TraverseAttr); ok {
+			names = append(names, attr.Name)
+		} else {
+			break
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	switch names[0] {
+	case "var", "local", "module", "path", "terraform", "each", "count", "self":
+		return ""
+	case "data":
+		if len(names) < 3 {
+			return ""
+		}
+		return strings.Join(names[:3], ".")
+	default:
+		if len(names) < 2 {
+			return ""
+		}
+		return strings.Join(names[:2], ".")
+	}
+}
+
+// extractBody stores the attributes and nested blocks of body in attrs.
+// Raw expressions are recorded in rawExprs under their path (e.g.
+// "ingress[0].cidr_blocks") so function call detection also covers
+// nested blocks.
+//
+// A block type that appears once decodes as an object and one that appears
+// several times as a tuple of objects, matching how rules typically access
+// them (self.versioning.enabled). Rules that need a uniform shape can use
+// the blocks() function. Dynamic blocks cannot be expanded statically and
+// are recorded as unknown.
+func extractBody(body *hclsyntax.Body, prefix string, attrs map[string]cty.Value, rawExprs map[string]hcl.Expression) {
+	for name, attr := range body.Attributes {
+		// Store raw expression for function call detection
+		rawExprs[prefix+name] = attr.Expr
+
+		// Also evaluate and store the value
+		attrs[name] = evaluateStatic(attr.Expr)
+	}
+
+	grouped := make(map[string][]cty.Value)
+	var order []string
+	for _, nested := range body.Blocks {
+		blockType := nested.Type
+		nestedBody := nested.Body
+
+		if nested.Type == "dynamic" && len(nested.Labels) > 0 {
+			blockType = nested.Labels[0]
+			if _, seen := grouped[blockType]; !seen {
+				order = append(order, blockType)
+			}
+			grouped[blockType] = append(grouped[blockType], cty.DynamicVal)
+			for name, attr := range nestedBody.Attributes {
+				rawExprs[fmt.Sprintf("%s%s[%d].%s", prefix, blockType, len(grouped[blockType])-1, name)] = attr.Expr
+			}
+			for _, content := range nestedBody.Blocks {
+				extractBody(content.Body, fmt.Sprintf("%s%s[%d].", prefix, blockType, len(grouped[blockType])-1), make(map[string]cty.Value), rawExprs)
+			}
+			continue
+		}
+
+		if _, seen := grouped[blockType]; !seen {
+			order = append(order, blockType)
+		}
+		nestedAttrs := make(map[string]cty.Value)
+		extractBody(nestedBody, fmt.Sprintf("%s%s[%d].", prefix, blockType, len(grouped[blockType])), nestedAttrs, rawExprs)
+		grouped[blockType] = append(grouped[blockType], cty.ObjectVal(nestedAttrs))
+	}
+
+	for _, blockType := range order {
+		// Attributes win over blocks of the same name
+		if _, exists := attrs[blockType]; exists {
+			continue
+		}
+
+		values := grouped[blockType]
+		switch {
+		case len(values) == 1 && values[0].IsKnown():
+			attrs[blockType] = values[0]
+		case len(values) == 1:
+			attrs[blockType] = cty.DynamicVal
+		default:
+			attrs[blockType] = cty.TupleVal(values)
+		}
+	}
+}
+
+// evaluateStatic evaluates an expression without any variables or
+// functions. Expressions that depend on variables, other resources, or
+// functions cannot be resolved statically and are recorded as unknown.
+func evaluateStatic(expr hcl.Expression) cty.Value {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.DynamicVal
+	}
+	return val
+}
@@ -1,12 +1,16 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestNewParser(t *testing.T) {
@@ -122,6 +126,46 @@ resource "aws_s3_bucket" "example" {
 	}
 }
 
+func TestParseFilesProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.tf", "b.tf", "c.tf"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(`resource "aws_s3_bucket" "example" {}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var calls [][2]int
+	p := NewParser()
+	p.SetProgress(func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	if _, err := p.ParseFiles(context.Background(), paths); err != nil {
+		t.Fatalf("ParseFiles() error = %v", err)
+	}
+
+	want := [][2]int{{1, 3}, {2, 3}, {3, 3}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress calls = %v, want %v", calls, want)
+	}
+}
+
+func TestParseDirectoryContextCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(`resource "aws_s3_bucket" "example" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewParser().ParseDirectoryContext(ctx, tmpDir, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseDirectoryContext() error = %v, want context.Canceled", err)
+	}
+}
+
 func TestParseDirectoryWithExcludes(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -172,6 +216,83 @@ func TestParseDirectoryEmpty(t *testing.T) {
 	}
 }
 
+func TestListFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.tf", "README.md", "modules/vpc/main.tf", ".terraform/modules/cached.tf"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`resource "aws_vpc" "main" {}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := ListFiles(context.Background(), tmpDir, []string{".terraform"})
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "main.tf"), filepath.Join(tmpDir, "modules/vpc/main.tf")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("ListFiles() = %v, want %v", paths, want)
+	}
+
+	files, err := NewParser().ParseFiles(context.Background(), paths)
+	if err != nil {
+		t.Fatalf("ParseFiles() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 parsed files, got %d", len(files))
+	}
+}
+
+func TestBatches(t *testing.T) {
+	files := []string{"a/main.tf", "a/sub/main.tf", "a/variables.tf", "b/main.tf", "c/main.tf", "c/outputs.tf", "c/variables.tf", "d/main.tf"}
+
+	tests := []struct {
+		size int
+		want [][]string
+	}{
+		{
+			size: 3,
+			want: [][]string{
+				{"a/main.tf", "a/variables.tf", "a/sub/main.tf"},
+				{"b/main.tf"},
+				{"c/main.tf", "c/outputs.tf", "c/variables.tf"},
+				{"d/main.tf"},
+			},
+		},
+		{
+			size: 4,
+			want: [][]string{
+				{"a/main.tf", "a/variables.tf", "a/sub/main.tf", "b/main.tf"},
+				{"c/main.tf", "c/outputs.tf", "c/variables.tf", "d/main.tf"},
+			},
+		},
+		{
+			// Directories are never split
+			size: 1,
+			want: [][]string{
+				{"a/main.tf", "a/variables.tf"},
+				{"a/sub/main.tf"},
+				{"b/main.tf"},
+				{"c/main.tf", "c/outputs.tf", "c/variables.tf"},
+				{"d/main.tf"},
+			},
+		},
+		{
+			size: 100,
+			want: [][]string{{"a/main.tf", "a/variables.tf", "a/sub/main.tf", "b/main.tf", "c/main.tf", "c/outputs.tf", "c/variables.tf", "d/main.tf"}},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Batches(files, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Batches(size %d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
 func TestExtractResources(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.tf")
@@ -398,3 +519,507 @@ func TestMatchesPath(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractResourcesUnresolvableAttributes(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+resource "aws_s3_bucket" "logs" {
+  bucket = var.bucket_name
+  acl    = "private"
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	resources, err := ExtractResources(files)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+
+	bucket, ok := resources[0].Attributes["bucket"]
+	if !ok {
+		t.Fatal("Attribute referencing a variable should be present")
+	}
+	if bucket.IsKnown() {
+		t.Errorf("Attribute referencing a variable should be unknown, got %#v", bucket)
+	}
+	if acl := resources[0].Attributes["acl"]; !acl.IsKnown() || acl.AsString() != "private" {
+		t.Errorf("Literal attribute should be known, got %#v", acl)
+	}
+}
+
+func TestExtractResourcesNestedBlocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+resource "aws_security_group" "web" {
+  name = "web"
+
+  ingress {
+    from_port   = 443
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  ingress {
+    from_port   = 22
+    cidr_blocks = ["10.0.0.0/8"]
+    description = nonsensitive(var.secret)
+  }
+
+  egress {
+    from_port = 0
+  }
+
+  dynamic "tag" {
+    for_each = var.tags
+    content {
+      key = tag.key
+    }
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	resources, err := ExtractResources(files)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+
+	attrs := resources[0].Attributes
+
+	if name := attrs["name"]; name.AsString() != "web" {
+		t.Errorf("Attributes alongside nested blocks should be extracted, got %#v", name)
+	}
+
+	ingress := attrs["ingress"]
+	if !ingress.Type().IsTupleType() || ingress.LengthInt() != 2 {
+		t.Fatalf("Repeated blocks should decode as a tuple of 2, got %#v", ingress)
+	}
+	first := ingress.Index(cty.NumberIntVal(0))
+	if first.GetAttr("cidr_blocks").Index(cty.NumberIntVal(0)).AsString() != "0.0.0.0/0" {
+		t.Errorf("Nested block attributes should be extracted, got %#v", first)
+	}
+
+	egress := attrs["egress"]
+	if !egress.Type().IsObjectType() {
+		t.Errorf("Single block should decode as an object, got %#v", egress)
+	}
+
+	if tag := attrs["tag"]; tag.IsKnown() {
+		t.Errorf("Dynamic blocks should be unknown, got %#v", tag)
+	}
+
+	if _, ok := resources[0].RawExprs["ingress[1].description"]; !ok {
+		t.Errorf("Nested raw expressions should be recorded by path, got keys %v", rawExprKeys(resources[0]))
+	}
+	if _, ok := resources[0].RawExprs["tag[0].key"]; !ok {
+		t.Errorf("Dynamic block content expressions should be recorded, got keys %v", rawExprKeys(resources[0]))
+	}
+}
+
+func rawExprKeys(resource *config.Resource) []string {
+	var keys []string
+	for k := range resource.RawExprs {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestExtractResourcesVariablesAndChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+variable "environment" {
+  type = string
+
+  validation {
+    condition     = contains(["dev", "prod"], var.environment)
+    error_message = "Unknown environment."
+  }
+}
+
+variable "region" {
+  default = "us-east-1"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+check "logs_versioned" {
+  data "aws_s3_bucket" "current" {
+    bucket = aws_s3_bucket.logs.id
+  }
+
+  assert {
+    condition     = aws_s3_bucket.logs.versioning[0].enabled && var.region != ""
+    error_message = "Logs bucket must be versioned."
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	resources, err := ExtractResources(files)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+
+	ctx := NewScanContext(resources)
+
+	variables := ctx.GetResourcesByType("variable")
+	if len(variables) != 2 {
+		t.Fatalf("Expected 2 variables, got %d", len(variables))
+	}
+	for _, v := range variables {
+		if v.Kind != config.KindVariable {
+			t.Errorf("Variable %s should have kind %q, got %q", v.Name, config.KindVariable, v.Kind)
+		}
+		_, hasValidation := v.Attributes["validation"]
+		if v.Name == "environment" && !hasValidation {
+			t.Error("Variable validation block should be extracted")
+		}
+		if v.Name == "region" && hasValidation {
+			t.Error("Variable without validation should not have a validation attribute")
+		}
+	}
+
+	checks := ctx.GetResourcesByType("check")
+	if len(checks) != 1 {
+		t.Fatalf("Expected 1 check, got %d", len(checks))
+	}
+	check := checks[0]
+	if check.Name != "logs_versioned" {
+		t.Errorf("Check name = %s, want logs_versioned", check.Name)
+	}
+	if _, ok := check.RawExprs["assert[0].condition"]; !ok {
+		t.Errorf("Assert expressions should be recorded, got keys %v", rawExprKeys(check))
+	}
+
+	var refs []string
+	for _, ref := range check.Attributes["references"].AsValueSlice() {
+		refs = append(refs, ref.AsString())
+	}
+	if len(refs) != 1 || refs[0] != "aws_s3_bucket.logs" {
+		t.Errorf("Check references = %v, want [aws_s3_bucket.logs]", refs)
+	}
+
+	// Wildcards keep targeting infrastructure only
+	if all := ctx.GetResourcesByType("*"); len(all) != 1 {
+		t.Errorf("Expected * to match only the bucket, got %d resources", len(all))
+	}
+}
+
+func TestReferenceCount(t *testing.T) {
+	tmpDir := t.TempDir()
+	moduleDir := filepath.Join(tmpDir, "modules", "bucket")
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	root := `
+variable "region" {}
+
+variable "unused" {
+  validation {
+    condition     = var.unused != ""
+    error_message = "Must be set."
+  }
+}
+
+locals {
+  name  = "logs-${var.region}"
+  stale = "x"
+}
+
+provider "aws" {
+  region = var.region
+}
+
+module "bucket" {
+  source = "./modules/bucket"
+  name   = local.name
+}
+
+resource "aws_s3_bucket" "orphan" {
+  bucket = "orphan"
+}
+
+output "bucket_arn" {
+  value = module.bucket.arn
+}
+`
+	module := `
+variable "name" {}
+
+resource "aws_s3_bucket" "this" {
+  bucket = var.name
+}
+
+output "arn" {
+  value = aws_s3_bucket.this.arn
+}
+
+output "id" {
+  value = aws_s3_bucket.this.id
+}
+
+# Same variable name as the root module; references must not cross modules
+variable "stale" {}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(root), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte(module), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	resources, err := ExtractResources(files)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+	ctx := NewScanContext(resources)
+
+	tests := []struct {
+		dir   string
+		typ   string
+		name  string
+		count int
+	}{
+		{dir: tmpDir, typ: "variable", name: "region", count: 2},
+		{dir: tmpDir, typ: "variable", name: "unused", count: 0},
+		{dir: tmpDir, typ: "local", name: "name", count: 1},
+		{dir: tmpDir, typ: "local", name: "stale", count: 0},
+		{dir: tmpDir, typ: "module", name: "bucket", count: 1},
+		{dir: tmpDir, typ: "aws_s3_bucket", name: "orphan", count: 0},
+		{dir: tmpDir, typ: "output", name: "bucket_arn", count: 0},
+		{dir: moduleDir, typ: "variable", name: "name", count: 1},
+		{dir: moduleDir, typ: "variable", name: "stale", count: 0},
+		{dir: moduleDir, typ: "aws_s3_bucket", name: "this", count: 2},
+		{dir: moduleDir, typ: "output", name: "arn", count: 1},
+		{dir: moduleDir, typ: "output", name: "id", count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+"."+tt.name, func(t *testing.T) {
+			var found *config.Resource
+			for _, resource := range ctx.GetResourcesByType(tt.typ) {
+				if resource.Name == tt.name && filepath.Dir(resource.File) == tt.dir {
+					found = resource
+				}
+			}
+			if found == nil {
+				t.Fatalf("%s.%s not found in %s", tt.typ, tt.name, tt.dir)
+			}
+			if got := ctx.ReferenceCount(found); got != tt.count {
+				t.Errorf("ReferenceCount() = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
+
+func TestReferenceGraph(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+resource "aws_vpc" "main" {}
+
+resource "aws_internet_gateway" "gw" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_route_table" "public" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block = "0.0.0.0/0"
+    gateway_id = aws_internet_gateway.gw.id
+  }
+}
+
+resource "aws_subnet" "public" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_route_table_association" "public" {
+  subnet_id      = aws_subnet.public.id
+  route_table_id = aws_route_table.public.id
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	resources, err := ExtractResources(files)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+	ctx := NewScanContext(resources)
+
+	get := func(typ string) *config.Resource {
+		t.Helper()
+		found := ctx.GetResourcesByType(typ)
+		if len(found) != 1 {
+			t.Fatalf("Expected one %s, got %d", typ, len(found))
+		}
+		return found[0]
+	}
+	names := func(resources []*config.Resource) []string {
+		var out []string
+		for _, r := range resources {
+			out = append(out, r.Type+"."+r.Name)
+		}
+		return out
+	}
+
+	subnet := get("aws_subnet")
+	association := get("aws_route_table_association")
+
+	tests := []struct {
+		name string
+		got  []*config.Resource
+		want []string
+	}{
+		{
+			name: "dependencies",
+			got:  ctx.Dependencies(association),
+			want: []string{"aws_route_table.public", "aws_subnet.public"},
+		},
+		{
+			name: "dependents",
+			got:  ctx.Dependents(subnet),
+			want: []string{"aws_route_table_association.public"},
+		},
+		{
+			name: "attached in both directions",
+			got:  ctx.AttachedTo(subnet, "*"),
+			want: []string{"aws_vpc.main", "aws_route_table_association.public"},
+		},
+		{
+			name: "attached filtered by type",
+			got:  ctx.AttachedTo(subnet, "aws_route_table_association"),
+			want: []string{"aws_route_table_association.public"},
+		},
+		{
+			name: "reachable through route table",
+			got:  ctx.ReachableFrom(association, "aws_internet_gateway"),
+			want: []string{"aws_internet_gateway.gw"},
+		},
+		{
+			name: "reachable with pattern",
+			got:  ctx.ReachableFrom(association, "aws_*"),
+			want: []string{"aws_vpc.main", "aws_internet_gateway.gw", "aws_route_table.public", "aws_subnet.public"},
+		},
+		{
+			name: "references are not followed backwards",
+			got:  ctx.ReachableFrom(subnet, "aws_internet_gateway"),
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(tt.got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if ctx.ResourceAt(subnet.File, subnet.Line) != subnet {
+		t.Error("ResourceAt() should find the subnet by file and line")
+	}
+}
+
+func TestExtractRequiredProviders(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+terraform {
+  required_version = ">= 1.5"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+    legacy = "~> 2.1"
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "versions.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	path := filepath.Join(tmpDir, "versions.tf")
+	want := []config.ProviderRequirement{
+		{Name: "aws", Source: "hashicorp/aws", Version: "~> 5.0", File: path, Line: 6},
+		{Name: "random", Source: "hashicorp/random", File: path, Line: 10},
+		{Name: "legacy", Version: "~> 2.1", File: path, Line: 13},
+	}
+	if got := ExtractRequiredProviders(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractRequiredProviders() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	module := func(name, file, source string) *config.Resource {
+		return &config.Resource{Kind: config.KindModule, Type: config.KindModule, Name: name, File: file, Attributes: map[string]cty.Value{"source": cty.StringVal(source)}}
+	}
+	bucket := &config.Resource{Kind: config.KindResource, Type: "aws_s3_bucket", Name: "logs", File: "modules/bucket/main.tf"}
+	root := &config.Resource{Kind: config.KindResource, Type: "aws_s3_bucket", Name: "root", File: "main.tf"}
+	lookup := &config.Resource{Kind: config.KindData, Type: "aws_caller_identity", Name: "current", File: "modules/storage/data.tf"}
+
+	ctx := NewScanContext([]*config.Resource{
+		module("storage", "main.tf", "./modules/storage"),
+		module("logs", "modules/storage/main.tf", "../bucket"),
+		module("archive", "main.tf", "./modules/bucket"),
+		module("registry", "main.tf", "terraform-aws-modules/s3-bucket/aws"),
+		bucket, root, lookup,
+	})
+
+	tests := []struct {
+		resource *config.Resource
+		want     []string
+	}{
+		{root, []string{"aws_s3_bucket.root"}},
+		{lookup, []string{"module.storage.data.aws_caller_identity.current"}},
+		{bucket, []string{"module.archive.aws_s3_bucket.logs", "module.storage.module.logs.aws_s3_bucket.logs"}},
+	}
+
+	for _, tt := range tests {
+		if got := ctx.Addresses(tt.resource); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Addresses(%s.%s) = %v, want %v", tt.resource.Type, tt.resource.Name, got, tt.want)
+		}
+	}
+}
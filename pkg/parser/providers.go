@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Attributes recorded on resources and data sources for the provider
+// configuration they use
+const (
+	ProviderAliasAttribute  = "provider_alias"
+	ProviderRegionAttribute = "provider_region"
+)
+
+// providerKey identifies a provider configuration within a module directory
+type providerKey struct {
+	dir, name, alias string
+}
+
+// resolveProviders records on each resource and data source the alias of
+// the provider configuration it uses ("" for the default configuration)
+// and that configuration's region. Provider blocks are looked up in the
+// resource's own directory. The region is unknown unless the provider sets
+// it to a literal, or to a variable with a literal default, e.g. when the
+// configuration is passed in from a calling module.
+func resolveProviders(resources []*config.Resource) {
+	providers := make(map[providerKey]*config.Resource)
+	variables := make(map[string]*config.Resource)
+	for _, r := range resources {
+		dir := filepath.Dir(r.File)
+		switch r.Kind {
+		case config.KindProvider:
+			alias, _ := stringAttr(r.Attributes["alias"])
+			providers[providerKey{dir, r.Name, alias}] = r
+		case config.KindVariable:
+			variables[dir+"\x00"+r.Name] = r
+		}
+	}
+
+	for _, r := range resources {
+		if r.Kind != config.KindResource && r.Kind != config.KindData {
+			continue
+		}
+		dir := filepath.Dir(r.File)
+		name, alias := providerRef(r)
+
+		region := cty.UnknownVal(cty.String)
+		if provider, ok := providers[providerKey{dir, name, alias}]; ok {
+			region = providerRegion(provider, variables, dir)
+		}
+		r.Attributes[ProviderAliasAttribute] = cty.StringVal(alias)
+		r.Attributes[ProviderRegionAttribute] = region
+	}
+}
+
+// providerRef returns the local name and alias of the provider a resource
+// uses: its provider meta-argument (e.g. aws.us_east_1), or by default the
+// prefix of its type (aws for aws_s3_bucket)
+func providerRef(r *config.Resource) (name, alias string) {
+	if expr, ok := r.RawExprs["provider"]; ok {
+		if traversal, diags := hcl.AbsTraversalForExpr(expr); !diags.HasErrors() && len(traversal) > 0 {
+			name = traversal.RootName()
+			if len(traversal) > 1 {
+				if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+					alias = attr.Name
+				}
+			}
+			return name, alias
+		}
+	}
+
+	name, _, _ = strings.Cut(r.Type, "_")
+	return name, ""
+}
+
+// providerRegion returns a provider block's region when it is a literal or
+// a variable with a literal default, and unknown otherwise
+func providerRegion(provider *config.Resource, variables map[string]*config.Resource, dir string) cty.Value {
+	if region, ok := stringAttr(provider.Attributes["region"]); ok {
+		return cty.StringVal(region)
+	}
+
+	if expr, ok := provider.RawExprs["region"]; ok {
+		traversal, diags := hcl.AbsTraversalForExpr(expr)
+		if !diags.HasErrors() && len(traversal) == 2 && traversal.RootName() == "var" {
+			if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+				if variable, ok := variables[dir+"\x00"+attr.Name]; ok {
+					if region, ok := stringAttr(variable.Attributes["default"]); ok {
+						return cty.StringVal(region)
+					}
+				}
+			}
+		}
+	}
+
+	return cty.UnknownVal(cty.String)
+}
+
+// stringAttr returns the value of a known, non-null string attribute
+func stringAttr(v cty.Value) (string, bool) {
+	if v == cty.NilVal || !v.IsKnown() || v.IsNull() || v.Type() != cty.String {
+		return "", false
+	}
+	return v.AsString(), true
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestResolveProviders(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"providers.tf": `
+variable "dr_region" {
+  default = "eu-west-1"
+}
+
+variable "region" {}
+
+provider "aws" {
+  region = "us-west-2"
+}
+
+provider "aws" {
+  alias  = "us_east_1"
+  region = "us-east-1"
+}
+
+provider "aws" {
+  alias  = "dr"
+  region = var.dr_region
+}
+
+provider "aws" {
+  alias  = "dynamic"
+  region = var.region
+}
+`,
+		"main.tf": `
+resource "aws_s3_bucket" "data" {}
+
+resource "aws_acm_certificate" "cdn" {
+  provider = aws.us_east_1
+}
+
+data "aws_ami" "backup" {
+  provider = aws.dr
+}
+
+resource "aws_s3_bucket" "dynamic" {
+  provider = aws.dynamic
+}
+
+resource "aws_s3_bucket" "undeclared" {
+  provider = aws.missing
+}
+`,
+		// Modules receive provider configurations from their callers
+		"modules/cdn/main.tf": `
+resource "aws_cloudfront_distribution" "site" {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parsed, err := NewParser().ParseDirectory(tmpDir, []string{})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	resources, err := ExtractResources(parsed)
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+
+	unknown := cty.UnknownVal(cty.String)
+	tests := []struct {
+		name       string
+		wantAlias  string
+		wantRegion cty.Value
+	}{
+		{name: "data", wantAlias: "", wantRegion: cty.StringVal("us-west-2")},
+		{name: "cdn", wantAlias: "us_east_1", wantRegion: cty.StringVal("us-east-1")},
+		{name: "backup", wantAlias: "dr", wantRegion: cty.StringVal("eu-west-1")},
+		{name: "dynamic", wantAlias: "dynamic", wantRegion: unknown},
+		{name: "undeclared", wantAlias: "missing", wantRegion: unknown},
+		{name: "site", wantAlias: "", wantRegion: unknown},
+	}
+
+	byName := make(map[string]int)
+	for i, r := range resources {
+		byName[r.Name] = i
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("Resource %s not extracted", tt.name)
+			}
+			attrs := resources[i].Attributes
+			if got := attrs[ProviderAliasAttribute]; !got.RawEquals(cty.StringVal(tt.wantAlias)) {
+				t.Errorf("provider_alias = %#v, want %q", got, tt.wantAlias)
+			}
+			if got := attrs[ProviderRegionAttribute]; !got.RawEquals(tt.wantRegion) {
+				t.Errorf("provider_region = %#v, want %#v", got, tt.wantRegion)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,245 @@
+// Package plan reads Terraform plans in the JSON form printed by
+// `terraform show -json` and merges their planned values into resources
+// parsed from the configuration's source.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Resource is a resource instance the plan will create, update, or keep
+type Resource struct {
+	Address string // e.g. module.vpc.aws_subnet.private[0]
+	Mode    string // "managed" or "data"
+	Type    string
+	Name    string
+	Values  cty.Value // Planned attribute values; unknown attributes are absent
+}
+
+// Plan holds the resource instances of a plan
+type Plan struct {
+	Resources []Resource
+}
+
+// planJSON mirrors the parts of `terraform show -json` output that are read
+type planJSON struct {
+	FormatVersion string `json:"format_version"`
+	PlannedValues *struct {
+		RootModule moduleJSON `json:"root_module"`
+	} `json:"planned_values"`
+}
+
+type moduleJSON struct {
+	Resources    []resourceJSON `json:"resources"`
+	ChildModules []moduleJSON   `json:"child_modules"`
+}
+
+type resourceJSON struct {
+	Address string          `json:"address"`
+	Mode    string          `json:"mode"`
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Values  json.RawMessage `json:"values"`
+}
+
+// Load reads a plan from a JSON file
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses a plan from `terraform show -json` output
+func Parse(data []byte) (*Plan, error) {
+	var raw planJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+	if raw.FormatVersion == "" || raw.PlannedValues == nil {
+		return nil, fmt.Errorf("not a Terraform plan: expected the output of terraform show -json with planned_values")
+	}
+
+	p := &Plan{}
+	if err := p.addModule(raw.PlannedValues.RootModule); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plan) addModule(module moduleJSON) error {
+	for _, r := range module.Resources {
+		values := cty.EmptyObjectVal
+		if len(r.Values) > 0 && string(r.Values) != "null" {
+			ty, err := ctyjson.ImpliedType(r.Values)
+			if err != nil {
+				return fmt.Errorf("failed to read values of %s: %w", r.Address, err)
+			}
+			values, err = ctyjson.Unmarshal(r.Values, ty)
+			if err != nil {
+				return fmt.Errorf("failed to read values of %s: %w", r.Address, err)
+			}
+		}
+		p.Resources = append(p.Resources, Resource{
+			Address: r.Address,
+			Mode:    r.Mode,
+			Type:    r.Type,
+			Name:    r.Name,
+			Values:  values,
+		})
+	}
+	for _, child := range module.ChildModules {
+		if err := p.addModule(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instanceKeys matches the instance keys in an address, e.g. the [0] in
+// module.vpc[0].aws_subnet.private["a"]
+var instanceKeys = regexp.MustCompile(`\[[^\]]*\]`)
+
+// blockAddress returns the address of the block that declares an instance,
+// e.g. module.vpc.aws_subnet.private for module.vpc[0].aws_subnet.private["a"]
+func blockAddress(address string) string {
+	return instanceKeys.ReplaceAllString(address, "")
+}
+
+// Merge returns resources with planned values from p. Each resource or data
+// block found in the plan becomes one resource per planned instance, keeping
+// the block's file and line so violations point at the source, with its
+// attributes replaced by the planned values the plan knows. Blocks the plan
+// does not mention are kept as they are. Planned instances without a block
+// in the scanned source, e.g. from remote modules, are added with planPath
+// as their file.
+func (p *Plan) Merge(resources []*config.Resource, planPath string) []*config.Resource {
+	byAddress := make(map[string][]Resource)
+	for _, r := range p.Resources {
+		address := blockAddress(r.Address)
+		byAddress[address] = append(byAddress[address], r)
+	}
+
+	ctx := parser.NewScanContext(resources)
+	matched := make(map[string]bool)
+
+	var merged []*config.Resource
+	for _, resource := range resources {
+		if resource.Kind != config.KindResource && resource.Kind != config.KindData {
+			merged = append(merged, resource)
+			continue
+		}
+
+		var instances []Resource
+		for _, address := range ctx.Addresses(resource) {
+			if matched[address] {
+				continue
+			}
+			if found, ok := byAddress[address]; ok {
+				instances = append(instances, found...)
+				matched[address] = true
+			}
+		}
+		if len(instances) == 0 {
+			merged = append(merged, resource)
+			continue
+		}
+
+		for _, instance := range instances {
+			merged = append(merged, withPlannedValues(resource, instance.Values))
+		}
+	}
+
+	for _, r := range p.Resources {
+		if matched[blockAddress(r.Address)] {
+			continue
+		}
+		kind := config.KindResource
+		if r.Mode == "data" {
+			kind = config.KindData
+		}
+		merged = append(merged, withPlannedValues(&config.Resource{
+			Kind:       kind,
+			Type:       r.Type,
+			Name:       r.Name,
+			File:       planPath,
+			Labels:     []string{r.Type, r.Name},
+			Attributes: make(map[string]cty.Value),
+		}, r.Values))
+	}
+
+	return merged
+}
+
+// withPlannedValues copies resource with its attributes overlaid by the
+// known, non-null planned values
+func withPlannedValues(resource *config.Resource, values cty.Value) *config.Resource {
+	attrs := make(map[string]cty.Value, len(resource.Attributes))
+	for name, value := range resource.Attributes {
+		attrs[name] = value
+	}
+
+	if values.Type().IsObjectType() {
+		for name, value := range values.AsValueMap() {
+			if value.IsNull() {
+				continue
+			}
+			attrs[name] = normalize(value, attrs[name])
+		}
+	}
+
+	merged := *resource
+	merged.Attributes = attrs
+	return &merged
+}
+
+// normalize shapes a planned value like the parser shapes source: plans
+// list nested blocks, while a single block in source is an object. A list
+// holding one object is unwrapped unless the source value is a list too.
+func normalize(planned, source cty.Value) cty.Value {
+	if !planned.IsKnown() || planned.IsNull() {
+		return planned
+	}
+
+	ty := planned.Type()
+	switch {
+	case ty.IsTupleType() && planned.LengthInt() == 1:
+		element := planned.Index(cty.NumberIntVal(0))
+		sourceIsList := isKnown(source) && (source.Type().IsTupleType() || source.Type().IsListType())
+		if element.Type().IsObjectType() && !sourceIsList {
+			return normalize(element, source)
+		}
+		return planned
+
+	case ty.IsObjectType():
+		var sourceAttrs map[string]cty.Value
+		if isKnown(source) && source.Type().IsObjectType() {
+			sourceAttrs = source.AsValueMap()
+		}
+		attrs := planned.AsValueMap()
+		if len(attrs) == 0 {
+			return planned
+		}
+		for name, value := range attrs {
+			attrs[name] = normalize(value, sourceAttrs[name])
+		}
+		return cty.ObjectVal(attrs)
+
+	default:
+		return planned
+	}
+}
+
+// isKnown reports whether v is set, known, and not null
+func isKnown(v cty.Value) bool {
+	return v != cty.NilVal && v.IsKnown() && !v.IsNull()
+}
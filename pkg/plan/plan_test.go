@@ -0,0 +1,166 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testPlan = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_s3_bucket.logs",
+          "mode": "managed",
+          "type": "aws_s3_bucket",
+          "name": "logs",
+          "values": {"bucket": "prod-logs", "acl": "public-read", "versioning": [{"enabled": true}], "policy": null}
+        },
+        {
+          "address": "aws_instance.web[0]",
+          "mode": "managed",
+          "type": "aws_instance",
+          "name": "web",
+          "index": 0,
+          "values": {"instance_type": "t3.micro", "metadata_options": [{"http_tokens": "required"}]}
+        },
+        {
+          "address": "aws_instance.web[1]",
+          "mode": "managed",
+          "type": "aws_instance",
+          "name": "web",
+          "index": 1,
+          "values": {"instance_type": "m5.large", "metadata_options": [{"http_tokens": "optional"}]}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.remote",
+          "resources": [
+            {
+              "address": "module.remote.aws_sqs_queue.jobs",
+              "mode": "managed",
+              "type": "aws_sqs_queue",
+              "name": "jobs",
+              "values": {"name": "jobs"}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+const testSource = `
+resource "aws_s3_bucket" "logs" {
+  bucket = "${var.env}-logs"
+  acl    = var.acl
+
+  versioning {
+    enabled = true
+  }
+}
+
+resource "aws_instance" "web" {
+  count         = 2
+  instance_type = var.instance_type
+
+  metadata_options {
+    http_tokens = var.http_tokens
+  }
+}
+
+resource "aws_iam_role" "unplanned" {
+  name = "ci"
+}
+
+variable "env" {}
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testPlan))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(p.Resources) != 4 {
+		t.Fatalf("Parse() returned %d resources, want 4", len(p.Resources))
+	}
+	if got := p.Resources[3].Address; got != "module.remote.aws_sqs_queue.jobs" {
+		t.Errorf("child module resource address = %q", got)
+	}
+
+	for _, invalid := range []string{`{`, `{"resources": []}`} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("Parse(%s) should fail", invalid)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	p, err := Parse([]byte(testPlan))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL([]byte(testSource), "main.tf")
+	if diags.HasErrors() {
+		t.Fatalf("ParseHCL() error = %v", diags)
+	}
+	resources, err := parser.ExtractResources(map[string]*hcl.File{"main.tf": file})
+	if err != nil {
+		t.Fatalf("ExtractResources() error = %v", err)
+	}
+
+	merged := p.Merge(resources, "plan.json")
+
+	byType := make(map[string][]*config.Resource)
+	for _, r := range merged {
+		byType[r.Type] = append(byType[r.Type], r)
+	}
+
+	// Planned values replace values unknown in source, at the source location
+	bucket := byType["aws_s3_bucket"][0]
+	if bucket.File != "main.tf" || bucket.Line != 2 {
+		t.Errorf("bucket location = %s:%d, want main.tf:2", bucket.File, bucket.Line)
+	}
+	if got := bucket.Attributes["acl"]; !got.RawEquals(cty.StringVal("public-read")) {
+		t.Errorf("bucket acl = %#v, want public-read from the plan", got)
+	}
+	if _, ok := bucket.Attributes["policy"]; ok {
+		t.Error("null planned values should not add attributes")
+	}
+	if got := bucket.Attributes["versioning"]; !got.Type().IsObjectType() || !got.GetAttr("enabled").True() {
+		t.Errorf("bucket versioning = %#v, want a single block object", got)
+	}
+	if len(bucket.RawExprs) == 0 {
+		t.Error("raw expressions from source should be kept")
+	}
+
+	// Each planned instance of a counted block is its own resource
+	instances := byType["aws_instance"]
+	if len(instances) != 2 {
+		t.Fatalf("got %d aws_instance resources, want one per planned instance", len(instances))
+	}
+	for i, want := range []string{"required", "optional"} {
+		got := instances[i].Attributes["metadata_options"].GetAttr("http_tokens")
+		if !got.RawEquals(cty.StringVal(want)) || instances[i].Line != 11 || instances[i].Name != "web" {
+			t.Errorf("instance %d = %s.%s at line %d with http_tokens %#v, want %q at line 11", i, instances[i].Type, instances[i].Name, instances[i].Line, got, want)
+		}
+	}
+
+	// Blocks missing from the plan are kept; planned resources missing from
+	// source are added at the plan file
+	if len(byType["aws_iam_role"]) != 1 || len(byType["variable"]) != 1 {
+		t.Error("blocks missing from the plan should be kept")
+	}
+	queue := byType["aws_sqs_queue"]
+	if len(queue) != 1 || queue[0].File != "plan.json" || queue[0].Kind != config.KindResource {
+		t.Errorf("aws_sqs_queue = %+v, want one resource from plan.json", queue)
+	}
+}
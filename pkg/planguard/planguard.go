@@ -0,0 +1,384 @@
+// Package planguard is the API for embedding planguard in other Go tools.
+// It loads configuration and rules the way the planguard command does, and
+// scans directories of Terraform with them:
+//
+//	pg, err := planguard.New(planguard.WithConfigFile(".planguard/config.hcl"), planguard.WithFormat("sarif"))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := pg.ScanDirectory(ctx, "./terraform")
+//	if err != nil {
+//		return err
+//	}
+//	report, err := result.Report()
+//
+// Config files are loaded as the planguard command loads a single config,
+// with its exception files, profiles, rule aliases, and severity map, but
+// without config layers, rule packs, rule_sources, or exception ticket
+// validation. Exception budgets and fail_on_expired_exceptions decide the
+// planguard command's exit code, not ShouldFail.
+//
+// The packages under pkg/ that this package builds on remain importable for
+// finer control, but this package is the one whose API is kept stable.
+package planguard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+// Formats accepted by WithFormat
+const (
+	FormatText      = "text"
+	FormatJSON      = "json"
+	FormatJSONV2    = "json-v2"
+	FormatSARIF     = "sarif"
+	FormatOneline   = "oneline"
+	FormatMarkdown  = "markdown"
+	FormatTAP       = "tap"
+	FormatTable     = "table"
+	FormatBadge     = "badge"
+	FormatPRComment = "pr-comment"
+)
+
+// Option configures a Planguard
+type Option func(*options)
+
+type options struct {
+	configFile  string
+	profile     string
+	config      *config.Config
+	rulesDir    string
+	presupplied *bool
+	categories  []string
+	rules       []config.Rule
+	format      string
+	concurrency int
+	mutators    []scanner.ResourceMutator
+	processors  []scanner.ViolationProcessor
+}
+
+// WithConfigFile loads settings, rules, exceptions, and functions from an
+// HCL config file, plus the exception files in the exceptions directory
+// next to it. Unlike the planguard command, no config file is looked up by
+// default and the file is not merged with organization or user config
+// layers. A config with rule_sources is rejected.
+func WithConfigFile(path string) Option {
+	return func(o *options) { o.configFile = path }
+}
+
+// WithProfile applies the config's profile with this name, as the
+// planguard command's -profile flag does
+func WithProfile(name string) Option {
+	return func(o *options) { o.profile = name }
+}
+
+// WithConfig uses an already loaded config in place of a config file
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) { o.config = cfg }
+}
+
+// WithRulesDir loads presupplied rules from a rules directory instead of
+// the rules built into planguard
+func WithRulesDir(dir string) Option {
+	return func(o *options) { o.rulesDir = dir }
+}
+
+// WithPresuppliedRules turns the presupplied rules on or off, overriding
+// the config's use_presupplied_rules. They are on by default.
+func WithPresuppliedRules(enabled bool) Option {
+	return func(o *options) { o.presupplied = &enabled }
+}
+
+// WithCategories limits the presupplied rules to categories, e.g. "aws" or
+// "security", overriding the config's presupplied_rules_categories
+func WithCategories(categories ...string) Option {
+	return func(o *options) { o.categories = categories }
+}
+
+// WithRules adds rules to those from the config. As with rules defined in a
+// config file, presupplied rules are then not loaded.
+func WithRules(rules ...config.Rule) Option {
+	return func(o *options) { o.rules = append(o.rules, rules...) }
+}
+
+// WithFormat sets the format Result.Report renders: text (the default),
+// json, json-v2, sarif, oneline, markdown, tap, table, badge, or pr-comment
+func WithFormat(format string) Option {
+	return func(o *options) { o.format = format }
+}
+
+// WithConcurrency sets how many rules are evaluated in parallel (default:
+// number of CPUs)
+func WithConcurrency(n int) Option {
+	return func(o *options) { o.concurrency = n }
+}
+
+// WithResourceMutator registers a mutator to run at the start of each scan;
+// see scanner.ResourceMutator
+func WithResourceMutator(mutator scanner.ResourceMutator) Option {
+	return func(o *options) { o.mutators = append(o.mutators, mutator) }
+}
+
+// WithViolationProcessor registers a processor to run at the end of each
+// scan; see scanner.ViolationProcessor
+func WithViolationProcessor(processor scanner.ViolationProcessor) Option {
+	return func(o *options) { o.processors = append(o.processors, processor) }
+}
+
+// Planguard scans Terraform with a loaded configuration. It is safe to use
+// for several scans, including concurrent ones.
+type Planguard struct {
+	config *config.Config
+	opts   options
+}
+
+// New loads the configuration and rules described by opts
+func New(opts ...Option) (*Planguard, error) {
+	o := options{format: FormatText}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch o.format {
+	case FormatText, FormatJSON, FormatJSONV2, FormatSARIF, FormatOneline, FormatMarkdown, FormatTAP, FormatTable, FormatBadge, FormatPRComment:
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected text, json, json-v2, sarif, oneline, markdown, tap, table, badge, or pr-comment)", o.format)
+	}
+
+	cfg, err := loadConfig(o)
+	if err != nil {
+		return nil, err
+	}
+	return &Planguard{config: cfg, opts: o}, nil
+}
+
+// loadConfig loads the config and its rules. Presupplied rules are only
+// loaded when neither the config nor WithRules define any, as with the
+// planguard command and a single config file. Settings that need the
+// planguard command's plumbing are rejected rather than silently ignored.
+func loadConfig(o options) (*config.Config, error) {
+	var cfg config.Config
+	switch {
+	case o.config != nil:
+		cfg = *o.config
+	case o.configFile != "":
+		loaded, err := config.LoadConfig(o.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", o.configFile, err)
+		}
+		cfg = *loaded
+		// Exception files next to the config apply, as they do for the
+		// planguard command
+		exceptions, err := config.LoadExceptionsDir(filepath.Join(filepath.Dir(o.configFile), config.ExceptionsDir))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Exceptions = append(append([]config.Exception(nil), cfg.Exceptions...), exceptions...)
+	}
+
+	if cfg.Settings == nil {
+		usePresupplied := true
+		cfg.Settings = &config.Settings{
+			ExcludePaths:        []string{"**/.terraform/**", "**/node_modules/**"},
+			UsePresuppliedRules: &usePresupplied,
+		}
+	}
+	if len(cfg.Settings.RuleSources) > 0 {
+		return nil, fmt.Errorf("rule_sources are not supported when embedding planguard; load their rules yourself and pass them with WithRules")
+	}
+	settings := *cfg.Settings
+	if o.profile != "" {
+		active, err := cfg.FindProfile(o.profile)
+		if err != nil {
+			return nil, err
+		}
+		active.Apply(&settings)
+		cfg.ActiveProfile = active
+	}
+	if o.presupplied != nil {
+		settings.UsePresuppliedRules = o.presupplied
+	}
+	if len(o.categories) > 0 {
+		settings.PresuppliedRulesCategories = o.categories
+	}
+	cfg.Settings = &settings
+
+	cfg.Rules = append(append([]config.Rule(nil), cfg.Rules...), o.rules...)
+	usePresupplied := settings.UsePresuppliedRules == nil || *settings.UsePresuppliedRules
+	if len(cfg.Rules) == 0 && usePresupplied {
+		rules, err := loadPresuppliedRules(o.rulesDir, settings.PresuppliedRulesCategories)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rules = rules
+	}
+	if err := checkUnknown(&cfg); err != nil {
+		return nil, err
+	}
+
+	// Former IDs of renamed rules resolve before rules are filtered. Unknown
+	// IDs in enabled_rules or disabled_rules, like problems with aliases,
+	// only earn a warning from the planguard command, so they are not an
+	// error here.
+	cfg.Aliases, _ = config.RuleAliases(cfg.Rules)
+	cfg.Aliases.ResolveConfig(&cfg)
+	cfg.Rules, _ = config.FilterRules(cfg.Rules, cfg.Settings)
+
+	if settings.SeverityMap != nil && *settings.SeverityMap != "" {
+		severityMap, err := config.LoadSeverityMap(*settings.SeverityMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load severity map: %w", err)
+		}
+		cfg.Aliases.ResolveSeverities(severityMap.Rules, "severity map "+*settings.SeverityMap)
+		cfg.Rules = severityMap.Apply(cfg.Rules)
+	}
+	if cfg.ActiveProfile != nil {
+		cfg.Rules = cfg.ActiveProfile.ApplySeverities(cfg.Rules)
+	}
+	return &cfg, nil
+}
+
+// checkUnknown fails with strict = true in settings when the config or the
+// files its rules came from have attributes or blocks planguard does not
+// know. Otherwise they are left in the config's and rules' Unknown for the
+// embedder to report, as the planguard command warns about them.
+func checkUnknown(cfg *config.Config) error {
+	if !cfg.Settings.Strict {
+		return nil
+	}
+	unknown := append(hcl.Diagnostics(nil), cfg.Unknown...)
+	seen := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		if !seen[rule.Source] {
+			seen[rule.Source] = true
+			unknown = append(unknown, rule.Unknown...)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	for i, diag := range unknown {
+		strict := *diag
+		strict.Severity = hcl.DiagError
+		unknown[i] = &strict
+	}
+	return fmt.Errorf("unknown attributes or blocks with strict = true: %w", unknown)
+}
+
+// loadPresuppliedRules loads the presupplied rules in categories from
+// rulesDir, or from the rules built into planguard when it is empty
+func loadPresuppliedRules(rulesDir string, categories []string) ([]config.Rule, error) {
+	if rulesDir == "" {
+		rules, err := config.LoadDefaultRulesFS(embeddedrules.FS, categories)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load embedded presupplied rules: %w", err)
+		}
+		return rules, nil
+	}
+
+	if _, err := os.Stat(rulesDir); err != nil {
+		return nil, fmt.Errorf("rules directory not found: %s", rulesDir)
+	}
+	rules, err := config.LoadDefaultRulesWithCategories(rulesDir, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load presupplied rules from %s: %w", rulesDir, err)
+	}
+	return rules, nil
+}
+
+// Config returns the loaded configuration, including the rules scans use.
+// It must not be modified.
+func (p *Planguard) Config() *config.Config {
+	return p.config
+}
+
+// ScanDirectory parses the Terraform files in dir and scans them. Files
+// matching the config's exclude_paths are skipped.
+func (p *Planguard) ScanDirectory(ctx context.Context, dir string) (*Result, error) {
+	files, err := parser.NewParser().ParseDirectoryContext(ctx, dir, p.config.Settings.ExcludePaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+
+	resources, err := parser.ExtractResources(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract resources: %w", err)
+	}
+	return p.ScanResources(ctx, resources)
+}
+
+// ScanResources scans resources that have already been extracted, e.g.
+// with parser.ExtractResources
+func (p *Planguard) ScanResources(ctx context.Context, resources []*config.Resource) (*Result, error) {
+	s := scanner.NewScanner(p.config, p.config.Rules, parser.NewScanContext(resources))
+	if p.opts.concurrency > 0 {
+		s.SetConcurrency(p.opts.concurrency)
+	}
+	for _, mutator := range p.opts.mutators {
+		s.AddResourceMutator(mutator)
+	}
+	for _, processor := range p.opts.processors {
+		s.AddViolationProcessor(processor)
+	}
+
+	result, err := s.ScanWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan: %w", err)
+	}
+	return &Result{ScanResult: result, format: p.opts.format, rules: p.config.Rules}, nil
+}
+
+// Result is the result of a scan
+type Result struct {
+	*scanner.ScanResult
+	format string
+	rules  []config.Rule // The rules scanned with, for TAP reports
+}
+
+// Report renders the result in the format set with WithFormat
+func (r *Result) Report() (string, error) {
+	rep := reporter.NewReporter(r.Violations, r.FilteredViolations)
+	rep.SetRules(r.rules)
+	switch r.format {
+	case FormatJSON:
+		return rep.FormatJSON()
+	case FormatJSONV2:
+		return rep.FormatJSONV2()
+	case FormatSARIF:
+		return rep.FormatSARIF()
+	case FormatOneline:
+		return rep.FormatOneline(r.Resources), nil
+	case FormatMarkdown:
+		return rep.FormatMarkdown(), nil
+	case FormatTAP:
+		return rep.FormatTAP(r.rules, r.EvaluatedRules()), nil
+	case FormatTable:
+		return rep.FormatTable(), nil
+	case FormatBadge:
+		return rep.FormatBadge()
+	case FormatPRComment:
+		return rep.FormatPRComment(), nil
+	default:
+		return rep.FormatText(), nil
+	}
+}
+
+// ShouldFail reports whether the result has violations at or above the
+// failOn severity (error, warning, or info), as the planguard command's
+// -fail-on flag decides its exit code
+func (r *Result) ShouldFail(failOn string) bool {
+	return reporter.NewReporter(r.Violations, r.FilteredViolations).ShouldFail(failOn)
+}
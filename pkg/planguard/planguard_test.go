@@ -0,0 +1,298 @@
+package planguard
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+const terraform = `
+resource "aws_s3_bucket" "data" {
+  bucket = "data"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+  tags = {
+    Environment = "prod"
+  }
+}
+`
+
+const configFile = `
+rule "bucket_tags" {
+  name          = "Buckets must be tagged"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+  message = "Bucket has no tags"
+}
+`
+
+func TestScanDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(terraform), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(t.TempDir(), "config.hcl")
+	if err := os.WriteFile(cfgPath, []byte(configFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	named := config.Rule{
+		ID:           "bucket_name",
+		Name:         "Buckets are named after their purpose",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: `self.bucket == "data"`}},
+		Message:      "Bucket is called data",
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantRules  []string // IDs of the violated rules, in order
+		wantFail   string   // A -fail-on severity the result fails at
+		wantReport string
+	}{
+		{
+			name:       "config file rules replace presupplied rules",
+			opts:       []Option{WithConfigFile(cfgPath)},
+			wantRules:  []string{"bucket_tags"},
+			wantFail:   "warning",
+			wantReport: "Bucket has no tags",
+		},
+		{
+			name:       "rules from options",
+			opts:       []Option{WithRules(named), WithFormat(FormatOneline)},
+			wantRules:  []string{"bucket_name"},
+			wantFail:   "error",
+			wantReport: "planguard: 1 errors, 0 warnings, 0 info, 2 resources",
+		},
+		{
+			name:      "config and option rules combine",
+			opts:      []Option{WithConfigFile(cfgPath), WithRules(named)},
+			wantRules: []string{"bucket_name", "bucket_tags"},
+		},
+		{
+			name:       "presupplied rules turned off",
+			opts:       []Option{WithPresuppliedRules(false)},
+			wantReport: "No violations found",
+		},
+		{
+			name: "violation processor",
+			opts: []Option{WithRules(named), WithViolationProcessor(scanner.ViolationProcessorFunc(func(ctx context.Context, result *scanner.ScanResult) error {
+				result.Violations = nil
+				return nil
+			}))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, err := New(tt.opts...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			result, err := pg.ScanDirectory(context.Background(), dir)
+			if err != nil {
+				t.Fatalf("ScanDirectory() error = %v", err)
+			}
+
+			var got []string
+			for _, v := range result.Violations {
+				got = append(got, v.RuleID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantRules, ",") {
+				t.Errorf("violated rules = %v, want %v", got, tt.wantRules)
+			}
+			if tt.wantFail != "" && !result.ShouldFail(tt.wantFail) {
+				t.Errorf("ShouldFail(%q) = false, want true", tt.wantFail)
+			}
+
+			report, err := result.Report()
+			if err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+			if !strings.Contains(report, tt.wantReport) {
+				t.Errorf("Report() = %q, want it to contain %q", report, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestNewPresuppliedRules(t *testing.T) {
+	pg, err := New(WithCategories("aws"), WithFormat(FormatJSON))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if len(pg.Config().Rules) == 0 {
+		t.Fatal("Expected the built-in aws rules to be loaded")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(terraform), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := pg.ScanDirectory(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("ScanDirectory() error = %v", err)
+	}
+	report, err := result.Report()
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	var violations []config.Violation
+	if err := json.Unmarshal([]byte(report), &violations); err != nil {
+		t.Fatalf("Report() is not a JSON array: %v\n%s", err, report)
+	}
+}
+
+func TestNewConfigFileExtras(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.hcl")
+	profile := `
+profile "strict" {
+  severities = { bucket_tags = "error" }
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(configFile+profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, config.ExceptionsDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	exception := `
+exception {
+  rules       = ["bucket_tags"]
+  reason      = "Tagged by the platform team"
+  approved_by = "platform"
+  expires_at  = "2099-01-01"
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, config.ExceptionsDir, "tags.hcl"), []byte(exception), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pg, err := New(WithConfigFile(cfgPath), WithProfile("strict"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	cfg := pg.Config()
+	if len(cfg.Exceptions) != 1 {
+		t.Errorf("got %d exceptions, want the one from the exceptions directory", len(cfg.Exceptions))
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Severity != "error" {
+		t.Errorf("rules = %+v, want bucket_tags with the profile's severity error", cfg.Rules)
+	}
+}
+
+func TestNewConfigFileSeverityMap(t *testing.T) {
+	// The map is found next to the config, not in the working directory
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.hcl")
+	settings := `
+settings {
+  severity_map = "severity.yaml"
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(configFile+settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "severity.yaml"), []byte("rules:\n  bucket_tags: error\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pg, err := New(WithConfigFile(cfgPath))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if rules := pg.Config().Rules; len(rules) != 1 || rules[0].Severity != "error" {
+		t.Errorf("rules = %+v, want bucket_tags with the mapped severity error", rules)
+	}
+}
+
+func TestNewConfigFileUnknown(t *testing.T) {
+	tests := []struct {
+		name    string
+		strict  string
+		wantErr string
+	}{
+		{"ignored by default", "", ""},
+		{"fail with strict", "strict = true", "severty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgPath := filepath.Join(t.TempDir(), "config.hcl")
+			content := strings.Replace(configFile, `severity      = "warning"`, `severty       = "error"
+  severity      = "warning"`, 1) + "settings {\n  " + tt.strict + "\n}\n"
+			if err := os.WriteFile(cfgPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			pg, err := New(WithConfigFile(cfgPath))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if unknown := pg.Config().Unknown; len(unknown) != 1 || !strings.Contains(unknown[0].Detail, "severty") {
+				t.Errorf("Unknown = %v, want the misspelled severty", unknown)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	sourcesPath := filepath.Join(t.TempDir(), "config.hcl")
+	if err := os.WriteFile(sourcesPath, []byte(`settings {
+  rule_sources = ["git::https://example.com/rules.git"]
+}
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "unknown format", opts: []Option{WithFormat("xml")}, wantErr: `unknown format "xml"`},
+		{name: "missing config file", opts: []Option{WithConfigFile("/nonexistent/config.hcl")}, wantErr: "failed to load config"},
+		{name: "missing rules directory", opts: []Option{WithRulesDir("/nonexistent/rules")}, wantErr: "rules directory not found"},
+		{name: "rule sources", opts: []Option{WithConfigFile(sourcesPath)}, wantErr: "rule_sources are not supported"},
+		{name: "unknown profile", opts: []Option{WithProfile("prod")}, wantErr: `unknown profile "prod"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanDirectoryEmpty(t *testing.T) {
+	pg, err := New(WithPresuppliedRules(false))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := pg.ScanDirectory(context.Background(), t.TempDir()); err == nil || !strings.Contains(err.Error(), "no Terraform files") {
+		t.Errorf("ScanDirectory() error = %v, want no Terraform files", err)
+	}
+}
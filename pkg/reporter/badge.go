@@ -0,0 +1,54 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FormatBadge formats the violation counts as shields.io endpoint JSON
+// (https://shields.io/badges/endpoint-badge): "passing" in green without
+// violations, otherwise the counts of each severity present, colored by the
+// most severe. Partial results are marked and grey.
+func (r *Reporter) FormatBadge() (string, error) {
+	counts := []struct {
+		severity string
+		label    string
+		color    string
+	}{
+		{"error", "errors", "red"},
+		{"warning", "warnings", "yellow"},
+		{"info", "info", "blue"},
+	}
+
+	var parts []string
+	color := "brightgreen"
+	for _, c := range counts {
+		if n := len(r.filterBySeverity(c.severity)); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, c.label))
+			if len(parts) == 1 {
+				color = c.color
+			}
+		}
+	}
+	message := "passing"
+	if len(parts) > 0 {
+		message = strings.Join(parts, ", ")
+	}
+	if r.partialReason != "" {
+		message += " (partial)"
+		color = "lightgrey"
+	}
+
+	badge := struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Label         string `json:"label"`
+		Message       string `json:"message"`
+		Color         string `json:"color"`
+	}{1, "planguard", message, color}
+	data, err := json.MarshalIndent(badge, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,52 @@
+package reporter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatBadge(t *testing.T) {
+	tests := []struct {
+		name        string
+		severities  []string
+		partial     string
+		wantMessage string
+		wantColor   string
+	}{
+		{"no violations", nil, "", "passing", "brightgreen"},
+		{"errors and warnings", []string{"warning", "error", "error"}, "", "2 errors, 1 warnings", "red"},
+		{"warnings and info", []string{"info", "warning"}, "", "1 warnings, 1 info", "yellow"},
+		{"info only", []string{"info"}, "", "1 info", "blue"},
+		{"partial", []string{"error"}, "scan interrupted", "1 errors (partial)", "lightgrey"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []config.Violation
+			for _, severity := range tt.severities {
+				violations = append(violations, config.Violation{RuleID: "r", Severity: severity})
+			}
+			rep := NewReporter(violations, nil)
+			if tt.partial != "" {
+				rep.SetPartial(tt.partial)
+			}
+			output, err := rep.FormatBadge()
+			if err != nil {
+				t.Fatalf("FormatBadge() error = %v", err)
+			}
+
+			var badge map[string]interface{}
+			if err := json.Unmarshal([]byte(output), &badge); err != nil {
+				t.Fatalf("Invalid JSON: %v", err)
+			}
+			if badge["schemaVersion"] != float64(1) || badge["label"] != "planguard" {
+				t.Errorf("badge = %v", badge)
+			}
+			if badge["message"] != tt.wantMessage || badge["color"] != tt.wantColor {
+				t.Errorf("message = %q, color = %q, want %q, %q", badge["message"], badge["color"], tt.wantMessage, tt.wantColor)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package reporter
+
+// ANSI escape sequences used by colored text output
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// SetColor turns ANSI colors in FormatText on or off. They are off by
+// default; callers enable them when writing to a terminal.
+func (r *Reporter) SetColor(enabled bool) {
+	r.color = enabled
+}
+
+// paint wraps text in the escape sequence code when colors are on
+func (r *Reporter) paint(code, text string) string {
+	if !r.color || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// severityColor returns the color of a severity: red for errors, yellow
+// for warnings, and cyan for info
+func severityColor(severity string) string {
+	switch severity {
+	case "error":
+		return ansiRed
+	case "warning":
+		return ansiYellow
+	default:
+		return ansiCyan
+	}
+}
@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatTextColor(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", File: "main.tf", Line: 3, Column: 1, ResourceType: "aws_s3_bucket", ResourceName: "data", Fingerprint: "abc123"},
+		{RuleID: "s3_versioning", RuleName: "Versioning", Severity: "warning", File: "main.tf", Line: 9, Column: 1, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "tags", RuleName: "Tags", Severity: "info", File: "main.tf", Line: 12, Column: 1, ResourceType: "aws_vpc", ResourceName: "main"},
+	}
+
+	rep := NewReporter(violations, nil)
+	if output := rep.FormatText(); strings.Contains(output, "\x1b[") {
+		t.Errorf("Colors should be off by default:\n%q", output)
+	}
+
+	rep.SetColor(true)
+	output := rep.FormatText()
+	for _, want := range []string{
+		"\x1b[1m\x1b[31m❌ ERRORS: 1\x1b[0m\n",
+		"\x1b[1m\x1b[33m⚠️  WARNINGS: 1\x1b[0m\n",
+		"\x1b[1m\x1b[36mℹ️  INFO: 1\x1b[0m\n",
+		"\x1b[31mmain.tf:3:1\x1b[0m\n",
+		"\x1b[33mmain.tf:9:1\x1b[0m\n",
+		"  Resource: \x1b[1maws_s3_bucket.data\x1b[0m\n",
+		"  Fingerprint: \x1b[2mabc123\x1b[0m\n",
+		"\x1b[1mTotal: 3 violations\x1b[0m\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%q", want, output)
+		}
+	}
+
+	empty := NewReporter(nil, nil)
+	empty.SetColor(true)
+	if output := empty.FormatText(); output != "\x1b[32m✅ No violations found!\x1b[0m\n" {
+		t.Errorf("FormatText() = %q", output)
+	}
+}
@@ -0,0 +1,125 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// DefaultCommentMaxBytes is the default size limit of FormatPRComment,
+// GitHub's limit on the body of a comment
+const DefaultCommentMaxBytes = 65536
+
+// ReportURLPlaceholder stands in for the link to the full report when no
+// URL is set, for a CI step to replace once the report is uploaded
+const ReportURLPlaceholder = "{{REPORT_URL}}"
+
+// SetPRComment sets the size limit in bytes of FormatPRComment and the URL
+// it links to the full report with. Zero and "" keep the defaults.
+func (r *Reporter) SetPRComment(maxBytes int, reportURL string) {
+	r.commentMaxBytes = maxBytes
+	r.reportURL = reportURL
+}
+
+// FormatPRComment formats a compact Markdown summary for posting as a
+// single pull request comment: the counts, a table of the most severe
+// violations, and a link to the full report. Table rows are dropped from
+// the end, with a note of how many were left out, to keep the comment
+// within its size limit.
+func (r *Reporter) FormatPRComment() string {
+	maxBytes := r.commentMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultCommentMaxBytes
+	}
+	reportURL := r.reportURL
+	if reportURL == "" {
+		reportURL = ReportURLPlaceholder
+	}
+
+	errors := r.filterBySeverity("error")
+	warnings := r.filterBySeverity("warning")
+	infos := r.filterBySeverity("info")
+
+	var header strings.Builder
+	header.WriteString("## 🛡️ Planguard\n\n")
+	if r.partialReason != "" {
+		header.WriteString(fmt.Sprintf("> ⚠️ **Partial results:** %s.\n\n", r.partialReason))
+	}
+	if len(r.violations) == 0 {
+		header.WriteString("✅ No violations found")
+	} else {
+		header.WriteString(fmt.Sprintf("**%d violations:** ❌ %d errors, ⚠️ %d warnings, ℹ️ %d info", len(r.violations), len(errors), len(warnings), len(infos)))
+	}
+	var notes []string
+	if len(r.filteredViolations) > 0 {
+		notes = append(notes, fmt.Sprintf("%d excepted", len(r.filteredViolations)))
+	}
+	if len(r.knownViolations) > 0 {
+		notes = append(notes, fmt.Sprintf("%d known", len(r.knownViolations)))
+	}
+	if len(notes) > 0 {
+		header.WriteString(fmt.Sprintf(" (%s)", strings.Join(notes, ", ")))
+	}
+	header.WriteString("\n")
+
+	footer := fmt.Sprintf("\n📄 [Full report](%s)\n", reportURL)
+
+	var rows []string
+	for _, group := range [][]config.Violation{errors, warnings, infos} {
+		for _, v := range group {
+			rows = append(rows, fmt.Sprintf("| %s | `%s` | `%s.%s` | `%s:%d` | %s |\n",
+				v.Severity, v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line, commentMessage(v.Message)))
+		}
+	}
+	const tableHeader = "\n| Severity | Rule | Resource | Location | Message |\n| --- | --- | --- | --- | --- |\n"
+
+	// Fit as many rows as the limit allows, leaving room for the note
+	// saying how many were left out
+	shown := len(rows)
+	size := header.Len() + len(footer)
+	if shown > 0 {
+		size += len(tableHeader)
+		for _, row := range rows {
+			size += len(row)
+		}
+	}
+	for shown > 0 && size+len(omittedNote(len(rows)-shown)) > maxBytes {
+		shown--
+		size -= len(rows[shown])
+		if shown == 0 {
+			size -= len(tableHeader)
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(header.String())
+	if shown > 0 {
+		output.WriteString(tableHeader)
+		for _, row := range rows[:shown] {
+			output.WriteString(row)
+		}
+	}
+	output.WriteString(omittedNote(len(rows) - shown))
+	output.WriteString(footer)
+	return output.String()
+}
+
+// commentMessage shortens a violation message to its first line, at most
+// 120 characters, for a table cell
+func commentMessage(message string) string {
+	message, _, _ = strings.Cut(strings.TrimSpace(message), "\n")
+	if runes := []rune(message); len(runes) > 120 {
+		message = string(runes[:119]) + "…"
+	}
+	return markdownCell(message)
+}
+
+// omittedNote says how many violations were left out of the table, or is
+// empty when none were
+func omittedNote(omitted int) string {
+	if omitted == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n_…and %d more violations, see the full report._\n", omitted)
+}
@@ -0,0 +1,100 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatPRComment(t *testing.T) {
+	var violations []config.Violation
+	for i := 0; i < 50; i++ {
+		severity := "warning"
+		if i%10 == 0 {
+			severity = "error"
+		}
+		violations = append(violations, config.Violation{
+			RuleID:       fmt.Sprintf("rule_%d", i),
+			Severity:     severity,
+			ResourceType: "aws_s3_bucket",
+			ResourceName: fmt.Sprintf("b%d", i),
+			File:         "main.tf",
+			Line:         i + 1,
+			Message:      "Bucket | is public\nsecond line",
+		})
+	}
+
+	tests := []struct {
+		name        string
+		violations  []config.Violation
+		maxBytes    int
+		reportURL   string
+		wantRows    int
+		wantOmitted int
+		want        []string
+	}{
+		{
+			name:     "no violations",
+			wantRows: 0,
+			want:     []string{"✅ No violations found\n", "[Full report](" + ReportURLPlaceholder + ")"},
+		},
+		{
+			name:       "all fit",
+			violations: violations,
+			reportURL:  "https://ci.example.com/run/1",
+			wantRows:   50,
+			want: []string{
+				"**50 violations:** ❌ 5 errors, ⚠️ 45 warnings, ℹ️ 0 info\n",
+				"| error | `rule_0` | `aws_s3_bucket.b0` | `main.tf:1` | Bucket \\| is public |\n",
+				"[Full report](https://ci.example.com/run/1)",
+			},
+		},
+		{
+			name:        "capped",
+			violations:  violations,
+			maxBytes:    2048,
+			wantRows:    -1,
+			wantOmitted: -1,
+			want:        []string{"more violations, see the full report._"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := NewReporter(tt.violations, nil)
+			rep.SetPRComment(tt.maxBytes, tt.reportURL)
+			output := rep.FormatPRComment()
+
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected %q in output:\n%s", want, output)
+				}
+			}
+			if tt.maxBytes > 0 && len(output) > tt.maxBytes {
+				t.Errorf("len(output) = %d, want at most %d", len(output), tt.maxBytes)
+			}
+
+			rows := 0
+			for _, line := range strings.Split(output, "\n") {
+				if strings.HasPrefix(line, "| ") && !strings.HasPrefix(line, "| Severity |") && !strings.HasPrefix(line, "| --- |") {
+					rows++
+				}
+			}
+			if tt.wantRows >= 0 && rows != tt.wantRows {
+				t.Errorf("rows = %d, want %d", rows, tt.wantRows)
+			}
+			if tt.wantOmitted < 0 {
+				// Capped: every row that fit plus the omitted ones add up
+				want := fmt.Sprintf("_…and %d more violations", len(tt.violations)-rows)
+				if rows == 0 || !strings.Contains(output, want) {
+					t.Errorf("Expected %d rows and %q in output:\n%s", rows, want, output)
+				}
+				if !strings.Contains(output, "| error | `rule_0` |") {
+					t.Errorf("Expected errors to be kept first:\n%s", output)
+				}
+			}
+		})
+	}
+}
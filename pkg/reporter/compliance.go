@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Control statuses
+const (
+	ControlPass          = "pass"
+	ControlFail          = "fail"
+	ControlNotApplicable = "not_applicable" // No resource was evaluated by the control's rules
+)
+
+// ControlResult is the outcome of a compliance framework control, such as
+// CIS AWS 2.1.1, across the rules mapped to it
+type ControlResult struct {
+	Framework  string
+	Control    string
+	Status     string
+	Rules      []string // IDs of the rules mapped to the control
+	Violations int      // Violations of those rules, including known ones
+	Excepted   int      // Violations of those rules covered by exceptions
+}
+
+// ComplianceSummary aggregates violations by the compliance controls that
+// rules map to through their compliance attribute. A control fails when any
+// of its rules has a violation that is not excepted, passes when its rules
+// evaluated at least one resource without one, and is otherwise not
+// applicable. evaluated holds the IDs of rules that evaluated a resource.
+// Results are sorted by framework, then control.
+func ComplianceSummary(rules []config.Rule, violations []config.Violation, excepted []config.FilteredViolation, evaluated map[string]bool) []ControlResult {
+	violationsByRule := make(map[string]int)
+	for _, v := range violations {
+		violationsByRule[v.RuleID]++
+	}
+	exceptedByRule := make(map[string]int)
+	for _, fv := range excepted {
+		exceptedByRule[fv.Violation.RuleID]++
+	}
+
+	controls := make(map[[2]string]*ControlResult)
+	for _, rule := range rules {
+		for framework, control := range rule.Compliance {
+			key := [2]string{framework, control}
+			result, ok := controls[key]
+			if !ok {
+				result = &ControlResult{Framework: framework, Control: control, Status: ControlNotApplicable}
+				controls[key] = result
+			}
+			result.Rules = append(result.Rules, rule.ID)
+			result.Violations += violationsByRule[rule.ID]
+			result.Excepted += exceptedByRule[rule.ID]
+			if evaluated[rule.ID] && result.Status == ControlNotApplicable {
+				result.Status = ControlPass
+			}
+		}
+	}
+
+	results := make([]ControlResult, 0, len(controls))
+	for _, result := range controls {
+		if result.Violations > 0 {
+			result.Status = ControlFail
+		}
+		sort.Strings(result.Rules)
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Framework != results[j].Framework {
+			return results[i].Framework < results[j].Framework
+		}
+		return lessControl(results[i].Control, results[j].Control)
+	})
+	return results
+}
+
+// lessControl orders control IDs such as "2.1.10" after "2.1.9", comparing
+// dot-separated numeric parts as numbers
+func lessControl(a, b string) bool {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] == bParts[i] {
+			continue
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr == nil && bErr == nil {
+			return aNum < bNum
+		}
+		return aParts[i] < bParts[i]
+	}
+	return len(aParts) < len(bParts)
+}
+
+// SetCompliance records compliance control results to include in the report
+func (r *Reporter) SetCompliance(controls []ControlResult) {
+	r.compliance = controls
+}
+
+// FormatCompliance lists each framework's controls with their status
+func (r *Reporter) FormatCompliance() string {
+	if len(r.compliance) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	frameworks := make(map[string]bool)
+	for _, c := range r.compliance {
+		counts[c.Status]++
+		frameworks[c.Framework] = true
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📑 COMPLIANCE: %d controls in %d frameworks (%d pass, %d fail, %d not applicable)\n",
+		len(r.compliance), len(frameworks), counts[ControlPass], counts[ControlFail], counts[ControlNotApplicable]))
+	output.WriteString(strings.Repeat("-", 50) + "\n")
+	framework := ""
+	for _, c := range r.compliance {
+		if c.Framework != framework {
+			framework = c.Framework
+			output.WriteString(fmt.Sprintf("  %s\n", framework))
+		}
+		status := strings.ToUpper(strings.ReplaceAll(c.Status, "_", " "))
+		output.WriteString(fmt.Sprintf("    %-12s %-16s violations: %d  excepted: %d  rules: %s\n",
+			c.Control, status, c.Violations, c.Excepted, strings.Join(c.Rules, ", ")))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
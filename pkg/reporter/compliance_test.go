@@ -0,0 +1,87 @@
+package reporter
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestComplianceSummary(t *testing.T) {
+	rules := []config.Rule{
+		{ID: "s3_public", Compliance: map[string]string{"cis_aws": "2.1.5", "pci": "1.3"}},
+		{ID: "s3_acl", Compliance: map[string]string{"cis_aws": "2.1.5"}},
+		{ID: "rds_encryption", Compliance: map[string]string{"cis_aws": "2.3.1"}},
+		{ID: "sg_ingress", Compliance: map[string]string{"cis_aws": "5.2"}},
+		{ID: "ebs_encryption", Compliance: map[string]string{"cis_aws": "2.10.1"}},
+		{ID: "unmapped"},
+	}
+	violations := []config.Violation{
+		{RuleID: "s3_public"},
+		{RuleID: "s3_public"},
+		{RuleID: "unmapped"},
+	}
+	excepted := []config.FilteredViolation{
+		{Violation: config.Violation{RuleID: "rds_encryption"}},
+	}
+	evaluated := map[string]bool{"s3_public": true, "s3_acl": true, "rds_encryption": true, "ebs_encryption": true}
+
+	got := ComplianceSummary(rules, violations, excepted, evaluated)
+	want := []ControlResult{
+		{Framework: "cis_aws", Control: "2.1.5", Status: ControlFail, Rules: []string{"s3_acl", "s3_public"}, Violations: 2},
+		{Framework: "cis_aws", Control: "2.3.1", Status: ControlPass, Rules: []string{"rds_encryption"}, Excepted: 1},
+		{Framework: "cis_aws", Control: "2.10.1", Status: ControlPass, Rules: []string{"ebs_encryption"}},
+		{Framework: "cis_aws", Control: "5.2", Status: ControlNotApplicable, Rules: []string{"sg_ingress"}},
+		{Framework: "pci", Control: "1.3", Status: ControlFail, Rules: []string{"s3_public"}, Violations: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComplianceSummary() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestLessControl(t *testing.T) {
+	controls := []string{"10.1", "2.1.10", "A.5", "2.1", "2.1.9", "2.1.1"}
+	sort.Slice(controls, func(i, j int) bool { return lessControl(controls[i], controls[j]) })
+	want := []string{"2.1", "2.1.1", "2.1.9", "2.1.10", "10.1", "A.5"}
+	if !reflect.DeepEqual(controls, want) {
+		t.Errorf("sorted controls = %v, want %v", controls, want)
+	}
+}
+
+func TestFormatCompliance(t *testing.T) {
+	reporter := NewReporter(nil, nil)
+	reporter.SetCompliance([]ControlResult{
+		{Framework: "cis_aws", Control: "2.1.5", Status: ControlFail, Rules: []string{"s3_public"}, Violations: 2},
+		{Framework: "cis_aws", Control: "5.2", Status: ControlNotApplicable, Rules: []string{"sg_ingress"}},
+		{Framework: "pci", Control: "1.3", Status: ControlPass, Rules: []string{"s3_public"}},
+	})
+
+	text := reporter.FormatText()
+	for _, want := range []string{
+		"COMPLIANCE: 3 controls in 2 frameworks (1 pass, 1 fail, 1 not applicable)",
+		"  cis_aws\n",
+		"2.1.5        FAIL             violations: 2  excepted: 0  rules: s3_public",
+		"NOT APPLICABLE",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text)
+		}
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Compliance []ControlResult
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with compliance should be an object: %v", err)
+	}
+	if len(parsed.Compliance) != 3 || parsed.Compliance[0].Status != ControlFail {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+}
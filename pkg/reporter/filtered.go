@@ -0,0 +1,81 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// SetShowFiltered adds the violations covered by exceptions, with the
+// exception that matched each of them, to JSON and SARIF output. Text and
+// Markdown output always list them.
+func (r *Reporter) SetShowFiltered(show bool) {
+	r.showFiltered = show
+}
+
+// ExceptionScope describes what an exception matches, e.g. "rules
+// s3_public; paths legacy/**", so a reader can tell which exception
+// covered a violation
+func ExceptionScope(e config.Exception) string {
+	parts := []string{"rules " + strings.Join(e.Rules, ", ")}
+	for _, matcher := range []struct {
+		name   string
+		values []string
+	}{
+		{"paths", e.Paths},
+		{"resource names", e.ResourceNames},
+		{"addresses", e.Addresses},
+		{"severities", e.Severities},
+	} {
+		if len(matcher.values) > 0 {
+			parts = append(parts, matcher.name+" "+strings.Join(matcher.values, ", "))
+		}
+	}
+	if len(e.Tags) > 0 {
+		keys := make([]string, 0, len(e.Tags))
+		for k := range e.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var tags []string
+		for _, k := range keys {
+			tags = append(tags, fmt.Sprintf("%s=%s", k, e.Tags[k]))
+		}
+		parts = append(parts, "tags "+strings.Join(tags, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// sarifSuppressedResults converts excepted violations to SARIF results
+// with a suppression recording the exception that covered them, which
+// viewers such as GitHub code scanning show as dismissed
+func (r *Reporter) sarifSuppressedResults() []map[string]interface{} {
+	var results []map[string]interface{}
+	for _, fv := range r.filteredViolations {
+		e := fv.Exception
+		properties := map[string]interface{}{
+			"approvedBy": e.ApprovedBy,
+			"exception":  ExceptionScope(e),
+		}
+		if e.Ticket != nil {
+			properties["ticket"] = *e.Ticket
+		}
+		if e.ExpiresAt != nil {
+			properties["expiresAt"] = *e.ExpiresAt
+		}
+
+		result := r.sarifResults([]config.Violation{fv.Violation}, "")[0]
+		result["suppressions"] = []map[string]interface{}{
+			{
+				"kind":          "external",
+				"status":        "accepted",
+				"justification": e.Reason,
+				"properties":    properties,
+			},
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,115 @@
+package reporter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestExceptionScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		exception config.Exception
+		want      string
+	}{
+		{"rules only", config.Exception{Rules: []string{"s3_public"}}, "rules s3_public"},
+		{
+			name: "every matcher",
+			exception: config.Exception{
+				Rules:         []string{"s3_public", "s3_versioning"},
+				Paths:         []string{"legacy/**"},
+				ResourceNames: []string{"logs"},
+				Addresses:     []string{"module.storage.*"},
+				Severities:    []string{"warning"},
+				Tags:          map[string]string{"team": "data", "env": "dev"},
+			},
+			want: "rules s3_public, s3_versioning; paths legacy/**; resource names logs; addresses module.storage.*; severities warning; tags env=dev, team=data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExceptionScope(tt.exception); got != tt.want {
+				t.Errorf("ExceptionScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShowFiltered(t *testing.T) {
+	ticket := "SEC-42"
+	expires := "2030-01-01"
+	filtered := []config.FilteredViolation{{
+		Violation: config.Violation{RuleID: "s3_public", Severity: "error", File: "legacy/main.tf", Line: 3, Column: 1, ResourceType: "aws_s3_bucket", ResourceName: "site"},
+		Exception: config.Exception{Rules: []string{"s3_public"}, Paths: []string{"legacy/**"}, Reason: "Static website", ApprovedBy: "security@example.com", Ticket: &ticket, ExpiresAt: &expires},
+	}}
+
+	rep := NewReporter(nil, filtered)
+	if text := rep.FormatText(); !strings.Contains(text, "✓ EXCEPTED: 1") || !strings.Contains(text, "  Exception: rules s3_public; paths legacy/**\n") {
+		t.Errorf("Expected the matching exception in text output, got:\n%s", text)
+	}
+
+	// Without -show-filtered, JSON keeps its plain form and SARIF only counts
+	if output, _ := rep.FormatJSON(); strings.Contains(output, "Excepted") {
+		t.Errorf("Expected no excepted violations in JSON, got %s", output)
+	}
+	output, err := rep.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(output, `"exceptedCount": 1`) || strings.Contains(output, "suppressions") {
+		t.Errorf("Expected only exceptedCount in SARIF, got:\n%s", output)
+	}
+
+	rep.SetShowFiltered(true)
+	output, err = rep.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var report struct {
+		Violations []config.Violation
+		Excepted   []config.FilteredViolation
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(report.Excepted) != 1 || report.Excepted[0].Exception.Reason != "Static website" {
+		t.Errorf("Excepted = %+v", report.Excepted)
+	}
+
+	output, err = rep.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID       string
+				Suppressions []struct {
+					Kind          string
+					Status        string
+					Justification string
+					Properties    map[string]string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 1 || len(results[0].Suppressions) != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	s := results[0].Suppressions[0]
+	if s.Kind != "external" || s.Status != "accepted" || s.Justification != "Static website" {
+		t.Errorf("suppression = %+v", s)
+	}
+	want := map[string]string{"approvedBy": "security@example.com", "exception": "rules s3_public; paths legacy/**", "ticket": "SEC-42", "expiresAt": "2030-01-01"}
+	for k, v := range want {
+		if s.Properties[k] != v {
+			t.Errorf("suppression properties[%q] = %q, want %q", k, s.Properties[k], v)
+		}
+	}
+}
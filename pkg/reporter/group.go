@@ -0,0 +1,164 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Keys accepted by SetGroupBy
+const (
+	GroupBySeverity     = "severity"
+	GroupByRule         = "rule"
+	GroupByFile         = "file"
+	GroupByResourceType = "resource_type"
+)
+
+// ValidGroupBy reports whether key is one of the GroupBy keys
+func ValidGroupBy(key string) bool {
+	switch key {
+	case GroupBySeverity, GroupByRule, GroupByFile, GroupByResourceType:
+		return true
+	}
+	return false
+}
+
+// SetGroupBy sets how FormatText and FormatMarkdown group violations. By
+// severity, the default, every violation is printed in full. Grouped by
+// rule, file, or resource type, each group is printed once with a line per
+// violation, so hundreds of violations of one rule collapse into one group.
+func (r *Reporter) SetGroupBy(key string) {
+	r.groupBy = key
+}
+
+// grouped reports whether violations are grouped by something other than
+// severity
+func (r *Reporter) grouped() bool {
+	return r.groupBy != "" && r.groupBy != GroupBySeverity
+}
+
+// violationGroup is a set of violations sharing the value of the GroupBy key
+type violationGroup struct {
+	title      string
+	severity   string // Most severe severity in the group
+	violations []config.Violation
+}
+
+// groupViolations groups the violations by the GroupBy key, groups with
+// the most severe violations first, then the largest, then by title.
+// Violations keep their order within a group.
+func (r *Reporter) groupViolations() []*violationGroup {
+	rank := map[string]int{"error": 0, "warning": 1, "info": 2}
+	index := make(map[string]*violationGroup)
+	var groups []*violationGroup
+	for _, v := range r.violations {
+		var key, title string
+		switch r.groupBy {
+		case GroupByRule:
+			key, title = v.RuleID, fmt.Sprintf("%s (%s)", v.RuleName, v.RuleID)
+		case GroupByFile:
+			key, title = v.File, v.File
+		default:
+			key, title = v.ResourceType, v.ResourceType
+		}
+		g, ok := index[key]
+		if !ok {
+			g = &violationGroup{title: title, severity: v.Severity}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		if rank[v.Severity] < rank[g.severity] {
+			g.severity = v.Severity
+		}
+		g.violations = append(g.violations, v)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if rank[a.severity] != rank[b.severity] {
+			return rank[a.severity] < rank[b.severity]
+		}
+		if len(a.violations) != len(b.violations) {
+			return len(a.violations) > len(b.violations)
+		}
+		return a.title < b.title
+	})
+	return groups
+}
+
+// formatGroups renders the violation groups for FormatText
+func (r *Reporter) formatGroups() string {
+	var output strings.Builder
+	for _, g := range r.groupViolations() {
+		output.WriteString(r.paint(ansiBold+severityColor(g.severity), fmt.Sprintf("%s: %d", g.title, len(g.violations))) + "\n")
+		output.WriteString(strings.Repeat("-", 50) + "\n")
+		// Color codes have the same length within a column, so they do not
+		// throw off the alignment
+		w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+		for _, v := range g.violations {
+			fmt.Fprintln(w, "  "+strings.Join(r.groupedFields(v, true), "\t"))
+		}
+		w.Flush()
+		if remediation := groupRemediation(r.groupBy, g); remediation != "" {
+			output.WriteString(fmt.Sprintf("  Remediation:\n%s\n", indent(remediation, 4)))
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// markdownGroups renders the violation groups for FormatMarkdown
+func (r *Reporter) markdownGroups() string {
+	var output strings.Builder
+	for _, g := range r.groupViolations() {
+		output.WriteString(fmt.Sprintf("### %s (%d)\n\n", markdownCell(g.title), len(g.violations)))
+		for _, v := range g.violations {
+			fields := r.groupedFields(v, false)
+			message := fields[len(fields)-1]
+			output.WriteString("- `" + strings.Join(fields[:len(fields)-1], "` `") + "`: " + message + "\n")
+		}
+		if remediation := groupRemediation(r.groupBy, g); remediation != "" {
+			fence := markdownFence(remediation)
+			output.WriteString("\n<details>\n<summary>Remediation</summary>\n\n")
+			output.WriteString(fence + "\n" + strings.TrimRight(remediation, "\n") + "\n" + fence)
+			output.WriteString("\n\n</details>\n")
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// groupedFields lists what a grouped violation line shows: its location,
+// severity, rule, and resource, leaving out what the group already says,
+// followed by its message
+func (r *Reporter) groupedFields(v config.Violation, color bool) []string {
+	paint := func(code, text string) string {
+		if color {
+			return r.paint(code, text)
+		}
+		return text
+	}
+
+	location := fmt.Sprintf("%s:%d", v.File, v.Line)
+	if r.groupBy == GroupByFile {
+		location = fmt.Sprintf("line %d", v.Line)
+	}
+	fields := []string{paint(severityColor(v.Severity), location)}
+	if r.groupBy != GroupByRule {
+		fields = append(fields, v.Severity, v.RuleID)
+	}
+	fields = append(fields, paint(ansiBold, v.ResourceType+"."+v.ResourceName))
+	return append(fields, strings.ReplaceAll(strings.TrimSpace(v.Message), "\n", " "))
+}
+
+// groupRemediation returns the remediation shared by a rule's group, or ""
+// when violations are not grouped by rule
+func groupRemediation(groupBy string, g *violationGroup) string {
+	if groupBy != GroupByRule {
+		return ""
+	}
+	return g.violations[0].Remediation
+}
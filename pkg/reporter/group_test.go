@@ -0,0 +1,124 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestValidGroupBy(t *testing.T) {
+	for _, key := range []string{"severity", "rule", "file", "resource_type"} {
+		if !ValidGroupBy(key) {
+			t.Errorf("ValidGroupBy(%q) = false", key)
+		}
+	}
+	if ValidGroupBy("module") {
+		t.Error(`ValidGroupBy("module") = true`)
+	}
+}
+
+func groupTestViolations() []config.Violation {
+	return []config.Violation{
+		{RuleID: "tags", RuleName: "Required tags", Severity: "warning", Message: "Missing tags", File: "main.tf", Line: 1, ResourceType: "aws_vpc", ResourceName: "main", Remediation: "Add tags"},
+		{RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", Message: "Bucket is public", File: "storage.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data"},
+		{RuleID: "tags", RuleName: "Required tags", Severity: "warning", Message: "Missing tags", File: "storage.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data", Remediation: "Add tags"},
+		{RuleID: "tags", RuleName: "Required tags", Severity: "warning", Message: "Missing tags", File: "storage.tf", Line: 9, ResourceType: "aws_s3_bucket", ResourceName: "logs", Remediation: "Add tags"},
+	}
+}
+
+func TestFormatTextGroupBy(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{
+			groupBy: GroupByRule,
+			want: `No public buckets (s3_public): 1
+--------------------------------------------------
+  storage.tf:3  aws_s3_bucket.data  Bucket is public
+
+Required tags (tags): 3
+--------------------------------------------------
+  main.tf:1     aws_vpc.main        Missing tags
+  storage.tf:3  aws_s3_bucket.data  Missing tags
+  storage.tf:9  aws_s3_bucket.logs  Missing tags
+  Remediation:
+    Add tags
+
+`,
+		},
+		{
+			groupBy: GroupByFile,
+			want: `storage.tf: 3
+--------------------------------------------------
+  line 3  error    s3_public  aws_s3_bucket.data  Bucket is public
+  line 3  warning  tags       aws_s3_bucket.data  Missing tags
+  line 9  warning  tags       aws_s3_bucket.logs  Missing tags
+
+main.tf: 1
+--------------------------------------------------
+  line 1  warning  tags  aws_vpc.main  Missing tags
+
+`,
+		},
+		{
+			groupBy: GroupByResourceType,
+			want: `aws_s3_bucket: 3
+--------------------------------------------------
+  storage.tf:3  error    s3_public  aws_s3_bucket.data  Bucket is public
+  storage.tf:3  warning  tags       aws_s3_bucket.data  Missing tags
+  storage.tf:9  warning  tags       aws_s3_bucket.logs  Missing tags
+
+aws_vpc: 1
+--------------------------------------------------
+  main.tf:1  warning  tags  aws_vpc.main  Missing tags
+
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			rep := NewReporter(groupTestViolations(), nil)
+			rep.SetGroupBy(tt.groupBy)
+			output := rep.FormatText()
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("Expected groups in output:\n%s\ngot:\n%s", tt.want, output)
+			}
+			if strings.Contains(output, "ERRORS:") || strings.Contains(output, "  Rule:") {
+				t.Errorf("Expected no severity sections when grouped, got:\n%s", output)
+			}
+			if !strings.Contains(output, "Total: 4 violations") {
+				t.Errorf("Expected total in output, got:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestFormatTextGroupBySeverity(t *testing.T) {
+	rep := NewReporter(groupTestViolations(), nil)
+	want := rep.FormatText()
+	rep.SetGroupBy(GroupBySeverity)
+	if output := rep.FormatText(); output != want {
+		t.Errorf("Grouping by severity should not change the output, got:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+func TestFormatMarkdownGroupBy(t *testing.T) {
+	rep := NewReporter(groupTestViolations(), nil)
+	rep.SetGroupBy(GroupByRule)
+	output := rep.FormatMarkdown()
+
+	want := "### Required tags (tags) (3)\n\n" +
+		"- `main.tf:1` `aws_vpc.main`: Missing tags\n" +
+		"- `storage.tf:3` `aws_s3_bucket.data`: Missing tags\n" +
+		"- `storage.tf:9` `aws_s3_bucket.logs`: Missing tags\n" +
+		"\n<details>\n<summary>Remediation</summary>\n\n```\nAdd tags\n```\n\n</details>\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected %q in output:\n%s", want, output)
+	}
+	if strings.Contains(output, "### ❌ Errors") {
+		t.Errorf("Expected no severity sections when grouped, got:\n%s", output)
+	}
+}
@@ -0,0 +1,244 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// FormatMarkdown formats violations as GitHub-flavored Markdown for pull
+// request descriptions and wiki pages: a summary table of violations per
+// rule, then each violation with its remediation in a collapsible section.
+// Excepted and baseline-known violations are collapsed too. Compliance
+// results and rule stats are not included.
+func (r *Reporter) FormatMarkdown() string {
+	var output strings.Builder
+	output.WriteString("## 🛡️ Planguard Scan Results\n\n")
+
+	if r.partialReason != "" {
+		output.WriteString(fmt.Sprintf("> ⚠️ **Partial results:** %s; rules that did not run may have found more violations.\n\n", r.partialReason))
+	}
+
+	errors := r.filterBySeverity("error")
+	warnings := r.filterBySeverity("warning")
+	infos := r.filterBySeverity("info")
+
+	if len(r.violations) == 0 {
+		output.WriteString("✅ No violations found!")
+		if len(r.knownViolations) > 0 {
+			output.WriteString(fmt.Sprintf(" (%d known violations in baseline)", len(r.knownViolations)))
+		}
+		output.WriteString("\n\n")
+	} else {
+		output.WriteString(fmt.Sprintf("**%d violations:** %d errors, %d warnings, %d info", len(r.violations), len(errors), len(warnings), len(infos)))
+		var notes []string
+		if len(r.filteredViolations) > 0 {
+			notes = append(notes, fmt.Sprintf("%d excepted", len(r.filteredViolations)))
+		}
+		if len(r.knownViolations) > 0 {
+			notes = append(notes, fmt.Sprintf("%d known", len(r.knownViolations)))
+		}
+		if len(notes) > 0 {
+			output.WriteString(fmt.Sprintf(" (%s)", strings.Join(notes, ", ")))
+		}
+		output.WriteString("\n\n")
+		output.WriteString(r.markdownRuleTable())
+	}
+
+	if r.score != nil {
+		output.WriteString(fmt.Sprintf("**Risk score:** %g\n\n", *r.score))
+	}
+	output.WriteString(r.markdownTopIssues())
+
+	if r.grouped() {
+		output.WriteString(r.markdownGroups())
+	} else {
+		for _, group := range []struct {
+			heading    string
+			violations []config.Violation
+		}{
+			{"❌ Errors", errors},
+			{"⚠️ Warnings", warnings},
+			{"ℹ️ Info", infos},
+		} {
+			if len(group.violations) == 0 {
+				continue
+			}
+			output.WriteString(fmt.Sprintf("### %s (%d)\n\n", group.heading, len(group.violations)))
+			for _, v := range group.violations {
+				output.WriteString(markdownViolation(v))
+			}
+		}
+	}
+
+	if len(r.filteredViolations) > 0 {
+		output.WriteString(fmt.Sprintf("<details>\n<summary>✓ Excepted (%d)</summary>\n\n", len(r.filteredViolations)))
+		output.WriteString("| Location | Rule | Resource | Reason | Approved by |\n")
+		output.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, fv := range r.filteredViolations {
+			v := fv.Violation
+			output.WriteString(fmt.Sprintf("| `%s:%d` | `%s` | `%s.%s` | %s | %s |\n",
+				v.File, v.Line, v.RuleID, v.ResourceType, v.ResourceName, markdownCell(fv.Exception.Reason), markdownCell(fv.Exception.ApprovedBy)))
+		}
+		output.WriteString("\n</details>\n\n")
+	}
+
+	if len(r.knownViolations) > 0 {
+		output.WriteString(fmt.Sprintf("<details>\n<summary>📋 Known from baseline (%d)</summary>\n\n", len(r.knownViolations)))
+		output.WriteString("| Location | Rule | Resource |\n")
+		output.WriteString("| --- | --- | --- |\n")
+		for _, v := range r.knownViolations {
+			output.WriteString(fmt.Sprintf("| `%s:%d` | `%s` | `%s.%s` |\n", v.File, v.Line, v.RuleID, v.ResourceType, v.ResourceName))
+		}
+		output.WriteString("\n</details>\n\n")
+	}
+
+	if len(r.expiringExceptions) > 0 {
+		output.WriteString(fmt.Sprintf("### ⏳ Exception Expiry (%d)\n\n", len(r.expiringExceptions)))
+		for _, ee := range r.expiringExceptions {
+			status := fmt.Sprintf("expires %s (in %d days)", ee.ExpiresAt, ee.DaysLeft)
+			if ee.Expired {
+				status = fmt.Sprintf("**expired** %s", ee.ExpiresAt)
+			}
+			output.WriteString(fmt.Sprintf("- %s: `%s`, approved by %s. %s\n", status, strings.Join(ee.Exception.Rules, "`, `"), ee.Exception.ApprovedBy, ee.Exception.Reason))
+		}
+		output.WriteString("\n")
+	}
+
+	if summaries := r.LabelSummaries(); len(summaries) > 0 {
+		output.WriteString("### 📊 Summary by Label\n\n")
+		output.WriteString("| Label | Errors | Warnings | Info | Excepted |\n")
+		output.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+		for _, ls := range summaries {
+			output.WriteString(fmt.Sprintf("| `%s=%s` | %d | %d | %d | %d |\n", ls.Key, ls.Value, ls.Errors, ls.Warnings, ls.Infos, ls.Excepted))
+		}
+		output.WriteString("\n")
+	}
+
+	return strings.TrimRight(output.String(), "\n") + "\n"
+}
+
+// markdownRuleTable counts the violations of each rule, most severe and
+// most frequent first
+func (r *Reporter) markdownRuleTable() string {
+	type ruleCount struct {
+		severity string
+		id       string
+		name     string
+		count    int
+	}
+	counts := make(map[string]*ruleCount)
+	var rules []*ruleCount
+	for _, v := range r.violations {
+		rc, ok := counts[v.RuleID]
+		if !ok {
+			rc = &ruleCount{severity: v.Severity, id: v.RuleID, name: v.RuleName}
+			counts[v.RuleID] = rc
+			rules = append(rules, rc)
+		}
+		rc.count++
+	}
+
+	rank := map[string]int{"error": 0, "warning": 1, "info": 2}
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if rank[a.severity] != rank[b.severity] {
+			return rank[a.severity] < rank[b.severity]
+		}
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.id < b.id
+	})
+
+	var output strings.Builder
+	output.WriteString("| Severity | Rule | Violations |\n")
+	output.WriteString("| --- | --- | ---: |\n")
+	for _, rc := range rules {
+		output.WriteString(fmt.Sprintf("| %s | %s (`%s`) | %d |\n", rc.severity, markdownCell(rc.name), rc.id, rc.count))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
+// markdownTopIssues renders the top rules and resources as tables, or ""
+// when no top issues were recorded
+func (r *Reporter) markdownTopIssues() string {
+	if len(r.topRules) == 0 && len(r.topResources) == 0 {
+		return ""
+	}
+
+	var output strings.Builder
+	output.WriteString("### 🏆 Top Issues\n\n")
+	output.WriteString("| # | Rule | Violations | Score |\n")
+	output.WriteString("| ---: | --- | ---: | ---: |\n")
+	for i, issue := range r.topRules {
+		output.WriteString(fmt.Sprintf("| %d | `%s` | %d | %g |\n", i+1, issue.Name, issue.Violations, issue.Score))
+	}
+	output.WriteString("\n| # | Resource | File | Violations | Score |\n")
+	output.WriteString("| ---: | --- | --- | ---: | ---: |\n")
+	for i, issue := range r.topResources {
+		output.WriteString(fmt.Sprintf("| %d | `%s` | `%s` | %d | %g |\n", i+1, issue.Name, issue.File, issue.Violations, issue.Score))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
+// markdownViolation renders a violation as a list item, with its
+// remediation in a collapsible section
+func markdownViolation(v config.Violation) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("- **%s** (`%s`) at `%s:%d`: `%s.%s`\n", v.RuleName, v.RuleID, v.File, v.Line, v.ResourceType, v.ResourceName))
+	output.WriteString(fmt.Sprintf("  %s\n", strings.ReplaceAll(strings.TrimSpace(v.Message), "\n", "\n  ")))
+
+	var details []string
+	if v.Deprecation != "" {
+		details = append(details, "Rule "+v.Deprecation)
+	}
+	if len(v.Labels) > 0 {
+		details = append(details, fmt.Sprintf("Labels: `%s`", formatLabels(v.Labels)))
+	}
+	if v.Blame != nil {
+		details = append(details, fmt.Sprintf("Blame: %s", formatBlame(*v.Blame)))
+	}
+	if len(details) > 0 {
+		output.WriteString(fmt.Sprintf("  <br>%s\n", strings.Join(details, " · ")))
+	}
+
+	if v.Remediation != "" {
+		fence := markdownFence(v.Remediation)
+		output.WriteString("\n  <details>\n  <summary>Remediation</summary>\n\n")
+		output.WriteString(indent(fence+"\n"+strings.TrimRight(v.Remediation, "\n")+"\n"+fence, 2))
+		output.WriteString("\n\n  </details>\n")
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
+// markdownCell makes text safe to put in a table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "<br>")
+}
+
+// markdownFence returns a code fence longer than any run of backticks in
+// text, so the text cannot close it
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
@@ -0,0 +1,89 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", Message: "Bucket is public", File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data", Remediation: "Set acl:\n\n```hcl\nacl = \"private\"\n```"},
+		{RuleID: "s3_versioning", RuleName: "Versioning | enabled", Severity: "warning", Message: "No versioning", File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data"},
+		{RuleID: "s3_versioning", RuleName: "Versioning | enabled", Severity: "warning", Message: "No versioning", File: "main.tf", Line: 9, ResourceType: "aws_s3_bucket", ResourceName: "logs", Labels: map[string]string{"env": "prod"}},
+	}
+	filtered := []config.FilteredViolation{
+		{
+			Violation: config.Violation{RuleID: "s3_public", File: "web.tf", Line: 1, ResourceType: "aws_s3_bucket", ResourceName: "site"},
+			Exception: config.Exception{Reason: "Static\nwebsite", ApprovedBy: "security@example.com"},
+		},
+	}
+
+	rep := NewReporter(violations, filtered)
+	rep.SetKnownViolations([]config.Violation{{RuleID: "tags", File: "old.tf", Line: 7, ResourceType: "aws_instance", ResourceName: "web"}})
+	rep.SetScore(16)
+	rep.SetTopIssues([]TopIssue{{Name: "s3_public", Violations: 1, Score: 10}}, []TopIssue{{Name: "aws_s3_bucket.data", File: "main.tf", Violations: 2, Score: 13}})
+	output := rep.FormatMarkdown()
+
+	for _, want := range []string{
+		"## 🛡️ Planguard Scan Results\n",
+		"**3 violations:** 1 errors, 2 warnings, 0 info (1 excepted, 1 known)\n",
+		"| error | No public buckets (`s3_public`) | 1 |\n| warning | Versioning \\| enabled (`s3_versioning`) | 2 |\n",
+		"**Risk score:** 16\n",
+		"| 1 | `aws_s3_bucket.data` | `main.tf` | 2 | 13 |\n",
+		"### ❌ Errors (1)\n\n- **No public buckets** (`s3_public`) at `main.tf:3`: `aws_s3_bucket.data`\n  Bucket is public\n",
+		"  <summary>Remediation</summary>\n\n  ````\n  Set acl:\n",
+		"  ```\n  ````\n\n  </details>\n",
+		"### ⚠️ Warnings (2)\n",
+		"  <br>Labels: `env=prod`\n",
+		"<summary>✓ Excepted (1)</summary>",
+		"| `web.tf:1` | `s3_public` | `aws_s3_bucket.site` | Static<br>website | security@example.com |\n",
+		"<summary>📋 Known from baseline (1)</summary>",
+		"| `env=prod` | 0 | 1 | 0 | 0 |\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Info (") {
+		t.Errorf("Empty severities should be omitted:\n%s", output)
+	}
+	if !strings.HasSuffix(output, "\n") || strings.HasSuffix(output, "\n\n") {
+		t.Errorf("Output should end with a single newline: %q", output[len(output)-10:])
+	}
+}
+
+func TestFormatMarkdownNoViolations(t *testing.T) {
+	rep := NewReporter(nil, nil)
+	rep.SetPartial("scan timed out after 1m0s")
+	output := rep.FormatMarkdown()
+
+	if !strings.Contains(output, "> ⚠️ **Partial results:** scan timed out after 1m0s;") {
+		t.Errorf("Expected partial banner in output:\n%s", output)
+	}
+	if !strings.Contains(output, "✅ No violations found!\n") {
+		t.Errorf("Expected no-violations message in output:\n%s", output)
+	}
+	if strings.Contains(output, "| Severity |") {
+		t.Errorf("No summary table expected without violations:\n%s", output)
+	}
+}
+
+func TestMarkdownFence(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain text", "```"},
+		{"use `acl`", "```"},
+		{"```hcl\nacl = \"private\"\n```", "````"},
+		{"`````", "``````"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownFence(tt.text); got != tt.want {
+			t.Errorf("markdownFence(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package reporter
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jonathanhle/planguard/pkg/config"
 )
@@ -12,6 +14,30 @@ import (
 type Reporter struct {
 	violations         []config.Violation
 	filteredViolations []config.FilteredViolation
+	knownViolations    []config.Violation
+	hiddenViolations   []config.Violation // Below the severity threshold; see SetSeverityThreshold
+	baseline           bool
+	expiringExceptions []config.ExpiringException
+	partialReason      string // Set when the scan stopped before finishing
+	ruleStats          []RuleStats
+	compliance         []ControlResult
+	score              *float64 // Risk score, when risk scoring is enabled
+	topRules           []TopIssue
+	topResources       []TopIssue
+	color              bool   // Whether FormatText uses ANSI colors
+	groupBy            string // How violations are grouped; see SetGroupBy
+	rules              []config.Rule
+	showFiltered       bool   // Whether JSON and SARIF include excepted violations
+	commentMaxBytes    int    // Size limit of FormatPRComment; see SetPRComment
+	reportURL          string // Full report link of FormatPRComment
+}
+
+// RuleStats records what evaluating a rule cost during a scan
+type RuleStats struct {
+	RuleID     string
+	Seconds    float64 // Wall time spent evaluating the rule
+	Evaluated  int     // Resources whose conditions were checked
+	Violations int     // Violations found, before exceptions are applied
 }
 
 // NewReporter creates a new reporter
@@ -22,66 +48,178 @@ func NewReporter(violations []config.Violation, filtered []config.FilteredViolat
 	}
 }
 
-// FormatText formats violations as human-readable text
+// SetRules records the rules that were loaded for the scan, so SARIF
+// output describes each of them, with its remediation, references, and
+// tags, and not only the rules that were violated
+func (r *Reporter) SetRules(rules []config.Rule) {
+	r.rules = rules
+}
+
+// SetSeverityThreshold hides violations less severe than threshold (error,
+// warning, or info) from the formatted reports. Hidden violations still
+// count for ShouldFail and ExitCode. It returns how many were hidden.
+func (r *Reporter) SetSeverityThreshold(threshold string) int {
+	var shown []config.Violation
+	for _, v := range r.violations {
+		if !SeverityAtLeast(v.Severity, threshold) {
+			r.hiddenViolations = append(r.hiddenViolations, v)
+			continue
+		}
+		shown = append(shown, v)
+	}
+	r.violations = shown
+	return len(r.hiddenViolations)
+}
+
+// SeverityAtLeast reports whether severity is at least as severe as
+// threshold. Unknown severities are never below a threshold.
+func SeverityAtLeast(severity, threshold string) bool {
+	rank := map[string]int{"info": 1, "warning": 2, "error": 3}
+	level, ok := rank[severity]
+	return !ok || level >= rank[threshold]
+}
+
+// SetKnownViolations records violations matched by a baseline. Known
+// violations are reported separately and never fail the scan.
+func (r *Reporter) SetKnownViolations(known []config.Violation) {
+	r.knownViolations = known
+	r.baseline = true
+}
+
+// SetExpiringExceptions records exceptions that have expired or expire
+// soon, so the report can prompt owners to renew or remove them
+func (r *Reporter) SetExpiringExceptions(expiring []config.ExpiringException) {
+	r.expiringExceptions = expiring
+}
+
+// SetPartial marks the results as partial because the scan stopped early,
+// e.g. on a timeout, so reports don't present them as a complete scan
+func (r *Reporter) SetPartial(reason string) {
+	r.partialReason = reason
+}
+
+// SetRuleStats records per-rule evaluation costs to include in the report,
+// slowest rule first
+func (r *Reporter) SetRuleStats(stats []RuleStats) {
+	r.ruleStats = append([]RuleStats(nil), stats...)
+	sort.SliceStable(r.ruleStats, func(i, j int) bool {
+		return r.ruleStats[i].Seconds > r.ruleStats[j].Seconds
+	})
+}
+
+// SetScore records the scan's risk score to include in the report
+func (r *Reporter) SetScore(score float64) {
+	r.score = &score
+}
+
+// formatScore returns the risk score line, or "" when no score was set
+func (r *Reporter) formatScore() string {
+	if r.score == nil {
+		return ""
+	}
+	return fmt.Sprintf("Risk score: %g\n", *r.score)
+}
+
+// FormatText formats violations as human-readable text, colored by
+// severity when colors are enabled with SetColor
 func (r *Reporter) FormatText() string {
+	partial := ""
+	if r.partialReason != "" {
+		partial = r.paint(ansiBold+ansiYellow, fmt.Sprintf("⚠️  PARTIAL RESULTS: %s; rules that did not run may have found more violations", r.partialReason)) + "\n\n"
+	}
+
 	if len(r.violations) == 0 {
-		return "✅ No violations found!\n"
+		if len(r.knownViolations) > 0 {
+			return partial + r.paint(ansiGreen, fmt.Sprintf("✅ No new violations found! (%d known violations in baseline)", len(r.knownViolations))) + "\n" + r.formatScore() + r.formatExcepted() + r.formatExpiringExceptions() + r.FormatCompliance() + r.FormatRuleStats()
+		}
+		return partial + r.paint(ansiGreen, "✅ No violations found!") + "\n" + r.formatScore() + r.formatExcepted() + r.formatExpiringExceptions() + r.FormatCompliance() + r.FormatRuleStats()
 	}
 
 	var output strings.Builder
+	output.WriteString(partial)
+	output.WriteString(r.FormatTopIssues())
 
 	// Group by severity
 	errors := r.filterBySeverity("error")
 	warnings := r.filterBySeverity("warning")
 	infos := r.filterBySeverity("info")
 
-	output.WriteString("🔒 Terraform Guardian Scan Results\n")
+	output.WriteString(r.paint(ansiBold, "🔒 Terraform Guardian Scan Results") + "\n")
 	output.WriteString(strings.Repeat("=", 50) + "\n\n")
 
-	if len(errors) > 0 {
-		output.WriteString(fmt.Sprintf("❌ ERRORS: %d\n", len(errors)))
-		output.WriteString(strings.Repeat("-", 50) + "\n")
-		for _, v := range errors {
-			output.WriteString(r.formatViolation(v))
+	if r.grouped() {
+		output.WriteString(r.formatGroups())
+	} else {
+		if len(errors) > 0 {
+			output.WriteString(r.paint(ansiBold+ansiRed, fmt.Sprintf("❌ ERRORS: %d", len(errors))) + "\n")
+			output.WriteString(strings.Repeat("-", 50) + "\n")
+			for _, v := range errors {
+				output.WriteString(r.formatViolation(v))
+			}
+			output.WriteString("\n")
 		}
-		output.WriteString("\n")
-	}
 
-	if len(warnings) > 0 {
-		output.WriteString(fmt.Sprintf("⚠️  WARNINGS: %d\n", len(warnings)))
-		output.WriteString(strings.Repeat("-", 50) + "\n")
-		for _, v := range warnings {
-			output.WriteString(r.formatViolation(v))
+		if len(warnings) > 0 {
+			output.WriteString(r.paint(ansiBold+ansiYellow, fmt.Sprintf("⚠️  WARNINGS: %d", len(warnings))) + "\n")
+			output.WriteString(strings.Repeat("-", 50) + "\n")
+			for _, v := range warnings {
+				output.WriteString(r.formatViolation(v))
+			}
+			output.WriteString("\n")
+		}
+
+		if len(infos) > 0 {
+			output.WriteString(r.paint(ansiBold+ansiCyan, fmt.Sprintf("ℹ️  INFO: %d", len(infos))) + "\n")
+			output.WriteString(strings.Repeat("-", 50) + "\n")
+			for _, v := range infos {
+				output.WriteString(r.formatViolation(v))
+			}
+			output.WriteString("\n")
 		}
-		output.WriteString("\n")
 	}
 
-	if len(infos) > 0 {
-		output.WriteString(fmt.Sprintf("ℹ️  INFO: %d\n", len(infos)))
+	output.WriteString(r.formatExcepted())
+
+	// Show violations already recorded in the baseline
+	if len(r.knownViolations) > 0 {
+		output.WriteString(fmt.Sprintf("📋 KNOWN (baseline): %d\n", len(r.knownViolations)))
 		output.WriteString(strings.Repeat("-", 50) + "\n")
-		for _, v := range infos {
-			output.WriteString(r.formatViolation(v))
+		for _, v := range r.knownViolations {
+			output.WriteString(fmt.Sprintf("  %s:%d  %s  %s.%s\n", v.File, v.Line, v.RuleID, v.ResourceType, v.ResourceName))
 		}
 		output.WriteString("\n")
 	}
 
-	// Show filtered violations (exceptions)
-	if len(r.filteredViolations) > 0 {
-		output.WriteString(fmt.Sprintf("✓ EXCEPTED: %d\n", len(r.filteredViolations)))
+	output.WriteString(r.formatExpiringExceptions())
+	output.WriteString(r.FormatCompliance())
+	output.WriteString(r.FormatRuleStats())
+
+	// Show per-label rollups when scan targets are labeled
+	if summaries := r.LabelSummaries(); len(summaries) > 0 {
+		output.WriteString("📊 SUMMARY BY LABEL\n")
 		output.WriteString(strings.Repeat("-", 50) + "\n")
-		for _, fv := range r.filteredViolations {
-			output.WriteString(r.formatFilteredViolation(fv))
+		for _, ls := range summaries {
+			output.WriteString(fmt.Sprintf("  %-30s errors: %d  warnings: %d  info: %d  excepted: %d\n",
+				ls.Key+"="+ls.Value, ls.Errors, ls.Warnings, ls.Infos, ls.Excepted))
 		}
 		output.WriteString("\n")
 	}
 
 	output.WriteString(strings.Repeat("=", 50) + "\n")
-	output.WriteString(fmt.Sprintf("Total: %d violations", len(r.violations)))
+	output.WriteString(r.paint(ansiBold, fmt.Sprintf("Total: %d violations", len(r.violations))))
+	var notes []string
 	if len(r.filteredViolations) > 0 {
-		output.WriteString(fmt.Sprintf(" (%d excepted)\n", len(r.filteredViolations)))
+		notes = append(notes, fmt.Sprintf("%d excepted", len(r.filteredViolations)))
+	}
+	if len(r.knownViolations) > 0 {
+		notes = append(notes, fmt.Sprintf("%d known", len(r.knownViolations)))
+	}
+	if len(notes) > 0 {
+		output.WriteString(fmt.Sprintf(" (%s)\n", strings.Join(notes, ", ")))
 	} else {
 		output.WriteString("\n")
 	}
+	output.WriteString(r.formatScore())
 
 	return output.String()
 }
@@ -89,11 +227,31 @@ func (r *Reporter) FormatText() string {
 func (r *Reporter) formatViolation(v config.Violation) string {
 	var output strings.Builder
 
-	output.WriteString(fmt.Sprintf("\n%s:%d:%d\n", v.File, v.Line, v.Column))
-	output.WriteString(fmt.Sprintf("  Rule: %s (%s)\n", v.RuleName, v.RuleID))
-	output.WriteString(fmt.Sprintf("  Resource: %s.%s\n", v.ResourceType, v.ResourceName))
+	output.WriteString("\n" + r.paint(severityColor(v.Severity), fmt.Sprintf("%s:%d:%d", v.File, v.Line, v.Column)) + "\n")
+	rule := fmt.Sprintf("%s (%s)", v.RuleName, v.RuleID)
+	if v.Deprecation != "" {
+		rule += " " + r.paint(ansiYellow, "["+v.Deprecation+"]")
+	}
+	output.WriteString(fmt.Sprintf("  Rule: %s\n", rule))
+	output.WriteString(fmt.Sprintf("  Resource: %s\n", r.paint(ansiBold, v.ResourceType+"."+v.ResourceName)))
 	output.WriteString(fmt.Sprintf("  Message: %s\n", v.Message))
 
+	if v.Fingerprint != "" {
+		output.WriteString(fmt.Sprintf("  Fingerprint: %s\n", r.paint(ansiDim, v.Fingerprint)))
+	}
+
+	if len(v.Labels) > 0 {
+		output.WriteString(fmt.Sprintf("  Labels: %s\n", formatLabels(v.Labels)))
+	}
+
+	if v.Blame != nil {
+		output.WriteString(fmt.Sprintf("  Blame: %s\n", formatBlame(*v.Blame)))
+	}
+
+	if v.Source != nil {
+		output.WriteString("  Source:\n" + r.formatSource(v))
+	}
+
 	if v.Remediation != "" {
 		output.WriteString(fmt.Sprintf("  Remediation:\n%s\n", indent(v.Remediation, 4)))
 	}
@@ -101,6 +259,22 @@ func (r *Reporter) formatViolation(v config.Violation) string {
 	return output.String()
 }
 
+// formatExcepted lists the violations covered by exceptions, or returns ""
+// when there are none
+func (r *Reporter) formatExcepted() string {
+	if len(r.filteredViolations) == 0 {
+		return ""
+	}
+	var output strings.Builder
+	output.WriteString(r.paint(ansiBold+ansiGreen, fmt.Sprintf("✓ EXCEPTED: %d", len(r.filteredViolations))) + "\n")
+	output.WriteString(strings.Repeat("-", 50) + "\n")
+	for _, fv := range r.filteredViolations {
+		output.WriteString(r.formatFilteredViolation(fv))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
 func (r *Reporter) formatFilteredViolation(fv config.FilteredViolation) string {
 	var output strings.Builder
 
@@ -109,7 +283,8 @@ func (r *Reporter) formatFilteredViolation(fv config.FilteredViolation) string {
 
 	output.WriteString(fmt.Sprintf("\n%s:%d:%d\n", v.File, v.Line, v.Column))
 	output.WriteString(fmt.Sprintf("  Rule: %s (%s)\n", v.RuleName, v.RuleID))
-	output.WriteString(fmt.Sprintf("  Resource: %s.%s\n", v.ResourceType, v.ResourceName))
+	output.WriteString(fmt.Sprintf("  Resource: %s\n", r.paint(ansiBold, v.ResourceType+"."+v.ResourceName)))
+	output.WriteString(fmt.Sprintf("  Exception: %s\n", ExceptionScope(e)))
 	output.WriteString(fmt.Sprintf("  Exception Reason: %s\n", e.Reason))
 	output.WriteString(fmt.Sprintf("  Approved By: %s\n", e.ApprovedBy))
 
@@ -124,8 +299,147 @@ func (r *Reporter) formatFilteredViolation(fv config.FilteredViolation) string {
 	return output.String()
 }
 
-// FormatJSON formats violations as JSON
+// formatExpiringExceptions lists expired and soon-to-expire exceptions
+func (r *Reporter) formatExpiringExceptions() string {
+	if len(r.expiringExceptions) == 0 {
+		return ""
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("⏳ EXCEPTION EXPIRY: %d\n", len(r.expiringExceptions)))
+	output.WriteString(strings.Repeat("-", 50) + "\n")
+	for _, ee := range r.expiringExceptions {
+		status := fmt.Sprintf("expires %s (in %d days)", ee.ExpiresAt, ee.DaysLeft)
+		if ee.Expired {
+			status = fmt.Sprintf("EXPIRED %s", ee.ExpiresAt)
+		}
+		output.WriteString(fmt.Sprintf("  %s  rules: %s  approved by: %s\n", status, strings.Join(ee.Exception.Rules, ", "), ee.Exception.ApprovedBy))
+		output.WriteString(fmt.Sprintf("    Reason: %s\n", ee.Exception.Reason))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
+// FormatOneline formats a single status line for shell prompts, Makefiles,
+// and status bars, e.g. "planguard: 3 errors, 5 warnings, 0 info, 120
+// resources". The wording is fixed so scripts can parse it: every count is
+// always present and never singularized, and " (partial)" is appended when
+// the scan stopped early.
+func (r *Reporter) FormatOneline(resources int) string {
+	line := fmt.Sprintf("planguard: %d errors, %d warnings, %d info, %d resources",
+		len(r.filterBySeverity("error")), len(r.filterBySeverity("warning")), len(r.filterBySeverity("info")), resources)
+	if r.score != nil {
+		line += fmt.Sprintf(", risk score %g", *r.score)
+	}
+	if r.partialReason != "" {
+		line += " (partial)"
+	}
+	return line
+}
+
+// FormatRuleStats lists the rules by the time spent evaluating them,
+// slowest first. It returns "" when no stats were recorded.
+func (r *Reporter) FormatRuleStats() string {
+	if len(r.ruleStats) == 0 {
+		return ""
+	}
+
+	var total float64
+	for _, rs := range r.ruleStats {
+		total += rs.Seconds
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("⏱️  RULE STATS: %d rules, %s\n", len(r.ruleStats), formatSeconds(total)))
+	output.WriteString(strings.Repeat("-", 50) + "\n")
+	for _, rs := range r.ruleStats {
+		share := 0.0
+		if total > 0 {
+			share = rs.Seconds / total * 100
+		}
+		output.WriteString(fmt.Sprintf("  %10s %5.1f%%  %-40s evaluated: %d  violations: %d\n",
+			formatSeconds(rs.Seconds), share, rs.RuleID, rs.Evaluated, rs.Violations))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
+
+// formatSeconds formats a duration in seconds to microsecond precision,
+// e.g. "1.5ms"
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond).String()
+}
+
+// LabelSummary counts violations for a single label value across all
+// scan targets carrying that label
+type LabelSummary struct {
+	Key      string
+	Value    string
+	Errors   int
+	Warnings int
+	Infos    int
+	Excepted int
+}
+
+// LabelSummaries groups violations by label, sorted by key then value.
+// It returns nil when no violation carries labels.
+func (r *Reporter) LabelSummaries() []LabelSummary {
+	groups := make(map[[2]string]*LabelSummary)
+	group := func(key, value string) *LabelSummary {
+		id := [2]string{key, value}
+		if groups[id] == nil {
+			groups[id] = &LabelSummary{Key: key, Value: value}
+		}
+		return groups[id]
+	}
+
+	for _, v := range r.violations {
+		for key, value := range v.Labels {
+			ls := group(key, value)
+			switch v.Severity {
+			case "error":
+				ls.Errors++
+			case "warning":
+				ls.Warnings++
+			case "info":
+				ls.Infos++
+			}
+		}
+	}
+	for _, fv := range r.filteredViolations {
+		for key, value := range fv.Violation.Labels {
+			group(key, value).Excepted++
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	summaries := make([]LabelSummary, 0, len(groups))
+	for _, ls := range groups {
+		summaries = append(summaries, *ls)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Key != summaries[j].Key {
+			return summaries[i].Key < summaries[j].Key
+		}
+		return summaries[i].Value < summaries[j].Value
+	})
+	return summaries
+}
+
+// FormatJSON formats violations as JSON. Output is an array of violations;
+// when scan targets are labeled, a baseline is in use, exceptions are
+// expiring, results are partial, or rule stats, compliance results, a risk
+// score, or top issues were recorded it is an object that also carries the
+// per-label summaries, known violations, expiring exceptions, why the
+// results are partial, the rule stats, the compliance controls, the score,
+// and the top rules and resources. FormatJSONV2 always writes the object.
 func (r *Reporter) FormatJSON() (string, error) {
+	if len(r.LabelSummaries()) > 0 || r.baseline || len(r.expiringExceptions) > 0 || r.partialReason != "" || len(r.ruleStats) > 0 || len(r.compliance) > 0 || r.score != nil || len(r.topRules) > 0 || r.showFiltered {
+		return r.FormatJSONV2()
+	}
 	data, err := json.MarshalIndent(r.violations, "", "  ")
 	if err != nil {
 		return "", err
@@ -133,8 +447,63 @@ func (r *Reporter) FormatJSON() (string, error) {
 	return string(data), nil
 }
 
+// FormatJSONV2 formats violations as a JSON object, whatever was recorded:
+// the violations and the number of violations covered by exceptions, plus,
+// when recorded, the excepted violations themselves (with -show-filtered),
+// known violations, per-label summaries, expiring exceptions, why the
+// results are partial, the rule stats, the compliance controls, the score,
+// and the top rules and resources.
+func (r *Reporter) FormatJSONV2() (string, error) {
+	violations := r.violations
+	if violations == nil {
+		violations = []config.Violation{}
+	}
+	var excepted []config.FilteredViolation
+	if r.showFiltered {
+		excepted = r.filteredViolations
+		if excepted == nil {
+			excepted = []config.FilteredViolation{}
+		}
+	}
+	report := struct {
+		Violations         []config.Violation
+		ExceptedCount      int
+		Excepted           []config.FilteredViolation `json:",omitempty"`
+		KnownViolations    []config.Violation         `json:",omitempty"`
+		LabelSummaries     []LabelSummary             `json:",omitempty"`
+		ExpiringExceptions []config.ExpiringException `json:",omitempty"`
+		Partial            string                     `json:",omitempty"`
+		RuleStats          []RuleStats                `json:",omitempty"`
+		Compliance         []ControlResult            `json:",omitempty"`
+		Score              *float64                   `json:",omitempty"`
+		TopRules           []TopIssue                 `json:",omitempty"`
+		TopResources       []TopIssue                 `json:",omitempty"`
+	}{violations, len(r.filteredViolations), excepted, r.knownViolations, r.LabelSummaries(), r.expiringExceptions, r.partialReason, r.ruleStats, r.compliance, r.score, r.topRules, r.topResources}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // FormatSARIF formats violations as SARIF (Static Analysis Results Interchange Format)
 func (r *Reporter) FormatSARIF() (string, error) {
+	rules := r.buildSARIFRules()
+	results := r.buildSARIFResults()
+	if r.showFiltered {
+		results = append(results, r.sarifSuppressedResults()...)
+	}
+	ruleIndex := make(map[string]int, len(rules))
+	for i, rule := range rules {
+		ruleIndex[rule["id"].(string)] = i
+	}
+	for _, result := range results {
+		if i, ok := ruleIndex[result["ruleId"].(string)]; ok {
+			result["ruleIndex"] = i
+		}
+	}
+
 	sarif := map[string]interface{}{
 		"version": "2.1.0",
 		"$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
@@ -145,14 +514,36 @@ func (r *Reporter) FormatSARIF() (string, error) {
 						"name":           "Terraform Guardian",
 						"informationUri": "https://github.com/jonathanhle/planguard",
 						"version":        "1.0.0",
-						"rules":          r.buildSARIFRules(),
+						"rules":          rules,
 					},
 				},
-				"results": r.buildSARIFResults(),
+				"results": results,
 			},
 		},
 	}
 
+	// The count is always present, so a report shows nothing was excepted
+	properties := map[string]interface{}{"exceptedCount": len(r.filteredViolations)}
+	if summaries := r.LabelSummaries(); len(summaries) > 0 {
+		properties["labelSummaries"] = summaries
+	}
+	if r.score != nil {
+		properties["riskScore"] = *r.score
+	}
+	runs := sarif["runs"].([]map[string]interface{})
+	runs[0]["properties"] = properties
+
+	if r.partialReason != "" {
+		runs[0]["invocations"] = []map[string]interface{}{
+			{
+				"executionSuccessful": false,
+				"toolExecutionNotifications": []map[string]interface{}{
+					{"level": "error", "message": map[string]interface{}{"text": "Partial results: " + r.partialReason}},
+				},
+			},
+		}
+	}
+
 	data, err := json.MarshalIndent(sarif, "", "  ")
 	if err != nil {
 		return "", err
@@ -160,40 +551,141 @@ func (r *Reporter) FormatSARIF() (string, error) {
 	return string(data), nil
 }
 
+// buildSARIFRules describes the loaded rules, and any other rule with
+// violations, sorted by ID so the output is stable between runs
 func (r *Reporter) buildSARIFRules() []map[string]interface{} {
-	// Build unique rules list
-	ruleMap := make(map[string]config.Violation)
-	for _, v := range r.violations {
+	ruleMap := make(map[string]map[string]interface{})
+	for _, rule := range r.rules {
+		remediation := ""
+		if rule.Remediation != nil {
+			remediation = *rule.Remediation
+		}
+		ruleMap[rule.ID] = r.sarifRule(rule.ID, rule.Name, rule.Message, rule.Severity, remediation, rule.References, rule.Tags, rule.Metadata)
+	}
+	for _, v := range append(append([]config.Violation(nil), r.violations...), r.knownViolations...) {
 		if _, exists := ruleMap[v.RuleID]; !exists {
-			ruleMap[v.RuleID] = v
+			ruleMap[v.RuleID] = r.sarifRule(v.RuleID, v.RuleName, v.Message, v.Severity, v.Remediation, nil, nil, v.Metadata)
 		}
 	}
 
+	ids := make([]string, 0, len(ruleMap))
+	for id := range ruleMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
 	var rules []map[string]interface{}
-	for id, v := range ruleMap {
-		rule := map[string]interface{}{
-			"id":   id,
-			"name": v.RuleName,
-			"shortDescription": map[string]interface{}{
-				"text": v.RuleName,
-			},
-			"fullDescription": map[string]interface{}{
-				"text": v.Message,
-			},
-			"defaultConfiguration": map[string]interface{}{
-				"level": r.severityToLevel(v.Severity),
-			},
+	for _, id := range ids {
+		rules = append(rules, ruleMap[id])
+	}
+	return rules
+}
+
+// sarifRule builds a SARIF reportingDescriptor. The metadata's docs_url,
+// or else the first reference, becomes its helpUri, and the severity is
+// also given as a security-severity score, which GitHub code scanning uses
+// to rank alerts of rules tagged "security".
+func (r *Reporter) sarifRule(id, name, message, severity, remediation string, references, tags []string, metadata *config.RuleMetadata) map[string]interface{} {
+	rule := map[string]interface{}{
+		"id":   id,
+		"name": name,
+		"shortDescription": map[string]interface{}{
+			"text": name,
+		},
+		"fullDescription": map[string]interface{}{
+			"text": message,
+		},
+		"defaultConfiguration": map[string]interface{}{
+			"level": r.severityToLevel(severity),
+		},
+	}
+	if remediation != "" {
+		rule["help"] = map[string]interface{}{
+			"text":     remediation,
+			"markdown": remediation,
 		}
-		rules = append(rules, rule)
+	}
+	if metadata != nil && metadata.DocsURL != "" {
+		rule["helpUri"] = metadata.DocsURL
+	} else if len(references) > 0 {
+		rule["helpUri"] = references[0]
 	}
 
-	return rules
+	properties := map[string]interface{}{
+		"tags":              append([]string{"security"}, tags...),
+		"security-severity": securitySeverity(severity),
+	}
+	if len(references) > 1 {
+		properties["references"] = references
+	}
+	if metadata := sarifMetadata(metadata); metadata != nil {
+		properties["metadata"] = metadata
+	}
+	rule["properties"] = properties
+	return rule
+}
+
+// sarifMetadata converts a rule's metadata to a SARIF property, or nil if
+// it has none
+func sarifMetadata(metadata *config.RuleMetadata) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	properties := make(map[string]string)
+	for key, value := range map[string]string{
+		"owner":   metadata.Owner,
+		"docsUrl": metadata.DocsURL,
+		"cisId":   metadata.CISID,
+		"created": metadata.Created,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return properties
+}
+
+// securitySeverity maps a severity to a SARIF security-severity score:
+// high for errors, medium for warnings, and low for info
+func securitySeverity(severity string) string {
+	switch severity {
+	case "error":
+		return "8.0"
+	case "warning":
+		return "5.0"
+	default:
+		return "2.0"
+	}
 }
 
 func (r *Reporter) buildSARIFResults() []map[string]interface{} {
+	results := r.sarifResults(r.violations, "new")
+	return append(results, r.sarifResults(r.knownViolations, "unchanged")...)
+}
+
+// sarifResults converts violations to SARIF results. When a baseline is in
+// use, results carry baselineState, if given, so viewers can tell new
+// findings apart.
+func (r *Reporter) sarifResults(violations []config.Violation, baselineState string) []map[string]interface{} {
 	var results []map[string]interface{}
 
-	for _, v := range r.violations {
+	for _, v := range violations {
+		region := map[string]interface{}{
+			"startLine":   v.Line,
+			"startColumn": v.Column,
+		}
+		physicalLocation := map[string]interface{}{
+			"artifactLocation": map[string]interface{}{
+				"uri": v.File,
+			},
+			"region": region,
+		}
+		if v.Source != nil {
+			addSARIFSnippet(physicalLocation, region, v)
+		}
 		result := map[string]interface{}{
 			"ruleId": v.RuleID,
 			"level":  r.severityToLevel(v.Severity),
@@ -201,25 +693,81 @@ func (r *Reporter) buildSARIFResults() []map[string]interface{} {
 				"text": v.Message,
 			},
 			"locations": []map[string]interface{}{
-				{
-					"physicalLocation": map[string]interface{}{
-						"artifactLocation": map[string]interface{}{
-							"uri": v.File,
-						},
-						"region": map[string]interface{}{
-							"startLine":   v.Line,
-							"startColumn": v.Column,
-						},
-					},
-				},
+				{"physicalLocation": physicalLocation},
 			},
 		}
+		if r.baseline && baselineState != "" {
+			result["baselineState"] = baselineState
+		}
+		if v.Remediation != "" {
+			result["fixes"] = sarifFixes(v)
+		}
+		if v.Fingerprint != "" {
+			result["partialFingerprints"] = map[string]string{"planguard/v1": v.Fingerprint}
+		}
+		properties := map[string]interface{}{}
+		if len(v.Labels) > 0 {
+			properties["labels"] = v.Labels
+		}
+		if v.Blame != nil {
+			properties["blame"] = v.Blame
+		}
+		// Repeated from the rule so each result can be routed on its own
+		if metadata := sarifMetadata(v.Metadata); metadata != nil {
+			properties["metadata"] = metadata
+		}
+		if len(properties) > 0 {
+			result["properties"] = properties
+		}
 		results = append(results, result)
 	}
 
 	return results
 }
 
+// addSARIFSnippet adds the violating line to a result's region and the
+// surrounding source as the context region of its location
+func addSARIFSnippet(physicalLocation, region map[string]interface{}, v config.Violation) {
+	if i := v.Line - v.Source.StartLine; i >= 0 && i < len(v.Source.Lines) {
+		region["snippet"] = map[string]interface{}{"text": v.Source.Lines[i]}
+	}
+	physicalLocation["contextRegion"] = map[string]interface{}{
+		"startLine": v.Source.StartLine,
+		"endLine":   v.Source.StartLine + len(v.Source.Lines) - 1,
+		"snippet":   map[string]interface{}{"text": strings.Join(v.Source.Lines, "\n") + "\n"},
+	}
+}
+
+// sarifFixes describes the fix for a violation with its remediation.
+// Remediation is guidance rather than an edit, so the fix's only
+// replacement is empty and anchored at the violation.
+func sarifFixes(v config.Violation) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"description": map[string]interface{}{
+				"text": strings.TrimSpace(v.Remediation),
+			},
+			"artifactChanges": []map[string]interface{}{
+				{
+					"artifactLocation": map[string]interface{}{
+						"uri": v.File,
+					},
+					"replacements": []map[string]interface{}{
+						{
+							"deletedRegion": map[string]interface{}{
+								"startLine":   v.Line,
+								"startColumn": v.Column,
+								"endLine":     v.Line,
+								"endColumn":   v.Column,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func (r *Reporter) severityToLevel(severity string) string {
 	switch severity {
 	case "error":
@@ -243,22 +791,82 @@ func (r *Reporter) filterBySeverity(severity string) []config.Violation {
 	return filtered
 }
 
-// ShouldFail determines if the scan should fail based on severity
+// ShouldFail determines if the scan should fail based on severity.
+// Violations hidden by SetSeverityThreshold still count.
 func (r *Reporter) ShouldFail(failOn string) bool {
-	if len(r.violations) == 0 {
-		return false
-	}
-
 	switch failOn {
-	case "error":
-		return len(r.filterBySeverity("error")) > 0
 	case "warning":
-		return len(r.filterBySeverity("warning")) > 0 || len(r.filterBySeverity("error")) > 0
+		return r.hasSeverity("warning") || r.hasSeverity("error")
 	case "info":
-		return len(r.violations) > 0
+		return len(r.violations) > 0 || len(r.hiddenViolations) > 0
 	default:
-		return len(r.filterBySeverity("error")) > 0
+		return r.hasSeverity("error")
+	}
+}
+
+// ExitCode looks up the exit code for the most severe of the violations in
+// codes, keyed by severity, including violations hidden by
+// SetSeverityThreshold. It returns 0 when there are no violations or their
+// most severe severity has no code.
+func (r *Reporter) ExitCode(codes map[string]int) int {
+	for _, severity := range []string{"error", "warning", "info"} {
+		if r.hasSeverity(severity) {
+			return codes[severity]
+		}
+	}
+	return 0
+}
+
+// hasSeverity reports whether any violation, shown or hidden, has severity
+func (r *Reporter) hasSeverity(severity string) bool {
+	for _, violations := range [][]config.Violation{r.violations, r.hiddenViolations} {
+		for _, v := range violations {
+			if v.Severity == severity {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+// formatLabels renders labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// formatBlame summarizes who last changed a line, e.g.
+// "3f2a9c1 Jane Doe <jane@example.com>, 2021-03-04"
+func formatBlame(b config.Blame) string {
+	if b.Uncommitted {
+		return "not committed yet"
+	}
+	commit := b.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return fmt.Sprintf("%s %s <%s>, %s", commit, b.Author, b.AuthorEmail, b.Date.Format("2006-01-02"))
+}
+
+// formatSource numbers the lines of a violation's source, marking the
+// violating line with ">"
+func (r *Reporter) formatSource(v config.Violation) string {
+	var output strings.Builder
+	width := len(fmt.Sprint(v.Source.StartLine + len(v.Source.Lines) - 1))
+	for i, line := range v.Source.Lines {
+		number := v.Source.StartLine + i
+		text := fmt.Sprintf("%*d | %s", width, number, line)
+		if number == v.Line {
+			output.WriteString("  > " + r.paint(severityColor(v.Severity), text) + "\n")
+		} else {
+			output.WriteString("    " + text + "\n")
+		}
+	}
+	return output.String()
 }
 
 func indent(text string, spaces int) string {
@@ -2,8 +2,10 @@ package reporter
 
 import (
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jonathanhle/planguard/pkg/config"
 )
@@ -141,6 +143,31 @@ func TestFormatTextWithRemediation(t *testing.T) {
 	}
 }
 
+func TestFormatTextWithDeprecation(t *testing.T) {
+	violations := []config.Violation{
+		{
+			RuleID:       "old_rule",
+			RuleName:     "Old",
+			Severity:     "warning",
+			Message:      "Test message",
+			File:         "test.tf",
+			Line:         10,
+			ResourceType: "aws_instance",
+			ResourceName: "test",
+			Deprecation:  "deprecated, superseded by new_rule",
+		},
+	}
+
+	reporter := NewReporter(violations, []config.FilteredViolation{})
+	reporter.SetColor(false)
+	if output := reporter.FormatText(); !strings.Contains(output, "Rule: Old (old_rule) [deprecated, superseded by new_rule]") {
+		t.Errorf("Expected the rule's deprecation, got:\n%s", output)
+	}
+	if output := reporter.FormatMarkdown(); !strings.Contains(output, "Rule deprecated, superseded by new_rule") {
+		t.Errorf("Expected the rule's deprecation in Markdown, got:\n%s", output)
+	}
+}
+
 func TestFormatTextWithExceptions(t *testing.T) {
 	violation := config.Violation{
 		RuleID:       "test",
@@ -374,6 +401,32 @@ func TestFormatSARIF(t *testing.T) {
 	}
 }
 
+func TestFormatSARIFStable(t *testing.T) {
+	var violations []config.Violation
+	for _, id := range []string{"rule_c", "rule_a", "rule_d", "rule_b"} {
+		violations = append(violations, config.Violation{RuleID: id, RuleName: id, Severity: "error", File: "main.tf"})
+	}
+
+	first, err := NewReporter(violations, nil).FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		output, err := NewReporter(violations, nil).FormatSARIF()
+		if err != nil {
+			t.Fatalf("FormatSARIF() error = %v", err)
+		}
+		if output != first {
+			t.Fatalf("FormatSARIF() output differs between runs:\n%s\n---\n%s", first, output)
+		}
+	}
+
+	a, b := strings.Index(first, `"id": "rule_a"`), strings.Index(first, `"id": "rule_b"`)
+	if a < 0 || b < 0 || a > b {
+		t.Errorf("Expected SARIF rules sorted by ID, got:\n%s", first)
+	}
+}
+
 func TestFormatSARIFEmpty(t *testing.T) {
 	reporter := NewReporter([]config.Violation{}, []config.FilteredViolation{})
 	output, err := reporter.FormatSARIF()
@@ -492,6 +545,85 @@ func TestShouldFailDefault(t *testing.T) {
 	}
 }
 
+func TestExitCode(t *testing.T) {
+	codes := map[string]int{"error": 3, "warning": 2}
+	tests := []struct {
+		name       string
+		severities []string
+		want       int
+	}{
+		{"no violations", nil, 0},
+		{"errors and warnings", []string{"warning", "error"}, 3},
+		{"warnings only", []string{"warning", "info"}, 2},
+		{"unmapped severity", []string{"info"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []config.Violation
+			for _, severity := range tt.severities {
+				violations = append(violations, config.Violation{RuleID: "r", Severity: severity})
+			}
+			if got := NewReporter(violations, nil).ExitCode(codes); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSeverityThreshold(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "e1", Severity: "error"},
+		{RuleID: "w1", Severity: "warning"},
+		{RuleID: "i1", Severity: "info"},
+	}
+	tests := []struct {
+		threshold  string
+		wantHidden int
+		wantShown  []string
+	}{
+		{"info", 0, []string{"e1", "w1", "i1"}},
+		{"warning", 1, []string{"e1", "w1"}},
+		{"error", 2, []string{"e1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			reporter := NewReporter(violations, nil)
+			if hidden := reporter.SetSeverityThreshold(tt.threshold); hidden != tt.wantHidden {
+				t.Errorf("SetSeverityThreshold() = %d, want %d", hidden, tt.wantHidden)
+			}
+			var shown []string
+			for _, v := range reporter.violations {
+				shown = append(shown, v.RuleID)
+			}
+			if !reflect.DeepEqual(shown, tt.wantShown) {
+				t.Errorf("shown violations = %v, want %v", shown, tt.wantShown)
+			}
+
+			// Hidden violations still decide the exit status
+			if !reporter.ShouldFail("info") {
+				t.Error("ShouldFail(info) = false, want true")
+			}
+			if got := reporter.ExitCode(map[string]int{"info": 4}); got != 0 {
+				t.Errorf("ExitCode() = %d, want 0 for an error", got)
+			}
+			if got := reporter.ExitCode(map[string]int{"error": 3}); got != 3 {
+				t.Errorf("ExitCode() = %d, want 3", got)
+			}
+		})
+	}
+
+	reporter := NewReporter([]config.Violation{{RuleID: "w1", Severity: "warning"}}, nil)
+	reporter.SetSeverityThreshold("error")
+	if !reporter.ShouldFail("warning") {
+		t.Error("ShouldFail(warning) = false with a hidden warning, want true")
+	}
+	if !strings.Contains(reporter.FormatText(), "No violations found") {
+		t.Errorf("FormatText() should not list hidden violations, got:\n%s", reporter.FormatText())
+	}
+}
+
 func TestFilterBySeverity(t *testing.T) {
 	violations := []config.Violation{
 		{RuleID: "e1", Severity: "error"},
@@ -625,6 +757,171 @@ func TestBuildSARIFRules(t *testing.T) {
 	}
 }
 
+func TestFormatSARIFRuleMetadata(t *testing.T) {
+	remediation := "Set acl to private"
+	rules := []config.Rule{
+		{ID: "s3_public", Name: "No public buckets", Severity: "error", Message: "Buckets must not be public", Remediation: &remediation,
+			References: []string{"https://docs.example.com/s3", "https://cis.example.com/2.1"}, Tags: []string{"s3"}},
+		{ID: "tags", Name: "Required tags", Severity: "info", Message: "Tag everything"},
+	}
+	violations := []config.Violation{
+		{RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", Message: "Bucket data is public", File: "main.tf", Line: 3, Column: 1, Remediation: remediation},
+		{RuleID: "custom", RuleName: "Custom", Severity: "warning", Message: "Custom", File: "main.tf", Line: 9, Column: 1},
+	}
+
+	reporter := NewReporter(violations, nil)
+	reporter.SetRules(rules)
+	output, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string
+						HelpURI              string `json:"helpUri"`
+						Help                 struct{ Text string }
+						DefaultConfiguration struct{ Level string }
+						Properties           struct {
+							Tags             []string
+							SecuritySeverity string `json:"security-severity"`
+							References       []string
+						}
+					}
+				}
+			}
+			Results []struct {
+				RuleID    string
+				RuleIndex *int
+				Fixes     []struct {
+					Description     struct{ Text string }
+					ArtifactChanges []struct {
+						ArtifactLocation struct{ URI string }
+						Replacements     []struct {
+							DeletedRegion struct{ StartLine, EndLine int }
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+
+	run := log.Runs[0]
+	var ids []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ids = append(ids, rule.ID)
+	}
+	if strings.Join(ids, ",") != "custom,s3_public,tags" {
+		t.Fatalf("rules = %v, want every loaded rule and violated rules, sorted", ids)
+	}
+
+	s3 := run.Tool.Driver.Rules[1]
+	if s3.HelpURI != "https://docs.example.com/s3" {
+		t.Errorf("helpUri = %q", s3.HelpURI)
+	}
+	if s3.Help.Text != remediation {
+		t.Errorf("help = %q", s3.Help.Text)
+	}
+	if s3.DefaultConfiguration.Level != "error" || s3.Properties.SecuritySeverity != "8.0" {
+		t.Errorf("level = %q, security-severity = %q", s3.DefaultConfiguration.Level, s3.Properties.SecuritySeverity)
+	}
+	if strings.Join(s3.Properties.Tags, ",") != "security,s3" || len(s3.Properties.References) != 2 {
+		t.Errorf("properties = %+v", s3.Properties)
+	}
+	if tags := run.Tool.Driver.Rules[2]; tags.DefaultConfiguration.Level != "note" || tags.Properties.SecuritySeverity != "2.0" || tags.HelpURI != "" {
+		t.Errorf("tags rule = %+v", tags)
+	}
+
+	for i, want := range []int{1, 0} {
+		if index := run.Results[i].RuleIndex; index == nil || *index != want {
+			t.Errorf("results[%d].ruleIndex = %v, want %d", i, index, want)
+		}
+	}
+	fixes := run.Results[0].Fixes
+	if len(fixes) != 1 || fixes[0].Description.Text != remediation {
+		t.Fatalf("fixes = %+v", fixes)
+	}
+	change := fixes[0].ArtifactChanges[0]
+	if change.ArtifactLocation.URI != "main.tf" || change.Replacements[0].DeletedRegion.StartLine != 3 {
+		t.Errorf("artifactChanges = %+v", fixes[0].ArtifactChanges)
+	}
+	if len(run.Results[1].Fixes) != 0 {
+		t.Errorf("Expected no fixes without remediation, got %+v", run.Results[1].Fixes)
+	}
+}
+
+func TestFormatSARIFOwnerMetadata(t *testing.T) {
+	metadata := &config.RuleMetadata{Owner: "team-storage", DocsURL: "https://wiki.example.com/s3", CISID: "2.1.1"}
+	rules := []config.Rule{
+		{ID: "s3_public", Name: "No public buckets", Severity: "error", Message: "Buckets must not be public",
+			References: []string{"https://docs.example.com/s3"}, Metadata: metadata},
+		{ID: "tags", Name: "Required tags", Severity: "info", Message: "Tag everything", Metadata: &config.RuleMetadata{}},
+	}
+	violations := []config.Violation{
+		{RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", Message: "Bucket data is public", File: "main.tf", Line: 3, Column: 1, Metadata: metadata},
+	}
+
+	reporter := NewReporter(violations, nil)
+	reporter.SetRules(rules)
+	output, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						HelpURI    string `json:"helpUri"`
+						Properties struct {
+							Metadata map[string]string
+						}
+					}
+				}
+			}
+			Results []struct {
+				Properties struct {
+					Metadata map[string]string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+
+	run := log.Runs[0]
+	want := map[string]string{"owner": "team-storage", "docsUrl": "https://wiki.example.com/s3", "cisId": "2.1.1"}
+	s3 := run.Tool.Driver.Rules[0]
+	if s3.HelpURI != "https://wiki.example.com/s3" {
+		t.Errorf("helpUri = %q, want the docs_url", s3.HelpURI)
+	}
+	if !reflect.DeepEqual(s3.Properties.Metadata, want) {
+		t.Errorf("rule metadata = %v, want %v", s3.Properties.Metadata, want)
+	}
+	if tags := run.Tool.Driver.Rules[1]; tags.Properties.Metadata != nil {
+		t.Errorf("empty metadata = %v, want none", tags.Properties.Metadata)
+	}
+	if got := run.Results[0].Properties.Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("result metadata = %v, want %v", got, want)
+	}
+
+	jsonOutput, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	if !strings.Contains(jsonOutput, `"Owner": "team-storage"`) || !strings.Contains(jsonOutput, `"CISID": "2.1.1"`) || strings.Contains(jsonOutput, `"Created"`) {
+		t.Errorf("JSON output does not carry the metadata:\n%s", jsonOutput)
+	}
+}
+
 func TestBuildSARIFResults(t *testing.T) {
 	violations := []config.Violation{
 		{
@@ -670,3 +967,436 @@ func TestBuildSARIFResults(t *testing.T) {
 		t.Error("Start column not set correctly")
 	}
 }
+
+func TestLabelSummaries(t *testing.T) {
+	prod := map[string]string{"env": "prod", "team": "payments"}
+	dev := map[string]string{"env": "dev", "team": "payments"}
+
+	violations := []config.Violation{
+		{RuleID: "a", Severity: "error", Labels: prod},
+		{RuleID: "b", Severity: "warning", Labels: prod},
+		{RuleID: "c", Severity: "error", Labels: dev},
+	}
+	filtered := []config.FilteredViolation{
+		{Violation: config.Violation{RuleID: "d", Severity: "error", Labels: prod}},
+	}
+
+	summaries := NewReporter(violations, filtered).LabelSummaries()
+
+	expected := []LabelSummary{
+		{Key: "env", Value: "dev", Errors: 1},
+		{Key: "env", Value: "prod", Errors: 1, Warnings: 1, Excepted: 1},
+		{Key: "team", Value: "payments", Errors: 2, Warnings: 1, Excepted: 1},
+	}
+	if len(summaries) != len(expected) {
+		t.Fatalf("Expected %d summaries, got %+v", len(expected), summaries)
+	}
+	for i := range expected {
+		if summaries[i] != expected[i] {
+			t.Errorf("Summary %d = %+v, want %+v", i, summaries[i], expected[i])
+		}
+	}
+
+	if NewReporter([]config.Violation{{RuleID: "a", Severity: "error"}}, nil).LabelSummaries() != nil {
+		t.Error("Expected no summaries for unlabeled violations")
+	}
+}
+
+func TestFormatWithLabels(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "test", RuleName: "Test", Severity: "error", File: "main.tf", Labels: map[string]string{"env": "prod"}},
+	}
+	reporter := NewReporter(violations, []config.FilteredViolation{})
+
+	text := reporter.FormatText()
+	if !strings.Contains(text, "SUMMARY BY LABEL") || !strings.Contains(text, "env=prod") {
+		t.Errorf("Text output should include label summary, got:\n%s", text)
+	}
+	if !strings.Contains(text, "Labels: env=prod") {
+		t.Errorf("Text output should show violation labels, got:\n%s", text)
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations     []config.Violation
+		LabelSummaries []LabelSummary
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("Labeled JSON output should be an object: %v", err)
+	}
+	if len(parsed.Violations) != 1 || parsed.Violations[0].Labels["env"] != "prod" {
+		t.Errorf("Expected labeled violation, got %+v", parsed.Violations)
+	}
+	if len(parsed.LabelSummaries) != 1 || parsed.LabelSummaries[0].Errors != 1 {
+		t.Errorf("Expected label summary, got %+v", parsed.LabelSummaries)
+	}
+
+	sarif, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(sarif, "labelSummaries") {
+		t.Errorf("SARIF output should include label summaries in run properties")
+	}
+}
+
+func TestFormatWithKnownViolations(t *testing.T) {
+	known := []config.Violation{
+		{RuleID: "old", RuleName: "Old", Severity: "error", File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+	}
+
+	reporter := NewReporter([]config.Violation{}, []config.FilteredViolation{})
+	reporter.SetKnownViolations(known)
+
+	if reporter.ShouldFail("error") {
+		t.Error("Known violations should not fail the scan")
+	}
+	if text := reporter.FormatText(); !strings.Contains(text, "No new violations") {
+		t.Errorf("Expected no-new-violations message, got:\n%s", text)
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations      []config.Violation
+		KnownViolations []config.Violation
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with a baseline should be an object: %v", err)
+	}
+	if parsed.Violations == nil || len(parsed.Violations) != 0 || len(parsed.KnownViolations) != 1 {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+
+	withNew := NewReporter([]config.Violation{{RuleID: "new", Severity: "error", File: "main.tf"}}, nil)
+	withNew.SetKnownViolations(known)
+
+	text := withNew.FormatText()
+	if !strings.Contains(text, "KNOWN (baseline): 1") || !strings.Contains(text, "1 known") {
+		t.Errorf("Expected known section and total, got:\n%s", text)
+	}
+
+	sarif, err := withNew.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(sarif, `"baselineState": "new"`) || !strings.Contains(sarif, `"baselineState": "unchanged"`) {
+		t.Errorf("SARIF results should carry baselineState, got:\n%s", sarif)
+	}
+}
+
+func TestFormatWithExpiringExceptions(t *testing.T) {
+	expiring := []config.ExpiringException{
+		{Exception: config.Exception{Rules: []string{"old"}, Reason: "Legacy bucket", ApprovedBy: "security"}, ExpiresAt: "2024-01-01", Expired: true},
+		{Exception: config.Exception{Rules: []string{"soon"}, Reason: "Migration", ApprovedBy: "platform"}, ExpiresAt: "2024-02-01", DaysLeft: 3},
+	}
+
+	reporter := NewReporter(nil, nil)
+	reporter.SetExpiringExceptions(expiring)
+
+	text := reporter.FormatText()
+	for _, want := range []string{"No violations found", "EXCEPTION EXPIRY: 2", "EXPIRED 2024-01-01", "expires 2024-02-01 (in 3 days)", "Reason: Migration"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text)
+		}
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations         []config.Violation
+		ExpiringExceptions []config.ExpiringException
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with expiring exceptions should be an object: %v", err)
+	}
+	if len(parsed.ExpiringExceptions) != 2 || !parsed.ExpiringExceptions[0].Expired {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+}
+
+func TestFormatPartial(t *testing.T) {
+	reporter := NewReporter([]config.Violation{{RuleID: "found", RuleName: "Found", Severity: "error", File: "main.tf"}}, nil)
+	reporter.SetPartial("scan timed out after 5m0s")
+
+	if text := reporter.FormatText(); !strings.HasPrefix(text, "⚠️  PARTIAL RESULTS: scan timed out after 5m0s") {
+		t.Errorf("Expected partial warning first, got:\n%s", text)
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations []config.Violation
+		Partial    string
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with partial results should be an object: %v", err)
+	}
+	if parsed.Partial != "scan timed out after 5m0s" || len(parsed.Violations) != 1 {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+
+	sarif, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(sarif, `"executionSuccessful": false`) {
+		t.Errorf("SARIF should mark the run unsuccessful, got:\n%s", sarif)
+	}
+}
+
+func TestFormatRuleStats(t *testing.T) {
+	reporter := NewReporter(nil, nil)
+	reporter.SetRuleStats([]RuleStats{
+		{RuleID: "fast", Seconds: 0.25, Evaluated: 10},
+		{RuleID: "slow_regex", Seconds: 0.75, Evaluated: 10, Violations: 2},
+	})
+
+	text := reporter.FormatText()
+	if !strings.Contains(text, "RULE STATS: 2 rules, 1s") {
+		t.Errorf("Expected stats header, got:\n%s", text)
+	}
+	slow, fast := strings.Index(text, "slow_regex"), strings.Index(text, "fast")
+	if slow < 0 || fast < 0 || slow > fast {
+		t.Errorf("Expected slowest rule first, got:\n%s", text)
+	}
+	if !strings.Contains(text, "750ms  75.0%") {
+		t.Errorf("Expected share of total time, got:\n%s", text)
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations []config.Violation
+		RuleStats  []RuleStats
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with rule stats should be an object: %v", err)
+	}
+	if len(parsed.RuleStats) != 2 || parsed.RuleStats[0].RuleID != "slow_regex" || parsed.RuleStats[0].Violations != 2 {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+}
+
+func TestFormatOneline(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "a", Severity: "error"},
+		{RuleID: "b", Severity: "error"},
+		{RuleID: "c", Severity: "warning"},
+		{RuleID: "d", Severity: "info"},
+	}
+
+	tests := []struct {
+		name       string
+		violations []config.Violation
+		resources  int
+		partial    string
+		score      *float64
+		want       string
+	}{
+		{name: "clean", resources: 120, want: "planguard: 0 errors, 0 warnings, 0 info, 120 resources"},
+		{name: "violations", violations: violations, resources: 1, want: "planguard: 2 errors, 1 warnings, 1 info, 1 resources"},
+		{name: "partial", violations: violations[:1], resources: 7, partial: "scan interrupted", want: "planguard: 1 errors, 0 warnings, 0 info, 7 resources (partial)"},
+		{name: "score", violations: violations[:1], resources: 3, score: ptrFloat(10), want: "planguard: 1 errors, 0 warnings, 0 info, 3 resources, risk score 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := NewReporter(tt.violations, nil)
+			if tt.partial != "" {
+				reporter.SetPartial(tt.partial)
+			}
+			if tt.score != nil {
+				reporter.SetScore(*tt.score)
+			}
+			if got := reporter.FormatOneline(tt.resources); got != tt.want {
+				t.Errorf("FormatOneline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBlame(t *testing.T) {
+	committed := config.Violation{
+		RuleID: "a", RuleName: "A", Severity: "error", File: "main.tf", Line: 3,
+		Blame: &config.Blame{
+			Commit:      "3f2a9c1d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b",
+			Author:      "Jane Doe",
+			AuthorEmail: "jane@example.com",
+			Date:        time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	uncommitted := config.Violation{
+		RuleID: "b", RuleName: "B", Severity: "error", File: "main.tf", Line: 9,
+		Blame: &config.Blame{Uncommitted: true},
+	}
+	reporter := NewReporter([]config.Violation{committed, uncommitted}, nil)
+
+	text := reporter.FormatText()
+	for _, want := range []string{"Blame: 3f2a9c1 Jane Doe <jane@example.com>, 2021-03-04", "Blame: not committed yet"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text)
+		}
+	}
+
+	sarif, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(sarif, `"Author": "Jane Doe"`) {
+		t.Errorf("Expected blame in SARIF properties, got:\n%s", sarif)
+	}
+}
+
+func TestFormatSource(t *testing.T) {
+	v := config.Violation{
+		RuleID: "s3_public", RuleName: "No public buckets", Severity: "error", File: "main.tf", Line: 10, Column: 3,
+		Source: &config.Source{
+			StartLine: 8,
+			Lines:     []string{`resource "aws_s3_bucket" "data" {`, `  bucket = "data"`, `  acl    = "public-read"`, `}`},
+		},
+	}
+	reporter := NewReporter([]config.Violation{v}, nil)
+
+	text := reporter.FormatText()
+	want := "  Source:\n" +
+		"     8 | resource \"aws_s3_bucket\" \"data\" {\n" +
+		"     9 |   bucket = \"data\"\n" +
+		"  > 10 |   acl    = \"public-read\"\n" +
+		"    11 | }\n"
+	if !strings.Contains(text, want) {
+		t.Errorf("Expected %q in text output, got:\n%s", want, text)
+	}
+
+	sarif, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							Snippet struct{ Text string }
+						}
+						ContextRegion struct {
+							StartLine int
+							EndLine   int
+							Snippet   struct{ Text string }
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(sarif), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	location := log.Runs[0].Results[0].Locations[0].PhysicalLocation
+	if location.Region.Snippet.Text != `  acl    = "public-read"` {
+		t.Errorf("region snippet = %q", location.Region.Snippet.Text)
+	}
+	if location.ContextRegion.StartLine != 8 || location.ContextRegion.EndLine != 11 {
+		t.Errorf("contextRegion lines = %d-%d, want 8-11", location.ContextRegion.StartLine, location.ContextRegion.EndLine)
+	}
+	if !strings.HasPrefix(location.ContextRegion.Snippet.Text, `resource "aws_s3_bucket" "data" {`+"\n") {
+		t.Errorf("contextRegion snippet = %q", location.ContextRegion.Snippet.Text)
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	violations := []config.Violation{{RuleID: "a", Severity: "error", File: "main.tf", Line: 1}}
+
+	for _, v := range [][]config.Violation{nil, violations} {
+		reporter := NewReporter(v, nil)
+		if strings.Contains(reporter.FormatText(), "Risk score") {
+			t.Error("Expected no risk score without SetScore")
+		}
+		reporter.SetScore(12.5)
+		if text := reporter.FormatText(); !strings.Contains(text, "Risk score: 12.5\n") {
+			t.Errorf("Expected risk score in text output, got:\n%s", text)
+		}
+	}
+
+	reporter := NewReporter(nil, nil)
+	reporter.SetScore(0)
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		Violations []config.Violation
+		Score      *float64
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with a score should be an object: %v", err)
+	}
+	if parsed.Score == nil || *parsed.Score != 0 || parsed.Violations == nil {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+
+	reporter = NewReporter(violations, nil)
+	reporter.SetScore(10)
+	sarif, err := reporter.FormatSARIF()
+	if err != nil {
+		t.Fatalf("FormatSARIF() error = %v", err)
+	}
+	if !strings.Contains(sarif, `"riskScore": 10`) {
+		t.Errorf("Expected riskScore in SARIF run properties, got:\n%s", sarif)
+	}
+}
+
+func ptrFloat(f float64) *float64 {
+	return &f
+}
+
+func TestFormatJSONV2(t *testing.T) {
+	tests := []struct {
+		name       string
+		violations []config.Violation
+		filtered   []config.FilteredViolation
+		wantCount  int
+	}{
+		{name: "empty"},
+		{
+			name:       "violations and excepted",
+			violations: []config.Violation{{RuleID: "test", Severity: "error"}},
+			filtered:   []config.FilteredViolation{{Violation: config.Violation{RuleID: "other"}}},
+			wantCount:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := NewReporter(tt.violations, tt.filtered).FormatJSONV2()
+			if err != nil {
+				t.Fatalf("FormatJSONV2() error = %v", err)
+			}
+			var parsed struct {
+				Violations    []config.Violation
+				ExceptedCount *int
+			}
+			if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+				t.Fatalf("Invalid JSON object: %v\n%s", err, output)
+			}
+			if parsed.Violations == nil || len(parsed.Violations) != len(tt.violations) {
+				t.Errorf("Violations = %v, want %d", parsed.Violations, len(tt.violations))
+			}
+			if parsed.ExceptedCount == nil || *parsed.ExceptedCount != tt.wantCount {
+				t.Errorf("ExceptedCount = %v, want %d", parsed.ExceptedCount, tt.wantCount)
+			}
+		})
+	}
+}
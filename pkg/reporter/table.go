@@ -0,0 +1,51 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// FormatTable formats violations as an aligned table with one row per
+// violation (severity, rule, resource, and file:line), errors first, and a
+// footer with the totals. It sits between FormatText and FormatJSON: one
+// line per violation without messages or remediation.
+func (r *Reporter) FormatTable() string {
+	var output strings.Builder
+	if r.partialReason != "" {
+		output.WriteString(fmt.Sprintf("PARTIAL RESULTS: %s; rules that did not run may have found more violations\n\n", r.partialReason))
+	}
+
+	errors := r.filterBySeverity("error")
+	warnings := r.filterBySeverity("warning")
+	infos := r.filterBySeverity("info")
+
+	if len(r.violations) > 0 {
+		w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tRULE\tRESOURCE\tLOCATION")
+		for _, group := range [][]config.Violation{errors, warnings, infos} {
+			for _, v := range group {
+				fmt.Fprintf(w, "%s\t%s\t%s.%s\t%s:%d\n", v.Severity, v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line)
+			}
+		}
+		w.Flush()
+		output.WriteString("\n")
+	}
+
+	output.WriteString(fmt.Sprintf("Total: %d violations (%d errors, %d warnings, %d info)", len(r.violations), len(errors), len(warnings), len(infos)))
+	var notes []string
+	if len(r.filteredViolations) > 0 {
+		notes = append(notes, fmt.Sprintf("%d excepted", len(r.filteredViolations)))
+	}
+	if len(r.knownViolations) > 0 {
+		notes = append(notes, fmt.Sprintf("%d known", len(r.knownViolations)))
+	}
+	if len(notes) > 0 {
+		output.WriteString(fmt.Sprintf("; %s", strings.Join(notes, ", ")))
+	}
+	output.WriteString("\n")
+	output.WriteString(r.formatScore())
+	return output.String()
+}
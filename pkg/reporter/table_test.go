@@ -0,0 +1,58 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatTable(t *testing.T) {
+	tests := []struct {
+		name       string
+		violations []config.Violation
+		filtered   []config.FilteredViolation
+		known      []config.Violation
+		partial    string
+		want       string
+	}{
+		{
+			name: "errors first and aligned",
+			violations: []config.Violation{
+				{RuleID: "tags", Severity: "info", File: "main.tf", Line: 1, ResourceType: "aws_vpc", ResourceName: "main"},
+				{RuleID: "s3_public", Severity: "error", File: "storage/main.tf", Line: 12, ResourceType: "aws_s3_bucket", ResourceName: "data"},
+				{RuleID: "s3_versioning", Severity: "warning", File: "main.tf", Line: 9, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+			},
+			want: `SEVERITY  RULE           RESOURCE            LOCATION
+error     s3_public      aws_s3_bucket.data  storage/main.tf:12
+warning   s3_versioning  aws_s3_bucket.logs  main.tf:9
+info      tags           aws_vpc.main        main.tf:1
+
+Total: 3 violations (1 errors, 1 warnings, 1 info)
+`,
+		},
+		{
+			name:     "excepted and known",
+			filtered: []config.FilteredViolation{{Violation: config.Violation{RuleID: "s3_public"}}},
+			known:    []config.Violation{{RuleID: "tags"}, {RuleID: "tags"}},
+			want:     "Total: 0 violations (0 errors, 0 warnings, 0 info); 1 excepted, 2 known\n",
+		},
+		{
+			name:    "partial",
+			partial: "scan interrupted",
+			want:    "PARTIAL RESULTS: scan interrupted; rules that did not run may have found more violations\n\nTotal: 0 violations (0 errors, 0 warnings, 0 info)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := NewReporter(tt.violations, tt.filtered)
+			rep.SetKnownViolations(tt.known)
+			if tt.partial != "" {
+				rep.SetPartial(tt.partial)
+			}
+			if output := rep.FormatTable(); output != tt.want {
+				t.Errorf("FormatTable() =\n%s\nwant\n%s", output, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// FormatTAP formats the results as TAP version 13 (Test Anything Protocol)
+// with one test point per rule: "not ok" when the rule has violations,
+// which are listed in a YAML diagnostic block, and "ok" otherwise. Rules
+// that evaluated no resource are marked SKIP; evaluated holds the IDs of
+// rules that evaluated a resource. Violations of rules not in rules get
+// test points of their own after the others.
+func (r *Reporter) FormatTAP(rules []config.Rule, evaluated map[string]bool) string {
+	type point struct {
+		id, name string
+	}
+	var points []point
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if !seen[rule.ID] {
+			seen[rule.ID] = true
+			points = append(points, point{rule.ID, rule.Name})
+		}
+	}
+	byRule := make(map[string][]config.Violation)
+	for _, v := range r.violations {
+		if !seen[v.RuleID] {
+			seen[v.RuleID] = true
+			points = append(points, point{v.RuleID, v.RuleName})
+		}
+		byRule[v.RuleID] = append(byRule[v.RuleID], v)
+	}
+	exceptedByRule := make(map[string]int)
+	for _, fv := range r.filteredViolations {
+		exceptedByRule[fv.Violation.RuleID]++
+	}
+	knownByRule := make(map[string]int)
+	for _, v := range r.knownViolations {
+		knownByRule[v.RuleID]++
+	}
+
+	var output strings.Builder
+	output.WriteString("TAP version 13\n")
+	output.WriteString(fmt.Sprintf("1..%d\n", len(points)))
+	if r.partialReason != "" {
+		output.WriteString(fmt.Sprintf("# PARTIAL RESULTS: %s; rules that did not run may have found more violations\n", r.partialReason))
+	}
+
+	for i, p := range points {
+		description := tapEscape(p.id)
+		if p.name != "" {
+			description += ": " + tapEscape(p.name)
+		}
+		var notes []string
+		if n := exceptedByRule[p.id]; n > 0 {
+			notes = append(notes, fmt.Sprintf("%d excepted", n))
+		}
+		if n := knownByRule[p.id]; n > 0 {
+			notes = append(notes, fmt.Sprintf("%d known in baseline", n))
+		}
+		if len(notes) > 0 {
+			description += " (" + strings.Join(notes, ", ") + ")"
+		}
+
+		violations := byRule[p.id]
+		switch {
+		case len(violations) > 0:
+			output.WriteString(fmt.Sprintf("not ok %d - %s\n", i+1, description))
+			output.WriteString(tapDiagnostics(violations))
+		case !evaluated[p.id] && exceptedByRule[p.id] == 0 && knownByRule[p.id] == 0:
+			output.WriteString(fmt.Sprintf("ok %d - %s # SKIP no resources evaluated\n", i+1, description))
+		default:
+			output.WriteString(fmt.Sprintf("ok %d - %s\n", i+1, description))
+		}
+	}
+
+	return output.String()
+}
+
+// tapDiagnostics renders a rule's violations as a TAP YAML block. Strings
+// are written as JSON, which YAML parses as double-quoted scalars.
+func tapDiagnostics(violations []config.Violation) string {
+	var output strings.Builder
+	output.WriteString("  ---\n")
+	output.WriteString(fmt.Sprintf("  severity: %s\n", tapString(violations[0].Severity)))
+	output.WriteString("  violations:\n")
+	for _, v := range violations {
+		output.WriteString(fmt.Sprintf("    - file: %s\n", tapString(v.File)))
+		output.WriteString(fmt.Sprintf("      line: %d\n", v.Line))
+		output.WriteString(fmt.Sprintf("      resource: %s\n", tapString(v.ResourceType+"."+v.ResourceName)))
+		output.WriteString(fmt.Sprintf("      message: %s\n", tapString(v.Message)))
+		if v.Fingerprint != "" {
+			output.WriteString(fmt.Sprintf("      fingerprint: %s\n", tapString(v.Fingerprint)))
+		}
+		if len(v.Labels) > 0 {
+			output.WriteString(fmt.Sprintf("      labels: %s\n", tapString(formatLabels(v.Labels))))
+		}
+	}
+	output.WriteString("  ...\n")
+	return output.String()
+}
+
+func tapString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
+
+// tapEscape escapes # in a test point description, where it would start a
+// directive, and keeps the description on one line
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "#", `\#`)
+	return strings.Join(strings.Fields(s), " ")
+}
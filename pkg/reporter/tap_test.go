@@ -0,0 +1,75 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatTAP(t *testing.T) {
+	rules := []config.Rule{
+		{ID: "s3_public", Name: "No public buckets"},
+		{ID: "s3_versioning", Name: "Versioning #1 priority"},
+		{ID: "rds_encryption", Name: "Encrypted databases"},
+		{ID: "s3_logging", Name: "Bucket logging"},
+		{ID: "tags", Name: "Required tags"},
+	}
+	violations := []config.Violation{
+		{RuleID: "s3_public", Severity: "error", Message: `Bucket "data" is public`, File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data", Fingerprint: "abc123"},
+		{RuleID: "s3_public", Severity: "error", Message: "Bucket is public", File: "main.tf", Line: 9, ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "custom", RuleName: "Custom rule", Severity: "info", Message: "Custom", File: "main.tf", Line: 1, ResourceType: "aws_vpc", ResourceName: "main"},
+	}
+	filtered := []config.FilteredViolation{
+		{Violation: config.Violation{RuleID: "s3_logging", File: "web.tf", ResourceType: "aws_s3_bucket", ResourceName: "site"}},
+	}
+	evaluated := map[string]bool{"s3_public": true, "s3_versioning": true}
+
+	rep := NewReporter(violations, filtered)
+	rep.SetKnownViolations([]config.Violation{{RuleID: "tags", File: "old.tf"}})
+	output := rep.FormatTAP(rules, evaluated)
+
+	want := `TAP version 13
+1..6
+not ok 1 - s3_public: No public buckets
+  ---
+  severity: "error"
+  violations:
+    - file: "main.tf"
+      line: 3
+      resource: "aws_s3_bucket.data"
+      message: "Bucket \"data\" is public"
+      fingerprint: "abc123"
+    - file: "main.tf"
+      line: 9
+      resource: "aws_s3_bucket.logs"
+      message: "Bucket is public"
+  ...
+ok 2 - s3_versioning: Versioning \#1 priority
+ok 3 - rds_encryption: Encrypted databases # SKIP no resources evaluated
+ok 4 - s3_logging: Bucket logging (1 excepted)
+ok 5 - tags: Required tags (1 known in baseline)
+not ok 6 - custom: Custom rule
+  ---
+  severity: "info"
+  violations:
+    - file: "main.tf"
+      line: 1
+      resource: "aws_vpc.main"
+      message: "Custom"
+  ...
+`
+	if output != want {
+		t.Errorf("FormatTAP() =\n%s\nwant\n%s", output, want)
+	}
+}
+
+func TestFormatTAPPartial(t *testing.T) {
+	rep := NewReporter(nil, nil)
+	rep.SetPartial("scan interrupted")
+	output := rep.FormatTAP(nil, nil)
+
+	want := "TAP version 13\n1..0\n# PARTIAL RESULTS: scan interrupted; rules that did not run may have found more violations\n"
+	if output != want {
+		t.Errorf("FormatTAP() = %q, want %q", output, want)
+	}
+}
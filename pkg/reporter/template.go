@@ -0,0 +1,89 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// TemplateData is what a template report is rendered from
+type TemplateData struct {
+	Violations         []config.Violation // New violations, most severe first
+	Errors             []config.Violation
+	Warnings           []config.Violation
+	Infos              []config.Violation
+	Excepted           []config.FilteredViolation
+	Known              []config.Violation // Violations recorded in the baseline
+	ExpiringExceptions []config.ExpiringException
+	LabelSummaries     []LabelSummary
+	Partial            string   // Why the scan stopped early, or ""
+	Score              *float64 // Risk score, when risk scoring is enabled
+	TopRules           []TopIssue
+	TopResources       []TopIssue
+	Compliance         []ControlResult
+	RuleStats          []RuleStats
+}
+
+// templateFuncs are the functions available to report templates in
+// addition to text/template's built-ins
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"join":    func(sep string, items []string) string { return strings.Join(items, sep) },
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+// ParseTemplate parses a report template from a file. Besides
+// text/template's built-ins, templates can call json, upper, lower, trim,
+// join, and replace.
+func ParseTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// FormatTemplate renders the report with a template from ParseTemplate,
+// executed with TemplateData
+func (r *Reporter) FormatTemplate(tmpl *template.Template) (string, error) {
+	errors := r.filterBySeverity("error")
+	warnings := r.filterBySeverity("warning")
+	infos := r.filterBySeverity("info")
+	data := TemplateData{
+		Violations:         append(append(append([]config.Violation(nil), errors...), warnings...), infos...),
+		Errors:             errors,
+		Warnings:           warnings,
+		Infos:              infos,
+		Excepted:           r.filteredViolations,
+		Known:              r.knownViolations,
+		ExpiringExceptions: r.expiringExceptions,
+		LabelSummaries:     r.LabelSummaries(),
+		Partial:            r.partialReason,
+		Score:              r.score,
+		TopRules:           r.topRules,
+		TopResources:       r.topResources,
+		Compliance:         r.compliance,
+		RuleStats:          r.ruleStats,
+	}
+
+	var output strings.Builder
+	if err := tmpl.Execute(&output, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "tags", Severity: "warning", Message: "Missing tags", File: "main.tf", Line: 1, ResourceType: "aws_vpc", ResourceName: "main"},
+		{RuleID: "s3_public", Severity: "error", Message: `Bucket "data" is public`, File: "main.tf", Line: 3, ResourceType: "aws_s3_bucket", ResourceName: "data"},
+	}
+	filtered := []config.FilteredViolation{{Violation: config.Violation{RuleID: "tags"}}}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "violations most severe first",
+			template: `{{range .Violations}}{{upper .Severity}} {{.RuleID}} {{.File}}:{{.Line}}{{"\n"}}{{end}}`,
+			want:     "ERROR s3_public main.tf:3\nWARNING tags main.tf:1\n",
+		},
+		{
+			name:     "counts",
+			template: `{{len .Errors}} errors, {{len .Warnings}} warnings, {{len .Infos}} info, {{len .Excepted}} excepted`,
+			want:     "1 errors, 1 warnings, 0 info, 1 excepted",
+		},
+		{
+			name:     "json",
+			template: `{"text": {{(index .Errors 0).Message | json}}}`,
+			want:     `{"text": "Bucket \"data\" is public"}`,
+		},
+		{
+			name:     "partial and score",
+			template: `{{if .Partial}}partial: {{.Partial}}{{end}} score: {{with .Score}}{{.}}{{else}}none{{end}}`,
+			want:     "partial: scan interrupted score: none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.tmpl")
+			if err := os.WriteFile(path, []byte(tt.template), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := ParseTemplate(path)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+
+			rep := NewReporter(violations, filtered)
+			rep.SetPartial("scan interrupted")
+			output, err := rep.FormatTemplate(tmpl)
+			if err != nil {
+				t.Fatalf("FormatTemplate() error = %v", err)
+			}
+			if output != tt.want {
+				t.Errorf("FormatTemplate() = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ParseTemplate(filepath.Join(dir, "missing.tmpl")); err == nil || !strings.Contains(err.Error(), "failed to read template") {
+		t.Errorf("ParseTemplate(missing) error = %v", err)
+	}
+
+	path := filepath.Join(dir, "broken.tmpl")
+	if err := os.WriteFile(path, []byte("{{range .Violations}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTemplate(path); err == nil || !strings.Contains(err.Error(), "failed to parse template") {
+		t.Errorf("ParseTemplate(broken) error = %v", err)
+	}
+
+	path = filepath.Join(dir, "field.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Nope}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ParseTemplate(path)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if _, err := NewReporter(nil, nil).FormatTemplate(tmpl); err == nil || !strings.Contains(err.Error(), "failed to render template") {
+		t.Errorf("FormatTemplate() error = %v", err)
+	}
+}
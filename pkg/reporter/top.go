@@ -0,0 +1,102 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// TopIssue is a rule or resource ranked by the violations it accounts for
+type TopIssue struct {
+	Name       string // Rule ID, or resource address (type.name)
+	File       string `json:",omitempty"` // File declaring the resource
+	Violations int
+	Score      float64 // Sum of the violations' risk weights
+}
+
+// TopIssues ranks the rules and the resources with the most weighted
+// violations, highest score first, and returns at most n of each. Ties are
+// broken by violation count, then name, so the ranking is stable.
+func TopIssues(violations []config.Violation, weight func(config.Violation) float64, n int) (rules, resources []TopIssue) {
+	byRule := make(map[string]*TopIssue)
+	byResource := make(map[[2]string]*TopIssue)
+
+	for _, v := range violations {
+		w := weight(v)
+
+		rule, ok := byRule[v.RuleID]
+		if !ok {
+			rule = &TopIssue{Name: v.RuleID}
+			byRule[v.RuleID] = rule
+		}
+		rule.Violations++
+		rule.Score += w
+
+		key := [2]string{v.File, v.ResourceType + "." + v.ResourceName}
+		resource, ok := byResource[key]
+		if !ok {
+			resource = &TopIssue{Name: key[1], File: key[0]}
+			byResource[key] = resource
+		}
+		resource.Violations++
+		resource.Score += w
+	}
+
+	for _, issue := range byRule {
+		rules = append(rules, *issue)
+	}
+	for _, issue := range byResource {
+		resources = append(resources, *issue)
+	}
+	return rankIssues(rules, n), rankIssues(resources, n)
+}
+
+func rankIssues(issues []TopIssue, n int) []TopIssue {
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Violations != b.Violations {
+			return a.Violations > b.Violations
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.File < b.File
+	})
+	if len(issues) > n {
+		issues = issues[:n]
+	}
+	return issues
+}
+
+// SetTopIssues records the top rules and resources to lead the report with
+func (r *Reporter) SetTopIssues(rules, resources []TopIssue) {
+	r.topRules = rules
+	r.topResources = resources
+}
+
+// FormatTopIssues lists the rules and resources that account for most of
+// the violations. It returns "" when no top issues were recorded.
+func (r *Reporter) FormatTopIssues() string {
+	if len(r.topRules) == 0 && len(r.topResources) == 0 {
+		return ""
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🏆 TOP ISSUES: top %d rules and %d resources by weighted violations\n", len(r.topRules), len(r.topResources)))
+	output.WriteString(strings.Repeat("-", 50) + "\n")
+	output.WriteString("  Rules\n")
+	for i, issue := range r.topRules {
+		output.WriteString(fmt.Sprintf("  %3d. %-40s violations: %d  score: %g\n", i+1, issue.Name, issue.Violations, issue.Score))
+	}
+	output.WriteString("  Resources\n")
+	for i, issue := range r.topResources {
+		output.WriteString(fmt.Sprintf("  %3d. %-40s violations: %d  score: %g  (%s)\n", i+1, issue.Name, issue.Violations, issue.Score, issue.File))
+	}
+	output.WriteString("\n")
+	return output.String()
+}
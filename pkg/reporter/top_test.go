@@ -0,0 +1,114 @@
+package reporter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestTopIssues(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "s3_public", Severity: "error", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "data"},
+		{RuleID: "s3_versioning", Severity: "warning", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "data"},
+		{RuleID: "s3_versioning", Severity: "warning", File: "main.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "s3_versioning", Severity: "warning", File: "other.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "tags", Severity: "info", File: "other.tf", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		{RuleID: "sg_ingress", Severity: "warning", File: "net.tf", ResourceType: "aws_security_group", ResourceName: "web"},
+	}
+	weights := map[string]float64{"error": 10, "warning": 3, "info": 1}
+	weight := func(v config.Violation) float64 { return weights[v.Severity] }
+
+	tests := []struct {
+		name          string
+		n             int
+		wantRules     []TopIssue
+		wantResources []TopIssue
+	}{
+		{
+			name: "ranked by score, then violations, then name",
+			n:    10,
+			wantRules: []TopIssue{
+				{Name: "s3_public", Violations: 1, Score: 10},
+				{Name: "s3_versioning", Violations: 3, Score: 9},
+				{Name: "sg_ingress", Violations: 1, Score: 3},
+				{Name: "tags", Violations: 1, Score: 1},
+			},
+			wantResources: []TopIssue{
+				{Name: "aws_s3_bucket.data", File: "main.tf", Violations: 2, Score: 13},
+				{Name: "aws_s3_bucket.logs", File: "other.tf", Violations: 2, Score: 4},
+				{Name: "aws_s3_bucket.logs", File: "main.tf", Violations: 1, Score: 3},
+				{Name: "aws_security_group.web", File: "net.tf", Violations: 1, Score: 3},
+			},
+		},
+		{
+			name: "truncated to n",
+			n:    1,
+			wantRules: []TopIssue{
+				{Name: "s3_public", Violations: 1, Score: 10},
+			},
+			wantResources: []TopIssue{
+				{Name: "aws_s3_bucket.data", File: "main.tf", Violations: 2, Score: 13},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, resources := TopIssues(violations, weight, tt.n)
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("rules =\n%+v\nwant\n%+v", rules, tt.wantRules)
+			}
+			if !reflect.DeepEqual(resources, tt.wantResources) {
+				t.Errorf("resources =\n%+v\nwant\n%+v", resources, tt.wantResources)
+			}
+		})
+	}
+}
+
+func TestFormatTopIssues(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "s3_public", Severity: "error", File: "main.tf", Line: 1, ResourceType: "aws_s3_bucket", ResourceName: "data"},
+	}
+	reporter := NewReporter(violations, nil)
+	if got := reporter.FormatTopIssues(); got != "" {
+		t.Errorf("FormatTopIssues() without top issues = %q, want empty", got)
+	}
+
+	reporter.SetTopIssues(
+		[]TopIssue{{Name: "s3_public", Violations: 1, Score: 10}},
+		[]TopIssue{{Name: "aws_s3_bucket.data", File: "main.tf", Violations: 1, Score: 10}},
+	)
+
+	text := reporter.FormatText()
+	for _, want := range []string{
+		"TOP ISSUES: top 1 rules and 1 resources by weighted violations",
+		"1. s3_public",
+		"1. aws_s3_bucket.data",
+		"(main.tf)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in text output, got:\n%s", want, text)
+		}
+	}
+	if strings.Index(text, "TOP ISSUES") > strings.Index(text, "ERRORS") {
+		t.Errorf("Top issues should lead the report, got:\n%s", text)
+	}
+
+	output, err := reporter.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	var parsed struct {
+		TopRules     []TopIssue
+		TopResources []TopIssue
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatalf("JSON output with top issues should be an object: %v", err)
+	}
+	if len(parsed.TopRules) != 1 || len(parsed.TopResources) != 1 || parsed.TopResources[0].File != "main.tf" {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+}
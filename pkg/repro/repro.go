@@ -0,0 +1,221 @@
+// Package repro extracts minimal reproductions of violations: the violating
+// block together with the variables and locals it needs, as a standalone
+// Terraform snippet that can be shared in tickets or used to seed rule
+// tests.
+package repro
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+)
+
+// Find returns the violation whose fingerprint is or starts with
+// fingerprint. A prefix must match the fingerprint of only one violation.
+func Find(violations []config.Violation, fingerprint string) (config.Violation, error) {
+	var found []config.Violation
+	for _, v := range violations {
+		if v.Fingerprint == fingerprint {
+			return v, nil
+		}
+		if fingerprint != "" && strings.HasPrefix(v.Fingerprint, fingerprint) {
+			found = append(found, v)
+		}
+	}
+
+	if len(found) == 0 {
+		return config.Violation{}, fmt.Errorf("no violation has fingerprint %s", fingerprint)
+	}
+	for _, v := range found[1:] {
+		if v.Fingerprint != found[0].Fingerprint {
+			return config.Violation{}, fmt.Errorf("fingerprint %s is ambiguous; give more of it", fingerprint)
+		}
+	}
+	return found[0], nil
+}
+
+// Extract returns Terraform source for a violation of rule: a header
+// describing the rule, then the variable and local blocks the violating
+// block refers to, directly or through other locals, then the block itself.
+// Blocks are copied verbatim from their files.
+func Extract(ctx *parser.ScanContext, v config.Violation, rule config.Rule) ([]byte, error) {
+	resource := ctx.ResourceAt(v.File, v.Line)
+	if resource == nil {
+		return nil, fmt.Errorf("no block found at %s:%d", v.File, v.Line)
+	}
+
+	needed := neededBlocks(ctx, resource)
+	sources := make(map[string]*hcl.File)
+
+	var variables, locals []string
+	for _, r := range needed {
+		switch r.Kind {
+		case config.KindVariable:
+			src, err := blockSource(sources, r)
+			if err != nil {
+				return nil, err
+			}
+			variables = append(variables, src)
+		case config.KindLocal:
+			src, err := localSource(sources, r)
+			if err != nil {
+				return nil, err
+			}
+			locals = append(locals, src)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(Header(v, rule))
+	for _, src := range variables {
+		out.WriteString("\n" + src + "\n")
+	}
+	if len(locals) > 0 {
+		out.WriteString("\nlocals {\n")
+		for _, src := range locals {
+			out.WriteString("  " + src + "\n")
+		}
+		out.WriteString("}\n")
+	}
+
+	if resource.Kind == config.KindLocal {
+		src, err := localSource(sources, resource)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("\nlocals {\n  " + src + "\n}\n")
+	} else {
+		src, err := blockSource(sources, resource)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("\n" + src + "\n")
+	}
+
+	return hclwrite.Format(out.Bytes()), nil
+}
+
+// Header returns comment lines naming the violation and describing the rule
+// it triggers
+func Header(v config.Violation, rule config.Rule) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("# Reproduction of %s (%s)\n", rule.ID, rule.Name))
+	out.WriteString(fmt.Sprintf("# Found in %s:%d on %s.%s", v.File, v.Line, v.ResourceType, v.ResourceName))
+	if v.Fingerprint != "" {
+		out.WriteString(fmt.Sprintf(", fingerprint %s", v.Fingerprint))
+	}
+	out.WriteString("\n")
+	out.WriteString(fmt.Sprintf("# Severity: %s\n", rule.Severity))
+	out.WriteString(fmt.Sprintf("# Message: %s\n", rule.Message))
+	out.WriteString(fmt.Sprintf("# Resource type: %s\n", rule.ResourceType))
+	if rule.When != nil {
+		out.WriteString("# When:\n")
+		out.WriteString(comment(rule.When.Expression))
+	}
+	if len(rule.Conditions) > 0 {
+		out.WriteString("# Conditions (any one is a violation):\n")
+		for _, condition := range rule.Conditions {
+			out.WriteString(comment(condition.Expression))
+		}
+	}
+	return out.String()
+}
+
+// comment indents each line of an expression as a comment
+func comment(expr string) string {
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(expr), "\n") {
+		out.WriteString("#   " + strings.TrimRight(line, " \t") + "\n")
+	}
+	return out.String()
+}
+
+// neededBlocks returns the variables and locals resource refers to, and
+// those the locals refer to in turn, in file and line order
+func neededBlocks(ctx *parser.ScanContext, resource *config.Resource) []*config.Resource {
+	seen := map[*config.Resource]bool{resource: true}
+	var needed []*config.Resource
+
+	queue := []*config.Resource{resource}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range ctx.Dependencies(current) {
+			if seen[dep] || (dep.Kind != config.KindVariable && dep.Kind != config.KindLocal) {
+				continue
+			}
+			seen[dep] = true
+			needed = append(needed, dep)
+			if dep.Kind == config.KindLocal {
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	sort.Slice(needed, func(i, j int) bool {
+		if needed[i].File != needed[j].File {
+			return needed[i].File < needed[j].File
+		}
+		return needed[i].Line < needed[j].Line
+	})
+	return needed
+}
+
+// blockSource returns the source of the block declaring resource
+func blockSource(sources map[string]*hcl.File, resource *config.Resource) (string, error) {
+	file, err := parseFile(sources, resource.File)
+	if err != nil {
+		return "", err
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return "", fmt.Errorf("%s is not native HCL syntax", resource.File)
+	}
+	for _, block := range body.Blocks {
+		if block.Type == resource.Kind && block.DefRange().Start.Line == resource.Line {
+			return string(block.Range().SliceBytes(file.Bytes)), nil
+		}
+	}
+	return "", fmt.Errorf("no %s block found at %s:%d", resource.Kind, resource.File, resource.Line)
+}
+
+// localSource returns the "name = expression" source of a local value
+func localSource(sources map[string]*hcl.File, local *config.Resource) (string, error) {
+	file, err := parseFile(sources, local.File)
+	if err != nil {
+		return "", err
+	}
+
+	expr, ok := local.RawExprs["value"]
+	if !ok {
+		return "", fmt.Errorf("local.%s has no value", local.Name)
+	}
+	return local.Name + " = " + string(expr.Range().SliceBytes(file.Bytes)), nil
+}
+
+// parseFile reads and parses a file once per extraction
+func parseFile(sources map[string]*hcl.File, path string) (*hcl.File, error) {
+	if file, ok := sources[path]; ok {
+		return file, nil
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+	sources[path] = file
+	return file, nil
+}
@@ -0,0 +1,158 @@
+package repro
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+)
+
+func TestFind(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "a", Fingerprint: "abc123"},
+		{RuleID: "b", Fingerprint: "abd456"},
+		{RuleID: "c", Fingerprint: "abd456"},
+	}
+
+	tests := []struct {
+		fingerprint string
+		wantRule    string
+		wantErr     string
+	}{
+		{fingerprint: "abc123", wantRule: "a"},
+		{fingerprint: "abc", wantRule: "a"},
+		{fingerprint: "abd", wantRule: "b"},
+		{fingerprint: "ab", wantErr: "ambiguous"},
+		{fingerprint: "fff", wantErr: "no violation has fingerprint fff"},
+		{fingerprint: "", wantErr: "no violation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fingerprint, func(t *testing.T) {
+			v, err := Find(violations, tt.fingerprint)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Find() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if v.RuleID != tt.wantRule {
+				t.Errorf("Find() = %s, want %s", v.RuleID, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"variables.tf": `variable "env" {
+  default = "prod"
+}
+
+variable "unused" {}
+
+variable "team" {}
+`,
+		"main.tf": `locals {
+  prefix = "${var.env}-data"
+  name   = "${local.prefix}-logs"
+  other  = "unrelated"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = local.name
+  acl    = "public-read"
+  tags = {
+    team = var.team
+  }
+}
+
+resource "aws_s3_bucket" "other" {
+  bucket = local.other
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parsed, err := parser.NewParser().ParseDirectory(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := parser.ExtractResources(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := parser.NewScanContext(resources)
+
+	rule := config.Rule{
+		ID:           "s3_public",
+		Name:         "Public bucket",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: "self.acl == \"public-read\""}},
+		Message:      "Bucket is public",
+	}
+	v := config.Violation{
+		RuleID:       "s3_public",
+		File:         filepath.Join(dir, "main.tf"),
+		Line:         7,
+		ResourceType: "aws_s3_bucket",
+		ResourceName: "logs",
+		Fingerprint:  "0123456789ab",
+	}
+
+	src, err := Extract(ctx, v, rule)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	got := string(src)
+
+	for _, want := range []string{
+		"# Reproduction of s3_public (Public bucket)\n",
+		"fingerprint 0123456789ab\n",
+		"#   self.acl == \"public-read\"\n",
+		"variable \"env\" {\n  default = \"prod\"\n}",
+		"variable \"team\" {}",
+		"locals {\n  prefix = \"${var.env}-data\"\n  name   = \"${local.prefix}-logs\"\n}",
+		"resource \"aws_s3_bucket\" \"logs\" {\n  bucket = local.name\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in reproduction, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"unused", "unrelated", "\"other\""} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Did not expect %q in reproduction, got:\n%s", unwanted, got)
+		}
+	}
+
+	// The reproduction parses on its own
+	if _, err := parser.NewParser().ParseFile(writeTemp(t, src)); err != nil {
+		t.Errorf("Reproduction does not parse: %v", err)
+	}
+
+	v.Line = 6
+	if _, err := Extract(ctx, v, rule); err == nil || !strings.Contains(err.Error(), "no block found") {
+		t.Errorf("Extract() at a line without a block error = %v", err)
+	}
+}
+
+func writeTemp(t *testing.T, src []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "repro.tf")
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,199 @@
+// Package resultdiff compares the violations of two planguard JSON reports
+// by fingerprint, so a change can be summarized by the violations it
+// introduces and the ones it fixes.
+package resultdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+// Diff holds the violations of a new report compared to an old one
+type Diff struct {
+	New       []config.Violation // Only in the new report
+	Fixed     []config.Violation // Only in the old report
+	Unchanged []config.Violation // In both, as found in the new report
+}
+
+// Load reads the violations of a report written with -format json, in
+// either of its forms: a list of violations, or an object with Violations.
+// Violations known from a baseline are included, since they still exist.
+func Load(path string) ([]config.Violation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var violations []config.Violation
+	if err := json.Unmarshal(data, &violations); err == nil {
+		return violations, nil
+	}
+	var report struct {
+		Violations      []config.Violation
+		KnownViolations []config.Violation
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s (expected planguard -format json output): %w", path, err)
+	}
+	return append(report.Violations, report.KnownViolations...), nil
+}
+
+// Compare matches the violations of two reports by fingerprint, which
+// ignores line numbers, so code moving around does not show up as a fix
+// and a new violation. Violations without a fingerprint get one computed.
+func Compare(old, new []config.Violation) Diff {
+	remaining := make(map[string][]config.Violation)
+	for _, v := range old {
+		key := fingerprint(v)
+		remaining[key] = append(remaining[key], v)
+	}
+
+	var d Diff
+	for _, v := range new {
+		key := fingerprint(v)
+		if matches := remaining[key]; len(matches) > 0 {
+			remaining[key] = matches[1:]
+			d.Unchanged = append(d.Unchanged, v)
+		} else {
+			d.New = append(d.New, v)
+		}
+	}
+	for _, v := range old {
+		key := fingerprint(v)
+		if matches := remaining[key]; len(matches) > 0 {
+			remaining[key] = matches[1:]
+			d.Fixed = append(d.Fixed, v)
+		}
+	}
+
+	sortViolations(d.New)
+	sortViolations(d.Fixed)
+	sortViolations(d.Unchanged)
+	return d
+}
+
+func fingerprint(v config.Violation) string {
+	if v.Fingerprint != "" {
+		return v.Fingerprint
+	}
+	return config.Fingerprint(v)
+}
+
+// sortViolations orders violations most severe first, then by location
+func sortViolations(violations []config.Violation) {
+	rank := map[string]int{"error": 0, "warning": 1, "info": 2}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.RuleID < b.RuleID
+	})
+}
+
+// Summary describes the diff in one sentence, e.g. "This change introduces
+// 3 new violations and fixes 1."
+func (d Diff) Summary() string {
+	switch {
+	case len(d.New) > 0 && len(d.Fixed) > 0:
+		return fmt.Sprintf("This change introduces %s and fixes %d.", count(len(d.New), "new violation"), len(d.Fixed))
+	case len(d.New) > 0:
+		return fmt.Sprintf("This change introduces %s.", count(len(d.New), "new violation"))
+	case len(d.Fixed) > 0:
+		return fmt.Sprintf("This change introduces no new violations and fixes %s.", count(len(d.Fixed), "violation"))
+	default:
+		return "This change introduces no new violations and fixes none."
+	}
+}
+
+func count(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// FormatText formats the diff as text: the summary, then the new and fixed
+// violations
+func (d Diff) FormatText() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s %d violations are unchanged.\n", d.Summary(), len(d.Unchanged)))
+	for _, section := range []struct {
+		title      string
+		violations []config.Violation
+	}{
+		{"NEW", d.New},
+		{"FIXED", d.Fixed},
+	} {
+		if len(section.violations) == 0 {
+			continue
+		}
+		output.WriteString(fmt.Sprintf("\n%s: %d\n", section.title, len(section.violations)))
+		output.WriteString(strings.Repeat("-", 50) + "\n")
+		for _, v := range section.violations {
+			output.WriteString(fmt.Sprintf("  %-8s %s:%d  %s  %s.%s\n", v.Severity, v.File, v.Line, v.RuleID, v.ResourceType, v.ResourceName))
+		}
+	}
+	return output.String()
+}
+
+// FormatMarkdown formats the diff as GitHub-flavored Markdown for pull
+// request comments
+func (d Diff) FormatMarkdown() string {
+	var output strings.Builder
+	output.WriteString("## 🛡️ Planguard Diff\n\n")
+	output.WriteString(fmt.Sprintf("%s %d violations are unchanged.\n", d.Summary(), len(d.Unchanged)))
+	for _, section := range []struct {
+		title      string
+		violations []config.Violation
+	}{
+		{"🆕 New", d.New},
+		{"✅ Fixed", d.Fixed},
+	} {
+		if len(section.violations) == 0 {
+			continue
+		}
+		output.WriteString(fmt.Sprintf("\n### %s (%d)\n\n", section.title, len(section.violations)))
+		output.WriteString("| Severity | Rule | Resource | Location |\n")
+		output.WriteString("| --- | --- | --- | --- |\n")
+		for _, v := range section.violations {
+			output.WriteString(fmt.Sprintf("| %s | `%s` | `%s.%s` | `%s:%d` |\n", v.Severity, v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line))
+		}
+	}
+	return output.String()
+}
+
+// FormatJSON formats the diff as JSON with New, Fixed, and Unchanged lists
+func (d Diff) FormatJSON() (string, error) {
+	report := struct {
+		Summary   string
+		New       []config.Violation
+		Fixed     []config.Violation
+		Unchanged []config.Violation
+	}{d.Summary(), nonNil(d.New), nonNil(d.Fixed), nonNil(d.Unchanged)}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// nonNil returns violations, or an empty list when nil, so JSON has []
+// rather than null
+func nonNil(violations []config.Violation) []config.Violation {
+	if violations == nil {
+		return []config.Violation{}
+	}
+	return violations
+}
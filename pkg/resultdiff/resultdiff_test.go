@@ -0,0 +1,131 @@
+package resultdiff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func violation(rule, file string, line int, name string) config.Violation {
+	v := config.Violation{RuleID: rule, Severity: "error", File: file, Line: line, ResourceType: "aws_s3_bucket", ResourceName: name}
+	v.Fingerprint = config.Fingerprint(v)
+	return v
+}
+
+func TestCompare(t *testing.T) {
+	old := []config.Violation{
+		violation("s3_public", "main.tf", 3, "data"),
+		violation("s3_versioning", "main.tf", 3, "data"),
+		violation("s3_public", "main.tf", 9, "logs"),
+	}
+	new := []config.Violation{
+		violation("s3_public", "main.tf", 5, "data"), // Moved down two lines
+		violation("s3_public", "main.tf", 11, "logs"),
+		violation("s3_public", "web.tf", 1, "site"),
+	}
+	// Violations without a fingerprint, e.g. from older reports, still match
+	new[1].Fingerprint = ""
+
+	d := Compare(old, new)
+	if len(d.New) != 1 || d.New[0].ResourceName != "site" {
+		t.Errorf("New = %+v", d.New)
+	}
+	if len(d.Fixed) != 1 || d.Fixed[0].RuleID != "s3_versioning" {
+		t.Errorf("Fixed = %+v", d.Fixed)
+	}
+	if len(d.Unchanged) != 2 || d.Unchanged[0].Line != 5 {
+		t.Errorf("Unchanged = %+v, want the new reports' violations", d.Unchanged)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	v := violation("s3_public", "main.tf", 3, "data")
+	tests := []struct {
+		diff Diff
+		want string
+	}{
+		{Diff{New: []config.Violation{v, v, v}, Fixed: []config.Violation{v}}, "This change introduces 3 new violations and fixes 1."},
+		{Diff{New: []config.Violation{v}}, "This change introduces 1 new violation."},
+		{Diff{Fixed: []config.Violation{v, v}}, "This change introduces no new violations and fixes 2 violations."},
+		{Diff{Unchanged: []config.Violation{v}}, "This change introduces no new violations and fixes none."},
+	}
+	for _, tt := range tests {
+		if got := tt.diff.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"list", `[{"RuleID": "a", "File": "main.tf"}]`, 1, false},
+		{"object with known violations", `{"Violations": [{"RuleID": "a"}], "KnownViolations": [{"RuleID": "b"}], "Partial": "timeout"}`, 2, false},
+		{"empty", `[]`, 0, false},
+		{"not a report", `"text"`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			violations, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(violations) != tt.want {
+				t.Errorf("Load() = %d violations, want %d", len(violations), tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing report")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	d := Compare(
+		[]config.Violation{violation("s3_versioning", "main.tf", 3, "data")},
+		[]config.Violation{violation("s3_public", "main.tf", 3, "data")},
+	)
+
+	text := d.FormatText()
+	want := `This change introduces 1 new violation and fixes 1. 0 violations are unchanged.
+
+NEW: 1
+--------------------------------------------------
+  error    main.tf:3  s3_public  aws_s3_bucket.data
+
+FIXED: 1
+--------------------------------------------------
+  error    main.tf:3  s3_versioning  aws_s3_bucket.data
+`
+	if text != want {
+		t.Errorf("FormatText() =\n%s\nwant\n%s", text, want)
+	}
+
+	markdown := d.FormatMarkdown()
+	for _, want := range []string{"### 🆕 New (1)", "| error | `s3_public` | `aws_s3_bucket.data` | `main.tf:3` |", "### ✅ Fixed (1)"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in markdown:\n%s", want, markdown)
+		}
+	}
+
+	data, err := Diff{}.FormatJSON()
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	if !strings.Contains(data, `"New": []`) || !strings.Contains(data, `"Summary": "This change introduces no new violations and fixes none."`) {
+		t.Errorf("FormatJSON() = %s", data)
+	}
+}
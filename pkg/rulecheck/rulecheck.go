@@ -0,0 +1,372 @@
+// Package rulecheck finds conflicts and overlaps between rules: IDs defined
+// more than once, rules or conditions with identical logic, and conditions
+// that contradict the rule's when expression and so can never match.
+package rulecheck
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Finding kinds
+const (
+	KindDuplicateID        = "duplicate-id"
+	KindIdenticalRules     = "identical-rules"
+	KindIdenticalCondition = "identical-condition"
+	KindContradiction      = "contradiction"
+	KindAlwaysMatches      = "always-matches"
+)
+
+// Finding is a conflict or overlap between rules, or within one rule
+type Finding struct {
+	Kind    string
+	Rules   []string // IDs of the rules involved
+	Sources []string // Files the rules were loaded from, where known
+	Message string
+}
+
+// String formats the finding as one line of rules check output
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s: %s", f.Kind, f.Message)
+	if len(f.Sources) > 0 {
+		s += " (" + strings.Join(f.Sources, ", ") + ")"
+	}
+	return s
+}
+
+// Check analyzes rules as loaded, before duplicates are dropped, and
+// returns what it finds ordered by kind, then rule ID. Expressions that do
+// not parse are skipped; the scanner reports those.
+func Check(rules []config.Rule) []Finding {
+	var findings []Finding
+	findings = append(findings, duplicateIDs(rules)...)
+	findings = append(findings, identicalRules(rules)...)
+	for _, rule := range rules {
+		findings = append(findings, checkRule(rule)...)
+	}
+
+	order := map[string]int{KindDuplicateID: 0, KindContradiction: 1, KindAlwaysMatches: 2, KindIdenticalRules: 3, KindIdenticalCondition: 4}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return order[findings[i].Kind] < order[findings[j].Kind]
+		}
+		return findings[i].Rules[0] < findings[j].Rules[0]
+	})
+	return findings
+}
+
+// duplicateIDs reports IDs shared by more than one rule. Only the first
+// definition of an ID is used when scanning.
+func duplicateIDs(rules []config.Rule) []Finding {
+	byID := make(map[string][]config.Rule)
+	var ids []string
+	for _, rule := range rules {
+		if _, ok := byID[rule.ID]; !ok {
+			ids = append(ids, rule.ID)
+		}
+		byID[rule.ID] = append(byID[rule.ID], rule)
+	}
+
+	var findings []Finding
+	for _, id := range ids {
+		defs := byID[id]
+		if len(defs) < 2 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:    KindDuplicateID,
+			Rules:   []string{id},
+			Sources: sources(defs),
+			Message: fmt.Sprintf("rule %s is defined %d times; only the first definition is used", id, len(defs)),
+		})
+	}
+	return findings
+}
+
+// identicalRules reports rules with different IDs that target the same
+// resource type with the same when expression and conditions, so each
+// violation of one is also a violation of the others
+func identicalRules(rules []config.Rule) []Finding {
+	byLogic := make(map[string][]config.Rule)
+	var keys []string
+	for _, rule := range rules {
+		key, ok := logicKey(rule)
+		if !ok {
+			continue
+		}
+		if _, seen := byLogic[key]; !seen {
+			keys = append(keys, key)
+		}
+		byLogic[key] = append(byLogic[key], rule)
+	}
+
+	var findings []Finding
+	for _, key := range keys {
+		group := byLogic[key]
+		var ids []string
+		var defs []config.Rule
+		seen := make(map[string]bool)
+		for _, rule := range group {
+			if !seen[rule.ID] {
+				seen[rule.ID] = true
+				ids = append(ids, rule.ID)
+				defs = append(defs, rule)
+			}
+		}
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		findings = append(findings, Finding{
+			Kind:    KindIdenticalRules,
+			Rules:   ids,
+			Sources: sources(defs),
+			Message: fmt.Sprintf("rules %s have identical logic for %s and report the same resources", strings.Join(ids, ", "), group[0].ResourceType),
+		})
+	}
+	return findings
+}
+
+// logicKey identifies what a rule matches: its resource type, its when
+// expression, and its set of conditions, each in canonical form
+func logicKey(rule config.Rule) (string, bool) {
+	when := ""
+	if rule.When != nil {
+		c, ok := canonical(rule.When.Expression)
+		if !ok {
+			return "", false
+		}
+		when = c
+	}
+
+	conditions := make([]string, 0, len(rule.Conditions))
+	for _, condition := range rule.Conditions {
+		c, ok := canonical(condition.Expression)
+		if !ok {
+			return "", false
+		}
+		conditions = append(conditions, c)
+	}
+	sort.Strings(conditions)
+	return rule.ResourceType + "\x00" + when + "\x00" + strings.Join(conditions, "\x00"), true
+}
+
+// checkRule reports conditions of a rule that repeat one another, that can
+// never be true where the rule applies, or that are always true there
+func checkRule(rule config.Rule) []Finding {
+	var findings []Finding
+	finding := func(kind, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Kind:    kind,
+			Rules:   []string{rule.ID},
+			Sources: sources([]config.Rule{rule}),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	var when []fact
+	whenText := ""
+	if rule.When != nil {
+		expr, src, ok := parse(rule.When.Expression)
+		if !ok {
+			return nil
+		}
+		when = facts(expr, src)
+		whenText, _ = canonical(rule.When.Expression)
+		if a, b, ok := contradiction(when); ok {
+			finding(KindContradiction, "rule %s: when expression can never be true: %s contradicts %s", rule.ID, a.text, b.text)
+			return findings
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, condition := range rule.Conditions {
+		expr, src, ok := parse(condition.Expression)
+		if !ok {
+			continue
+		}
+		text, _ := canonical(condition.Expression)
+
+		if first, ok := seen[text]; ok {
+			finding(KindIdenticalCondition, "rule %s: condition %d repeats condition %d: %s", rule.ID, i+1, first, text)
+			continue
+		}
+		seen[text] = i + 1
+
+		conjuncts := facts(expr, src)
+		if a, b, ok := contradiction(append(append([]fact(nil), when...), conjuncts...)); ok {
+			finding(KindContradiction, "rule %s: condition %d can never match: %s contradicts %s", rule.ID, i+1, a.text, b.text)
+			continue
+		}
+		if whenText != "" && text == whenText {
+			finding(KindAlwaysMatches, "rule %s: condition %d is the when expression, so every %s it applies to is a violation", rule.ID, i+1, rule.ResourceType)
+		}
+	}
+	return findings
+}
+
+// fact is one conjunct of an expression: an expression that must be true,
+// or must be false when negated, or a comparison of an expression with a
+// literal
+type fact struct {
+	text    string    // Canonical source of the conjunct
+	subject string    // Canonical source of what the conjunct is about
+	negated bool      // !subject
+	op      string    // "==" or "!=" for comparisons with value
+	value   cty.Value // Literal compared with
+}
+
+// facts splits an expression on && into facts
+func facts(expr hclsyntax.Expression, src []byte) []fact {
+	expr = unwrap(expr)
+	if op, ok := expr.(*hclsyntax.BinaryOpExpr); ok && op.Op == hclsyntax.OpLogicalAnd {
+		return append(facts(op.LHS, src), facts(op.RHS, src)...)
+	}
+
+	f := fact{text: sourceOf(expr, src)}
+	f.subject = f.text
+	switch e := expr.(type) {
+	case *hclsyntax.UnaryOpExpr:
+		if e.Op == hclsyntax.OpLogicalNot {
+			f.negated = true
+			f.subject = sourceOf(unwrap(e.Val), src)
+		}
+	case *hclsyntax.BinaryOpExpr:
+		if e.Op != hclsyntax.OpEqual && e.Op != hclsyntax.OpNotEqual {
+			break
+		}
+		subject, value, ok := comparison(e.LHS, e.RHS)
+		if !ok {
+			subject, value, ok = comparison(e.RHS, e.LHS)
+		}
+		if ok {
+			f.subject = sourceOf(unwrap(subject), src)
+			f.value = value
+			f.op = "=="
+			if e.Op == hclsyntax.OpNotEqual {
+				f.op = "!="
+			}
+		}
+	}
+	return []fact{f}
+}
+
+// comparison returns the other side of a comparison whose right side is a
+// literal, and the literal's value
+func comparison(lhs, rhs hclsyntax.Expression) (hclsyntax.Expression, cty.Value, bool) {
+	switch r := unwrap(rhs).(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return lhs, r.Val, true
+	case *hclsyntax.TemplateExpr:
+		if r.IsStringLiteral() {
+			value, diags := r.Value(nil)
+			if !diags.HasErrors() {
+				return lhs, value, true
+			}
+		}
+	}
+	return nil, cty.NilVal, false
+}
+
+// contradiction returns two facts that cannot both be true: an expression
+// and its negation, a comparison with two different values, or == and !=
+// with the same value
+func contradiction(all []fact) (fact, fact, bool) {
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.subject != b.subject {
+				continue
+			}
+			switch {
+			case a.op == "" && b.op == "":
+				if a.negated != b.negated {
+					return a, b, true
+				}
+			case a.op == "==" && b.op == "==":
+				if !a.value.RawEquals(b.value) {
+					return a, b, true
+				}
+			case a.op != "" && b.op != "" && a.op != b.op:
+				if a.value.RawEquals(b.value) {
+					return a, b, true
+				}
+			}
+		}
+	}
+	return fact{}, fact{}, false
+}
+
+// unwrap removes parentheses around an expression
+func unwrap(expr hclsyntax.Expression) hclsyntax.Expression {
+	for {
+		parens, ok := expr.(*hclsyntax.ParenthesesExpr)
+		if !ok {
+			return expr
+		}
+		expr = parens.Expression
+	}
+}
+
+// parse parses a rule expression, returning its source for slicing
+func parse(exprStr string) (hclsyntax.Expression, []byte, bool) {
+	src := []byte(exprStr)
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, nil, false
+	}
+	return expr, src, true
+}
+
+// sourceOf returns the canonical source of a parsed expression
+func sourceOf(expr hclsyntax.Expression, src []byte) string {
+	c, _ := canonical(string(expr.Range().SliceBytes(src)))
+	return c
+}
+
+// canonical returns an expression formatted like terraform fmt would,
+// with each run of whitespace, newlines, and comments between its tokens
+// replaced by a single space, so expressions that differ only in layout
+// compare equal
+func canonical(exprStr string) (string, bool) {
+	formatted := hclwrite.Format([]byte("x = " + exprStr))
+	tokens, diags := hclsyntax.LexExpression(bytes.TrimPrefix(formatted, []byte("x = ")), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return "", false
+	}
+
+	var out strings.Builder
+	end := -1
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment, hclsyntax.TokenEOF:
+			continue
+		}
+		if end >= 0 && token.Range.Start.Byte > end {
+			out.WriteByte(' ')
+		}
+		out.Write(token.Bytes)
+		end = token.Range.End.Byte
+	}
+	return out.String(), true
+}
+
+// sources returns the distinct files rules were loaded from
+func sources(rules []config.Rule) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Source != "" && !seen[rule.Source] {
+			seen[rule.Source] = true
+			files = append(files, rule.Source)
+		}
+	}
+	return files
+}
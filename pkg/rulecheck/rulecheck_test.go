@@ -0,0 +1,128 @@
+package rulecheck
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func rule(id, resourceType, when string, conditions ...string) config.Rule {
+	r := config.Rule{ID: id, Severity: "error", ResourceType: resourceType, Source: id + ".hcl"}
+	if when != "" {
+		r.When = &config.WhenBlock{Expression: when}
+	}
+	for _, c := range conditions {
+		r.Conditions = append(r.Conditions, config.Condition{Expression: c})
+	}
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	duplicate := rule("s3_acl", "aws_s3_bucket", "", `self.acl == "public-read"`)
+	duplicate.Source = "custom.hcl"
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		want  []Finding
+	}{
+		{
+			name: "no problems",
+			rules: []config.Rule{
+				rule("s3_acl", "aws_s3_bucket", `has(self, "acl")`, `self.acl == "public-read"`),
+				rule("s3_tags", "aws_s3_bucket", "", `!has(self, "tags")`),
+				rule("ec2_tags", "aws_instance", "", `!has(self, "tags")`),
+			},
+		},
+		{
+			name: "duplicate IDs",
+			rules: []config.Rule{
+				rule("s3_acl", "aws_s3_bucket", "", `self.acl == "public-read"`),
+				duplicate,
+			},
+			want: []Finding{
+				{Kind: KindDuplicateID, Rules: []string{"s3_acl"}, Sources: []string{"s3_acl.hcl", "custom.hcl"}, Message: "rule s3_acl is defined 2 times; only the first definition is used"},
+			},
+		},
+		{
+			name: "identical rules ignore layout and condition order",
+			rules: []config.Rule{
+				rule("a", "aws_instance", "", `!has(self, "tags")`, `self.monitoring == false`),
+				rule("b", "aws_instance", "", "self.monitoring==false", "!has(self,\n  \"tags\")"),
+			},
+			want: []Finding{
+				{Kind: KindIdenticalRules, Rules: []string{"a", "b"}, Sources: []string{"a.hcl", "b.hcl"}, Message: "rules a, b have identical logic for aws_instance and report the same resources"},
+			},
+		},
+		{
+			name: "repeated condition",
+			rules: []config.Rule{
+				rule("a", "aws_instance", "", `!has(self, "tags")`, `!has(self,  "tags")`),
+			},
+			want: []Finding{
+				{Kind: KindIdenticalCondition, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: `rule a: condition 2 repeats condition 1: !has(self, "tags")`},
+			},
+		},
+		{
+			name: "condition negates when",
+			rules: []config.Rule{
+				rule("a", "aws_instance", `has(self, "tags") && self.monitoring`, `!(has(self, "tags"))`),
+			},
+			want: []Finding{
+				{Kind: KindContradiction, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: `rule a: condition 1 can never match: has(self, "tags") contradicts !(has(self, "tags"))`},
+			},
+		},
+		{
+			name: "conflicting comparisons",
+			rules: []config.Rule{
+				rule("a", "aws_s3_bucket", `self.acl == "private"`, `self.acl == "public-read"`, `"private" != self.acl`, `self.acl != "public-read"`),
+			},
+			want: []Finding{
+				{Kind: KindContradiction, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: `rule a: condition 1 can never match: self.acl == "private" contradicts self.acl == "public-read"`},
+				{Kind: KindContradiction, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: `rule a: condition 2 can never match: self.acl == "private" contradicts "private" != self.acl`},
+			},
+		},
+		{
+			name: "contradictory when",
+			rules: []config.Rule{
+				rule("a", "aws_instance", `self.count == 1 && self.count == 2`, `true`),
+			},
+			want: []Finding{
+				{Kind: KindContradiction, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: "rule a: when expression can never be true: self.count == 1 contradicts self.count == 2"},
+			},
+		},
+		{
+			name: "condition repeats when",
+			rules: []config.Rule{
+				rule("a", "aws_instance", `has(self, "tags")`, `has(self, "tags")`),
+			},
+			want: []Finding{
+				{Kind: KindAlwaysMatches, Rules: []string{"a"}, Sources: []string{"a.hcl"}, Message: "rule a: condition 1 is the when expression, so every aws_instance it applies to is a violation"},
+			},
+		},
+		{
+			name: "unparseable expressions are skipped",
+			rules: []config.Rule{
+				rule("a", "aws_instance", `has(self,`, `true`),
+				rule("b", "aws_instance", "", `self.x ==`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.rules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Kind: KindDuplicateID, Rules: []string{"a"}, Sources: []string{"a.hcl", "b.hcl"}, Message: "rule a is defined 2 times"}
+	if got, want := f.String(), "duplicate-id: rule a is defined 2 times (a.hcl, b.hcl)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,346 @@
+package rulecheck
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/functions"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// Problem is an error in a rule file, at a position in the file
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Rule    string // ID of the rule, when the problem is inside one
+	Message string
+}
+
+// String formats the problem as file:line:column: message, like a compiler
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s:%d:%d: ", p.File, p.Line, p.Column)
+	if p.Rule != "" {
+		s += fmt.Sprintf("rule %s: ", p.Rule)
+	}
+	return s + p.Message
+}
+
+// Validate parses rule files and checks each rule the way a scan would use
+// it: the file must decode into rule blocks, severities, on_unknown, and
+// metadata must be valid, when and condition expressions must parse and
+// only call functions that exist with the right number of arguments, and
+// rule IDs must be unique across all the files. Problems are ordered by
+// file and position. Pack manifests and rule test files are skipped.
+func Validate(files []string) []Problem {
+	v := newValidator()
+	for _, file := range files {
+		if filepath.Base(file) == config.PackManifestFile || config.IsRuleTestFile(file) {
+			continue
+		}
+		v.validateFile(file, true)
+	}
+	return v.sorted()
+}
+
+// ValidateLoaded checks the rules of files that loaded, such as config
+// files, as Validate checks rule files. The files may hold more than rules,
+// and rules may override rules of the same ID defined in other files, so
+// each file is checked on its own.
+func ValidateLoaded(files []string) []Problem {
+	var problems []Problem
+	for _, file := range files {
+		v := newValidator()
+		v.validateFile(file, false)
+		problems = append(problems, v.sorted()...)
+	}
+	return problems
+}
+
+func newValidator() *validator {
+	return &validator{
+		functions: functions.BuildFunctions(parser.NewScanContext(nil)),
+		defined:   make(map[string]hcl.Range),
+		aliases:   make(map[string]hcl.Range),
+	}
+}
+
+// sorted returns the problems found, ordered by file and position
+func (v *validator) sorted() []Problem {
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.problems
+}
+
+type validator struct {
+	functions map[string]function.Function
+	defined   map[string]hcl.Range // Where each rule ID was first defined
+	aliases   map[string]hcl.Range // Where each alias was first listed
+	src       []byte               // The file being validated
+	problems  []Problem
+}
+
+func (v *validator) add(rng hcl.Range, rule, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		File:    rng.Filename,
+		Line:    rng.Start.Line,
+		Column:  rng.Start.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addDiagnostics records HCL error diagnostics as problems
+func (v *validator) addDiagnostics(diags hcl.Diagnostics, rule string) {
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		message := diag.Summary
+		if diag.Detail != "" {
+			message += "; " + diag.Detail
+		}
+		rng := hcl.Range{}
+		if diag.Subject != nil {
+			rng = *diag.Subject
+		}
+		v.add(rng, rule, "%s", message)
+	}
+}
+
+// validateFile checks the rule blocks of a file. With decode set, the file
+// must also hold nothing but rules.
+func (v *validator) validateFile(path string, decode bool) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		v.problems = append(v.problems, Problem{File: path, Message: fmt.Sprintf("failed to read rule file: %v", err)})
+		return
+	}
+	v.src = src
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		v.addDiagnostics(diags, "")
+		return
+	}
+
+	// Decoding reports unknown blocks and attributes and missing required
+	// ones, as loading the rules would
+	if decode {
+		var decoded struct {
+			Rules []config.Rule `hcl:"rule,block"`
+		}
+		v.addDiagnostics(gohcl.DecodeBody(file.Body, nil, &decoded), "")
+	}
+
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type == "rule" && len(block.Labels) == 1 {
+			v.validateRule(block)
+		}
+	}
+}
+
+func (v *validator) validateRule(block *hclsyntax.Block) {
+	id := block.Labels[0]
+	if first, ok := v.defined[id]; ok {
+		v.add(block.LabelRanges[0], id, "rule ID is already defined at %s:%d; only the first definition is used", first.Filename, first.Start.Line)
+	} else {
+		v.defined[id] = block.LabelRanges[0]
+	}
+	if listed, ok := v.aliases[id]; ok {
+		v.add(block.LabelRanges[0], id, "rule ID is an alias listed at %s:%d", listed.Filename, listed.Start.Line)
+	}
+	if attr, ok := block.Body.Attributes["aliases"]; ok {
+		v.validateAliases(attr, id)
+	}
+
+	if attr, ok := block.Body.Attributes["severity"]; ok {
+		if severity, ok := v.stringValue(attr, id); ok {
+			switch severity {
+			case "error", "warning", "info":
+			default:
+				v.add(attr.Expr.Range(), id, "invalid severity %q (expected error, warning, or info)", severity)
+			}
+		}
+	}
+	if attr, ok := block.Body.Attributes["superseded_by"]; ok {
+		if successor, ok := v.stringValue(attr, id); ok && successor == id {
+			v.add(attr.Expr.Range(), id, "rule cannot be superseded by itself")
+		}
+	}
+	if attr, ok := block.Body.Attributes["on_unknown"]; ok {
+		if onUnknown, ok := v.stringValue(attr, id); ok && onUnknown != "skip" && onUnknown != "violation" {
+			v.add(attr.Expr.Range(), id, "invalid on_unknown %q (expected skip or violation)", onUnknown)
+		}
+	}
+
+	conditions := 0
+	for _, nested := range block.Body.Blocks {
+		if nested.Type == "metadata" {
+			v.validateMetadata(nested, id)
+			continue
+		}
+		if nested.Type != "when" && nested.Type != "condition" {
+			continue
+		}
+		if nested.Type == "condition" {
+			conditions++
+		}
+		if attr, ok := nested.Body.Attributes["expression"]; ok {
+			v.validateExpression(attr, id)
+		}
+	}
+	if conditions == 0 {
+		v.add(block.DefRange(), id, "rule has no condition blocks, so it never reports a violation")
+	}
+}
+
+// stringValue evaluates a constant string attribute
+func (v *validator) stringValue(attr *hclsyntax.Attribute, rule string) (string, bool) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		v.addDiagnostics(diags, rule)
+		return "", false
+	}
+	if value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		v.add(attr.Expr.Range(), rule, "%s must be a string", attr.Name)
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// validateAliases checks that a rule's aliases are strings naming neither
+// a rule nor another rule's alias
+func (v *validator) validateAliases(attr *hclsyntax.Attribute, id string) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		v.addDiagnostics(diags, id)
+		return
+	}
+	if value.IsNull() || !value.IsKnown() || !(value.Type().IsListType() || value.Type().IsTupleType()) {
+		v.add(attr.Expr.Range(), id, "aliases must be a list of strings")
+		return
+	}
+	for it := value.ElementIterator(); it.Next(); {
+		_, alias := it.Element()
+		if alias.IsNull() || !alias.IsKnown() || alias.Type() != cty.String {
+			v.add(attr.Expr.Range(), id, "aliases must be a list of strings")
+			return
+		}
+		name := alias.AsString()
+		defined, isRule := v.defined[name]
+		first, listed := v.aliases[name]
+		switch {
+		case name == id:
+			v.add(attr.Expr.Range(), id, "rule cannot be an alias of itself")
+		case isRule:
+			v.add(attr.Expr.Range(), id, "alias %q is the ID of the rule at %s:%d", name, defined.Filename, defined.Start.Line)
+		case listed:
+			v.add(attr.Expr.Range(), id, "alias %q is already listed at %s:%d", name, first.Filename, first.Start.Line)
+		default:
+			v.aliases[name] = attr.Expr.Range()
+		}
+	}
+}
+
+// validateMetadata checks that a rule's metadata block has a created date
+// in YYYY-MM-DD form and an absolute docs_url
+func (v *validator) validateMetadata(block *hclsyntax.Block, id string) {
+	if attr, ok := block.Body.Attributes["created"]; ok {
+		if created, ok := v.stringValue(attr, id); ok {
+			if _, err := time.Parse("2006-01-02", created); err != nil {
+				v.add(attr.Expr.Range(), id, "invalid metadata created %q (expected YYYY-MM-DD)", created)
+			}
+		}
+	}
+	if attr, ok := block.Body.Attributes["docs_url"]; ok {
+		if docsURL, ok := v.stringValue(attr, id); ok {
+			if u, err := url.Parse(docsURL); err != nil || !u.IsAbs() {
+				v.add(attr.Expr.Range(), id, "invalid metadata docs_url %q (expected an absolute URL)", docsURL)
+			}
+		}
+	}
+}
+
+// validateExpression parses an expression attribute's string as the
+// scanner does, placing diagnostics at their line in the rule file, and
+// checks its function calls and variables
+func (v *validator) validateExpression(attr *hclsyntax.Attribute, rule string) {
+	text, ok := v.stringValue(attr, rule)
+	if !ok {
+		return
+	}
+
+	// The expression starts after the opening quote, or on the line after
+	// a heredoc marker. Columns in indented heredocs are approximate.
+	start := attr.Expr.Range().Start
+	if bytes.HasPrefix(v.src[start.Byte:], []byte("<<")) {
+		start = hcl.Pos{Line: start.Line + 1, Column: 1}
+	} else {
+		start.Column++
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(text), attr.SrcRange.Filename, start)
+	if diags.HasErrors() {
+		v.addDiagnostics(diags, rule)
+		return
+	}
+
+	for _, traversal := range expr.Variables() {
+		if root := traversal.RootName(); root != "self" {
+			v.add(traversal.SourceRange(), rule, "unknown variable %q (only self is available)", root)
+		}
+	}
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok {
+			return nil
+		}
+		fn, ok := v.functions[call.Name]
+		if !ok {
+			v.add(call.NameRange, rule, "unknown function %q", call.Name)
+			return nil
+		}
+		if call.ExpandFinal {
+			return nil
+		}
+		params := len(fn.Params())
+		switch {
+		case len(call.Args) < params:
+			v.add(call.NameRange, rule, "%s takes %s, got %d", call.Name, arguments(params, fn.VarParam() != nil), len(call.Args))
+		case len(call.Args) > params && fn.VarParam() == nil:
+			v.add(call.NameRange, rule, "%s takes %s, got %d", call.Name, arguments(params, false), len(call.Args))
+		}
+		return nil
+	})
+}
+
+// arguments describes how many arguments a function takes
+func arguments(n int, variadic bool) string {
+	noun := "arguments"
+	if n == 1 {
+		noun = "argument"
+	}
+	if variadic {
+		return fmt.Sprintf("at least %d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %s", n, noun)
+}
@@ -0,0 +1,257 @@
+package rulecheck
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validRule = `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "valid file",
+			files: map[string]string{"s3.hcl": validRule},
+		},
+		{
+			name: "heredoc expression",
+			files: map[string]string{"iam.hcl": `rule "iam_wildcard" {
+  name          = "IAM wildcard"
+  severity      = "error"
+  resource_type = "aws_iam_policy"
+
+  condition {
+    expression = <<-EOT
+      contains(try(tolist(jsondecode(self.policy).Action), []), "*")
+    EOT
+  }
+
+  message = "No wildcards"
+}
+`},
+		},
+		{
+			name:  "pack manifest skipped",
+			files: map[string]string{"pack.hcl": `pack "aws" { version = "1.0.0" }`},
+		},
+		{
+			name:  "parse error",
+			files: map[string]string{"bad.hcl": "rule \"a\" {\n  name = \n}\n"},
+			want:  []string{"bad.hcl:2:10: Invalid expression; Expected the start of an expression, but found an invalid expression token."},
+		},
+		{
+			name: "bad severity and on_unknown",
+			files: map[string]string{"s3.hcl": `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "critical"
+  resource_type = "aws_s3_bucket"
+  on_unknown    = "ignore"
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+`},
+			want: []string{
+				`s3.hcl:3:19: rule s3_tags: invalid severity "critical" (expected error, warning, or info)`,
+				`s3.hcl:5:19: rule s3_tags: invalid on_unknown "ignore" (expected skip or violation)`,
+			},
+		},
+		{
+			name: "superseded by itself",
+			files: map[string]string{"s3.hcl": `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+  superseded_by = "s3_tags"
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+`},
+			want: []string{
+				`s3.hcl:5:19: rule s3_tags: rule cannot be superseded by itself`,
+			},
+		},
+		{
+			name: "aliases",
+			files: map[string]string{"a.hcl": validRule, "b.hcl": `rule "s3_tags_v2" {
+  name          = "S3 tags"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+  aliases       = ["s3_tags", "s3_tags_v2", "s3_old"]
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+
+rule "s3_old" {
+  name          = "Old S3 tags"
+  severity      = "warning"
+  resource_type = "aws_s3_bucket"
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+`},
+			want: []string{
+				`b.hcl:5:19: rule s3_tags_v2: alias "s3_tags" is the ID of the rule at a.hcl:1`,
+				`b.hcl:5:19: rule s3_tags_v2: rule cannot be an alias of itself`,
+				`b.hcl:14:6: rule s3_old: rule ID is an alias listed at b.hcl:5`,
+			},
+		},
+		{
+			name: "invalid metadata",
+			files: map[string]string{"s3.hcl": `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+
+  metadata {
+    owner    = "team-storage"
+    docs_url = "wiki/s3-tags"
+    created  = "March 2024"
+  }
+
+  condition {
+    expression = "!has(self, \"tags\")"
+  }
+
+  message = "Add tags"
+}
+`},
+			want: []string{
+				`s3.hcl:8:16: rule s3_tags: invalid metadata docs_url "wiki/s3-tags" (expected an absolute URL)`,
+				`s3.hcl:9:16: rule s3_tags: invalid metadata created "March 2024" (expected YYYY-MM-DD)`,
+			},
+		},
+		{
+			name: "expression problems",
+			files: map[string]string{"s3.hcl": `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+
+  when {
+    expression = "lenght(self.tags) > 0"
+  }
+
+  condition {
+    expression = "contains(self.tags) || var.strict"
+  }
+
+  condition {
+    expression = "self.acl =="
+  }
+
+  message = "Add tags"
+}
+`},
+			want: []string{
+				`s3.hcl:7:19: rule s3_tags: unknown function "lenght"`,
+				`s3.hcl:11:19: rule s3_tags: contains takes 2 arguments, got 1`,
+				`s3.hcl:11:42: rule s3_tags: unknown variable "var" (only self is available)`,
+				`s3.hcl:15:30: rule s3_tags: Missing expression; Expected the start of an expression, but found the end of the file.`,
+			},
+		},
+		{
+			name: "no conditions",
+			files: map[string]string{"s3.hcl": `rule "s3_tags" {
+  name          = "S3 tags"
+  severity      = "error"
+  resource_type = "aws_s3_bucket"
+  message       = "Add tags"
+}
+`},
+			want: []string{`s3.hcl:1:1: rule s3_tags: rule has no condition blocks, so it never reports a violation`},
+		},
+		{
+			name:  "duplicate ID across files",
+			files: map[string]string{"a.hcl": validRule, "b.hcl": validRule},
+			want:  []string{`b.hcl:1:6: rule s3_tags: rule ID is already defined at a.hcl:1; only the first definition is used`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var files []string
+			for _, name := range []string{"a.hcl", "b.hcl", "bad.hcl", "iam.hcl", "pack.hcl", "s3.hcl"} {
+				content, ok := tt.files[name]
+				if !ok {
+					continue
+				}
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, path)
+			}
+
+			var got []string
+			for _, problem := range Validate(files) {
+				got = append(got, strings.ReplaceAll(problem.String(), dir+string(filepath.Separator), ""))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLoaded(t *testing.T) {
+	dir := t.TempDir()
+	config := `settings {
+  fail_on_warning = true
+}
+
+` + strings.Replace(validRule, `"warning"`, `"eror"`, 1)
+	files := map[string]string{"config.hcl": config, "rules.hcl": validRule}
+	var paths []string
+	for _, name := range []string{"config.hcl", "rules.hcl"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	// The settings block and the rule overriding s3_tags are not problems
+	var got []string
+	for _, problem := range ValidateLoaded(paths) {
+		got = append(got, strings.ReplaceAll(problem.String(), dir+string(filepath.Separator), ""))
+	}
+	want := []string{`config.hcl:7:19: rule s3_tags: invalid severity "eror" (expected error, warning, or info)`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateLoaded() =\n%q\nwant\n%q", got, want)
+	}
+}
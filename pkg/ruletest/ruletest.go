@@ -0,0 +1,256 @@
+// Package ruletest runs the tests rule authors write for their rules: test
+// blocks in *_test.hcl files that scan a Terraform fixture with the rules
+// and assert which violations it has.
+package ruletest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+)
+
+// Test is a test block: a fixture, the rules it is scanned with, and the
+// violations expected from them
+type Test struct {
+	Name      string
+	File      string // Test file the block is in
+	Line      int
+	Rules     []string      `hcl:"rules,optional"`     // Rules to scan with (default: those in expect blocks)
+	Terraform *string       `hcl:"terraform,optional"` // Inline Terraform fixture
+	Fixture   *string       `hcl:"fixture,optional"`   // Fixture file or directory, relative to the test file
+	Expect    []Expectation `hcl:"expect,block"`
+}
+
+// Expectation asserts violations of a rule, optionally on one resource.
+// Without a count, at least one violation is expected.
+type Expectation struct {
+	Rule     string  `hcl:"rule"`
+	Resource *string `hcl:"resource,optional"` // Resource address, e.g. aws_s3_bucket.logs
+	Count    *int    `hcl:"count,optional"`
+}
+
+// Result is the outcome of running a test. A test passes when it has no
+// failures.
+type Result struct {
+	Test     Test
+	Failures []string
+}
+
+// Passed reports whether the test passed
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Discover lists the rule test files in paths, searching directories
+// recursively. Files named in paths are returned as they are.
+func Discover(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && config.IsRuleTestFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tests in %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
+
+// LoadFile loads the test blocks in a rule test file
+func LoadFile(path string) ([]Test, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test file: %w", err)
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "test", LabelNames: []string{"name"}}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var tests []Test
+	for _, block := range content.Blocks {
+		test := Test{Name: block.Labels[0], File: path, Line: block.DefRange.Start.Line}
+		if diags := gohcl.DecodeBody(block.Body, nil, &test); diags.HasErrors() {
+			return nil, diags
+		}
+		if (test.Terraform == nil) == (test.Fixture == nil) {
+			return nil, fmt.Errorf("%s:%d: test %q must set exactly one of terraform and fixture", path, test.Line, test.Name)
+		}
+		if len(test.Rules) == 0 && len(test.Expect) == 0 {
+			return nil, fmt.Errorf("%s:%d: test %q must name rules or expect violations", path, test.Line, test.Name)
+		}
+		tests = append(tests, test)
+	}
+	return tests, nil
+}
+
+// Run scans the test's fixture with the rules it tests, taken from rules
+// by ID, and checks the violations against its expectations. Violations of
+// the tested rules that no expectation covers are failures too, so a test
+// without expect blocks asserts that the fixture has no violations.
+func Run(test Test, rules []config.Rule) Result {
+	result := Result{Test: test}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	ids := test.Rules
+	if len(ids) == 0 {
+		for _, expect := range test.Expect {
+			ids = append(ids, expect.Rule)
+		}
+	}
+	byID := make(map[string]config.Rule)
+	for _, rule := range rules {
+		if _, ok := byID[rule.ID]; !ok {
+			byID[rule.ID] = rule
+		}
+	}
+	var tested []config.Rule
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		rule, ok := byID[id]
+		if !ok {
+			fail("no loaded rule has ID %q", id)
+			return result
+		}
+		tested = append(tested, rule)
+	}
+	for _, expect := range test.Expect {
+		if !seen[expect.Rule] {
+			fail("expect names rule %q, which is not in rules", expect.Rule)
+			return result
+		}
+	}
+
+	violations, err := scanFixture(test, tested)
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+
+	covered := make([]bool, len(violations))
+	for _, expect := range test.Expect {
+		count := 0
+		for i, v := range violations {
+			if v.RuleID == expect.Rule && (expect.Resource == nil || *expect.Resource == v.ResourceType+"."+v.ResourceName) {
+				count++
+				covered[i] = true
+			}
+		}
+		what := expect.Rule
+		if expect.Resource != nil {
+			what += " on " + *expect.Resource
+		}
+		switch {
+		case expect.Count != nil && count != *expect.Count:
+			fail("expected %d violations of %s, got %d", *expect.Count, what, count)
+		case expect.Count == nil && count == 0:
+			fail("expected violations of %s, got none", what)
+		}
+	}
+	for i, v := range violations {
+		if !covered[i] {
+			fail("unexpected violation of %s on %s.%s at %s:%d", v.RuleID, v.ResourceType, v.ResourceName, v.File, v.Line)
+		}
+	}
+	return result
+}
+
+// scanFixture parses the test's fixture and scans it with rules, returning
+// the violations in order of file and line
+func scanFixture(test Test, rules []config.Rule) ([]config.Violation, error) {
+	var files map[string]*hcl.File
+	if test.Terraform != nil {
+		filename := "terraform.tf"
+		file, diags := hclsyntax.ParseConfig([]byte(*test.Terraform), filename, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse terraform: %s", diags.Error())
+		}
+		files = map[string]*hcl.File{filename: file}
+	} else {
+		path := *test.Fixture
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(test.File), path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		paths := []string{path}
+		if info.IsDir() {
+			paths, err = parser.ListFiles(context.Background(), path, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list fixture files: %w", err)
+			}
+		}
+		files, err = parser.NewParser().ParseFiles(context.Background(), paths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fixture: %w", err)
+		}
+	}
+
+	resources, err := parser.ExtractResources(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract resources: %w", err)
+	}
+
+	// Exceptions are about real configurations, so none apply to fixtures
+	result, err := scanner.NewScanner(&config.Config{}, rules, parser.NewScanContext(resources)).Scan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan fixture: %w", err)
+	}
+	violations := result.Violations
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].File != violations[j].File {
+			return violations[i].File < violations[j].File
+		}
+		return violations[i].Line < violations[j].Line
+	})
+	return violations, nil
+}
+
+// Summary counts the passed and failed results, e.g. "3 passed, 1 failed"
+func Summary(results []Result) string {
+	passed := 0
+	for _, result := range results {
+		if result.Passed() {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d passed, %d failed", passed, len(results)-passed)
+}
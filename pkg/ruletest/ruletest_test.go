@@ -0,0 +1,219 @@
+package ruletest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+const buckets = `
+resource "aws_s3_bucket" "public" {
+  acl = "public-read"
+}
+
+resource "aws_s3_bucket" "private" {
+  acl = "private"
+}
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	rules := []config.Rule{
+		{ID: "public_bucket", Severity: "error", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: `self.acl == "public-read"`}}},
+		{ID: "any_bucket", Severity: "info", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "true"}}},
+	}
+	inline := buckets
+	one, two := 1, 2
+	public := "aws_s3_bucket.public"
+	private := "aws_s3_bucket.private"
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "fixtures", "buckets", "main.tf"), buckets)
+	fixtureDir := "fixtures/buckets"
+	fixtureFile := "fixtures/buckets/main.tf"
+	missing := "fixtures/missing.tf"
+
+	tests := []struct {
+		name         string
+		test         Test
+		wantFailures []string
+	}{
+		{
+			name: "expected violation",
+			test: Test{Terraform: &inline, Expect: []Expectation{{Rule: "public_bucket", Resource: &public, Count: &one}}},
+		},
+		{
+			name: "expected violation without resource or count",
+			test: Test{Terraform: &inline, Expect: []Expectation{{Rule: "public_bucket"}}},
+		},
+		{
+			name: "fixture directory",
+			test: Test{Fixture: &fixtureDir, Expect: []Expectation{{Rule: "public_bucket", Resource: &public}}},
+		},
+		{
+			name: "fixture file",
+			test: Test{Fixture: &fixtureFile, Expect: []Expectation{{Rule: "any_bucket", Count: &two}}},
+		},
+		{
+			name:         "expected no violations",
+			test:         Test{Terraform: &inline, Rules: []string{"public_bucket"}},
+			wantFailures: []string{"unexpected violation of public_bucket on aws_s3_bucket.public at terraform.tf:2"},
+		},
+		{
+			name:         "wrong count",
+			test:         Test{Terraform: &inline, Expect: []Expectation{{Rule: "any_bucket", Count: &one}}},
+			wantFailures: []string{"expected 1 violations of any_bucket, got 2"},
+		},
+		{
+			name:         "missing violation",
+			test:         Test{Terraform: &inline, Expect: []Expectation{{Rule: "public_bucket", Resource: &private}}},
+			wantFailures: []string{"expected violations of public_bucket on aws_s3_bucket.private, got none", "unexpected violation of public_bucket on aws_s3_bucket.public at terraform.tf:2"},
+		},
+		{
+			name:         "unknown rule",
+			test:         Test{Terraform: &inline, Rules: []string{"nope"}},
+			wantFailures: []string{`no loaded rule has ID "nope"`},
+		},
+		{
+			name:         "expectation outside rules",
+			test:         Test{Terraform: &inline, Rules: []string{"any_bucket"}, Expect: []Expectation{{Rule: "public_bucket"}}},
+			wantFailures: []string{`expect names rule "public_bucket", which is not in rules`},
+		},
+		{
+			name:         "missing fixture",
+			test:         Test{Fixture: &missing, Rules: []string{"public_bucket"}},
+			wantFailures: []string{"failed to read fixture"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test.File = filepath.Join(dir, "s3_test.hcl")
+			result := Run(tt.test, rules)
+			if len(result.Failures) != len(tt.wantFailures) {
+				t.Fatalf("Run() failures = %q, want %q", result.Failures, tt.wantFailures)
+			}
+			for i, want := range tt.wantFailures {
+				if !strings.Contains(result.Failures[i], want) {
+					t.Errorf("Run() failure %d = %q, want it to contain %q", i, result.Failures[i], want)
+				}
+			}
+			if result.Passed() != (len(tt.wantFailures) == 0) {
+				t.Errorf("Passed() = %v", result.Passed())
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // Test names
+		wantErr string
+	}{
+		{
+			name: "tests",
+			content: `test "flagged" {
+  terraform = "resource \"aws_s3_bucket\" \"a\" {}"
+  expect {
+    rule = "public_bucket"
+  }
+}
+
+test "clean" {
+  rules   = ["public_bucket"]
+  fixture = "fixtures/clean"
+}
+`,
+			want: []string{"flagged", "clean"},
+		},
+		{
+			name:    "both fixtures",
+			content: "test \"a\" {\n  terraform = \"\"\n  fixture   = \"x\"\n  rules     = [\"r\"]\n}\n",
+			wantErr: "must set exactly one of terraform and fixture",
+		},
+		{
+			name:    "no fixture",
+			content: "test \"a\" {\n  rules = [\"r\"]\n}\n",
+			wantErr: "must set exactly one of terraform and fixture",
+		},
+		{
+			name:    "nothing tested",
+			content: "test \"a\" {\n  terraform = \"\"\n}\n",
+			wantErr: "must name rules or expect violations",
+		},
+		{
+			name:    "unknown block",
+			content: "rule \"a\" {}\n",
+			wantErr: "Unsupported block type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "s3_test.hcl")
+			writeFile(t, path, tt.content)
+
+			tests, err := LoadFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			var names []string
+			for _, test := range tests {
+				names = append(names, test.Name)
+				if test.File != path {
+					t.Errorf("File = %q, want %q", test.File, path)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("LoadFile() names = %v, want %v", names, tt.want)
+			}
+			if tests[1].Line != 8 {
+				t.Errorf("Line = %d, want 8", tests[1].Line)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"s3.hcl", "s3_test.hcl", "aws/iam_test.hcl", "aws/iam.hcl", "fixtures/main.tf"} {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	files, err := Discover([]string{dir})
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "aws", "iam_test.hcl"), filepath.Join(dir, "s3_test.hcl")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Discover() = %v, want %v", files, want)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	results := []Result{{}, {Failures: []string{"x"}}, {}}
+	if got, want := Summary(results), "2 passed, 1 failed"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
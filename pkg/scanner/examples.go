@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+)
+
+// CheckExamples scans a rule's example_pass and example_fail snippets with
+// the rule alone and reports each one that does not behave as documented:
+// example_pass must not violate the rule and example_fail must. Rules
+// without examples pass.
+func CheckExamples(rule config.Rule) []error {
+	var errs []error
+	if rule.ExamplePass != nil {
+		count, err := countExampleViolations(rule, "example_pass", *rule.ExamplePass)
+		if err != nil {
+			errs = append(errs, err)
+		} else if count > 0 {
+			errs = append(errs, fmt.Errorf("rule %s: example_pass has %d violations, want none", rule.ID, count))
+		}
+	}
+	if rule.ExampleFail != nil {
+		count, err := countExampleViolations(rule, "example_fail", *rule.ExampleFail)
+		if err != nil {
+			errs = append(errs, err)
+		} else if count == 0 {
+			errs = append(errs, fmt.Errorf("rule %s: example_fail has no violations, want at least one", rule.ID))
+		}
+	}
+	return errs
+}
+
+// countExampleViolations parses an example as a Terraform file named after
+// the example and counts the violations the rule finds in it
+func countExampleViolations(rule config.Rule, name, example string) (int, error) {
+	filename := name + ".tf"
+	file, diags := hclsyntax.ParseConfig([]byte(example), filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return 0, fmt.Errorf("rule %s: failed to parse %s: %s", rule.ID, name, diags.Error())
+	}
+
+	resources, err := parser.ExtractResources(map[string]*hcl.File{filename: file})
+	if err != nil {
+		return 0, fmt.Errorf("rule %s: failed to extract resources from %s: %w", rule.ID, name, err)
+	}
+
+	// Exceptions are about real configurations, so none apply to examples
+	result, err := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources)).Scan()
+	if err != nil {
+		return 0, fmt.Errorf("rule %s: failed to scan %s: %w", rule.ID, name, err)
+	}
+	return len(result.Violations), nil
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	embeddedrules "github.com/jonathanhle/planguard/rules"
+)
+
+func TestCheckExamples(t *testing.T) {
+	publicBucket := `
+resource "aws_s3_bucket" "logs" {
+  acl = "public-read"
+}`
+	privateBucket := `
+resource "aws_s3_bucket" "logs" {
+  acl = "private"
+}`
+	invalid := `resource "aws_s3_bucket" {`
+
+	tests := []struct {
+		name        string
+		examplePass *string
+		exampleFail *string
+		wantErrs    []string
+	}{
+		{name: "no examples"},
+		{name: "examples behave", examplePass: &privateBucket, exampleFail: &publicBucket},
+		{name: "pass example violates", examplePass: &publicBucket, wantErrs: []string{"example_pass has 1 violations"}},
+		{name: "fail example passes", exampleFail: &privateBucket, wantErrs: []string{"example_fail has no violations"}},
+		{name: "unparseable example", examplePass: &invalid, wantErrs: []string{"failed to parse example_pass"}},
+		{
+			name:        "both wrong",
+			examplePass: &publicBucket,
+			exampleFail: &privateBucket,
+			wantErrs:    []string{"example_pass has 1 violations", "example_fail has no violations"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := config.Rule{
+				ID:           "public_bucket",
+				ResourceType: "aws_s3_bucket",
+				Conditions:   []config.Condition{{Expression: `self.acl == "public-read"`}},
+				ExamplePass:  tt.examplePass,
+				ExampleFail:  tt.exampleFail,
+			}
+
+			errs := CheckExamples(rule)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("CheckExamples() = %v, want %d errors", errs, len(tt.wantErrs))
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("CheckExamples()[%d] = %v, want it to contain %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPresuppliedRuleExamples(t *testing.T) {
+	categories, err := config.RuleCategoryNames(embeddedrules.FS)
+	if err != nil {
+		t.Fatalf("RuleCategoryNames() error = %v", err)
+	}
+	rules, err := config.LoadDefaultRulesFS(embeddedrules.FS, categories)
+	if err != nil {
+		t.Fatalf("LoadDefaultRulesFS() error = %v", err)
+	}
+
+	withExamples := 0
+	for _, rule := range rules {
+		if rule.ExamplePass != nil || rule.ExampleFail != nil {
+			withExamples++
+		}
+		for _, err := range CheckExamples(rule) {
+			t.Error(err)
+		}
+	}
+	if withExamples == 0 {
+		t.Error("Expected presupplied rules with examples")
+	}
+}
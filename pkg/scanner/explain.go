@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Explanation shows how a rule evaluates against one resource: the result
+// of its when expression and of every condition, each with the values of
+// the attributes it reads
+type Explanation struct {
+	Rule       config.Rule
+	Resource   *config.Resource
+	Targeted   bool               // Whether the rule's resource_type matches the resource
+	When       *ExpressionResult  // nil when the rule has no when
+	Runs       bool               // Whether the conditions are evaluated in a scan
+	Conditions []ExpressionResult // Evaluated even when the rule does not run
+	Violated   bool
+}
+
+// ExpressionResult is the result of evaluating a rule expression for a
+// resource
+type ExpressionResult struct {
+	Expression string
+	Result     string // true, false, null, unknown, or the evaluation error
+	Values     []AttributeValue
+}
+
+// AttributeValue is the value of an attribute an expression reads, such as
+// self.tags.Owner, formatted as JSON. Attributes the resource does not set
+// have the value "(not set)", and values only known after apply are
+// "(unknown)".
+type AttributeValue struct {
+	Path  string
+	Value string
+}
+
+// Explain evaluates rule against resource as a scan does, but evaluates
+// every condition rather than stopping at the first true one, and records
+// the attribute values each expression reads. The resource must be in the
+// scanner's context, so cross-resource functions see its neighbours.
+func (s *Scanner) Explain(rule config.Rule, resource *config.Resource) (*Explanation, error) {
+	unknownIsViolation, err := unknownIsViolation(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{Rule: rule, Resource: resource}
+	for _, candidate := range s.context.GetResourcesByType(rule.ResourceType) {
+		if candidate == resource {
+			explanation.Targeted = true
+			break
+		}
+	}
+	explanation.Runs = explanation.Targeted
+
+	s.context.CurrentResource = resource
+	if rule.When != nil {
+		when, value, ok := s.explainExpression(rule.When.Expression, resource)
+		explanation.When = &when
+		switch {
+		case !ok:
+			explanation.Runs = false
+		case !value.IsKnown():
+			explanation.Runs = explanation.Runs && unknownIsViolation
+		case value.IsNull() || value.False():
+			explanation.Runs = false
+		}
+	}
+
+	for _, condition := range rule.Conditions {
+		result, value, ok := s.explainExpression(condition.Expression, resource)
+		explanation.Conditions = append(explanation.Conditions, result)
+		if !explanation.Runs || !ok {
+			continue
+		}
+		if (!value.IsKnown() && unknownIsViolation) || (value.IsKnown() && !value.IsNull() && value.True()) {
+			explanation.Violated = true
+		}
+	}
+	return explanation, nil
+}
+
+// explainExpression evaluates an expression, returning its result and, when
+// evaluation succeeded, its boolean value
+func (s *Scanner) explainExpression(exprStr string, resource *config.Resource) (ExpressionResult, cty.Value, bool) {
+	result := ExpressionResult{Expression: strings.TrimSpace(exprStr)}
+	result.Values = attributeValues(exprStr, resource)
+
+	value, err := s.evaluateCondition(exprStr, resource)
+	switch {
+	case err != nil:
+		result.Result = err.Error()
+		return result, cty.NilVal, false
+	case !value.IsKnown():
+		result.Result = "unknown"
+	case value.IsNull():
+		result.Result = "null"
+	case value.True():
+		result.Result = "true"
+	default:
+		result.Result = "false"
+	}
+	return result, value, true
+}
+
+// attributeValues lists the self attributes an expression reads, in order
+// of first use, with their values on resource. Uses of self as a whole,
+// such as has(self, "tags"), are left out.
+func attributeValues(exprStr string, resource *config.Resource) []AttributeValue {
+	expr, diags := hclsyntax.ParseExpression([]byte(exprStr), "", hcl.Pos{})
+	if diags.HasErrors() {
+		return nil
+	}
+
+	self := map[string]cty.Value{"self": resourceToCtyValue(resource)}
+	seen := make(map[string]bool)
+	var values []AttributeValue
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "self" || len(traversal) == 1 {
+			continue
+		}
+		path := traversalPath(traversal)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		value, diags := traversal.TraverseAbs(&hcl.EvalContext{Variables: self})
+		if diags.HasErrors() {
+			values = append(values, AttributeValue{Path: path, Value: "(not set)"})
+			continue
+		}
+		values = append(values, AttributeValue{Path: path, Value: formatValue(value)})
+	}
+	return values
+}
+
+// traversalPath formats a traversal as it is written, e.g. self.tags["Owner"]
+func traversalPath(traversal hcl.Traversal) string {
+	var path strings.Builder
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			path.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			path.WriteString("." + step.Name)
+		case hcl.TraverseIndex:
+			path.WriteString("[" + formatValue(step.Key) + "]")
+		case hcl.TraverseSplat:
+			path.WriteString("[*]")
+		}
+	}
+	return path.String()
+}
+
+// formatValue formats a value as JSON, or "(unknown)" when it is not
+// wholly known
+func formatValue(value cty.Value) string {
+	if !value.IsWhollyKnown() {
+		return "(unknown)"
+	}
+	if value.IsNull() {
+		return "null"
+	}
+	data, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return fmt.Sprintf("(%s)", value.Type().FriendlyName())
+	}
+	return string(data)
+}
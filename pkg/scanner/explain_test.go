@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestExplain(t *testing.T) {
+	bucket := &config.Resource{
+		Type: "aws_s3_bucket",
+		Name: "logs",
+		Attributes: map[string]cty.Value{
+			"acl":    cty.StringVal("private"),
+			"tags":   cty.MapVal(map[string]cty.Value{"Owner": cty.StringVal("team")}),
+			"policy": cty.UnknownVal(cty.String),
+		},
+	}
+	violation := "violation"
+
+	tests := []struct {
+		name           string
+		rule           config.Rule
+		wantTargeted   bool
+		wantRuns       bool
+		wantViolated   bool
+		wantWhen       *ExpressionResult
+		wantConditions []ExpressionResult
+	}{
+		{
+			name: "every condition is evaluated",
+			rule: config.Rule{ResourceType: "aws_s3_bucket", Conditions: []config.Condition{
+				{Expression: `self.acl == "public-read"`},
+				{Expression: `self.tags["Owner"] == "team"`},
+			}},
+			wantTargeted: true,
+			wantRuns:     true,
+			wantViolated: true,
+			wantConditions: []ExpressionResult{
+				{Expression: `self.acl == "public-read"`, Result: "false", Values: []AttributeValue{{Path: "self.acl", Value: `"private"`}}},
+				{Expression: `self.tags["Owner"] == "team"`, Result: "true", Values: []AttributeValue{{Path: `self.tags["Owner"]`, Value: `"team"`}}},
+			},
+		},
+		{
+			name: "attributes that are not set",
+			rule: config.Rule{ResourceType: "aws_s3_bucket", Conditions: []config.Condition{
+				{Expression: `!has(self, "versioning") || try(self.versioning.enabled, false) != true`},
+			}},
+			wantTargeted: true,
+			wantRuns:     true,
+			wantViolated: true,
+			wantConditions: []ExpressionResult{
+				{Expression: `!has(self, "versioning") || try(self.versioning.enabled, false) != true`, Result: "true", Values: []AttributeValue{{Path: "self.versioning.enabled", Value: "(not set)"}}},
+			},
+		},
+		{
+			name: "when is false",
+			rule: config.Rule{
+				ResourceType: "aws_s3_bucket",
+				When:         &config.WhenBlock{Expression: `self.acl != "private"`},
+				Conditions:   []config.Condition{{Expression: "true"}},
+			},
+			wantTargeted: true,
+			wantWhen:     &ExpressionResult{Expression: `self.acl != "private"`, Result: "false", Values: []AttributeValue{{Path: "self.acl", Value: `"private"`}}},
+			wantConditions: []ExpressionResult{
+				{Expression: "true", Result: "true"},
+			},
+		},
+		{
+			name: "unknown value skipped",
+			rule: config.Rule{ResourceType: "aws_s3_bucket", Conditions: []config.Condition{
+				{Expression: `self.policy != ""`},
+			}},
+			wantTargeted: true,
+			wantRuns:     true,
+			wantConditions: []ExpressionResult{
+				{Expression: `self.policy != ""`, Result: "unknown", Values: []AttributeValue{{Path: "self.policy", Value: "(unknown)"}}},
+			},
+		},
+		{
+			name: "unknown value is a violation",
+			rule: config.Rule{ResourceType: "aws_s3_bucket", OnUnknown: &violation, Conditions: []config.Condition{
+				{Expression: `self.policy != ""`},
+			}},
+			wantTargeted: true,
+			wantRuns:     true,
+			wantViolated: true,
+			wantConditions: []ExpressionResult{
+				{Expression: `self.policy != ""`, Result: "unknown", Values: []AttributeValue{{Path: "self.policy", Value: "(unknown)"}}},
+			},
+		},
+		{
+			name: "other resource type",
+			rule: config.Rule{ResourceType: "aws_instance", Conditions: []config.Condition{
+				{Expression: "true"},
+			}},
+			wantConditions: []ExpressionResult{
+				{Expression: "true", Result: "true"},
+			},
+		},
+		{
+			name: "condition that fails to evaluate",
+			rule: config.Rule{ResourceType: "aws_s3_bucket", Conditions: []config.Condition{
+				{Expression: `"not a bool"`},
+				{Expression: "false"},
+			}},
+			wantTargeted: true,
+			wantRuns:     true,
+			wantConditions: []ExpressionResult{
+				{Expression: `"not a bool"`, Result: "expression must return boolean, got string"},
+				{Expression: "false", Result: "false"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner(&config.Config{}, []config.Rule{tt.rule}, parser.NewScanContext([]*config.Resource{bucket}))
+			got, err := s.Explain(tt.rule, bucket)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if got.Targeted != tt.wantTargeted || got.Runs != tt.wantRuns || got.Violated != tt.wantViolated {
+				t.Errorf("Explain() targeted, runs, violated = %v, %v, %v, want %v, %v, %v",
+					got.Targeted, got.Runs, got.Violated, tt.wantTargeted, tt.wantRuns, tt.wantViolated)
+			}
+			if !reflect.DeepEqual(got.When, tt.wantWhen) {
+				t.Errorf("Explain() when = %+v, want %+v", got.When, tt.wantWhen)
+			}
+			if !reflect.DeepEqual(got.Conditions, tt.wantConditions) {
+				t.Errorf("Explain() conditions = %+v, want %+v", got.Conditions, tt.wantConditions)
+			}
+		})
+	}
+}
+
+func TestExplainInvalidOnUnknown(t *testing.T) {
+	invalid := "maybe"
+	rule := config.Rule{ResourceType: "aws_s3_bucket", OnUnknown: &invalid}
+	bucket := &config.Resource{Type: "aws_s3_bucket", Name: "logs"}
+	s := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext([]*config.Resource{bucket}))
+	if _, err := s.Explain(rule, bucket); err == nil {
+		t.Error("Explain() error = nil, want an error for on_unknown")
+	}
+}
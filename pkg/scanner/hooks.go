@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/functions"
+	"github.com/jonathanhle/planguard/pkg/parser"
+)
+
+// ResourceMutator changes the scanned resources before any rule is
+// evaluated, e.g. to add tags looked up in a CMDB. It returns the resources
+// to scan, and may modify, add, or drop resources. Rules, including
+// cross-resource functions such as resources(), see only the returned
+// resources.
+type ResourceMutator interface {
+	MutateResources(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error)
+}
+
+// ResourceMutatorFunc adapts a function to a ResourceMutator
+type ResourceMutatorFunc func(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error)
+
+// MutateResources calls f
+func (f ResourceMutatorFunc) MutateResources(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error) {
+	return f(ctx, resources)
+}
+
+// ViolationProcessor changes the result of a scan after exceptions have
+// been applied, e.g. to route violations to their owners by setting
+// metadata or to drop violations handled elsewhere. It may modify result's
+// violations in place.
+type ViolationProcessor interface {
+	ProcessViolations(ctx context.Context, result *ScanResult) error
+}
+
+// ViolationProcessorFunc adapts a function to a ViolationProcessor
+type ViolationProcessorFunc func(ctx context.Context, result *ScanResult) error
+
+// ProcessViolations calls f
+func (f ViolationProcessorFunc) ProcessViolations(ctx context.Context, result *ScanResult) error {
+	return f(ctx, result)
+}
+
+// AddResourceMutator registers a mutator to run at the start of each scan.
+// Mutators run in the order they were added, each receiving the previous
+// one's resources. Caching is bypassed while mutators are registered, since
+// cached results are keyed on the files' contents alone.
+func (s *Scanner) AddResourceMutator(mutator ResourceMutator) {
+	s.mutators = append(s.mutators, mutator)
+}
+
+// AddViolationProcessor registers a processor to run at the end of each
+// scan. Processors run in the order they were added. They do not run on
+// incomplete results of a cancelled scan.
+func (s *Scanner) AddViolationProcessor(processor ViolationProcessor) {
+	s.processors = append(s.processors, processor)
+}
+
+// mutateResources runs the registered mutators and returns a copy of the
+// scanner whose scan context holds their resources. The scanner itself is
+// left unchanged, so every scan starts from the parsed resources.
+func (s *Scanner) mutateResources(ctx context.Context) (*Scanner, error) {
+	if len(s.mutators) == 0 {
+		return s, nil
+	}
+
+	resources := append([]*config.Resource(nil), s.context.AllResources...)
+	for _, mutator := range s.mutators {
+		var err error
+		resources, err = mutator.MutateResources(ctx, resources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mutate resources: %w", err)
+		}
+	}
+
+	scanCtx := parser.NewScanContext(resources)
+	scanCtx.Metadata = s.context.Metadata
+
+	clone := *s
+	clone.context = scanCtx
+	clone.functions = functions.BuildFunctions(scanCtx)
+	return &clone, nil
+}
+
+// processViolations runs the registered processors on result
+func (s *Scanner) processViolations(ctx context.Context, result *ScanResult) error {
+	for _, processor := range s.processors {
+		if err := processor.ProcessViolations(ctx, result); err != nil {
+			return fmt.Errorf("failed to process violations: %w", err)
+		}
+	}
+	return nil
+}
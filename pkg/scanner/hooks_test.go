@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestScanHooks(t *testing.T) {
+	untaggedRule := config.Rule{
+		ID:           "untagged",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: `!has(self, "tags")`}},
+		Message:      "Bucket has no tags",
+	}
+	countRule := config.Rule{
+		ID:           "too_many_buckets",
+		Severity:     "warning",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: `length(resources("aws_s3_bucket")) > 2`}},
+		Message:      "Too many buckets",
+	}
+
+	// Tags the logs bucket, as a CMDB lookup would
+	tagLogs := ResourceMutatorFunc(func(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error) {
+		var mutated []*config.Resource
+		for _, r := range resources {
+			if r.Name == "logs" {
+				tagged := *r
+				tagged.Attributes = map[string]cty.Value{"tags": cty.ObjectVal(map[string]cty.Value{"owner": cty.StringVal("platform")})}
+				r = &tagged
+			}
+			mutated = append(mutated, r)
+		}
+		return mutated, nil
+	})
+	addBucket := ResourceMutatorFunc(func(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error) {
+		return append(resources, &config.Resource{Type: "aws_s3_bucket", Name: "extra", Attributes: map[string]cty.Value{}}), nil
+	})
+	// Routes violations to a team through their labels
+	route := ViolationProcessorFunc(func(ctx context.Context, result *ScanResult) error {
+		for i := range result.Violations {
+			result.Violations[i].Labels = map[string]string{"team": "platform"}
+		}
+		return nil
+	})
+	dropWarnings := ViolationProcessorFunc(func(ctx context.Context, result *ScanResult) error {
+		var kept []config.Violation
+		for _, v := range result.Violations {
+			if v.Severity != "warning" {
+				kept = append(kept, v)
+			}
+		}
+		result.Violations = kept
+		return nil
+	})
+	failing := ResourceMutatorFunc(func(ctx context.Context, resources []*config.Resource) ([]*config.Resource, error) {
+		return nil, errors.New("cmdb unavailable")
+	})
+
+	tests := []struct {
+		name          string
+		mutators      []ResourceMutator
+		processors    []ViolationProcessor
+		want          []string // rule_id:name of each violation
+		wantResources int
+		wantTeam      bool
+		wantErr       string
+	}{
+		{name: "no hooks", want: []string{"untagged:data", "untagged:logs"}, wantResources: 2},
+		{name: "mutator enriches resources", mutators: []ResourceMutator{tagLogs}, want: []string{"untagged:data"}, wantResources: 2},
+		{
+			name:          "mutators run in order and feed cross-resource functions",
+			mutators:      []ResourceMutator{tagLogs, addBucket},
+			want:          []string{"too_many_buckets:data", "too_many_buckets:extra", "too_many_buckets:logs", "untagged:data", "untagged:extra"},
+			wantResources: 3,
+		},
+		{
+			name:          "processors run in order",
+			mutators:      []ResourceMutator{addBucket},
+			processors:    []ViolationProcessor{dropWarnings, route},
+			want:          []string{"untagged:data", "untagged:extra", "untagged:logs"},
+			wantResources: 3,
+			wantTeam:      true,
+		},
+		{name: "mutator error", mutators: []ResourceMutator{failing}, wantErr: "failed to mutate resources: cmdb unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := []*config.Resource{
+				{Type: "aws_s3_bucket", Name: "data", Attributes: map[string]cty.Value{}},
+				{Type: "aws_s3_bucket", Name: "logs", Attributes: map[string]cty.Value{}},
+			}
+			s := NewScanner(&config.Config{}, []config.Rule{untaggedRule, countRule}, parser.NewScanContext(resources))
+			for _, m := range tt.mutators {
+				s.AddResourceMutator(m)
+			}
+			for _, p := range tt.processors {
+				s.AddViolationProcessor(p)
+			}
+
+			result, err := s.Scan()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Scan() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			var got []string
+			for _, v := range result.Violations {
+				got = append(got, v.RuleID+":"+v.ResourceName)
+				if tt.wantTeam && v.Labels["team"] != "platform" {
+					t.Errorf("Violation %s:%s was not routed", v.RuleID, v.ResourceName)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Violations = %v, want %v", got, tt.want)
+			}
+			if result.Resources != tt.wantResources {
+				t.Errorf("Resources = %d, want %d", result.Resources, tt.wantResources)
+			}
+
+			// The scanner keeps its parsed resources between scans
+			if len(s.context.AllResources) != len(resources) {
+				t.Errorf("Scan changed the scanner's resources to %d", len(s.context.AllResources))
+			}
+		})
+	}
+}
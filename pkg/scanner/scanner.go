@@ -1,12 +1,19 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jonathanhle/planguard/pkg/cache"
 	"github.com/jonathanhle/planguard/pkg/config"
 	"github.com/jonathanhle/planguard/pkg/functions"
 	"github.com/jonathanhle/planguard/pkg/parser"
@@ -20,8 +27,24 @@ type Scanner struct {
 	rules     []config.Rule
 	context   *parser.ScanContext
 	functions map[string]function.Function
+	filters   []ResourceFilter
+
+	mutators   []ResourceMutator
+	processors []ViolationProcessor
+
+	concurrency int
+	progress    func(done, total int)
+	ruleDone    func() // Reports a finished rule to progress; set per scan
+
+	cache       *cache.Cache
+	ruleSetHash string
 }
 
+// ResourceFilter decides whether rules are evaluated against a resource.
+// Filtered-out resources remain visible to cross-resource functions such as
+// resources().
+type ResourceFilter func(resource *config.Resource) bool
+
 // NewScanner creates a new scanner instance
 func NewScanner(cfg *config.Config, rules []config.Rule, ctx *parser.ScanContext) *Scanner {
 	return &Scanner{
@@ -29,68 +52,602 @@ func NewScanner(cfg *config.Config, rules []config.Rule, ctx *parser.ScanContext
 		rules:     rules,
 		context:   ctx,
 		functions: functions.BuildFunctions(ctx),
+
+		concurrency: 1,
+	}
+}
+
+// SetConcurrency sets how many rules are evaluated in parallel. Values
+// below 1 are treated as 1. Output order does not depend on concurrency.
+func (s *Scanner) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.concurrency = n
+}
+
+// SetProgress sets a function called each time a rule has been evaluated,
+// with the number of rules evaluated so far and the number to evaluate.
+// Calls are serialized, but come from the goroutines evaluating rules.
+func (s *Scanner) SetProgress(progress func(done, total int)) {
+	s.progress = progress
+}
+
+// AddResourceFilter restricts evaluation to resources accepted by filter
+func (s *Scanner) AddResourceFilter(filter ResourceFilter) {
+	s.filters = append(s.filters, filter)
+}
+
+// SampleFilter returns a filter that deterministically selects roughly
+// percent% of resources. The same resource is always either in or out of
+// the sample, so repeated runs give stable results.
+func SampleFilter(percent float64) ResourceFilter {
+	return func(resource *config.Resource) bool {
+		h := fnv.New32a()
+		fmt.Fprintf(h, "%s\x00%s\x00%s", resource.File, resource.Type, resource.Name)
+		return float64(h.Sum32()%10000) < percent*100
+	}
+}
+
+// FileFilter returns a filter that selects resources declared in one of
+// files. Paths are compared after cleaning, so they must be in the same form
+// as the parsed resources' paths (e.g. both relative to the same directory).
+func FileFilter(files []string) ResourceFilter {
+	selected := make(map[string]bool, len(files))
+	for _, file := range files {
+		selected[filepath.Clean(file)] = true
+	}
+	return func(resource *config.Resource) bool {
+		return selected[filepath.Clean(resource.File)]
 	}
 }
 
+// SetCache enables per-file result caching. Results of rules that only
+// look at the resource being evaluated are reused for files whose contents
+// and rule set are unchanged; other rules are always evaluated. Caching is
+// bypassed while resource filters are active, since filtered scans are
+// partial, and while resource mutators are registered.
+func (s *Scanner) SetCache(c *cache.Cache, ruleSetHash string) {
+	s.cache = c
+	s.ruleSetHash = ruleSetHash
+}
+
+func (s *Scanner) shouldEvaluate(resource *config.Resource) bool {
+	for _, filter := range s.filters {
+		if !filter(resource) {
+			return false
+		}
+	}
+	return true
+}
+
 // ScanResult contains both violations and filtered violations
 type ScanResult struct {
 	Violations         []config.Violation
 	FilteredViolations []config.FilteredViolation
+	CachedFiles        int            // Files whose results were reused from the cache
+	Resources          int            // Resources in the scanned configuration
+	Coverage           []RuleCoverage // One entry per rule, in rule order
+	Incomplete         bool           // The scan was cancelled before every rule was evaluated
+	Score              float64        // Risk score of the violations; see RiskScore
+}
+
+// RuleCoverage records how far a rule got against the scanned resources
+// and what evaluating it cost. Resources whose results came from the cache
+// are counted as filtered.
+type RuleCoverage struct {
+	RuleID        string
+	ResourceType  string
+	Matched       int           // Resources matching the rule's resource_type
+	Filtered      int           // Matched resources excluded by resource filters
+	SkippedByWhen int           // Resources the rule's when condition skipped
+	Evaluated     int           // Resources whose conditions were checked
+	Violations    int           // Violations found, before exceptions are applied
+	Duration      time.Duration // Wall time spent evaluating the rule
+	Errors        []string      // Resources the rule could not be evaluated against
+}
+
+// MergeCoverage adds the coverage of another scan, such as another target,
+// to coverage. Rules are matched by ID; rules only in other are appended.
+func MergeCoverage(coverage, other []RuleCoverage) []RuleCoverage {
+	index := make(map[string]int, len(coverage))
+	for i, c := range coverage {
+		index[c.RuleID] = i
+	}
+	for _, c := range other {
+		i, ok := index[c.RuleID]
+		if !ok {
+			index[c.RuleID] = len(coverage)
+			coverage = append(coverage, c)
+			continue
+		}
+		coverage[i].Matched += c.Matched
+		coverage[i].Filtered += c.Filtered
+		coverage[i].SkippedByWhen += c.SkippedByWhen
+		coverage[i].Evaluated += c.Evaluated
+		coverage[i].Violations += c.Violations
+		coverage[i].Duration += c.Duration
+		coverage[i].Errors = append(coverage[i].Errors, c.Errors...)
+	}
+	return coverage
+}
+
+// EvaluationErrors returns the resources that rules could not be evaluated
+// against, in rule order. Those resources were skipped by the rule rather
+// than failing the scan, so a clean result may still be incomplete.
+func (r *ScanResult) EvaluationErrors() []string {
+	var errs []string
+	for _, c := range r.Coverage {
+		for _, e := range c.Errors {
+			errs = append(errs, fmt.Sprintf("rule %s: %s", c.RuleID, e))
+		}
+	}
+	return errs
+}
+
+// EvaluatedRules returns the IDs of the rules that evaluated at least one
+// resource. Resources whose results came from the cache count as filtered,
+// but were evaluated in an earlier run, so they count too.
+func (r *ScanResult) EvaluatedRules() map[string]bool {
+	evaluated := make(map[string]bool)
+	for _, c := range r.Coverage {
+		if c.Evaluated > 0 || (r.CachedFiles > 0 && c.Filtered > 0) {
+			evaluated[c.RuleID] = true
+		}
+	}
+	return evaluated
 }
 
 // Scan performs the security scan
 func (s *Scanner) Scan() (*ScanResult, error) {
+	return s.ScanWithContext(context.Background())
+}
+
+// ScanWithContext performs the security scan, stopping early once ctx is
+// done. A cancelled scan returns ctx's error together with a result marked
+// Incomplete that holds the violations found so far.
+func (s *Scanner) ScanWithContext(ctx context.Context) (*ScanResult, error) {
+	s, err := s.mutateResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.progress != nil {
+		s = s.withProgress()
+	}
+
 	var violations []config.Violation
+	var coverage []RuleCoverage
+	var cachedFiles int
 
-	// Scan each rule
-	for _, rule := range s.rules {
-		ruleViolations, err := s.scanRule(rule)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning rule %s: %w", rule.ID, err)
-		}
-		violations = append(violations, ruleViolations...)
+	if s.cache != nil && len(s.filters) == 0 && len(s.mutators) == 0 {
+		violations, coverage, cachedFiles, err = s.scanWithCache(ctx)
+	} else {
+		violations, coverage, err = s.scanRules(ctx)
+	}
+	if err != nil && !isCancellation(err) {
+		return nil, err
+	}
+
+	for i := range violations {
+		violations[i].Fingerprint = config.Fingerprint(violations[i])
 	}
 
 	// Filter exceptions and track filtered violations
+	SortViolations(violations)
 	filtered, excepted := s.filterExceptions(violations)
 
-	return &ScanResult{
+	result := &ScanResult{
 		Violations:         filtered,
 		FilteredViolations: excepted,
-	}, nil
+		CachedFiles:        cachedFiles,
+		Resources:          config.CountResources(s.context.AllResources),
+		Coverage:           coverage,
+		Incomplete:         err != nil,
+	}
+	if err != nil {
+		result.Score = RiskScore(s.config.Settings, result.Violations)
+		return result, err
+	}
+	if err := s.processViolations(ctx, result); err != nil {
+		return nil, err
+	}
+	result.Score = RiskScore(s.config.Settings, result.Violations)
+	return result, nil
 }
 
-func (s *Scanner) scanRule(rule config.Rule) ([]config.Violation, error) {
+// SortViolations orders violations by file, line, column, and rule ID, so
+// reports are byte-for-byte identical between runs regardless of rule
+// order, concurrency, or which results came from the cache. Scans sort
+// their results; callers merging several scans sort them again.
+func SortViolations(violations []config.Violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violationLess(violations[i], violations[j])
+	})
+}
+
+// SortFilteredViolations orders excepted violations as SortViolations
+// orders violations
+func SortFilteredViolations(filtered []config.FilteredViolation) {
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return violationLess(filtered[i].Violation, filtered[j].Violation)
+	})
+}
+
+func violationLess(a, b config.Violation) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Column != b.Column {
+		return a.Column < b.Column
+	}
+	if a.RuleID != b.RuleID {
+		return a.RuleID < b.RuleID
+	}
+	if a.ResourceType != b.ResourceType {
+		return a.ResourceType < b.ResourceType
+	}
+	return a.ResourceName < b.ResourceName
+}
+
+// isCancellation reports whether err means the scan's context was done
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// scanWithCache scans like scanRules, reusing cached results of cacheable
+// rules for unchanged files and caching the results of the files it
+// evaluates. Exceptions are applied afterwards, so they are never cached.
+func (s *Scanner) scanWithCache(ctx context.Context) ([]config.Violation, []RuleCoverage, int, error) {
+	var cacheable, uncacheable []config.Rule
+	for _, rule := range s.rules {
+		if isCacheable(rule) {
+			cacheable = append(cacheable, rule)
+		} else {
+			uncacheable = append(uncacheable, rule)
+		}
+	}
+
+	keys := make(map[string]string)
+	cached := make(map[string][]config.Violation)
+	for file := range s.context.ResourcesByFile {
+		key, err := s.cache.Key(s.ruleSetHash, file)
+		if err != nil {
+			// Unreadable files are simply evaluated without caching
+			continue
+		}
+		keys[file] = key
+		if violations, ok := s.cache.Get(key); ok {
+			cached[file] = violations
+		}
+	}
+
+	fresh := s.withRules(cacheable)
+	fresh.AddResourceFilter(func(resource *config.Resource) bool {
+		_, hit := cached[resource.File]
+		return !hit
+	})
+	freshViolations, freshCoverage, err := fresh.scanRules(ctx)
+	if err != nil && !isCancellation(err) {
+		return nil, nil, 0, err
+	}
+
+	var uncachedViolations []config.Violation
+	var uncachedCoverage []RuleCoverage
+	if err == nil {
+		uncachedViolations, uncachedCoverage, err = s.withRules(uncacheable).scanRules(ctx)
+		if err != nil && !isCancellation(err) {
+			return nil, nil, 0, err
+		}
+	}
+	cancelErr := err
+
+	// Store results of the files evaluated in this run. Caching is best
+	// effort: a failed write only costs a re-evaluation next time. Results
+	// of a cancelled scan are incomplete, so they are never cached, and
+	// neither are results with evaluation errors, so the errors are
+	// reported again on the next run.
+	if cancelErr == nil && !hasErrors(freshCoverage) {
+		byFile := make(map[string][]config.Violation)
+		for _, v := range freshViolations {
+			byFile[v.File] = append(byFile[v.File], v)
+		}
+		for file, key := range keys {
+			if _, hit := cached[file]; !hit {
+				_ = s.cache.Put(key, byFile[file])
+			}
+		}
+	}
+
+	violations := append(freshViolations, uncachedViolations...)
+	for _, v := range cached {
+		violations = append(violations, v...)
+	}
+
+	// Restore rule order
+	ruleIndex := make(map[string]int, len(s.rules))
+	for i, rule := range s.rules {
+		ruleIndex[rule.ID] = i
+	}
+	coverage := append(freshCoverage, uncachedCoverage...)
+	sort.SliceStable(coverage, func(i, j int) bool {
+		return ruleIndex[coverage[i].RuleID] < ruleIndex[coverage[j].RuleID]
+	})
+
+	return violations, coverage, len(cached), cancelErr
+}
+
+// hasErrors reports whether any rule in coverage had evaluation errors
+func hasErrors(coverage []RuleCoverage) bool {
+	for _, c := range coverage {
+		if len(c.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withRules returns a copy of the scanner that evaluates rules instead of
+// the scanner's own rules
+func (s *Scanner) withRules(rules []config.Rule) *Scanner {
+	clone := *s
+	clone.rules = rules
+	clone.filters = append([]ResourceFilter(nil), s.filters...)
+	return &clone
+}
+
+// nonLocalFunctions are functions whose results depend on more than the
+// resource being evaluated: other resources, the clock, or the environment
+var nonLocalFunctions = map[string]bool{
+	"resources":         true,
+	"resources_in_file": true,
+	"reference_count":   true,
+	"attached_to":       true,
+	"reachable_from":    true,
+	"timestamp":         true,
+	"day_of_week":       true,
+	"git_branch":        true,
+	"uuid":              true,
+}
+
+// crossResourceFunctions are the nonLocalFunctions that read resources
+// outside the resource's own file
+var crossResourceFunctions = map[string]bool{
+	"resources":       true,
+	"reference_count": true,
+	"attached_to":     true,
+	"reachable_from":  true,
+}
+
+// isCacheable reports whether a rule's results for a file depend only on
+// that file's contents
+func isCacheable(rule config.Rule) bool {
+	return !callsAny(rule, nonLocalFunctions, true)
+}
+
+// NeedsAllResources reports whether a rule looks at resources other than
+// the one being evaluated and those in its file, e.g. with resources(), so
+// its results are only right when every resource is scanned together
+func NeedsAllResources(rule config.Rule) bool {
+	return callsAny(rule, crossResourceFunctions, false)
+}
+
+// callsAny reports whether any of a rule's expressions calls one of the
+// functions, treating expressions that do not parse as calling them. With
+// providerRegion, reading self.provider_region counts as a call too: the
+// provider's region comes from the file declaring the provider, which may
+// not be the resource's.
+func callsAny(rule config.Rule, functions map[string]bool, providerRegion bool) bool {
+	expressions := make([]string, 0, len(rule.Conditions)+1)
+	if rule.When != nil {
+		expressions = append(expressions, rule.When.Expression)
+	}
+	for _, condition := range rule.Conditions {
+		expressions = append(expressions, condition.Expression)
+	}
+
+	for _, exprStr := range expressions {
+		expr, diags := hclsyntax.ParseExpression([]byte(exprStr), "", hcl.Pos{})
+		if diags.HasErrors() {
+			return true
+		}
+
+		calls := false
+		hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, ok := node.(*hclsyntax.FunctionCallExpr); ok && functions[call.Name] {
+				calls = true
+			}
+			if traversal, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && providerRegion && traversal.Traversal.RootName() == "self" && len(traversal.Traversal) > 1 {
+				if attr, ok := traversal.Traversal[1].(hcl.TraverseAttr); ok && attr.Name == parser.ProviderRegionAttribute {
+					calls = true
+				}
+			}
+			return nil
+		})
+		if calls {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanRules evaluates every rule, spreading rules across up to
+// s.concurrency workers. Violations are returned in rule order, and when
+// several rules fail the error of the first one is reported, so results are
+// the same regardless of scheduling. Once ctx is done, remaining rules are
+// skipped and the violations found so far are returned with ctx's error.
+func (s *Scanner) scanRules(ctx context.Context) ([]config.Violation, []RuleCoverage, error) {
+	results := make([][]config.Violation, len(s.rules))
+	coverage := make([]RuleCoverage, len(s.rules))
+	errs := make([]error, len(s.rules))
+
+	workers := s.concurrency
+	if workers > len(s.rules) {
+		workers = len(s.rules)
+	}
+
+	if workers <= 1 {
+		for i, rule := range s.rules {
+			results[i], coverage[i], errs[i] = s.scanRule(ctx, rule)
+			if errs[i] != nil {
+				break
+			}
+			if s.ruleDone != nil {
+				s.ruleDone()
+			}
+		}
+	} else {
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			worker := s.fork()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					if err := ctx.Err(); err != nil {
+						errs[i] = err
+						continue
+					}
+					results[i], coverage[i], errs[i] = worker.scanRule(ctx, worker.rules[i])
+					if errs[i] == nil && worker.ruleDone != nil {
+						worker.ruleDone()
+					}
+				}
+			}()
+		}
+		for i := range s.rules {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}
+
 	var violations []config.Violation
+	var cancelErr error
+	for i, rule := range s.rules {
+		if errs[i] != nil {
+			if !isCancellation(errs[i]) {
+				return nil, nil, fmt.Errorf("error scanning rule %s: %w", rule.ID, errs[i])
+			}
+			cancelErr = errs[i]
+		}
+		violations = append(violations, results[i]...)
+	}
+
+	return violations, coverage, cancelErr
+}
+
+// withProgress returns a copy of the scanner that counts evaluated rules
+// for its progress function. Copies made for cached and uncached rules and
+// for workers share the count.
+func (s *Scanner) withProgress() *Scanner {
+	var mu sync.Mutex
+	done, total := 0, len(s.rules)
+	clone := *s
+	clone.ruleDone = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		s.progress(done, total)
+	}
+	return &clone
+}
+
+// fork returns a copy of the scanner with its own scan context and function
+// table. Functions such as contains_function_call() read the context's
+// current resource, so each worker needs a private one; the resource
+// indexes themselves are shared read-only.
+func (s *Scanner) fork() *Scanner {
+	ctx := *s.context
+	ctx.CurrentResource = nil
+
+	clone := *s
+	clone.context = &ctx
+	clone.functions = functions.BuildFunctions(&ctx)
+	return &clone
+}
+
+func (s *Scanner) scanRule(ctx context.Context, rule config.Rule) (violations []config.Violation, coverage RuleCoverage, err error) {
+	coverage = RuleCoverage{RuleID: rule.ID, ResourceType: rule.ResourceType}
+	start := time.Now()
+	defer func() {
+		coverage.Duration = time.Since(start)
+		coverage.Violations = len(violations)
+	}()
+
+	unknownIsViolation, err := unknownIsViolation(rule)
+	if err != nil {
+		return nil, coverage, err
+	}
 
 	// Get resources matching the resource type
 	resources := s.context.GetResourcesByType(rule.ResourceType)
+	coverage.Matched = len(resources)
 
 	for _, resource := range resources {
+		if err := ctx.Err(); err != nil {
+			return violations, coverage, err
+		}
+		if !s.shouldEvaluate(resource) {
+			coverage.Filtered++
+			continue
+		}
+
 		// Set current resource in context
 		s.context.CurrentResource = resource
 
-		// Check when condition
+		// Check when condition. An unknown result only lets the rule run
+		// when the rule treats unknown values as violations.
 		if rule.When != nil {
-			shouldRun, err := s.evaluateExpression(rule.When.Expression, resource)
+			shouldRun, err := s.evaluateCondition(rule.When.Expression, resource)
+			if errors.Is(err, errEvaluationPanic) {
+				coverage.Errors = append(coverage.Errors, err.Error())
+				continue
+			}
 			if err != nil {
-				return nil, fmt.Errorf("error evaluating when condition: %w", err)
+				return nil, coverage, fmt.Errorf("error evaluating when condition: %w", err)
 			}
-			if !shouldRun {
+			if !shouldRun.IsKnown() {
+				if !unknownIsViolation {
+					coverage.SkippedByWhen++
+					continue
+				}
+			} else if shouldRun.IsNull() || shouldRun.False() {
+				coverage.SkippedByWhen++
 				continue
 			}
 		}
 
+		coverage.Evaluated++
+
 		// Check all conditions
 		violated := false
 		for _, condition := range rule.Conditions {
-			result, err := s.evaluateExpression(condition.Expression, resource)
+			result, err := s.evaluateCondition(condition.Expression, resource)
+			if errors.Is(err, errEvaluationPanic) {
+				coverage.Errors = append(coverage.Errors, err.Error())
+				break
+			}
 			if err != nil {
-				return nil, fmt.Errorf("error evaluating condition: %w", err)
+				return nil, coverage, fmt.Errorf("error evaluating condition: %w", err)
+			}
+
+			// An unknown result is a violation only if the rule opts in;
+			// otherwise the condition is skipped
+			if !result.IsKnown() {
+				if unknownIsViolation {
+					violated = true
+					break
+				}
+				continue
 			}
 
 			// If condition is true, it's a violation
-			if result {
+			if !result.IsNull() && result.True() {
 				violated = true
 				break
 			}
@@ -112,19 +669,227 @@ func (s *Scanner) scanRule(rule config.Rule) ([]config.Violation, error) {
 			if rule.Remediation != nil {
 				violation.Remediation = *rule.Remediation
 			}
+			violation.Deprecation = rule.Deprecation()
+			violation.Metadata = rule.Metadata
 
 			violations = append(violations, violation)
 		}
 	}
 
-	return violations, nil
+	return violations, coverage, nil
+}
+
+// Diagnose explains why a scan of resources evaluated no rule at all, which
+// would otherwise look like a clean scan. It returns nil when at least one
+// rule was evaluated, when there were no resources to evaluate, or when
+// results were reused from the cache.
+func Diagnose(result *ScanResult, resources []*config.Resource) []string {
+	if len(resources) == 0 || result.CachedFiles > 0 {
+		return nil
+	}
+
+	if len(result.Coverage) == 0 {
+		return []string{"no rules are loaded; check use_presupplied_rules, presupplied_rules_categories, enabled_rules, disabled_rules, and -rules-dir"}
+	}
+
+	var unmatched, filtered, skipped []RuleCoverage
+	for _, coverage := range result.Coverage {
+		switch {
+		case coverage.Evaluated > 0:
+			return nil
+		case coverage.Matched == 0:
+			unmatched = append(unmatched, coverage)
+		case coverage.SkippedByWhen > 0:
+			skipped = append(skipped, coverage)
+		default:
+			filtered = append(filtered, coverage)
+		}
+	}
+
+	diagnostics := []string{fmt.Sprintf("none of the %d rules was evaluated against the %d resources found", len(result.Coverage), config.CountResources(resources))}
+
+	if len(unmatched) > 0 {
+		wanted := make(map[string]bool)
+		for _, coverage := range unmatched {
+			wanted[coverage.ResourceType] = true
+		}
+		found := make(map[string]bool)
+		for _, resource := range resources {
+			found[resource.Type] = true
+		}
+		diagnostics = append(diagnostics, fmt.Sprintf("%d rules target resource types not present (%s); found types: %s",
+			len(unmatched), summarizeTypes(wanted), summarizeTypes(found)))
+	}
+	if len(filtered) > 0 {
+		diagnostics = append(diagnostics, fmt.Sprintf("%d rules matched only resources excluded by resource filters (e.g. -changed-only or -sample)", len(filtered)))
+	}
+	if len(skipped) > 0 {
+		diagnostics = append(diagnostics, fmt.Sprintf("%d rules were skipped by their when condition for every matching resource", len(skipped)))
+	}
+
+	return diagnostics
+}
+
+// summarizeTypes lists up to five type names in sorted order, noting how
+// many more there are
+func summarizeTypes(types map[string]bool) string {
+	const limit = 5
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > limit {
+		return fmt.Sprintf("%s, and %d more", strings.Join(names[:limit], ", "), len(names)-limit)
+	}
+	return strings.Join(names, ", ")
+}
+
+// RuleMatch records how a rule's targeting applied to a resource. When is
+// "" for rules without a when condition, otherwise the condition's result:
+// "true", "false", or "unknown".
+type RuleMatch struct {
+	RuleID string
+	When   string
+	Runs   bool // Whether the rule's conditions would be evaluated
+}
+
+// ResourceMatches lists the rules whose resource_type matches a resource
+type ResourceMatches struct {
+	Resource *config.Resource
+	Rules    []RuleMatch
+}
+
+// ExplainMatching reports, for each resource accepted by the resource
+// filters, which rules target it by resource_type and whether their when
+// conditions let them run. Rule conditions are not evaluated. Resources are
+// listed in file and line order, rules in rule order.
+func (s *Scanner) ExplainMatching() ([]ResourceMatches, error) {
+	byResource := make(map[*config.Resource][]RuleMatch)
+
+	for _, rule := range s.rules {
+		unknownIsViolation, err := unknownIsViolation(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range s.context.GetResourcesByType(rule.ResourceType) {
+			if !s.shouldEvaluate(resource) {
+				continue
+			}
+
+			match := RuleMatch{RuleID: rule.ID, Runs: true}
+			if rule.When != nil {
+				s.context.CurrentResource = resource
+				result, err := s.evaluateCondition(rule.When.Expression, resource)
+				if err != nil {
+					return nil, fmt.Errorf("error evaluating when condition of rule %s: %w", rule.ID, err)
+				}
+				switch {
+				case !result.IsKnown():
+					match.When = "unknown"
+					match.Runs = unknownIsViolation
+				case result.IsNull() || result.False():
+					match.When = "false"
+					match.Runs = false
+				default:
+					match.When = "true"
+				}
+			}
+			byResource[resource] = append(byResource[resource], match)
+		}
+	}
+
+	var matches []ResourceMatches
+	for _, resource := range s.context.AllResources {
+		if !s.shouldEvaluate(resource) {
+			continue
+		}
+		matches = append(matches, ResourceMatches{Resource: resource, Rules: byResource[resource]})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i].Resource, matches[j].Resource
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	return matches, nil
 }
 
+// unknownIsViolation reports how a rule treats conditions whose result
+// cannot be determined statically
+func unknownIsViolation(rule config.Rule) (bool, error) {
+	if rule.OnUnknown == nil {
+		return false, nil
+	}
+
+	switch *rule.OnUnknown {
+	case "skip":
+		return false, nil
+	case "violation":
+		return true, nil
+	default:
+		return false, fmt.Errorf("rule %s: invalid on_unknown value %q (expected \"skip\" or \"violation\")", rule.ID, *rule.OnUnknown)
+	}
+}
+
+// evaluateExpression evaluates an expression to a boolean. Unknown and null
+// results evaluate to false.
 func (s *Scanner) evaluateExpression(exprStr string, resource *config.Resource) (bool, error) {
+	value, err := s.evaluateCondition(exprStr, resource)
+	if err != nil {
+		return false, err
+	}
+
+	if !value.IsKnown() || value.IsNull() {
+		return false, nil
+	}
+
+	return value.True(), nil
+}
+
+// errEvaluationPanic marks errors recovered from a panic while evaluating
+// an expression. Such errors come from the resource's values rather than
+// the rule, so scanRule skips the resource instead of failing the scan.
+var errEvaluationPanic = errors.New("panic while evaluating")
+
+// functionPanic returns the value a function panicked with, if one of
+// diags reports a function call that panicked
+func functionPanic(diags hcl.Diagnostics) (interface{}, bool) {
+	for _, diag := range diags {
+		extra, ok := hcl.DiagnosticExtra[hclsyntax.FunctionCallDiagExtra](diag)
+		if !ok {
+			continue
+		}
+		var panicErr function.PanicError
+		if errors.As(extra.FunctionCallError(), &panicErr) {
+			return panicErr.Value, true
+		}
+	}
+	return nil, false
+}
+
+// evaluateCondition evaluates an expression that must produce a boolean.
+// The result may be unknown when it depends on values that cannot be
+// resolved statically, or null.
+func (s *Scanner) evaluateCondition(exprStr string, resource *config.Resource) (result cty.Value, err error) {
+	// A panic inside expression evaluation (e.g. a function receiving a
+	// value shape it does not expect) must not take down the whole scan
+	defer func() {
+		if r := recover(); r != nil {
+			result = cty.NilVal
+			err = fmt.Errorf("%w %s.%s (%s:%d): %v", errEvaluationPanic, resource.Type, resource.Name, resource.File, resource.Line, r)
+		}
+	}()
+
 	// Parse the expression
 	expr, diags := hclsyntax.ParseExpression([]byte(exprStr), "", hcl.Pos{})
 	if diags.HasErrors() {
-		return false, fmt.Errorf("invalid expression: %s", diags.Error())
+		return cty.NilVal, fmt.Errorf("invalid expression: %s", diags.Error())
 	}
 
 	// Build evaluation context
@@ -138,15 +903,29 @@ func (s *Scanner) evaluateExpression(exprStr string, resource *config.Resource)
 	// Evaluate expression
 	value, diags := expr.Value(evalCtx)
 	if diags.HasErrors() {
-		return false, fmt.Errorf("evaluation error: %s", diags.Error())
+		// Functions recover their own panics, which then arrive as
+		// diagnostics rather than through the deferred recover above
+		if r, ok := functionPanic(diags); ok {
+			return cty.NilVal, fmt.Errorf("%w %s.%s (%s:%d): %v", errEvaluationPanic, resource.Type, resource.Name, resource.File, resource.Line, r)
+		}
+		return cty.NilVal, fmt.Errorf("evaluation error: %s", diags.Error())
+	}
+
+	// Unknown values of any type may still resolve to a boolean later
+	if !value.IsKnown() && (value.Type() == cty.Bool || value.Type() == cty.DynamicPseudoType) {
+		return cty.UnknownVal(cty.Bool), nil
 	}
 
 	// Convert to boolean
 	if value.Type() == cty.Bool {
-		return value.True(), nil
+		return value, nil
+	}
+
+	if value.IsNull() && value.Type() == cty.DynamicPseudoType {
+		return cty.NullVal(cty.Bool), nil
 	}
 
-	return false, fmt.Errorf("expression must return boolean, got %s", value.Type().FriendlyName())
+	return cty.NilVal, fmt.Errorf("expression must return boolean, got %s", value.Type().FriendlyName())
 }
 
 func (s *Scanner) filterExceptions(violations []config.Violation) ([]config.Violation, []config.FilteredViolation) {
@@ -156,13 +935,6 @@ func (s *Scanner) filterExceptions(violations []config.Violation) ([]config.Viol
 	for _, violation := range violations {
 		exception, isExcepted := s.findException(violation)
 		if isExcepted {
-			// Log real-time feedback when exception is applied
-			fmt.Fprintf(os.Stderr, "✓ Exception applied: %s.%s - %s (Reason: %s)\n",
-				violation.ResourceType,
-				violation.ResourceName,
-				violation.RuleID,
-				exception.Reason)
-
 			excepted = append(excepted, config.FilteredViolation{
 				Violation: violation,
 				Exception: *exception,
@@ -175,6 +947,68 @@ func (s *Scanner) filterExceptions(violations []config.Violation) ([]config.Viol
 	return filtered, excepted
 }
 
+// Suppression silences a rule for one resource for a single run, e.g.
+// aws_s3_versioning:aws_s3_bucket.logs. Address may be a glob pattern.
+type Suppression struct {
+	RuleID  string
+	Address string
+}
+
+// ParseSuppression parses a suppression written as rule_id:type.name
+func ParseSuppression(value string) (Suppression, error) {
+	ruleID, address, ok := strings.Cut(value, ":")
+	if !ok || ruleID == "" || !strings.Contains(address, ".") {
+		return Suppression{}, fmt.Errorf("expected rule_id:resource_type.resource_name, got %q", value)
+	}
+	if _, err := filepath.Match(address, ""); err != nil {
+		return Suppression{}, fmt.Errorf("invalid resource pattern %q: %w", address, err)
+	}
+	return Suppression{RuleID: ruleID, Address: address}, nil
+}
+
+// Matches reports whether the suppression covers violation
+func (s Suppression) Matches(violation config.Violation) bool {
+	if s.RuleID != violation.RuleID {
+		return false
+	}
+	matched, _ := filepath.Match(s.Address, violation.ResourceType+"."+violation.ResourceName)
+	return matched
+}
+
+// ApplySuppressions moves violations covered by suppressions to the
+// filtered violations, recorded with an exception explaining why, so
+// suppressed findings stay visible in reports
+func ApplySuppressions(result *ScanResult, suppressions []Suppression) {
+	if len(suppressions) == 0 {
+		return
+	}
+
+	var kept []config.Violation
+	for _, violation := range result.Violations {
+		suppressed := false
+		for _, suppression := range suppressions {
+			if suppression.Matches(violation) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, violation)
+			continue
+		}
+
+		result.FilteredViolations = append(result.FilteredViolations, config.FilteredViolation{
+			Violation: violation,
+			Exception: config.Exception{
+				Rules:      []string{violation.RuleID},
+				Reason:     "Suppressed with -suppress for this run",
+				ApprovedBy: "command line",
+			},
+		})
+	}
+	result.Violations = kept
+}
+
 func (s *Scanner) findException(violation config.Violation) (*config.Exception, bool) {
 	for _, exception := range s.config.Exceptions {
 		// Check if rule matches
@@ -217,13 +1051,37 @@ func (s *Scanner) findException(violation config.Violation) (*config.Exception,
 			}
 		}
 
-		// Check expiration
-		if exception.ExpiresAt != nil {
-			expiryDate, err := time.Parse("2006-01-02", *exception.ExpiresAt)
-			if err == nil && time.Now().After(expiryDate) {
-				// Exception expired
+		// Check if severity matches
+		if len(exception.Severities) > 0 {
+			severityMatched := false
+			for _, severity := range exception.Severities {
+				if severity == violation.Severity {
+					severityMatched = true
+					break
+				}
+			}
+			if !severityMatched {
+				continue
+			}
+		}
+
+		// Check if the resource's address and tags match
+		if len(exception.Addresses) > 0 || len(exception.Tags) > 0 {
+			resource := s.context.ResourceAt(violation.File, violation.Line)
+			if resource == nil {
 				continue
 			}
+			if len(exception.Addresses) > 0 && !matchesAnyAddress(exception.Addresses, s.context.Addresses(resource)) {
+				continue
+			}
+			if !matchesTags(exception.Tags, resource) {
+				continue
+			}
+		}
+
+		// Expired exceptions no longer apply
+		if exception.Expired(time.Now()) {
+			continue
 		}
 
 		// All checks passed - exception applies
@@ -233,6 +1091,54 @@ func (s *Scanner) findException(violation config.Violation) (*config.Exception,
 	return nil, false
 }
 
+// matchesAnyAddress reports whether any of a resource's addresses matches
+// one of patterns
+func matchesAnyAddress(patterns, addresses []string) bool {
+	for _, pattern := range patterns {
+		for _, address := range addresses {
+			if matched, _ := filepath.Match(pattern, address); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesTags reports whether the resource's tags attribute has every key in
+// want, with a value matching its pattern
+func matchesTags(want map[string]string, resource *config.Resource) bool {
+	if len(want) == 0 {
+		return true
+	}
+
+	tags, ok := resource.Attributes["tags"]
+	if !ok || !tags.IsKnown() || tags.IsNull() || !(tags.Type().IsObjectType() || tags.Type().IsMapType()) {
+		return false
+	}
+
+	for key, pattern := range want {
+		var value cty.Value
+		if tags.Type().IsObjectType() {
+			if !tags.Type().HasAttribute(key) {
+				return false
+			}
+			value = tags.GetAttr(key)
+		} else {
+			if !tags.HasIndex(cty.StringVal(key)).True() {
+				return false
+			}
+			value = tags.Index(cty.StringVal(key))
+		}
+		if !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern, value.AsString()); !matched {
+			return false
+		}
+	}
+	return true
+}
+
 func resourceToCtyValue(resource *config.Resource) cty.Value {
 	attrs := make(map[string]cty.Value)
 
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+)
+
+// FuzzScan mutates rule expressions and resource attribute shapes and checks
+// that the scanner reports errors instead of panicking. Run with:
+//
+//	go test ./pkg/scanner -run '^$' -fuzz FuzzScan
+func FuzzScan(f *testing.F) {
+	seeds := []struct {
+		expression string
+		attributes string
+	}{
+		{`self.acl == "public-read"`, `acl = "public-read"`},
+		{`!has(self, "tags")`, `tags = { Name = "x" }`},
+		{`anytrue([for r in try(self.ingress, []) : contains(try(r.cidr_blocks, []), "0.0.0.0/0")])`, `ingress = [{ cidr_blocks = ["0.0.0.0/0"] }]`},
+		{`jsondecode(self.policy).Statement[0].Effect == "Allow"`, `policy = "{\"Statement\":[{\"Effect\":\"Allow\"}]}"`},
+		{`length(self.list) > 2`, `list = [1, "two", null, { a = true }]`},
+		{`self.count == null`, `count = var.n`},
+		{`is_unknown(self.name) || is_null(self.value)`, `name = local.x
+value = null`},
+		{`cidrhost(self.cidr, 300) == ""`, `cidr = "10.0.0.0/24"`},
+		{`base64decode(self.user_data) != ""`, `user_data = "!!!"`},
+		{`contains_function_call("nonsensitive")`, `value = nonsensitive(var.secret)`},
+		{`length(resources("aws_*")) > 0`, `x = 1`},
+		{`tonumber(self.port) > 1024`, `port = "abc"`},
+	}
+
+	for _, seed := range seeds {
+		f.Add(seed.expression, seed.attributes)
+	}
+
+	f.Fuzz(func(t *testing.T, expression, attributes string) {
+		src := []byte("resource \"aws_fuzz\" \"target\" {\n" + attributes + "\n}\n")
+		file, diags := hclparse.NewParser().ParseHCL(src, "fuzz.tf")
+		if diags.HasErrors() {
+			return
+		}
+
+		resources, err := parser.ExtractResources(map[string]*hcl.File{"fuzz.tf": file})
+		if err != nil {
+			return
+		}
+
+		rule := config.Rule{
+			ID:           "fuzz",
+			Name:         "Fuzz",
+			Severity:     "error",
+			ResourceType: "aws_fuzz",
+			When:         &config.WhenBlock{Expression: expression},
+			Conditions:   []config.Condition{{Expression: expression}},
+			Message:      "fuzz",
+		}
+
+		s := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+
+		// Errors are expected for most inputs; panics are not
+		_, _ = s.Scan()
+	})
+}
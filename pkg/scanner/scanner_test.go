@@ -1,13 +1,23 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/jonathanhle/planguard/pkg/cache"
 	"github.com/jonathanhle/planguard/pkg/config"
 	"github.com/jonathanhle/planguard/pkg/parser"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 )
 
 func TestNewScanner(t *testing.T) {
@@ -226,6 +236,7 @@ func TestScanWithRemediation(t *testing.T) {
 		},
 		Message:     "Test",
 		Remediation: &remediation,
+		Metadata:    &config.RuleMetadata{Owner: "team-compute"},
 	}
 
 	cfg := &config.Config{}
@@ -244,6 +255,9 @@ func TestScanWithRemediation(t *testing.T) {
 	if result.Violations[0].Remediation != remediation {
 		t.Errorf("Remediation not set correctly")
 	}
+	if metadata := result.Violations[0].Metadata; metadata == nil || metadata.Owner != "team-compute" {
+		t.Errorf("Metadata = %+v, want the rule's", metadata)
+	}
 }
 
 func TestFilterExceptions(t *testing.T) {
@@ -746,3 +760,1195 @@ func TestFilterExceptionsInvalidExpirationDate(t *testing.T) {
 		t.Errorf("Expected 0 filtered violations, got %d", len(filtered))
 	}
 }
+
+func TestScanUnknownValues(t *testing.T) {
+	onUnknownViolation := "violation"
+	onUnknownSkip := "skip"
+
+	tests := []struct {
+		name      string
+		onUnknown *string
+		when      *config.WhenBlock
+		want      int
+	}{
+		{name: "default skips unknown", onUnknown: nil, want: 0},
+		{name: "explicit skip", onUnknown: &onUnknownSkip, want: 0},
+		{name: "unknown as violation", onUnknown: &onUnknownViolation, want: 1},
+		{
+			name:      "unknown when clause skips by default",
+			onUnknown: nil,
+			when:      &config.WhenBlock{Expression: `self.acl == "private"`},
+			want:      0,
+		},
+		{
+			name:      "unknown when clause runs when opted in",
+			onUnknown: &onUnknownViolation,
+			when:      &config.WhenBlock{Expression: `self.acl == "private"`},
+			want:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := []*config.Resource{
+				{
+					Type: "aws_s3_bucket",
+					Name: "interpolated",
+					Attributes: map[string]cty.Value{
+						"acl": cty.DynamicVal,
+					},
+				},
+			}
+
+			rule := config.Rule{
+				ID:           "public_acl",
+				Name:         "Public ACL",
+				Severity:     "error",
+				ResourceType: "aws_s3_bucket",
+				When:         tt.when,
+				Conditions: []config.Condition{
+					{Expression: `self.acl == "public-read"`},
+				},
+				Message:   "Bucket is public",
+				OnUnknown: tt.onUnknown,
+			}
+
+			scanner := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+			result, err := scanner.Scan()
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			if len(result.Violations) != tt.want {
+				t.Errorf("Expected %d violations, got %d", tt.want, len(result.Violations))
+			}
+		})
+	}
+}
+
+func TestScanUnknownDoesNotMaskKnownCondition(t *testing.T) {
+	resources := []*config.Resource{
+		{
+			Type: "aws_s3_bucket",
+			Name: "mixed",
+			Attributes: map[string]cty.Value{
+				"acl":    cty.DynamicVal,
+				"bucket": cty.StringVal("public-assets"),
+			},
+		},
+	}
+
+	rule := config.Rule{
+		ID:           "mixed",
+		Name:         "Mixed",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions: []config.Condition{
+			{Expression: `self.acl == "public-read"`},
+			{Expression: `self.bucket == "public-assets"`},
+		},
+		Message: "Violation",
+	}
+
+	scanner := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(result.Violations) != 1 {
+		t.Errorf("Known true condition should still trigger after an unknown one, got %d violations", len(result.Violations))
+	}
+}
+
+func TestScanInvalidOnUnknown(t *testing.T) {
+	invalid := "maybe"
+	resources := []*config.Resource{{Type: "aws_s3_bucket", Name: "b"}}
+	rule := config.Rule{
+		ID:           "bad",
+		Name:         "Bad",
+		Severity:     "error",
+		ResourceType: "aws_s3_bucket",
+		Conditions:   []config.Condition{{Expression: "true"}},
+		Message:      "Bad",
+		OnUnknown:    &invalid,
+	}
+
+	scanner := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+	if _, err := scanner.Scan(); err == nil || !strings.Contains(err.Error(), "on_unknown") {
+		t.Errorf("Expected on_unknown validation error, got %v", err)
+	}
+}
+
+func TestEvaluateExpressionNullAndUnknown(t *testing.T) {
+	resource := &config.Resource{
+		Type: "aws_instance",
+		Name: "test",
+		Attributes: map[string]cty.Value{
+			"monitoring": cty.NullVal(cty.Bool),
+			"ami":        cty.DynamicVal,
+		},
+	}
+
+	scanner := NewScanner(&config.Config{}, []config.Rule{}, parser.NewScanContext([]*config.Resource{resource}))
+
+	for _, expr := range []string{"self.monitoring", `self.ami == "ami-123"`, "null"} {
+		result, err := scanner.evaluateExpression(expr, resource)
+		if err != nil {
+			t.Errorf("evaluateExpression(%q) error = %v", expr, err)
+		}
+		if result {
+			t.Errorf("evaluateExpression(%q) = true, want false", expr)
+		}
+	}
+
+	for expr, want := range map[string]bool{
+		"is_null(self.monitoring)": true,
+		"is_unknown(self.ami)":     true,
+		"is_unknown(self.type)":    false,
+	} {
+		result, err := scanner.evaluateExpression(expr, resource)
+		if err != nil {
+			t.Fatalf("evaluateExpression(%q) error = %v", expr, err)
+		}
+		if result != want {
+			t.Errorf("evaluateExpression(%q) = %v, want %v", expr, result, want)
+		}
+	}
+}
+
+func TestEvaluateConditionRecoversFromPanic(t *testing.T) {
+	// A zero cty.Value is never produced by the parser but can be supplied
+	// by embedders constructing resources by hand
+	resource := &config.Resource{
+		Type: "aws_instance",
+		Name: "malformed",
+		File: "main.tf",
+		Line: 3,
+		Attributes: map[string]cty.Value{
+			"ami": cty.NilVal,
+		},
+	}
+	scanner := NewScanner(&config.Config{}, []config.Rule{}, parser.NewScanContext([]*config.Resource{resource}))
+
+	_, err := scanner.evaluateExpression(`self.ami`, resource)
+	if err == nil {
+		t.Fatal("Expected panic to be reported as an error")
+	}
+	if !strings.Contains(err.Error(), "aws_instance.malformed") || !strings.Contains(err.Error(), "main.tf:3") {
+		t.Errorf("Error should identify the resource, got: %v", err)
+	}
+}
+
+func TestScanContinuesAfterPanic(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_instance", Name: "first", File: "main.tf", Line: 1},
+		{Type: "aws_instance", Name: "malformed", File: "main.tf", Line: 5},
+		{Type: "aws_instance", Name: "second", File: "main.tf", Line: 9},
+	}
+	rules := []config.Rule{
+		{
+			ID:           "in_condition",
+			Severity:     "error",
+			ResourceType: "aws_instance",
+			Conditions:   []config.Condition{{Expression: `fragile(self.name)`}},
+		},
+		{
+			ID:           "in_when",
+			Severity:     "warning",
+			ResourceType: "aws_instance",
+			When:         &config.WhenBlock{Expression: `fragile(self.name)`},
+			Conditions:   []config.Condition{{Expression: `true`}},
+		},
+	}
+	scanner := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+
+	// Stands in for a function with a bug that only one resource's values
+	// reach
+	scanner.functions["fragile"] = function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "name", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if args[0].AsString() == "malformed" {
+				panic("index out of range")
+			}
+			return cty.True, nil
+		},
+	})
+
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var got []string
+	for _, v := range result.Violations {
+		got = append(got, v.RuleID+" "+v.ResourceName)
+	}
+	want := []string{"in_condition first", "in_when first", "in_condition second", "in_when second"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations = %v, want %v", got, want)
+	}
+
+	errs := result.EvaluationErrors()
+	if len(errs) != 2 {
+		t.Fatalf("EvaluationErrors() = %v, want one per rule", errs)
+	}
+	for i, ruleID := range []string{"in_condition", "in_when"} {
+		if !strings.HasPrefix(errs[i], "rule "+ruleID+": ") || !strings.Contains(errs[i], "aws_instance.malformed (main.tf:5)") {
+			t.Errorf("EvaluationErrors()[%d] = %q, want rule %s and the malformed resource", i, errs[i], ruleID)
+		}
+	}
+}
+
+func TestAddResourceFilter(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_instance", Name: "keep"},
+		{Type: "aws_instance", Name: "skip"},
+	}
+
+	rule := config.Rule{
+		ID:           "always",
+		Name:         "Always",
+		Severity:     "error",
+		ResourceType: "aws_instance",
+		Conditions: []config.Condition{
+			// Filtered resources must stay visible to cross-resource functions
+			{Expression: `length(resources("aws_instance")) == 2`},
+		},
+		Message: "Violation",
+	}
+
+	scanner := NewScanner(&config.Config{}, []config.Rule{rule}, parser.NewScanContext(resources))
+	scanner.AddResourceFilter(func(r *config.Resource) bool { return r.Name != "skip" })
+
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(result.Violations) != 1 || result.Violations[0].ResourceName != "keep" {
+		t.Errorf("Expected only the unfiltered resource to be evaluated, got %+v", result.Violations)
+	}
+}
+
+func TestSampleFilter(t *testing.T) {
+	var resources []*config.Resource
+	for i := 0; i < 2000; i++ {
+		resources = append(resources, &config.Resource{
+			Type: "aws_instance",
+			Name: fmt.Sprintf("r%d", i),
+			File: "main.tf",
+		})
+	}
+
+	filter := SampleFilter(10)
+	selected := 0
+	for _, r := range resources {
+		if filter(r) {
+			selected++
+		}
+		if filter(r) != SampleFilter(10)(r) {
+			t.Fatalf("Sampling should be deterministic for %s", r.Name)
+		}
+	}
+
+	if selected < 100 || selected > 300 {
+		t.Errorf("Expected roughly 10%% of 2000 resources, got %d", selected)
+	}
+
+	all := SampleFilter(100)
+	for _, r := range resources {
+		if !all(r) {
+			t.Fatalf("100%% sample should include every resource, missed %s", r.Name)
+		}
+	}
+}
+
+func TestFileFilter(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "changed", File: "infra/main.tf", Attributes: map[string]cty.Value{}},
+		{Type: "aws_s3_bucket", Name: "unchanged", File: "infra/other.tf", Attributes: map[string]cty.Value{}},
+	}
+
+	rules := []config.Rule{
+		{
+			ID:           "too_many_buckets",
+			Name:         "Only one bucket allowed",
+			Severity:     "error",
+			ResourceType: "aws_s3_bucket",
+			Conditions:   []config.Condition{{Expression: `length(resources("aws_s3_bucket")) > 1`}},
+			Message:      "Too many buckets",
+		},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	s.AddResourceFilter(FileFilter([]string{"./infra/main.tf"}))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	// Only the changed file is evaluated, but resources() still sees both
+	if len(result.Violations) != 1 || result.Violations[0].ResourceName != "changed" {
+		t.Errorf("Expected one violation for the changed resource, got %+v", result.Violations)
+	}
+}
+
+func TestScanVariablesAndChecks(t *testing.T) {
+	src := []byte(`
+variable "environment" {
+  validation {
+    condition     = length(var.environment) > 0
+    error_message = "Required."
+  }
+}
+
+variable "db_password" {}
+
+resource "aws_s3_bucket" "logs" {}
+resource "aws_s3_bucket" "assets" {}
+
+check "logs_versioned" {
+  assert {
+    condition     = aws_s3_bucket.logs.versioning[0].enabled
+    error_message = "Logs bucket must be versioned."
+  }
+}
+`)
+	file, diags := hclparse.NewParser().ParseHCL(src, "main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	resources, err := parser.ExtractResources(map[string]*hcl.File{"main.tf": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []config.Rule{
+		{
+			ID:           "variable_validation",
+			Name:         "Variables must declare validation",
+			Severity:     "warning",
+			ResourceType: "variable",
+			Conditions:   []config.Condition{{Expression: `!has(self, "validation")`}},
+			Message:      "Add a validation block",
+		},
+		{
+			ID:           "bucket_versioning",
+			Name:         "Buckets must be versioned",
+			Severity:     "error",
+			ResourceType: "aws_s3_bucket",
+			When: &config.WhenBlock{
+				Expression: `!anytrue([for c in resources("check") : contains(c.references, "${self.type}.${self.name}")])`,
+			},
+			Conditions: []config.Condition{{Expression: `!has(self, "versioning")`}},
+			Message:    "Enable versioning",
+		},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, v := range result.Violations {
+		got[v.RuleID+":"+v.ResourceName] = true
+	}
+	want := []string{"variable_validation:db_password", "bucket_versioning:assets"}
+	if len(result.Violations) != len(want) {
+		t.Errorf("Expected %d violations, got %v", len(want), got)
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("Expected violation %s, got %v", w, got)
+		}
+	}
+}
+
+func TestScanReferenceCount(t *testing.T) {
+	src := []byte(`
+variable "region" {}
+variable "unused" {}
+
+locals {
+  prefix = "app-${var.region}"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "${local.prefix}-logs"
+}
+
+resource "aws_s3_bucket" "assets" {}
+
+output "logs_arn" {
+  value = aws_s3_bucket.logs.arn
+}
+`)
+	file, diags := hclparse.NewParser().ParseHCL(src, "main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	resources, err := parser.ExtractResources(map[string]*hcl.File{"main.tf": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rules []config.Rule
+	for _, resourceType := range []string{"variable", "local", "*"} {
+		rules = append(rules, config.Rule{
+			ID:           "unreferenced_" + resourceType,
+			Name:         "Unreferenced",
+			Severity:     "info",
+			ResourceType: resourceType,
+			Conditions:   []config.Condition{{Expression: "reference_count() == 0"}},
+			Message:      "Never referenced",
+		})
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, v := range result.Violations {
+		got[v.ResourceType+"."+v.ResourceName] = true
+	}
+	want := []string{"variable.unused", "aws_s3_bucket.assets"}
+	if len(result.Violations) != len(want) {
+		t.Errorf("Expected %d violations, got %v", len(want), got)
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("Expected violation for %s, got %v", w, got)
+		}
+	}
+}
+
+func TestScanGraphFunctions(t *testing.T) {
+	src := []byte(`
+resource "aws_internet_gateway" "gw" {}
+
+resource "aws_route_table" "public" {
+  route {
+    gateway_id = aws_internet_gateway.gw.id
+  }
+}
+
+resource "aws_route_table" "private" {}
+
+resource "aws_subnet" "public" {}
+resource "aws_subnet" "private" {}
+resource "aws_subnet" "empty_public" {}
+
+resource "aws_route_table_association" "public" {
+  subnet_id      = aws_subnet.public.id
+  route_table_id = aws_route_table.public.id
+}
+
+resource "aws_route_table_association" "empty_public" {
+  subnet_id      = aws_subnet.empty_public.id
+  route_table_id = aws_route_table.public.id
+}
+
+resource "aws_route_table_association" "private" {
+  subnet_id      = aws_subnet.private.id
+  route_table_id = aws_route_table.private.id
+}
+
+resource "aws_db_subnet_group" "db" {
+  subnet_ids = [aws_subnet.public.id, aws_subnet.private.id]
+}
+`)
+	file, diags := hclparse.NewParser().ParseHCL(src, "main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+	resources, err := parser.ExtractResources(map[string]*hcl.File{"main.tf": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []config.Rule{
+		{
+			ID:           "no_database_in_public_subnet",
+			Name:         "Databases must not be placed in public subnets",
+			Severity:     "error",
+			ResourceType: "aws_subnet",
+			When: &config.WhenBlock{
+				Expression: `anytrue([for a in attached_to(self, "aws_route_table_association") : length(reachable_from(a, "aws_internet_gateway")) > 0])`,
+			},
+			Conditions: []config.Condition{{Expression: `length(attached_to(self, "aws_db_subnet_group")) > 0`}},
+			Message:    "Public subnet hosts a database",
+		},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if len(result.Violations) != 1 || result.Violations[0].ResourceName != "public" {
+		t.Errorf("Expected one violation for the public subnet, got %+v", result.Violations)
+	}
+}
+
+func TestScanGraphFunctionsRejectNonResource(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_subnet", Name: "a", File: "main.tf", Line: 1, Attributes: map[string]cty.Value{}},
+	}
+	rules := []config.Rule{
+		{
+			ID:           "bad_call",
+			Name:         "Bad call",
+			Severity:     "error",
+			ResourceType: "aws_subnet",
+			Conditions:   []config.Condition{{Expression: `length(attached_to("aws_subnet.a", "*")) > 0`}},
+			Message:      "unreachable",
+		},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	if _, err := s.Scan(); err == nil {
+		t.Error("Expected an error when attached_to is given a string")
+	}
+}
+
+func TestScanSortsViolations(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "late", File: "b.tf", Line: 1},
+		{Type: "aws_s3_bucket", Name: "second", File: "a.tf", Line: 20},
+		{Type: "aws_s3_bucket", Name: "first", File: "a.tf", Line: 3},
+	}
+	rules := []config.Rule{
+		{ID: "z_rule", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "true"}}},
+		{ID: "a_rule", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "true"}}},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if result.Resources != 3 {
+		t.Errorf("Resources = %d, want 3", result.Resources)
+	}
+
+	var got []string
+	for _, v := range result.Violations {
+		got = append(got, fmt.Sprintf("%s:%d %s", v.File, v.Line, v.RuleID))
+	}
+	want := []string{"a.tf:3 a_rule", "a.tf:3 z_rule", "a.tf:20 a_rule", "a.tf:20 z_rule", "b.tf:1 a_rule", "b.tf:1 z_rule"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Violation order = %v, want %v", got, want)
+	}
+}
+
+func TestScanConcurrencyPreservesOrder(t *testing.T) {
+	var resources []*config.Resource
+	for i := 0; i < 20; i++ {
+		resources = append(resources, &config.Resource{
+			Type: "aws_instance",
+			Name: fmt.Sprintf("web%d", i),
+			File: "main.tf",
+			Line: i + 1,
+			Attributes: map[string]cty.Value{
+				"index": cty.NumberIntVal(int64(i)),
+			},
+		})
+	}
+
+	var rules []config.Rule
+	for i := 0; i < 10; i++ {
+		rules = append(rules, config.Rule{
+			ID:           fmt.Sprintf("rule_%d", i),
+			Name:         "Modulo",
+			Severity:     "error",
+			ResourceType: "aws_instance",
+			Conditions: []config.Condition{
+				{Expression: fmt.Sprintf("self.index %% %d == 0 && !contains_function_call(\"nonsensitive\")", i+1)},
+			},
+			Message: "Test",
+		})
+	}
+
+	scan := func(concurrency int) []config.Violation {
+		s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+		s.SetConcurrency(concurrency)
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan() with concurrency %d error = %v", concurrency, err)
+		}
+		return result.Violations
+	}
+
+	sequential := scan(1)
+	parallel := scan(8)
+
+	if len(sequential) == 0 {
+		t.Fatal("Expected violations")
+	}
+	if len(parallel) != len(sequential) {
+		t.Fatalf("Parallel scan found %d violations, sequential found %d", len(parallel), len(sequential))
+	}
+	for i := range sequential {
+		if !reflect.DeepEqual(sequential[i], parallel[i]) {
+			t.Errorf("Violation %d differs: sequential %+v, parallel %+v", i, sequential[i], parallel[i])
+		}
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	resources := []*config.Resource{{Type: "aws_instance", Name: "web", File: "main.tf"}}
+	var rules []config.Rule
+	for i := 0; i < 6; i++ {
+		rules = append(rules, config.Rule{
+			ID:           fmt.Sprintf("rule_%d", i),
+			ResourceType: "aws_instance",
+			Conditions:   []config.Condition{{Expression: "true"}},
+		})
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		var calls [][2]int
+		s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+		s.SetConcurrency(concurrency)
+		s.SetProgress(func(done, total int) {
+			calls = append(calls, [2]int{done, total})
+		})
+		if _, err := s.Scan(); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		want := [][2]int{{1, 6}, {2, 6}, {3, 6}, {4, 6}, {5, 6}, {6, 6}}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("concurrency %d: progress calls = %v, want %v", concurrency, calls, want)
+		}
+	}
+}
+
+func TestScanConcurrencyReportsFirstError(t *testing.T) {
+	resources := []*config.Resource{{Type: "aws_instance", Name: "web"}}
+	rules := []config.Rule{
+		{ID: "ok", ResourceType: "aws_instance", Conditions: []config.Condition{{Expression: "false"}}},
+		{ID: "first_bad", ResourceType: "aws_instance", Conditions: []config.Condition{{Expression: "invalid((("}}},
+		{ID: "second_bad", ResourceType: "aws_instance", Conditions: []config.Condition{{Expression: "\"not a bool\""}}},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	s.SetConcurrency(4)
+
+	_, err := s.Scan()
+	if err == nil || !strings.Contains(err.Error(), "first_bad") {
+		t.Errorf("Expected error from first failing rule, got %v", err)
+	}
+}
+
+func TestScanWithCache(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.tf": `resource "aws_s3_bucket" "a" { acl = "public-read" }`,
+		"b.tf": `resource "aws_s3_bucket" "b" { acl = "private" }`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := []config.Rule{
+		{
+			ID:           "public_acl",
+			Severity:     "error",
+			ResourceType: "aws_s3_bucket",
+			Conditions:   []config.Condition{{Expression: `self.acl == "public-read"`}},
+		},
+		{
+			ID:           "cross_resource",
+			Severity:     "warning",
+			ResourceType: "aws_s3_bucket",
+			Conditions:   []config.Condition{{Expression: `length(resources("aws_s3_bucket")) > 1`}},
+		},
+	}
+
+	c, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func() *ScanResult {
+		parsed, err := parser.NewParser().ParseDirectory(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resources, err := parser.ExtractResources(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+		s.SetCache(c, "rules-v1")
+		result, err := s.Scan()
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		return result
+	}
+
+	first := scan()
+	if first.CachedFiles != 0 {
+		t.Errorf("First scan should not hit the cache, got %d cached files", first.CachedFiles)
+	}
+	if len(first.Violations) != 3 {
+		t.Fatalf("Expected 3 violations, got %+v", first.Violations)
+	}
+
+	second := scan()
+	if second.CachedFiles != 2 {
+		t.Errorf("Second scan should reuse both files, got %d", second.CachedFiles)
+	}
+	if !reflect.DeepEqual(first.Violations, second.Violations) {
+		t.Errorf("Cached scan differs:\nfirst  %+v\nsecond %+v", first.Violations, second.Violations)
+	}
+
+	// Changing a file invalidates only that file
+	if err := os.WriteFile(filepath.Join(dir, "b.tf"), []byte(`resource "aws_s3_bucket" "b" { acl = "public-read" }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third := scan()
+	if third.CachedFiles != 1 {
+		t.Errorf("Expected 1 cached file after edit, got %d", third.CachedFiles)
+	}
+	publicCount := 0
+	for _, v := range third.Violations {
+		if v.RuleID == "public_acl" {
+			publicCount++
+		}
+	}
+	if publicCount != 2 {
+		t.Errorf("Edited file should be re-evaluated, got %+v", third.Violations)
+	}
+}
+
+func TestIsCacheable(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`self.acl == "private"`, true},
+		{`contains_function_call("nonsensitive")`, true},
+		{`length(resources("aws_flow_log")) == 0`, false},
+		{`day_of_week() == "friday"`, false},
+		{`self.provider_alias == "us_east_1"`, true},
+		{`self.provider_region != "us-east-1"`, false},
+		{`invalid(((`, false},
+	}
+
+	for _, tt := range tests {
+		rule := config.Rule{Conditions: []config.Condition{{Expression: tt.expression}}}
+		if got := isCacheable(rule); got != tt.expected {
+			t.Errorf("isCacheable(%q) = %v, want %v", tt.expression, got, tt.expected)
+		}
+	}
+
+	rule := config.Rule{
+		When:       &config.WhenBlock{Expression: `git_branch() == "main"`},
+		Conditions: []config.Condition{{Expression: "true"}},
+	}
+	if isCacheable(rule) {
+		t.Error("Rules with a non-local when expression should not be cacheable")
+	}
+}
+
+func TestNeedsAllResources(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`self.acl == "private"`, false},
+		{`length(resources("aws_flow_log")) == 0`, true},
+		{`length(resources_in_file("aws_flow_log")) == 0`, false},
+		{`reference_count() > 0`, true},
+		{`length(attached_to("aws_iam_*")) > 0`, true},
+		{`day_of_week() == "friday"`, false},
+		{`self.provider_region != "us-east-1"`, false},
+		{`invalid(((`, true},
+	}
+
+	for _, tt := range tests {
+		rule := config.Rule{Conditions: []config.Condition{{Expression: tt.expression}}}
+		if got := NeedsAllResources(rule); got != tt.expected {
+			t.Errorf("NeedsAllResources(%q) = %v, want %v", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSuppression(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Suppression
+		wantErr bool
+	}{
+		{value: "aws_s3_versioning:aws_s3_bucket.logs", want: Suppression{RuleID: "aws_s3_versioning", Address: "aws_s3_bucket.logs"}},
+		{value: "require_tags:aws_*.*", want: Suppression{RuleID: "require_tags", Address: "aws_*.*"}},
+		{value: "aws_s3_versioning", wantErr: true},
+		{value: ":aws_s3_bucket.logs", wantErr: true},
+		{value: "aws_s3_versioning:logs", wantErr: true},
+		{value: "aws_s3_versioning:aws_s3_bucket.[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSuppression(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSuppression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseSuppression() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySuppressions(t *testing.T) {
+	result := &ScanResult{
+		Violations: []config.Violation{
+			{RuleID: "aws_s3_versioning", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+			{RuleID: "aws_s3_versioning", ResourceType: "aws_s3_bucket", ResourceName: "assets"},
+			{RuleID: "require_tags", ResourceType: "aws_s3_bucket", ResourceName: "logs"},
+		},
+	}
+
+	ApplySuppressions(result, []Suppression{{RuleID: "aws_s3_versioning", Address: "aws_s3_bucket.logs"}})
+
+	if len(result.Violations) != 2 {
+		t.Fatalf("Expected 2 remaining violations, got %+v", result.Violations)
+	}
+	if len(result.FilteredViolations) != 1 {
+		t.Fatalf("Expected 1 suppressed violation, got %+v", result.FilteredViolations)
+	}
+	suppressed := result.FilteredViolations[0]
+	if suppressed.Violation.ResourceName != "logs" || suppressed.Violation.RuleID != "aws_s3_versioning" {
+		t.Errorf("Wrong violation suppressed: %+v", suppressed.Violation)
+	}
+	if suppressed.Exception.Reason == "" {
+		t.Error("Suppressed violation should record a reason")
+	}
+}
+
+func TestScanCoverage(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "a", File: "main.tf", Attributes: map[string]cty.Value{"acl": cty.StringVal("private")}},
+		{Type: "aws_s3_bucket", Name: "b", File: "other.tf", Attributes: map[string]cty.Value{"acl": cty.StringVal("public-read")}},
+	}
+
+	rules := []config.Rule{
+		{ID: "public", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: `self.acl == "public-read"`}}},
+		{ID: "private_only", ResourceType: "aws_s3_bucket", When: &config.WhenBlock{Expression: `self.acl == "private"`}, Conditions: []config.Condition{{Expression: "true"}}},
+		{ID: "instances", ResourceType: "aws_instance", Conditions: []config.Condition{{Expression: "true"}}},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	s.AddResourceFilter(FileFilter([]string{"main.tf", "other.tf"}))
+	result, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	// Durations vary from run to run
+	for i := range result.Coverage {
+		result.Coverage[i].Duration = 0
+	}
+
+	want := []RuleCoverage{
+		{RuleID: "public", ResourceType: "aws_s3_bucket", Matched: 2, Evaluated: 2, Violations: 1},
+		{RuleID: "private_only", ResourceType: "aws_s3_bucket", Matched: 2, SkippedByWhen: 1, Evaluated: 1, Violations: 1},
+		{RuleID: "instances", ResourceType: "aws_instance"},
+	}
+	if !reflect.DeepEqual(result.Coverage, want) {
+		t.Errorf("Coverage = %+v, want %+v", result.Coverage, want)
+	}
+}
+
+func TestMergeCoverage(t *testing.T) {
+	coverage := []RuleCoverage{
+		{RuleID: "a", Matched: 1, Evaluated: 1, Violations: 1, Duration: time.Second},
+		{RuleID: "b", Matched: 2, Filtered: 2},
+	}
+	other := []RuleCoverage{
+		{RuleID: "b", Matched: 1, SkippedByWhen: 1, Duration: time.Millisecond},
+		{RuleID: "c", Matched: 3, Evaluated: 3},
+	}
+
+	got := MergeCoverage(coverage, other)
+	want := []RuleCoverage{
+		{RuleID: "a", Matched: 1, Evaluated: 1, Violations: 1, Duration: time.Second},
+		{RuleID: "b", Matched: 3, Filtered: 2, SkippedByWhen: 1, Duration: time.Millisecond},
+		{RuleID: "c", Matched: 3, Evaluated: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeCoverage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEvaluatedRules(t *testing.T) {
+	coverage := []RuleCoverage{
+		{RuleID: "evaluated", Matched: 2, Evaluated: 1, Filtered: 1},
+		{RuleID: "filtered", Matched: 1, Filtered: 1},
+		{RuleID: "unmatched"},
+	}
+
+	tests := []struct {
+		name        string
+		cachedFiles int
+		want        map[string]bool
+	}{
+		{name: "fresh scan", want: map[string]bool{"evaluated": true}},
+		{name: "cached files", cachedFiles: 1, want: map[string]bool{"evaluated": true, "filtered": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ScanResult{Coverage: coverage, CachedFiles: tt.cachedFiles}
+			if got := result.EvaluatedRules(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EvaluatedRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "a", File: "main.tf"},
+	}
+
+	tests := []struct {
+		name     string
+		result   *ScanResult
+		wantNil  bool
+		contains []string
+	}{
+		{
+			name:    "rule evaluated",
+			result:  &ScanResult{Coverage: []RuleCoverage{{RuleID: "a", Matched: 1, Evaluated: 1}, {RuleID: "b"}}},
+			wantNil: true,
+		},
+		{
+			name:    "cached results",
+			result:  &ScanResult{CachedFiles: 1, Coverage: []RuleCoverage{{RuleID: "a", Matched: 1, Filtered: 1}}},
+			wantNil: true,
+		},
+		{
+			name:     "no rules",
+			result:   &ScanResult{},
+			contains: []string{"no rules are loaded"},
+		},
+		{
+			name: "every filter explained",
+			result: &ScanResult{Coverage: []RuleCoverage{
+				{RuleID: "a", ResourceType: "aws_instance"},
+				{RuleID: "b", ResourceType: "aws_s3_bucket", Matched: 1, Filtered: 1},
+				{RuleID: "c", ResourceType: "aws_s3_bucket", Matched: 1, SkippedByWhen: 1},
+			}},
+			contains: []string{
+				"none of the 3 rules was evaluated against the 1 resources found",
+				"1 rules target resource types not present (aws_instance); found types: aws_s3_bucket",
+				"1 rules matched only resources excluded by resource filters",
+				"1 rules were skipped by their when condition",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Diagnose(tt.result, resources)
+			if tt.wantNil {
+				if diagnostics != nil {
+					t.Errorf("Diagnose() = %v, want nil", diagnostics)
+				}
+				return
+			}
+			joined := strings.Join(diagnostics, "\n")
+			for _, want := range tt.contains {
+				if !strings.Contains(joined, want) {
+					t.Errorf("Diagnose() = %q, want it to contain %q", joined, want)
+				}
+			}
+		})
+	}
+
+	if diagnostics := Diagnose(&ScanResult{}, nil); diagnostics != nil {
+		t.Errorf("Diagnose() without resources = %v, want nil", diagnostics)
+	}
+}
+
+func TestFilterExceptionsByAddressTagsAndSeverity(t *testing.T) {
+	resources := []*config.Resource{
+		{Kind: config.KindModule, Type: config.KindModule, Name: "storage", File: "main.tf", Line: 1, Attributes: map[string]cty.Value{"source": cty.StringVal("./modules/storage")}},
+		{Kind: config.KindResource, Type: "aws_s3_bucket", Name: "logs", File: "modules/storage/main.tf", Line: 1, Attributes: map[string]cty.Value{
+			"tags": cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("dev-1")}),
+		}},
+		{Kind: config.KindResource, Type: "aws_s3_bucket", Name: "data", File: "modules/storage/main.tf", Line: 10, Attributes: map[string]cty.Value{
+			"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		}},
+		{Kind: config.KindResource, Type: "aws_s3_bucket", Name: "logs", File: "other.tf", Line: 1, Attributes: map[string]cty.Value{}},
+	}
+	ctx := parser.NewScanContext(resources)
+
+	violation := func(resource *config.Resource, severity string) config.Violation {
+		return config.Violation{RuleID: "test", Severity: severity, File: resource.File, Line: resource.Line, ResourceType: resource.Type, ResourceName: resource.Name}
+	}
+
+	tests := []struct {
+		name      string
+		exception config.Exception
+		want      []bool // Whether each violation is excepted
+	}{
+		{
+			name:      "module address",
+			exception: config.Exception{Addresses: []string{"module.storage.aws_s3_bucket.logs"}},
+			want:      []bool{true, false, false},
+		},
+		{
+			name:      "address glob",
+			exception: config.Exception{Addresses: []string{"module.storage.*"}},
+			want:      []bool{true, true, false},
+		},
+		{
+			name:      "root address",
+			exception: config.Exception{Addresses: []string{"aws_s3_bucket.logs"}},
+			want:      []bool{false, false, true},
+		},
+		{
+			name:      "tag value glob",
+			exception: config.Exception{Tags: map[string]string{"env": "dev-*"}},
+			want:      []bool{true, false, false},
+		},
+		{
+			name:      "map tags",
+			exception: config.Exception{Tags: map[string]string{"env": "prod"}},
+			want:      []bool{false, true, false},
+		},
+		{
+			name:      "severity",
+			exception: config.Exception{Severities: []string{"info"}},
+			want:      []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.exception.Rules = []string{"test"}
+			s := NewScanner(&config.Config{Exceptions: []config.Exception{tt.exception}}, nil, ctx)
+
+			violations := []config.Violation{
+				violation(resources[1], "error"),
+				violation(resources[2], "error"),
+				violation(resources[3], "info"),
+			}
+			for i, v := range violations {
+				if _, got := s.findException(v); got != tt.want[i] {
+					t.Errorf("findException(%s.%s in %s) = %v, want %v", v.ResourceType, v.ResourceName, v.File, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExplainMatching(t *testing.T) {
+	violation := "violation"
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "public", File: "b.tf", Line: 1, Attributes: map[string]cty.Value{"acl": cty.StringVal("public-read")}},
+		{Type: "aws_s3_bucket", Name: "private", File: "a.tf", Line: 5, Attributes: map[string]cty.Value{"acl": cty.StringVal("private")}},
+		{Type: "aws_s3_bucket", Name: "computed", File: "a.tf", Line: 9, Attributes: map[string]cty.Value{"acl": cty.UnknownVal(cty.String)}},
+		{Type: "aws_instance", Name: "web", File: "a.tf", Line: 1, Attributes: map[string]cty.Value{}},
+	}
+
+	rules := []config.Rule{
+		{ID: "versioning", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "nonexistent_function()"}}},
+		{ID: "public_only", ResourceType: "aws_s3_bucket", When: &config.WhenBlock{Expression: `self.acl == "public-read"`}},
+		{ID: "public_strict", ResourceType: "aws_s3_bucket", OnUnknown: &violation, When: &config.WhenBlock{Expression: `self.acl == "public-read"`}},
+	}
+
+	s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+	matches, err := s.ExplainMatching()
+	if err != nil {
+		t.Fatalf("ExplainMatching() error = %v", err)
+	}
+
+	got := make(map[string][]RuleMatch)
+	var order []string
+	for _, m := range matches {
+		order = append(order, m.Resource.Name)
+		got[m.Resource.Name] = m.Rules
+	}
+
+	// Sorted by file then line; conditions are never evaluated
+	if want := []string{"web", "private", "computed", "public"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Resource order = %v, want %v", order, want)
+	}
+	if len(got["web"]) != 0 {
+		t.Errorf("Expected no rules for aws_instance, got %+v", got["web"])
+	}
+
+	want := map[string][]RuleMatch{
+		"public": {
+			{RuleID: "versioning", Runs: true},
+			{RuleID: "public_only", When: "true", Runs: true},
+			{RuleID: "public_strict", When: "true", Runs: true},
+		},
+		"private": {
+			{RuleID: "versioning", Runs: true},
+			{RuleID: "public_only", When: "false"},
+			{RuleID: "public_strict", When: "false"},
+		},
+		"computed": {
+			{RuleID: "versioning", Runs: true},
+			{RuleID: "public_only", When: "unknown"},
+			{RuleID: "public_strict", When: "unknown", Runs: true},
+		},
+	}
+	for name, rules := range want {
+		if !reflect.DeepEqual(got[name], rules) {
+			t.Errorf("Matches for %s = %+v, want %+v", name, got[name], rules)
+		}
+	}
+}
+
+func TestScanWithContextCancelled(t *testing.T) {
+	resources := []*config.Resource{
+		{Type: "aws_s3_bucket", Name: "a", File: "main.tf", Attributes: map[string]cty.Value{}},
+	}
+	rules := []config.Rule{
+		{ID: "first", Severity: "error", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "true"}}},
+		{ID: "second", Severity: "error", ResourceType: "aws_s3_bucket", Conditions: []config.Condition{{Expression: "true"}}},
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources))
+		s.SetConcurrency(concurrency)
+		result, err := s.ScanWithContext(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("concurrency %d: ScanWithContext() error = %v, want context.Canceled", concurrency, err)
+		}
+		if result == nil || !result.Incomplete {
+			t.Fatalf("concurrency %d: expected an incomplete result, got %+v", concurrency, result)
+		}
+		if len(result.Violations) != 0 {
+			t.Errorf("concurrency %d: expected no violations from a cancelled scan, got %d", concurrency, len(result.Violations))
+		}
+	}
+
+	result, err := NewScanner(&config.Config{}, rules, parser.NewScanContext(resources)).ScanWithContext(context.Background())
+	if err != nil || result.Incomplete || len(result.Violations) != 2 {
+		t.Errorf("ScanWithContext() = %+v, %v; want 2 violations from a complete scan", result, err)
+	}
+}
@@ -0,0 +1,36 @@
+package scanner
+
+import "github.com/jonathanhle/planguard/pkg/config"
+
+// DefaultSeverityWeights are the risk weights of severities that
+// risk_severity_weights does not set
+var DefaultSeverityWeights = map[string]float64{
+	"error":   10,
+	"warning": 3,
+	"info":    1,
+}
+
+// RiskScore sums the risk weights of violations into a single number that
+// can be tracked over time. A violation weighs its rule's weight from
+// risk_rule_weights if set, otherwise its severity's weight from
+// risk_severity_weights or DefaultSeverityWeights.
+func RiskScore(settings *config.Settings, violations []config.Violation) float64 {
+	var score float64
+	for _, v := range violations {
+		score += RiskWeight(settings, v)
+	}
+	return score
+}
+
+// RiskWeight returns the risk weight of a single violation
+func RiskWeight(settings *config.Settings, v config.Violation) float64 {
+	if settings != nil {
+		if weight, ok := settings.RiskRuleWeights[v.RuleID]; ok {
+			return weight
+		}
+		if weight, ok := settings.RiskSeverityWeights[v.Severity]; ok {
+			return weight
+		}
+	}
+	return DefaultSeverityWeights[v.Severity]
+}
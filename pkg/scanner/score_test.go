@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func TestRiskScore(t *testing.T) {
+	violations := []config.Violation{
+		{RuleID: "s3_public", Severity: "error"},
+		{RuleID: "s3_public", Severity: "error"},
+		{RuleID: "imdsv2", Severity: "warning"},
+		{RuleID: "tags", Severity: "info"},
+	}
+
+	tests := []struct {
+		name     string
+		settings *config.Settings
+		want     float64
+	}{
+		{name: "no settings", want: 10 + 10 + 3 + 1},
+		{name: "default weights", settings: &config.Settings{}, want: 24},
+		{
+			name:     "severity weights",
+			settings: &config.Settings{RiskSeverityWeights: map[string]float64{"error": 5, "info": 0}},
+			want:     5 + 5 + 3 + 0,
+		},
+		{
+			name: "rule weights take precedence",
+			settings: &config.Settings{
+				RiskSeverityWeights: map[string]float64{"error": 5},
+				RiskRuleWeights:     map[string]float64{"s3_public": 50, "tags": 0.5},
+			},
+			want: 50 + 50 + 3 + 0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RiskScore(tt.settings, violations); got != tt.want {
+				t.Errorf("RiskScore() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+
+	if got := RiskScore(nil, nil); got != 0 {
+		t.Errorf("RiskScore() of no violations = %g, want 0", got)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// instrumentationName identifies planguard's meter to metric backends
+const instrumentationName = "github.com/jonathanhle/planguard/pkg/server"
+
+// metrics holds the instruments describing the server's health. Without a
+// meter provider they are no-ops.
+type metrics struct {
+	decisions        metric.Int64Counter
+	decisionsActive  metric.Int64UpDownCounter
+	decisionDuration metric.Float64Histogram
+	evalErrors       metric.Int64Counter
+	rulesLoaded      metric.Int64ObservableGauge
+	registration     metric.Registration
+}
+
+// SetMeterProvider publishes the server's metrics through provider:
+//   - planguard.rules.loaded: rules in each rule set (gauge)
+//   - planguard.decisions.active: decisions being evaluated
+//   - planguard.decisions: decisions made, by rule set and outcome
+//   - planguard.decision.duration: time to evaluate a decision, in seconds
+//   - planguard.evaluation.errors: decisions that failed to evaluate
+//
+// Requests are evaluated as they arrive rather than queued, so
+// planguard.decisions.active doubles as the queue depth.
+func (s *Server) SetMeterProvider(provider metric.MeterProvider) error {
+	m, err := newMetrics(provider.Meter(instrumentationName), s.ruleSets)
+	if err != nil {
+		return fmt.Errorf("failed to register metrics: %w", err)
+	}
+	if s.metrics.registration != nil {
+		_ = s.metrics.registration.Unregister()
+	}
+	s.metrics = m
+	return nil
+}
+
+func newMetrics(meter metric.Meter, ruleSets map[string][]config.Rule) (*metrics, error) {
+	m := &metrics{}
+	var err error
+
+	if m.decisions, err = meter.Int64Counter("planguard.decisions",
+		metric.WithDescription("Policy decisions made, by rule set and decision")); err != nil {
+		return nil, err
+	}
+	if m.decisionsActive, err = meter.Int64UpDownCounter("planguard.decisions.active",
+		metric.WithDescription("Policy decisions currently being evaluated")); err != nil {
+		return nil, err
+	}
+	if m.decisionDuration, err = meter.Float64Histogram("planguard.decision.duration",
+		metric.WithDescription("Time taken to evaluate a policy decision"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.evalErrors, err = meter.Int64Counter("planguard.evaluation.errors",
+		metric.WithDescription("Policy decisions that failed because a rule could not be evaluated")); err != nil {
+		return nil, err
+	}
+	if m.rulesLoaded, err = meter.Int64ObservableGauge("planguard.rules.loaded",
+		metric.WithDescription("Rules loaded in each rule set")); err != nil {
+		return nil, err
+	}
+
+	m.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for name, rules := range ruleSets {
+			o.ObserveInt64(m.rulesLoaded, int64(len(rules)), metric.WithAttributes(attribute.String("rule_set", name)))
+		}
+		return nil
+	}, m.rulesLoaded)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// noopMetrics returns instruments that record nothing
+func noopMetrics() *metrics {
+	m, _ := newMetrics(noop.NewMeterProvider().Meter(instrumentationName), nil)
+	return m
+}
+
+// startDecision records a decision starting and returns a function that
+// records its outcome: "allow", "deny", "invalid" for malformed requests, or
+// "error" when rules could not be evaluated.
+func (m *metrics) startDecision(ctx context.Context, ruleSet string) func(outcome string) {
+	start := time.Now()
+	m.decisionsActive.Add(ctx, 1)
+
+	return func(outcome string) {
+		m.decisionsActive.Add(ctx, -1)
+
+		attrs := metric.WithAttributes(attribute.String("rule_set", ruleSet), attribute.String("decision", outcome))
+		m.decisions.Add(ctx, 1, attrs)
+		m.decisionDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		if outcome == "error" {
+			m.evalErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("rule_set", ruleSet)))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestServerMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	s := testServer()
+	if err := s.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))); err != nil {
+		t.Fatalf("SetMeterProvider() error = %v", err)
+	}
+
+	postDecision(t, s, `{"resource": {"type": "aws_s3_bucket", "attributes": {"acl": "public-read"}}}`)
+	postDecision(t, s, `{"resource": {"type": "aws_s3_bucket", "attributes": {"tags": {}}}}`)
+	postDecision(t, s, `{"resource": {"type": "aws_s3_bucket", "attributes": []}}`)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	sums := make(map[string]map[string]int64) // metric -> decision or rule set -> value
+	gauges := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				sums[m.Name] = make(map[string]int64)
+				for _, dp := range data.DataPoints {
+					key, _ := dp.Attributes.Value("decision")
+					sums[m.Name][key.AsString()] += dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					ruleSet, _ := dp.Attributes.Value("rule_set")
+					gauges[ruleSet.AsString()] = dp.Value
+				}
+			}
+		}
+	}
+
+	decisions := sums["planguard.decisions"]
+	if decisions["deny"] != 1 || decisions["allow"] != 1 || decisions["invalid"] != 1 {
+		t.Errorf("planguard.decisions = %v, want one deny, allow, and invalid", decisions)
+	}
+	if active := sums["planguard.decisions.active"][""]; active != 0 {
+		t.Errorf("planguard.decisions.active = %d, want 0 after all decisions finished", active)
+	}
+	if gauges[DefaultRuleSet] != 2 || gauges["empty"] != 0 {
+		t.Errorf("planguard.rules.loaded = %v, want default=2 and empty=0", gauges)
+	}
+}
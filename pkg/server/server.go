@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/jonathanhle/planguard/pkg/config"
+	"github.com/jonathanhle/planguard/pkg/parser"
+	"github.com/jonathanhle/planguard/pkg/reporter"
+	"github.com/jonathanhle/planguard/pkg/scanner"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// DefaultRuleSet is the rule set used when a request does not name one
+const DefaultRuleSet = "default"
+
+// maxRequestBytes bounds the size of a decision request body
+const maxRequestBytes = 1 << 20
+
+// Server answers policy decisions for single resources over HTTP, so
+// systems outside Terraform can reuse the same rules. It never modifies
+// any state.
+type Server struct {
+	config   *config.Config
+	ruleSets map[string][]config.Rule
+	metrics  *metrics
+}
+
+// DecisionRequest is the body of a POST /decision request
+type DecisionRequest struct {
+	RuleSet  string           `json:"rule_set"`
+	FailOn   string           `json:"fail_on,omitempty"`
+	Resource ResourceDocument `json:"resource"`
+}
+
+// ResourceDocument describes the resource to evaluate. Attributes use the
+// same shape as the resource's Terraform arguments.
+type ResourceDocument struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// DecisionResponse is the result of a decision
+type DecisionResponse struct {
+	Decision string   `json:"decision"` // "allow" or "deny"
+	RuleSet  string   `json:"rule_set"`
+	Reasons  []Reason `json:"reasons"`
+}
+
+// Reason is a rule violated by the evaluated resource
+type Reason struct {
+	RuleID      string `json:"rule_id"`
+	RuleName    string `json:"rule_name"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// RequestError is returned by Decide for requests that cannot be evaluated
+// as sent
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// New creates a decision server for the given named rule sets. Exceptions
+// from cfg apply to decisions the same way they apply to scans.
+func New(cfg *config.Config, ruleSets map[string][]config.Rule) *Server {
+	return &Server{
+		config:   cfg,
+		ruleSets: ruleSets,
+		metrics:  noopMetrics(),
+	}
+}
+
+// RuleSets returns the names of the available rule sets in sorted order
+func (s *Server) RuleSets() []string {
+	names := make([]string, 0, len(s.ruleSets))
+	for name := range s.ruleSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handler returns the HTTP handler serving /decision and /healthz
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/decision", s.handleDecision)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":    "ok",
+			"rule_sets": s.RuleSets(),
+		})
+	})
+	return mux
+}
+
+func (s *Server) handleDecision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var req DecisionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := s.Decide(req)
+	if err != nil {
+		if reqErr, ok := err.(*RequestError); ok {
+			writeError(w, reqErr.Status, reqErr.Message)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Decide evaluates a single resource against a rule set. The decision is
+// "deny" when a violation reaches the request's fail_on severity (default
+// "error"); every violation is returned as a reason either way.
+func (s *Server) Decide(req DecisionRequest) (*DecisionResponse, error) {
+	ruleSet := req.RuleSet
+	if ruleSet == "" {
+		ruleSet = DefaultRuleSet
+	}
+	rules, ok := s.ruleSets[ruleSet]
+	if !ok {
+		return nil, &RequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("unknown rule set %q", ruleSet)}
+	}
+
+	done := s.metrics.startDecision(context.Background(), ruleSet)
+	resp, err := s.decide(req, ruleSet, rules)
+	switch {
+	case err == nil:
+		done(resp.Decision)
+	case isEvaluationError(err):
+		done("error")
+	default:
+		done("invalid")
+	}
+	return resp, err
+}
+
+// isEvaluationError reports whether err means a rule failed to evaluate,
+// as opposed to a malformed request
+func isEvaluationError(err error) bool {
+	reqErr, ok := err.(*RequestError)
+	return !ok || reqErr.Status == http.StatusUnprocessableEntity
+}
+
+func (s *Server) decide(req DecisionRequest, ruleSet string, rules []config.Rule) (*DecisionResponse, error) {
+
+	if req.Resource.Type == "" {
+		return nil, &RequestError{Status: http.StatusBadRequest, Message: "resource.type is required"}
+	}
+
+	attributes, err := decodeAttributes(req.Resource.Attributes)
+	if err != nil {
+		return nil, &RequestError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	name := req.Resource.Name
+	if name == "" {
+		name = "this"
+	}
+	resource := &config.Resource{
+		Kind:       config.KindResource,
+		Type:       req.Resource.Type,
+		Name:       name,
+		File:       "decision",
+		Labels:     []string{req.Resource.Type, name},
+		Attributes: attributes,
+		RawExprs:   make(map[string]hcl.Expression),
+	}
+
+	ctx := parser.NewScanContext([]*config.Resource{resource})
+	result, err := scanner.NewScanner(s.config, rules, ctx).Scan()
+	if err != nil {
+		return nil, &RequestError{Status: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+
+	failOn := req.FailOn
+	if failOn == "" {
+		failOn = "error"
+	}
+
+	resp := &DecisionResponse{
+		Decision: "allow",
+		RuleSet:  ruleSet,
+		Reasons:  []Reason{},
+	}
+	if reporter.NewReporter(result.Violations, result.FilteredViolations).ShouldFail(failOn) {
+		resp.Decision = "deny"
+	}
+	for _, v := range result.Violations {
+		resp.Reasons = append(resp.Reasons, Reason{
+			RuleID:      v.RuleID,
+			RuleName:    v.RuleName,
+			Severity:    v.Severity,
+			Message:     v.Message,
+			Remediation: v.Remediation,
+		})
+	}
+
+	return resp, nil
+}
+
+// decodeAttributes converts a JSON object into resource attributes
+func decodeAttributes(raw json.RawMessage) (map[string]cty.Value, error) {
+	attributes := make(map[string]cty.Value)
+	if len(raw) == 0 || string(raw) == "null" {
+		return attributes, nil
+	}
+
+	ty, err := ctyjson.ImpliedType(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource.attributes: %w", err)
+	}
+	if !ty.IsObjectType() {
+		return nil, fmt.Errorf("resource.attributes must be a JSON object")
+	}
+
+	val, err := ctyjson.Unmarshal(raw, ty)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource.attributes: %w", err)
+	}
+
+	for name, attr := range val.AsValueMap() {
+		attributes[name] = attr
+	}
+	return attributes, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonathanhle/planguard/pkg/config"
+)
+
+func testServer() *Server {
+	rules := []config.Rule{
+		{
+			ID:           "s3_public_acl",
+			Name:         "S3 bucket must not be public",
+			Severity:     "error",
+			ResourceType: "aws_s3_bucket",
+			Conditions:   []config.Condition{{Expression: `try(self.acl, "private") == "public-read"`}},
+			Message:      "Bucket is public",
+		},
+		{
+			ID:           "s3_tags",
+			Name:         "S3 bucket should be tagged",
+			Severity:     "warning",
+			ResourceType: "aws_s3_bucket",
+			Conditions:   []config.Condition{{Expression: `!has(self, "tags")`}},
+			Message:      "Bucket has no tags",
+		},
+	}
+
+	return New(&config.Config{}, map[string][]config.Rule{
+		DefaultRuleSet: rules,
+		"empty":        {},
+	})
+}
+
+func postDecision(t *testing.T, s *Server, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/decision", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &parsed); err != nil {
+		t.Fatalf("Response is not JSON: %v\n%s", err, rec.Body.String())
+	}
+	return rec, parsed
+}
+
+func TestDecisionDeny(t *testing.T) {
+	rec, resp := postDecision(t, testServer(), `{
+		"resource": {"type": "aws_s3_bucket", "name": "logs", "attributes": {"acl": "public-read"}}
+	}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if resp["decision"] != "deny" {
+		t.Errorf("Decision = %v, want deny", resp["decision"])
+	}
+	if resp["rule_set"] != DefaultRuleSet {
+		t.Errorf("Rule set = %v, want %s", resp["rule_set"], DefaultRuleSet)
+	}
+	if reasons := resp["reasons"].([]interface{}); len(reasons) != 2 {
+		t.Errorf("Expected 2 reasons, got %v", reasons)
+	}
+}
+
+func TestDecisionAllowWithWarnings(t *testing.T) {
+	body := `{"resource": {"type": "aws_s3_bucket", "attributes": {"acl": "private"}}}`
+
+	_, resp := postDecision(t, testServer(), body)
+	if resp["decision"] != "allow" {
+		t.Errorf("Warnings should not deny by default, got %v", resp["decision"])
+	}
+	if reasons := resp["reasons"].([]interface{}); len(reasons) != 1 {
+		t.Errorf("Warnings should still be reported as reasons, got %v", reasons)
+	}
+
+	_, resp = postDecision(t, testServer(), `{"fail_on": "warning", "resource": {"type": "aws_s3_bucket", "attributes": {"acl": "private"}}}`)
+	if resp["decision"] != "deny" {
+		t.Errorf("fail_on=warning should deny on warnings, got %v", resp["decision"])
+	}
+}
+
+func TestDecisionRuleSetSelection(t *testing.T) {
+	_, resp := postDecision(t, testServer(), `{"rule_set": "empty", "resource": {"type": "aws_s3_bucket", "attributes": {"acl": "public-read"}}}`)
+	if resp["decision"] != "allow" {
+		t.Errorf("Empty rule set should allow, got %v", resp["decision"])
+	}
+
+	rec, _ := postDecision(t, testServer(), `{"rule_set": "missing", "resource": {"type": "aws_s3_bucket"}}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Unknown rule set status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDecisionBadRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"unknown field", `{"resource": {"type": "aws_s3_bucket"}, "extra": 1}`, http.StatusBadRequest},
+		{"missing type", `{"resource": {"name": "x"}}`, http.StatusBadRequest},
+		{"attributes not object", `{"resource": {"type": "aws_s3_bucket", "attributes": [1]}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := postDecision(t, testServer(), tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.status)
+			}
+			if resp["error"] == nil {
+				t.Errorf("Expected error message, got %v", resp)
+			}
+		})
+	}
+}
+
+func TestDecisionMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testServer().Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/decision", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Status = %d, want 405", rec.Code)
+	}
+}
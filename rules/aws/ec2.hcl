@@ -15,6 +15,24 @@ rule "aws_ec2_imdsv2" {
   }
   
   message = "EC2 instances should require IMDSv2 for enhanced security"
+
+  example_pass = <<-EOT
+    resource "aws_instance" "web" {
+      ami           = "ami-12345678"
+      instance_type = "t3.micro"
+
+      metadata_options {
+        http_tokens = "required"
+      }
+    }
+  EOT
+
+  example_fail = <<-EOT
+    resource "aws_instance" "web" {
+      ami           = "ami-12345678"
+      instance_type = "t3.micro"
+    }
+  EOT
 }
 
 rule "aws_security_group_ingress_all" {
@@ -26,11 +44,33 @@ rule "aws_security_group_ingress_all" {
   condition {
     expression = <<-EXPR
       anytrue([
-        for rule in try(self.ingress, []) :
+        for rule in blocks(self, "ingress") :
         contains(try(rule.cidr_blocks, []), "0.0.0.0/0")
       ])
     EXPR
   }
   
   message = "Security groups must not allow ingress from 0.0.0.0/0"
+
+  example_pass = <<-EOT
+    resource "aws_security_group" "web" {
+      ingress {
+        from_port   = 443
+        to_port     = 443
+        protocol    = "tcp"
+        cidr_blocks = ["10.0.0.0/8"]
+      }
+    }
+  EOT
+
+  example_fail = <<-EOT
+    resource "aws_security_group" "web" {
+      ingress {
+        from_port   = 22
+        to_port     = 22
+        protocol    = "tcp"
+        cidr_blocks = ["0.0.0.0/0"]
+      }
+    }
+  EOT
 }
@@ -0,0 +1,11 @@
+# AWS rule pack manifest
+#
+# Provider major versions these rules were written and tested against.
+# Scans warn when required_providers allows a major version outside this
+# list, since renamed or split resources can cause false positives.
+
+pack "aws" {
+  provider "aws" {
+    major_versions = [4, 5]
+  }
+}
@@ -20,6 +20,19 @@ rule "aws_rds_encryption" {
       # ... other config
     }
   EOT
+
+  example_pass = <<-EOT
+    resource "aws_db_instance" "main" {
+      engine            = "postgres"
+      storage_encrypted = true
+    }
+  EOT
+
+  example_fail = <<-EOT
+    resource "aws_db_instance" "main" {
+      engine = "postgres"
+    }
+  EOT
 }
 
 rule "aws_rds_public_access" {
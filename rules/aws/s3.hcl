@@ -20,6 +20,20 @@ rule "aws_s3_public_read" {
       # Remove: acl = "public-read"
     }
   EOT
+
+  example_pass = <<-EOT
+    resource "aws_s3_bucket" "logs" {
+      bucket = "my-logs"
+      acl    = "private"
+    }
+  EOT
+
+  example_fail = <<-EOT
+    resource "aws_s3_bucket" "logs" {
+      bucket = "my-logs"
+      acl    = "public-read"
+    }
+  EOT
 }
 
 rule "aws_s3_public_readwrite" {
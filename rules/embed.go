@@ -0,0 +1,10 @@
+// Package rules embeds the presupplied rules, so planguard can compare them
+// with the copy installed in the rules directory or use them directly.
+package rules
+
+import "embed"
+
+// FS holds the presupplied rules, laid out like a rules directory
+//
+//go:embed aws common hygiene
+var FS embed.FS
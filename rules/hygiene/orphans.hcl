@@ -0,0 +1,19 @@
+# Orphan Resource Rules
+#
+# Flag resources and data sources that no other block, output, or module
+# call references. Many resources are legitimately standalone, so this
+# category is opt-in and best used with exceptions for known leaves.
+
+rule "orphan_resource" {
+  name     = "Resource is never referenced"
+  severity = "info"
+
+  resource_type = "*"
+
+  condition {
+    expression = "reference_count() == 0"
+  }
+
+  message     = "Resource is not referenced by any other resource, output, or module in its module"
+  remediation = "Remove the resource if it is left over, or expose it through an output"
+}
@@ -0,0 +1,47 @@
+# Configuration Hygiene Rules
+#
+# Flag declarations nothing uses. References are counted per Terraform
+# module (directory); outputs also count module.<name>.<output> references
+# from callers anywhere in the scan.
+
+rule "unreferenced_variable" {
+  name     = "Variable is never referenced"
+  severity = "info"
+
+  resource_type = "variable"
+
+  condition {
+    expression = "reference_count() == 0"
+  }
+
+  message     = "Variable is declared but never referenced in its module"
+  remediation = "Remove the variable or use it; unused inputs mislead callers about what the module configures"
+}
+
+rule "unreferenced_local" {
+  name     = "Local value is never referenced"
+  severity = "info"
+
+  resource_type = "local"
+
+  condition {
+    expression = "reference_count() == 0"
+  }
+
+  message     = "Local value is declared but never referenced in its module"
+  remediation = "Remove the unused local value"
+}
+
+rule "unreferenced_output" {
+  name     = "Output is never referenced"
+  severity = "info"
+
+  resource_type = "output"
+
+  condition {
+    expression = "reference_count() == 0"
+  }
+
+  message     = "Output is not referenced by any module call in the scanned configuration"
+  remediation = "Remove the output, or add an exception if it is consumed outside this repository (e.g. a root module output)"
+}